@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -15,8 +16,10 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
+	internal "github.com/taskflow/taskflow/internal"
 	"github.com/taskflow/taskflow/internal/api"
 	"github.com/taskflow/taskflow/internal/auth"
 	"github.com/taskflow/taskflow/internal/config"
@@ -30,11 +33,13 @@ import (
 const pidFileName = "taskflow.pid"
 
 func main() {
+	configPath, args := configFlag(os.Args[1:])
+
 	// Handle service commands
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
+	if len(args) > 0 {
+		switch args[0] {
 		case "start":
-			startDaemon()
+			startDaemon(configPath)
 			return
 		case "stop":
 			stopDaemon()
@@ -42,11 +47,14 @@ func main() {
 		case "status":
 			checkStatus()
 			return
+		case "config":
+			printConfig(configPath, args[1:])
+			return
 		case "help", "-h", "--help":
 			printUsage()
 			return
 		default:
-			fmt.Printf("Unknown command: %s\n", os.Args[1])
+			fmt.Printf("Unknown command: %s\n", args[0])
 			printUsage()
 			os.Exit(1)
 		}
@@ -66,8 +74,11 @@ func main() {
 	}
 	defer removePIDFile()
 
+	// Captured for uptime reporting on /api/version
+	startTime := time.Now()
+
 	// Load configuration
-	cfg := config.Load()
+	cfg := loadConfig(configPath)
 
 	// Auto-generate JWT secret if not provided
 	if cfg.JWTSecret == "" {
@@ -79,6 +90,15 @@ func main() {
 		log.Println("Warning: JWT_SECRET not set, generated random secret. Sessions will not persist across restarts.")
 	}
 
+	// Validate the API base path and notification proxy before doing any other work
+	if err := config.ValidateAPIBasePath(cfg.APIBasePath); err != nil {
+		log.Fatalf("Invalid API_BASE_PATH: %v", err)
+	}
+	notifyHTTPClient, err := notification.NewHTTPClient(cfg.NotifyProxyURL)
+	if err != nil {
+		log.Fatalf("Invalid NOTIFY_PROXY_URL: %v", err)
+	}
+
 	// Initialize database
 	log.Printf("Initializing database at %s\n", cfg.DBPath)
 	db, err := store.New(cfg.DBPath)
@@ -103,40 +123,54 @@ func main() {
 
 	// Initialize JWT manager
 	jwtManager := auth.NewJWTManager(cfg.JWTSecret)
+	jwtManager.SetIssuer(cfg.JWTIssuer, cfg.JWTAudience)
+	jwtManager.SetLeeway(time.Duration(cfg.JWTLeewaySeconds) * time.Second)
 
 	// Initialize scheduler and executor
 	sched := scheduler.New(db)
+	sched.SetRestartGrace(time.Duration(cfg.RestartGraceSeconds) * time.Second)
+	sched.SetQueueOverflowPolicy(cfg.QueueOverflowPolicy)
+	// Drop the scheduler's cached schedule as soon as an admin edits it
+	db.SetScheduleChangeHook(sched.InvalidateSchedule)
+	db.SetEnforceUniqueJobNames(cfg.UniqueJobNames)
+	db.SetManualTriggerInterval(time.Duration(cfg.ManualTriggerInterval) * time.Second)
+	db.SetWriteRetries(cfg.DBWriteRetries)
 	exec := executor.New(db)
+	exec.SetCompressLogs(cfg.CompressLogs)
+	exec.SetMaxTotalRuns(cfg.MaxTotalRuns)
+	exec.SetMaxLogBytes(cfg.MaxRunLogBytes)
+	exec.SetMaxTimeoutSeconds(cfg.MaxJobTimeout)
+	// Runs go to this executor or to registered remote agents, weighted by capacity
+	dispatcher := executor.NewDispatcher(db, exec)
+	dispatcher.SetLocalWeight(cfg.LocalExecutorWeight)
+	// Retries of remote runs go back through the queue to be dispatched again
+	dispatcher.SetRetryEnqueuer(sched.EnqueueWithRun)
 
 	// Create WebSocket hub with CORS validation
-	wsHub := api.NewWSHub(cfg.AllowedOrigins)
+	wsHub, err := api.NewWSHub(cfg.AllowedOrigins)
+	if err != nil {
+		log.Fatalf("Invalid ALLOWED_ORIGINS: %v", err)
+	}
+	wsHub.SetPingInterval(time.Duration(cfg.WSPingIntervalSeconds) * time.Second)
+	wsHub.SetLogStore(db)
+	wsHub.SetMaxConnectionsPerRun(cfg.WSMaxConnsPerRun)
+	wsHub.SetCompression(cfg.WSCompression)
 	go wsHub.Run()
 
 	// Wire up executor to broadcast logs and status via WebSocket
-	exec.SetLogBroadcaster(func(runID string, stream string, content string, timestamp time.Time) {
-		wsHub.Broadcast(api.WSMessage{
-			Type:      "log",
-			RunID:     runID,
-			Timestamp: timestamp.Format(time.RFC3339),
-			Data: map[string]string{
-				"stream":  stream,
-				"content": content,
-			},
-		})
+	exec.SetLogBroadcaster(func(runID string, id int, stream string, content string, timestamp time.Time) {
+		wsHub.Broadcast(api.LogMessage(runID, id, stream, content, timestamp))
 	})
-	exec.SetStatusBroadcaster(func(runID string, status string) {
-		wsHub.Broadcast(api.WSMessage{
-			Type:      "status",
-			RunID:     runID,
-			Timestamp: time.Now().Format(time.RFC3339),
-			Data: map[string]string{
-				"status": status,
-			},
-		})
+	exec.SetStatusBroadcaster(func(runID string, status string, job *store.Job) {
+		// The hub also mirrors this to the global activity feed
+		wsHub.Broadcast(api.StatusMessage(runID, status, job))
 	})
 
-	// Set up email notification sender
+	// Set up email, Teams and PagerDuty notification sender
 	notifier := notification.New(db)
+	notifier.SetHTTPClient(notifyHTTPClient)
+	notifier.SetOutbox(db)
+	notifier.SetChannels(db)
 	exec.SetNotificationSender(func(job *store.Job, run *store.Run) {
 		// Send notification asynchronously to not block job execution
 		go func() {
@@ -145,9 +179,40 @@ func main() {
 			}
 		}()
 	})
+	exec.SetTimeoutWarningSender(func(job *store.Job, run *store.Run, elapsed time.Duration) {
+		// The run is still executing; never block it on SMTP
+		go func() {
+			if err := notifier.SendTimeoutWarning(job, run, elapsed); err != nil {
+				log.Printf("Failed to send timeout warning for job %s: %v", job.ID, err)
+			}
+		}()
+	})
+
+	// Slow or missed scheduler ticks are always logged; admins are emailed when recipients are set
+	sched.SetSlowTickThreshold(time.Duration(cfg.SlowTickSeconds) * time.Second)
+	if cfg.SchedulerAlertEmails != "" {
+		sched.SetTickAlertHandler(func(alert scheduler.TickAlert) {
+			go func() {
+				subject := "Scheduler tick " + alert.Kind
+				if err := notifier.SendAdminAlert(cfg.SchedulerAlertEmails, subject, alert.Message()); err != nil {
+					log.Printf("Failed to send scheduler alert: %v", err)
+				}
+			}()
+		})
+	}
+
+	// Safe mode scans job scripts on save; a custom rules file replaces the built-in rules
+	scriptRules := api.DefaultScriptRules()
+	if cfg.SafeModeRules != "" {
+		scriptRules, err = api.LoadScriptRules(cfg.SafeModeRules)
+		if err != nil {
+			log.Fatalf("Invalid SAFE_MODE_RULES: %v", err)
+		}
+	}
+	scriptGuard := api.NewScriptGuard(cfg.SafeMode, scriptRules)
 
 	// Create HTTP router (pass wsHub and scheduler for job processing)
-	router := api.NewRouter(db, jwtManager, wsHub, cfg.AllowedOrigins, sched, cfg.APIBasePath)
+	router := api.NewRouter(db, jwtManager, wsHub, cfg.AllowedOrigins, sched, cfg.APIBasePath, startTime, scriptGuard, cfg.AllowedWorkDirRoots, cfg.MaxJobTimeout, cfg.MinScheduleInterval, exec, dispatcher)
 	apiBasePath := cfg.APIBasePath
 
 	// Initialize embedded filesystem for serving frontend
@@ -160,7 +225,7 @@ func main() {
 	// Create main handler that combines router and file server
 	mainHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Route API, health, and setup requests to the router
-		if isAPIPath(r.URL.Path, apiBasePath) {
+		if api.IsAPIPath(r.URL.Path, apiBasePath) {
 			router.ServeHTTP(w, r)
 			return
 		}
@@ -228,13 +293,21 @@ func main() {
 		Handler: mainHandler,
 	}
 
+	// Base context for job execution; cancelled on shutdown so in-flight runs
+	// are killed and recorded as cancelled rather than left "running".
+	jobCtx, cancelJobs := context.WithCancel(context.Background())
+	defer cancelJobs()
+
+	// Fail remote runs whose timeout passed or whose agent stopped sending heartbeats
+	go dispatcher.RunReaper(jobCtx)
+
 	// Start scheduler
 	go func() {
 		jobHandler := func(job *store.Job, existingRun *store.Run) error {
 			var run *store.Run
 			var err error
 
-			// Use existing run if provided (manual triggers), otherwise create new one (scheduled)
+			// Use the pre-created run if provided (scheduled and manual triggers), otherwise create one
 			if existingRun != nil {
 				run = existingRun
 			} else {
@@ -245,14 +318,11 @@ func main() {
 				}
 			}
 
-			// Execute the job
-			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(job.TimeoutSeconds)*time.Second)
-			defer cancel()
-
-			return exec.Execute(ctx, run, job)
+			// Run the job here or on a remote agent; each attempt enforces the job timeout itself
+			return dispatcher.Dispatch(jobCtx, run, job)
 		}
 
-		if err := sched.Start(context.Background(), jobHandler); err != nil {
+		if err := sched.Start(jobCtx, jobHandler); err != nil {
 			log.Printf("Failed to start scheduler: %v\n", err)
 		}
 	}()
@@ -266,9 +336,31 @@ func main() {
 			if err := db.DeleteOldRuns(cfg.LogRetentionDays); err != nil {
 				log.Printf("Failed to cleanup old runs: %v\n", err)
 			}
+			if cfg.LogContentRetention > 0 {
+				if purged, err := db.PurgeOldRunContent(cfg.LogContentRetention); err != nil {
+					log.Printf("Failed to purge old run logs: %v\n", err)
+				} else if purged > 0 {
+					log.Printf("Purged logs and metrics of %d old runs\n", purged)
+				}
+			}
+			if cfg.MaxTotalRuns > 0 {
+				if evicted, err := db.EnforceGlobalRunCap(cfg.MaxTotalRuns); err != nil {
+					log.Printf("Failed to enforce MAX_TOTAL_RUNS: %v\n", err)
+				} else if evicted > 0 {
+					log.Printf("Evicted %d runs over MAX_TOTAL_RUNS\n", evicted)
+				}
+			}
 		}
 	}()
 
+	// Retry failed notification deliveries from the outbox
+	go notifier.RunOutbox(jobCtx, internal.NotificationOutboxPollInterval)
+
+	// Periodic activity digest for managers, alongside per-run alerts
+	if cfg.DigestSchedule != "" {
+		go notifier.RunDigest(jobCtx, db, cfg.DigestRecipients, cfg.DigestSchedule, cfg.DigestHour)
+	}
+
 	// Start server in background
 	go func() {
 		log.Printf("Starting TaskFlow on %s\n", server.Addr)
@@ -290,6 +382,17 @@ func main() {
 
 	sched.Stop()
 
+	// Give the in-flight job a chance to finish, then cancel it and wait for its run to be finalized
+	if err := sched.Wait(ctx); err != nil {
+		log.Println("Running job did not finish in time, cancelling it")
+		cancelJobs()
+		cancelCtx, cancelWait := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := sched.Wait(cancelCtx); err != nil {
+			log.Printf("Cancelled job did not stop cleanly: %v\n", err)
+		}
+		cancelWait()
+	}
+
 	if err := server.Shutdown(ctx); err != nil {
 		log.Printf("Shutdown error: %v\n", err)
 	}
@@ -297,24 +400,6 @@ func main() {
 	log.Println("Shutdown complete")
 }
 
-// isAPIPath checks if a path should be handled by the API router
-func isAPIPath(path string, apiBasePath string) bool {
-	// Derive setup base path from API base path (e.g., /taskflow/api -> /taskflow/setup)
-	setupBasePath := "/taskflow/setup"
-	if apiBasePath != "/taskflow/api" {
-		prefix := apiBasePath
-		if len(prefix) > 4 && prefix[len(prefix)-4:] == "/api" {
-			prefix = prefix[:len(prefix)-4]
-		}
-		setupBasePath = prefix + "/setup"
-	}
-	return path == "/health" ||
-		strings.HasPrefix(path, "/taskflow-app/") ||
-		strings.HasPrefix(path, setupBasePath+"/") ||
-		path == setupBasePath+"/status" ||
-		strings.HasPrefix(path, apiBasePath+"/")
-}
-
 // isAssetPath checks if a path is likely an asset file
 func isAssetPath(path string) bool {
 	if path == "/" || path == "/index.html" {
@@ -370,8 +455,40 @@ func isProcessRunning(pid int) bool {
 	return err == nil
 }
 
+// configFlag removes --config <file> (or --config=<file>) from args and returns the file,
+// falling back to TASKFLOW_CONFIG when the flag is absent
+func configFlag(args []string) (string, []string) {
+	path := os.Getenv("TASKFLOW_CONFIG")
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--config" || arg == "-config":
+			if i+1 >= len(args) {
+				fmt.Println("--config requires a file path")
+				os.Exit(1)
+			}
+			i++
+			path = args[i]
+		case strings.HasPrefix(arg, "--config="):
+			path = strings.TrimPrefix(arg, "--config=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return path, rest
+}
+
+// loadConfig loads the configuration from the config file, if any, and the environment
+func loadConfig(path string) *config.Config {
+	cfg, err := config.LoadFile(path)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	return cfg
+}
+
 // startDaemon starts TaskFlow as a background daemon
-func startDaemon() {
+func startDaemon(configPath string) {
 	// Check if already running
 	if pid, err := readPID(); err == nil {
 		if isProcessRunning(pid) {
@@ -389,8 +506,15 @@ func startDaemon() {
 		os.Exit(1)
 	}
 
-	// Start the process in background
-	cmd := exec.Command(execPath)
+	// Start the process in background, with the same config file
+	var args []string
+	if configPath != "" {
+		if abs, err := filepath.Abs(configPath); err == nil {
+			configPath = abs
+		}
+		args = append(args, "--config", configPath)
+	}
+	cmd := exec.Command(execPath, args...)
 	cmd.Env = append(os.Environ(), "TASKFLOW_DAEMON=1")
 
 	// Detach from terminal
@@ -467,6 +591,45 @@ func checkStatus() {
 	}
 }
 
+// printConfig prints the effective configuration with secrets redacted, as a table or JSON (--json)
+func printConfig(configPath string, args []string) {
+	asJSON := false
+	for _, arg := range args {
+		switch arg {
+		case "--json", "-json":
+			asJSON = true
+		default:
+			fmt.Printf("Unknown option for config: %s\n", arg)
+			os.Exit(1)
+		}
+	}
+
+	entries := loadConfig(configPath).Entries()
+
+	if asJSON {
+		values := make(map[string]string, len(entries))
+		for _, e := range entries {
+			values[e.Name] = e.Value
+		}
+		out, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to encode config: %v", err)
+		}
+		fmt.Println(string(out))
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, e := range entries {
+		value := e.Value
+		if value == "" {
+			value = "(not set)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", e.Name, value)
+	}
+	tw.Flush()
+}
+
 // printUsage prints the usage information
 func printUsage() {
 	fmt.Println("TaskFlow - Lightweight Task Scheduler")
@@ -476,13 +639,20 @@ func printUsage() {
 	fmt.Println("  taskflow start    Start TaskFlow as a background daemon")
 	fmt.Println("  taskflow stop     Stop the running TaskFlow daemon")
 	fmt.Println("  taskflow status   Check if TaskFlow is running")
+	fmt.Println("  taskflow config   Print the effective configuration (--json for JSON)")
 	fmt.Println("  taskflow help     Show this help message")
 	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --config FILE     YAML or JSON file of settings keyed by variable name; variables")
+	fmt.Println("                    that are set override it (or set TASKFLOW_CONFIG)")
+	fmt.Println()
 	fmt.Println("Environment Variables:")
 	fmt.Println("  PORT              HTTP listen port (default: 8080)")
 	fmt.Println("  DB_PATH           SQLite database path (default: taskflow.db)")
 	fmt.Println("  JWT_SECRET        JWT signing secret (auto-generated if not set)")
 	fmt.Println("  API_BASE_PATH     API base path (default: /taskflow/api)")
 	fmt.Println("  LOG_RETENTION_DAYS  Days to keep run logs (default: 30)")
+	fmt.Println("  LOG_CONTENT_RETENTION_DAYS  Days to keep logs and metrics of kept runs (default: 0, off)")
+	fmt.Println("  MAX_TOTAL_RUNS    Cap on stored runs across all jobs (default: 0, off)")
 	fmt.Println("  ALLOWED_ORIGINS   CORS allowed origins (default: *)")
 }
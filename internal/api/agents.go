@@ -0,0 +1,204 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	internal "github.com/taskflow/taskflow/internal"
+	"github.com/taskflow/taskflow/internal/executor"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// AgentHandlers handles remote executor agent registration and result reporting.
+// Agents call these endpoints with an admin token.
+type AgentHandlers struct {
+	store      *store.Store
+	dispatcher *executor.Dispatcher
+}
+
+// NewAgentHandlers creates agent handlers
+func NewAgentHandlers(st *store.Store) *AgentHandlers {
+	return &AgentHandlers{store: st}
+}
+
+// SetDispatcher sets the dispatcher that finishes reported runs. Without one a result
+// only updates the run.
+func (h *AgentHandlers) SetDispatcher(d *executor.Dispatcher) {
+	h.dispatcher = d
+}
+
+// AgentRequest is the body of POST /api/agents
+type AgentRequest struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	Capacity int    `json:"capacity"`
+}
+
+// AgentResultRequest is the body an agent posts when a dispatched run finishes
+type AgentResultRequest struct {
+	Status       string           `json:"status"`
+	ExitCode     *int             `json:"exit_code"`
+	ErrorMessage *string          `json:"error_message"`
+	Logs         []store.LogEntry `json:"logs"`
+}
+
+// RegisterAgent handles POST /api/agents. Re-registering an ID updates the agent.
+func (h *AgentHandlers) RegisterAgent(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
+		return
+	}
+
+	var req AgentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteBodyError(w, err, "Invalid request body", "INVALID_REQUEST")
+		return
+	}
+
+	req.ID = strings.TrimSpace(req.ID)
+	if req.ID == "" || req.ID == internal.LocalAgentID {
+		WriteError(w, http.StatusBadRequest, "id is required and cannot be \""+internal.LocalAgentID+"\"", "VALIDATION_ERROR")
+		return
+	}
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		WriteError(w, http.StatusBadRequest, "url must be an http(s) URL", "VALIDATION_ERROR")
+		return
+	}
+	if req.Capacity < 1 {
+		WriteError(w, http.StatusBadRequest, "capacity must be at least 1", "VALIDATION_ERROR")
+		return
+	}
+	if req.Name == "" {
+		req.Name = req.ID
+	}
+
+	agent, err := h.store.RegisterAgent(req.ID, req.Name, req.URL, req.Capacity)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to register agent", "INTERNAL_ERROR")
+		return
+	}
+	WriteJSON(w, http.StatusOK, agent)
+}
+
+// ListAgents handles GET /api/agents
+func (h *AgentHandlers) ListAgents(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
+		return
+	}
+
+	agents, err := h.store.ListAgents()
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to list agents", "INTERNAL_ERROR")
+		return
+	}
+	if agents == nil {
+		agents = []*store.Agent{}
+	}
+	WriteJSON(w, http.StatusOK, agents)
+}
+
+// Heartbeat handles POST /api/agents/{id}/heartbeat
+func (h *AgentHandlers) Heartbeat(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
+		return
+	}
+
+	if err := h.store.HeartbeatAgent(r.PathValue("id")); err != nil {
+		WriteError(w, http.StatusNotFound, "Agent not found", "NOT_FOUND")
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]string{"message": "Heartbeat recorded"})
+}
+
+// DeleteAgent handles DELETE /api/agents/{id}
+func (h *AgentHandlers) DeleteAgent(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
+		return
+	}
+
+	if err := h.store.DeleteAgent(r.PathValue("id")); err != nil {
+		WriteError(w, http.StatusNotFound, "Agent not found", "NOT_FOUND")
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]string{"message": "Agent deleted"})
+}
+
+// ReportResult handles POST /api/agents/{id}/runs/{runId}/result, recording the outcome
+// and output of a run the agent was dispatched. The run then finishes like a local
+// attempt: it may be retried, and its execution notifies and counts toward the job's
+// failure streak.
+func (h *AgentHandlers) ReportResult(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
+		return
+	}
+
+	agentID := r.PathValue("id")
+	run, err := h.store.GetRun(r.PathValue("runId"))
+	if err != nil || run.AgentID == nil || *run.AgentID != agentID {
+		WriteError(w, http.StatusNotFound, "Run not found for this agent", "NOT_FOUND")
+		return
+	}
+	if run.Status != internal.JobStatusRunning {
+		WriteError(w, http.StatusConflict, "Run has already finished", "INVALID_STATE")
+		return
+	}
+
+	var req AgentResultRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteBodyError(w, err, "Invalid request body", "INVALID_REQUEST")
+		return
+	}
+	switch req.Status {
+	case internal.JobStatusSuccess, internal.JobStatusFailure, internal.JobStatusTimeout, internal.JobStatusCancelled:
+	default:
+		WriteError(w, http.StatusBadRequest, "status must be success, failure, timeout or cancelled", "VALIDATION_ERROR")
+		return
+	}
+
+	if len(req.Logs) > 0 {
+		if err := h.store.AddLogs(run.ID, req.Logs); err != nil {
+			WriteError(w, http.StatusInternalServerError, "Failed to save logs", "INTERNAL_ERROR")
+			return
+		}
+	}
+
+	now := time.Now()
+	run.Status = req.Status
+	run.ExitCode = req.ExitCode
+	run.ErrorMsg = req.ErrorMessage
+	run.FinishedAt = &now
+	if run.StartedAt != nil {
+		duration := now.Sub(*run.StartedAt).Milliseconds()
+		run.DurationMs = &duration
+	}
+
+	if h.dispatcher == nil {
+		if err := h.store.UpdateRun(run); err != nil {
+			WriteError(w, http.StatusInternalServerError, "Failed to update run", "INTERNAL_ERROR")
+			return
+		}
+		WriteJSON(w, http.StatusOK, run)
+		return
+	}
+
+	if err := h.dispatcher.FinishRemote(run); err != nil {
+		if errors.Is(err, executor.ErrRunAlreadyFinished) {
+			WriteError(w, http.StatusConflict, "Run has already finished", "INVALID_STATE")
+			return
+		}
+		log.Printf("Failed to finish remote run %s: %v\n", run.ID, err)
+		WriteError(w, http.StatusInternalServerError, "Failed to update run", "INTERNAL_ERROR")
+		return
+	}
+	WriteJSON(w, http.StatusOK, run)
+}
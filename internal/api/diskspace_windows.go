@@ -0,0 +1,10 @@
+//go:build windows
+
+package api
+
+import "errors"
+
+// freeDiskBytes is not supported on Windows; the disk component reports it cannot check
+func freeDiskBytes(path string) (uint64, error) {
+	return 0, errors.New("disk space check is not supported on Windows")
+}
@@ -2,13 +2,23 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
+	"reflect"
+	"runtime"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	internal "github.com/taskflow/taskflow/internal"
 	"github.com/taskflow/taskflow/internal/auth"
+	"github.com/taskflow/taskflow/internal/executor"
 	"github.com/taskflow/taskflow/internal/notification"
 	"github.com/taskflow/taskflow/internal/scheduler"
 	"github.com/taskflow/taskflow/internal/store"
@@ -33,7 +43,7 @@ func (h *AuthHandlers) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		WriteError(w, http.StatusBadRequest, "Invalid request body", "VALIDATION_ERROR")
+		WriteBodyError(w, err, "Invalid request body", "VALIDATION_ERROR")
 		return
 	}
 
@@ -101,7 +111,7 @@ func (h *AuthHandlers) CreateFirstAdmin(w http.ResponseWriter, r *http.Request)
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		WriteError(w, http.StatusBadRequest, "Invalid request body", "VALIDATION_ERROR")
+		WriteBodyError(w, err, "Invalid request body", "VALIDATION_ERROR")
 		return
 	}
 
@@ -158,7 +168,7 @@ func (h *AuthHandlers) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		WriteError(w, http.StatusBadRequest, "Invalid request body", "VALIDATION_ERROR")
+		WriteBodyError(w, err, "Invalid request body", "VALIDATION_ERROR")
 		return
 	}
 
@@ -218,7 +228,7 @@ func (h *AuthHandlers) ChangeEmail(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&emailUpdate); err != nil {
-		WriteError(w, http.StatusBadRequest, "Invalid request body", "VALIDATION_ERROR")
+		WriteBodyError(w, err, "Invalid request body", "VALIDATION_ERROR")
 		return
 	}
 
@@ -266,6 +276,46 @@ func validateEmail(email string) string {
 	return ""
 }
 
+// UserSummary is the part of a user that assignment pickers need. It is a separate type
+// rather than store.User so new sensitive fields never leak through this endpoint.
+type UserSummary struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+}
+
+// ListUsersMinimal handles GET /api/users/minimal
+// Lists every user's id, username, email and role, sorted by username, for admin dropdowns
+// such as job ownership and notify_emails.
+func (h *AuthHandlers) ListUsersMinimal(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
+		return
+	}
+
+	users, err := h.store.ListUsers()
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to list users", "INTERNAL_ERROR")
+		return
+	}
+
+	summaries := make([]UserSummary, 0, len(users))
+	for _, user := range users {
+		summaries = append(summaries, UserSummary{
+			ID:       user.ID,
+			Username: user.Username,
+			Email:    user.Email,
+			Role:     user.Role,
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return strings.ToLower(summaries[i].Username) < strings.ToLower(summaries[j].Username)
+	})
+
+	WriteJSON(w, http.StatusOK, summaries)
+}
+
 // GetSMTPSettings handles GET /api/settings/smtp
 func (h *AuthHandlers) GetSMTPSettings(w http.ResponseWriter, r *http.Request) {
 	// Check if user is admin
@@ -313,7 +363,7 @@ func (h *AuthHandlers) UpdateSMTPSettings(w http.ResponseWriter, r *http.Request
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		WriteError(w, http.StatusBadRequest, "Invalid request body", "VALIDATION_ERROR")
+		WriteBodyError(w, err, "Invalid request body", "VALIDATION_ERROR")
 		return
 	}
 
@@ -385,6 +435,250 @@ func (h *AuthHandlers) TestSMTPSettings(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// GetChannelSettings handles GET /api/settings/notifications
+func (h *AuthHandlers) GetChannelSettings(w http.ResponseWriter, r *http.Request) {
+	// Check if user is admin
+	role := r.Header.Get("X-User-Role")
+	if role != "admin" {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
+		return
+	}
+
+	settings, err := h.store.GetChannelSettings()
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get notification settings", "INTERNAL_ERROR")
+		return
+	}
+
+	// The webhook URL and routing key both grant posting rights, so mask them like a password
+	defaultChannels := settings.DefaultChannels
+	if defaultChannels == nil {
+		defaultChannels = []string{}
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"teams_webhook_url":     maskPassword(settings.TeamsWebhookURL),
+		"pagerduty_routing_key": maskPassword(settings.PagerDutyRoutingKey),
+		"default_channels":      defaultChannels,
+	})
+}
+
+// UpdateChannelSettings handles PUT /api/settings/notifications
+func (h *AuthHandlers) UpdateChannelSettings(w http.ResponseWriter, r *http.Request) {
+	// Check if user is admin
+	role := r.Header.Get("X-User-Role")
+	if role != "admin" {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
+		return
+	}
+
+	var req store.ChannelSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteBodyError(w, err, "Invalid request body", "VALIDATION_ERROR")
+		return
+	}
+
+	for _, name := range req.DefaultChannels {
+		if !notification.IsKnownChannel(name) {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("Unknown notification channel %q (must be teams or pagerduty)", name), "VALIDATION_ERROR")
+			return
+		}
+	}
+
+	// Masked values are unchanged; keep the stored secrets
+	if req.TeamsWebhookURL == "********" || req.PagerDutyRoutingKey == "********" {
+		existing, err := h.store.GetChannelSettings()
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, "Failed to get notification settings", "INTERNAL_ERROR")
+			return
+		}
+		if req.TeamsWebhookURL == "********" {
+			req.TeamsWebhookURL = existing.TeamsWebhookURL
+		}
+		if req.PagerDutyRoutingKey == "********" {
+			req.PagerDutyRoutingKey = existing.PagerDutyRoutingKey
+		}
+	}
+
+	if req.TeamsWebhookURL != "" {
+		if u, err := url.Parse(req.TeamsWebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			WriteError(w, http.StatusBadRequest, "Teams webhook URL must be an http or https URL", "VALIDATION_ERROR")
+			return
+		}
+	}
+
+	if err := h.store.SetChannelSettings(&req); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to save notification settings", "INTERNAL_ERROR")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Notification settings updated successfully",
+	})
+}
+
+// SetMaintenanceMode handles POST /api/admin/maintenance
+func (h *AuthHandlers) SetMaintenanceMode(w http.ResponseWriter, r *http.Request) {
+	// Check if user is admin
+	role := r.Header.Get("X-User-Role")
+	if role != "admin" {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
+		return
+	}
+
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+		WriteBodyError(w, err, "Request body must include enabled (true or false)", "VALIDATION_ERROR")
+		return
+	}
+
+	if err := h.store.SetMaintenanceMode(*req.Enabled); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to update maintenance mode", "INTERNAL_ERROR")
+		return
+	}
+
+	log.Printf("Maintenance mode set to %t by user %s", *req.Enabled, r.Header.Get("X-User-ID"))
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"maintenance": *req.Enabled,
+	})
+}
+
+// ListMigrations handles GET /api/admin/migrations
+// Reports the schema version (the last applied migration) with the applied and pending migrations.
+func (h *AuthHandlers) ListMigrations(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
+		return
+	}
+
+	statuses, err := h.store.ListMigrations()
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to list migrations", "INTERNAL_ERROR")
+		return
+	}
+
+	applied := []store.MigrationStatus{}
+	pending := []string{}
+	schemaVersion := ""
+	for _, m := range statuses {
+		if m.Applied {
+			applied = append(applied, m)
+			schemaVersion = m.Name
+		} else {
+			pending = append(pending, m.Name)
+		}
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"schema_version": schemaVersion,
+		"applied":        applied,
+		"pending":        pending,
+	})
+}
+
+// ListNotificationOutbox handles GET /api/notifications/outbox
+// Lists queued notifications newest first, optionally filtered by status (pending, delivered, failed).
+func (h *AuthHandlers) ListNotificationOutbox(w http.ResponseWriter, r *http.Request) {
+	// Check if user is admin
+	role := r.Header.Get("X-User-Role")
+	if role != "admin" {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", "pending", "delivered", "failed":
+	default:
+		WriteError(w, http.StatusBadRequest, "Status must be one of: pending, delivered, failed", "VALIDATION_ERROR")
+		return
+	}
+
+	limit := internal.DefaultPageLimit
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= internal.MaxPageLimit {
+		limit = l
+	}
+
+	offset := 0
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	notifications, err := h.store.ListOutboxNotifications(status, limit, offset)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to list notifications", "INTERNAL_ERROR")
+		return
+	}
+	if notifications == nil {
+		notifications = []*store.OutboxNotification{}
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"notifications": notifications,
+		"total":         len(notifications),
+	})
+}
+
+// PreviewEmail handles POST /api/settings/email/preview
+// Renders the job notification email for a real run (run_id) or a sample job/run without sending it.
+func (h *AuthHandlers) PreviewEmail(w http.ResponseWriter, r *http.Request) {
+	// Check if user is admin
+	role := r.Header.Get("X-User-Role")
+	if role != "admin" {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
+		return
+	}
+
+	var req struct {
+		RunID string     `json:"run_id"`
+		Job   *store.Job `json:"job"`
+		Run   *store.Run `json:"run"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteBodyError(w, err, "Invalid request body", "VALIDATION_ERROR")
+		return
+	}
+
+	job, run := req.Job, req.Run
+	if req.RunID != "" {
+		var err error
+		run, err = h.store.GetRun(req.RunID)
+		if err != nil {
+			if err.Error() == "run not found" {
+				WriteError(w, http.StatusNotFound, "Run not found", "NOT_FOUND")
+				return
+			}
+			WriteError(w, http.StatusInternalServerError, "Failed to get run", "INTERNAL_ERROR")
+			return
+		}
+		job, err = h.store.GetJob(run.JobID)
+		if err != nil {
+			if err.Error() == "job not found" {
+				WriteError(w, http.StatusNotFound, "Job not found", "NOT_FOUND")
+				return
+			}
+			WriteError(w, http.StatusInternalServerError, "Failed to get job", "INTERNAL_ERROR")
+			return
+		}
+	}
+
+	if job == nil || run == nil {
+		WriteError(w, http.StatusBadRequest, "Either run_id or both job and run are required", "VALIDATION_ERROR")
+		return
+	}
+
+	subject, body := notification.PreviewJobNotification(job, run)
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"subject": subject,
+		"body":    body,
+	})
+}
+
 // maskPassword masks a password for display
 func maskPassword(password string) string {
 	if password == "" {
@@ -398,6 +692,9 @@ type JobHandlers struct {
 	store     *store.Store
 	scheduler *scheduler.Scheduler
 	validator *JobValidator
+	guard     *ScriptGuard
+	logHub    *WSHub
+	executor  *executor.Executor
 }
 
 // NewJobHandlers creates job handlers
@@ -409,6 +706,40 @@ func NewJobHandlers(st *store.Store, sched *scheduler.Scheduler) *JobHandlers {
 	}
 }
 
+// SetAllowedWorkingDirRoots restricts the working_dir of created and updated jobs to
+// a path-list-separated set of roots; empty allows any directory
+func (h *JobHandlers) SetAllowedWorkingDirRoots(roots string) {
+	h.validator.SetAllowedWorkingDirRoots(parseRoots(roots))
+}
+
+// SetMaxTimeoutSeconds lowers the timeout_seconds ceiling for created and updated jobs;
+// 0 keeps the built-in maximum
+func (h *JobHandlers) SetMaxTimeoutSeconds(max int) {
+	h.validator.SetMaxTimeoutSeconds(max)
+}
+
+// SetMinScheduleIntervalMinutes rejects schedules of created and updated jobs that fire
+// more often than every minutes minutes; 0 allows any schedule
+func (h *JobHandlers) SetMinScheduleIntervalMinutes(minutes int) {
+	h.validator.SetMinScheduleIntervalMinutes(minutes)
+}
+
+// SetLogHub sets the hub that issues stream tokens for manually triggered runs
+func (h *JobHandlers) SetLogHub(hub *WSHub) {
+	h.logHub = hub
+}
+
+// SetScriptGuard sets the safe mode scanner applied to job scripts on create and update
+func (h *JobHandlers) SetScriptGuard(guard *ScriptGuard) {
+	h.guard = guard
+}
+
+// SetExecutor sets the executor that test runs use, so they share its resource locks and
+// settings; without one each test run gets a bare executor
+func (h *JobHandlers) SetExecutor(exec *executor.Executor) {
+	h.executor = exec
+}
+
 // ListJobs handles GET /api/jobs
 func (h *JobHandlers) ListJobs(w http.ResponseWriter, r *http.Request) {
 	var createdBy *int
@@ -424,13 +755,31 @@ func (h *JobHandlers) ListJobs(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	jobs, err := h.store.ListJobs(createdBy)
+	// Optional single metadata match: ?metadata.<key>=<value>
+	var metaKey, metaValue string
+	for param, values := range r.URL.Query() {
+		key, ok := strings.CutPrefix(param, "metadata.")
+		if !ok {
+			continue
+		}
+		if metaKey != "" {
+			WriteError(w, http.StatusBadRequest, "Only one metadata filter is supported", "VALIDATION_ERROR")
+			return
+		}
+		if key == "" || !metadataKeyPattern.MatchString(key) {
+			WriteError(w, http.StatusBadRequest, "Invalid metadata filter key", "VALIDATION_ERROR")
+			return
+		}
+		metaKey, metaValue = key, values[0]
+	}
+
+	jobs, err := h.store.ListJobsByMetadata(createdBy, metaKey, metaValue)
 	if err != nil {
 		WriteError(w, http.StatusInternalServerError, "Failed to list jobs", "INTERNAL_ERROR")
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, map[string]interface{}{
+	WriteNegotiated(w, r, http.StatusOK, map[string]interface{}{
 		"jobs":  jobs,
 		"total": len(jobs),
 	})
@@ -454,11 +803,16 @@ func (h *JobHandlers) CreateJob(w http.ResponseWriter, r *http.Request) {
 
 	var req JobRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		WriteError(w, http.StatusBadRequest, "Invalid request body", "VALIDATION_ERROR")
+		WriteBodyError(w, err, "Invalid request body", "VALIDATION_ERROR")
 		return
 	}
 
-	if validErr := h.validator.ValidateJobRequest(&req); validErr != nil {
+	h.createJob(w, &req, userID)
+}
+
+// createJob validates req and creates the job and its schedule, writing the response
+func (h *JobHandlers) createJob(w http.ResponseWriter, req *JobRequest, userID int) {
+	if validErr := h.validator.ValidateJobRequest(req); validErr != nil {
 		WriteError(w, http.StatusBadRequest, validErr.Message, validErr.Code)
 		return
 	}
@@ -471,126 +825,440 @@ func (h *JobHandlers) CreateJob(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	h.validator.ApplyDefaults(&req)
+	findings := h.guard.Scan(req)
+	if len(findings) > 0 && h.guard.Rejects() {
+		WriteError(w, http.StatusBadRequest, "Script blocked by safe mode: "+describeFindings(findings), "UNSAFE_SCRIPT")
+		return
+	}
+
+	h.validator.ApplyDefaults(req)
 
-	newJob := h.validator.ToJobModel(&req, nil)
+	newJob := h.validator.ToJobModel(req, nil)
 	newJob.Enabled = true
 	newJob.CreatedBy = userID
 
-	createdJob, err := h.store.CreateJob(newJob)
+	var schedule *store.Schedule
+	if req.Schedule != nil {
+		schedule = req.Schedule.toSchedule("")
+	}
+
+	// The job and its schedule are written together, so a schedule failure leaves no job behind
+	createdJob, err := h.store.CreateJobWithSchedule(newJob, schedule)
+	if errors.Is(err, store.ErrDuplicateJobName) {
+		WriteError(w, http.StatusConflict, "A job with this name already exists", "DUPLICATE_NAME")
+		return
+	}
 	if err != nil {
 		WriteError(w, http.StatusInternalServerError, "Failed to create job", "INTERNAL_ERROR")
 		return
 	}
 
-	// Save schedule if provided
-	if req.Schedule != nil {
-		schedule := &store.Schedule{
-			JobID:    createdJob.ID,
-			Years:    req.Schedule.Years,
-			Months:   req.Schedule.Months,
-			Days:     req.Schedule.Days,
-			Weekdays: req.Schedule.Weekdays,
-			Hours:    req.Schedule.Hours,
-			Minutes:  req.Schedule.Minutes,
-		}
-		if err := h.store.SetJobSchedule(createdJob.ID, schedule); err != nil {
-			// Job was created but schedule failed - delete the job to maintain consistency
-			if delErr := h.store.DeleteJob(createdJob.ID); delErr != nil {
-				log.Printf("Failed to rollback job %s after schedule error: %v\n", createdJob.ID, delErr)
-			}
-			WriteError(w, http.StatusInternalServerError, "Failed to set schedule", "INTERNAL_ERROR")
-			return
-		}
-	}
-
-	WriteJSON(w, http.StatusCreated, createdJob)
+	WriteJSON(w, http.StatusCreated, JobCreateResponse{Job: createdJob, Warnings: findings})
 }
 
-// GetJob handles GET /api/jobs/{id}
-func (h *JobHandlers) GetJob(w http.ResponseWriter, r *http.Request) {
-	jobID := r.PathValue("id")
+// JobCreateResponse is the created job with any safe mode warnings about its scripts
+type JobCreateResponse struct {
+	*store.Job
+	Warnings []ScriptFinding `json:"warnings,omitempty"`
+}
 
-	// Validate job ID is not empty
-	if jobID == "" {
-		WriteError(w, http.StatusBadRequest, "Job ID is required", "INVALID_ID")
+// ListJobTemplates handles GET /api/job-templates
+func (h *JobHandlers) ListJobTemplates(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Only admins can manage job templates", "UNAUTHORIZED")
 		return
 	}
 
-	job, err := h.store.GetJob(jobID)
+	templates, err := h.store.ListJobTemplates()
 	if err != nil {
-		WriteError(w, http.StatusNotFound, "Job not found", "NOT_FOUND")
+		WriteError(w, http.StatusInternalServerError, "Failed to list job templates", "INTERNAL_ERROR")
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, job)
+	WriteJSON(w, http.StatusOK, templates)
 }
 
-// UpdateJob handles PUT /api/jobs/{id}
-func (h *JobHandlers) UpdateJob(w http.ResponseWriter, r *http.Request) {
-	jobID := r.PathValue("id")
-	role := r.Header.Get("X-User-Role")
+// CreateJobTemplate handles POST /api/job-templates
+func (h *JobHandlers) CreateJobTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Only admins can manage job templates", "UNAUTHORIZED")
+		return
+	}
 
-	if role != internal.RoleAdmin {
-		WriteError(w, http.StatusForbidden, "Only admins can update jobs", "UNAUTHORIZED")
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid user ID", "INVALID_ID")
 		return
 	}
 
-	var req JobRequest
+	var req JobTemplateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		WriteError(w, http.StatusBadRequest, "Invalid request body", "VALIDATION_ERROR")
+		WriteBodyError(w, err, "Invalid request body", "VALIDATION_ERROR")
 		return
 	}
 
-	if validErr := h.validator.ValidateJobRequest(&req); validErr != nil {
+	if validErr := h.validator.ValidateTemplateRequest(&req); validErr != nil {
 		WriteError(w, http.StatusBadRequest, validErr.Message, validErr.Code)
 		return
 	}
 
-	// Validate schedule if provided
-	if req.Schedule != nil {
-		if validErr := h.validator.ValidateScheduleRequest(req.Schedule); validErr != nil {
-			WriteError(w, http.StatusBadRequest, validErr.Message, validErr.Code)
-			return
-		}
+	template, err := h.store.CreateJobTemplate(&store.JobTemplate{
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Definition:  req.Definition,
+		CreatedBy:   userID,
+	})
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to create job template", "INTERNAL_ERROR")
+		return
 	}
 
-	job := h.validator.ToJobModel(&req, &jobID)
-	job.Enabled = req.Enabled
+	WriteJSON(w, http.StatusCreated, template)
+}
 
-	if err := h.store.UpdateJob(job); err != nil {
-		WriteError(w, http.StatusInternalServerError, "Failed to update job", "INTERNAL_ERROR")
+// GetJobTemplate handles GET /api/job-templates/{id}
+func (h *JobHandlers) GetJobTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Only admins can manage job templates", "UNAUTHORIZED")
 		return
 	}
 
-	// Update schedule if provided
-	if req.Schedule != nil {
-		schedule := &store.Schedule{
-			JobID:    jobID,
-			Years:    req.Schedule.Years,
-			Months:   req.Schedule.Months,
-			Days:     req.Schedule.Days,
-			Weekdays: req.Schedule.Weekdays,
-			Hours:    req.Schedule.Hours,
-			Minutes:  req.Schedule.Minutes,
-		}
-		if err := h.store.SetJobSchedule(jobID, schedule); err != nil {
-			WriteError(w, http.StatusInternalServerError, "Job updated but failed to set schedule", "INTERNAL_ERROR")
-			return
-		}
+	template, err := h.store.GetJobTemplate(r.PathValue("id"))
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "Job template not found", "NOT_FOUND")
+		return
 	}
 
-	updatedJob, _ := h.store.GetJob(jobID)
-	WriteJSON(w, http.StatusOK, updatedJob)
+	WriteJSON(w, http.StatusOK, template)
 }
 
-// DeleteJob handles DELETE /api/jobs/{id}
-func (h *JobHandlers) DeleteJob(w http.ResponseWriter, r *http.Request) {
-	jobID := r.PathValue("id")
-	role := r.Header.Get("X-User-Role")
+// UpdateJobTemplate handles PUT /api/job-templates/{id}
+// Jobs already created from the template are not changed.
+func (h *JobHandlers) UpdateJobTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Only admins can manage job templates", "UNAUTHORIZED")
+		return
+	}
 
-	if role != internal.RoleAdmin {
-		WriteError(w, http.StatusForbidden, "Only admins can delete jobs", "UNAUTHORIZED")
+	template, err := h.store.GetJobTemplate(r.PathValue("id"))
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "Job template not found", "NOT_FOUND")
+		return
+	}
+
+	var req JobTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteBodyError(w, err, "Invalid request body", "VALIDATION_ERROR")
+		return
+	}
+
+	if validErr := h.validator.ValidateTemplateRequest(&req); validErr != nil {
+		WriteError(w, http.StatusBadRequest, validErr.Message, validErr.Code)
+		return
+	}
+
+	template.Name = strings.TrimSpace(req.Name)
+	template.Description = req.Description
+	template.Definition = req.Definition
+	if err := h.store.UpdateJobTemplate(template); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to update job template", "INTERNAL_ERROR")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, template)
+}
+
+// DeleteJobTemplate handles DELETE /api/job-templates/{id}
+func (h *JobHandlers) DeleteJobTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Only admins can manage job templates", "UNAUTHORIZED")
+		return
+	}
+
+	if err := h.store.DeleteJobTemplate(r.PathValue("id")); err != nil {
+		WriteError(w, http.StatusNotFound, "Job template not found", "NOT_FOUND")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Job template deleted successfully",
+	})
+}
+
+// CreateJobFromTemplate handles POST /api/job-templates/{id}/instantiate
+// The body holds job fields that override the template's definition; fields it omits are
+// inherited. The result is validated exactly like a POST /api/jobs body.
+func (h *JobHandlers) CreateJobFromTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Only admins can create jobs", "UNAUTHORIZED")
+		return
+	}
+
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid user ID", "INVALID_ID")
+		return
+	}
+
+	template, err := h.store.GetJobTemplate(r.PathValue("id"))
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "Job template not found", "NOT_FOUND")
+		return
+	}
+
+	var req JobRequest
+	if err := json.Unmarshal(template.Definition, &req); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Job template definition is invalid", "INTERNAL_ERROR")
+		return
+	}
+
+	// Decoding the overrides on top of the template replaces only the fields they contain
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		WriteBodyError(w, err, "Invalid request body", "VALIDATION_ERROR")
+		return
+	}
+
+	h.createJob(w, &req, userID)
+}
+
+// canViewJob reports whether the requester may read job. Admins see every job, other
+// users only the jobs they created. Like ListJobs, requests without a user ID are not
+// restricted; the auth middleware always sets one.
+func canViewJob(r *http.Request, job *store.Job) bool {
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" || r.Header.Get("X-User-Role") == internal.RoleAdmin {
+		return true
+	}
+	return userID == strconv.Itoa(job.CreatedBy)
+}
+
+// getVisibleJob loads a job the requester may read. Jobs owned by someone else are
+// reported exactly like missing ones, so callers answer 404 without confirming they exist.
+func getVisibleJob(st *store.Store, r *http.Request, jobID string) (*store.Job, bool) {
+	job, err := st.GetJob(jobID)
+	if err != nil || !canViewJob(r, job) {
+		return nil, false
+	}
+	return job, true
+}
+
+// canViewRun reports whether the requester may read run, which follows its job's
+// visibility. Only admins can see runs of deleted jobs, whose owner is unknown.
+func canViewRun(st *store.Store, r *http.Request, run *store.Run) bool {
+	if r.Header.Get("X-User-ID") == "" || r.Header.Get("X-User-Role") == internal.RoleAdmin {
+		return true
+	}
+	_, ok := getVisibleJob(st, r, run.JobID)
+	return ok
+}
+
+// getVisibleRun loads a run the requester may read, hiding others' runs like getVisibleJob
+func getVisibleRun(st *store.Store, r *http.Request, runID string) (*store.Run, bool) {
+	run, err := st.GetRun(runID)
+	if err != nil || !canViewRun(st, r, run) {
+		return nil, false
+	}
+	return run, true
+}
+
+// GetJob handles GET /api/jobs/{id}
+func (h *JobHandlers) GetJob(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+
+	// Validate job ID is not empty
+	if jobID == "" {
+		WriteError(w, http.StatusBadRequest, "Job ID is required", "INVALID_ID")
+		return
+	}
+
+	job, ok := getVisibleJob(h.store, r, jobID)
+	if !ok {
+		WriteError(w, http.StatusNotFound, "Job not found", "NOT_FOUND")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, job)
+}
+
+// JobDetail bundles a job with its schedule, recent runs, stats and next run time
+type JobDetail struct {
+	Job        *store.Job      `json:"job"`
+	Schedule   *store.Schedule `json:"schedule"`
+	RecentRuns []*store.Run    `json:"recent_runs"`
+	Stats      *store.JobStats `json:"stats"`
+	NextRunAt  *time.Time      `json:"next_run_at"`
+}
+
+// GetJobDetail handles GET /api/jobs/{id}/detail
+func (h *JobHandlers) GetJobDetail(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+
+	if jobID == "" {
+		WriteError(w, http.StatusBadRequest, "Job ID is required", "INVALID_ID")
+		return
+	}
+
+	job, ok := getVisibleJob(h.store, r, jobID)
+	if !ok {
+		WriteError(w, http.StatusNotFound, "Job not found", "NOT_FOUND")
+		return
+	}
+
+	schedule, err := h.store.GetJobSchedule(jobID)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get schedule", "INTERNAL_ERROR")
+		return
+	}
+
+	runs, err := h.store.ListRuns(store.RunFilter{JobID: jobID}, internal.JobDetailRecentRuns, 0)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to list runs", "INTERNAL_ERROR")
+		return
+	}
+	if runs == nil {
+		runs = []*store.Run{}
+	}
+
+	stats, err := h.store.GetJobStatsForJob(jobID)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get job stats", "INTERNAL_ERROR")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, JobDetail{
+		Job:        job,
+		Schedule:   schedule,
+		RecentRuns: runs,
+		Stats:      stats,
+		NextRunAt:  scheduler.NextRunTime(job, schedule, time.Now()),
+	})
+}
+
+// UpdateJob handles PUT /api/jobs/{id}
+func (h *JobHandlers) UpdateJob(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	role := r.Header.Get("X-User-Role")
+
+	if role != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Only admins can update jobs", "UNAUTHORIZED")
+		return
+	}
+
+	var req JobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteBodyError(w, err, "Invalid request body", "VALIDATION_ERROR")
+		return
+	}
+
+	if validErr := h.validator.ValidateJobRequest(&req); validErr != nil {
+		WriteError(w, http.StatusBadRequest, validErr.Message, validErr.Code)
+		return
+	}
+
+	// Validate schedule if provided
+	if req.Schedule != nil {
+		if validErr := h.validator.ValidateScheduleRequest(req.Schedule); validErr != nil {
+			WriteError(w, http.StatusBadRequest, validErr.Message, validErr.Code)
+			return
+		}
+	}
+
+	findings := h.guard.Scan(&req)
+	if len(findings) > 0 && h.guard.Rejects() {
+		WriteError(w, http.StatusBadRequest, "Script blocked by safe mode: "+describeFindings(findings), "UNSAFE_SCRIPT")
+		return
+	}
+
+	existing, err := h.store.GetJob(jobID)
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "Job not found", "NOT_FOUND")
+		return
+	}
+
+	job := h.validator.ToJobModel(&req, &jobID)
+	job.Enabled = req.Enabled
+	changes := diffJobs(existing, job)
+
+	if err := h.store.UpdateJob(job); err != nil {
+		if errors.Is(err, store.ErrDuplicateJobName) {
+			WriteError(w, http.StatusConflict, "A job with this name already exists", "DUPLICATE_NAME")
+			return
+		}
+		WriteError(w, http.StatusInternalServerError, "Failed to update job", "INTERNAL_ERROR")
+		return
+	}
+
+	// Update schedule if provided
+	if req.Schedule != nil {
+		schedule := req.Schedule.toSchedule(jobID)
+		if err := h.store.SetJobSchedule(jobID, schedule); err != nil {
+			WriteError(w, http.StatusInternalServerError, "Job updated but failed to set schedule", "INTERNAL_ERROR")
+			return
+		}
+	}
+
+	updatedJob, _ := h.store.GetJob(jobID)
+	WriteJSON(w, http.StatusOK, JobUpdateResponse{Job: updatedJob, Changes: changes, Warnings: findings})
+}
+
+// FieldChange describes one job field modified by an update
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// JobUpdateResponse is the updated job with the list of fields the update changed
+type JobUpdateResponse struct {
+	*store.Job
+	Changes  []FieldChange   `json:"changes"`
+	Warnings []ScriptFinding `json:"warnings,omitempty"`
+}
+
+// nonEditableJobFields are job fields an update request never sets, so they are left out of diffs
+var nonEditableJobFields = map[string]bool{
+	"id":                   true,
+	"script_external":      true,
+	"schedule_enabled":     true,
+	"consecutive_failures": true,
+	"schedule_paused":      true,
+	"created_by":           true,
+	"created_at":           true,
+	"updated_at":           true,
+}
+
+// diffJobs lists the editable fields whose values differ between old and updated, keyed by
+// their JSON names. Empty and nil slices or maps are treated as equal.
+func diffJobs(old, updated *store.Job) []FieldChange {
+	changes := make([]FieldChange, 0)
+	oldVal, newVal := reflect.ValueOf(old).Elem(), reflect.ValueOf(updated).Elem()
+	jobType := oldVal.Type()
+
+	for i := 0; i < jobType.NumField(); i++ {
+		name, _, _ := strings.Cut(jobType.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" || nonEditableJobFields[name] {
+			continue
+		}
+
+		a, b := oldVal.Field(i), newVal.Field(i)
+		if (a.Kind() == reflect.Slice || a.Kind() == reflect.Map) && a.Len() == 0 && b.Len() == 0 {
+			continue
+		}
+		if reflect.DeepEqual(a.Interface(), b.Interface()) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: name, Old: a.Interface(), New: b.Interface()})
+	}
+	return changes
+}
+
+// DeleteJob handles DELETE /api/jobs/{id}
+func (h *JobHandlers) DeleteJob(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	role := r.Header.Get("X-User-Role")
+
+	if role != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Only admins can delete jobs", "UNAUTHORIZED")
 		return
 	}
 
@@ -599,38 +1267,452 @@ func (h *JobHandlers) DeleteJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"message": "Job deleted successfully",
-	})
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Job deleted successfully",
+	})
+}
+
+// TriggerResponse is the run created by a manual trigger, with a stream token that
+// subscribes to its logs from the first line
+type TriggerResponse struct {
+	*store.Run
+	StreamToken string `json:"stream_token,omitempty"`
+}
+
+// TriggerJob handles POST /api/jobs/{id}/run
+// An optional {"tags": [...]} body labels the run for filtering with GET /api/runs?tag=.
+// Pass the returned stream_token to the run's logs WebSocket to receive every line, even
+// those written before the connection opens.
+func (h *JobHandlers) TriggerJob(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+
+	// Verify job exists
+	job, err := h.store.GetJob(jobID)
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "Job not found", "NOT_FOUND")
+		return
+	}
+
+	if !job.Enabled {
+		WriteError(w, http.StatusBadRequest, "Job is not enabled", "INVALID_STATE")
+		return
+	}
+
+	// The body is optional; it only carries tags for the new run
+	var req TriggerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		WriteBodyError(w, err, "Invalid request body", "VALIDATION_ERROR")
+		return
+	}
+	if validErr := h.validator.ValidateTriggerRequest(&req); validErr != nil {
+		WriteError(w, http.StatusBadRequest, validErr.Message, validErr.Code)
+		return
+	}
+
+	// Refuse a manual trigger that follows the previous one too closely, e.g. a double click
+	recent, err := h.store.RecentManualRun(jobID)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to check recent runs", "INTERNAL_ERROR")
+		return
+	}
+	if recent != nil {
+		wait := time.Until(recent.CreatedAt.Add(h.store.ManualTriggerInterval()))
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		WriteJSON(w, http.StatusTooManyRequests, Response{
+			Data:  recent,
+			Error: "This job was triggered manually moments ago",
+			Code:  "TRIGGER_TOO_SOON",
+		})
+		return
+	}
+
+	// Create a run with manual trigger type, held for approval if the job requires it
+	var triggeredBy *int
+	if userID, err := strconv.Atoi(r.Header.Get("X-User-ID")); err == nil {
+		triggeredBy = &userID
+	}
+	if job.RequiresApproval {
+		run, err := h.store.CreateManualRun(job, triggeredBy, req.Tags)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, "Failed to create run", "INTERNAL_ERROR")
+			return
+		}
+		log.Printf("Run %s of job %s is awaiting approval, triggered by user %s", run.ID, job.ID, r.Header.Get("X-User-ID"))
+		WriteJSON(w, http.StatusAccepted, run)
+		return
+	}
+
+	if !h.applyManualWhileScheduled(w, job) {
+		return
+	}
+
+	run, err := h.store.CreateManualRun(job, triggeredBy, req.Tags)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to create run", "INTERNAL_ERROR")
+		return
+	}
+
+	// Start holding the run's messages before it can start, so no line precedes the token
+	resp := TriggerResponse{Run: run}
+	if h.logHub != nil {
+		resp.StreamToken = h.logHub.IssueStreamToken(run.ID)
+	}
+
+	// Enqueue the job with the run to maintain sequential execution
+	if err := h.scheduler.EnqueueWithRun(job, run); err != nil {
+		h.store.CancelPendingRun(run.ID, "Rejected: the job queue was full")
+		WriteError(w, http.StatusServiceUnavailable, "The job queue is full, try again later", "QUEUE_FULL")
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, resp)
+}
+
+// applyManualWhileScheduled applies the job's manual_while_scheduled policy to its
+// scheduled runs still queued or running before a manual run is queued. It reports false
+// after writing an error response if the manual run must not go ahead.
+func (h *JobHandlers) applyManualWhileScheduled(w http.ResponseWriter, job *store.Job) bool {
+	if job.ManualWhileScheduled != internal.ManualWhileScheduledSkip && job.ManualWhileScheduled != internal.ManualWhileScheduledReplace {
+		return true
+	}
+
+	active, err := h.store.ListActiveRuns(job.ID, internal.TriggerScheduled)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to check active runs", "INTERNAL_ERROR")
+		return false
+	}
+	if len(active) > 0 && job.ManualWhileScheduled == internal.ManualWhileScheduledSkip {
+		WriteError(w, http.StatusConflict, "A scheduled run of this job is already pending or running", "RUN_ACTIVE")
+		return false
+	}
+	// replace: a running scheduled run cannot be stopped, so the manual run queues behind it
+	for _, scheduled := range active {
+		if _, err := h.store.CancelPendingRun(scheduled.ID, "Replaced by a manual trigger"); err != nil {
+			WriteError(w, http.StatusInternalServerError, "Failed to cancel scheduled run", "INTERNAL_ERROR")
+			return false
+		}
+	}
+	return true
+}
+
+// ApproveRun handles POST /api/runs/{id}/approve
+// Queues a manual run of a requires_approval job. The approver must be an admin other
+// than the user who triggered it.
+func (h *JobHandlers) ApproveRun(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
+		return
+	}
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid user ID", "INVALID_ID")
+		return
+	}
+
+	run, err := h.store.GetRun(r.PathValue("id"))
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "Run not found", "NOT_FOUND")
+		return
+	}
+	if run.Status != internal.JobStatusAwaitingApproval {
+		WriteError(w, http.StatusConflict, "Run is not awaiting approval", "INVALID_STATE")
+		return
+	}
+	if run.TriggeredBy != nil && *run.TriggeredBy == userID {
+		WriteError(w, http.StatusForbidden, "A run must be approved by someone other than who triggered it", "SELF_APPROVAL")
+		return
+	}
+
+	job, err := h.store.GetJob(run.JobID)
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "Job not found", "NOT_FOUND")
+		return
+	}
+	if !job.Enabled {
+		WriteError(w, http.StatusBadRequest, "Job is not enabled", "INVALID_STATE")
+		return
+	}
+	if !h.applyManualWhileScheduled(w, job) {
+		return
+	}
+
+	if err := h.store.ApproveRun(run.ID, userID); err != nil {
+		if errors.Is(err, store.ErrRunNotAwaitingApproval) {
+			WriteError(w, http.StatusConflict, "Run is not awaiting approval", "INVALID_STATE")
+			return
+		}
+		WriteError(w, http.StatusInternalServerError, "Failed to approve run", "INTERNAL_ERROR")
+		return
+	}
+	run, err = h.store.GetRun(run.ID)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get run", "INTERNAL_ERROR")
+		return
+	}
+
+	if err := h.scheduler.EnqueueWithRun(job, run); err != nil {
+		h.store.CancelPendingRun(run.ID, "Rejected: the job queue was full")
+		WriteError(w, http.StatusServiceUnavailable, "The job queue is full, try again later", "QUEUE_FULL")
+		return
+	}
+
+	log.Printf("Run %s of job %s approved by user %d", run.ID, job.ID, userID)
+	WriteJSON(w, http.StatusOK, run)
+}
+
+// RejectRun handles POST /api/runs/{id}/reject
+// Cancels a manual run awaiting approval; any admin, including the one who triggered
+// it, may reject it.
+func (h *JobHandlers) RejectRun(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
+		return
+	}
+
+	run, err := h.store.GetRun(r.PathValue("id"))
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "Run not found", "NOT_FOUND")
+		return
+	}
+
+	reason := "Rejected by user " + r.Header.Get("X-User-ID")
+	if err := h.store.RejectRun(run.ID, reason); err != nil {
+		if errors.Is(err, store.ErrRunNotAwaitingApproval) {
+			WriteError(w, http.StatusConflict, "Run is not awaiting approval", "INVALID_STATE")
+			return
+		}
+		WriteError(w, http.StatusInternalServerError, "Failed to reject run", "INTERNAL_ERROR")
+		return
+	}
+
+	run, err = h.store.GetRun(run.ID)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get run", "INTERNAL_ERROR")
+		return
+	}
+	WriteJSON(w, http.StatusOK, run)
+}
+
+// TestRunResponse is the result of a test run: the finished run and everything it logged
+type TestRunResponse struct {
+	Run  *store.Run        `json:"run"`
+	Logs []*store.LogEntry `json:"logs"`
+}
+
+// TestJob handles POST /api/jobs/{id}/test
+//
+// Runs the job's script synchronously in a scratch directory that is removed afterwards,
+// so it never touches the real working_dir, and returns the output and exit code. The run
+// is recorded with trigger_type "test": it does not count toward analytics, the failure
+// streak or pause_on_failure, and sends no notification. Disabled jobs can be tested.
+func (h *JobHandlers) TestJob(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Only admins can test jobs", "UNAUTHORIZED")
+		return
+	}
+
+	job, err := h.store.GetJob(r.PathValue("id"))
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "Job not found", "NOT_FOUND")
+		return
+	}
+
+	// A test run executes the real script, so it must not sidestep the second admin
+	if job.RequiresApproval {
+		WriteError(w, http.StatusConflict, "Job requires approval; trigger it and have another admin approve the run", "APPROVAL_REQUIRED")
+		return
+	}
+
+	run, err := h.store.CreateRun(job.ID, internal.TriggerTest)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to create run", "INTERNAL_ERROR")
+		return
+	}
+
+	exec := h.executor
+	if exec == nil {
+		exec = executor.New(h.store)
+	}
+	// Runs outside the job queue; the request context cancels the run if the client goes away
+	if err := exec.ExecuteTest(r.Context(), run, job); err != nil {
+		log.Printf("Test run %s of job %s failed to execute: %v", run.ID, job.ID, err)
+	}
+
+	logs, err := h.store.GetLogs(run.ID)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get logs", "INTERNAL_ERROR")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, TestRunResponse{Run: run, Logs: logs})
 }
 
-// TriggerJob handles POST /api/jobs/{id}/run
-func (h *JobHandlers) TriggerJob(w http.ResponseWriter, r *http.Request) {
-	jobID := r.PathValue("id")
+// RequeueRun handles POST /api/runs/{id}/requeue
+//
+// A run that was created but never picked up (full queue, transient error)
+// stays pending forever. This pushes its job back onto the queue with the
+// existing run so it executes under the same run ID.
+func (h *JobHandlers) RequeueRun(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Only admins can requeue runs", "UNAUTHORIZED")
+		return
+	}
 
-	// Verify job exists
-	job, err := h.store.GetJob(jobID)
+	run, err := h.store.GetRun(r.PathValue("id"))
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "Run not found", "NOT_FOUND")
+		return
+	}
+
+	if run.Status != internal.JobStatusPending {
+		WriteError(w, http.StatusBadRequest, "Only pending runs can be requeued", "INVALID_STATE")
+		return
+	}
+
+	job, err := h.store.GetJob(run.JobID)
 	if err != nil {
 		WriteError(w, http.StatusNotFound, "Job not found", "NOT_FOUND")
 		return
 	}
 
-	if !job.Enabled {
-		WriteError(w, http.StatusBadRequest, "Job is not enabled", "INVALID_STATE")
+	// The run stays pending if the queue is full, so it can be requeued again later
+	if err := h.scheduler.EnqueueWithRun(job, run); err != nil {
+		if errors.Is(err, scheduler.ErrAlreadyQueued) {
+			WriteError(w, http.StatusConflict, "Run is already waiting in the queue", "ALREADY_QUEUED")
+			return
+		}
+		WriteError(w, http.StatusServiceUnavailable, "The job queue is full, try again later", "QUEUE_FULL")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, run)
+}
+
+// MoveRunToFront handles POST /api/queue/{runId}/move-to-front
+// Makes a pending, queued run the next one executed, e.g. to unblock an incident fix.
+func (h *JobHandlers) MoveRunToFront(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
 		return
 	}
 
-	// Create a run with manual trigger type
-	run, err := h.store.CreateRun(jobID, "manual")
+	run, err := h.store.GetRun(r.PathValue("runId"))
 	if err != nil {
-		WriteError(w, http.StatusInternalServerError, "Failed to create run", "INTERNAL_ERROR")
+		WriteError(w, http.StatusNotFound, "Run not found", "NOT_FOUND")
 		return
 	}
 
-	// Enqueue the job with the run to maintain sequential execution
-	h.scheduler.EnqueueWithRun(job, run)
+	if run.Status != internal.JobStatusPending {
+		WriteError(w, http.StatusConflict, "Only pending runs can be reordered", "INVALID_STATE")
+		return
+	}
+
+	if err := h.scheduler.MoveToFront(run.ID); err != nil {
+		WriteError(w, http.StatusConflict, "Run is pending but not waiting in the queue; requeue it first", "NOT_QUEUED")
+		return
+	}
+
+	log.Printf("Run %s moved to the front of the queue by user %s", run.ID, r.Header.Get("X-User-ID"))
+	WriteJSON(w, http.StatusOK, run)
+}
+
+// Backup handles GET /api/admin/backup
+// Returns jobs, schedules, users (without password hashes) and settings; secret settings
+// such as the SMTP password are only included with ?include_secrets=true.
+func (h *JobHandlers) Backup(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Only admins can back up the configuration", "UNAUTHORIZED")
+		return
+	}
+
+	backup, err := h.store.CreateBackup(r.URL.Query().Get("include_secrets") == "true")
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to create backup", "INTERNAL_ERROR")
+		return
+	}
 
-	WriteJSON(w, http.StatusCreated, run)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="taskflow-backup-%s.json"`, backup.CreatedAt.UTC().Format("20060102-150405")))
+	WriteJSON(w, http.StatusOK, backup)
+}
+
+// Restore handles POST /api/admin/restore
+// Accepts a backup document, either bare or still wrapped in the backup response's "data"
+// envelope, and applies it in one transaction. ?dry_run=true validates and reports without
+// changing anything.
+func (h *JobHandlers) Restore(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Only admins can restore the configuration", "UNAUTHORIZED")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		WriteBodyError(w, err, "Failed to read request body", "VALIDATION_ERROR")
+		return
+	}
+	var envelope struct {
+		Data *store.Backup `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid request body", "VALIDATION_ERROR")
+		return
+	}
+	backup := envelope.Data
+	if backup == nil {
+		backup = &store.Backup{}
+		if err := json.Unmarshal(body, backup); err != nil {
+			WriteError(w, http.StatusBadRequest, "Invalid request body", "VALIDATION_ERROR")
+			return
+		}
+	}
+
+	if err := store.ValidateBackup(backup); err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+		return
+	}
+	if validErr := h.validateBackupJobs(backup); validErr != nil {
+		WriteError(w, http.StatusBadRequest, validErr.Message, validErr.Code)
+		return
+	}
+
+	result, err := h.store.RestoreBackup(backup, r.URL.Query().Get("dry_run") == "true")
+	if errors.Is(err, store.ErrDuplicateJobName) {
+		WriteError(w, http.StatusConflict, "A restored job name is already used by another job", "DUPLICATE_NAME")
+		return
+	}
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to restore backup", "INTERNAL_ERROR")
+		return
+	}
+
+	if !result.DryRun {
+		log.Printf("Restored backup from %s: %d jobs, %d users created, %d settings\n",
+			backup.CreatedAt.Format(time.RFC3339), result.Jobs, len(result.UsersCreated), result.Settings)
+	}
+	WriteJSON(w, http.StatusOK, result)
+}
+
+// validateBackupJobs runs every job and schedule in a backup through the same checks as
+// one saved through the API, including safe mode, so a restore cannot plant a job the API
+// would refuse. A restored one-shot loses its fire history, so a past run_at is refused
+// rather than fired on the next tick.
+func (h *JobHandlers) validateBackupJobs(backup *store.Backup) *ValidationError {
+	for _, entry := range backup.Jobs {
+		req := jobRequestFromModel(&entry.Job, entry.Schedule)
+		validErr := h.validator.ValidateJobRequest(req)
+		if validErr == nil && req.Schedule != nil {
+			validErr = h.validator.ValidateScheduleRequest(req.Schedule)
+		}
+		if validErr == nil {
+			if findings := h.guard.Scan(req); len(findings) > 0 && h.guard.Rejects() {
+				validErr = &ValidationError{Message: "Script blocked by safe mode: " + describeFindings(findings), Code: "UNSAFE_SCRIPT"}
+			}
+		}
+		if validErr != nil {
+			return &ValidationError{Message: fmt.Sprintf("Job %q: %s", entry.Name, validErr.Message), Code: validErr.Code}
+		}
+	}
+	return nil
 }
 
 // RunHandlers handles run endpoints
@@ -644,8 +1726,16 @@ func NewRunHandlers(st *store.Store) *RunHandlers {
 }
 
 // ListRuns handles GET /api/runs
+//
+// Cursor pagination is preferred: pass after_id (empty for the first page) and
+// follow next_cursor from each response. Pages stay stable while new runs are
+// inserted. Offset pagination (offset=N) is kept for backward compatibility.
+// tag=<name> limits either mode to runs carrying that trigger-time tag, and
+// acknowledged=false to runs nobody has acknowledged yet. Retries are collapsed into
+// their first attempt unless include_attempts=true.
 func (h *RunHandlers) ListRuns(w http.ResponseWriter, r *http.Request) {
 	jobID := r.URL.Query().Get("job_id")
+	tag := r.URL.Query().Get("tag")
 	limitStr := r.URL.Query().Get("limit")
 	offsetStr := r.URL.Query().Get("offset")
 
@@ -659,23 +1749,89 @@ func (h *RunHandlers) ListRuns(w http.ResponseWriter, r *http.Request) {
 		offset = o
 	}
 
-	var jobIDPtr *string
-	if jobID != "" {
-		jobIDPtr = &jobID
+	filter := store.RunFilter{JobID: jobID, Tag: tag, FirstAttempt: true}
+	if includeStr := r.URL.Query().Get("include_attempts"); includeStr != "" {
+		include, err := strconv.ParseBool(includeStr)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, "include_attempts must be true or false", "VALIDATION_ERROR")
+			return
+		}
+		filter.FirstAttempt = !include
+	}
+	if ackStr := r.URL.Query().Get("acknowledged"); ackStr != "" {
+		acknowledged, err := strconv.ParseBool(ackStr)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, "acknowledged must be true or false", "VALIDATION_ERROR")
+			return
+		}
+		filter.Acknowledged = &acknowledged
+	}
+
+	if r.URL.Query().Has("after_id") {
+		runs, err := h.store.ListRunsAfter(filter, r.URL.Query().Get("after_id"), limit)
+		if err != nil {
+			if err.Error() == "run not found" {
+				WriteError(w, http.StatusBadRequest, "Invalid cursor: run not found", "VALIDATION_ERROR")
+				return
+			}
+			WriteError(w, http.StatusInternalServerError, "Failed to list runs", "INTERNAL_ERROR")
+			return
+		}
+
+		total, err := h.store.CountRuns(filter)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, "Failed to count runs", "INTERNAL_ERROR")
+			return
+		}
+
+		nextCursor := ""
+		if len(runs) == limit {
+			nextCursor = runs[len(runs)-1].ID
+		}
+
+		WriteNegotiated(w, r, http.StatusOK, map[string]interface{}{
+			"runs":        runs,
+			"total":       total,
+			"next_cursor": nextCursor,
+		})
+		return
 	}
 
-	runs, err := h.store.ListRuns(jobIDPtr, limit, offset)
+	runs, err := h.store.ListRuns(filter, limit, offset)
 	if err != nil {
 		WriteError(w, http.StatusInternalServerError, "Failed to list runs", "INTERNAL_ERROR")
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, map[string]interface{}{
+	WriteNegotiated(w, r, http.StatusOK, map[string]interface{}{
 		"runs":  runs,
 		"total": len(runs),
 	})
 }
 
+// ListActiveRuns handles GET /api/runs/active
+// Returns every pending and running run with its job name and elapsed time; non-admins
+// only see runs of their own jobs.
+func (h *RunHandlers) ListActiveRuns(w http.ResponseWriter, r *http.Request) {
+	active, err := h.store.ListRunningRuns()
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to list active runs", "INTERNAL_ERROR")
+		return
+	}
+
+	visible := make([]*store.ActiveRun, 0, len(active))
+	for _, run := range active {
+		if canViewRun(h.store, r, run.Run) {
+			visible = append(visible, run)
+		}
+	}
+
+	WriteNegotiated(w, r, http.StatusOK, map[string]interface{}{
+		"runs":  visible,
+		"total": len(visible),
+	})
+}
+
 // GetRun handles GET /api/runs/{id}
 func (h *RunHandlers) GetRun(w http.ResponseWriter, r *http.Request) {
 	runID := r.PathValue("id")
@@ -686,19 +1842,90 @@ func (h *RunHandlers) GetRun(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	run, err := h.store.GetRun(runID)
+	run, ok := getVisibleRun(h.store, r, runID)
+	if !ok {
+		WriteError(w, http.StatusNotFound, "Run not found", "NOT_FOUND")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, run)
+}
+
+// ListRunAttempts handles GET /api/runs/{id}/attempts
+// Returns every attempt of the run's execution, first attempt first; the ID may be any of them.
+func (h *RunHandlers) ListRunAttempts(w http.ResponseWriter, r *http.Request) {
+	run, ok := getVisibleRun(h.store, r, r.PathValue("id"))
+	if !ok {
+		WriteError(w, http.StatusNotFound, "Run not found", "NOT_FOUND")
+		return
+	}
+
+	attempts, err := h.store.ListRunAttempts(run.ID)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to list run attempts", "INTERNAL_ERROR")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"attempts": attempts,
+		"total":    len(attempts),
+	})
+}
+
+// AcknowledgeRun handles POST /api/runs/{id}/ack
+// Marks a finished run as taken care of by the current user so others on call know it is
+// being handled. A run can only be acknowledged once.
+func (h *RunHandlers) AcknowledgeRun(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
 	if err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid user ID", "INVALID_ID")
+		return
+	}
+
+	run, ok := getVisibleRun(h.store, r, r.PathValue("id"))
+	if !ok {
 		WriteError(w, http.StatusNotFound, "Run not found", "NOT_FOUND")
 		return
 	}
 
+	if run.Status == internal.JobStatusPending || run.Status == internal.JobStatusRunning {
+		WriteError(w, http.StatusBadRequest, "Only finished runs can be acknowledged", "INVALID_STATE")
+		return
+	}
+
+	if err := h.store.AcknowledgeRun(run.ID, userID); err != nil {
+		if errors.Is(err, store.ErrRunAlreadyAcknowledged) {
+			// Include the run so the caller sees who is already on it
+			current, _ := h.store.GetRun(run.ID)
+			WriteJSON(w, http.StatusConflict, Response{
+				Data:  current,
+				Error: "Run has already been acknowledged",
+				Code:  "ALREADY_ACKNOWLEDGED",
+			})
+			return
+		}
+		WriteError(w, http.StatusInternalServerError, "Failed to acknowledge run", "INTERNAL_ERROR")
+		return
+	}
+
+	run, err = h.store.GetRun(run.ID)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get run", "INTERNAL_ERROR")
+		return
+	}
 	WriteJSON(w, http.StatusOK, run)
 }
 
 // GetRunLogs handles GET /api/runs/{id}/logs
+// Supports limit/offset pagination and a comma-separated level filter.
 func (h *RunHandlers) GetRunLogs(w http.ResponseWriter, r *http.Request) {
 	runID := r.PathValue("id")
 
+	if _, ok := getVisibleRun(h.store, r, runID); !ok {
+		WriteError(w, http.StatusNotFound, "Run not found", "NOT_FOUND")
+		return
+	}
+
 	// Parse pagination params
 	limit := 0
 	offset := 0
@@ -707,46 +1934,257 @@ func (h *RunHandlers) GetRunLogs(w http.ResponseWriter, r *http.Request) {
 			limit = parsed
 		}
 	}
-	if o := r.URL.Query().Get("offset"); o != "" {
-		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
-			offset = parsed
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	// ?level=error,warn keeps only lines tagged with those levels; total counts the matches
+	if param := r.URL.Query().Get("level"); param != "" {
+		var levels []string
+		for _, level := range strings.Split(param, ",") {
+			if level = store.NormalizeLogLevel(level); level != "" {
+				levels = append(levels, level)
+			}
+		}
+		if len(levels) == 0 {
+			WriteError(w, http.StatusBadRequest, "level must name at least one level", "VALIDATION_ERROR")
+			return
+		}
+
+		logs, total, err := h.store.GetLogsByLevel(runID, levels, limit, offset)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, "Failed to get logs", "INTERNAL_ERROR")
+			return
+		}
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"logs":   logs,
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+			"levels": levels,
+		})
+		return
+	}
+
+	total, err := h.store.GetLogCount(runID)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to count logs", "INTERNAL_ERROR")
+		return
+	}
+
+	logs, err := h.store.GetLogsPaginated(runID, limit, offset)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get logs", "INTERNAL_ERROR")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"logs":   logs,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetRunLogsTail handles GET /api/runs/{id}/logs/tail?n=50
+// Returns the last n log lines of a run in chronological order without loading the whole log.
+func (h *RunHandlers) GetRunLogsTail(w http.ResponseWriter, r *http.Request) {
+	runID := r.PathValue("id")
+
+	if _, ok := getVisibleRun(h.store, r, runID); !ok {
+		WriteError(w, http.StatusNotFound, "Run not found", "NOT_FOUND")
+		return
+	}
+
+	n := internal.DefaultLogTailLines
+	if raw := r.URL.Query().Get("n"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > internal.MaxLogTailLines {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("n must be between 1 and %d", internal.MaxLogTailLines), "VALIDATION_ERROR")
+			return
+		}
+		n = parsed
+	}
+
+	logs, err := h.store.GetLogsTail(runID, n)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get logs", "INTERNAL_ERROR")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"logs": logs,
+		"n":    n,
+	})
+}
+
+// RunReport is a self-contained export of one run for archiving, e.g. for postmortems
+type RunReport struct {
+	GeneratedAt time.Time         `json:"generated_at"`
+	Run         *store.Run        `json:"run"`
+	Job         *store.Job        `json:"job"`      // job definition at export time; nil if the job was deleted
+	Schedule    *store.Schedule   `json:"schedule"` // nil if the job was deleted
+	Logs        []*store.LogEntry `json:"logs"`
+	Metrics     []*store.Metric   `json:"metrics"`
+	Stats       RunReportStats    `json:"stats"`
+	JobStats    *store.JobStats   `json:"job_stats"` // aggregate over all of the job's runs
+}
+
+// RunReportStats summarizes a run's logs and resource metrics
+type RunReportStats struct {
+	LogLines        int            `json:"log_lines"`
+	LinesByStream   map[string]int `json:"lines_by_stream"`
+	MetricSamples   int            `json:"metric_samples"`
+	PeakCPUPercent  float64        `json:"peak_cpu_percent"`
+	PeakMemoryBytes int64          `json:"peak_memory_bytes"`
+}
+
+// GetRunReport handles GET /api/runs/{id}/report
+// Returns the run, its job, all logs, metrics and computed stats as one JSON download.
+func (h *RunHandlers) GetRunReport(w http.ResponseWriter, r *http.Request) {
+	runID := r.PathValue("id")
+
+	if runID == "" {
+		WriteError(w, http.StatusBadRequest, "Run ID is required", "INVALID_ID")
+		return
+	}
+
+	run, ok := getVisibleRun(h.store, r, runID)
+	if !ok {
+		WriteError(w, http.StatusNotFound, "Run not found", "NOT_FOUND")
+		return
+	}
+
+	logs, err := h.store.GetLogs(runID)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get logs", "INTERNAL_ERROR")
+		return
+	}
+
+	metrics, err := h.store.GetMetrics(runID)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get metrics", "INTERNAL_ERROR")
+		return
+	}
+	if metrics == nil {
+		metrics = []*store.Metric{}
+	}
+
+	report := RunReport{
+		GeneratedAt: time.Now().UTC(),
+		Run:         run,
+		Logs:        logs,
+		Metrics:     metrics,
+		Stats:       RunReportStats{LogLines: len(logs), LinesByStream: map[string]int{}, MetricSamples: len(metrics)},
+	}
+	for _, entry := range logs {
+		report.Stats.LinesByStream[entry.Stream]++
+	}
+	for _, m := range metrics {
+		report.Stats.PeakCPUPercent = max(report.Stats.PeakCPUPercent, m.CPUPercent)
+		report.Stats.PeakMemoryBytes = max(report.Stats.PeakMemoryBytes, m.MemoryBytes)
+	}
+
+	// The job may have been deleted since the run; the report still covers the run itself
+	if job, err := h.store.GetJob(run.JobID); err == nil {
+		report.Job = job
+		if report.Schedule, err = h.store.GetJobSchedule(job.ID); err != nil {
+			WriteError(w, http.StatusInternalServerError, "Failed to get schedule", "INTERNAL_ERROR")
+			return
+		}
+		if report.JobStats, err = h.store.GetJobStatsForJob(job.ID); err != nil {
+			WriteError(w, http.StatusInternalServerError, "Failed to get job stats", "INTERNAL_ERROR")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="run-%s-report.json"`, run.ID))
+	WriteJSON(w, http.StatusOK, report)
+}
+
+// GetBatchRunLogs handles GET /api/runs/logs?ids=a,b,c
+// Returns logs keyed by run ID; unknown run IDs, and for non-admins runs of other users'
+// jobs, are listed under "missing".
+func (h *RunHandlers) GetBatchRunLogs(w http.ResponseWriter, r *http.Request) {
+	var runIDs []string
+	seen := make(map[string]bool)
+	for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
 		}
+		seen[id] = true
+		runIDs = append(runIDs, id)
 	}
 
-	total, err := h.store.GetLogCount(runID)
-	if err != nil {
-		WriteError(w, http.StatusInternalServerError, "Failed to count logs", "INTERNAL_ERROR")
+	if len(runIDs) == 0 {
+		WriteError(w, http.StatusBadRequest, "At least one run ID is required", "VALIDATION_ERROR")
 		return
 	}
-
-	logs, err := h.store.GetLogsPaginated(runID, limit, offset)
-	if err != nil {
-		WriteError(w, http.StatusInternalServerError, "Failed to get logs", "INTERNAL_ERROR")
+	if len(runIDs) > internal.MaxBatchLogRunIDs {
+		WriteError(w, http.StatusBadRequest, fmt.Sprintf("Too many run IDs (max %d)", internal.MaxBatchLogRunIDs), "VALIDATION_ERROR")
 		return
 	}
 
+	logsByRun := make(map[string][]*store.LogEntry, len(runIDs))
+	missing := make([]string, 0)
+	for _, runID := range runIDs {
+		run, err := h.store.GetRun(runID)
+		if err != nil {
+			if err.Error() == "run not found" {
+				missing = append(missing, runID)
+				continue
+			}
+			WriteError(w, http.StatusInternalServerError, "Failed to get run", "INTERNAL_ERROR")
+			return
+		}
+		if !canViewRun(h.store, r, run) {
+			missing = append(missing, runID)
+			continue
+		}
+
+		logs, err := h.store.GetLogs(runID)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, "Failed to get logs", "INTERNAL_ERROR")
+			return
+		}
+		logsByRun[runID] = logs
+	}
+
 	WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"logs":   logs,
-		"total":  total,
-		"limit":  limit,
-		"offset": offset,
+		"logs":    logsByRun,
+		"missing": missing,
 	})
 }
 
 // ScheduleHandlers handles schedule endpoints
 type ScheduleHandlers struct {
-	store *store.Store
+	store     *store.Store
+	validator *JobValidator
 }
 
 // NewScheduleHandlers creates schedule handlers
 func NewScheduleHandlers(st *store.Store) *ScheduleHandlers {
-	return &ScheduleHandlers{store: st}
+	return &ScheduleHandlers{store: st, validator: NewJobValidator()}
+}
+
+// SetMinScheduleIntervalMinutes rejects saved or copied schedules that fire more often
+// than every minutes minutes; 0 allows any schedule
+func (h *ScheduleHandlers) SetMinScheduleIntervalMinutes(minutes int) {
+	h.validator.SetMinScheduleIntervalMinutes(minutes)
 }
 
 // GetJobSchedule handles GET /api/jobs/{id}/schedule
 func (h *ScheduleHandlers) GetJobSchedule(w http.ResponseWriter, r *http.Request) {
 	jobID := r.PathValue("id")
 
+	if _, ok := getVisibleJob(h.store, r, jobID); !ok {
+		WriteError(w, http.StatusNotFound, "Job not found", "NOT_FOUND")
+		return
+	}
+
 	schedule, err := h.store.GetJobSchedule(jobID)
 	if err != nil {
 		WriteError(w, http.StatusInternalServerError, "Failed to get schedule", "INTERNAL_ERROR")
@@ -768,24 +2206,86 @@ func (h *ScheduleHandlers) SetJobSchedule(w http.ResponseWriter, r *http.Request
 
 	var req ScheduleRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		WriteError(w, http.StatusBadRequest, "Invalid request body", "VALIDATION_ERROR")
+		WriteBodyError(w, err, "Invalid request body", "VALIDATION_ERROR")
 		return
 	}
 
-	validator := NewJobValidator()
-	if validErr := validator.ValidateScheduleRequest(&req); validErr != nil {
+	if validErr := h.validator.ValidateScheduleRequest(&req); validErr != nil {
 		WriteError(w, http.StatusBadRequest, validErr.Message, validErr.Code)
 		return
 	}
 
+	schedule := req.toSchedule(jobID)
+
+	if err := h.store.SetJobSchedule(jobID, schedule); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to set schedule", "INTERNAL_ERROR")
+		return
+	}
+
+	updatedSchedule, _ := h.store.GetJobSchedule(jobID)
+	WriteJSON(w, http.StatusOK, updatedSchedule)
+}
+
+// CopyJobSchedule handles POST /api/jobs/{id}/schedule/copy-from/{sourceId}
+// Replaces the job's schedule with the source job's; fire tracking stays with each job.
+func (h *ScheduleHandlers) CopyJobSchedule(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	sourceID := r.PathValue("sourceId")
+
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Only admins can set schedules", "UNAUTHORIZED")
+		return
+	}
+
+	if jobID == sourceID {
+		WriteError(w, http.StatusBadRequest, "A job cannot copy its own schedule", "VALIDATION_ERROR")
+		return
+	}
+	if _, err := h.store.GetJob(jobID); err != nil {
+		WriteError(w, http.StatusNotFound, "Job not found", "NOT_FOUND")
+		return
+	}
+	if _, err := h.store.GetJob(sourceID); err != nil {
+		WriteError(w, http.StatusNotFound, "Source job not found", "NOT_FOUND")
+		return
+	}
+
+	source, err := h.store.GetJobSchedule(sourceID)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get schedule", "INTERNAL_ERROR")
+		return
+	}
+	if source.ID == 0 {
+		WriteError(w, http.StatusBadRequest, "Source job has no schedule", "VALIDATION_ERROR")
+		return
+	}
+	if len(source.CorruptFields) > 0 {
+		// Copying would save the "any" stand-ins as if they were intended
+		WriteError(w, http.StatusBadRequest, "Source job's schedule has corrupt fields: "+strings.Join(source.CorruptFields, ", "), "VALIDATION_ERROR")
+		return
+	}
+
 	schedule := &store.Schedule{
 		JobID:    jobID,
-		Years:    req.Years,
-		Months:   req.Months,
-		Days:     req.Days,
-		Weekdays: req.Weekdays,
-		Hours:    req.Hours,
-		Minutes:  req.Minutes,
+		Years:    source.Years,
+		Months:   source.Months,
+		Days:     source.Days,
+		Weekdays: source.Weekdays,
+		Hours:    source.Hours,
+		Minutes:  source.Minutes,
+		Holidays: source.Holidays,
+		Solar:    source.Solar,
+		RunAt:    source.RunAt,
+	}
+	// A copied one-shot whose time has passed would fire on the target straight away
+	if schedule.RunAt != nil && !schedule.RunAt.After(time.Now()) {
+		WriteError(w, http.StatusBadRequest, "Source job's one-shot run_at has already passed", "VALIDATION_ERROR")
+		return
+	}
+	// The source may predate MIN_SCHEDULE_INTERVAL_MINUTES
+	if validErr := h.validator.validateScheduleInterval(schedule); validErr != nil {
+		WriteError(w, http.StatusBadRequest, validErr.Message, validErr.Code)
+		return
 	}
 
 	if err := h.store.SetJobSchedule(jobID, schedule); err != nil {
@@ -797,69 +2297,157 @@ func (h *ScheduleHandlers) SetJobSchedule(w http.ResponseWriter, r *http.Request
 	WriteJSON(w, http.StatusOK, updatedSchedule)
 }
 
-// DashboardHandlers handles dashboard endpoints
-type DashboardHandlers struct {
-	store *store.Store
+// DisableJobSchedule handles POST /api/jobs/{id}/schedule/disable
+func (h *ScheduleHandlers) DisableJobSchedule(w http.ResponseWriter, r *http.Request) {
+	h.setScheduleEnabled(w, r, false)
 }
 
-// NewDashboardHandlers creates dashboard handlers
-func NewDashboardHandlers(st *store.Store) *DashboardHandlers {
-	return &DashboardHandlers{store: st}
+// EnableJobSchedule handles POST /api/jobs/{id}/schedule/enable
+func (h *ScheduleHandlers) EnableJobSchedule(w http.ResponseWriter, r *http.Request) {
+	h.setScheduleEnabled(w, r, true)
 }
 
-// GetStats handles GET /api/dashboard/stats
-func (h *DashboardHandlers) GetStats(w http.ResponseWriter, r *http.Request) {
-	jobs, err := h.store.ListJobs(nil)
-	if err != nil {
-		WriteError(w, http.StatusInternalServerError, "Failed to get stats", "INTERNAL_ERROR")
+// setScheduleEnabled toggles scheduled triggering for a job; manual triggers keep working
+func (h *ScheduleHandlers) setScheduleEnabled(w http.ResponseWriter, r *http.Request, enabled bool) {
+	jobID := r.PathValue("id")
+	role := r.Header.Get("X-User-Role")
+
+	if role != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Only admins can change schedules", "UNAUTHORIZED")
+		return
+	}
+
+	if err := h.store.SetJobScheduleEnabled(jobID, enabled); err != nil {
+		if err.Error() == "job not found" {
+			WriteError(w, http.StatusNotFound, "Job not found", "NOT_FOUND")
+			return
+		}
+		WriteError(w, http.StatusInternalServerError, "Failed to update schedule", "INTERNAL_ERROR")
 		return
 	}
 
-	runs, err := h.store.ListRuns(nil, 100, 0)
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"job_id":           jobID,
+		"schedule_enabled": enabled,
+	})
+}
+
+// UpcomingRun is one future scheduled fire on the schedule board
+type UpcomingRun struct {
+	JobID    string    `json:"job_id"`
+	JobName  string    `json:"job_name"`
+	Timezone string    `json:"timezone"`
+	FireAt   time.Time `json:"fire_at"`
+}
+
+// ListUpcoming handles GET /api/schedule/upcoming
+// Returns the scheduled fires of enabled jobs within ?within= (a Go duration, default 24h,
+// at most 168h), soonest first and capped at ?limit= entries. Non-admins only see their own jobs.
+func (h *ScheduleHandlers) ListUpcoming(w http.ResponseWriter, r *http.Request) {
+	within := internal.DefaultUpcomingWindow
+	if withinStr := r.URL.Query().Get("within"); withinStr != "" {
+		parsed, err := time.ParseDuration(withinStr)
+		if err != nil || parsed <= 0 || parsed > internal.MaxUpcomingWindow {
+			WriteError(w, http.StatusBadRequest, "within must be a positive duration up to 168h", "VALIDATION_ERROR")
+			return
+		}
+		within = parsed
+	}
+
+	limit := internal.DefaultPageLimit
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= internal.MaxUpcomingRuns {
+		limit = l
+	}
+
+	jobs, err := h.store.ListJobs(nil)
 	if err != nil {
-		WriteError(w, http.StatusInternalServerError, "Failed to get stats", "INTERNAL_ERROR")
+		WriteError(w, http.StatusInternalServerError, "Failed to list jobs", "INTERNAL_ERROR")
 		return
 	}
 
-	activeJobs := 0
+	now := time.Now()
+	until := now.Add(within)
+	upcoming := make([]UpcomingRun, 0)
 	for _, job := range jobs {
-		if job.Enabled {
-			activeJobs++
+		if !canViewJob(r, job) {
+			continue
+		}
+		schedule, err := h.store.GetJobSchedule(job.ID)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, "Failed to get schedule", "INTERNAL_ERROR")
+			return
+		}
+		// Each job contributes at most limit entries, so the merged list can always be cut to limit
+		for _, t := range scheduler.UpcomingRunTimes(job, schedule, now, until, limit) {
+			upcoming = append(upcoming, UpcomingRun{JobID: job.ID, JobName: job.Name, Timezone: job.Timezone, FireAt: t})
+		}
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		if !upcoming[i].FireAt.Equal(upcoming[j].FireAt) {
+			return upcoming[i].FireAt.Before(upcoming[j].FireAt)
 		}
+		return upcoming[i].JobName < upcoming[j].JobName
+	})
+	truncated := len(upcoming) > limit
+	if truncated {
+		upcoming = upcoming[:limit]
 	}
 
-	successCount := 0
-	failureCount := 0
-	runningCount := 0
+	WriteNegotiated(w, r, http.StatusOK, map[string]interface{}{
+		"upcoming":  upcoming,
+		"total":     len(upcoming),
+		"truncated": truncated,
+		"until":     until,
+	})
+}
+
+// DashboardHandlers handles dashboard endpoints
+type DashboardHandlers struct {
+	store *store.Store
+}
+
+// NewDashboardHandlers creates dashboard handlers
+func NewDashboardHandlers(st *store.Store) *DashboardHandlers {
+	return &DashboardHandlers{store: st}
+}
 
-	for _, run := range runs {
-		switch run.Status {
-		case "success":
-			successCount++
-		case "failure", "timeout":
-			failureCount++
-		case "running":
-			runningCount++
+// GetStats handles GET /api/dashboard/stats
+// The success rate covers runs created within ?window= (a Go duration such as 24h or 168h, default 24h).
+func (h *DashboardHandlers) GetStats(w http.ResponseWriter, r *http.Request) {
+	window := internal.DefaultDashboardStatsWindow
+	if windowStr := r.URL.Query().Get("window"); windowStr != "" {
+		parsed, err := time.ParseDuration(windowStr)
+		if err != nil || parsed <= 0 {
+			WriteError(w, http.StatusBadRequest, "window must be a positive duration such as 24h", "VALIDATION_ERROR")
+			return
 		}
+		window = parsed
 	}
+	since := time.Now().Add(-window)
 
-	totalCompleted := successCount + failureCount
-	successRate := 0.0
-	if totalCompleted > 0 {
-		successRate = float64(successCount) / float64(totalCompleted)
+	stats, err := h.store.GetOverallStats(&since)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get stats", "INTERNAL_ERROR")
+		return
 	}
 
-	recentRuns := runs
-	if len(recentRuns) > 10 {
-		recentRuns = recentRuns[:10]
+	recentRuns, err := h.store.ListRuns(store.RunFilter{}, internal.DashboardRecentRuns, 0)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get stats", "INTERNAL_ERROR")
+		return
 	}
 
 	WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"total_jobs":   len(jobs),
-		"active_jobs":  activeJobs,
-		"success_rate": successRate,
-		"running_now":  runningCount,
-		"recent_runs":  recentRuns,
+		"total_jobs":     stats["total_jobs"],
+		"active_jobs":    stats["active_jobs"],
+		"success_rate":   stats["success_rate"],
+		"window":         window.String(),
+		"window_runs":    stats["total_runs"],
+		"window_success": stats["success_count"],
+		"window_failure": stats["failure_count"],
+		"running_now":    stats["running_now"],
+		"recent_runs":    recentRuns,
 	})
 }
 
@@ -870,6 +2458,43 @@ func Health(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, `{"status":"ok"}`)
 }
 
+// Version returns a handler for GET /api/version reporting build info and uptime
+func Version(startTime time.Time) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uptime := time.Since(startTime)
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"version":        internal.Version,
+			"go_version":     runtime.Version(),
+			"started_at":     startTime.UTC().Format(time.RFC3339),
+			"uptime_seconds": int64(uptime.Seconds()),
+		})
+	}
+}
+
+// GetSchedulerStatus handles GET /api/admin/scheduler
+// Reports whether the scheduler is running, which instance holds the scheduler lease and
+// its recent tick durations.
+func (h *JobHandlers) GetSchedulerStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
+		return
+	}
+
+	lease, err := h.store.GetSchedulerLease()
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get scheduler lease", "INTERNAL_ERROR")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"running":     h.scheduler.IsRunning(),
+		"instance_id": h.scheduler.InstanceID(),
+		"leader":      h.scheduler.IsLeader(),
+		"lease":       lease,
+		"ticks":       h.scheduler.TickStats(),
+	})
+}
+
 // AnalyticsHandlers handles analytics endpoints
 type AnalyticsHandlers struct {
 	store *store.Store
@@ -880,6 +2505,32 @@ func NewAnalyticsHandlers(st *store.Store) *AnalyticsHandlers {
 	return &AnalyticsHandlers{store: st}
 }
 
+// parseAnalyticsFilter reads the optional ?job_id= and ?tag=<key>=<value> analytics filters,
+// where tag matches a job metadata entry. Non-admins are always scoped to their own jobs.
+// On invalid input it writes the error response and returns false.
+func parseAnalyticsFilter(w http.ResponseWriter, r *http.Request) (store.AnalyticsFilter, bool) {
+	filter := store.AnalyticsFilter{JobID: r.URL.Query().Get("job_id")}
+
+	if tag := r.URL.Query().Get("tag"); tag != "" {
+		key, value, found := strings.Cut(tag, "=")
+		if !found || !metadataKeyPattern.MatchString(key) {
+			WriteError(w, http.StatusBadRequest, "tag must be <key>=<value> with a valid metadata key", "VALIDATION_ERROR")
+			return filter, false
+		}
+		filter.TagKey, filter.TagValue = key, value
+	}
+
+	if userIDStr := r.Header.Get("X-User-ID"); userIDStr != "" && r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		userID, err := strconv.Atoi(userIDStr)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, "Invalid user ID", "INVALID_ID")
+			return filter, false
+		}
+		filter.CreatedBy = &userID
+	}
+	return filter, true
+}
+
 // GetExecutionTrends handles GET /api/analytics/execution-trends
 func (h *AnalyticsHandlers) GetExecutionTrends(w http.ResponseWriter, r *http.Request) {
 	daysStr := r.URL.Query().Get("days")
@@ -888,7 +2539,12 @@ func (h *AnalyticsHandlers) GetExecutionTrends(w http.ResponseWriter, r *http.Re
 		days = d
 	}
 
-	trends, err := h.store.GetExecutionTrends(days)
+	filter, ok := parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	trends, err := h.store.GetExecutionTrendsFiltered(days, filter)
 	if err != nil {
 		WriteError(w, http.StatusInternalServerError, "Failed to get execution trends", "INTERNAL_ERROR")
 		return
@@ -928,30 +2584,197 @@ func (h *AnalyticsHandlers) GetJobDurationTrends(w http.ResponseWriter, r *http.
 		days = d
 	}
 
+	job, ok := getVisibleJob(h.store, r, jobID)
+	if !ok {
+		WriteError(w, http.StatusNotFound, "Job not found", "NOT_FOUND")
+		return
+	}
+
 	trends, err := h.store.GetJobDurationTrends(jobID, days)
 	if err != nil {
 		WriteError(w, http.StatusInternalServerError, "Failed to get duration trends", "INTERNAL_ERROR")
 		return
 	}
 
-	// Get job name
-	job, _ := h.store.GetJob(jobID)
-	jobName := ""
-	if job != nil {
-		jobName = job.Name
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"job_id":   jobID,
+		"job_name": job.Name,
+		"trends":   trends,
+		"days":     days,
+	})
+}
+
+// InventoryJob identifies a job in the inventory's outside-roots list
+type InventoryJob struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	WorkingDir string `json:"working_dir"`
+}
+
+// GetInventory handles GET /api/admin/inventory
+// Lists distinct working directories and interpreters with job counts. With
+// ?roots=/srv/jobs:/opt/scripts, also lists jobs whose working_dir is outside those roots.
+func (h *AnalyticsHandlers) GetInventory(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
+		return
+	}
+
+	inventory, err := h.store.GetJobInventory()
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get job inventory", "INTERNAL_ERROR")
+		return
+	}
+
+	roots := parseRoots(r.URL.Query().Get("roots"))
+	outside := []InventoryJob{}
+	if len(roots) > 0 {
+		jobs, err := h.store.ListJobs(nil)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, "Failed to list jobs", "INTERNAL_ERROR")
+			return
+		}
+		for _, job := range jobs {
+			if !isUnderRoots(job.WorkingDir, roots) {
+				outside = append(outside, InventoryJob{ID: job.ID, Name: job.Name, WorkingDir: job.WorkingDir})
+			}
+		}
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"working_dirs":  inventory.WorkingDirs,
+		"interpreters":  inventory.Interpreters,
+		"roots":         roots,
+		"outside_roots": outside,
+	})
+}
+
+// GetJobSLA handles GET /api/jobs/{id}/sla?days=30
+// Reports the job's success rate over finished runs in the last N days (1-365, default 30).
+func (h *AnalyticsHandlers) GetJobSLA(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	if jobID == "" {
+		WriteError(w, http.StatusBadRequest, "Job ID is required", "INVALID_ID")
+		return
+	}
+
+	days := 30 // default
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil || d <= 0 || d > 365 {
+			WriteError(w, http.StatusBadRequest, "days must be between 1 and 365", "VALIDATION_ERROR")
+			return
+		}
+		days = d
+	}
+
+	if _, ok := getVisibleJob(h.store, r, jobID); !ok {
+		WriteError(w, http.StatusNotFound, "Job not found", "NOT_FOUND")
+		return
+	}
+
+	rate, err := h.store.GetJobSuccessRate(jobID, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get success rate", "INTERNAL_ERROR")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"job_id":          rate.JobID,
+		"days":            days,
+		"since":           rate.Since,
+		"total_runs":      rate.TotalRuns,
+		"success_count":   rate.SuccessCount,
+		"failure_count":   rate.FailureCount,
+		"cancelled_count": rate.CancelledCount,
+		"success_rate":    rate.SuccessRate,
+	})
+}
+
+// GetJobOverlaps handles GET /api/jobs/{id}/overlaps?days=7
+// Reports pairs of finished runs started in the last N days (1-365, default 7) whose execution overlapped.
+func (h *AnalyticsHandlers) GetJobOverlaps(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	if jobID == "" {
+		WriteError(w, http.StatusBadRequest, "Job ID is required", "INVALID_ID")
+		return
+	}
+
+	days := 7 // default
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil || d <= 0 || d > 365 {
+			WriteError(w, http.StatusBadRequest, "days must be between 1 and 365", "VALIDATION_ERROR")
+			return
+		}
+		days = d
+	}
+
+	if _, ok := getVisibleJob(h.store, r, jobID); !ok {
+		WriteError(w, http.StatusNotFound, "Job not found", "NOT_FOUND")
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	overlaps, err := h.store.GetJobRunOverlaps(jobID, since)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to detect overlapping runs", "INTERNAL_ERROR")
+		return
 	}
 
 	WriteJSON(w, http.StatusOK, map[string]interface{}{
 		"job_id":   jobID,
-		"job_name": jobName,
-		"trends":   trends,
 		"days":     days,
+		"since":    since,
+		"count":    len(overlaps),
+		"overlaps": overlaps,
 	})
 }
 
+// GetUserUsage handles GET /api/users/{id}/usage
+// Admins may query any user; other users may only query themselves.
+func (h *AnalyticsHandlers) GetUserUsage(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid user ID", "INVALID_ID")
+		return
+	}
+
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin && r.Header.Get("X-User-ID") != strconv.Itoa(userID) {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -30) // default
+	if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+		parsed, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			parsed, err = time.Parse("2006-01-02", sinceStr)
+		}
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, "since must be RFC3339 or YYYY-MM-DD", "VALIDATION_ERROR")
+			return
+		}
+		since = parsed
+	}
+
+	usage, err := h.store.GetUserUsage(userID, since)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get usage", "INTERNAL_ERROR")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, usage)
+}
+
 // GetOverallStats handles GET /api/analytics/overview
 func (h *AnalyticsHandlers) GetOverallStats(w http.ResponseWriter, r *http.Request) {
-	stats, err := h.store.GetOverallStats()
+	filter, ok := parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := h.store.GetOverallStatsFiltered(nil, filter)
 	if err != nil {
 		WriteError(w, http.StatusInternalServerError, "Failed to get overall stats", "INTERNAL_ERROR")
 		return
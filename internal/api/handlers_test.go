@@ -2,15 +2,23 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	internal "github.com/taskflow/taskflow/internal"
 	"github.com/taskflow/taskflow/internal/auth"
+	"github.com/taskflow/taskflow/internal/executor"
+	"github.com/taskflow/taskflow/internal/scheduler"
 	"github.com/taskflow/taskflow/internal/store"
+	"gopkg.in/yaml.v3"
 )
 
 // TestCreateJobValidation tests input validation in CreateJob handler
@@ -74,7 +82,7 @@ func TestCreateJobValidation(t *testing.T) {
 			name:           "script too large",
 			role:           "admin",
 			jobName:        "Test Job",
-			script:         string(make([]byte, 2000000)), // 2MB
+			script:         string(make([]byte, 9000000)), // 9MB
 			expectStatus:   http.StatusBadRequest,
 			expectErrorMsg: "Script too long",
 		},
@@ -284,6 +292,729 @@ func TestHealthCheck(t *testing.T) {
 	assert.Contains(t, w.Body.String(), "ok")
 }
 
+// TestVersionEndpoint tests the version and uptime endpoint
+func TestVersionEndpoint(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/version", nil)
+	w := httptest.NewRecorder()
+
+	Version(time.Now().Add(-5*time.Second))(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data struct {
+			Version       string `json:"version"`
+			GoVersion     string `json:"go_version"`
+			UptimeSeconds int64  `json:"uptime_seconds"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+
+	assert.NotEmpty(t, response.Data.Version)
+	assert.NotEmpty(t, response.Data.GoVersion)
+	assert.GreaterOrEqual(t, response.Data.UptimeSeconds, int64(0))
+}
+
+// TestPreviewEmail tests rendering a notification email for a sample run
+func TestPreviewEmail(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	authHandlers := NewAuthHandlers(testStore, auth.NewJWTManager("test-secret-at-least-32-bytes-long"))
+
+	body := `{"job": {"name": "Nightly Backup", "description": "Backs up the database"},
+		"run": {"id": "run-123", "status": "failure", "trigger_type": "manual", "exit_code": 2}}`
+
+	t.Run("admin_sample_run", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/settings/email/preview", bytes.NewBufferString(body))
+		req.Header.Set("X-User-Role", "admin")
+		w := httptest.NewRecorder()
+
+		authHandlers.PreviewEmail(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Data struct {
+				Subject string `json:"subject"`
+				Body    string `json:"body"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+
+		assert.Contains(t, response.Data.Subject, "Nightly Backup")
+		assert.Contains(t, response.Data.Subject, "FAILURE")
+		assert.Contains(t, response.Data.Body, "Nightly Backup")
+		assert.Contains(t, response.Data.Body, "FAILURE")
+		assert.Contains(t, response.Data.Body, "run-123")
+	})
+
+	t.Run("non_admin_forbidden", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/settings/email/preview", bytes.NewBufferString(body))
+		req.Header.Set("X-User-Role", "user")
+		w := httptest.NewRecorder()
+
+		authHandlers.PreviewEmail(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("missing_input", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/settings/email/preview", bytes.NewBufferString(`{}`))
+		req.Header.Set("X-User-Role", "admin")
+		w := httptest.NewRecorder()
+
+		authHandlers.PreviewEmail(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+// TestListNotificationOutbox tests that admins can list outbox entries filtered by status
+func TestListNotificationOutbox(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	delivered, err := testStore.EnqueueNotification(&store.OutboxNotification{JobID: "j1", RunID: "r1", Recipients: "a@example.com", Subject: "ok", Body: "b"})
+	require.NoError(t, err)
+	require.NoError(t, testStore.MarkNotificationDelivered(delivered.ID))
+	stuck, err := testStore.EnqueueNotification(&store.OutboxNotification{JobID: "j2", RunID: "r2", Recipients: "b@example.com", Subject: "stuck", Body: "b"})
+	require.NoError(t, err)
+	require.NoError(t, testStore.RecordNotificationFailure(stuck.ID, "connection refused", nil))
+
+	authHandlers := NewAuthHandlers(testStore, auth.NewJWTManager("test-secret-at-least-32-bytes-long"))
+
+	list := func(role, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/notifications/outbox"+query, nil)
+		req.Header.Set("X-User-Role", role)
+		w := httptest.NewRecorder()
+		authHandlers.ListNotificationOutbox(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusForbidden, list("user", "").Code)
+	assert.Equal(t, http.StatusBadRequest, list("admin", "?status=bogus").Code)
+
+	w := list("admin", "?status=failed")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data struct {
+			Notifications []store.OutboxNotification `json:"notifications"`
+			Total         int                        `json:"total"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	require.Equal(t, 1, response.Data.Total)
+	entry := response.Data.Notifications[0]
+	assert.Equal(t, stuck.ID, entry.ID)
+	assert.Equal(t, "failed", entry.Status)
+	assert.Equal(t, 1, entry.Attempts)
+	require.NotNil(t, entry.LastError)
+	assert.Equal(t, "connection refused", *entry.LastError)
+}
+
+// TestListUsersMinimal tests that the user picker list carries only id, username, email and role
+func TestListUsersMinimal(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	_, err := testStore.CreateUser("zoe", "zoe@example.com", "$2a$10$secret-hash-zoe", "user")
+	require.NoError(t, err)
+	admin, err := testStore.CreateUser("alice", "alice@example.com", "$2a$10$secret-hash-alice", "admin")
+	require.NoError(t, err)
+
+	authHandlers := NewAuthHandlers(testStore, auth.NewJWTManager("test-secret-at-least-32-bytes-long"))
+	list := func(role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/users/minimal", nil)
+		req.Header.Set("X-User-Role", role)
+		w := httptest.NewRecorder()
+		authHandlers.ListUsersMinimal(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusForbidden, list("user").Code)
+
+	w := list("admin")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "secret-hash")
+	assert.NotContains(t, w.Body.String(), "password")
+
+	var response struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	require.Len(t, response.Data, 2)
+	first := response.Data[0]
+	assert.Len(t, first, 4, "only id, username, email and role are returned")
+	assert.Equal(t, float64(admin.ID), first["id"])
+	assert.Equal(t, "alice", first["username"])
+	assert.Equal(t, "alice@example.com", first["email"])
+	assert.Equal(t, "admin", first["role"])
+	assert.Equal(t, "zoe", response.Data[1]["username"])
+}
+
+// TestGetBatchRunLogs tests fetching logs for several runs in one request
+func TestGetBatchRunLogs(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "batch", Script: "echo hi"})
+	require.NoError(t, err)
+	runA, err := testStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+	runB, err := testStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	_, err = testStore.AddLog(runA.ID, "stdout", "from run A")
+	require.NoError(t, err)
+	_, err = testStore.AddLog(runB.ID, "stdout", "from run B")
+	require.NoError(t, err)
+	_, err = testStore.AddLog(runB.ID, "stderr", "more from run B")
+	require.NoError(t, err)
+
+	runHandlers := NewRunHandlers(testStore)
+
+	req := httptest.NewRequest("GET", "/api/runs/logs?ids="+runA.ID+","+runB.ID+",missing-run", nil)
+	w := httptest.NewRecorder()
+
+	runHandlers.GetBatchRunLogs(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data struct {
+			Logs    map[string][]store.LogEntry `json:"logs"`
+			Missing []string                    `json:"missing"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+
+	require.Len(t, response.Data.Logs, 2)
+	require.Len(t, response.Data.Logs[runA.ID], 1)
+	assert.Equal(t, "from run A", response.Data.Logs[runA.ID][0].Content)
+	require.Len(t, response.Data.Logs[runB.ID], 2)
+	assert.Equal(t, "from run B", response.Data.Logs[runB.ID][0].Content)
+	assert.Equal(t, []string{"missing-run"}, response.Data.Missing)
+
+	t.Run("no_ids", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		runHandlers.GetBatchRunLogs(w, httptest.NewRequest("GET", "/api/runs/logs", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+// TestTriggerJobWithDisabledSchedule tests that disabling a schedule keeps manual triggers working
+func TestTriggerJobWithDisabledSchedule(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "paused", Script: "echo hi", Enabled: true})
+	require.NoError(t, err)
+
+	scheduleHandlers := NewScheduleHandlers(testStore)
+	req := httptest.NewRequest("POST", "/api/jobs/"+job.ID+"/schedule/disable", nil)
+	req.SetPathValue("id", job.ID)
+	req.Header.Set("X-User-Role", "admin")
+	w := httptest.NewRecorder()
+	scheduleHandlers.DisableJobSchedule(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	stored, err := testStore.GetJob(job.ID)
+	require.NoError(t, err)
+	assert.False(t, stored.ScheduleEnabled)
+
+	jobHandlers := NewJobHandlers(testStore, scheduler.New(testStore))
+	req = httptest.NewRequest("POST", "/api/jobs/"+job.ID+"/run", nil)
+	req.SetPathValue("id", job.ID)
+	w = httptest.NewRecorder()
+	jobHandlers.TriggerJob(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+// TestCopyJobSchedule tests that a job's schedule can be copied onto another job
+func TestCopyJobSchedule(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	source, err := testStore.CreateJob(&store.Job{Name: "source", Script: "echo hi", Enabled: true})
+	require.NoError(t, err)
+	target, err := testStore.CreateJob(&store.Job{Name: "target", Script: "echo hi", Enabled: true})
+	require.NoError(t, err)
+	require.NoError(t, testStore.SetJobSchedule(source.ID, &store.Schedule{
+		Weekdays: []int{1, 3, 5},
+		Hours:    []int{2},
+		Minutes:  []int{15, 45},
+	}))
+	require.NoError(t, testStore.SetJobSchedule(target.ID, &store.Schedule{Hours: []int{9}, Minutes: []int{0}}))
+
+	scheduleHandlers := NewScheduleHandlers(testStore)
+	copySchedule := func(jobID, sourceID, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/jobs/"+jobID+"/schedule/copy-from/"+sourceID, nil)
+		req.SetPathValue("id", jobID)
+		req.SetPathValue("sourceId", sourceID)
+		req.Header.Set("X-User-Role", role)
+		w := httptest.NewRecorder()
+		scheduleHandlers.CopyJobSchedule(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusForbidden, copySchedule(target.ID, source.ID, "user").Code)
+	assert.Equal(t, http.StatusNotFound, copySchedule(target.ID, "missing", "admin").Code)
+	assert.Equal(t, http.StatusNotFound, copySchedule("missing", source.ID, "admin").Code)
+
+	w := copySchedule(target.ID, source.ID, "admin")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	want, err := testStore.GetJobSchedule(source.ID)
+	require.NoError(t, err)
+	got, err := testStore.GetJobSchedule(target.ID)
+	require.NoError(t, err)
+	assert.Equal(t, target.ID, got.JobID)
+	assert.Equal(t, want.Weekdays, got.Weekdays)
+	assert.Equal(t, want.Hours, got.Hours)
+	assert.Equal(t, want.Minutes, got.Minutes)
+	assert.Equal(t, want.Days, got.Days)
+	assert.Equal(t, want.Months, got.Months)
+	assert.Equal(t, want.Years, got.Years)
+
+	// The source keeps its schedule
+	unchanged, err := testStore.GetJobSchedule(source.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{15, 45}, unchanged.Minutes)
+}
+
+// TestRequeueRun tests that a pending run is pushed back onto the queue with its existing
+// run ID and that runs in any other state are rejected
+func TestRequeueRun(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "stuck", Script: "echo hi", Enabled: true})
+	require.NoError(t, err)
+
+	// A run that was created but never enqueued
+	pending, err := testStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	sched := scheduler.New(testStore)
+	executed := make(chan string, 1)
+	require.NoError(t, sched.Start(context.Background(), func(j *store.Job, r *store.Run) error {
+		executed <- r.ID
+		return nil
+	}))
+	defer sched.Stop()
+
+	jobHandlers := NewJobHandlers(testStore, sched)
+
+	requeue := func(runID, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/runs/"+runID+"/requeue", nil)
+		req.SetPathValue("id", runID)
+		req.Header.Set("X-User-Role", role)
+		w := httptest.NewRecorder()
+		jobHandlers.RequeueRun(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusForbidden, requeue(pending.ID, "user").Code)
+	assert.Equal(t, http.StatusNotFound, requeue("missing", "admin").Code)
+
+	w := requeue(pending.ID, "admin")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	select {
+	case runID := <-executed:
+		assert.Equal(t, pending.ID, runID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("requeued run was not executed")
+	}
+
+	finished, err := testStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+	finished.Status = "success"
+	require.NoError(t, testStore.UpdateRun(finished))
+
+	w = requeue(finished.ID, "admin")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "INVALID_STATE")
+}
+
+// TestRequeueRunAlreadyQueued tests that a pending run still waiting in the queue is not
+// queued a second time
+func TestRequeueRunAlreadyQueued(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "waiting", Script: "echo hi", Enabled: true})
+	require.NoError(t, err)
+	run, err := testStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	// Not started, so the run stays in the queue
+	sched := scheduler.New(testStore)
+	jobHandlers := NewJobHandlers(testStore, sched)
+
+	requeue := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/runs/"+run.ID+"/requeue", nil)
+		req.SetPathValue("id", run.ID)
+		req.Header.Set("X-User-Role", "admin")
+		w := httptest.NewRecorder()
+		jobHandlers.RequeueRun(w, req)
+		return w
+	}
+
+	require.Equal(t, http.StatusOK, requeue().Code)
+	w := requeue()
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "ALREADY_QUEUED")
+	assert.Equal(t, 1, sched.QueueLength())
+}
+
+// TestMoveRunToFront tests that only a pending run waiting in the queue can be moved
+// to the front
+func TestMoveRunToFront(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "urgent", Script: "echo hi", Enabled: true})
+	require.NoError(t, err)
+
+	// The scheduler is not started, so enqueued runs stay queued
+	sched := scheduler.New(testStore)
+	jobHandlers := NewJobHandlers(testStore, sched)
+
+	move := func(runID, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/queue/"+runID+"/move-to-front", nil)
+		req.SetPathValue("runId", runID)
+		req.Header.Set("X-User-Role", role)
+		w := httptest.NewRecorder()
+		jobHandlers.MoveRunToFront(w, req)
+		return w
+	}
+
+	queued, err := testStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+	require.NoError(t, sched.EnqueueWithRun(job, queued))
+	unqueued, err := testStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusForbidden, move(queued.ID, "user").Code)
+	assert.Equal(t, http.StatusNotFound, move("missing", "admin").Code)
+	assert.Equal(t, http.StatusOK, move(queued.ID, "admin").Code)
+
+	w := move(unqueued.ID, "admin")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "NOT_QUEUED")
+
+	unqueued.Status = "success"
+	require.NoError(t, testStore.UpdateRun(unqueued))
+	w = move(unqueued.ID, "admin")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "INVALID_STATE")
+}
+
+// TestRequiresApprovalHoldsManualRun tests that a manual run of a requires_approval job
+// is not queued until a different admin approves it, and that a rejected run is cancelled
+func TestRequiresApprovalHoldsManualRun(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "prod-migrate", Script: "echo hi", Enabled: true, RequiresApproval: true})
+	require.NoError(t, err)
+
+	// The scheduler is not started, so enqueued runs stay queued
+	sched := scheduler.New(testStore)
+	jobHandlers := NewJobHandlers(testStore, sched)
+
+	call := func(handler http.HandlerFunc, path, runID, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, nil)
+		req.SetPathValue("id", runID)
+		req.Header.Set("X-User-ID", userID)
+		req.Header.Set("X-User-Role", "admin")
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+	trigger := func() *store.Run {
+		w := call(jobHandlers.TriggerJob, "/api/jobs/"+job.ID+"/run", job.ID, "1")
+		require.Equal(t, http.StatusAccepted, w.Code)
+		var resp struct {
+			Data store.Run `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return &resp.Data
+	}
+
+	run := trigger()
+	assert.Equal(t, internal.JobStatusAwaitingApproval, run.Status)
+	require.NotNil(t, run.TriggeredBy)
+	assert.Equal(t, 1, *run.TriggeredBy)
+	assert.Equal(t, 0, sched.QueueLength(), "nothing runs before approval")
+
+	// The admin who triggered the run cannot approve it
+	w := call(jobHandlers.ApproveRun, "/api/runs/"+run.ID+"/approve", run.ID, "1")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "SELF_APPROVAL")
+	assert.Equal(t, 0, sched.QueueLength())
+
+	w = call(jobHandlers.ApproveRun, "/api/runs/"+run.ID+"/approve", run.ID, "2")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, sched.QueueLength(), "approved run is queued")
+	saved, err := testStore.GetRun(run.ID)
+	require.NoError(t, err)
+	assert.Equal(t, internal.JobStatusPending, saved.Status)
+	require.NotNil(t, saved.ApprovedBy)
+	assert.Equal(t, 2, *saved.ApprovedBy)
+
+	// Approving twice is a conflict
+	assert.Equal(t, http.StatusConflict, call(jobHandlers.ApproveRun, "/api/runs/"+run.ID+"/approve", run.ID, "3").Code)
+
+	rejected := trigger()
+	require.Equal(t, http.StatusOK, call(jobHandlers.RejectRun, "/api/runs/"+rejected.ID+"/reject", rejected.ID, "2").Code)
+	saved, err = testStore.GetRun(rejected.ID)
+	require.NoError(t, err)
+	assert.Equal(t, internal.JobStatusCancelled, saved.Status)
+	assert.Equal(t, 1, sched.QueueLength(), "rejected run is never queued")
+
+	// A test run would execute the script without approval
+	w = call(jobHandlers.TestJob, "/api/jobs/"+job.ID+"/test", job.ID, "1")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "APPROVAL_REQUIRED")
+	runs, err := testStore.ListRuns(store.RunFilter{JobID: job.ID}, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, runs, 2, "no test run is created")
+}
+
+// TestGetRunLogsFiltersByLevel tests that ?level= returns only lines tagged with the
+// requested levels, before and after the run's logs are compressed
+func TestGetRunLogsFiltersByLevel(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "noisy", Script: "true", Enabled: true})
+	require.NoError(t, err)
+	run, err := testStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+	require.NoError(t, testStore.AddLogs(run.ID, []store.LogEntry{
+		{Stream: "stdout", Content: "[INFO] starting", Level: "info"},
+		{Stream: "stderr", Content: "[ERROR] first", Level: "error"},
+		{Stream: "stdout", Content: "untagged"},
+		{Stream: "stdout", Content: "[WARN] slow", Level: "warn"},
+		{Stream: "stderr", Content: "[ERROR] second", Level: "error"},
+	}))
+
+	runHandlers := NewRunHandlers(testStore)
+	get := func(query string) (contents []string, total int) {
+		req := httptest.NewRequest("GET", "/api/runs/"+run.ID+"/logs?"+query, nil)
+		req.SetPathValue("id", run.ID)
+		req.Header.Set("X-User-Role", "admin")
+		w := httptest.NewRecorder()
+		runHandlers.GetRunLogs(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp struct {
+			Data struct {
+				Logs  []store.LogEntry `json:"logs"`
+				Total int              `json:"total"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		for _, entry := range resp.Data.Logs {
+			contents = append(contents, entry.Content)
+		}
+		return contents, resp.Data.Total
+	}
+
+	contents, total := get("level=ERROR")
+	assert.Equal(t, []string{"[ERROR] first", "[ERROR] second"}, contents)
+	assert.Equal(t, 2, total)
+
+	contents, _ = get("level=error,warning&limit=1&offset=1")
+	assert.Equal(t, []string{"[WARN] slow"}, contents)
+
+	_, total = get("")
+	assert.Equal(t, 5, total, "no filter returns every line")
+
+	require.NoError(t, testStore.ArchiveLogs(run.ID))
+	contents, _ = get("level=error")
+	assert.Equal(t, []string{"[ERROR] first", "[ERROR] second"}, contents)
+}
+
+// TestGetJobDetail tests that the detail endpoint bundles job, schedule, runs, stats and next run
+func TestGetJobDetail(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "report", Script: "echo hi", Enabled: true})
+	require.NoError(t, err)
+	require.NoError(t, testStore.SetJobSchedule(job.ID, &store.Schedule{Hours: []int{3}, Minutes: []int{30}}))
+
+	for _, status := range []string{"success", "failure", "success"} {
+		run, err := testStore.CreateRun(job.ID, "manual")
+		require.NoError(t, err)
+		started := time.Now().Add(-time.Minute)
+		duration := int64(1000)
+		run.Status = status
+		run.StartedAt = &started
+		run.DurationMs = &duration
+		require.NoError(t, testStore.UpdateRun(run))
+	}
+
+	jobHandlers := NewJobHandlers(testStore, nil)
+	req := httptest.NewRequest("GET", "/api/jobs/"+job.ID+"/detail", nil)
+	req.SetPathValue("id", job.ID)
+	w := httptest.NewRecorder()
+	jobHandlers.GetJobDetail(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Data JobDetail `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	detail := resp.Data
+
+	require.NotNil(t, detail.Job)
+	assert.Equal(t, job.ID, detail.Job.ID)
+
+	require.NotNil(t, detail.Schedule)
+	assert.Equal(t, job.ID, detail.Schedule.JobID)
+	assert.Equal(t, []int{3}, detail.Schedule.Hours)
+	assert.Equal(t, []int{30}, detail.Schedule.Minutes)
+
+	require.Len(t, detail.RecentRuns, 3)
+	for _, run := range detail.RecentRuns {
+		assert.Equal(t, job.ID, run.JobID)
+	}
+
+	require.NotNil(t, detail.Stats)
+	assert.Equal(t, job.ID, detail.Stats.JobID)
+	assert.Equal(t, len(detail.RecentRuns), detail.Stats.TotalRuns)
+	assert.Equal(t, 2, detail.Stats.SuccessCount)
+	assert.Equal(t, 1, detail.Stats.FailureCount)
+
+	require.NotNil(t, detail.NextRunAt)
+	next := detail.NextRunAt.Local()
+	assert.Equal(t, 3, next.Hour())
+	assert.Equal(t, 30, next.Minute())
+	assert.True(t, next.After(time.Now()))
+
+	// Unknown jobs are reported as not found
+	req = httptest.NewRequest("GET", "/api/jobs/missing/detail", nil)
+	req.SetPathValue("id", "missing")
+	w = httptest.NewRecorder()
+	jobHandlers.GetJobDetail(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// TestGetInventory tests that the inventory groups jobs by working dir and interpreter
+func TestGetInventory(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	for _, job := range []*store.Job{
+		{Name: "a", Script: "true", WorkingDir: "/srv/jobs"},
+		{Name: "b", Script: "true", WorkingDir: "/srv/jobs", Interpreter: "sh"},
+		{Name: "c", Script: "true", WorkingDir: "/srv/jobs/reports", Interpreter: "sh"},
+		{Name: "d", Script: "true", WorkingDir: "/etc"},
+		{Name: "e", Script: "true", WorkingDir: "/srv/jobs-old", Interpreter: "bash"},
+	} {
+		_, err := testStore.CreateJob(job)
+		require.NoError(t, err)
+	}
+
+	analyticsHandlers := NewAnalyticsHandlers(testStore)
+	get := func(role, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/admin/inventory"+query, nil)
+		req.Header.Set("X-User-Role", role)
+		w := httptest.NewRecorder()
+		analyticsHandlers.GetInventory(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusForbidden, get("user", "").Code)
+
+	w := get("admin", "?roots=/srv/jobs")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data struct {
+			WorkingDirs  []store.InventoryItem `json:"working_dirs"`
+			Interpreters []store.InventoryItem `json:"interpreters"`
+			Roots        []string              `json:"roots"`
+			OutsideRoots []InventoryJob        `json:"outside_roots"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+
+	assert.Equal(t, []store.InventoryItem{
+		{Value: "/srv/jobs", JobCount: 2},
+		{Value: "/etc", JobCount: 1},
+		{Value: "/srv/jobs-old", JobCount: 1},
+		{Value: "/srv/jobs/reports", JobCount: 1},
+	}, response.Data.WorkingDirs)
+	assert.Equal(t, []store.InventoryItem{
+		{Value: "default", JobCount: 2},
+		{Value: "sh", JobCount: 2},
+		{Value: "bash", JobCount: 1},
+	}, response.Data.Interpreters)
+
+	assert.Equal(t, []string{"/srv/jobs"}, response.Data.Roots)
+	var outside []string
+	for _, job := range response.Data.OutsideRoots {
+		outside = append(outside, job.Name)
+	}
+	assert.ElementsMatch(t, []string{"d", "e"}, outside)
+
+	// Without roots nothing is reported as outside
+	w = get("admin", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Empty(t, response.Data.OutsideRoots)
+}
+
+// TestListJobsYAMLNegotiation tests that Accept: application/yaml returns YAML matching the JSON structure
+func TestListJobsYAMLNegotiation(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	_, err := testStore.CreateJob(&store.Job{Name: "nightly", Script: "echo hi", NotifyExitCodes: []int{1, 2}})
+	require.NoError(t, err)
+
+	jobHandlers := NewJobHandlers(testStore, nil)
+
+	list := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/jobs", nil)
+		req.Header.Set("X-User-Role", "admin")
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		jobHandlers.ListJobs(w, req)
+		return w
+	}
+
+	jsonResp := list("")
+	require.Equal(t, http.StatusOK, jsonResp.Code)
+	assert.Equal(t, "application/json", jsonResp.Header().Get("Content-Type"))
+
+	yamlResp := list("application/yaml")
+	require.Equal(t, http.StatusOK, yamlResp.Code)
+	assert.Equal(t, "application/yaml", yamlResp.Header().Get("Content-Type"))
+
+	var fromJSON, fromYAML map[string]interface{}
+	require.NoError(t, json.Unmarshal(jsonResp.Body.Bytes(), &fromJSON))
+	require.NoError(t, yaml.Unmarshal(yamlResp.Body.Bytes(), &fromYAML))
+
+	// Normalise YAML scalars (ints) through JSON so both sides use the same types
+	normalised, err := json.Marshal(fromYAML)
+	require.NoError(t, err)
+	fromYAML = nil
+	require.NoError(t, json.Unmarshal(normalised, &fromYAML))
+
+	assert.Equal(t, fromJSON, fromYAML)
+	assert.Contains(t, yamlResp.Body.String(), "name: nightly")
+}
+
 // TestWriteJSON tests response writing
 func TestWriteJSON(t *testing.T) {
 	w := httptest.NewRecorder()
@@ -346,55 +1077,133 @@ func TestJobValidatorValidation(t *testing.T) {
 			expectError: false,
 		},
 		{
-			name: "empty name",
+			name: "nice out of range",
 			req: &JobRequest{
-				Name:              "",
+				Name:              "Test Job",
 				Script:            "echo 'hello'",
 				TimeoutSeconds:    3600,
-				RetryCount:        0,
 				RetryDelaySeconds: 60,
+				NotifyOn:          "failure",
+				Nice:              20,
 			},
 			expectError:    true,
-			expectErrorMsg: "Name and script are required",
+			expectErrorMsg: "Nice value must be between 0 and 19",
 		},
 		{
-			name: "empty script",
+			name: "unknown interpreter",
 			req: &JobRequest{
 				Name:              "Test Job",
-				Script:            "",
+				Script:            "echo 'hello'",
 				TimeoutSeconds:    3600,
-				RetryCount:        0,
 				RetryDelaySeconds: 60,
+				NotifyOn:          "failure",
+				Interpreter:       "zsh",
 			},
 			expectError:    true,
-			expectErrorMsg: "Name and script are required",
+			expectErrorMsg: "Interpreter must be one of: bash, sh, cmd, powershell",
 		},
 		{
-			name: "name too long",
+			name: "timeout warning percent out of range",
 			req: &JobRequest{
-				Name:              "a" + string(make([]byte, 255)),
-				Script:            "echo 'hello'",
-				TimeoutSeconds:    3600,
-				RetryCount:        0,
-				RetryDelaySeconds: 60,
+				Name:               "Test Job",
+				Script:             "echo 'hello'",
+				TimeoutSeconds:     3600,
+				RetryDelaySeconds:  60,
+				NotifyOn:           "failure",
+				TimeoutWarnPercent: 100,
 			},
 			expectError:    true,
-			expectErrorMsg: "too long",
+			expectErrorMsg: "Timeout warning percent must be between 0 and 99",
 		},
 		{
-			name: "timeout too small",
+			name: "invalid failure pattern",
 			req: &JobRequest{
 				Name:              "Test Job",
 				Script:            "echo 'hello'",
-				TimeoutSeconds:    0, // Less than MinTimeoutSeconds (1)
-				RetryCount:        0,
+				TimeoutSeconds:    3600,
 				RetryDelaySeconds: 60,
+				NotifyOn:          "failure",
+				FailurePattern:    "ERROR(",
 			},
 			expectError:    true,
-			expectErrorMsg: "Timeout must be between",
+			expectErrorMsg: "Invalid failure pattern",
 		},
 		{
-			name: "timeout too large",
+			name: "non-string metadata value",
+			req: &JobRequest{
+				Name:              "Test Job",
+				Script:            "echo 'hello'",
+				TimeoutSeconds:    3600,
+				RetryDelaySeconds: 60,
+				NotifyOn:          "failure",
+				Metadata:          map[string]interface{}{"ticket": map[string]interface{}{"id": "OPS-1"}},
+			},
+			expectError:    true,
+			expectErrorMsg: "Invalid metadata: value for \"ticket\" must be a string",
+		},
+		{
+			name: "metadata key with spaces",
+			req: &JobRequest{
+				Name:              "Test Job",
+				Script:            "echo 'hello'",
+				TimeoutSeconds:    3600,
+				RetryDelaySeconds: 60,
+				NotifyOn:          "failure",
+				Metadata:          map[string]interface{}{"service name": "billing"},
+			},
+			expectError:    true,
+			expectErrorMsg: "Invalid metadata: key",
+		},
+		{
+			name: "empty name",
+			req: &JobRequest{
+				Name:              "",
+				Script:            "echo 'hello'",
+				TimeoutSeconds:    3600,
+				RetryCount:        0,
+				RetryDelaySeconds: 60,
+			},
+			expectError:    true,
+			expectErrorMsg: "Name and script are required",
+		},
+		{
+			name: "empty script",
+			req: &JobRequest{
+				Name:              "Test Job",
+				Script:            "",
+				TimeoutSeconds:    3600,
+				RetryCount:        0,
+				RetryDelaySeconds: 60,
+			},
+			expectError:    true,
+			expectErrorMsg: "Name and script are required",
+		},
+		{
+			name: "name too long",
+			req: &JobRequest{
+				Name:              "a" + string(make([]byte, 255)),
+				Script:            "echo 'hello'",
+				TimeoutSeconds:    3600,
+				RetryCount:        0,
+				RetryDelaySeconds: 60,
+			},
+			expectError:    true,
+			expectErrorMsg: "too long",
+		},
+		{
+			name: "timeout too small",
+			req: &JobRequest{
+				Name:              "Test Job",
+				Script:            "echo 'hello'",
+				TimeoutSeconds:    0, // Less than MinTimeoutSeconds (1)
+				RetryCount:        0,
+				RetryDelaySeconds: 60,
+			},
+			expectError:    true,
+			expectErrorMsg: "Timeout must be between",
+		},
+		{
+			name: "timeout too large",
 			req: &JobRequest{
 				Name:              "Test Job",
 				Script:            "echo 'hello'",
@@ -442,6 +1251,43 @@ func TestJobValidatorValidation(t *testing.T) {
 			expectError:    true,
 			expectErrorMsg: "Invalid notify_on value",
 		},
+		{
+			name: "valid notify_emails list",
+			req: &JobRequest{
+				Name:              "Test Job",
+				Script:            "echo 'hello'",
+				TimeoutSeconds:    3600,
+				RetryCount:        0,
+				RetryDelaySeconds: 60,
+				NotifyEmails:      "ops@example.com, dev@example.org",
+			},
+			expectError: false,
+		},
+		{
+			name: "notify_emails with one bad address",
+			req: &JobRequest{
+				Name:              "Test Job",
+				Script:            "echo 'hello'",
+				TimeoutSeconds:    3600,
+				RetryCount:        0,
+				RetryDelaySeconds: 60,
+				NotifyEmails:      "ops@example.com, dev.example.org",
+			},
+			expectError:    true,
+			expectErrorMsg: "dev.example.org",
+		},
+		{
+			name: "empty notify_emails is valid",
+			req: &JobRequest{
+				Name:              "Test Job",
+				Script:            "echo 'hello'",
+				TimeoutSeconds:    3600,
+				RetryCount:        0,
+				RetryDelaySeconds: 60,
+				NotifyEmails:      "",
+			},
+			expectError: false,
+		},
 		{
 			name: "empty notify_on is valid (uses default)",
 			req: &JobRequest{
@@ -526,9 +1372,59 @@ func TestJobValidatorToJobModel(t *testing.T) {
 	assert.Equal(t, "UTC", job.Timezone)
 }
 
+// TestJobValidatorWorkingDirRoots tests that working_dir must be under an allowed root when roots are set
+func TestJobValidatorWorkingDirRoots(t *testing.T) {
+	validator := NewJobValidator()
+	validate := func(dir string) *ValidationError {
+		return validator.ValidateJobRequest(&JobRequest{Name: "job", Script: "true", WorkingDir: dir, TimeoutSeconds: 60})
+	}
+
+	assert.Nil(t, validate("/etc"), "any directory is allowed without roots")
+
+	validator.SetAllowedWorkingDirRoots(parseRoots("/srv/jobs:/opt/scripts/"))
+	assert.Nil(t, validate("/srv/jobs"))
+	assert.Nil(t, validate("/srv/jobs/reports/daily"))
+	assert.Nil(t, validate("/opt/scripts"))
+
+	for _, dir := range []string{"/etc", "/srv/jobs-other", "/srv/jobs/../../etc", "srv/jobs", ""} {
+		validErr := validate(dir)
+		if assert.NotNil(t, validErr, "%q should be rejected", dir) {
+			assert.Equal(t, "VALIDATION_ERROR", validErr.Code)
+			assert.Contains(t, validErr.Message, "allowed root")
+		}
+	}
+
+	validator.SetAllowedWorkingDirRoots(parseRoots("/tmp"))
+	assert.Nil(t, validate(""), "an empty working_dir is checked as the /tmp default")
+}
+
+// TestJobValidatorMaxTimeout tests that a configured timeout ceiling below the built-in
+// maximum rejects longer timeouts
+func TestJobValidatorMaxTimeout(t *testing.T) {
+	validator := NewJobValidator()
+	validate := func(timeout int) *ValidationError {
+		return validator.ValidateJobRequest(&JobRequest{Name: "job", Script: "true", TimeoutSeconds: timeout})
+	}
+
+	assert.Nil(t, validate(7200), "the built-in maximum applies without a ceiling")
+
+	validator.SetMaxTimeoutSeconds(3600)
+	assert.Nil(t, validate(3600))
+	validErr := validate(7200)
+	if assert.NotNil(t, validErr, "7200s is under the built-in maximum but over the configured one") {
+		assert.Equal(t, "VALIDATION_ERROR", validErr.Code)
+		assert.Contains(t, validErr.Message, "between 1 and 3600 seconds")
+	}
+
+	// A ceiling above the built-in maximum cannot raise it
+	validator.SetMaxTimeoutSeconds(internal.MaxTimeoutSeconds * 2)
+	assert.NotNil(t, validate(internal.MaxTimeoutSeconds+1))
+}
+
 // TestScheduleValidatorValidation tests schedule validation
 func TestScheduleValidatorValidation(t *testing.T) {
 	validator := NewJobValidator()
+	inAnHour, aMinuteAgo := time.Now().Add(time.Hour), time.Now().Add(-time.Minute)
 
 	tests := []struct {
 		name           string
@@ -598,6 +1494,60 @@ func TestScheduleValidatorValidation(t *testing.T) {
 			expectError:    true,
 			expectErrorMsg: "Weekdays must be between 0-6",
 		},
+		{
+			name: "business days with holidays and a sunset anchor",
+			req: &ScheduleRequest{
+				Weekdays: []int{1, 2, 3, 4, 5},
+				Holidays: []string{"2024-12-25", "2025-01-01"},
+				Solar:    &store.SolarAnchor{Event: "sunset", Latitude: 51.5, Longitude: -0.13, OffsetMinutes: -15},
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid holiday date",
+			req: &ScheduleRequest{
+				Holidays: []string{"25/12/2024"},
+			},
+			expectError:    true,
+			expectErrorMsg: "must be a YYYY-MM-DD date",
+		},
+		{
+			name: "invalid solar event",
+			req: &ScheduleRequest{
+				Solar: &store.SolarAnchor{Event: "noon"},
+			},
+			expectError:    true,
+			expectErrorMsg: "Solar event must be sunrise or sunset",
+		},
+		{
+			name: "solar anchor with hours",
+			req: &ScheduleRequest{
+				Hours: []int{9},
+				Solar: &store.SolarAnchor{Event: "sunrise", Latitude: 40.7, Longitude: -74},
+			},
+			expectError:    true,
+			expectErrorMsg: "Hours and minutes must be empty",
+		},
+		{
+			name:        "future one-shot",
+			req:         &ScheduleRequest{RunAt: &inAnHour},
+			expectError: false,
+		},
+		{
+			name:           "past one-shot",
+			req:            &ScheduleRequest{RunAt: &aMinuteAgo},
+			expectError:    true,
+			expectErrorMsg: "run_at must be in the future",
+		},
+		{
+			name: "one-shot with recurring fields",
+			req: &ScheduleRequest{
+				Hours: []int{9},
+				RunAt: &inAnHour,
+			},
+			expectError:    true,
+			expectErrorMsg: "cannot be combined",
+		},
 	}
 
 	for _, tt := range tests {
@@ -612,3 +1562,1259 @@ func TestScheduleValidatorValidation(t *testing.T) {
 		})
 	}
 }
+
+// TestValidateScheduleMinInterval tests that schedules firing more often than the
+// configured floor are rejected and that the floor is off by default
+func TestValidateScheduleMinInterval(t *testing.T) {
+	everyMinute := &ScheduleRequest{}
+	everyFive := &ScheduleRequest{Minutes: []int{0, 5, 10, 15, 20, 25, 30, 35, 40, 45, 50, 55}}
+
+	validator := NewJobValidator()
+	assert.Nil(t, validator.ValidateScheduleRequest(everyMinute))
+
+	validator.SetMinScheduleIntervalMinutes(5)
+	err := validator.ValidateScheduleRequest(everyMinute)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Message, "as little as 1 minute(s) apart; the minimum interval is 5 minutes")
+	assert.Nil(t, validator.ValidateScheduleRequest(everyFive))
+
+	// Two fires a minute apart, even once an hour, break the floor
+	err = validator.ValidateScheduleRequest(&ScheduleRequest{Minutes: []int{0, 1}})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Message, "1 minute(s) apart")
+}
+
+// TestDashboardStatsSuccessRateWindow tests that the dashboard success rate is the SQL
+// aggregate over runs inside ?window=, not a sample of the latest runs
+func TestDashboardStatsSuccessRateWindow(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "nightly", Script: "true", Enabled: true})
+	require.NoError(t, err)
+
+	seed := func(status string, age time.Duration) {
+		run, err := testStore.CreateRun(job.ID, "manual")
+		require.NoError(t, err)
+		run.Status = status
+		require.NoError(t, testStore.UpdateRun(run))
+		_, err = testStore.DB().Exec(`UPDATE runs SET created_at = ? WHERE id = ?`, time.Now().Add(-age), run.ID)
+		require.NoError(t, err)
+	}
+	for i := 0; i < 3; i++ {
+		seed("success", time.Hour)
+	}
+	seed("failure", 2*time.Hour)
+	seed("timeout", 3*time.Hour)
+	seed("running", time.Minute)
+	for i := 0; i < 4; i++ {
+		seed("failure", 48*time.Hour)
+	}
+
+	dashboardHandlers := NewDashboardHandlers(testStore)
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/dashboard/stats"+query, nil)
+		w := httptest.NewRecorder()
+		dashboardHandlers.GetStats(w, req)
+		return w
+	}
+	successRate := func(query string) float64 {
+		w := get(query)
+		require.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data struct {
+				SuccessRate float64 `json:"success_rate"`
+				WindowRuns  int     `json:"window_runs"`
+				RunningNow  int     `json:"running_now"`
+				ActiveJobs  int     `json:"active_jobs"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 1, response.Data.RunningNow)
+		assert.Equal(t, 1, response.Data.ActiveJobs)
+		return response.Data.SuccessRate
+	}
+	sqlRate := func(window time.Duration) float64 {
+		var success, completed float64
+		require.NoError(t, testStore.DB().QueryRow(
+			`SELECT SUM(status = 'success'), COUNT(*) FROM runs
+			 WHERE status IN ('success', 'failure', 'timeout') AND created_at >= ?`,
+			time.Now().Add(-window),
+		).Scan(&success, &completed))
+		return success / completed
+	}
+
+	// Default 24h window: 3 successes out of 5 completed runs
+	assert.InDelta(t, 0.6, successRate(""), 1e-9)
+	assert.InDelta(t, sqlRate(24*time.Hour), successRate(""), 1e-9)
+
+	// Wider window includes the older failures: 3 out of 9
+	assert.InDelta(t, 3.0/9.0, successRate("?window=72h"), 1e-9)
+	assert.InDelta(t, sqlRate(72*time.Hour), successRate("?window=72h"), 1e-9)
+
+	assert.Equal(t, http.StatusBadRequest, get("?window=yesterday").Code)
+	assert.Equal(t, http.StatusBadRequest, get("?window=-1h").Code)
+}
+
+// TestGetRunReport tests that the run report bundles the run, job, logs and metrics with matching IDs
+func TestGetRunReport(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "backup", Script: "echo hi"})
+	require.NoError(t, err)
+	require.NoError(t, testStore.SetJobSchedule(job.ID, &store.Schedule{JobID: job.ID, Hours: []int{2}, Minutes: []int{0}}))
+	run, err := testStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+	run.Status = "success"
+	require.NoError(t, testStore.UpdateRun(run))
+
+	_, err = testStore.AddLog(run.ID, "stdout", "copying files")
+	require.NoError(t, err)
+	_, err = testStore.AddLog(run.ID, "stderr", "warning: slow disk")
+	require.NoError(t, err)
+	_, err = testStore.AddLog(run.ID, "stdout", "done")
+	require.NoError(t, err)
+	_, err = testStore.AddMetric(run.ID, 12.5, 3.0, 1024)
+	require.NoError(t, err)
+	_, err = testStore.AddMetric(run.ID, 40.0, 4.0, 4096)
+	require.NoError(t, err)
+
+	runHandlers := NewRunHandlers(testStore)
+	get := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/runs/"+id+"/report", nil)
+		req.SetPathValue("id", id)
+		w := httptest.NewRecorder()
+		runHandlers.GetRunReport(w, req)
+		return w
+	}
+
+	w := get(run.ID)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "run-"+run.ID+"-report.json")
+
+	var response struct {
+		Data RunReport `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	report := response.Data
+
+	require.NotNil(t, report.Run)
+	assert.Equal(t, run.ID, report.Run.ID)
+	require.NotNil(t, report.Job)
+	assert.Equal(t, job.ID, report.Job.ID)
+	assert.Equal(t, report.Run.JobID, report.Job.ID)
+	require.NotNil(t, report.Schedule)
+	assert.Equal(t, []int{2}, report.Schedule.Hours)
+
+	require.Len(t, report.Logs, 3)
+	for _, entry := range report.Logs {
+		assert.Equal(t, run.ID, entry.RunID)
+	}
+	assert.Equal(t, "copying files", report.Logs[0].Content)
+	require.Len(t, report.Metrics, 2)
+	for _, m := range report.Metrics {
+		assert.Equal(t, run.ID, m.RunID)
+	}
+
+	assert.Equal(t, 3, report.Stats.LogLines)
+	assert.Equal(t, map[string]int{"stdout": 2, "stderr": 1}, report.Stats.LinesByStream)
+	assert.Equal(t, 40.0, report.Stats.PeakCPUPercent)
+	assert.Equal(t, int64(4096), report.Stats.PeakMemoryBytes)
+	require.NotNil(t, report.JobStats)
+	assert.Equal(t, job.ID, report.JobStats.JobID)
+
+	assert.Equal(t, http.StatusNotFound, get("missing-run").Code)
+}
+
+// TestGetJobSLA tests the per-job SLA endpoint's counts and parameter validation
+func TestGetJobSLA(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "etl", Script: "true"})
+	require.NoError(t, err)
+	for _, status := range []string{"success", "success", "success", "failure", "running"} {
+		run, err := testStore.CreateRun(job.ID, "scheduled")
+		require.NoError(t, err)
+		run.Status = status
+		require.NoError(t, testStore.UpdateRun(run))
+	}
+
+	analyticsHandlers := NewAnalyticsHandlers(testStore)
+	get := func(id, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/jobs/"+id+"/sla"+query, nil)
+		req.SetPathValue("id", id)
+		w := httptest.NewRecorder()
+		analyticsHandlers.GetJobSLA(w, req)
+		return w
+	}
+
+	w := get(job.ID, "?days=7")
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data struct {
+			Days         int     `json:"days"`
+			TotalRuns    int     `json:"total_runs"`
+			SuccessCount int     `json:"success_count"`
+			FailureCount int     `json:"failure_count"`
+			SuccessRate  float64 `json:"success_rate"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 7, response.Data.Days)
+	assert.Equal(t, 4, response.Data.TotalRuns, "running run is excluded")
+	assert.Equal(t, 3, response.Data.SuccessCount)
+	assert.Equal(t, 1, response.Data.FailureCount)
+	assert.InDelta(t, 0.75, response.Data.SuccessRate, 1e-9)
+
+	assert.Equal(t, http.StatusBadRequest, get(job.ID, "?days=0").Code)
+	assert.Equal(t, http.StatusBadRequest, get(job.ID, "?days=abc").Code)
+	assert.Equal(t, http.StatusNotFound, get("missing", "").Code)
+}
+
+// TestGetJobOverlaps tests that overlapping runs are reported and non-overlapping ones are not
+func TestGetJobOverlaps(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "etl", Script: "true"})
+	require.NoError(t, err)
+	base := time.Now().Add(-time.Hour)
+	addRun := func(start, finish time.Duration) *store.Run {
+		run, err := testStore.CreateRun(job.ID, "scheduled")
+		require.NoError(t, err)
+		startedAt, finishedAt := base.Add(start), base.Add(finish)
+		run.Status = "success"
+		run.StartedAt = &startedAt
+		run.FinishedAt = &finishedAt
+		require.NoError(t, testStore.UpdateRun(run))
+		return run
+	}
+	first := addRun(0, 10*time.Minute)
+	second := addRun(8*time.Minute, 12*time.Minute)
+	addRun(20*time.Minute, 25*time.Minute)
+
+	analyticsHandlers := NewAnalyticsHandlers(testStore)
+	get := func(id, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/jobs/"+id+"/overlaps"+query, nil)
+		req.SetPathValue("id", id)
+		w := httptest.NewRecorder()
+		analyticsHandlers.GetJobOverlaps(w, req)
+		return w
+	}
+
+	w := get(job.ID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data struct {
+			Days     int                `json:"days"`
+			Count    int                `json:"count"`
+			Overlaps []store.RunOverlap `json:"overlaps"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 7, response.Data.Days)
+	require.Equal(t, 1, response.Data.Count)
+	assert.Equal(t, first.ID, response.Data.Overlaps[0].FirstRunID)
+	assert.Equal(t, second.ID, response.Data.Overlaps[0].SecondRunID)
+	assert.Equal(t, int64(2*time.Minute/time.Millisecond), response.Data.Overlaps[0].OverlapDurationMs)
+
+	assert.Equal(t, http.StatusBadRequest, get(job.ID, "?days=400").Code)
+	assert.Equal(t, http.StatusNotFound, get("missing", "").Code)
+}
+
+// TestJobMetadataRoundTripAndFilter tests that metadata is returned verbatim and filters the job list
+func TestJobMetadataRoundTripAndFilter(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+	jobHandlers := NewJobHandlers(testStore, nil)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/jobs", bytes.NewBufferString(body))
+		req.Header.Set("X-User-ID", "1")
+		req.Header.Set("X-User-Role", "admin")
+		w := httptest.NewRecorder()
+		jobHandlers.CreateJob(w, req)
+		return w
+	}
+	list := func(query string) []*store.Job {
+		req := httptest.NewRequest("GET", "/api/jobs"+query, nil)
+		req.Header.Set("X-User-ID", "1")
+		req.Header.Set("X-User-Role", "admin")
+		w := httptest.NewRecorder()
+		jobHandlers.ListJobs(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response struct {
+			Data struct {
+				Jobs []*store.Job `json:"jobs"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Data.Jobs
+	}
+
+	w := create(`{"name": "billing-sync", "script": "true", "timeout_seconds": 60, "metadata": {"service": "billing", "ticket.id": "OPS-42"}}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = create(`{"name": "search-reindex", "script": "true", "timeout_seconds": 60, "metadata": {"service": "search"}}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = create(`{"name": "untagged", "script": "true", "timeout_seconds": 60}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var created struct {
+		Data *store.Job `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Nil(t, created.Data.Metadata)
+
+	billing := list("?metadata.service=billing")
+	require.Len(t, billing, 1)
+	assert.Equal(t, "billing-sync", billing[0].Name)
+	assert.Equal(t, map[string]string{"service": "billing", "ticket.id": "OPS-42"}, billing[0].Metadata)
+
+	// Keys containing dots match literally rather than as nested paths
+	ticket := list("?metadata.ticket.id=OPS-42")
+	require.Len(t, ticket, 1)
+	assert.Equal(t, "billing-sync", ticket[0].Name)
+
+	assert.Empty(t, list("?metadata.service=payments"))
+	assert.Len(t, list(""), 3)
+
+	req := httptest.NewRequest("GET", "/api/jobs?metadata.a=1&metadata.b=2", nil)
+	req.Header.Set("X-User-Role", "admin")
+	rec := httptest.NewRecorder()
+	jobHandlers.ListJobs(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// TestCreateJobFromTemplate tests that an instantiated job inherits the template's fields
+// and schedule, with overrides from the request body
+func TestCreateJobFromTemplate(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+	jobHandlers := NewJobHandlers(testStore, nil)
+
+	do := func(handler http.HandlerFunc, method, target, id, role, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+		req.Header.Set("X-User-ID", "1")
+		req.Header.Set("X-User-Role", role)
+		if id != "" {
+			req.SetPathValue("id", id)
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	w := do(jobHandlers.CreateJobTemplate, "POST", "/api/job-templates", "", "admin", `{
+		"name": "nightly backup",
+		"definition": {
+			"script": "backup.sh",
+			"timeout_seconds": 600,
+			"retry_count": 2,
+			"notify_emails": "ops@example.com",
+			"notify_on": "failure",
+			"schedule": {"hours": [2], "minutes": [30]}
+		}
+	}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created struct {
+		Data store.JobTemplate `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	templateID := created.Data.ID
+
+	w = do(jobHandlers.CreateJobFromTemplate, "POST", "/api/job-templates/"+templateID+"/instantiate", templateID, "admin",
+		`{"name": "backup-db1", "retry_count": 0}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var response struct {
+		Data store.Job `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	job, err := testStore.GetJob(response.Data.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "backup-db1", job.Name)
+	assert.Equal(t, "backup.sh", job.Script)
+	assert.Equal(t, 600, job.TimeoutSeconds)
+	assert.Equal(t, 0, job.RetryCount, "override wins over the template")
+	assert.Equal(t, "ops@example.com", job.NotifyEmails)
+	assert.Equal(t, "failure", job.NotifyOn)
+	schedule, err := testStore.GetJobSchedule(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, schedule.Hours)
+	assert.Equal(t, []int{30}, schedule.Minutes)
+
+	// The template has no name, so instantiating without one fails validation
+	w = do(jobHandlers.CreateJobFromTemplate, "POST", "/api/job-templates/"+templateID+"/instantiate", templateID, "admin", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do(jobHandlers.CreateJobFromTemplate, "POST", "/api/job-templates/missing/instantiate", "missing", "admin", `{"name": "x"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = do(jobHandlers.CreateJobFromTemplate, "POST", "/api/job-templates/"+templateID+"/instantiate", templateID, "user", `{"name": "x"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = do(jobHandlers.CreateJobTemplate, "POST", "/api/job-templates", "", "admin",
+		`{"name": "bad", "definition": {"schedule": {"hours": [25]}}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = do(jobHandlers.CreateJobTemplate, "POST", "/api/job-templates", "", "admin", `{"name": "bad", "definition": []}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestUpdateJobReportsChanges tests that the update response lists exactly the fields that changed
+func TestUpdateJobReportsChanges(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+	jobHandlers := NewJobHandlers(testStore, nil)
+
+	req := httptest.NewRequest("POST", "/api/jobs", bytes.NewBufferString(
+		`{"name": "backup", "script": "echo v1", "timeout_seconds": 60, "enabled": true, "notify_on": "failure"}`))
+	req.Header.Set("X-User-ID", "1")
+	req.Header.Set("X-User-Role", "admin")
+	w := httptest.NewRecorder()
+	jobHandlers.CreateJob(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var created struct {
+		Data *store.Job `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	jobID := created.Data.ID
+
+	req = httptest.NewRequest("PUT", "/api/jobs/"+jobID, bytes.NewBufferString(
+		`{"name": "backup", "script": "echo v2", "timeout_seconds": 120, "enabled": true, "notify_on": "failure",
+		  "working_dir": "/tmp", "timezone": "UTC"}`))
+	req.SetPathValue("id", jobID)
+	req.Header.Set("X-User-Role", "admin")
+	w = httptest.NewRecorder()
+	jobHandlers.UpdateJob(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var updated struct {
+		Data struct {
+			Name    string        `json:"name"`
+			Script  string        `json:"script"`
+			Changes []FieldChange `json:"changes"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.Equal(t, "backup", updated.Data.Name)
+	assert.Equal(t, "echo v2", updated.Data.Script)
+	assert.Equal(t, []FieldChange{
+		{Field: "script", Old: "echo v1", New: "echo v2"},
+		{Field: "timeout_seconds", Old: float64(60), New: float64(120)},
+	}, updated.Data.Changes)
+
+	req = httptest.NewRequest("PUT", "/api/jobs/missing", bytes.NewBufferString(
+		`{"name": "backup", "script": "echo v2", "timeout_seconds": 120}`))
+	req.SetPathValue("id", "missing")
+	req.Header.Set("X-User-Role", "admin")
+	w = httptest.NewRecorder()
+	jobHandlers.UpdateJob(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// TestReadsHideOtherUsersResources tests that a non-admin gets 404, not 403, for another
+// user's job, run, logs, schedule and job analytics, while the owner and admins get 200
+func TestReadsHideOtherUsersResources(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "owned", Script: "echo hi", Enabled: true, CreatedBy: 2})
+	require.NoError(t, err)
+	run, err := testStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	jobHandlers := NewJobHandlers(testStore, nil)
+	runHandlers := NewRunHandlers(testStore)
+	scheduleHandlers := NewScheduleHandlers(testStore)
+	analyticsHandlers := NewAnalyticsHandlers(testStore)
+
+	get := func(handler http.HandlerFunc, id, userID, role string) int {
+		req := httptest.NewRequest("GET", "/api/"+id, nil)
+		req.SetPathValue("id", id)
+		req.Header.Set("X-User-ID", userID)
+		req.Header.Set("X-User-Role", role)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w.Code
+	}
+
+	reads := []struct {
+		name    string
+		handler http.HandlerFunc
+		id      string
+	}{
+		{"job", jobHandlers.GetJob, job.ID},
+		{"job detail", jobHandlers.GetJobDetail, job.ID},
+		{"schedule", scheduleHandlers.GetJobSchedule, job.ID},
+		{"run", runHandlers.GetRun, run.ID},
+		{"run logs", runHandlers.GetRunLogs, run.ID},
+		{"run report", runHandlers.GetRunReport, run.ID},
+		{"job SLA", analyticsHandlers.GetJobSLA, job.ID},
+		{"job overlaps", analyticsHandlers.GetJobOverlaps, job.ID},
+		{"job duration trends", analyticsHandlers.GetJobDurationTrends, job.ID},
+	}
+	for _, tt := range reads {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, http.StatusNotFound, get(tt.handler, tt.id, "3", "user"), "other user")
+			assert.Equal(t, http.StatusOK, get(tt.handler, tt.id, "2", "user"), "owner")
+			assert.Equal(t, http.StatusOK, get(tt.handler, tt.id, "1", "admin"), "admin")
+		})
+	}
+
+	req := httptest.NewRequest("GET", "/api/runs/logs?ids="+run.ID, nil)
+	req.Header.Set("X-User-ID", "3")
+	req.Header.Set("X-User-Role", "user")
+	w := httptest.NewRecorder()
+	runHandlers.GetBatchRunLogs(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var batch struct {
+		Data struct {
+			Missing []string `json:"missing"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &batch))
+	assert.Equal(t, []string{run.ID}, batch.Data.Missing)
+}
+
+// TestAnalyticsFilters tests that overview stats honor ?job_id and ?tag, scope non-admins
+// to their own jobs and reject malformed tags
+func TestAnalyticsFilters(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	mine, err := testStore.CreateJob(&store.Job{Name: "mine", Script: "true", CreatedBy: 2, Metadata: map[string]string{"team": "data"}})
+	require.NoError(t, err)
+	theirs, err := testStore.CreateJob(&store.Job{Name: "theirs", Script: "true", CreatedBy: 3})
+	require.NoError(t, err)
+	for _, jobID := range []string{mine.ID, theirs.ID, theirs.ID} {
+		run, err := testStore.CreateRun(jobID, "manual")
+		require.NoError(t, err)
+		run.Status = "success"
+		require.NoError(t, testStore.UpdateRun(run))
+	}
+
+	analyticsHandlers := NewAnalyticsHandlers(testStore)
+	overview := func(query, userID, role string) (int, map[string]interface{}) {
+		req := httptest.NewRequest("GET", "/api/analytics/overview"+query, nil)
+		req.Header.Set("X-User-ID", userID)
+		req.Header.Set("X-User-Role", role)
+		w := httptest.NewRecorder()
+		analyticsHandlers.GetOverallStats(w, req)
+		var response struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response.Data
+	}
+
+	code, stats := overview("", "1", "admin")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(3), stats["total_runs"])
+
+	_, stats = overview("?job_id="+theirs.ID, "1", "admin")
+	assert.Equal(t, float64(2), stats["total_runs"])
+
+	_, stats = overview("?tag=team=data", "1", "admin")
+	assert.Equal(t, float64(1), stats["total_runs"])
+
+	_, stats = overview("", "2", "user")
+	assert.Equal(t, float64(1), stats["total_runs"], "non-admins only see their own jobs")
+	assert.Equal(t, float64(1), stats["total_jobs"])
+
+	_, stats = overview("?job_id="+theirs.ID, "2", "user")
+	assert.Equal(t, float64(0), stats["total_runs"], "another user's job_id yields nothing")
+
+	code, _ = overview("?tag=team", "1", "admin")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+// TestCreateJobDuplicateName tests that duplicate names get 409 only when uniqueness is enforced
+func TestCreateJobDuplicateName(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+	jobHandlers := NewJobHandlers(testStore, nil)
+
+	create := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/jobs", bytes.NewBufferString(`{"name": "backup", "script": "true", "timeout_seconds": 60}`))
+		req.Header.Set("X-User-ID", "1")
+		req.Header.Set("X-User-Role", "admin")
+		w := httptest.NewRecorder()
+		jobHandlers.CreateJob(w, req)
+		return w
+	}
+
+	require.Equal(t, http.StatusCreated, create().Code)
+	require.Equal(t, http.StatusCreated, create().Code, "duplicates are allowed by default")
+
+	testStore.SetEnforceUniqueJobNames(true)
+	w := create()
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "DUPLICATE_NAME", response["code"])
+}
+
+// TestBackupRestore tests that a backup response can be posted back to restore as-is
+func TestBackupRestore(t *testing.T) {
+	src := store.NewTestStore(t)
+	defer src.Close()
+	job, err := src.CreateJob(&store.Job{Name: "nightly", Script: "true", TimeoutSeconds: 60})
+	require.NoError(t, err)
+	require.NoError(t, src.SetJobSchedule(job.ID, &store.Schedule{Hours: []int{3}, Minutes: []int{0}}))
+	require.NoError(t, src.SetSMTPSettings(&store.SMTPSettings{Server: "smtp.example.com", Password: "s3cret"}))
+
+	do := func(handler http.HandlerFunc, method, target, role, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+		req.Header.Set("X-User-ID", "1")
+		req.Header.Set("X-User-Role", role)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	srcHandlers := NewJobHandlers(src, nil)
+	assert.Equal(t, http.StatusForbidden, do(srcHandlers.Backup, "GET", "/api/admin/backup", "user", "").Code)
+
+	w := do(srcHandlers.Backup, "GET", "/api/admin/backup?include_secrets=true", "admin", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "taskflow-backup-")
+	backup := w.Body.String()
+
+	dst := store.NewTestStore(t)
+	defer dst.Close()
+	dstHandlers := NewJobHandlers(dst, nil)
+
+	assert.Equal(t, http.StatusBadRequest, do(dstHandlers.Restore, "POST", "/api/admin/restore", "admin", `{"version": 2}`).Code)
+
+	w = do(dstHandlers.Restore, "POST", "/api/admin/restore?dry_run=true", "admin", backup)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	_, err = dst.GetJob(job.ID)
+	assert.Error(t, err, "dry run must not restore anything")
+
+	w = do(dstHandlers.Restore, "POST", "/api/admin/restore", "admin", backup)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response struct {
+		Data store.RestoreResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Data.Jobs)
+
+	restored, err := dst.GetJob(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "nightly", restored.Name)
+	schedule, err := dst.GetJobSchedule(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3}, schedule.Hours)
+	smtp, err := dst.GetSMTPSettings()
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", smtp.Password, "secrets are restored when included")
+}
+
+// TestRestoreRejectsJobsTheAPIWouldRefuse tests that restored jobs and schedules go through
+// the job validator and safe mode, on dry runs too
+func TestRestoreRejectsJobsTheAPIWouldRefuse(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name     string
+		job      store.Job
+		schedule *store.Schedule
+		code     string
+	}{
+		{name: "working dir outside roots", job: store.Job{WorkingDir: "/etc"}, code: "VALIDATION_ERROR"},
+		{name: "timeout above maximum", job: store.Job{TimeoutSeconds: 7200}, code: "VALIDATION_ERROR"},
+		{name: "unsafe script", job: store.Job{Script: "rm -rf /"}, code: "UNSAFE_SCRIPT"},
+		{name: "past one-shot", schedule: &store.Schedule{RunAt: &past}, code: "VALIDATION_ERROR"},
+		{name: "schedule below minimum interval", schedule: &store.Schedule{}, code: "VALIDATION_ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testStore := store.NewTestStore(t)
+			defer testStore.Close()
+			handlers := NewJobHandlers(testStore, nil)
+			handlers.SetAllowedWorkingDirRoots("/srv")
+			handlers.SetMaxTimeoutSeconds(3600)
+			handlers.SetMinScheduleIntervalMinutes(5)
+			handlers.SetScriptGuard(NewScriptGuard(internal.SafeModeReject, DefaultScriptRules()))
+
+			job := tt.job
+			job.ID, job.Name = "job-1", "restored"
+			if job.Script == "" {
+				job.Script = "true"
+			}
+			if job.WorkingDir == "" {
+				job.WorkingDir = "/srv/app"
+			}
+			if job.TimeoutSeconds == 0 {
+				job.TimeoutSeconds = 60
+			}
+			body, err := json.Marshal(store.Backup{
+				Version: internal.BackupVersion,
+				Jobs:    []*store.BackupJob{{Job: job, Schedule: tt.schedule}},
+			})
+			require.NoError(t, err)
+
+			for _, target := range []string{"/api/admin/restore?dry_run=true", "/api/admin/restore"} {
+				req := httptest.NewRequest("POST", target, bytes.NewReader(body))
+				req.Header.Set("X-User-ID", "1")
+				req.Header.Set("X-User-Role", "admin")
+				w := httptest.NewRecorder()
+				handlers.Restore(w, req)
+
+				assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+				assert.Contains(t, w.Body.String(), tt.code)
+			}
+			_, err = testStore.GetJob("job-1")
+			assert.Error(t, err, "a refused backup must not restore anything")
+		})
+	}
+}
+
+// TestTriggerJobManualWhileScheduled tests each manual_while_scheduled outcome when a
+// manual trigger arrives while a scheduled run is pending
+func TestTriggerJobManualWhileScheduled(t *testing.T) {
+	tests := []struct {
+		policy          string
+		expectStatus    int
+		expectScheduled string // status of the pending scheduled run afterwards
+	}{
+		{policy: "", expectStatus: http.StatusCreated, expectScheduled: internal.JobStatusPending},
+		{policy: internal.ManualWhileScheduledAllow, expectStatus: http.StatusCreated, expectScheduled: internal.JobStatusPending},
+		{policy: internal.ManualWhileScheduledSkip, expectStatus: http.StatusConflict, expectScheduled: internal.JobStatusPending},
+		{policy: internal.ManualWhileScheduledReplace, expectStatus: http.StatusCreated, expectScheduled: internal.JobStatusCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run("policy "+tt.policy, func(t *testing.T) {
+			testStore := store.NewTestStore(t)
+			defer testStore.Close()
+
+			job, err := testStore.CreateJob(&store.Job{Name: "nightly", Script: "echo hi", Enabled: true, ManualWhileScheduled: tt.policy})
+			require.NoError(t, err)
+			scheduled, err := testStore.CreateRun(job.ID, internal.TriggerScheduled)
+			require.NoError(t, err)
+
+			jobHandlers := NewJobHandlers(testStore, scheduler.New(testStore))
+			req := httptest.NewRequest("POST", "/api/jobs/"+job.ID+"/run", nil)
+			req.SetPathValue("id", job.ID)
+			w := httptest.NewRecorder()
+			jobHandlers.TriggerJob(w, req)
+			assert.Equal(t, tt.expectStatus, w.Code, w.Body.String())
+
+			stored, err := testStore.GetRun(scheduled.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectScheduled, stored.Status)
+
+			runs, err := testStore.ListRuns(store.RunFilter{JobID: job.ID}, 10, 0)
+			require.NoError(t, err)
+			if tt.expectStatus == http.StatusCreated {
+				assert.Len(t, runs, 2, "manual run created")
+			} else {
+				assert.Len(t, runs, 1, "manual trigger skipped")
+			}
+		})
+	}
+}
+
+// TestTriggerJobReplaceWhileScheduledRunning tests that replace cannot stop a scheduled run
+// that already started, so the manual run is queued behind it
+func TestTriggerJobReplaceWhileScheduledRunning(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "nightly", Script: "echo hi", Enabled: true, ManualWhileScheduled: internal.ManualWhileScheduledReplace})
+	require.NoError(t, err)
+	scheduled, err := testStore.CreateRun(job.ID, internal.TriggerScheduled)
+	require.NoError(t, err)
+	now := time.Now()
+	scheduled.Status = internal.JobStatusRunning
+	scheduled.StartedAt = &now
+	require.NoError(t, testStore.UpdateRun(scheduled))
+
+	jobHandlers := NewJobHandlers(testStore, scheduler.New(testStore))
+	req := httptest.NewRequest("POST", "/api/jobs/"+job.ID+"/run", nil)
+	req.SetPathValue("id", job.ID)
+	w := httptest.NewRecorder()
+	jobHandlers.TriggerJob(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	stored, err := testStore.GetRun(scheduled.ID)
+	require.NoError(t, err)
+	assert.Equal(t, internal.JobStatusRunning, stored.Status)
+}
+
+// TestListActiveRuns tests that all in-flight runs are returned, limited to the caller's
+// own jobs for non-admins
+func TestListActiveRuns(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	mine, err := testStore.CreateJob(&store.Job{Name: "mine", Script: "true", CreatedBy: 2})
+	require.NoError(t, err)
+	theirs, err := testStore.CreateJob(&store.Job{Name: "theirs", Script: "true", CreatedBy: 3})
+	require.NoError(t, err)
+	now := time.Now()
+	for _, job := range []*store.Job{mine, theirs, mine} {
+		run, err := testStore.CreateRun(job.ID, internal.TriggerScheduled)
+		require.NoError(t, err)
+		run.Status = internal.JobStatusRunning
+		run.StartedAt = &now
+		require.NoError(t, testStore.UpdateRun(run))
+	}
+
+	runHandlers := NewRunHandlers(testStore)
+	list := func(userID, role string) []store.ActiveRun {
+		req := httptest.NewRequest("GET", "/api/runs/active", nil)
+		req.Header.Set("X-User-ID", userID)
+		req.Header.Set("X-User-Role", role)
+		w := httptest.NewRecorder()
+		runHandlers.ListActiveRuns(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data struct {
+				Runs  []store.ActiveRun `json:"runs"`
+				Total int               `json:"total"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, len(response.Data.Runs), response.Data.Total)
+		return response.Data.Runs
+	}
+
+	all := list("1", "admin")
+	assert.Len(t, all, 3)
+	for _, run := range all {
+		assert.Equal(t, internal.JobStatusRunning, run.Status)
+		assert.NotEmpty(t, run.JobName)
+	}
+
+	own := list("2", "user")
+	require.Len(t, own, 2)
+	for _, run := range own {
+		assert.Equal(t, "mine", run.JobName)
+	}
+}
+
+// TestTriggerJobTagsFilterRuns tests that tags sent with a trigger are stored on the run
+// and that GET /api/runs?tag= returns only runs carrying the tag
+func TestTriggerJobTagsFilterRuns(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "deploy", Script: "true", Enabled: true})
+	require.NoError(t, err)
+
+	jobHandlers := NewJobHandlers(testStore, scheduler.New(testStore))
+	trigger := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/jobs/"+job.ID+"/run", bytes.NewBufferString(body))
+		req.SetPathValue("id", job.ID)
+		w := httptest.NewRecorder()
+		jobHandlers.TriggerJob(w, req)
+		return w
+	}
+
+	w := trigger(`{"tags": ["prod-deploy", "hotfix", "hotfix"]}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created struct {
+		Data store.Run `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, []string{"prod-deploy", "hotfix"}, created.Data.Tags, "duplicates dropped")
+
+	require.Equal(t, http.StatusCreated, trigger("").Code, "body is optional")
+	require.Equal(t, http.StatusCreated, trigger(`{"tags": ["prod-deploy"]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, trigger(`{"tags": ["has space"]}`).Code)
+
+	runHandlers := NewRunHandlers(testStore)
+	list := func(query string) []store.Run {
+		req := httptest.NewRequest("GET", "/api/runs?"+query, nil)
+		w := httptest.NewRecorder()
+		runHandlers.ListRuns(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data struct {
+				Runs []store.Run `json:"runs"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Data.Runs
+	}
+
+	assert.Len(t, list(""), 3)
+	assert.Len(t, list("tag=prod-deploy"), 2)
+	hotfix := list("tag=hotfix")
+	require.Len(t, hotfix, 1)
+	assert.Equal(t, created.Data.ID, hotfix[0].ID)
+	assert.Len(t, list("tag=hotfix&after_id="), 1, "cursor pagination applies the tag filter")
+	assert.Empty(t, list("tag=prod"), "tags match exactly")
+}
+
+// TestListRunsCursorTotal tests that cursor pagination reports the number of
+// matching runs rather than the size of the page
+func TestListRunsCursorTotal(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "cursor-total", Script: "true", TimeoutSeconds: 60})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := testStore.CreateRun(job.ID, "manual")
+		require.NoError(t, err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/runs?after_id=&limit=1", nil)
+	w := httptest.NewRecorder()
+	NewRunHandlers(testStore).ListRuns(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data struct {
+			Runs  []store.Run `json:"runs"`
+			Total int         `json:"total"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Data.Runs, 1)
+	assert.Equal(t, 3, response.Data.Total)
+}
+
+// TestTriggerJobMinInterval tests that a rapid second manual trigger is refused with the
+// existing run and that a trigger after the interval succeeds
+func TestTriggerJobMinInterval(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+	testStore.SetManualTriggerInterval(200 * time.Millisecond)
+
+	job, err := testStore.CreateJob(&store.Job{Name: "deploy", Script: "true", Enabled: true})
+	require.NoError(t, err)
+	// Scheduled runs don't count towards the interval
+	_, err = testStore.CreateRun(job.ID, internal.TriggerScheduled)
+	require.NoError(t, err)
+
+	jobHandlers := NewJobHandlers(testStore, scheduler.New(testStore))
+	trigger := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/jobs/"+job.ID+"/run", nil)
+		req.SetPathValue("id", job.ID)
+		w := httptest.NewRecorder()
+		jobHandlers.TriggerJob(w, req)
+		return w
+	}
+
+	first := trigger()
+	require.Equal(t, http.StatusCreated, first.Code, first.Body.String())
+	var created struct {
+		Data store.Run `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(first.Body.Bytes(), &created))
+
+	w := trigger()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	var rejected struct {
+		Data store.Run `json:"data"`
+		Code string    `json:"code"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rejected))
+	assert.Equal(t, "TRIGGER_TOO_SOON", rejected.Code)
+	assert.Equal(t, created.Data.ID, rejected.Data.ID, "the existing run is returned")
+
+	time.Sleep(250 * time.Millisecond)
+	assert.Equal(t, http.StatusCreated, trigger().Code)
+
+	runs, err := testStore.ListRuns(store.RunFilter{JobID: job.ID}, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, runs, 3)
+}
+
+// TestListUpcoming tests that upcoming fires of several jobs are merged in chronological order
+func TestListUpcoming(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	// :15 in UTC, and 00 minutes in Kolkata (UTC+05:30), which is :30 in UTC
+	_, err := testStore.CreateJobWithSchedule(
+		&store.Job{Name: "utc", Script: "true", Enabled: true, ScheduleEnabled: true, Timezone: "UTC"},
+		&store.Schedule{Minutes: []int{15}},
+	)
+	require.NoError(t, err)
+	_, err = testStore.CreateJobWithSchedule(
+		&store.Job{Name: "kolkata", Script: "true", Enabled: true, ScheduleEnabled: true, Timezone: "Asia/Kolkata"},
+		&store.Schedule{Minutes: []int{0}},
+	)
+	require.NoError(t, err)
+	_, err = testStore.CreateJobWithSchedule(
+		&store.Job{Name: "disabled", Script: "true", Enabled: false, ScheduleEnabled: true, Timezone: "UTC"},
+		&store.Schedule{Minutes: []int{45}},
+	)
+	require.NoError(t, err)
+
+	scheduleHandlers := NewScheduleHandlers(testStore)
+	list := func(query string) ([]UpcomingRun, bool) {
+		req := httptest.NewRequest("GET", "/api/schedule/upcoming?"+query, nil)
+		req.Header.Set("X-User-ID", "1")
+		req.Header.Set("X-User-Role", "admin")
+		w := httptest.NewRecorder()
+		scheduleHandlers.ListUpcoming(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response struct {
+			Data struct {
+				Upcoming  []UpcomingRun `json:"upcoming"`
+				Truncated bool          `json:"truncated"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Data.Upcoming, response.Data.Truncated
+	}
+
+	upcoming, truncated := list("within=3h")
+	assert.False(t, truncated)
+	require.GreaterOrEqual(t, len(upcoming), 5)
+	seen := map[string]int{}
+	for i, entry := range upcoming {
+		seen[entry.JobName]++
+		if i > 0 {
+			assert.True(t, upcoming[i-1].FireAt.Before(entry.FireAt), "entries are in chronological order")
+			assert.NotEqual(t, upcoming[i-1].JobName, entry.JobName, "the two hourly jobs alternate")
+		}
+		switch entry.JobName {
+		case "utc":
+			assert.Equal(t, 15, entry.FireAt.UTC().Minute())
+		case "kolkata":
+			assert.Equal(t, 30, entry.FireAt.UTC().Minute(), "matched in the job's timezone")
+		}
+	}
+	assert.Zero(t, seen["disabled"])
+	assert.GreaterOrEqual(t, seen["utc"], 2)
+	assert.GreaterOrEqual(t, seen["kolkata"], 2)
+
+	capped, truncated := list("within=3h&limit=2")
+	assert.True(t, truncated)
+	assert.Equal(t, upcoming[:2], capped)
+
+	req := httptest.NewRequest("GET", "/api/schedule/upcoming?within=720h", nil)
+	w := httptest.NewRecorder()
+	scheduleHandlers.ListUpcoming(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestAcknowledgeRun tests that acking records the user and time and that
+// ?acknowledged=false leaves acked runs out
+func TestAcknowledgeRun(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "backup", Script: "false", CreatedBy: 2})
+	require.NoError(t, err)
+	now := time.Now()
+	var failed []*store.Run
+	for i := 0; i < 2; i++ {
+		run, err := testStore.CreateRun(job.ID, internal.TriggerScheduled)
+		require.NoError(t, err)
+		run.Status = internal.JobStatusFailure
+		run.StartedAt = &now
+		run.FinishedAt = &now
+		require.NoError(t, testStore.UpdateRun(run))
+		failed = append(failed, run)
+	}
+	pending, err := testStore.CreateRun(job.ID, internal.TriggerManual)
+	require.NoError(t, err)
+
+	runHandlers := NewRunHandlers(testStore)
+	ack := func(runID, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/runs/"+runID+"/ack", nil)
+		req.SetPathValue("id", runID)
+		req.Header.Set("X-User-ID", userID)
+		req.Header.Set("X-User-Role", "user")
+		w := httptest.NewRecorder()
+		runHandlers.AcknowledgeRun(w, req)
+		return w
+	}
+
+	w := ack(failed[0].ID, "2")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response struct {
+		Data store.Run `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.NotNil(t, response.Data.AcknowledgedBy)
+	assert.Equal(t, 2, *response.Data.AcknowledgedBy)
+	require.NotNil(t, response.Data.AcknowledgedAt)
+	assert.WithinDuration(t, time.Now(), *response.Data.AcknowledgedAt, time.Minute)
+
+	assert.Equal(t, http.StatusConflict, ack(failed[0].ID, "2").Code, "a run is acknowledged once")
+	assert.Equal(t, http.StatusBadRequest, ack(pending.ID, "2").Code, "unfinished runs cannot be acknowledged")
+	assert.Equal(t, http.StatusNotFound, ack(failed[1].ID, "3").Code, "other users' runs are hidden")
+
+	list := func(query string) []string {
+		req := httptest.NewRequest("GET", "/api/runs?"+query, nil)
+		w := httptest.NewRecorder()
+		runHandlers.ListRuns(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data struct {
+				Runs []store.Run `json:"runs"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		ids := make([]string, 0, len(response.Data.Runs))
+		for _, run := range response.Data.Runs {
+			ids = append(ids, run.ID)
+		}
+		return ids
+	}
+
+	unacked := list("acknowledged=false")
+	assert.ElementsMatch(t, []string{failed[1].ID, pending.ID}, unacked)
+	assert.Equal(t, []string{failed[0].ID}, list("acknowledged=true"))
+	assert.Equal(t, []string{failed[1].ID}, list("acknowledged=false&after_id="+pending.ID), "filter applies with cursor pagination")
+}
+
+// TestListRunsCollapsesAttempts tests that retries are hidden from the run list unless
+// include_attempts=true and that GET /api/runs/{id}/attempts lists the whole family
+func TestListRunsCollapsesAttempts(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "flaky", Script: "true"})
+	require.NoError(t, err)
+	first, err := testStore.CreateRun(job.ID, internal.TriggerScheduled)
+	require.NoError(t, err)
+	second, err := testStore.CreateRetryRun(first, 2)
+	require.NoError(t, err)
+	third, err := testStore.CreateRetryRun(second, 3)
+	require.NoError(t, err)
+
+	runHandlers := NewRunHandlers(testStore)
+	get := func(handler http.HandlerFunc, target, id string) map[string]json.RawMessage {
+		req := httptest.NewRequest("GET", target, nil)
+		if id != "" {
+			req.SetPathValue("id", id)
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response struct {
+			Data map[string]json.RawMessage `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Data
+	}
+	runIDs := func(raw json.RawMessage) []string {
+		var runs []store.Run
+		require.NoError(t, json.Unmarshal(raw, &runs))
+		ids := make([]string, len(runs))
+		for i, run := range runs {
+			ids[i] = run.ID
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{first.ID}, runIDs(get(runHandlers.ListRuns, "/api/runs", "")["runs"]))
+	assert.ElementsMatch(t, []string{first.ID, second.ID, third.ID},
+		runIDs(get(runHandlers.ListRuns, "/api/runs?include_attempts=true", "")["runs"]))
+
+	// Any attempt's ID resolves to the whole family, in attempt order
+	assert.Equal(t, []string{first.ID, second.ID, third.ID},
+		runIDs(get(runHandlers.ListRunAttempts, "/api/runs/"+third.ID+"/attempts", third.ID)["attempts"]))
+
+	require.NoError(t, testStore.DeleteRun(first.ID))
+	_, err = testStore.GetRun(third.ID)
+	assert.Error(t, err, "deleting a run deletes its attempts")
+}
+
+// TestTestJobUsesConfiguredExecutor tests that test runs go through the executor set on
+// the handlers, so its broadcasters and limits apply
+func TestTestJobUsesConfiguredExecutor(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "probe", Script: "echo hello", TimeoutSeconds: 60, Enabled: true})
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var lines []string
+	exec := executor.New(testStore)
+	exec.SetLogBroadcaster(func(runID string, id int, stream, content string, timestamp time.Time) {
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, content)
+	})
+
+	jobHandlers := NewJobHandlers(testStore, nil)
+	jobHandlers.SetExecutor(exec)
+	req := httptest.NewRequest("POST", "/api/jobs/"+job.ID+"/test", nil)
+	req.Header.Set("X-User-ID", "1")
+	req.Header.Set("X-User-Role", "admin")
+	req.SetPathValue("id", job.ID)
+	w := httptest.NewRecorder()
+	jobHandlers.TestJob(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, lines, "hello", "output reaches the configured log broadcaster")
+}
+
+// TestTestJobRunsInScratchDir tests that a test run executes in a temporary directory that
+// is removed afterwards, is flagged as a test run and stays out of analytics
+func TestTestJobRunsInScratchDir(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	realDir := t.TempDir()
+	job, err := testStore.CreateJob(&store.Job{
+		Name:                     "cleanup",
+		Script:                   "pwd; touch created.txt; echo oops >&2; exit 3",
+		WorkingDir:               realDir,
+		TimeoutSeconds:           3600,
+		AutoDisableAfterFailures: 1,
+		Enabled:                  true,
+	})
+	require.NoError(t, err)
+
+	jobHandlers := NewJobHandlers(testStore, nil)
+	req := httptest.NewRequest("POST", "/api/jobs/"+job.ID+"/test", nil)
+	req.Header.Set("X-User-ID", "1")
+	req.Header.Set("X-User-Role", "admin")
+	req.SetPathValue("id", job.ID)
+	w := httptest.NewRecorder()
+	jobHandlers.TestJob(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		Data TestRunResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	run := resp.Data.Run
+	assert.Equal(t, internal.TriggerTest, run.TriggerType)
+	assert.Equal(t, internal.JobStatusFailure, run.Status)
+	require.NotNil(t, run.ExitCode)
+	assert.Equal(t, 3, *run.ExitCode)
+
+	var scratchDir string
+	var sawStderr bool
+	for _, entry := range resp.Data.Logs {
+		if entry.Stream == "stdout" && scratchDir == "" {
+			scratchDir = entry.Content
+		}
+		if entry.Stream == "stderr" && entry.Content == "oops" {
+			sawStderr = true
+		}
+	}
+	assert.True(t, sawStderr, "stderr is returned")
+	require.NotEmpty(t, scratchDir)
+	assert.NotEqual(t, realDir, scratchDir)
+	assert.NoDirExists(t, scratchDir, "scratch directory is removed after the run")
+	assert.NoFileExists(t, filepath.Join(realDir, "created.txt"), "the real working directory is untouched")
+
+	// The failure neither counts toward analytics nor auto-disables the job
+	stats, err := testStore.GetJobStatsForJob(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.TotalRuns)
+	stored, err := testStore.GetJob(job.ID)
+	require.NoError(t, err)
+	assert.True(t, stored.Enabled)
+	assert.Equal(t, 0, stored.ConsecutiveFailures)
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	internal "github.com/taskflow/taskflow/internal"
+	"github.com/taskflow/taskflow/internal/scheduler"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// ComponentHealth is the status of one subsystem in the detailed health report
+type ComponentHealth struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"` // internal.HealthStatusOK, HealthStatusDegraded or HealthStatusDown
+	Message string `json:"message"`
+}
+
+// HealthHandlers reports per-component health for admins
+type HealthHandlers struct {
+	store     *store.Store
+	scheduler *scheduler.Scheduler
+}
+
+// NewHealthHandlers creates detailed health handlers
+func NewHealthHandlers(st *store.Store, sched *scheduler.Scheduler) *HealthHandlers {
+	return &HealthHandlers{store: st, scheduler: sched}
+}
+
+// GetDetailedHealth handles GET /api/admin/health/detailed
+// Reports the database, scheduler, queue, active runs and disk space separately; the
+// overall status is the worst of them.
+func (h *HealthHandlers) GetDetailedHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
+		return
+	}
+
+	components := []ComponentHealth{
+		h.databaseHealth(r.Context()),
+		h.schedulerHealth(time.Now()),
+		h.queueHealth(),
+		h.activeRunsHealth(),
+		h.diskHealth(),
+	}
+
+	overall := internal.HealthStatusOK
+	for _, c := range components {
+		if healthRank(c.Status) > healthRank(overall) {
+			overall = c.Status
+		}
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"status":     overall,
+		"components": components,
+	})
+}
+
+// healthRank orders statuses from best to worst
+func healthRank(status string) int {
+	switch status {
+	case internal.HealthStatusDown:
+		return 2
+	case internal.HealthStatusDegraded:
+		return 1
+	}
+	return 0
+}
+
+func (h *HealthHandlers) databaseHealth(ctx context.Context) ComponentHealth {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if err := h.store.Ping(ctx); err != nil {
+		return ComponentHealth{Name: "database", Status: internal.HealthStatusDown, Message: fmt.Sprintf("ping failed: %v", err)}
+	}
+	return ComponentHealth{Name: "database", Status: internal.HealthStatusOK, Message: fmt.Sprintf("ping took %s", time.Since(start).Round(time.Microsecond))}
+}
+
+// schedulerHealth is degraded when the scheduler is stopped or its last tick is more than
+// internal.MissedTickFactor intervals old
+func (h *HealthHandlers) schedulerHealth(now time.Time) ComponentHealth {
+	if !h.scheduler.IsRunning() {
+		return ComponentHealth{Name: "scheduler", Status: internal.HealthStatusDegraded, Message: "scheduler is not running; scheduled jobs will not fire"}
+	}
+
+	stats := h.scheduler.TickStats()
+	if stats.LastTickAt == nil {
+		return ComponentHealth{Name: "scheduler", Status: internal.HealthStatusOK, Message: "running; no tick yet"}
+	}
+	age := now.Sub(*stats.LastTickAt)
+	if age > time.Duration(internal.MissedTickFactor)*internal.SchedulerCheckInterval {
+		return ComponentHealth{Name: "scheduler", Status: internal.HealthStatusDegraded, Message: fmt.Sprintf("last tick was %s ago", age.Round(time.Second))}
+	}
+	return ComponentHealth{Name: "scheduler", Status: internal.HealthStatusOK, Message: fmt.Sprintf("last tick %s ago, took %dms", age.Round(time.Second), stats.LastDurationMs)}
+}
+
+// queueHealth is degraded when the queue is full, since new runs then block or are turned away
+func (h *HealthHandlers) queueHealth() ComponentHealth {
+	depth := h.scheduler.QueueLength()
+	msg := fmt.Sprintf("%d of %d queued", depth, internal.JobQueueChannelSize)
+	if depth >= internal.JobQueueChannelSize {
+		return ComponentHealth{Name: "queue", Status: internal.HealthStatusDegraded, Message: msg + "; the queue is full"}
+	}
+	return ComponentHealth{Name: "queue", Status: internal.HealthStatusOK, Message: msg}
+}
+
+func (h *HealthHandlers) activeRunsHealth() ComponentHealth {
+	active, err := h.store.ListRunningRuns()
+	if err != nil {
+		return ComponentHealth{Name: "active_runs", Status: internal.HealthStatusDown, Message: fmt.Sprintf("failed to list active runs: %v", err)}
+	}
+	return ComponentHealth{Name: "active_runs", Status: internal.HealthStatusOK, Message: fmt.Sprintf("%d pending or running", len(active))}
+}
+
+// diskHealth checks the free space on the filesystem holding the database file
+func (h *HealthHandlers) diskHealth() ComponentHealth {
+	path := h.store.Path()
+	if path == "" || path == ":memory:" {
+		return ComponentHealth{Name: "disk", Status: internal.HealthStatusOK, Message: "in-memory database; no disk to check"}
+	}
+
+	free, err := freeDiskBytes(filepath.Dir(path))
+	if err != nil {
+		return ComponentHealth{Name: "disk", Status: internal.HealthStatusDegraded, Message: fmt.Sprintf("could not check free space: %v", err)}
+	}
+
+	msg := fmt.Sprintf("%d MB free for %s", free>>20, path)
+	switch {
+	case free < internal.HealthDiskDownBytes:
+		return ComponentHealth{Name: "disk", Status: internal.HealthStatusDown, Message: msg}
+	case free < internal.HealthDiskDegradedBytes:
+		return ComponentHealth{Name: "disk", Status: internal.HealthStatusDegraded, Message: msg}
+	}
+	return ComponentHealth{Name: "disk", Status: internal.HealthStatusOK, Message: msg}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taskflow/taskflow/internal/scheduler"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// TestDetailedHealthStoppedScheduler tests that a stopped scheduler reports degraded
+// while the database reports ok, and that the overall status follows the worst component
+func TestDetailedHealthStoppedScheduler(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	handlers := NewHealthHandlers(testStore, scheduler.New(testStore))
+
+	get := func(role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/admin/health/detailed", nil)
+		req.Header.Set("X-User-Role", role)
+		w := httptest.NewRecorder()
+		handlers.GetDetailedHealth(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusForbidden, get("user").Code)
+
+	w := get("admin")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Data struct {
+			Status     string            `json:"status"`
+			Components []ComponentHealth `json:"components"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	statuses := make(map[string]string)
+	for _, c := range resp.Data.Components {
+		statuses[c.Name] = c.Status
+		assert.NotEmpty(t, c.Message, c.Name)
+	}
+	assert.Equal(t, "ok", statuses["database"])
+	assert.Equal(t, "degraded", statuses["scheduler"])
+	assert.Equal(t, "ok", statuses["queue"])
+	assert.Equal(t, "ok", statuses["active_runs"])
+	assert.Equal(t, "ok", statuses["disk"])
+	assert.Equal(t, "degraded", resp.Data.Status)
+}
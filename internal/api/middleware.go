@@ -1,10 +1,15 @@
 package api
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/gorilla/websocket"
+	internal "github.com/taskflow/taskflow/internal"
 	"github.com/taskflow/taskflow/internal/auth"
 	"github.com/taskflow/taskflow/internal/store"
 )
@@ -23,13 +28,13 @@ func AuthMiddleware(jwtManager *auth.JWTManager, store *store.Store) func(http.H
 			// Extract token from Authorization header
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				WriteError(w, http.StatusUnauthorized, "Missing authorization header", "INVALID_TOKEN")
+				WriteError(w, http.StatusUnauthorized, "Missing authorization header", "TOKEN_INVALID")
 				return
 			}
 
 			parts := strings.Fields(authHeader)
 			if len(parts) != 2 || parts[0] != "Bearer" {
-				WriteError(w, http.StatusUnauthorized, "Invalid authorization header", "INVALID_TOKEN")
+				WriteError(w, http.StatusUnauthorized, "Invalid authorization header", "TOKEN_INVALID")
 				return
 			}
 
@@ -37,15 +42,19 @@ func AuthMiddleware(jwtManager *auth.JWTManager, store *store.Store) func(http.H
 
 			// Validate token
 			claims, err := jwtManager.ValidateToken(token)
+			if errors.Is(err, auth.ErrTokenExpired) {
+				WriteError(w, http.StatusUnauthorized, "Token has expired", "TOKEN_EXPIRED")
+				return
+			}
 			if err != nil {
-				WriteError(w, http.StatusUnauthorized, "Invalid token", "INVALID_TOKEN")
+				WriteError(w, http.StatusUnauthorized, "Invalid token", "TOKEN_INVALID")
 				return
 			}
 
 			// Get user from database
 			user, err := store.GetUser(claims.UserID)
 			if err != nil {
-				WriteError(w, http.StatusUnauthorized, "User not found", "INVALID_TOKEN")
+				WriteError(w, http.StatusUnauthorized, "User not found", "TOKEN_INVALID")
 				return
 			}
 
@@ -58,10 +67,48 @@ func AuthMiddleware(jwtManager *auth.JWTManager, store *store.Store) func(http.H
 	}
 }
 
-// RequestBodyLimitMiddleware limits the size of incoming request bodies
+// MaintenanceMiddleware rejects non-admin requests with 503 while maintenance mode is on.
+// Must run after AuthMiddleware so the user's role is known.
+func MaintenanceMiddleware(store *store.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+				enabled, err := store.IsMaintenanceMode()
+				if err != nil {
+					log.Printf("Failed to read maintenance mode: %v", err)
+				} else if enabled {
+					w.Header().Set("Retry-After", strconv.Itoa(internal.MaintenanceRetryAfterSeconds))
+					WriteError(w, http.StatusServiceUnavailable, "TaskFlow is down for maintenance", "MAINTENANCE")
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// TokenQueryMiddleware lets clients that cannot set headers (browser WebSockets)
+// pass their JWT as a "token" query parameter. Place it before AuthMiddleware.
+func TokenQueryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.URL.Query().Get("token"); token != "" && r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequestBodyLimitMiddleware limits the size of incoming request bodies to maxBytes.
+// A larger Content-Length is answered with 413 at once; any other body is cut off as the
+// handler reads it, and WriteBodyError turns that into a 413 as well.
 func RequestBodyLimitMiddleware(maxBytes int64) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				writeBodyTooLarge(w, maxBytes)
+				return
+			}
 			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
 			next.ServeHTTP(w, r)
 		})
@@ -90,3 +137,46 @@ func CORSMiddleware(allowedOrigins string) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// statusRecorder wraps http.ResponseWriter to capture the response status code
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code before delegating
+func (sr *statusRecorder) WriteHeader(code int) {
+	sr.status = code
+	sr.ResponseWriter.WriteHeader(code)
+}
+
+// Flush forwards to the underlying writer when it supports flushing
+func (sr *statusRecorder) Flush() {
+	if f, ok := sr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// LoggingMiddleware logs method, path, status, and duration for each request.
+// WebSocket upgrades are passed through untouched since they are long-lived.
+func LoggingMiddleware(logger *log.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if websocket.IsWebSocketUpgrade(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			logger.Printf("%s %s %d %s", r.Method, r.URL.Path, recorder.status, time.Since(start))
+		})
+	}
+}
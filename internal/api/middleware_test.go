@@ -1,18 +1,58 @@
 package api
 
 import (
+	"bytes"
+	"encoding/json"
+	"log"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	internal "github.com/taskflow/taskflow/internal"
 	"github.com/taskflow/taskflow/internal/auth"
+	"github.com/taskflow/taskflow/internal/scheduler"
 	"github.com/taskflow/taskflow/internal/store"
 )
 
+// TestAuthMiddlewareTokenErrorCodes tests that expired and invalid tokens get distinct error codes
+func TestAuthMiddlewareTokenErrorCodes(t *testing.T) {
+	jwtMgr := auth.NewJWTManager("test-secret-key-at-least-32-bytes-long")
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	_, err := testStore.CreateUser("admin", "admin@example.com", "test-password", "admin")
+	require.NoError(t, err)
+
+	expired, err := jwtMgr.GenerateToken(1, "admin", "admin", -time.Hour)
+	require.NoError(t, err)
+	valid, err := jwtMgr.GenerateToken(1, "admin", "admin", time.Hour)
+	require.NoError(t, err)
+
+	handler := AuthMiddleware(jwtMgr, testStore)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	code := func(token string) string {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+
+		var response Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Code
+	}
+
+	assert.Equal(t, "TOKEN_EXPIRED", code(expired))
+	assert.Equal(t, "TOKEN_INVALID", code(valid+"tampered"))
+	assert.Equal(t, "TOKEN_INVALID", code("malformed.token.here"))
+}
+
 // TestAuthMiddleware tests authentication middleware
 func TestAuthMiddleware(t *testing.T) {
 	secret := "test-secret-key-at-least-32-bytes-long"
@@ -190,3 +230,186 @@ func TestCORSMiddleware(t *testing.T) {
 		})
 	}
 }
+
+// TestLoggingMiddleware tests that the captured status code is logged
+func TestLoggingMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	middleware := LoggingMiddleware(log.New(&buf, "", 0))
+
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteError(w, http.StatusNotFound, "Job not found", "NOT_FOUND")
+	})
+
+	req := httptest.NewRequest("GET", "/api/jobs/missing", nil)
+	recorder := httptest.NewRecorder()
+
+	middleware(testHandler).ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Contains(t, buf.String(), "GET /api/jobs/missing 404 ")
+}
+
+// TestLoggingMiddlewareSkipsWebSocket tests that WebSocket upgrades are not logged
+func TestLoggingMiddlewareSkipsWebSocket(t *testing.T) {
+	var buf bytes.Buffer
+	middleware := LoggingMiddleware(log.New(&buf, "", 0))
+
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusSwitchingProtocols)
+	})
+
+	req := httptest.NewRequest("GET", "/api/ws/logs", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	recorder := httptest.NewRecorder()
+
+	middleware(testHandler).ServeHTTP(recorder, req)
+
+	assert.Empty(t, buf.String())
+}
+
+// TestMaintenanceMode tests that regular users get 503 while admins and health checks proceed
+func TestMaintenanceMode(t *testing.T) {
+	jwtMgr := auth.NewJWTManager("test-secret-key-at-least-32-bytes-long")
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	admin, err := testStore.CreateUser("admin", "admin@example.com", "hash", "admin")
+	require.NoError(t, err)
+	user, err := testStore.CreateUser("user", "user@example.com", "hash", "user")
+	require.NoError(t, err)
+
+	adminToken, err := jwtMgr.GenerateToken(admin.ID, admin.Username, admin.Role, time.Hour)
+	require.NoError(t, err)
+	userToken, err := jwtMgr.GenerateToken(user.ID, user.Username, user.Role, time.Hour)
+	require.NoError(t, err)
+
+	hub, err := NewWSHub("*")
+	require.NoError(t, err)
+	router := NewRouter(testStore, jwtMgr, hub, "*", scheduler.New(testStore), "/api", time.Now(), nil, "", 0, 0, nil, nil)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Regular users cannot toggle maintenance
+	assert.Equal(t, http.StatusForbidden, do("POST", "/api/admin/maintenance", userToken, `{"enabled": true}`).Code)
+
+	require.Equal(t, http.StatusOK, do("POST", "/api/admin/maintenance", adminToken, `{"enabled": true}`).Code)
+
+	w := do("GET", "/api/jobs", userToken, "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, strconv.Itoa(internal.MaintenanceRetryAfterSeconds), w.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, do("GET", "/api/jobs", adminToken, "").Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/health", "", "").Code)
+
+	require.Equal(t, http.StatusOK, do("POST", "/api/admin/maintenance", adminToken, `{"enabled": false}`).Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/api/jobs", userToken, "").Code)
+}
+
+// TestRequestBodyLimitMiddleware tests that auth routes reject oversized bodies with 413
+func TestRequestBodyLimitMiddleware(t *testing.T) {
+	jwtMgr := auth.NewJWTManager("test-secret-key-at-least-32-bytes-long")
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	hash, err := auth.HashPassword("password123")
+	require.NoError(t, err)
+	_, err = testStore.CreateUser("admin", "admin@example.com", hash, "admin")
+	require.NoError(t, err)
+
+	hub, err := NewWSHub("*")
+	require.NoError(t, err)
+	router := NewRouter(testStore, jwtMgr, hub, "*", scheduler.New(testStore), "/api", time.Now(), nil, "", 0, 0, nil, nil)
+
+	login := func(body string, chunked bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/auth/login", bytes.NewBufferString(body))
+		if chunked {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := login(`{"username": "admin", "password": "password123"}`, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	oversized := `{"username": "admin", "password": "` + strings.Repeat("a", internal.MaxAuthRequestBodySize) + `"}`
+	w = login(oversized, false)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "PAYLOAD_TOO_LARGE", resp["code"])
+
+	// Bodies without a Content-Length are cut off while reading
+	assert.Equal(t, http.StatusRequestEntityTooLarge, login(oversized, true).Code)
+	assert.Equal(t, http.StatusOK, login(`{"username": "admin", "password": "password123"}`, true).Code)
+}
+
+// TestRequestBodyLimitAfterAuth tests that the larger body limits apply only after
+// authentication, and that an oversized body is a 413 where the handler decodes it
+func TestRequestBodyLimitAfterAuth(t *testing.T) {
+	jwtMgr := auth.NewJWTManager("test-secret-key-at-least-32-bytes-long")
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	admin, err := testStore.CreateUser("admin", "admin@example.com", "hash", "admin")
+	require.NoError(t, err)
+	adminToken, err := jwtMgr.GenerateToken(admin.ID, admin.Username, admin.Role, time.Hour)
+	require.NoError(t, err)
+
+	hub, err := NewWSHub("*")
+	require.NoError(t, err)
+	router := NewRouter(testStore, jwtMgr, hub, "*", scheduler.New(testStore), "/api", time.Now(), nil, "", 0, 0, nil, nil)
+
+	// An anonymous client announcing a huge job body is turned away by auth first
+	req := httptest.NewRequest("POST", "/api/jobs", bytes.NewBufferString(`{}`))
+	req.ContentLength = internal.MaxJobRequestBodySize + 1
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Without a Content-Length, the handler's decode hits the limit
+	oversized := `{"enabled": true, "message": "` + strings.Repeat("a", internal.MaxRequestBodySize) + `"}`
+	req = httptest.NewRequest("POST", "/api/admin/maintenance", bytes.NewBufferString(oversized))
+	req.ContentLength = -1
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "PAYLOAD_TOO_LARGE")
+}
+
+// TestCustomAPIBasePathRouting tests that a custom base path such as /tf reaches the router
+// while frontend paths stay with the file server
+func TestCustomAPIBasePathRouting(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	hub, err := NewWSHub("*")
+	require.NoError(t, err)
+	router := NewRouter(testStore, auth.NewJWTManager("test-secret-key-at-least-32-bytes-long"), hub, "*", scheduler.New(testStore), "/tf", time.Now(), nil, "", 0, 0, nil, nil)
+
+	assert.Equal(t, "/tf/setup", SetupBasePath("/tf"))
+	assert.Equal(t, "/taskflow/setup", SetupBasePath("/taskflow/api"))
+	assert.Equal(t, "/api/setup", SetupBasePath("/api"))
+
+	for _, path := range []string{"/tf/version", "/tf/setup/status", "/health"} {
+		assert.True(t, IsAPIPath(path, "/tf"), path)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, http.StatusOK, w.Code, path)
+	}
+
+	for _, path := range []string{"/", "/taskflow/", "/taskflow/jobs", "/taskflow/assets/app.js", "/tfx/version", "/tf"} {
+		assert.False(t, IsAPIPath(path, "/tf"), path)
+	}
+}
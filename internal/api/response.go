@@ -2,7 +2,13 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
 	"net/http"
+	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Response is the standard API response wrapper
@@ -40,3 +46,64 @@ func WriteError(w http.ResponseWriter, statusCode int, errorMsg, errorCode strin
 	}
 	json.NewEncoder(w).Encode(response)
 }
+
+// WriteBodyError answers a request whose body could not be read or decoded: 413 if it
+// ran past the route's body limit, otherwise 400 with errorMsg and errorCode
+func WriteBodyError(w http.ResponseWriter, err error, errorMsg, errorCode string) {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		writeBodyTooLarge(w, maxErr.Limit)
+		return
+	}
+	WriteError(w, http.StatusBadRequest, errorMsg, errorCode)
+}
+
+// writeBodyTooLarge answers 413 for a body over limit bytes
+func writeBodyTooLarge(w http.ResponseWriter, limit int64) {
+	WriteError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body too large (max %d bytes)", limit), "PAYLOAD_TOO_LARGE")
+}
+
+// wantsYAML reports whether the Accept header asks for YAML
+func wantsYAML(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "application/yaml", "application/x-yaml", "text/yaml":
+			return true
+		}
+	}
+	return false
+}
+
+// WriteNegotiated writes a success response as YAML when the request's Accept
+// header asks for it, and as JSON (via WriteJSON) otherwise
+func WriteNegotiated(w http.ResponseWriter, r *http.Request, statusCode int, data interface{}) {
+	if !wantsYAML(r) {
+		WriteJSON(w, statusCode, data)
+		return
+	}
+
+	// Round-trip through JSON so YAML keys match the json tags used everywhere else
+	raw, err := json.Marshal(Response{Data: data, Status: "success"})
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to encode response", "INTERNAL_ERROR")
+		return
+	}
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to encode response", "INTERNAL_ERROR")
+		return
+	}
+	out, err := yaml.Marshal(generic)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to encode response", "INTERNAL_ERROR")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.WriteHeader(statusCode)
+	w.Write(out)
+}
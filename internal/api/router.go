@@ -1,34 +1,61 @@
 package api
 
 import (
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	internal "github.com/taskflow/taskflow/internal"
 	"github.com/taskflow/taskflow/internal/auth"
+	"github.com/taskflow/taskflow/internal/executor"
 	"github.com/taskflow/taskflow/internal/scheduler"
 	"github.com/taskflow/taskflow/internal/store"
 )
 
 // NewRouter creates and configures the HTTP router
-func NewRouter(st *store.Store, jwtManager *auth.JWTManager, wsHub *WSHub, corsOrigins string, sched *scheduler.Scheduler, apiBasePath string) *http.ServeMux {
+func NewRouter(st *store.Store, jwtManager *auth.JWTManager, wsHub *WSHub, corsOrigins string, sched *scheduler.Scheduler, apiBasePath string, startTime time.Time, scriptGuard *ScriptGuard, workingDirRoots string, maxJobTimeout, minScheduleInterval int, exec *executor.Executor, dispatcher *executor.Dispatcher) *http.ServeMux {
 	mux := http.NewServeMux()
 
 	// Handlers
 	authHandlers := NewAuthHandlers(st, jwtManager)
 	jobHandlers := NewJobHandlers(st, sched)
+	jobHandlers.SetScriptGuard(scriptGuard)
+	jobHandlers.SetAllowedWorkingDirRoots(workingDirRoots)
+	jobHandlers.SetMaxTimeoutSeconds(maxJobTimeout)
+	jobHandlers.SetMinScheduleIntervalMinutes(minScheduleInterval)
+	jobHandlers.SetLogHub(wsHub)
+	jobHandlers.SetExecutor(exec)
 	runHandlers := NewRunHandlers(st)
 	scheduleHandlers := NewScheduleHandlers(st)
+	scheduleHandlers.SetMinScheduleIntervalMinutes(minScheduleInterval)
 	dashboardHandlers := NewDashboardHandlers(st)
 	analyticsHandlers := NewAnalyticsHandlers(st)
+	agentHandlers := NewAgentHandlers(st)
+	agentHandlers.SetDispatcher(dispatcher)
+	healthHandlers := NewHealthHandlers(st, sched)
 
 	// Middleware
-	authMw := AuthMiddleware(jwtManager, st)
+	maintenanceMw := MaintenanceMiddleware(st)
+	authOnlyMw := AuthMiddleware(jwtManager, st)
+	// Every authenticated route is also subject to maintenance mode (admins pass through)
+	authMw := func(next http.Handler) http.Handler {
+		return authOnlyMw(maintenanceMw(next))
+	}
 	corsMw := CORSMiddleware(corsOrigins)
+	loggingMw := LoggingMiddleware(log.Default())
+	// The larger limits go inside authMw, so only authenticated clients get to send that much
 	bodyLimitMw := RequestBodyLimitMiddleware(internal.MaxRequestBodySize)
+	// Credential bodies are tiny, so a small cap blunts memory-exhaustion POSTs to unauthenticated routes
+	authBodyLimitMw := RequestBodyLimitMiddleware(internal.MaxAuthRequestBodySize)
+	jobBodyLimitMw := RequestBodyLimitMiddleware(internal.MaxJobRequestBodySize)
 
 	// Health check (no auth required)
 	mux.HandleFunc("GET /health", Health)
 
+	// Version and uptime (no auth required) - used for fleet monitoring
+	mux.HandleFunc("GET "+apiBasePath+"/version", Version(startTime))
+
 	// Config endpoint (no auth required) - provides runtime config to frontend
 	// Uses /taskflow-app prefix to avoid conflicts with other services behind nginx
 	mux.HandleFunc("GET /taskflow-app/config", func(w http.ResponseWriter, r *http.Request) {
@@ -38,43 +65,61 @@ func NewRouter(st *store.Store, jwtManager *auth.JWTManager, wsHub *WSHub, corsO
 	})
 
 	// Setup endpoints (no auth required for initial setup)
-	// Derive setup base path from API base path (e.g., /taskflow/api -> /taskflow/setup)
-	setupBasePath := "/taskflow/setup"
-	if apiBasePath != "/taskflow/api" {
-		// Extract prefix from apiBasePath (remove /api suffix if present)
-		prefix := apiBasePath
-		if len(prefix) > 4 && prefix[len(prefix)-4:] == "/api" {
-			prefix = prefix[:len(prefix)-4]
-		}
-		setupBasePath = prefix + "/setup"
-	}
+	setupBasePath := SetupBasePath(apiBasePath)
 	mux.HandleFunc("GET "+setupBasePath+"/status", authHandlers.SetupStatus)
-	mux.Handle("POST "+setupBasePath+"/admin", bodyLimitMw(http.HandlerFunc(authHandlers.CreateFirstAdmin)))
+	mux.Handle("POST "+setupBasePath+"/admin", authBodyLimitMw(http.HandlerFunc(authHandlers.CreateFirstAdmin)))
 
 	// Auth endpoints (no auth required for login)
-	mux.Handle("POST "+apiBasePath+"/auth/login", bodyLimitMw(http.HandlerFunc(authHandlers.Login)))
+	mux.Handle("POST "+apiBasePath+"/auth/login", authBodyLimitMw(http.HandlerFunc(authHandlers.Login)))
 
 	// Auth endpoints (requires auth)
-	mux.Handle("PUT "+apiBasePath+"/auth/password", bodyLimitMw(authMw(http.HandlerFunc(authHandlers.ChangePassword))))
-	mux.Handle("PUT "+apiBasePath+"/auth/email", bodyLimitMw(authMw(http.HandlerFunc(authHandlers.ChangeEmail))))
+	mux.Handle("PUT "+apiBasePath+"/auth/password", authBodyLimitMw(authMw(http.HandlerFunc(authHandlers.ChangePassword))))
+	mux.Handle("PUT "+apiBasePath+"/auth/email", authBodyLimitMw(authMw(http.HandlerFunc(authHandlers.ChangeEmail))))
 
 	// Protected endpoints - wrap with auth middleware
 	// Jobs endpoints
 	mux.Handle("GET "+apiBasePath+"/jobs", authMw(http.HandlerFunc(jobHandlers.ListJobs)))
-	mux.Handle("POST "+apiBasePath+"/jobs", bodyLimitMw(authMw(http.HandlerFunc(jobHandlers.CreateJob))))
+	mux.Handle("POST "+apiBasePath+"/jobs", authMw(jobBodyLimitMw(http.HandlerFunc(jobHandlers.CreateJob))))
 	mux.Handle("GET "+apiBasePath+"/jobs/{id}", authMw(http.HandlerFunc(jobHandlers.GetJob)))
-	mux.Handle("PUT "+apiBasePath+"/jobs/{id}", bodyLimitMw(authMw(http.HandlerFunc(jobHandlers.UpdateJob))))
+	mux.Handle("GET "+apiBasePath+"/jobs/{id}/detail", authMw(http.HandlerFunc(jobHandlers.GetJobDetail)))
+	mux.Handle("PUT "+apiBasePath+"/jobs/{id}", authMw(jobBodyLimitMw(http.HandlerFunc(jobHandlers.UpdateJob))))
 	mux.Handle("DELETE "+apiBasePath+"/jobs/{id}", authMw(http.HandlerFunc(jobHandlers.DeleteJob)))
 	mux.Handle("POST "+apiBasePath+"/jobs/{id}/run", authMw(http.HandlerFunc(jobHandlers.TriggerJob)))
+	mux.Handle("POST "+apiBasePath+"/jobs/{id}/test", authMw(http.HandlerFunc(jobHandlers.TestJob)))
+
+	// Job template routes (admin only)
+	mux.Handle("GET "+apiBasePath+"/job-templates", authMw(http.HandlerFunc(jobHandlers.ListJobTemplates)))
+	mux.Handle("POST "+apiBasePath+"/job-templates", authMw(jobBodyLimitMw(http.HandlerFunc(jobHandlers.CreateJobTemplate))))
+	mux.Handle("GET "+apiBasePath+"/job-templates/{id}", authMw(http.HandlerFunc(jobHandlers.GetJobTemplate)))
+	mux.Handle("PUT "+apiBasePath+"/job-templates/{id}", authMw(jobBodyLimitMw(http.HandlerFunc(jobHandlers.UpdateJobTemplate))))
+	mux.Handle("DELETE "+apiBasePath+"/job-templates/{id}", authMw(http.HandlerFunc(jobHandlers.DeleteJobTemplate)))
+	// Not /jobs/from-template/{id}: that would conflict with POST /jobs/{id}/run in ServeMux
+	mux.Handle("POST "+apiBasePath+"/job-templates/{id}/instantiate", authMw(jobBodyLimitMw(http.HandlerFunc(jobHandlers.CreateJobFromTemplate))))
 
 	// Schedule endpoints
 	mux.Handle("GET "+apiBasePath+"/jobs/{id}/schedule", authMw(http.HandlerFunc(scheduleHandlers.GetJobSchedule)))
-	mux.Handle("PUT "+apiBasePath+"/jobs/{id}/schedule", bodyLimitMw(authMw(http.HandlerFunc(scheduleHandlers.SetJobSchedule))))
+	mux.Handle("PUT "+apiBasePath+"/jobs/{id}/schedule", authMw(bodyLimitMw(http.HandlerFunc(scheduleHandlers.SetJobSchedule))))
+	mux.Handle("POST "+apiBasePath+"/jobs/{id}/schedule/copy-from/{sourceId}", authMw(http.HandlerFunc(scheduleHandlers.CopyJobSchedule)))
+	mux.Handle("POST "+apiBasePath+"/jobs/{id}/schedule/disable", authMw(http.HandlerFunc(scheduleHandlers.DisableJobSchedule)))
+	mux.Handle("POST "+apiBasePath+"/jobs/{id}/schedule/enable", authMw(http.HandlerFunc(scheduleHandlers.EnableJobSchedule)))
+	mux.Handle("GET "+apiBasePath+"/schedule/upcoming", authMw(http.HandlerFunc(scheduleHandlers.ListUpcoming)))
 
 	// Runs endpoints
 	mux.Handle("GET "+apiBasePath+"/runs", authMw(http.HandlerFunc(runHandlers.ListRuns)))
+	mux.Handle("GET "+apiBasePath+"/runs/logs", authMw(http.HandlerFunc(runHandlers.GetBatchRunLogs)))
+	mux.Handle("GET "+apiBasePath+"/runs/active", authMw(http.HandlerFunc(runHandlers.ListActiveRuns)))
 	mux.Handle("GET "+apiBasePath+"/runs/{id}", authMw(http.HandlerFunc(runHandlers.GetRun)))
 	mux.Handle("GET "+apiBasePath+"/runs/{id}/logs", authMw(http.HandlerFunc(runHandlers.GetRunLogs)))
+	mux.Handle("GET "+apiBasePath+"/runs/{id}/logs/tail", authMw(http.HandlerFunc(runHandlers.GetRunLogsTail)))
+	// Live logs over server-sent events; EventSource can't set headers either, so ?token= is accepted
+	mux.Handle("GET "+apiBasePath+"/runs/{id}/logs/stream", TokenQueryMiddleware(authMw(wsHub.HandleLogsSSE(st))))
+	mux.Handle("GET "+apiBasePath+"/runs/{id}/report", authMw(http.HandlerFunc(runHandlers.GetRunReport)))
+	mux.Handle("GET "+apiBasePath+"/runs/{id}/attempts", authMw(http.HandlerFunc(runHandlers.ListRunAttempts)))
+	mux.Handle("POST "+apiBasePath+"/runs/{id}/ack", authMw(http.HandlerFunc(runHandlers.AcknowledgeRun)))
+	mux.Handle("POST "+apiBasePath+"/runs/{id}/requeue", authMw(http.HandlerFunc(jobHandlers.RequeueRun)))
+	mux.Handle("POST "+apiBasePath+"/runs/{id}/approve", authMw(http.HandlerFunc(jobHandlers.ApproveRun)))
+	mux.Handle("POST "+apiBasePath+"/runs/{id}/reject", authMw(http.HandlerFunc(jobHandlers.RejectRun)))
+	mux.Handle("POST "+apiBasePath+"/queue/{runId}/move-to-front", authMw(http.HandlerFunc(jobHandlers.MoveRunToFront)))
 
 	// Dashboard endpoints
 	mux.Handle("GET "+apiBasePath+"/dashboard/stats", authMw(http.HandlerFunc(dashboardHandlers.GetStats)))
@@ -84,18 +129,66 @@ func NewRouter(st *store.Store, jwtManager *auth.JWTManager, wsHub *WSHub, corsO
 	mux.Handle("GET "+apiBasePath+"/analytics/execution-trends", authMw(http.HandlerFunc(analyticsHandlers.GetExecutionTrends)))
 	mux.Handle("GET "+apiBasePath+"/analytics/job-stats", authMw(http.HandlerFunc(analyticsHandlers.GetJobStats)))
 	mux.Handle("GET "+apiBasePath+"/analytics/jobs/{id}/duration-trends", authMw(http.HandlerFunc(analyticsHandlers.GetJobDurationTrends)))
+	mux.Handle("GET "+apiBasePath+"/jobs/{id}/sla", authMw(http.HandlerFunc(analyticsHandlers.GetJobSLA)))
+	mux.Handle("GET "+apiBasePath+"/jobs/{id}/overlaps", authMw(http.HandlerFunc(analyticsHandlers.GetJobOverlaps)))
+
+	// Usage endpoints
+	mux.Handle("GET "+apiBasePath+"/admin/inventory", authMw(http.HandlerFunc(analyticsHandlers.GetInventory)))
+	mux.Handle("GET "+apiBasePath+"/users/minimal", authMw(http.HandlerFunc(authHandlers.ListUsersMinimal)))
+	mux.Handle("GET "+apiBasePath+"/users/{id}/usage", authMw(http.HandlerFunc(analyticsHandlers.GetUserUsage)))
 
 	// Settings endpoints (admin only)
 	mux.Handle("GET "+apiBasePath+"/settings/smtp", authMw(http.HandlerFunc(authHandlers.GetSMTPSettings)))
-	mux.Handle("PUT "+apiBasePath+"/settings/smtp", bodyLimitMw(authMw(http.HandlerFunc(authHandlers.UpdateSMTPSettings))))
+	mux.Handle("PUT "+apiBasePath+"/settings/smtp", authMw(bodyLimitMw(http.HandlerFunc(authHandlers.UpdateSMTPSettings))))
 	mux.Handle("POST "+apiBasePath+"/settings/smtp/test", authMw(http.HandlerFunc(authHandlers.TestSMTPSettings)))
-
-	// WebSocket endpoints (no auth middleware applied here - handler manages auth internally)
-	mux.HandleFunc("GET "+apiBasePath+"/ws/logs", wsHub.HandleLogsWebSocket)
+	mux.Handle("GET "+apiBasePath+"/settings/notifications", authMw(http.HandlerFunc(authHandlers.GetChannelSettings)))
+	mux.Handle("PUT "+apiBasePath+"/settings/notifications", authMw(bodyLimitMw(http.HandlerFunc(authHandlers.UpdateChannelSettings))))
+	mux.Handle("POST "+apiBasePath+"/admin/maintenance", authMw(bodyLimitMw(http.HandlerFunc(authHandlers.SetMaintenanceMode))))
+	mux.Handle("GET "+apiBasePath+"/admin/health/detailed", authMw(http.HandlerFunc(healthHandlers.GetDetailedHealth)))
+	mux.Handle("GET "+apiBasePath+"/admin/scheduler", authMw(http.HandlerFunc(jobHandlers.GetSchedulerStatus)))
+	mux.Handle("GET "+apiBasePath+"/admin/migrations", authMw(http.HandlerFunc(authHandlers.ListMigrations)))
+	mux.Handle("GET "+apiBasePath+"/admin/backup", authMw(http.HandlerFunc(jobHandlers.Backup)))
+	mux.Handle("POST "+apiBasePath+"/admin/restore", authMw(jobBodyLimitMw(http.HandlerFunc(jobHandlers.Restore))))
+	mux.Handle("POST "+apiBasePath+"/settings/email/preview", authMw(bodyLimitMw(http.HandlerFunc(authHandlers.PreviewEmail))))
+	mux.Handle("GET "+apiBasePath+"/notifications/outbox", authMw(http.HandlerFunc(authHandlers.ListNotificationOutbox)))
+	mux.Handle("GET "+apiBasePath+"/admin/ws/connections", authMw(http.HandlerFunc(wsHub.HandleListConnections)))
+	mux.Handle("POST "+apiBasePath+"/admin/ws/disconnect", authMw(http.HandlerFunc(wsHub.HandleDisconnect)))
+
+	// Remote executor agents (admin only)
+	mux.Handle("GET "+apiBasePath+"/agents", authMw(http.HandlerFunc(agentHandlers.ListAgents)))
+	mux.Handle("POST "+apiBasePath+"/agents", authMw(bodyLimitMw(http.HandlerFunc(agentHandlers.RegisterAgent))))
+	mux.Handle("DELETE "+apiBasePath+"/agents/{id}", authMw(http.HandlerFunc(agentHandlers.DeleteAgent)))
+	mux.Handle("POST "+apiBasePath+"/agents/{id}/heartbeat", authMw(http.HandlerFunc(agentHandlers.Heartbeat)))
+	mux.Handle("POST "+apiBasePath+"/agents/{id}/runs/{runId}/result", authMw(jobBodyLimitMw(http.HandlerFunc(agentHandlers.ReportResult))))
+
+	// WebSocket endpoints (auth required; token may be passed as ?token= since browsers can't set WS headers)
+	mux.Handle("GET "+apiBasePath+"/ws/logs", TokenQueryMiddleware(authMw(http.HandlerFunc(wsHub.HandleLogsWebSocket))))
+	// Global activity feed (auth required; token may be passed as ?token= since browsers can't set WS headers)
+	mux.Handle("GET "+apiBasePath+"/ws/activity", TokenQueryMiddleware(authMw(http.HandlerFunc(wsHub.HandleActivityWebSocket))))
 
 	// Return wrapped mux with CORS and other global middleware
 	wrappedMux := http.NewServeMux()
-	wrappedMux.Handle("/", corsMw(mux))
+	wrappedMux.Handle("/", loggingMw(corsMw(mux)))
 
 	return wrappedMux
 }
+
+// SetupBasePath derives the setup endpoints' base path from the API base path by
+// replacing a trailing /api (e.g. /taskflow/api -> /taskflow/setup, /tf -> /tf/setup)
+func SetupBasePath(apiBasePath string) string {
+	prefix := strings.TrimSuffix(apiBasePath, "/api")
+	if prefix == "" {
+		prefix = apiBasePath
+	}
+	return prefix + "/setup"
+}
+
+// IsAPIPath reports whether a request path belongs to the router rather than the
+// frontend file server
+func IsAPIPath(path string, apiBasePath string) bool {
+	setupBasePath := SetupBasePath(apiBasePath)
+	return path == "/health" ||
+		strings.HasPrefix(path, "/taskflow-app/") ||
+		strings.HasPrefix(path, setupBasePath+"/") ||
+		strings.HasPrefix(path, apiBasePath+"/")
+}
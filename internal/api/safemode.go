@@ -0,0 +1,139 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	internal "github.com/taskflow/taskflow/internal"
+)
+
+// ScriptRule flags job scripts containing a pattern
+type ScriptRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// ScriptFinding is one rule match in a job's script
+type ScriptFinding struct {
+	Rule  string `json:"rule"`
+	Field string `json:"field"`
+	Match string `json:"match"`
+}
+
+// DefaultScriptRules returns the built-in safe mode rules. They catch obvious mistakes
+// and are trivially bypassed on purpose, so they are advisory, not a sandbox.
+func DefaultScriptRules() []ScriptRule {
+	return []ScriptRule{
+		{Name: "rm-rf-root", Pattern: regexp.MustCompile(`(?m)\brm\s+(?:-{1,2}[\w-]+\s+)*(?:/\*?|~/?|\$HOME/?)(?:\s|;|&|\||$)`)},
+		{Name: "fork-bomb", Pattern: regexp.MustCompile(`:\(\)\s*\{\s*:\s*\|\s*:\s*&\s*\}\s*;\s*:`)},
+		{Name: "mkfs", Pattern: regexp.MustCompile(`\bmkfs(?:\.\w+)?\s`)},
+		{Name: "dd-to-device", Pattern: regexp.MustCompile(`\bdd\b[^\n]*\bof=/dev/(?:sd|hd|nvme|xvd|vd|mmcblk|disk)`)},
+		{Name: "write-block-device", Pattern: regexp.MustCompile(`>\s*/dev/(?:sd|hd|nvme|xvd|vd|mmcblk|disk)`)},
+		{Name: "chmod-777-root", Pattern: regexp.MustCompile(`(?m)\bchmod\s+(?:-\w+\s+)*0?777\s+/(?:\s|;|&|\||$)`)},
+		{Name: "write-system-dir", Pattern: regexp.MustCompile(`(?:>|\btee\s+(?:-a\s+)?)\s*/(?:etc|bin|sbin|usr|boot|lib|lib64)/`)},
+	}
+}
+
+// ParseScriptRules reads safe mode rules, one "name = regex" per line.
+// Blank lines and lines starting with # are ignored.
+func ParseScriptRules(r io.Reader) ([]ScriptRule, error) {
+	var rules []ScriptRule
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, expr, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		expr = strings.TrimSpace(expr)
+		if !ok || name == "" || expr == "" {
+			return nil, fmt.Errorf("line %d: expected \"name = regex\"", lineNum)
+		}
+
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: rule %q: %w", lineNum, name, err)
+		}
+		rules = append(rules, ScriptRule{Name: name, Pattern: pattern})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// LoadScriptRules reads safe mode rules from a file
+func LoadScriptRules(path string) ([]ScriptRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseScriptRules(f)
+}
+
+// ScriptGuard scans job scripts against safe mode rules when jobs are created or updated.
+// A nil guard scans nothing.
+type ScriptGuard struct {
+	mode  string
+	rules []ScriptRule
+}
+
+// NewScriptGuard creates a guard with the given mode (off, warn or reject) and rules
+func NewScriptGuard(mode string, rules []ScriptRule) *ScriptGuard {
+	return &ScriptGuard{mode: mode, rules: rules}
+}
+
+// Rejects reports whether findings should block the job from being saved
+func (g *ScriptGuard) Rejects() bool {
+	return g != nil && g.mode == internal.SafeModeReject
+}
+
+// Scan returns every rule match in the request's script and hooks
+func (g *ScriptGuard) Scan(req *JobRequest) []ScriptFinding {
+	if g == nil || g.mode == internal.SafeModeOff || g.mode == "" {
+		return nil
+	}
+
+	var findings []ScriptFinding
+	fields := []struct {
+		name   string
+		script string
+	}{
+		{"script", req.Script},
+		{"pre_script", req.PreScript},
+		{"post_script", req.PostScript},
+	}
+	for _, f := range fields {
+		if f.script == "" {
+			continue
+		}
+		for _, rule := range g.rules {
+			if match := rule.Pattern.FindString(f.script); match != "" {
+				findings = append(findings, ScriptFinding{
+					Rule:  rule.Name,
+					Field: f.name,
+					Match: strings.TrimSpace(match),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// describeFindings formats findings for an error message
+func describeFindings(findings []ScriptFinding) string {
+	parts := make([]string, len(findings))
+	for i, f := range findings {
+		parts[i] = fmt.Sprintf("%s in %s", f.Rule, f.Field)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	internal "github.com/taskflow/taskflow/internal"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// TestScriptGuardDefaultRules tests that dangerous scripts are flagged and benign ones pass
+func TestScriptGuardDefaultRules(t *testing.T) {
+	guard := NewScriptGuard(internal.SafeModeReject, DefaultScriptRules())
+
+	flagged := map[string]string{
+		"rm -rf /":                                     "rm-rf-root",
+		"cd /tmp\nrm -rf / \necho done":                "rm-rf-root",
+		"rm -rf --no-preserve-root /":                  "rm-rf-root",
+		"rm -rf /*":                                    "rm-rf-root",
+		":(){ :|:& };:":                                "fork-bomb",
+		"mkfs.ext4 /dev/sdb1":                          "mkfs",
+		"dd if=/dev/zero of=/dev/sda bs=1M":            "dd-to-device",
+		"echo x > /dev/nvme0n1":                        "write-block-device",
+		"chmod -R 777 /":                               "chmod-777-root",
+		"echo 'nameserver 1.1.1.1' > /etc/resolv.conf": "write-system-dir",
+		"echo alias | tee -a /etc/profile":             "write-system-dir",
+	}
+	for script, rule := range flagged {
+		findings := guard.Scan(&JobRequest{Script: script})
+		require.NotEmpty(t, findings, "expected %q to be flagged", script)
+		assert.Equal(t, rule, findings[0].Rule, script)
+		assert.Equal(t, "script", findings[0].Field)
+	}
+
+	benign := []string{
+		"echo hello",
+		"rm -rf /tmp/build-cache",
+		"rm -rf ./dist",
+		"tar czf /var/backups/db.tgz /srv/data",
+		"cat /etc/hostname > /tmp/host.txt",
+		"python3 report.py >> /var/log/report.log",
+	}
+	for _, script := range benign {
+		assert.Empty(t, guard.Scan(&JobRequest{Script: script}), "expected %q to pass", script)
+	}
+}
+
+// TestScriptGuardScansHooks tests that pre and post scripts are scanned too
+func TestScriptGuardScansHooks(t *testing.T) {
+	guard := NewScriptGuard(internal.SafeModeWarn, DefaultScriptRules())
+
+	findings := guard.Scan(&JobRequest{Script: "echo ok", PostScript: "rm -rf /"})
+	require.Len(t, findings, 1)
+	assert.Equal(t, "post_script", findings[0].Field)
+	assert.False(t, guard.Rejects())
+}
+
+// TestScriptGuardOff tests that a disabled or nil guard flags nothing
+func TestScriptGuardOff(t *testing.T) {
+	assert.Empty(t, NewScriptGuard(internal.SafeModeOff, DefaultScriptRules()).Scan(&JobRequest{Script: "rm -rf /"}))
+
+	var guard *ScriptGuard
+	assert.Empty(t, guard.Scan(&JobRequest{Script: "rm -rf /"}))
+	assert.False(t, guard.Rejects())
+}
+
+// TestParseScriptRules tests that a rules file replaces the default rule list
+func TestParseScriptRules(t *testing.T) {
+	rules, err := ParseScriptRules(strings.NewReader(`
+# site rules
+curl-pipe-shell = curl[^|]*\|\s*(ba)?sh
+no-sudo = \bsudo\b
+`))
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, "curl-pipe-shell", rules[0].Name)
+
+	guard := NewScriptGuard(internal.SafeModeReject, rules)
+	findings := guard.Scan(&JobRequest{Script: "curl -s https://example.com/install | sh"})
+	require.Len(t, findings, 1)
+	assert.Equal(t, "curl-pipe-shell", findings[0].Rule)
+	assert.Empty(t, guard.Scan(&JobRequest{Script: "rm -rf /"}), "custom rules replace the defaults")
+
+	_, err = ParseScriptRules(strings.NewReader("missing-separator"))
+	assert.Error(t, err)
+	_, err = ParseScriptRules(strings.NewReader("bad = ("))
+	assert.Error(t, err)
+}
+
+// TestCreateJobSafeMode tests that reject mode blocks a flagged script and warn mode saves it with warnings
+func TestCreateJobSafeMode(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+	jobHandlers := NewJobHandlers(testStore, nil)
+
+	create := func(script string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{"name": "cleanup", "script": script, "timeout_seconds": 60})
+		req := httptest.NewRequest("POST", "/api/jobs", bytes.NewBuffer(body))
+		req.Header.Set("X-User-ID", "1")
+		req.Header.Set("X-User-Role", "admin")
+		w := httptest.NewRecorder()
+		jobHandlers.CreateJob(w, req)
+		return w
+	}
+
+	jobHandlers.SetScriptGuard(NewScriptGuard(internal.SafeModeReject, DefaultScriptRules()))
+	w := create("rm -rf /")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var errResp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+	assert.Equal(t, "UNSAFE_SCRIPT", errResp["code"])
+
+	assert.Equal(t, http.StatusCreated, create("echo hello").Code)
+
+	jobHandlers.SetScriptGuard(NewScriptGuard(internal.SafeModeWarn, DefaultScriptRules()))
+	w = create("rm -rf /")
+	require.Equal(t, http.StatusCreated, w.Code)
+	var resp struct {
+		Data JobCreateResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Data.Warnings, 1)
+	assert.Equal(t, "rm-rf-root", resp.Data.Warnings[0].Rule)
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// errSubscriberClosed is returned when writing to a subscriber the hub has already closed
+var errSubscriberClosed = errors.New("subscriber closed")
+
+// sseSubscriber delivers messages as server-sent events on a streaming HTTP response.
+// The hub and the handler's keep-alives both write, so writes are serialized.
+type sseSubscriber struct {
+	mu     sync.Mutex
+	w      io.Writer
+	rc     *http.ResponseController
+	closed bool
+	done   chan struct{}
+}
+
+func newSSESubscriber(w http.ResponseWriter) *sseSubscriber {
+	return &sseSubscriber{w: w, rc: http.NewResponseController(w), done: make(chan struct{})}
+}
+
+// Send writes data as one event. Encoded JSON has no raw newlines, so a single data line suffices.
+func (s *sseSubscriber) Send(data []byte) error {
+	return s.write("data: " + string(data) + "\n\n")
+}
+
+// ping writes a comment line, which clients ignore but which keeps proxies from timing out
+func (s *sseSubscriber) ping() error {
+	return s.write(": ping\n\n")
+}
+
+func (s *sseSubscriber) write(frame string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errSubscriberClosed
+	}
+	if _, err := io.WriteString(s.w, frame); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// Close stops further writes and ends the handler serving the stream
+func (s *sseSubscriber) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+	return nil
+}
+
+// HandleLogsSSE handles GET /api/runs/{id}/logs/stream, a server-sent events alternative to
+// the logs WebSocket for clients behind proxies that break upgrades. Every event carries the
+// same JSON message as the WebSocket, and from_id/max_backlog resume the same way.
+// Must be wrapped in auth middleware.
+func (h *WSHub) HandleLogsSSE(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runID := r.PathValue("id")
+		if _, ok := getVisibleRun(st, r, runID); !ok {
+			WriteError(w, http.StatusNotFound, "Run not found", "NOT_FOUND")
+			return
+		}
+
+		backlog, err := h.requestedBacklog(r, runID)
+		if err != nil {
+			WriteError(w, backlogErrorStatus(err), err.Error(), "VALIDATION_ERROR")
+			return
+		}
+
+		if !h.reserveRunConn(runID) {
+			WriteError(w, http.StatusTooManyRequests, "Too many connections for this run", "TOO_MANY_CONNECTIONS")
+			return
+		}
+		defer h.releaseRunConn(runID)
+
+		sse := newSSESubscriber(w)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		// Stop nginx from buffering the stream
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		if err := sse.rc.Flush(); err != nil {
+			log.Printf("Log stream for run %s cannot be flushed: %v\n", runID, err)
+			return
+		}
+
+		sub := &WSSubscription{RunID: runID, Subscriber: sse, backlog: backlog}
+		h.register <- sub
+		defer func() { h.unregister <- sub }()
+
+		var pings <-chan time.Time
+		if h.pingInterval > 0 {
+			ticker := time.NewTicker(h.pingInterval)
+			defer ticker.Stop()
+			pings = ticker.C
+		}
+
+		for {
+			select {
+			case <-r.Context().Done():
+				// Client went away
+				return
+			case <-sse.done:
+				// The hub dropped the subscriber after a failed write
+				return
+			case <-pings:
+				if err := sse.ping(); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taskflow/taskflow/internal/auth"
+	"github.com/taskflow/taskflow/internal/scheduler"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// TestLogsSSEDeliversBroadcast tests that a broadcast log line reaches a connected
+// server-sent events client and that disconnecting unregisters it
+func TestLogsSSEDeliversBroadcast(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	jwtMgr := auth.NewJWTManager("test-secret-key-at-least-32-bytes-long")
+	admin, err := testStore.CreateUser("admin", "admin@example.com", "hash", "admin")
+	require.NoError(t, err)
+	token, err := jwtMgr.GenerateToken(admin.ID, admin.Username, admin.Role, time.Hour)
+	require.NoError(t, err)
+
+	job, err := testStore.CreateJob(&store.Job{Name: "stream", Script: "echo hi", TimeoutSeconds: 10})
+	require.NoError(t, err)
+	run, err := testStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	hub, err := NewWSHub("*")
+	require.NoError(t, err)
+	go hub.Run()
+
+	server := httptest.NewServer(NewRouter(testStore, jwtMgr, hub, "*", scheduler.New(testStore), "/api", time.Now(), nil, "", 0, 0, nil, nil))
+	defer server.Close()
+	streamURL := server.URL + "/api/runs/" + run.ID + "/logs/stream"
+
+	resp, err := http.Get(streamURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = http.Get(streamURL + "?token=" + token)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	require.Eventually(t, func() bool { return hub.subscriberCount(run.ID) == 1 }, time.Second, 10*time.Millisecond)
+
+	hub.Broadcast(WSMessage{Type: "log", RunID: run.ID, Data: map[string]string{"stream": "stdout", "content": "live line"}})
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if line := scanner.Text(); strings.HasPrefix(line, "data: ") {
+				lines <- strings.TrimPrefix(line, "data: ")
+			}
+		}
+		close(lines)
+	}()
+
+	select {
+	case data := <-lines:
+		var msg struct {
+			Type  string            `json:"type"`
+			RunID string            `json:"run_id"`
+			Data  map[string]string `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(data), &msg))
+		assert.Equal(t, "log", msg.Type)
+		assert.Equal(t, run.ID, msg.RunID)
+		assert.Equal(t, "live line", msg.Data["content"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the broadcast log line as an SSE event")
+	}
+
+	// Closing the response ends the stream and frees the subscription
+	resp.Body.Close()
+	require.Eventually(t, func() bool { return hub.subscriberCount(run.ID) == 0 }, 2*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	internal "github.com/taskflow/taskflow/internal"
+)
+
+// streamHold buffers a triggered run's messages until the client holding its stream
+// token subscribes, so lines logged between the trigger response and the WebSocket
+// upgrade are not lost
+type streamHold struct {
+	token    string
+	expires  time.Time
+	messages []WSMessage
+	dropped  int // oldest messages discarded once the buffer was full
+}
+
+// IssueStreamToken starts holding runID's messages and returns a single-use token that
+// replays them, from the first, to the WebSocket opened with it. Call it before the run
+// is enqueued. Messages are held for internal.StreamTokenTTL; after that the token is
+// rejected and stored logs must be read with from_id instead.
+func (h *WSHub) IssueStreamToken(runID string) string {
+	token := uuid.New().String()
+	now := time.Now()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for id, hold := range h.holds {
+		if now.After(hold.expires) {
+			delete(h.holds, id)
+		}
+	}
+	h.holds[runID] = &streamHold{token: token, expires: now.Add(internal.StreamTokenTTL)}
+	return token
+}
+
+// streamTokenValid reports whether token is the unexpired stream token of runID
+func (h *WSHub) streamTokenValid(runID, token string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	hold, ok := h.holds[runID]
+	return ok && hold.token == token && time.Now().Before(hold.expires)
+}
+
+// hold appends msg to its run's buffer if a stream token is outstanding for the run.
+// Only called from Run, so it is ordered with registrations.
+func (h *WSHub) hold(msg WSMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hold, ok := h.holds[msg.RunID]
+	if !ok {
+		return
+	}
+	if time.Now().After(hold.expires) {
+		delete(h.holds, msg.RunID)
+		return
+	}
+	if len(hold.messages) >= internal.MaxWSBacklog {
+		hold.messages = hold.messages[1:]
+		hold.dropped++
+	}
+	hold.messages = append(hold.messages, msg)
+}
+
+// claimHold ends the hold of a subscription's run and returns its buffered messages,
+// followed by a "backlog" message, or false if the token is wrong, spent or expired.
+// Only called from Run, so nothing is broadcast between the replay and registration.
+func (h *WSHub) claimHold(sub *WSSubscription) ([]WSMessage, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hold, ok := h.holds[sub.RunID]
+	if !ok || hold.token != sub.StreamToken || time.Now().After(hold.expires) {
+		return nil, false
+	}
+	delete(h.holds, sub.RunID)
+
+	return append(hold.messages, WSMessage{
+		Type:      "backlog",
+		RunID:     sub.RunID,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Data: map[string]interface{}{
+			"count":   len(hold.messages),
+			"dropped": hold.dropped,
+			"more":    false,
+		},
+	}), true
+}
+
+// heldCount reports how many messages are held for runID
+func (h *WSHub) heldCount(runID string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if hold, ok := h.holds[runID]; ok {
+		return len(hold.messages)
+	}
+	return 0
+}
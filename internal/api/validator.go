@@ -1,34 +1,103 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/mail"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	internal "github.com/taskflow/taskflow/internal"
+	"github.com/taskflow/taskflow/internal/notification"
+	"github.com/taskflow/taskflow/internal/scheduler"
 	"github.com/taskflow/taskflow/internal/store"
 )
 
 // JobValidator validates job creation and update requests
-type JobValidator struct{}
+type JobValidator struct {
+	// workingDirRoots, when non-empty, are the only directories (and their subdirectories)
+	// a job's working_dir may point to
+	workingDirRoots []string
+	// maxTimeoutSeconds, when set, is a ceiling on timeout_seconds below MaxTimeoutSeconds
+	maxTimeoutSeconds int
+	// minScheduleInterval, when set, is the shortest time allowed between a schedule's fires
+	minScheduleInterval time.Duration
+}
 
 // NewJobValidator creates a new job validator
 func NewJobValidator() *JobValidator {
 	return &JobValidator{}
 }
 
+// SetAllowedWorkingDirRoots restricts working_dir to the given roots; empty allows any directory
+func (v *JobValidator) SetAllowedWorkingDirRoots(roots []string) {
+	v.workingDirRoots = roots
+}
+
+// SetMaxTimeoutSeconds lowers the timeout_seconds ceiling; 0, or a value not below
+// MaxTimeoutSeconds, keeps the built-in maximum
+func (v *JobValidator) SetMaxTimeoutSeconds(max int) {
+	v.maxTimeoutSeconds = max
+}
+
+// SetMinScheduleIntervalMinutes rejects schedules that would fire more often than every
+// minutes minutes; 0 allows every-minute schedules
+func (v *JobValidator) SetMinScheduleIntervalMinutes(minutes int) {
+	v.minScheduleInterval = time.Duration(minutes) * time.Minute
+}
+
+// timeoutCeiling returns the largest timeout_seconds a job may set
+func (v *JobValidator) timeoutCeiling() int {
+	if v.maxTimeoutSeconds > 0 && v.maxTimeoutSeconds < internal.MaxTimeoutSeconds {
+		return v.maxTimeoutSeconds
+	}
+	return internal.MaxTimeoutSeconds
+}
+
 // JobRequest represents the common fields for create/update requests
 type JobRequest struct {
-	Name              string           `json:"name"`
-	Description       string           `json:"description"`
-	Script            string           `json:"script"`
-	WorkingDir        string           `json:"working_dir"`
-	TimeoutSeconds    int              `json:"timeout_seconds"`
-	RetryCount        int              `json:"retry_count"`
-	RetryDelaySeconds int              `json:"retry_delay_seconds"`
-	NotifyEmails      string           `json:"notify_emails"`
-	NotifyOn          string           `json:"notify_on"`
-	Timezone          string           `json:"timezone"`
-	Enabled           bool             `json:"enabled"`
-	Schedule          *ScheduleRequest `json:"schedule,omitempty"`
+	Name                     string                 `json:"name"`
+	Description              string                 `json:"description"`
+	Script                   string                 `json:"script"`
+	WorkingDir               string                 `json:"working_dir"`
+	TimeoutSeconds           int                    `json:"timeout_seconds"`
+	RetryCount               int                    `json:"retry_count"`
+	RetryDelaySeconds        int                    `json:"retry_delay_seconds"`
+	RetryOnExitCodes         []int                  `json:"retry_on_exit_codes"`
+	NotifyEmails             string                 `json:"notify_emails"`
+	NotifyOn                 string                 `json:"notify_on"`
+	NotifyExitCodes          []int                  `json:"notify_exit_codes"`
+	NotifyChannels           []string               `json:"notify_channels"`
+	Timezone                 string                 `json:"timezone"`
+	ResourceLock             string                 `json:"resource_lock"`
+	AutoDisableAfterFailures int                    `json:"auto_disable_after_failures"`
+	Nice                     int                    `json:"nice"`
+	Interpreter              string                 `json:"interpreter"`
+	LoginShell               bool                   `json:"login_shell"`
+	TimeoutWarnPercent       int                    `json:"timeout_warn_percent"`
+	TimeoutWarnNotify        bool                   `json:"timeout_warn_notify"`
+	BlackoutWindows          []store.BlackoutWindow `json:"blackout_windows"`
+	SuccessPattern           string                 `json:"success_pattern"`
+	FailurePattern           string                 `json:"failure_pattern"`
+	LogLevelPattern          string                 `json:"log_level_pattern"`
+	Metadata                 map[string]interface{} `json:"metadata"`
+	PreScript                string                 `json:"pre_script"`
+	PostScript               string                 `json:"post_script"`
+	PauseOnFailure           bool                   `json:"pause_on_failure"`
+	ManualWhileScheduled     string                 `json:"manual_while_scheduled"`
+	RequiresApproval         bool                   `json:"requires_approval"`
+	Enabled                  bool                   `json:"enabled"`
+	Schedule                 *ScheduleRequest       `json:"schedule,omitempty"`
+}
+
+// JobTemplateRequest represents the fields for job template create/update requests.
+// Definition holds any subset of a JobRequest's fields.
+type JobTemplateRequest struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Definition  json.RawMessage `json:"definition"`
 }
 
 // ValidationError represents a validation error with code
@@ -63,11 +132,21 @@ func (v *JobValidator) ValidateJobRequest(req *JobRequest) *ValidationError {
 			Code:    "VALIDATION_ERROR",
 		}
 	}
+	if len(req.PreScript) > internal.InlineScriptMaxSize || len(req.PostScript) > internal.InlineScriptMaxSize {
+		return &ValidationError{
+			Message: fmt.Sprintf("Pre/post script too long (max %s)", internal.InlineScriptMaxSizeReadable),
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+
+	if validErr := v.validateWorkingDir(req.WorkingDir); validErr != nil {
+		return validErr
+	}
 
 	// Validate timeout
-	if req.TimeoutSeconds < internal.MinTimeoutSeconds || req.TimeoutSeconds > internal.MaxTimeoutSeconds {
+	if maxTimeout := v.timeoutCeiling(); req.TimeoutSeconds < internal.MinTimeoutSeconds || req.TimeoutSeconds > maxTimeout {
 		return &ValidationError{
-			Message: fmt.Sprintf("Timeout must be between %d and %d seconds", internal.MinTimeoutSeconds, internal.MaxTimeoutSeconds),
+			Message: fmt.Sprintf("Timeout must be between %d and %d seconds", internal.MinTimeoutSeconds, maxTimeout),
 			Code:    "VALIDATION_ERROR",
 		}
 	}
@@ -88,6 +167,101 @@ func (v *JobValidator) ValidateJobRequest(req *JobRequest) *ValidationError {
 		}
 	}
 
+	// Validate auto-disable threshold
+	if req.AutoDisableAfterFailures < 0 || req.AutoDisableAfterFailures > internal.MaxAutoDisableAfterFailures {
+		return &ValidationError{
+			Message: fmt.Sprintf("Auto-disable threshold must be between 0 and %d", internal.MaxAutoDisableAfterFailures),
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+
+	// Validate nice value
+	if req.Nice < internal.MinNice || req.Nice > internal.MaxNice {
+		return &ValidationError{
+			Message: fmt.Sprintf("Nice value must be between %d and %d", internal.MinNice, internal.MaxNice),
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+
+	// Validate timeout warning threshold
+	if req.TimeoutWarnPercent < 0 || req.TimeoutWarnPercent > internal.MaxTimeoutWarnPercent {
+		return &ValidationError{
+			Message: fmt.Sprintf("Timeout warning percent must be between 0 and %d", internal.MaxTimeoutWarnPercent),
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+
+	// Validate blackout windows
+	if len(req.BlackoutWindows) > internal.MaxBlackoutWindows {
+		return &ValidationError{
+			Message: fmt.Sprintf("At most %d blackout windows are allowed", internal.MaxBlackoutWindows),
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+	for i, window := range req.BlackoutWindows {
+		if err := scheduler.ValidateBlackoutWindow(window); err != nil {
+			return &ValidationError{
+				Message: fmt.Sprintf("Invalid blackout window %d: %v", i+1, err),
+				Code:    "VALIDATION_ERROR",
+			}
+		}
+	}
+
+	// Validate output success/failure regexes
+	if _, err := regexp.Compile(req.SuccessPattern); err != nil {
+		return &ValidationError{
+			Message: fmt.Sprintf("Invalid success pattern: %v", err),
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+	if _, err := regexp.Compile(req.FailurePattern); err != nil {
+		return &ValidationError{
+			Message: fmt.Sprintf("Invalid failure pattern: %v", err),
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+	if req.LogLevelPattern != "" {
+		re, err := regexp.Compile(req.LogLevelPattern)
+		if err != nil {
+			return &ValidationError{
+				Message: fmt.Sprintf("Invalid log level pattern: %v", err),
+				Code:    "VALIDATION_ERROR",
+			}
+		}
+		if re.NumSubexp() == 0 {
+			return &ValidationError{
+				Message: "Log level pattern needs a capture group for the level, e.g. ^\\[(\\w+)\\]",
+				Code:    "VALIDATION_ERROR",
+			}
+		}
+	}
+
+	// Validate metadata is a small, flat map of strings
+	if err := validateMetadata(req.Metadata); err != nil {
+		return &ValidationError{
+			Message: fmt.Sprintf("Invalid metadata: %v", err),
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+
+	// Validate interpreter enum; availability on this platform is checked at run time
+	if !v.isValidInterpreter(req.Interpreter) {
+		return &ValidationError{
+			Message: "Interpreter must be one of: bash, sh, cmd, powershell",
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+
+	// Validate manual_while_scheduled enum; empty means allow
+	switch req.ManualWhileScheduled {
+	case "", internal.ManualWhileScheduledAllow, internal.ManualWhileScheduledSkip, internal.ManualWhileScheduledReplace:
+	default:
+		return &ValidationError{
+			Message: "manual_while_scheduled must be one of: allow, skip, replace",
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+
 	// Validate notify_on enum
 	if !v.isValidNotifyOn(req.NotifyOn) {
 		return &ValidationError{
@@ -96,9 +270,75 @@ func (v *JobValidator) ValidateJobRequest(req *JobRequest) *ValidationError {
 		}
 	}
 
+	// Validate resource lock name length
+	if len(req.ResourceLock) > internal.MaxResourceLockLength {
+		return &ValidationError{
+			Message: fmt.Sprintf("Resource lock name too long (max %d characters)", internal.MaxResourceLockLength),
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+
+	// Validate exit code lists
+	if !isValidExitCodeList(req.NotifyExitCodes) {
+		return &ValidationError{
+			Message: "Notify exit codes must be between 0-255",
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+	if !isValidExitCodeList(req.RetryOnExitCodes) {
+		return &ValidationError{
+			Message: "Retry exit codes must be between 0-255",
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+
+	// Validate notification channel names
+	for _, name := range req.NotifyChannels {
+		if !notification.IsKnownChannel(name) {
+			return &ValidationError{
+				Message: fmt.Sprintf("Unknown notification channel %q (must be teams or pagerduty)", name),
+				Code:    "VALIDATION_ERROR",
+			}
+		}
+	}
+
+	// Validate notify_emails addresses
+	if bad := v.findInvalidNotifyEmail(req.NotifyEmails); bad != "" {
+		return &ValidationError{
+			Message: fmt.Sprintf("Invalid notification email address: %q", bad),
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+
 	return nil
 }
 
+// isValidExitCodeList checks that every code is a valid process exit status
+func isValidExitCodeList(codes []int) bool {
+	for _, code := range codes {
+		if code < 0 || code > 255 {
+			return false
+		}
+	}
+	return true
+}
+
+// findInvalidNotifyEmail returns the first malformed address in a comma-separated
+// notify_emails list, or "" if all entries are valid. Empty entries are ignored.
+func (v *JobValidator) findInvalidNotifyEmail(notifyEmails string) string {
+	for _, entry := range strings.Split(notifyEmails, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		addr, err := mail.ParseAddress(entry)
+		if err != nil || addr.Address != entry {
+			return entry
+		}
+	}
+	return ""
+}
+
 // validNotifyValues is a map for O(1) lookup of valid notify_on values
 var validNotifyValues = map[string]bool{
 	internal.NotifyAlways:  true,
@@ -114,6 +354,95 @@ func (v *JobValidator) isValidNotifyOn(notifyOn string) bool {
 	return validNotifyValues[notifyOn]
 }
 
+// parseRoots splits a path-list-separated set of directory roots, dropping empty entries
+func parseRoots(list string) []string {
+	roots := []string{}
+	for _, root := range filepath.SplitList(list) {
+		if root = strings.TrimSpace(root); root != "" {
+			roots = append(roots, filepath.Clean(root))
+		}
+	}
+	return roots
+}
+
+// isUnderRoots reports whether path is one of roots or nested inside one
+func isUnderRoots(path string, roots []string) bool {
+	path = filepath.Clean(path)
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateWorkingDir checks working_dir against the allowed roots, if any. An empty
+// working_dir is checked as the default it will be given.
+func (v *JobValidator) validateWorkingDir(dir string) *ValidationError {
+	if len(v.workingDirRoots) == 0 {
+		return nil
+	}
+	if dir == "" {
+		dir = internal.DefaultWorkingDir
+	}
+	if !filepath.IsAbs(dir) || !isUnderRoots(dir, v.workingDirRoots) {
+		return &ValidationError{
+			Message: fmt.Sprintf("Working directory %q is not under an allowed root (%s)", dir, strings.Join(v.workingDirRoots, string(filepath.ListSeparator))),
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+	return nil
+}
+
+// validInterpreters is a map for O(1) lookup of valid interpreter values
+var validInterpreters = map[string]bool{
+	internal.InterpreterBash:       true,
+	internal.InterpreterSh:         true,
+	internal.InterpreterCmd:        true,
+	internal.InterpreterPowerShell: true,
+}
+
+// metadataKeyPattern restricts metadata keys to characters that are safe in query
+// parameters and JSON paths
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]+$`)
+
+// validateMetadata checks that metadata is a flat string map within the size limits
+func validateMetadata(metadata map[string]interface{}) error {
+	for key, value := range metadata {
+		if len(key) > internal.MaxJobMetadataKeyLength || !metadataKeyPattern.MatchString(key) {
+			return fmt.Errorf("key %q must be 1-%d letters, digits or _.:-", key, internal.MaxJobMetadataKeyLength)
+		}
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("value for %q must be a string", key)
+		}
+	}
+	if data, _ := json.Marshal(metadata); len(data) > internal.MaxJobMetadataSize {
+		return fmt.Errorf("must be at most %d bytes", internal.MaxJobMetadataSize)
+	}
+	return nil
+}
+
+// metadataStrings converts validated request metadata to a string map
+func metadataStrings(metadata map[string]interface{}) map[string]string {
+	if len(metadata) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		out[key], _ = value.(string)
+	}
+	return out
+}
+
+// isValidInterpreter checks if the interpreter value is valid
+func (v *JobValidator) isValidInterpreter(interpreter string) bool {
+	if interpreter == "" {
+		return true // Empty is allowed (platform default)
+	}
+	return validInterpreters[interpreter]
+}
+
 // ApplyDefaults applies default values to job request fields
 func (v *JobValidator) ApplyDefaults(req *JobRequest) {
 	if req.WorkingDir == "" {
@@ -133,16 +462,36 @@ func (v *JobValidator) ApplyDefaults(req *JobRequest) {
 // ToJobModel converts a validated request to a job model
 func (v *JobValidator) ToJobModel(req *JobRequest, jobID *string) *store.Job {
 	job := &store.Job{
-		Name:              req.Name,
-		Description:       req.Description,
-		Script:            req.Script,
-		WorkingDir:        req.WorkingDir,
-		TimeoutSeconds:    req.TimeoutSeconds,
-		RetryCount:        req.RetryCount,
-		RetryDelaySeconds: req.RetryDelaySeconds,
-		NotifyEmails:      req.NotifyEmails,
-		NotifyOn:          req.NotifyOn,
-		Timezone:          req.Timezone,
+		Name:                     req.Name,
+		Description:              req.Description,
+		Script:                   req.Script,
+		WorkingDir:               req.WorkingDir,
+		TimeoutSeconds:           req.TimeoutSeconds,
+		RetryCount:               req.RetryCount,
+		RetryDelaySeconds:        req.RetryDelaySeconds,
+		RetryOnExitCodes:         req.RetryOnExitCodes,
+		NotifyEmails:             req.NotifyEmails,
+		NotifyOn:                 req.NotifyOn,
+		NotifyExitCodes:          req.NotifyExitCodes,
+		NotifyChannels:           req.NotifyChannels,
+		Timezone:                 req.Timezone,
+		ResourceLock:             strings.TrimSpace(req.ResourceLock),
+		AutoDisableAfterFailures: req.AutoDisableAfterFailures,
+		Nice:                     req.Nice,
+		Interpreter:              req.Interpreter,
+		LoginShell:               req.LoginShell,
+		TimeoutWarnPercent:       req.TimeoutWarnPercent,
+		TimeoutWarnNotify:        req.TimeoutWarnNotify,
+		SuccessPattern:           req.SuccessPattern,
+		FailurePattern:           req.FailurePattern,
+		LogLevelPattern:          req.LogLevelPattern,
+		BlackoutWindows:          req.BlackoutWindows,
+		Metadata:                 metadataStrings(req.Metadata),
+		PreScript:                req.PreScript,
+		PostScript:               req.PostScript,
+		PauseOnFailure:           req.PauseOnFailure,
+		ManualWhileScheduled:     req.ManualWhileScheduled,
+		RequiresApproval:         req.RequiresApproval,
 	}
 	if jobID != nil {
 		job.ID = *jobID
@@ -150,18 +499,171 @@ func (v *JobValidator) ToJobModel(req *JobRequest, jobID *string) *store.Job {
 	return job
 }
 
+// jobRequestFromModel builds the request that would save job with schedule, so a stored
+// definition, such as one in a backup, can be checked like one sent to the API
+func jobRequestFromModel(job *store.Job, schedule *store.Schedule) *JobRequest {
+	req := &JobRequest{
+		Name:                     job.Name,
+		Description:              job.Description,
+		Script:                   job.Script,
+		WorkingDir:               job.WorkingDir,
+		TimeoutSeconds:           job.TimeoutSeconds,
+		RetryCount:               job.RetryCount,
+		RetryDelaySeconds:        job.RetryDelaySeconds,
+		RetryOnExitCodes:         job.RetryOnExitCodes,
+		NotifyEmails:             job.NotifyEmails,
+		NotifyOn:                 job.NotifyOn,
+		NotifyExitCodes:          job.NotifyExitCodes,
+		NotifyChannels:           job.NotifyChannels,
+		Timezone:                 job.Timezone,
+		ResourceLock:             job.ResourceLock,
+		AutoDisableAfterFailures: job.AutoDisableAfterFailures,
+		Nice:                     job.Nice,
+		Interpreter:              job.Interpreter,
+		LoginShell:               job.LoginShell,
+		TimeoutWarnPercent:       job.TimeoutWarnPercent,
+		TimeoutWarnNotify:        job.TimeoutWarnNotify,
+		BlackoutWindows:          job.BlackoutWindows,
+		SuccessPattern:           job.SuccessPattern,
+		FailurePattern:           job.FailurePattern,
+		LogLevelPattern:          job.LogLevelPattern,
+		PreScript:                job.PreScript,
+		PostScript:               job.PostScript,
+		PauseOnFailure:           job.PauseOnFailure,
+		ManualWhileScheduled:     job.ManualWhileScheduled,
+		RequiresApproval:         job.RequiresApproval,
+		Enabled:                  job.Enabled,
+	}
+	if len(job.Metadata) > 0 {
+		req.Metadata = make(map[string]interface{}, len(job.Metadata))
+		for key, value := range job.Metadata {
+			req.Metadata[key] = value
+		}
+	}
+	if schedule != nil {
+		req.Schedule = &ScheduleRequest{
+			Years:    schedule.Years,
+			Months:   schedule.Months,
+			Days:     schedule.Days,
+			Weekdays: schedule.Weekdays,
+			Hours:    schedule.Hours,
+			Minutes:  schedule.Minutes,
+			Holidays: schedule.Holidays,
+			Solar:    schedule.Solar,
+			RunAt:    schedule.RunAt,
+		}
+	}
+	return req
+}
+
+// ValidateTemplateRequest validates a job template. The definition may be partial, so
+// required job fields are only enforced when a job is created from it, but every field
+// it does contain must decode and its schedule, if any, must be valid.
+func (v *JobValidator) ValidateTemplateRequest(req *JobTemplateRequest) *ValidationError {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return &ValidationError{Message: "Template name is required", Code: "VALIDATION_ERROR"}
+	}
+	if len(name) > internal.MaxJobNameLength {
+		return &ValidationError{
+			Message: fmt.Sprintf("Template name too long (max %d characters)", internal.MaxJobNameLength),
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+
+	var definition JobRequest
+	if len(req.Definition) == 0 || req.Definition[0] != '{' || json.Unmarshal(req.Definition, &definition) != nil {
+		return &ValidationError{Message: "Template definition must be a job object", Code: "VALIDATION_ERROR"}
+	}
+	if len(definition.Script) > internal.MaxScriptSize {
+		return &ValidationError{
+			Message: fmt.Sprintf("Script too long (max %s)", internal.MaxScriptSizeReadable),
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+	if definition.Schedule != nil {
+		return v.ValidateScheduleRequest(definition.Schedule)
+	}
+	return nil
+}
+
+// TriggerRequest represents the optional body of a manual trigger
+type TriggerRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// ValidateTriggerRequest validates run tags and drops duplicates
+func (v *JobValidator) ValidateTriggerRequest(req *TriggerRequest) *ValidationError {
+	if len(req.Tags) > internal.MaxRunTags {
+		return &ValidationError{
+			Message: fmt.Sprintf("At most %d tags are allowed per run", internal.MaxRunTags),
+			Code:    "VALIDATION_ERROR",
+		}
+	}
+
+	seen := make(map[string]bool, len(req.Tags))
+	tags := make([]string, 0, len(req.Tags))
+	for _, tag := range req.Tags {
+		// Tags share the metadata key alphabet so they pass through ?tag= unescaped
+		if len(tag) > internal.MaxRunTagLength || !metadataKeyPattern.MatchString(tag) {
+			return &ValidationError{
+				Message: fmt.Sprintf("Tag %q must be 1-%d letters, digits or _.:-", tag, internal.MaxRunTagLength),
+				Code:    "VALIDATION_ERROR",
+			}
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	req.Tags = tags
+	return nil
+}
+
 // ScheduleRequest represents the fields for schedule create/update requests
 type ScheduleRequest struct {
-	Years    []int `json:"years"`
-	Months   []int `json:"months"`
-	Days     []int `json:"days"`
-	Weekdays []int `json:"weekdays"`
-	Hours    []int `json:"hours"`
-	Minutes  []int `json:"minutes"`
+	Years    []int              `json:"years"`
+	Months   []int              `json:"months"`
+	Days     []int              `json:"days"`
+	Weekdays []int              `json:"weekdays"`
+	Hours    []int              `json:"hours"`
+	Minutes  []int              `json:"minutes"`
+	Holidays []string           `json:"holidays"`
+	Solar    *store.SolarAnchor `json:"solar"`
+	RunAt    *time.Time         `json:"run_at"` // one-shot; cannot be combined with the fields above
+}
+
+// toSchedule builds the schedule to save for jobID from the request
+func (req *ScheduleRequest) toSchedule(jobID string) *store.Schedule {
+	return &store.Schedule{
+		JobID:    jobID,
+		Years:    req.Years,
+		Months:   req.Months,
+		Days:     req.Days,
+		Weekdays: req.Weekdays,
+		Hours:    req.Hours,
+		Minutes:  req.Minutes,
+		Holidays: req.Holidays,
+		Solar:    req.Solar,
+		RunAt:    runAtMinute(req.RunAt),
+	}
+}
+
+// runAtMinute normalizes a one-shot time to UTC at the minute it fires in
+func runAtMinute(runAt *time.Time) *time.Time {
+	if runAt == nil {
+		return nil
+	}
+	t := runAt.UTC().Truncate(time.Minute)
+	return &t
 }
 
 // ValidateScheduleRequest validates all schedule fields
 func (v *JobValidator) ValidateScheduleRequest(req *ScheduleRequest) *ValidationError {
+	if req.RunAt != nil {
+		return validateRunAt(req)
+	}
+
 	// Validate months
 	for _, m := range req.Months {
 		if m < 1 || m > 12 {
@@ -212,5 +714,72 @@ func (v *JobValidator) ValidateScheduleRequest(req *ScheduleRequest) *Validation
 		}
 	}
 
+	// Validate holidays
+	for _, date := range req.Holidays {
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return &ValidationError{
+				Message: fmt.Sprintf("Holiday %q must be a YYYY-MM-DD date", date),
+				Code:    "VALIDATION_ERROR",
+			}
+		}
+	}
+
+	if req.Solar != nil {
+		if validErr := validateSolarAnchor(req); validErr != nil {
+			return validErr
+		}
+	}
+
+	return v.validateScheduleInterval(req.toSchedule(""))
+}
+
+// validateScheduleInterval rejects a schedule whose consecutive fires are closer than the
+// configured minimum interval
+func (v *JobValidator) validateScheduleInterval(schedule *store.Schedule) *ValidationError {
+	if v.minScheduleInterval <= 0 {
+		return nil
+	}
+	gap, ok := scheduler.MinFireInterval(schedule)
+	if !ok || gap >= v.minScheduleInterval {
+		return nil
+	}
+	return &ValidationError{
+		Message: fmt.Sprintf("Schedule fires as little as %d minute(s) apart; the minimum interval is %d minutes",
+			int(gap.Minutes()), int(v.minScheduleInterval.Minutes())),
+		Code: "VALIDATION_ERROR",
+	}
+}
+
+// validateRunAt checks a one-shot schedule, which fires once and takes the place of every
+// recurring field
+func validateRunAt(req *ScheduleRequest) *ValidationError {
+	if len(req.Years) > 0 || len(req.Months) > 0 || len(req.Days) > 0 || len(req.Weekdays) > 0 ||
+		len(req.Hours) > 0 || len(req.Minutes) > 0 || len(req.Holidays) > 0 || req.Solar != nil {
+		return &ValidationError{Message: "run_at cannot be combined with recurring schedule fields", Code: "VALIDATION_ERROR"}
+	}
+	if !req.RunAt.After(time.Now()) {
+		return &ValidationError{Message: "run_at must be in the future", Code: "VALIDATION_ERROR"}
+	}
+	return nil
+}
+
+// validateSolarAnchor checks a sunrise/sunset anchor, which takes the place of hours and minutes
+func validateSolarAnchor(req *ScheduleRequest) *ValidationError {
+	solar := req.Solar
+	switch {
+	case solar.Event != internal.SolarEventSunrise && solar.Event != internal.SolarEventSunset:
+		return &ValidationError{Message: "Solar event must be sunrise or sunset", Code: "VALIDATION_ERROR"}
+	case solar.Latitude < -90 || solar.Latitude > 90:
+		return &ValidationError{Message: "Solar latitude must be between -90 and 90", Code: "VALIDATION_ERROR"}
+	case solar.Longitude < -180 || solar.Longitude > 180:
+		return &ValidationError{Message: "Solar longitude must be between -180 and 180", Code: "VALIDATION_ERROR"}
+	case solar.OffsetMinutes < -internal.MaxSolarOffsetMinutes || solar.OffsetMinutes > internal.MaxSolarOffsetMinutes:
+		return &ValidationError{
+			Message: fmt.Sprintf("Solar offset_minutes must be between -%d and %d", internal.MaxSolarOffsetMinutes, internal.MaxSolarOffsetMinutes),
+			Code:    "VALIDATION_ERROR",
+		}
+	case len(req.Hours) > 0 || len(req.Minutes) > 0:
+		return &ValidationError{Message: "Hours and minutes must be empty when a solar anchor sets the time of day", Code: "VALIDATION_ERROR"}
+	}
 	return nil
 }
@@ -1,24 +1,54 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"net/url"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
+	internal "github.com/taskflow/taskflow/internal"
+	"github.com/taskflow/taskflow/internal/store"
 )
 
-// WSHub manages WebSocket connections for log streaming
+// GlobalActivityRunID is the pseudo run ID that activity subscribers register under.
+// Every run's status changes are mirrored to it as "activity" messages.
+const GlobalActivityRunID = "_global"
+
+// WSHub fans broadcast messages out to the subscribers of each run, over WebSockets or
+// server-sent events
 type WSHub struct {
-	clients         map[string]map[*websocket.Conn]bool
+	clients         map[string]map[Subscriber]*WSSubscription
 	broadcast       chan WSMessage
 	register        chan *WSSubscription
 	unregister      chan *WSSubscription
+	disconnect      chan disconnectRequest
 	mu              sync.RWMutex
-	allowedOrigins  string
+	allowAllOrigins bool
+	allowedOrigins  []allowedOrigin
+	pingInterval    time.Duration
+	logs            *store.Store
+	compression     bool
+
+	// Log subscriptions per run, counted from before the upgrade until the reader exits
+	maxConnsPerRun int
+	runConns       map[string]int
+
+	// holds buffers the messages of runs with an outstanding stream token, by run ID
+	holds map[string]*streamHold
+}
+
+// allowedOrigin is one ALLOWED_ORIGINS entry reduced to the parts compared against Origin headers
+type allowedOrigin struct {
+	scheme string
+	host   string
 }
 
 // WSMessage represents a message to broadcast
@@ -27,20 +57,114 @@ type WSMessage struct {
 	RunID     string      `json:"run_id"`
 	Timestamp string      `json:"timestamp"`
 	Data      interface{} `json:"data,omitempty"`
+
+	owner string // ID of the user who owns the run's job, "" if unknown; never sent
+	logID int    // stored log entry ID of a "log" message, 0 if unknown; never sent
+}
+
+// LogMessage builds the message for one output line of a run. id is the line's stored
+// log entry ID, sent as data.id so clients can resume from it; 0 leaves it out.
+func LogMessage(runID string, id int, stream, content string, timestamp time.Time) WSMessage {
+	data := map[string]interface{}{
+		"stream":  stream,
+		"content": content,
+	}
+	if id > 0 {
+		data["id"] = id
+	}
+	return WSMessage{
+		Type:      "log",
+		RunID:     runID,
+		Timestamp: timestamp.Format(time.RFC3339),
+		Data:      data,
+		logID:     id,
+	}
+}
+
+// StatusMessage builds the status-change message for a run, including the job it belongs to
+func StatusMessage(runID, status string, job *store.Job) WSMessage {
+	data := map[string]string{"status": status}
+	if job != nil {
+		data["job_id"] = job.ID
+		data["job_name"] = job.Name
+	}
+	msg := WSMessage{
+		Type:      "status",
+		RunID:     runID,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Data:      data,
+	}
+	if job != nil {
+		msg.owner = strconv.Itoa(job.CreatedBy)
+	}
+	return msg
+}
+
+// Subscriber is one client connection receiving a run's messages, whatever the transport.
+// The hub calls Send and Close from its Run goroutine only.
+type Subscriber interface {
+	// Send writes one JSON-encoded WSMessage; an error drops the subscriber
+	Send(data []byte) error
+	// Close ends the connection once the subscriber is dropped or unregistered
+	Close() error
 }
 
 // WSSubscription represents a client subscribing to a run's logs
 type WSSubscription struct {
-	RunID string
-	Conn  *websocket.Conn
+	RunID       string
+	Subscriber  Subscriber
+	Backlog     []WSMessage // stored messages replayed before live ones
+	StreamToken string      // replays the run's held messages instead of Backlog
+	// Allow, if set, picks the live messages the subscriber receives
+	Allow func(msg WSMessage) bool
+
+	// backlog, if set, is loaded into Backlog by Run as the subscriber registers;
+	// afterID is the last log ID it replayed, and live lines up to it are skipped
+	backlog *backlogRequest
+	afterID int
 }
 
-// isOriginAllowed checks if a WebSocket origin is allowed
-func isOriginAllowed(origin string, allowedOrigins string) bool {
-	if allowedOrigins == "*" {
-		return true
-	}
+// backlogRequest is a resuming client's from_id and max_backlog
+type backlogRequest struct {
+	fromID     int
+	maxBacklog int
+}
+
+// disconnectRequest asks Run to drop every subscriber of a run and reply with how many there were
+type disconnectRequest struct {
+	runID  string
+	closed chan int
+}
 
+// WSConnectionStats counts a run's live log connections
+type WSConnectionStats struct {
+	RunID string `json:"run_id"`
+	// Subscribers are registered with the hub and receive broadcasts
+	Subscribers int `json:"subscribers"`
+	// Reserved are slots held against the per-run cap, including connections still
+	// upgrading or not yet unregistered; a lasting gap to Subscribers points to a leak
+	Reserved int `json:"reserved"`
+}
+
+// wsSubscriber delivers messages as WebSocket text frames
+type wsSubscriber struct {
+	conn *websocket.Conn
+}
+
+// Send writes data as a text frame, deflating it only when it is large enough to benefit.
+// Compression applies only if the client negotiated it.
+func (s *wsSubscriber) Send(data []byte) error {
+	s.conn.EnableWriteCompression(len(data) >= internal.WSCompressionMinBytes)
+	return s.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// Close closes the WebSocket connection
+func (s *wsSubscriber) Close() error {
+	return s.conn.Close()
+}
+
+// isOriginAllowed checks if a WebSocket origin is allowed
+func isOriginAllowed(origin string, allowed []allowedOrigin) bool {
 	// Parse origin URL
 	originURL, err := url.Parse(origin)
 	if err != nil {
@@ -48,45 +172,161 @@ func isOriginAllowed(origin string, allowedOrigins string) bool {
 	}
 
 	// Use slices.ContainsFunc for idiomatic slice searching (Go 1.21+)
-	allowed := strings.Split(allowedOrigins, ",")
-	return slices.ContainsFunc(allowed, func(ao string) bool {
-		allowedURL, err := url.Parse(strings.TrimSpace(ao))
-		return err == nil &&
-			originURL.Scheme == allowedURL.Scheme &&
-			originURL.Host == allowedURL.Host
+	return slices.ContainsFunc(allowed, func(ao allowedOrigin) bool {
+		return originURL.Scheme == ao.scheme && originURL.Host == ao.host
 	})
 }
 
-// NewWSHub creates a new WebSocket hub with CORS validation
-func NewWSHub(allowedOrigins string) *WSHub {
-	return &WSHub{
-		clients:        make(map[string]map[*websocket.Conn]bool),
+// parseAllowedOrigins parses a comma-separated origin allowlist. Entries that are not a
+// bare scheme://host[:port] are returned in invalid; blank entries are ignored.
+func parseAllowedOrigins(allowedOrigins string) (origins []allowedOrigin, invalid []string) {
+	for _, entry := range strings.Split(allowedOrigins, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		u, err := url.Parse(entry)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.User != nil ||
+			(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+			invalid = append(invalid, entry)
+			continue
+		}
+		origins = append(origins, allowedOrigin{scheme: u.Scheme, host: u.Host})
+	}
+	return origins, invalid
+}
+
+// NewWSHub creates a new WebSocket hub with CORS validation.
+// The allowlist is parsed once here: unparseable entries are logged and ignored, and
+// an allowlist with no usable entries (other than "*") is an error.
+func NewWSHub(allowedOrigins string) (*WSHub, error) {
+	h := &WSHub{
+		clients:        make(map[string]map[Subscriber]*WSSubscription),
 		broadcast:      make(chan WSMessage, 100),
 		register:       make(chan *WSSubscription),
 		unregister:     make(chan *WSSubscription),
-		allowedOrigins: allowedOrigins,
+		disconnect:     make(chan disconnectRequest),
+		pingInterval:   internal.DefaultWSPingInterval,
+		maxConnsPerRun: internal.DefaultWSMaxConnectionsPerRun,
+		runConns:       make(map[string]int),
+		holds:          make(map[string]*streamHold),
+		compression:    true,
+	}
+
+	if strings.TrimSpace(allowedOrigins) == "*" {
+		h.allowAllOrigins = true
+		return h, nil
+	}
+
+	origins, invalid := parseAllowedOrigins(allowedOrigins)
+	for _, entry := range invalid {
+		log.Printf("Warning: ignoring invalid allowed origin %q (expected scheme://host)\n", entry)
 	}
+	if len(origins) == 0 {
+		return nil, fmt.Errorf("no valid origins in allowlist %q", allowedOrigins)
+	}
+	h.allowedOrigins = origins
+	return h, nil
+}
+
+// originAllowed reports whether a request's Origin header passes the allowlist
+func (h *WSHub) originAllowed(origin string) bool {
+	return h.allowAllOrigins || isOriginAllowed(origin, h.allowedOrigins)
+}
+
+// SetPingInterval sets how often the server pings each client. A client that
+// misses two consecutive pongs is disconnected. Zero disables server pings.
+// Must be called before the hub starts accepting connections.
+func (h *WSHub) SetPingInterval(interval time.Duration) {
+	h.pingInterval = interval
 }
 
-// Run starts the WebSocket hub
+// SetLogStore sets the store that log subscribers' backlogs are read from.
+// Without one, from_id and max_backlog are rejected.
+// Must be called before the hub starts accepting connections.
+func (h *WSHub) SetLogStore(st *store.Store) {
+	h.logs = st
+}
+
+// SetMaxConnectionsPerRun caps concurrent log subscriptions to one run; further upgrades
+// are rejected with 429. Zero removes the cap.
+// Must be called before the hub starts accepting connections.
+func (h *WSHub) SetMaxConnectionsPerRun(max int) {
+	h.maxConnsPerRun = max
+}
+
+// SetCompression turns per-message deflate on or off. When on, it is negotiated with
+// clients that support it and applied to messages of at least WSCompressionMinBytes.
+// Must be called before the hub starts accepting connections.
+func (h *WSHub) SetCompression(enabled bool) {
+	h.compression = enabled
+}
+
+// reserveRunConn claims a log subscription slot for runID, reporting false if the run
+// is at its cap. Every successful reservation must be paired with releaseRunConn.
+func (h *WSHub) reserveRunConn(runID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.maxConnsPerRun > 0 && h.runConns[runID] >= h.maxConnsPerRun {
+		return false
+	}
+	h.runConns[runID]++
+	return true
+}
+
+// releaseRunConn returns a slot claimed by reserveRunConn
+func (h *WSHub) releaseRunConn(runID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.runConns[runID] <= 1 {
+		delete(h.runConns, runID)
+		return
+	}
+	h.runConns[runID]--
+}
+
+// Run starts the hub
 func (h *WSHub) Run() {
 	for {
 		select {
 		case sub := <-h.register:
+			if sub.StreamToken != "" {
+				held, ok := h.claimHold(sub)
+				if !ok {
+					sub.Subscriber.Close()
+					continue
+				}
+				sub.Backlog = held
+			} else if sub.backlog != nil {
+				// Every line broadcast before this point is already stored, so loading
+				// here leaves no gap before the live lines; afterID drops the overlap
+				backlog, lastID, err := h.logBacklog(sub.RunID, sub.backlog.fromID, sub.backlog.maxBacklog)
+				if err != nil {
+					log.Printf("Failed to load log backlog for run %s: %v\n", sub.RunID, err)
+					sub.Subscriber.Close()
+					continue
+				}
+				sub.Backlog, sub.afterID = backlog, lastID
+			}
+			// Replay before registering so the backlog precedes any live message
+			if !h.replay(sub) {
+				sub.Subscriber.Close()
+				continue
+			}
 			h.mu.Lock()
 			if h.clients[sub.RunID] == nil {
-				h.clients[sub.RunID] = make(map[*websocket.Conn]bool)
+				h.clients[sub.RunID] = make(map[Subscriber]*WSSubscription)
 			}
-			h.clients[sub.RunID][sub.Conn] = true
+			h.clients[sub.RunID][sub.Subscriber] = sub
 			h.mu.Unlock()
 			log.Printf("Client registered for run %s\n", sub.RunID)
 
 		case unsub := <-h.unregister:
 			h.mu.Lock()
 			if conns, ok := h.clients[unsub.RunID]; ok {
-				if _, ok := conns[unsub.Conn]; ok {
-					delete(conns, unsub.Conn)
-					unsub.Conn.Close()
+				if _, ok := conns[unsub.Subscriber]; ok {
+					delete(conns, unsub.Subscriber)
+					unsub.Subscriber.Close()
 					if len(conns) == 0 {
 						delete(h.clients, unsub.RunID)
 					}
@@ -94,33 +334,166 @@ func (h *WSHub) Run() {
 			}
 			h.mu.Unlock()
 
+		case req := <-h.disconnect:
+			h.mu.Lock()
+			conns := h.clients[req.runID]
+			delete(h.clients, req.runID)
+			h.mu.Unlock()
+			// Closing makes each connection's handler exit and release its slot
+			for sub := range conns {
+				sub.Close()
+			}
+			req.closed <- len(conns)
+
 		case msg := <-h.broadcast:
-			h.mu.RLock()
-			if conns, ok := h.clients[msg.RunID]; ok {
-				for conn := range conns {
-					if err := conn.WriteJSON(msg); err != nil {
-						h.unregister <- &WSSubscription{
-							RunID: msg.RunID,
-							Conn:  conn,
-						}
-					}
-				}
+			h.send(msg.RunID, msg)
+			h.hold(msg)
+
+			// Mirror status changes to the global activity topic
+			if msg.Type == "status" && msg.RunID != GlobalActivityRunID {
+				activity := msg
+				activity.Type = "activity"
+				h.send(GlobalActivityRunID, activity)
 			}
-			h.mu.RUnlock()
 		}
 	}
 }
 
+// send writes msg to every subscriber of key, dropping subscribers that fail.
+// Only called from Run, so failed subscribers are removed directly rather than
+// via the unregister channel (which Run itself drains).
+func (h *WSHub) send(key string, msg WSMessage) {
+	var failed []Subscriber
+
+	// Encode once for every subscriber rather than once per connection
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Failed to encode WebSocket message: %v\n", err)
+		return
+	}
+
+	h.mu.RLock()
+	for sub, subscription := range h.clients[key] {
+		if subscription.Allow != nil && !subscription.Allow(msg) {
+			continue
+		}
+		if msg.logID != 0 && msg.logID <= subscription.afterID {
+			continue
+		}
+		if err := sub.Send(data); err != nil {
+			failed = append(failed, sub)
+		}
+	}
+	h.mu.RUnlock()
+
+	if len(failed) == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	for _, sub := range failed {
+		if conns, ok := h.clients[key]; ok {
+			delete(conns, sub)
+			if len(conns) == 0 {
+				delete(h.clients, key)
+			}
+		}
+		sub.Close()
+	}
+	h.mu.Unlock()
+}
+
+// replay writes a subscription's backlog to its subscriber, reporting whether every write
+// succeeded. Only called from Run, which is the sole writer to registered subscribers.
+func (h *WSHub) replay(sub *WSSubscription) bool {
+	for _, msg := range sub.Backlog {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return false
+		}
+		if err := sub.Subscriber.Send(data); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// subscriberCount returns the number of subscribers registered for key
+func (h *WSHub) subscriberCount(key string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients[key])
+}
+
+// Connections returns a snapshot of the connection counts of every run with a registered
+// subscriber or a reserved slot, ordered by run ID
+func (h *WSHub) Connections() []WSConnectionStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := make([]WSConnectionStats, 0, len(h.clients))
+	seen := make(map[string]bool, len(h.clients))
+	for runID, conns := range h.clients {
+		stats = append(stats, WSConnectionStats{RunID: runID, Subscribers: len(conns), Reserved: h.runConns[runID]})
+		seen[runID] = true
+	}
+	for runID, reserved := range h.runConns {
+		if !seen[runID] {
+			stats = append(stats, WSConnectionStats{RunID: runID, Reserved: reserved})
+		}
+	}
+	slices.SortFunc(stats, func(a, b WSConnectionStats) int { return strings.Compare(a.RunID, b.RunID) })
+	return stats
+}
+
+// Disconnect closes every subscriber registered for runID and returns how many there were.
+// It waits for Run, which must be running.
+func (h *WSHub) Disconnect(runID string) int {
+	req := disconnectRequest{runID: runID, closed: make(chan int, 1)}
+	h.disconnect <- req
+	return <-req.closed
+}
+
+// HandleListConnections handles GET /api/admin/ws/connections (admin only).
+// Must be wrapped in auth middleware.
+func (h *WSHub) HandleListConnections(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
+		return
+	}
+	WriteJSON(w, http.StatusOK, h.Connections())
+}
+
+// HandleDisconnect handles POST /api/admin/ws/disconnect?run_id= (admin only), force-closing
+// every log and activity connection subscribed to the run. Must be wrapped in auth middleware.
+func (h *WSHub) HandleDisconnect(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		WriteError(w, http.StatusForbidden, "Admin access required", "FORBIDDEN")
+		return
+	}
+
+	runID := r.URL.Query().Get("run_id")
+	if runID == "" {
+		WriteError(w, http.StatusBadRequest, "run_id is required", "VALIDATION_ERROR")
+		return
+	}
+
+	closed := h.Disconnect(runID)
+	log.Printf("Force-disconnected %d subscribers of run %s\n", closed, runID)
+	WriteJSON(w, http.StatusOK, map[string]interface{}{"run_id": runID, "disconnected": closed})
+}
+
 // Broadcast sends a message to all clients for a run
 func (h *WSHub) Broadcast(msg WSMessage) {
 	h.broadcast <- msg
 }
 
-// HandleLogsWebSocket handles WebSocket upgrade for log streaming
+// HandleLogsWebSocket handles WebSocket upgrade for log streaming. Must be wrapped in
+// auth middleware; runs the requester may not read are reported as not found.
 func (h *WSHub) HandleLogsWebSocket(w http.ResponseWriter, r *http.Request) {
 	// Validate origin for WebSocket connection
 	origin := r.Header.Get("Origin")
-	if origin != "" && !isOriginAllowed(origin, h.allowedOrigins) {
+	if origin != "" && !h.originAllowed(origin) {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
@@ -130,32 +503,193 @@ func (h *WSHub) HandleLogsWebSocket(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Missing run_id parameter", http.StatusBadRequest)
 		return
 	}
+	// The activity topic is only reachable through HandleActivityWebSocket, which filters it
+	if runID == GlobalActivityRunID {
+		http.Error(w, "Invalid run_id parameter", http.StatusBadRequest)
+		return
+	}
+	if h.logs != nil {
+		if _, ok := getVisibleRun(h.logs, r, runID); !ok {
+			http.Error(w, "Run not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	// A stream token from POST /api/jobs/{id}/run replays the run from its first line
+	token := r.URL.Query().Get("stream_token")
+	if token != "" && !h.streamTokenValid(runID, token) {
+		http.Error(w, "Invalid or expired stream token", http.StatusUnauthorized)
+		return
+	}
+
+	var backlog *backlogRequest
+	if token == "" {
+		var err error
+		backlog, err = h.requestedBacklog(r, runID)
+		if err != nil {
+			http.Error(w, err.Error(), backlogErrorStatus(err))
+			return
+		}
+	}
+
+	// Claim the slot before upgrading so a flood of concurrent upgrades can't overshoot the cap
+	if !h.reserveRunConn(runID) {
+		http.Error(w, "Too many connections for this run", http.StatusTooManyRequests)
+		return
+	}
+
+	sub := &WSSubscription{RunID: runID, StreamToken: token, backlog: backlog}
+	h.subscribe(w, r, sub, func() { h.releaseRunConn(runID) })
+}
+
+// errBacklogUnauthenticated is returned when stored lines are asked for without a user
+var errBacklogUnauthenticated = errors.New("from_id and max_backlog require authentication")
+
+// requestedBacklog validates the stored lines a resuming client asks for with from_id or
+// max_backlog before switching to live messages; it returns nil when neither is set.
+// Run loads them once the subscriber registers.
+func (h *WSHub) requestedBacklog(r *http.Request, runID string) (*backlogRequest, error) {
+	query := r.URL.Query()
+	if !query.Has("from_id") && !query.Has("max_backlog") {
+		return nil, nil
+	}
+	// Stored lines outlive the run, so they are never served to an anonymous connection
+	if r.Header.Get("X-User-ID") == "" {
+		return nil, errBacklogUnauthenticated
+	}
+	if h.logs == nil {
+		return nil, errors.New("log backlog is not available")
+	}
+	fromID, maxBacklog, err := parseBacklogParams(query)
+	if err != nil {
+		return nil, err
+	}
+	return &backlogRequest{fromID: fromID, maxBacklog: maxBacklog}, nil
+}
+
+// backlogErrorStatus maps a requestedBacklog error to its HTTP status
+func backlogErrorStatus(err error) int {
+	if errors.Is(err, errBacklogUnauthenticated) {
+		return http.StatusUnauthorized
+	}
+	return http.StatusBadRequest
+}
+
+// parseBacklogParams reads from_id (default 0) and max_backlog (default DefaultWSMaxBacklog)
+func parseBacklogParams(query url.Values) (fromID, maxBacklog int, err error) {
+	if v := query.Get("from_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 0 {
+			return 0, 0, fmt.Errorf("from_id must be a non-negative integer")
+		}
+		fromID = id
+	}
+
+	maxBacklog = internal.DefaultWSMaxBacklog
+	if v := query.Get("max_backlog"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > internal.MaxWSBacklog {
+			return 0, 0, fmt.Errorf("max_backlog must be between 1 and %d", internal.MaxWSBacklog)
+		}
+		maxBacklog = n
+	}
+	return fromID, maxBacklog, nil
+}
+
+// logBacklog builds the replay for a resuming client: stored lines with an ID above fromID,
+// oldest first and at most maxBacklog of them, followed by a "backlog" message carrying the
+// last replayed ID and whether more lines remain. It also returns that last ID.
+func (h *WSHub) logBacklog(runID string, fromID, maxBacklog int) ([]WSMessage, int, error) {
+	// Fetch one extra line to learn whether the backlog was cut short
+	entries, err := h.logs.GetLogsSince(runID, fromID, maxBacklog+1)
+	if err != nil {
+		return nil, 0, err
+	}
+	more := len(entries) > maxBacklog
+	if more {
+		entries = entries[:maxBacklog]
+	}
+
+	lastID := fromID
+	backlog := make([]WSMessage, 0, len(entries)+1)
+	for _, entry := range entries {
+		backlog = append(backlog, LogMessage(runID, entry.ID, entry.Stream, entry.Content, entry.Timestamp))
+		lastID = entry.ID
+	}
+	backlog = append(backlog, WSMessage{
+		Type:      "backlog",
+		RunID:     runID,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Data: map[string]interface{}{
+			"count":   len(entries),
+			"last_id": lastID,
+			"more":    more,
+		},
+	})
+	return backlog, lastID, nil
+}
+
+// HandleActivityWebSocket handles GET /api/ws/activity, streaming status changes for all runs
+// to admins and for the runs of their own jobs to other users. Must be wrapped in auth middleware.
+func (h *WSHub) HandleActivityWebSocket(w http.ResponseWriter, r *http.Request) {
+	// Validate origin for WebSocket connection
+	origin := r.Header.Get("Origin")
+	if origin != "" && !h.originAllowed(origin) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	sub := &WSSubscription{RunID: GlobalActivityRunID}
+	if r.Header.Get("X-User-Role") != internal.RoleAdmin {
+		userID := r.Header.Get("X-User-ID")
+		sub.Allow = func(msg WSMessage) bool { return msg.owner != "" && msg.owner == userID }
+	}
+	h.subscribe(w, r, sub, nil)
+}
+
+// subscribe upgrades the connection, replays sub's backlog (or the messages held for its
+// stream token) and registers it until the client disconnects. release, if set, is called
+// once the connection is gone or could not be upgraded.
+func (h *WSHub) subscribe(w http.ResponseWriter, r *http.Request, sub *WSSubscription, release func()) {
+	if release == nil {
+		release = func() {}
+	}
 
 	// Create upgrader with proper origin check
 	upgrader := websocket.Upgrader{
+		EnableCompression: h.compression,
 		CheckOrigin: func(r *http.Request) bool {
 			origin := r.Header.Get("Origin")
-			return origin == "" || isOriginAllowed(origin, h.allowedOrigins)
+			return origin == "" || h.originAllowed(origin)
 		},
 	}
 
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
+		release()
 		log.Printf("Failed to upgrade WebSocket: %v\n", err)
 		return
 	}
 
-	sub := &WSSubscription{
-		RunID: runID,
-		Conn:  conn,
-	}
-
+	sub.Subscriber = &wsSubscriber{conn: conn}
 	h.register <- sub
 
+	done := make(chan struct{})
+	if h.pingInterval > 0 {
+		pongWait := 2 * h.pingInterval
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(pongWait))
+		})
+		go h.ping(conn, done)
+	}
+
 	// Read messages from client (for keep-alive pings)
 	go func() {
 		defer func() {
+			close(done)
 			h.unregister <- sub
+			release()
 		}()
 
 		for {
@@ -169,3 +703,22 @@ func (h *WSHub) HandleLogsWebSocket(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 }
+
+// ping sends a ping to conn every pingInterval until done is closed or a write fails.
+// A failed ping leaves cleanup to the reader, which hits its read deadline.
+func (h *WSHub) ping(conn *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(h.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// WriteControl is safe to call concurrently with the hub's writes
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(h.pingInterval)); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,615 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taskflow/taskflow/internal/auth"
+	"github.com/taskflow/taskflow/internal/executor"
+	"github.com/taskflow/taskflow/internal/scheduler"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// TestActivityWebSocketReceivesRunFinished tests that a finishing run produces a global activity message
+func TestActivityWebSocketReceivesRunFinished(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	jwtMgr := auth.NewJWTManager("test-secret-key-at-least-32-bytes-long")
+	user, err := testStore.CreateUser("viewer", "viewer@example.com", "hash", "user")
+	require.NoError(t, err)
+	token, err := jwtMgr.GenerateToken(user.ID, user.Username, user.Role, time.Hour)
+	require.NoError(t, err)
+
+	hub, err := NewWSHub("*")
+	require.NoError(t, err)
+	go hub.Run()
+
+	server := httptest.NewServer(NewRouter(testStore, jwtMgr, hub, "*", scheduler.New(testStore), "/api", time.Now(), nil, "", 0, 0, nil, nil))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/ws/activity"
+
+	// Unauthenticated subscribers are rejected
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?token="+token, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Eventually(t, func() bool { return hub.subscriberCount(GlobalActivityRunID) == 1 }, time.Second, 10*time.Millisecond)
+
+	exec := executor.New(testStore)
+	exec.SetStatusBroadcaster(func(runID string, status string, job *store.Job) {
+		hub.Broadcast(StatusMessage(runID, status, job))
+	})
+
+	// Another user's run is not shown to a non-admin
+	theirs, err := testStore.CreateJob(&store.Job{Name: "theirs", Script: "echo hidden", WorkingDir: t.TempDir(), TimeoutSeconds: 10, CreatedBy: user.ID + 1})
+	require.NoError(t, err)
+	hidden, err := testStore.CreateRun(theirs.ID, "manual")
+	require.NoError(t, err)
+	require.NoError(t, exec.Execute(context.Background(), hidden, theirs))
+
+	job, err := testStore.CreateJob(&store.Job{Name: "report", Script: "echo done", WorkingDir: t.TempDir(), TimeoutSeconds: 10, CreatedBy: user.ID})
+	require.NoError(t, err)
+	run, err := testStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+	require.NoError(t, exec.Execute(context.Background(), run, job))
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	for {
+		var msg struct {
+			Type  string            `json:"type"`
+			RunID string            `json:"run_id"`
+			Data  map[string]string `json:"data"`
+		}
+		require.NoError(t, conn.ReadJSON(&msg), "expected a finished activity message")
+		assert.Equal(t, "activity", msg.Type)
+		assert.Equal(t, run.ID, msg.RunID)
+		assert.Equal(t, "report", msg.Data["job_name"])
+		if msg.Data["status"] == "success" {
+			break
+		}
+	}
+
+	// The activity topic cannot be joined through the logs socket
+	_, resp, err = websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/ws/logs?run_id="+GlobalActivityRunID+"&token="+token, nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// TestWebSocketServerPings tests that the server pings clients at the configured
+// interval and drops clients that stop answering with pongs
+func TestWebSocketServerPings(t *testing.T) {
+	const interval = 50 * time.Millisecond
+
+	hub, err := NewWSHub("*")
+	require.NoError(t, err)
+	hub.SetPingInterval(interval)
+	go hub.Run()
+
+	server := httptest.NewServer(http.HandlerFunc(hub.HandleLogsWebSocket))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	// A responsive client receives regular pings and stays connected
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?run_id=alive", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	pings := make(chan time.Time, 10)
+	conn.SetPingHandler(func(data string) error {
+		select {
+		case pings <- time.Now():
+		default:
+		}
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	var received []time.Time
+	timeout := time.After(2 * time.Second)
+	for len(received) < 4 {
+		select {
+		case at := <-pings:
+			received = append(received, at)
+		case <-timeout:
+			t.Fatalf("received %d pings, expected 4", len(received))
+		}
+	}
+	for i := 1; i < len(received); i++ {
+		gap := received[i].Sub(received[i-1])
+		assert.InDelta(t, interval, gap, float64(interval), "ping gap %v", gap)
+	}
+	assert.Equal(t, 1, hub.subscriberCount("alive"))
+
+	// A client that never answers pings is disconnected after the pong deadline
+	silent, _, err := websocket.DefaultDialer.Dial(wsURL+"?run_id=silent", nil)
+	require.NoError(t, err)
+	defer silent.Close()
+	silent.SetPingHandler(func(string) error { return nil })
+	go func() {
+		for {
+			if _, _, err := silent.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	require.Eventually(t, func() bool { return hub.subscriberCount("silent") == 1 }, time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return hub.subscriberCount("silent") == 0 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, hub.subscriberCount("alive"))
+}
+
+// TestNewWSHubValidatesAllowedOrigins tests that malformed allowlist entries are reported when the hub is built
+func TestNewWSHubValidatesAllowedOrigins(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	hub, err := NewWSHub("https://app.example.com, app.example.com ,http://ops.example.com:8443")
+	require.NoError(t, err)
+	assert.Contains(t, logs.String(), `"app.example.com"`)
+	assert.NotContains(t, logs.String(), "ops.example.com")
+
+	assert.True(t, hub.originAllowed("https://app.example.com"))
+	assert.True(t, hub.originAllowed("http://ops.example.com:8443"))
+	assert.False(t, hub.originAllowed("http://app.example.com"))
+	assert.False(t, hub.originAllowed("https://evil.example.com"))
+
+	_, err = NewWSHub("app.example.com,https://app.example.com/path")
+	assert.Error(t, err, "an allowlist with no usable entries is rejected")
+
+	all, err := NewWSHub("*")
+	require.NoError(t, err)
+	assert.True(t, all.originAllowed("https://anything.example.com"))
+}
+
+// TestLogsWebSocketReplaysBacklogFromID tests that a reconnecting client with from_id is
+// replayed only newer stored lines, up to max_backlog, before live messages
+func TestLogsWebSocketReplaysBacklogFromID(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "chatty", Script: "true"})
+	require.NoError(t, err)
+	run, err := testStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+	var ids []int
+	for i := 0; i < 10; i++ {
+		entry, err := testStore.AddLog(run.ID, "stdout", fmt.Sprintf("line %d", i))
+		require.NoError(t, err)
+		ids = append(ids, entry.ID)
+	}
+
+	hub, err := NewWSHub("*")
+	require.NoError(t, err)
+	hub.SetLogStore(testStore)
+	go hub.Run()
+
+	// Stand in for the auth middleware
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set("X-User-ID", "1")
+		r.Header.Set("X-User-Role", "admin")
+		hub.HandleLogsWebSocket(w, r)
+	}))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "?run_id=" + run.ID
+
+	conn, _, err := websocket.DefaultDialer.Dial(fmt.Sprintf("%s&from_id=%d&max_backlog=4", wsURL, ids[2]), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	type message struct {
+		Type string                 `json:"type"`
+		Data map[string]interface{} `json:"data"`
+	}
+	var replayed []string
+	for i := 0; i < 4; i++ {
+		var msg message
+		require.NoError(t, conn.ReadJSON(&msg))
+		require.Equal(t, "log", msg.Type)
+		replayed = append(replayed, msg.Data["content"].(string))
+	}
+	assert.Equal(t, []string{"line 3", "line 4", "line 5", "line 6"}, replayed)
+
+	var marker message
+	require.NoError(t, conn.ReadJSON(&marker))
+	assert.Equal(t, "backlog", marker.Type)
+	assert.Equal(t, float64(4), marker.Data["count"])
+	assert.Equal(t, float64(ids[6]), marker.Data["last_id"])
+	assert.Equal(t, true, marker.Data["more"])
+
+	// Live messages follow the backlog
+	require.Eventually(t, func() bool { return hub.subscriberCount(run.ID) == 1 }, time.Second, 10*time.Millisecond)
+	hub.Broadcast(WSMessage{Type: "log", RunID: run.ID, Data: map[string]string{"stream": "stdout", "content": "live"}})
+	var live message
+	require.NoError(t, conn.ReadJSON(&live))
+	assert.Equal(t, "live", live.Data["content"])
+
+	// Parameters are validated before the upgrade
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL+"&max_backlog=0", nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// TestLogsWebSocketSkipsReplayedLiveLines tests that a line both replayed from the store
+// and broadcast after the client registered reaches the client once, and that live
+// lines carry their stored ID
+func TestLogsWebSocketSkipsReplayedLiveLines(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	job, err := testStore.CreateJob(&store.Job{Name: "chatty", Script: "true"})
+	require.NoError(t, err)
+	run, err := testStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+	stored, err := testStore.AddLog(run.ID, "stdout", "stored")
+	require.NoError(t, err)
+
+	hub, err := NewWSHub("*")
+	require.NoError(t, err)
+	hub.SetLogStore(testStore)
+	go hub.Run()
+
+	// Stand in for the auth middleware
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set("X-User-ID", "1")
+		r.Header.Set("X-User-Role", "admin")
+		hub.HandleLogsWebSocket(w, r)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"?run_id="+run.ID+"&from_id=0", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	type message struct {
+		Type string                 `json:"type"`
+		Data map[string]interface{} `json:"data"`
+	}
+	var replayed, marker message
+	require.NoError(t, conn.ReadJSON(&replayed))
+	assert.Equal(t, "stored", replayed.Data["content"])
+	require.NoError(t, conn.ReadJSON(&marker))
+	require.Equal(t, "backlog", marker.Type)
+
+	// The stored line's own broadcast arrives late; only the next line gets through
+	require.Eventually(t, func() bool { return hub.subscriberCount(run.ID) == 1 }, time.Second, 10*time.Millisecond)
+	next, err := testStore.AddLog(run.ID, "stdout", "next")
+	require.NoError(t, err)
+	hub.Broadcast(LogMessage(run.ID, stored.ID, "stdout", "stored", stored.Timestamp))
+	hub.Broadcast(LogMessage(run.ID, next.ID, "stdout", "next", next.Timestamp))
+
+	var live message
+	require.NoError(t, conn.ReadJSON(&live))
+	assert.Equal(t, "next", live.Data["content"])
+	assert.Equal(t, float64(next.ID), live.Data["id"])
+}
+
+// TestLogsWebSocketRequiresVisibleRun tests that the logs socket needs a token and hides
+// other users' runs, so their stored output cannot be replayed
+func TestLogsWebSocketRequiresVisibleRun(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	jwtMgr := auth.NewJWTManager("test-secret-key-at-least-32-bytes-long")
+	owner, err := testStore.CreateUser("owner", "owner@example.com", "hash", "user")
+	require.NoError(t, err)
+	other, err := testStore.CreateUser("other", "other@example.com", "hash", "user")
+	require.NoError(t, err)
+	ownerToken, err := jwtMgr.GenerateToken(owner.ID, owner.Username, owner.Role, time.Hour)
+	require.NoError(t, err)
+	otherToken, err := jwtMgr.GenerateToken(other.ID, other.Username, other.Role, time.Hour)
+	require.NoError(t, err)
+
+	job, err := testStore.CreateJob(&store.Job{Name: "private", Script: "true", CreatedBy: owner.ID})
+	require.NoError(t, err)
+	run, err := testStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+	_, err = testStore.AddLog(run.ID, "stdout", "secret output")
+	require.NoError(t, err)
+
+	hub, err := NewWSHub("*")
+	require.NoError(t, err)
+	hub.SetLogStore(testStore)
+	go hub.Run()
+
+	server := httptest.NewServer(NewRouter(testStore, jwtMgr, hub, "*", scheduler.New(testStore), "/api", time.Now(), nil, "", 0, 0, nil, nil))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/ws/logs?run_id=" + run.ID + "&from_id=0"
+
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	_, resp, err = websocket.DefaultDialer.Dial(wsURL+"&token="+otherToken, nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"&token="+ownerToken, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg struct {
+		Type string                 `json:"type"`
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "log", msg.Type)
+	assert.Equal(t, "secret output", msg.Data["content"])
+}
+
+// TestLogsWebSocketConnectionCapPerRun tests that subscriptions beyond the per-run cap are
+// rejected with 429 and that closing a connection frees its slot
+func TestLogsWebSocketConnectionCapPerRun(t *testing.T) {
+	hub, err := NewWSHub("*")
+	require.NoError(t, err)
+	hub.SetMaxConnectionsPerRun(2)
+	go hub.Run()
+
+	server := httptest.NewServer(http.HandlerFunc(hub.HandleLogsWebSocket))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	var conns []*websocket.Conn
+	for i := 0; i < 2; i++ {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?run_id=busy", nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL+"?run_id=busy", nil)
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+	// Other runs have their own allowance
+	other, _, err := websocket.DefaultDialer.Dial(wsURL+"?run_id=quiet", nil)
+	require.NoError(t, err)
+	defer other.Close()
+
+	// Once a subscriber leaves, a new one fits
+	require.NoError(t, conns[0].Close())
+	require.Eventually(t, func() bool {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?run_id=busy", nil)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, 2*time.Second, 20*time.Millisecond)
+}
+
+// countingConn counts the bytes read from the underlying connection
+type countingConn struct {
+	net.Conn
+	read atomic.Int64
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+// TestLogsWebSocketCompression tests that a large log broadcast reaches a client that
+// negotiated per-message deflate intact, using far fewer bytes on the wire than without it
+func TestLogsWebSocketCompression(t *testing.T) {
+	content := strings.Repeat("2024-01-01T00:00:00Z INFO processing batch item with a fairly repetitive log line\n", 3000)
+
+	// receive subscribes with a compression-capable client and returns the received
+	// content and the bytes read from the network after the handshake
+	receive := func(compression bool) (string, int64, string) {
+		hub, err := NewWSHub("*")
+		require.NoError(t, err)
+		hub.SetCompression(compression)
+		go hub.Run()
+
+		server := httptest.NewServer(http.HandlerFunc(hub.HandleLogsWebSocket))
+		defer server.Close()
+
+		var counter *countingConn
+		dialer := websocket.Dialer{
+			EnableCompression: true,
+			NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+				if err != nil {
+					return nil, err
+				}
+				counter = &countingConn{Conn: conn}
+				return counter, nil
+			},
+		}
+		conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"?run_id=big", nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		extensions := resp.Header.Get("Sec-WebSocket-Extensions")
+
+		require.Eventually(t, func() bool { return hub.subscriberCount("big") == 1 }, time.Second, 10*time.Millisecond)
+		handshake := counter.read.Load()
+		hub.Broadcast(WSMessage{Type: "log", RunID: "big", Data: map[string]string{"stream": "stdout", "content": content}})
+
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var msg struct {
+			Data map[string]string `json:"data"`
+		}
+		require.NoError(t, conn.ReadJSON(&msg))
+		return msg.Data["content"], counter.read.Load() - handshake, extensions
+	}
+
+	received, compressedBytes, extensions := receive(true)
+	assert.Contains(t, extensions, "permessage-deflate")
+	assert.Equal(t, content, received)
+
+	received, plainBytes, extensions := receive(false)
+	assert.NotContains(t, extensions, "permessage-deflate")
+	assert.Equal(t, content, received)
+
+	t.Logf("log broadcast of %d bytes: %d bytes compressed, %d bytes uncompressed", len(content), compressedBytes, plainBytes)
+	assert.Less(t, compressedBytes*10, plainBytes, "compression should shrink a repetitive log at least tenfold")
+}
+
+// closeRecorder is a Subscriber that only records whether it was closed
+type closeRecorder struct {
+	closed atomic.Bool
+}
+
+func (c *closeRecorder) Send([]byte) error { return nil }
+
+func (c *closeRecorder) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+// TestAdminWSConnections tests that the connections endpoint counts a registered
+// subscription and that force-disconnecting the run closes and removes it
+func TestAdminWSConnections(t *testing.T) {
+	hub, err := NewWSHub("*")
+	require.NoError(t, err)
+	go hub.Run()
+
+	sub := &closeRecorder{}
+	require.True(t, hub.reserveRunConn("run-1"))
+	hub.register <- &WSSubscription{RunID: "run-1", Subscriber: sub}
+	require.Eventually(t, func() bool { return hub.subscriberCount("run-1") == 1 }, time.Second, 10*time.Millisecond)
+
+	request := func(handler http.HandlerFunc, method, target, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("X-User-Role", role)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusForbidden, request(hub.HandleListConnections, "GET", "/api/admin/ws/connections", "user").Code)
+
+	w := request(hub.HandleListConnections, "GET", "/api/admin/ws/connections", "admin")
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Data []WSConnectionStats `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, []WSConnectionStats{{RunID: "run-1", Subscribers: 1, Reserved: 1}}, list.Data)
+
+	assert.Equal(t, http.StatusBadRequest, request(hub.HandleDisconnect, "POST", "/api/admin/ws/disconnect", "admin").Code)
+
+	w = request(hub.HandleDisconnect, "POST", "/api/admin/ws/disconnect?run_id=run-1", "admin")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"disconnected":1`)
+	assert.True(t, sub.closed.Load())
+	assert.Equal(t, 0, hub.subscriberCount("run-1"))
+
+	// The slot is freed by the connection's handler as it exits
+	hub.releaseRunConn("run-1")
+	assert.Empty(t, hub.Connections())
+}
+
+// TestTriggerStreamTokenLosesNoEarlyLines tests that a client subscribing with the stream
+// token from a manual trigger receives the lines logged before it connected, in order,
+// followed by live lines, and that the token cannot be reused
+func TestTriggerStreamTokenLosesNoEarlyLines(t *testing.T) {
+	testStore := store.NewTestStore(t)
+	defer testStore.Close()
+
+	jwtMgr := auth.NewJWTManager("test-secret-key-at-least-32-bytes-long")
+	user, err := testStore.CreateUser("runner", "runner@example.com", "hash", "user")
+	require.NoError(t, err)
+	token, err := jwtMgr.GenerateToken(user.ID, user.Username, user.Role, time.Hour)
+	require.NoError(t, err)
+	job, err := testStore.CreateJob(&store.Job{Name: "eager", Script: "true", Enabled: true, TimeoutSeconds: 10})
+	require.NoError(t, err)
+
+	hub, err := NewWSHub("*")
+	require.NoError(t, err)
+	go hub.Run()
+
+	server := httptest.NewServer(NewRouter(testStore, jwtMgr, hub, "*", scheduler.New(testStore), "/api", time.Now(), nil, "", 0, 0, nil, nil))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/api/jobs/"+job.ID+"/run", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var triggered struct {
+		Data struct {
+			ID          string `json:"id"`
+			StreamToken string `json:"stream_token"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&triggered))
+	runID := triggered.Data.ID
+	require.NotEmpty(t, triggered.Data.StreamToken)
+
+	// The run logs before anyone has subscribed
+	for i := 0; i < 20; i++ {
+		hub.Broadcast(WSMessage{Type: "log", RunID: runID, Data: map[string]string{"stream": "stdout", "content": fmt.Sprintf("early %d", i)}})
+	}
+	require.Eventually(t, func() bool { return hub.heldCount(runID) == 20 }, time.Second, 10*time.Millisecond)
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/ws/logs?token=" + token + "&run_id=" + runID
+	_, resp, err = websocket.DefaultDialer.Dial(wsURL+"&stream_token=wrong", nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"&stream_token="+triggered.Data.StreamToken, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	type message struct {
+		Type string                 `json:"type"`
+		Data map[string]interface{} `json:"data"`
+	}
+	for i := 0; i < 20; i++ {
+		var msg message
+		require.NoError(t, conn.ReadJSON(&msg))
+		require.Equal(t, "log", msg.Type)
+		assert.Equal(t, fmt.Sprintf("early %d", i), msg.Data["content"])
+	}
+	var marker message
+	require.NoError(t, conn.ReadJSON(&marker))
+	assert.Equal(t, "backlog", marker.Type)
+	assert.Equal(t, float64(20), marker.Data["count"])
+	assert.Equal(t, float64(0), marker.Data["dropped"])
+
+	require.Eventually(t, func() bool { return hub.subscriberCount(runID) == 1 }, time.Second, 10*time.Millisecond)
+	hub.Broadcast(WSMessage{Type: "log", RunID: runID, Data: map[string]string{"stream": "stdout", "content": "live"}})
+	var live message
+	require.NoError(t, conn.ReadJSON(&live))
+	assert.Equal(t, "live", live.Data["content"])
+
+	// The token was spent by the first subscription
+	_, resp, err = websocket.DefaultDialer.Dial(wsURL+"&stream_token="+triggered.Data.StreamToken, nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
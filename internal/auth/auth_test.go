@@ -1,9 +1,11 @@
 package auth
 
 import (
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -237,3 +239,91 @@ func TestJWTWithDifferentSecret(t *testing.T) {
 	_, err = jwtMgr2.ValidateToken(token)
 	require.Error(t, err)
 }
+
+// TestJWTIssuerAndAudience tests that tokens are only accepted with the expected issuer and audience
+func TestJWTIssuerAndAudience(t *testing.T) {
+	secret := "shared-secret-at-least-32-bytes-long"
+
+	taskflow := NewJWTManager(secret)
+	otherIssuer := NewJWTManager(secret)
+	otherIssuer.SetIssuer("billing", DefaultAudience)
+	otherAudience := NewJWTManager(secret)
+	otherAudience.SetIssuer(DefaultIssuer, "billing-api")
+
+	token, err := taskflow.GenerateToken(7, "alice", "admin", time.Hour)
+	require.NoError(t, err)
+
+	claims, err := taskflow.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultIssuer, claims.Issuer)
+	assert.Equal(t, jwt.ClaimStrings{DefaultAudience}, claims.Audience)
+
+	_, err = otherIssuer.ValidateToken(token)
+	assert.Error(t, err, "token from a different issuer must be rejected")
+	_, err = otherAudience.ValidateToken(token)
+	assert.Error(t, err, "token for a different audience must be rejected")
+
+	// Correctly signed tokens missing iss/aud, e.g. from before issuers were set, are rejected
+	bare := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		UserID: 7,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	bareToken, err := bare.SignedString([]byte(secret))
+	require.NoError(t, err)
+	_, err = taskflow.ValidateToken(bareToken)
+	assert.Error(t, err)
+
+	// Only HS256 is accepted, even with the right secret and claims
+	hs512 := jwt.NewWithClaims(jwt.SigningMethodHS512, Claims{
+		UserID: 7,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    DefaultIssuer,
+			Audience:  jwt.ClaimStrings{DefaultAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	hs512Token, err := hs512.SignedString([]byte(secret))
+	require.NoError(t, err)
+	_, err = taskflow.ValidateToken(hs512Token)
+	assert.Error(t, err)
+}
+
+// TestJWTExpiryErrors tests that expired and tampered tokens fail with distinct errors
+func TestJWTExpiryErrors(t *testing.T) {
+	jwtMgr := NewJWTManager("test-secret-key-at-least-32-bytes-long")
+
+	expired, err := jwtMgr.GenerateToken(1, "user", "user", -time.Hour)
+	require.NoError(t, err)
+	_, err = jwtMgr.ValidateToken(expired)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrTokenExpired), "got %v", err)
+	assert.False(t, errors.Is(err, ErrTokenInvalid))
+
+	valid, err := jwtMgr.GenerateToken(1, "user", "user", time.Hour)
+	require.NoError(t, err)
+	_, err = jwtMgr.ValidateToken(valid + "tampered")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrTokenInvalid), "got %v", err)
+	assert.False(t, errors.Is(err, ErrTokenExpired))
+
+	// An expired token whose signature was tampered with is invalid, not merely expired
+	_, err = jwtMgr.ValidateToken(expired + "tampered")
+	assert.True(t, errors.Is(err, ErrTokenInvalid), "got %v", err)
+}
+
+// TestJWTLeeway tests that a token expired by less than the leeway is still accepted
+func TestJWTLeeway(t *testing.T) {
+	jwtMgr := NewJWTManager("test-secret-key-at-least-32-bytes-long")
+
+	justExpired, err := jwtMgr.GenerateToken(1, "user", "user", -10*time.Second)
+	require.NoError(t, err)
+
+	_, err = jwtMgr.ValidateToken(justExpired)
+	assert.NoError(t, err, "default leeway should absorb 10s of clock skew")
+
+	jwtMgr.SetLeeway(0)
+	_, err = jwtMgr.ValidateToken(justExpired)
+	assert.True(t, errors.Is(err, ErrTokenExpired), "got %v", err)
+}
@@ -15,13 +15,43 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
+// Default registered claims identifying tokens issued by and for this server
+const (
+	DefaultIssuer   = "taskflow"
+	DefaultAudience = "taskflow-api"
+)
+
+// DefaultLeeway is the clock skew tolerated when checking a token's time-based claims
+const DefaultLeeway = 30 * time.Second
+
+// ValidateToken errors, distinguishing a token that needs a fresh login from one that was never valid
+var (
+	ErrTokenExpired = errors.New("token has expired")
+	ErrTokenInvalid = errors.New("token is invalid")
+)
+
 type JWTManager struct {
-	secret string
+	secret   string
+	issuer   string
+	audience string
+	leeway   time.Duration
 }
 
-// NewJWTManager creates a new JWT manager
+// NewJWTManager creates a new JWT manager using the default issuer, audience and leeway
 func NewJWTManager(secret string) *JWTManager {
-	return &JWTManager{secret: secret}
+	return &JWTManager{secret: secret, issuer: DefaultIssuer, audience: DefaultAudience, leeway: DefaultLeeway}
+}
+
+// SetLeeway sets how much clock skew is tolerated when checking expiry and not-before times
+func (jm *JWTManager) SetLeeway(leeway time.Duration) {
+	jm.leeway = leeway
+}
+
+// SetIssuer sets the issuer and audience written into new tokens and required on
+// validated ones, so services sharing a secret cannot accept each other's tokens
+func (jm *JWTManager) SetIssuer(issuer, audience string) {
+	jm.issuer = issuer
+	jm.audience = audience
 }
 
 // GenerateToken generates a JWT token for a user
@@ -35,6 +65,8 @@ func (jm *JWTManager) GenerateToken(userID int, username, role string, expiresIn
 		Username: username,
 		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    jm.issuer,
+			Audience:  jwt.ClaimStrings{jm.audience},
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
@@ -49,7 +81,9 @@ func (jm *JWTManager) GenerateToken(userID int, username, role string, expiresIn
 	return tokenString, nil
 }
 
-// ValidateToken validates a JWT token and returns claims
+// ValidateToken validates a JWT token and returns claims. The token must be signed
+// with HS256 and carry this manager's issuer and audience. Errors wrap ErrTokenExpired
+// for an otherwise valid token past its expiry, and ErrTokenInvalid for anything else.
 func (jm *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 
@@ -58,14 +92,22 @@ func (jm *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(jm.secret), nil
-	})
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(jm.issuer),
+		jwt.WithAudience(jm.audience),
+		jwt.WithLeeway(jm.leeway),
+	)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse token: %w", err)
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, fmt.Errorf("%w: %v", ErrTokenExpired, err)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrTokenInvalid, err)
 	}
 
 	if !token.Valid {
-		return nil, errors.New("invalid token")
+		return nil, ErrTokenInvalid
 	}
 
 	return claims, nil
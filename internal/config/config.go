@@ -1,90 +1,414 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"net/url"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
+
+	internal "github.com/taskflow/taskflow/internal"
+	"github.com/taskflow/taskflow/internal/auth"
 )
 
 type Config struct {
-	Port              int
-	DBPath            string
-	JWTSecret         string
-	LogLevel          string
-	SMTPServer        string
-	SMTPPort          int
-	SMTPUsername      string
-	SMTPPassword      string
-	AllowedOrigins    string
-	LogRetentionDays  int
-	APIBasePath       string
+	Port                  int
+	DBPath                string
+	JWTSecret             string
+	JWTIssuer             string
+	JWTAudience           string
+	JWTLeewaySeconds      int
+	LogLevel              string
+	SMTPServer            string
+	SMTPPort              int
+	SMTPUsername          string
+	SMTPPassword          string
+	AllowedOrigins        string
+	LogRetentionDays      int
+	LogContentRetention   int
+	MaxTotalRuns          int
+	MaxRunLogBytes        int64
+	APIBasePath           string
+	RestartGraceSeconds   int
+	SlowTickSeconds       int
+	SchedulerAlertEmails  string
+	QueueOverflowPolicy   string
+	MaxJobTimeout         int
+	MinScheduleInterval   int
+	LocalExecutorWeight   int
+	DBWriteRetries        int
+	ManualTriggerInterval int
+	NotifyProxyURL        string
+	CompressLogs          bool
+	UniqueJobNames        bool
+	SafeMode              string
+	SafeModeRules         string
+	AllowedWorkDirRoots   string
+	WSPingIntervalSeconds int
+	WSMaxConnsPerRun      int
+	WSCompression         bool
+	DigestSchedule        string
+	DigestRecipients      string
+	DigestHour            int
 }
 
+// Load reads the configuration from environment variables over the built-in defaults
 func Load() *Config {
+	return load(os.Getenv)
+}
+
+// load builds the configuration from the values getenv returns for each setting's
+// environment variable, empty meaning unset
+func load(getenv func(string) string) *Config {
 	cfg := &Config{
-		Port:             8080,
-		DBPath:           "taskflow.db",
-		LogLevel:         "info",
-		LogRetentionDays: 30,
-		APIBasePath:      "/taskflow/api",
+		Port:                  8080,
+		DBPath:                "taskflow.db",
+		JWTIssuer:             auth.DefaultIssuer,
+		JWTAudience:           auth.DefaultAudience,
+		JWTLeewaySeconds:      int(auth.DefaultLeeway / time.Second),
+		LogLevel:              "info",
+		LogRetentionDays:      30,
+		APIBasePath:           "/taskflow/api",
+		RestartGraceSeconds:   internal.DefaultRestartGraceSeconds,
+		SlowTickSeconds:       int(internal.DefaultSlowTickThreshold / time.Second),
+		QueueOverflowPolicy:   internal.QueueOverflowBlock,
+		LocalExecutorWeight:   1,
+		DBWriteRetries:        internal.DefaultDBWriteRetries,
+		WSPingIntervalSeconds: int(internal.DefaultWSPingInterval / time.Second),
+		WSMaxConnsPerRun:      50,
+		WSCompression:         true,
+		DigestHour:            internal.DefaultDigestHour,
+		SafeMode:              internal.SafeModeOff,
 	}
 
-	if port := os.Getenv("PORT"); port != "" {
+	if port := getenv("PORT"); port != "" {
 		if p, err := strconv.Atoi(port); err == nil {
 			cfg.Port = p
 		}
 	}
 
-	if dbPath := os.Getenv("DB_PATH"); dbPath != "" {
+	if dbPath := getenv("DB_PATH"); dbPath != "" {
 		cfg.DBPath = dbPath
 	}
 
-	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+	if secret := getenv("JWT_SECRET"); secret != "" {
 		cfg.JWTSecret = secret
 	}
 
-	if level := os.Getenv("LOG_LEVEL"); level != "" {
+	// Registered iss/aud claims; change these when several services share JWT_SECRET
+	if issuer := getenv("JWT_ISSUER"); issuer != "" {
+		cfg.JWTIssuer = issuer
+	}
+
+	if audience := getenv("JWT_AUDIENCE"); audience != "" {
+		cfg.JWTAudience = audience
+	}
+
+	// Clock skew tolerated when checking token expiry
+	if leeway := getenv("JWT_LEEWAY_SECONDS"); leeway != "" {
+		if l, err := strconv.Atoi(leeway); err == nil && l >= 0 {
+			cfg.JWTLeewaySeconds = l
+		}
+	}
+
+	if level := getenv("LOG_LEVEL"); level != "" {
 		cfg.LogLevel = level
 	}
 
-	if server := os.Getenv("SMTP_SERVER"); server != "" {
+	if server := getenv("SMTP_SERVER"); server != "" {
 		cfg.SMTPServer = server
 	}
 
-	if port := os.Getenv("SMTP_PORT"); port != "" {
+	if port := getenv("SMTP_PORT"); port != "" {
 		if p, err := strconv.Atoi(port); err == nil {
 			cfg.SMTPPort = p
 		}
 	}
 
-	if user := os.Getenv("SMTP_USERNAME"); user != "" {
+	if user := getenv("SMTP_USERNAME"); user != "" {
 		cfg.SMTPUsername = user
 	}
 
-	if pass := os.Getenv("SMTP_PASSWORD"); pass != "" {
+	if pass := getenv("SMTP_PASSWORD"); pass != "" {
 		cfg.SMTPPassword = pass
 	}
 
-	if origins := os.Getenv("ALLOWED_ORIGINS"); origins != "" {
+	if origins := getenv("ALLOWED_ORIGINS"); origins != "" {
 		cfg.AllowedOrigins = origins
 	} else {
 		cfg.AllowedOrigins = "*"
 	}
 
-	if days := os.Getenv("LOG_RETENTION_DAYS"); days != "" {
+	if days := getenv("LOG_RETENTION_DAYS"); days != "" {
 		if d, err := strconv.Atoi(days); err == nil {
 			cfg.LogRetentionDays = d
 		}
 	}
 
-	if basePath := os.Getenv("API_BASE_PATH"); basePath != "" {
-		// Ensure base path starts with / and doesn't end with /
+	// Days to keep run logs and metrics; older runs keep their metadata. 0 disables the purge
+	if days := getenv("LOG_CONTENT_RETENTION_DAYS"); days != "" {
+		if d, err := strconv.Atoi(days); err == nil && d >= 0 {
+			cfg.LogContentRetention = d
+		}
+	}
+
+	// Hard cap on stored runs across all jobs; the oldest are evicted beyond it. 0 disables the cap
+	if max := getenv("MAX_TOTAL_RUNS"); max != "" {
+		if m, err := strconv.Atoi(max); err == nil && m >= 0 {
+			cfg.MaxTotalRuns = m
+		}
+	}
+
+	// Bytes of output stored per run attempt; the rest is counted but dropped. 0 stores everything
+	if max := getenv("MAX_RUN_LOG_BYTES"); max != "" {
+		if m, err := strconv.ParseInt(max, 10, 64); err == nil && m >= 0 {
+			cfg.MaxRunLogBytes = m
+		}
+	}
+
+	// Ceiling on a job's timeout_seconds, for deployments that want less than the built-in 24h
+	if max := getenv("MAX_JOB_TIMEOUT_SECONDS"); max != "" {
+		if m, err := strconv.Atoi(max); err == nil && m >= 0 {
+			cfg.MaxJobTimeout = m
+		}
+	}
+
+	// Retries for run and log writes that hit a busy SQLite database; 0 disables them
+	if retries := getenv("DB_WRITE_RETRIES"); retries != "" {
+		if r, err := strconv.Atoi(retries); err == nil && r >= 0 {
+			cfg.DBWriteRetries = r
+		}
+	}
+
+	// This host's share of runs against remote agents, whose weight is their capacity. 0 = only when no agent is free
+	if weight := getenv("LOCAL_EXECUTOR_WEIGHT"); weight != "" {
+		if w, err := strconv.Atoi(weight); err == nil && w >= 0 {
+			cfg.LocalExecutorWeight = w
+		}
+	}
+
+	// What enqueueing does when the job queue is full: block, reject or drop_oldest
+	if policy := strings.ToLower(getenv("QUEUE_OVERFLOW_POLICY")); policy == "block" || policy == "reject" || policy == "drop_oldest" {
+		cfg.QueueOverflowPolicy = policy
+	}
+
+	if grace := getenv("RESTART_GRACE_SECONDS"); grace != "" {
+		if g, err := strconv.Atoi(grace); err == nil && g >= 0 {
+			cfg.RestartGraceSeconds = g
+		}
+	}
+
+	// Scheduler ticks taking longer than this log a warning; 0 disables the check
+	if slow := getenv("SCHEDULER_SLOW_TICK_SECONDS"); slow != "" {
+		if sl, err := strconv.Atoi(slow); err == nil && sl >= 0 {
+			cfg.SlowTickSeconds = sl
+		}
+	}
+
+	// Admins emailed about slow or missed scheduler ticks; unset only logs them
+	if recipients := getenv("SCHEDULER_ALERT_RECIPIENTS"); recipients != "" {
+		cfg.SchedulerAlertEmails = recipients
+	}
+
+	// Shortest allowed gap between a schedule's fires; saving a more frequent schedule fails. 0 disables
+	if interval := getenv("MIN_SCHEDULE_INTERVAL_MINUTES"); interval != "" {
+		if i, err := strconv.Atoi(interval); err == nil && i >= 0 && i <= 1440 {
+			cfg.MinScheduleInterval = i
+		}
+	}
+
+	// Minimum seconds between manual triggers of the same job; 0 disables the check
+	if interval := getenv("MANUAL_TRIGGER_MIN_INTERVAL_SECONDS"); interval != "" {
+		if i, err := strconv.Atoi(interval); err == nil && i >= 0 {
+			cfg.ManualTriggerInterval = i
+		}
+	}
+
+	// Server-side WebSocket ping interval; 0 disables keepalive pings
+	if interval := getenv("WS_PING_INTERVAL_SECONDS"); interval != "" {
+		if i, err := strconv.Atoi(interval); err == nil && i >= 0 {
+			cfg.WSPingIntervalSeconds = i
+		}
+	}
+
+	// Concurrent log WebSocket subscriptions allowed per run; 0 removes the cap
+	if maxConns := getenv("WS_MAX_CONNECTIONS_PER_RUN"); maxConns != "" {
+		if m, err := strconv.Atoi(maxConns); err == nil && m >= 0 {
+			cfg.WSMaxConnsPerRun = m
+		}
+	}
+
+	if compression := getenv("WS_COMPRESSION"); compression != "" {
+		if c, err := strconv.ParseBool(compression); err == nil {
+			cfg.WSCompression = c
+		}
+	}
+
+	// Activity digest emails: "daily" or "weekly"; unset disables them
+	if schedule := strings.ToLower(getenv("DIGEST_SCHEDULE")); schedule == "daily" || schedule == "weekly" {
+		cfg.DigestSchedule = schedule
+	}
+
+	if recipients := getenv("DIGEST_RECIPIENTS"); recipients != "" {
+		cfg.DigestRecipients = recipients
+	}
+
+	// Local hour at which digests are sent
+	if hour := getenv("DIGEST_HOUR"); hour != "" {
+		if h, err := strconv.Atoi(hour); err == nil && h >= 0 && h <= 23 {
+			cfg.DigestHour = h
+		}
+	}
+
+	// Explicit proxy for outbound HTTP notifications; HTTP_PROXY/HTTPS_PROXY apply otherwise
+	if proxyURL := getenv("NOTIFY_PROXY_URL"); proxyURL != "" {
+		cfg.NotifyProxyURL = proxyURL
+	}
+
+	if compress := getenv("COMPRESS_LOGS"); compress != "" {
+		if c, err := strconv.ParseBool(compress); err == nil {
+			cfg.CompressLogs = c
+		}
+	}
+
+	if unique := getenv("ENFORCE_UNIQUE_JOB_NAMES"); unique != "" {
+		if u, err := strconv.ParseBool(unique); err == nil {
+			cfg.UniqueJobNames = u
+		}
+	}
+
+	// Advisory script scanner applied when jobs are saved: off, warn or reject
+	if safeMode := strings.ToLower(getenv("SAFE_MODE")); safeMode == "off" || safeMode == "warn" || safeMode == "reject" {
+		cfg.SafeMode = safeMode
+	}
+
+	if rules := getenv("SAFE_MODE_RULES"); rules != "" {
+		cfg.SafeModeRules = rules
+	}
+
+	// Colon-separated directories a job's working_dir must be under; empty allows any
+	if roots := getenv("ALLOWED_WORKING_DIR_ROOTS"); roots != "" {
+		cfg.AllowedWorkDirRoots = roots
+	}
+
+	if basePath := getenv("API_BASE_PATH"); basePath != "" {
+		// Ensure base path starts with / and doesn't end with /; ValidateAPIBasePath rejects
+		// what is left of "/" at startup
 		if !strings.HasPrefix(basePath, "/") {
 			basePath = "/" + basePath
 		}
-		basePath = strings.TrimSuffix(basePath, "/")
+		basePath = strings.TrimRight(basePath, "/")
 		cfg.APIBasePath = basePath
 	}
 
 	return cfg
 }
+
+// apiBasePathPattern allows /-separated segments of unreserved URL characters, so the base
+// path can be spliced into ServeMux patterns and matched as a plain prefix
+var apiBasePathPattern = regexp.MustCompile(`^(/[A-Za-z0-9._~-]+)+$`)
+
+// reservedBasePaths are served outside the API router: the frontend at /taskflow/ and the
+// runtime config at /taskflow-app/
+var reservedBasePaths = []string{"/taskflow", "/taskflow-app"}
+
+// ValidateAPIBasePath rejects an API base path that would break routing. An empty or root
+// path would send every request, frontend routes included, to the API router.
+func ValidateAPIBasePath(basePath string) error {
+	if basePath == "" || basePath == "/" {
+		return errors.New("must not be empty or /")
+	}
+	if !apiBasePathPattern.MatchString(basePath) {
+		return fmt.Errorf("%q must be /-separated segments of letters, digits and ._~-", basePath)
+	}
+	for _, segment := range strings.Split(basePath[1:], "/") {
+		if segment == "." || segment == ".." {
+			return fmt.Errorf("%q must not contain . or .. segments", basePath)
+		}
+	}
+	for _, reserved := range reservedBasePaths {
+		if basePath == reserved || (reserved != "/taskflow" && strings.HasPrefix(basePath, reserved+"/")) {
+			return fmt.Errorf("%q collides with %s, which is served outside the API", basePath, reserved)
+		}
+	}
+	return nil
+}
+
+// redactedValue replaces secret values in printed configuration
+const redactedValue = "********"
+
+// Entry is one effective setting, keyed by the environment variable that controls it
+type Entry struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// Entries returns the effective configuration in a stable order with secrets redacted.
+// Secrets that are set print as a fixed mask; unset secrets print empty.
+func (c *Config) Entries() []Entry {
+	return []Entry{
+		{Name: "PORT", Value: strconv.Itoa(c.Port)},
+		{Name: "DB_PATH", Value: c.DBPath},
+		{Name: "JWT_SECRET", Value: redact(c.JWTSecret), Secret: true},
+		{Name: "JWT_ISSUER", Value: c.JWTIssuer},
+		{Name: "JWT_AUDIENCE", Value: c.JWTAudience},
+		{Name: "JWT_LEEWAY_SECONDS", Value: strconv.Itoa(c.JWTLeewaySeconds)},
+		{Name: "LOG_LEVEL", Value: c.LogLevel},
+		{Name: "API_BASE_PATH", Value: c.APIBasePath},
+		{Name: "ALLOWED_ORIGINS", Value: c.AllowedOrigins},
+		{Name: "LOG_RETENTION_DAYS", Value: strconv.Itoa(c.LogRetentionDays)},
+		{Name: "LOG_CONTENT_RETENTION_DAYS", Value: strconv.Itoa(c.LogContentRetention)},
+		{Name: "MAX_TOTAL_RUNS", Value: strconv.Itoa(c.MaxTotalRuns)},
+		{Name: "MAX_RUN_LOG_BYTES", Value: strconv.FormatInt(c.MaxRunLogBytes, 10)},
+		{Name: "COMPRESS_LOGS", Value: strconv.FormatBool(c.CompressLogs)},
+		{Name: "ENFORCE_UNIQUE_JOB_NAMES", Value: strconv.FormatBool(c.UniqueJobNames)},
+		{Name: "SAFE_MODE", Value: c.SafeMode},
+		{Name: "SAFE_MODE_RULES", Value: c.SafeModeRules},
+		{Name: "ALLOWED_WORKING_DIR_ROOTS", Value: c.AllowedWorkDirRoots},
+		{Name: "RESTART_GRACE_SECONDS", Value: strconv.Itoa(c.RestartGraceSeconds)},
+		{Name: "SCHEDULER_SLOW_TICK_SECONDS", Value: strconv.Itoa(c.SlowTickSeconds)},
+		{Name: "SCHEDULER_ALERT_RECIPIENTS", Value: c.SchedulerAlertEmails},
+		{Name: "QUEUE_OVERFLOW_POLICY", Value: c.QueueOverflowPolicy},
+		{Name: "MAX_JOB_TIMEOUT_SECONDS", Value: strconv.Itoa(c.MaxJobTimeout)},
+		{Name: "MIN_SCHEDULE_INTERVAL_MINUTES", Value: strconv.Itoa(c.MinScheduleInterval)},
+		{Name: "LOCAL_EXECUTOR_WEIGHT", Value: strconv.Itoa(c.LocalExecutorWeight)},
+		{Name: "DB_WRITE_RETRIES", Value: strconv.Itoa(c.DBWriteRetries)},
+		{Name: "MANUAL_TRIGGER_MIN_INTERVAL_SECONDS", Value: strconv.Itoa(c.ManualTriggerInterval)},
+		{Name: "WS_PING_INTERVAL_SECONDS", Value: strconv.Itoa(c.WSPingIntervalSeconds)},
+		{Name: "WS_MAX_CONNECTIONS_PER_RUN", Value: strconv.Itoa(c.WSMaxConnsPerRun)},
+		{Name: "WS_COMPRESSION", Value: strconv.FormatBool(c.WSCompression)},
+		{Name: "NOTIFY_PROXY_URL", Value: redactURL(c.NotifyProxyURL)},
+		{Name: "DIGEST_SCHEDULE", Value: c.DigestSchedule},
+		{Name: "DIGEST_RECIPIENTS", Value: c.DigestRecipients},
+		{Name: "DIGEST_HOUR", Value: strconv.Itoa(c.DigestHour)},
+		{Name: "SMTP_SERVER", Value: c.SMTPServer},
+		{Name: "SMTP_PORT", Value: strconv.Itoa(c.SMTPPort)},
+		{Name: "SMTP_USERNAME", Value: c.SMTPUsername},
+		{Name: "SMTP_PASSWORD", Value: redact(c.SMTPPassword), Secret: true},
+	}
+}
+
+// redact masks a secret value, leaving empty values empty so "not set" stays visible
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// redactURL masks any password embedded in a URL's user info
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		// Unparseable values may still carry credentials; don't echo them
+		return redactedValue
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFile reads the configuration from a YAML or JSON file of settings keyed by their
+// environment variable names (PORT: 9090), then applies environment variables over it,
+// so a variable that is set always wins. An empty path is the same as Load.
+func LoadFile(path string) (*Config, error) {
+	if path == "" {
+		return Load(), nil
+	}
+
+	values, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return load(func(key string) string {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+		return values[key]
+	}), nil
+}
+
+// readFile parses a config file into values by setting name. Keys are matched without
+// regard to case and must name a known setting; values must be scalars.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// JSON is a subset of YAML, so one decoder handles both
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	known := make(map[string]bool)
+	for _, e := range (&Config{}).Entries() {
+		known[e.Name] = true
+	}
+
+	values := make(map[string]string, len(raw))
+	var unknown []string
+	for key, value := range raw {
+		name := strings.ToUpper(key)
+		if !known[name] {
+			unknown = append(unknown, key)
+			continue
+		}
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("config file %s: %s must be a single value", path, key)
+		case nil:
+			continue
+		}
+		values[name] = fmt.Sprint(value)
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("config file %s: unknown settings: %s", path, strings.Join(unknown, ", "))
+	}
+	return values, nil
+}
@@ -4,10 +4,18 @@ import "time"
 
 // ===== Script Configuration =====
 const (
-	// MaxScriptSize is the maximum allowed job script size (1MB)
-	MaxScriptSize = 1_000_000
+	// MaxScriptSize is the maximum allowed job script size (8MB)
+	MaxScriptSize = 8_000_000
 	// MaxScriptSizeReadable is the human-readable version for error messages
-	MaxScriptSizeReadable = "1MB"
+	MaxScriptSizeReadable = "8MB"
+	// InlineScriptMaxSize is the largest script stored in the jobs row itself (1MB).
+	// Larger scripts are kept in the job_scripts table and loaded when the job runs.
+	InlineScriptMaxSize = 1_000_000
+	// InlineScriptMaxSizeReadable is the human-readable version for error messages
+	InlineScriptMaxSizeReadable = "1MB"
+	// MaxScriptArgSize is the largest script passed to the interpreter as a command-line
+	// argument; larger ones are run from a temp file (Linux caps one argument at 128KB)
+	MaxScriptArgSize = 100_000
 )
 
 // ===== Timeout Configuration =====
@@ -18,6 +26,15 @@ const (
 	MaxTimeoutSeconds = 86400 // 24 hours in seconds
 	// DefaultTimeoutSeconds is the default job execution timeout (1 hour)
 	DefaultTimeoutSeconds = 3600 // 1 hour in seconds
+	// MaxTimeoutWarnPercent is the highest share of the timeout at which a warning can be raised
+	MaxTimeoutWarnPercent = 99
+	// HookTimeout bounds each pre/post-script separately from the job's own timeout
+	HookTimeout = 5 * time.Minute
+	// OutputWaitDelay is how long a finished script's output is still read before the pipes
+	// are closed, so a background child that keeps them open cannot hold the run
+	OutputWaitDelay = 5 * time.Second
+	// TestRunTimeoutSeconds caps the timeout of a test run started with POST /jobs/{id}/test
+	TestRunTimeoutSeconds = 60
 )
 
 // ===== Retry Configuration =====
@@ -30,6 +47,8 @@ const (
 	MinRetryDelaySeconds = 0
 	// MaxRetryDelaySeconds is the maximum retry delay (24 hours)
 	MaxRetryDelaySeconds = 86400
+	// MaxAutoDisableAfterFailures is the maximum consecutive-failure threshold before a job is auto-disabled
+	MaxAutoDisableAfterFailures = 1000
 )
 
 // ===== Job Configuration =====
@@ -42,25 +61,68 @@ const (
 	DefaultTimeZone = "UTC"
 	// DefaultNotifyOn is the default notification trigger ("failure", "success", "always")
 	DefaultNotifyOn = "failure"
+	// MaxResourceLockLength is the maximum length of a job's resource lock name
+	MaxResourceLockLength = 255
+	// MinNice is the lowest (default) niceness a job can run with
+	MinNice = 0
+	// MaxNice is the highest niceness (lowest CPU priority) a job can run with
+	MaxNice = 19
+	// MaxBlackoutWindows is the maximum number of blackout windows per job
+	MaxBlackoutWindows = 20
+	// MaxJobMetadataSize is the maximum size in bytes of a job's metadata encoded as JSON
+	MaxJobMetadataSize = 4096
+	// MaxJobMetadataKeyLength is the maximum length of a job metadata key
+	MaxJobMetadataKeyLength = 64
+	// MaxRunTags is the maximum number of tags a triggered run can carry
+	MaxRunTags = 10
+	// MaxRunTagLength is the maximum length of a run tag
+	MaxRunTagLength = 64
 )
 
 // ===== Request Size Limits =====
 const (
-	// MaxRequestBodySize is the maximum allowed HTTP request body size (10MB)
+	// MaxRequestBodySize is the default maximum HTTP request body size (10MB)
 	MaxRequestBodySize = 10 * 1024 * 1024
+	// MaxAuthRequestBodySize caps login, setup and credential change bodies (4KB),
+	// which only ever carry a few short fields
+	MaxAuthRequestBodySize = 4 * 1024
+	// MaxJobRequestBodySize caps job and template bodies (20MB), leaving room for an
+	// 8MB script after JSON escaping
+	MaxJobRequestBodySize = 20 * 1024 * 1024
 )
 
 // ===== Log Streaming =====
 const (
 	// LogStreamBufferSize is the buffer size for log streaming (matches OS page size)
 	LogStreamBufferSize = 4096 // 4KB page size
+	// LogFlushBatchSize is the maximum number of buffered output lines written in one insert
+	LogFlushBatchSize = 100
+	// LogWriteAttempts is how many times a batch of output lines is written before it is dropped
+	LogWriteAttempts = 3
+	// LogWriteRetryDelay is the pause between attempts to write a batch of output lines
+	LogWriteRetryDelay = 100 * time.Millisecond
+	// MaxOutputPreviewLength is the maximum number of characters kept in a run's output preview
+	MaxOutputPreviewLength = 200
+	// DefaultWSPingInterval is how often the server pings each WebSocket client
+	DefaultWSPingInterval = 30 * time.Second
+	// DefaultWSMaxBacklog is how many stored lines a log subscriber resuming with from_id is replayed
+	DefaultWSMaxBacklog = 1000
+	// MaxWSBacklog is the largest max_backlog a log subscriber may request
+	MaxWSBacklog = 10000
+	// StreamTokenTTL is how long a triggered run's messages are held for the client holding its stream token
+	StreamTokenTTL = time.Minute
+	// DefaultWSMaxConnectionsPerRun caps concurrent log subscribers to a single run
+	DefaultWSMaxConnectionsPerRun = 50
+	// WSCompressionMinBytes is the smallest WebSocket message sent deflated; smaller ones
+	// gain too little to be worth the CPU
+	WSCompressionMinBytes = 512
 )
 
 // ===== Channel Buffers =====
 const (
 	// WebSocketBroadcastChannelSize is the buffer size for WebSocket broadcast channel
 	WebSocketBroadcastChannelSize = 100
-	// JobQueueChannelSize is the buffer size for the job queue
+	// JobQueueChannelSize is how many runs the job queue holds
 	JobQueueChannelSize = 100
 )
 
@@ -70,10 +132,34 @@ const (
 	DefaultPageLimit = 100
 	// MaxPageLimit is the maximum number of items per page
 	MaxPageLimit = 1000
+	// MaxBatchLogRunIDs is the maximum number of runs whose logs can be fetched in one request
+	MaxBatchLogRunIDs = 10
+	// JobDetailRecentRuns is the number of recent runs included in the job detail response
+	JobDetailRecentRuns = 10
+	// MaxUpcomingRuns caps the entries returned by the upcoming schedule board
+	MaxUpcomingRuns = 500
+	// DefaultLogTailLines is how many lines GET /runs/{id}/logs/tail returns by default
+	DefaultLogTailLines = 50
+	// MaxLogTailLines caps the lines a log tail request may ask for
+	MaxLogTailLines = 10000
+)
+
+// ===== Dashboard =====
+const (
+	// DefaultDashboardStatsWindow is how far back the dashboard success rate looks by default
+	DefaultDashboardStatsWindow = 24 * time.Hour
+	// DashboardRecentRuns is the number of recent runs shown on the dashboard
+	DashboardRecentRuns = 10
+	// DefaultUpcomingWindow is how far ahead the upcoming schedule board looks by default
+	DefaultUpcomingWindow = 24 * time.Hour
+	// MaxUpcomingWindow is the furthest ahead the upcoming schedule board can look
+	MaxUpcomingWindow = 7 * 24 * time.Hour
 )
 
 // ===== Job Status Values =====
 const (
+	// JobStatusAwaitingApproval indicates a manual run of a requires_approval job waiting for an admin
+	JobStatusAwaitingApproval = "awaiting_approval"
 	// JobStatusPending indicates a job is waiting to be executed
 	JobStatusPending = "pending"
 	// JobStatusRunning indicates a job is currently running
@@ -106,12 +192,143 @@ const (
 	NotifyFailure = "failure"
 )
 
+// ===== Notification Outbox =====
+const (
+	// NotificationMaxAttempts is how many delivery attempts are made before a notification is marked failed
+	NotificationMaxAttempts = 5
+	// NotificationRetryBaseDelay is the delay before the first retry; it doubles on each further failure
+	NotificationRetryBaseDelay = 30 * time.Second
+	// NotificationRetryMaxDelay caps the delay between retries
+	NotificationRetryMaxDelay = 30 * time.Minute
+	// NotificationOutboxPollInterval is how often the outbox worker looks for due retries
+	NotificationOutboxPollInterval = 15 * time.Second
+	// NotificationOutboxBatchSize is the maximum number of notifications retried per poll
+	NotificationOutboxBatchSize = 50
+	// NotificationClaimTimeout is how long a delivery attempt owns a notification; one whose
+	// attempt never records an outcome (e.g. the process died mid-send) is retried after it
+	NotificationClaimTimeout = 5 * time.Minute
+)
+
+// ===== Digest Emails =====
+const (
+	// DigestDaily sends the activity digest every day, covering the previous 24 hours
+	DigestDaily = "daily"
+	// DigestWeekly sends the activity digest every Monday, covering the previous 7 days
+	DigestWeekly = "weekly"
+	// DefaultDigestHour is the local hour (0-23) at which digests are sent
+	DefaultDigestHour = 8
+	// DigestWorstJobs is how many jobs with failures are listed in a digest
+	DigestWorstJobs = 5
+)
+
+// ===== SMTP =====
+const (
+	// SMTPDialTimeout bounds connecting to the SMTP server, including the TLS handshake on port 465
+	SMTPDialTimeout = 10 * time.Second
+	// SMTPSessionTimeout bounds a whole SMTP exchange once connected
+	SMTPSessionTimeout = 60 * time.Second
+	// SMTPConnectRetryDelay is the pause before the single reconnect after a failed connection
+	SMTPConnectRetryDelay = 2 * time.Second
+)
+
+// ===== Notification Channels =====
+const (
+	// NotifyChannelEmail marks outbox entries sent over SMTP to the job's notify_emails
+	NotifyChannelEmail = "email"
+	// NotifyChannelTeams posts a message card to a Microsoft Teams incoming webhook
+	NotifyChannelTeams = "teams"
+	// NotifyChannelPagerDuty triggers a PagerDuty incident on failure and resolves it on success
+	NotifyChannelPagerDuty = "pagerduty"
+	// PagerDutyEventsURL is the PagerDuty Events API v2 endpoint
+	PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+)
+
+// ===== Interpreters =====
+const (
+	// InterpreterBash runs scripts with bash -c (Unix default)
+	InterpreterBash = "bash"
+	// InterpreterSh runs scripts with sh -c
+	InterpreterSh = "sh"
+	// InterpreterCmd runs scripts with cmd /C (Windows default)
+	InterpreterCmd = "cmd"
+	// InterpreterPowerShell runs scripts with powershell -Command (Windows only)
+	InterpreterPowerShell = "powershell"
+)
+
 // ===== Trigger Types =====
 const (
 	// TriggerScheduled indicates a job was triggered by scheduler
 	TriggerScheduled = "scheduled"
 	// TriggerManual indicates a job was triggered manually
 	TriggerManual = "manual"
+	// TriggerTest indicates a test run in a scratch directory; analytics leave these out
+	TriggerTest = "test"
+)
+
+// ===== Manual Trigger Policies =====
+const (
+	// ManualWhileScheduledAllow queues a manual trigger even if a scheduled run is pending or running
+	ManualWhileScheduledAllow = "allow"
+	// ManualWhileScheduledSkip rejects a manual trigger while a scheduled run is pending or running
+	ManualWhileScheduledSkip = "skip"
+	// ManualWhileScheduledReplace cancels a pending scheduled run and queues the manual trigger instead
+	ManualWhileScheduledReplace = "replace"
+)
+
+// ===== Safe Mode =====
+const (
+	// SafeModeOff disables the job script scanner
+	SafeModeOff = "off"
+	// SafeModeWarn saves flagged jobs and returns the findings as warnings
+	SafeModeWarn = "warn"
+	// SafeModeReject refuses to save jobs whose scripts match a rule
+	SafeModeReject = "reject"
+)
+
+// ===== Queue Overflow =====
+const (
+	// QueueOverflowBlock makes enqueueing wait for room in a full job queue
+	QueueOverflowBlock = "block"
+	// QueueOverflowReject turns away a job enqueued while the queue is full
+	QueueOverflowReject = "reject"
+	// QueueOverflowDropOldest evicts the oldest queued job to make room for a new one
+	QueueOverflowDropOldest = "drop_oldest"
+)
+
+// ===== Agents =====
+const (
+	// LocalAgentID names the built-in executor when it competes with remote agents for runs
+	LocalAgentID = "local"
+	// AgentHeartbeatTimeout is how long an agent may go without a heartbeat before runs stop being dispatched to it
+	AgentHeartbeatTimeout = 90 * time.Second
+	// AgentDispatchTimeout bounds the request that hands a run to a remote agent
+	AgentDispatchTimeout = 10 * time.Second
+	// AgentReapInterval is how often remote runs are checked for a passed timeout or a silent agent
+	AgentReapInterval = 30 * time.Second
+)
+
+// ===== Solar Schedules =====
+const (
+	// SolarEventSunrise anchors a schedule to sunrise
+	SolarEventSunrise = "sunrise"
+	// SolarEventSunset anchors a schedule to sunset
+	SolarEventSunset = "sunset"
+	// MaxSolarOffsetMinutes bounds how far a solar schedule may be shifted from its event
+	MaxSolarOffsetMinutes = 720
+)
+
+// ===== Detailed Health =====
+const (
+	// HealthStatusOK means a component is working normally
+	HealthStatusOK = "ok"
+	// HealthStatusDegraded means a component works but needs attention
+	HealthStatusDegraded = "degraded"
+	// HealthStatusDown means a component is not working
+	HealthStatusDown = "down"
+	// HealthDiskDegradedBytes is the free space on the database's disk below which it reports degraded
+	HealthDiskDegradedBytes = 1 << 30
+	// HealthDiskDownBytes is the free space on the database's disk below which it reports down
+	HealthDiskDownBytes = 100 << 20
 )
 
 // ===== Log Streams =====
@@ -132,6 +349,35 @@ const (
 	LogCleanupInterval = 24 * time.Hour
 	// SchedulerCheckInterval is how often the scheduler checks for jobs to run
 	SchedulerCheckInterval = time.Minute
+	// DefaultSlowTickThreshold is how long a scheduler tick may take before a warning is logged
+	DefaultSlowTickThreshold = 30 * time.Second
+	// MissedTickFactor is how many check intervals may pass between ticks before minutes count as missed
+	MissedTickFactor = 2
+	// TickDurationHistory is how many recent tick durations the scheduler status reports
+	TickDurationHistory = 60
+	// SchedulerLeaseTTL is how long the scheduler lease lasts without renewal; a standby
+	// instance takes over scheduling once it lapses
+	SchedulerLeaseTTL = 3 * SchedulerCheckInterval
+	// SchedulerAlertCooldown is the least time between slow or missed tick notifications
+	SchedulerAlertCooldown = 15 * time.Minute
+	// DefaultRestartGraceSeconds is how long after startup a job that ran recently is not re-fired
+	DefaultRestartGraceSeconds = 90
+	// DefaultDBWriteRetries is how many times a write failing with SQLITE_BUSY/LOCKED is retried
+	DefaultDBWriteRetries = 3
+	// DBWriteRetryBaseDelay is the wait before the first write retry; it doubles on each further one
+	DBWriteRetryBaseDelay = 25 * time.Millisecond
+)
+
+// ===== Maintenance =====
+const (
+	// MaintenanceRetryAfterSeconds is the Retry-After value sent to non-admins during maintenance
+	MaintenanceRetryAfterSeconds = 300
+)
+
+// ===== Backup =====
+const (
+	// BackupVersion is the format version written to and accepted by configuration backups
+	BackupVersion = 1
 )
 
 // ===== CORS =====
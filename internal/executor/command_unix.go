@@ -0,0 +1,66 @@
+//go:build !windows
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	internal "github.com/taskflow/taskflow/internal"
+)
+
+// defaultInterpreter runs scripts of jobs that leave interpreter empty
+const defaultInterpreter = internal.InterpreterBash
+
+// shellCommand builds the command that runs script with the given interpreter; login
+// starts a login shell (bash -lc) that sources the profile scripts first.
+// The script runs in its own process group so cancellation kills everything it spawned.
+func shellCommand(ctx context.Context, interpreter, script string, login bool) (*exec.Cmd, error) {
+	return interpreterCommand(ctx, interpreter, login, "-c", script)
+}
+
+// scriptFileCommand builds the command that runs the script file at path, for scripts
+// too large to pass as an argument
+func scriptFileCommand(ctx context.Context, interpreter, path string, login bool) (*exec.Cmd, error) {
+	return interpreterCommand(ctx, interpreter, login, path)
+}
+
+// scriptFileExt is the temp file extension for a script run by scriptFileCommand
+func scriptFileExt(interpreter string) string {
+	return ".sh"
+}
+
+// interpreterCommand runs the interpreter with args in its own process group, as a
+// login shell if login is set
+func interpreterCommand(ctx context.Context, interpreter string, login bool, args ...string) (*exec.Cmd, error) {
+	if login {
+		args = append([]string{"-l"}, args...)
+	}
+
+	var cmd *exec.Cmd
+	switch interpreter {
+	case "", internal.InterpreterBash:
+		cmd = exec.CommandContext(ctx, "bash", args...)
+	case internal.InterpreterSh:
+		cmd = exec.CommandContext(ctx, "sh", args...)
+	default:
+		return nil, fmt.Errorf("interpreter %q is not supported on this platform", interpreter)
+	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		// A negative PID signals the whole process group
+		if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil {
+			if errors.Is(err, syscall.ESRCH) {
+				return os.ErrProcessDone
+			}
+			return err
+		}
+		return nil
+	}
+	return cmd, nil
+}
@@ -0,0 +1,160 @@
+//go:build !windows
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// runScript executes script with interpreter through the executor and returns the finished run
+func runScript(t *testing.T, interpreter, script string, timeoutSeconds int) (*store.Run, []string) {
+	t.Helper()
+
+	mockStore := newMockStoreForTesting(t)
+	t.Cleanup(func() { mockStore.Close() })
+
+	job, err := mockStore.CreateJob(&store.Job{
+		Name:           "echo",
+		Script:         script,
+		Interpreter:    interpreter,
+		WorkingDir:     t.TempDir(),
+		TimeoutSeconds: timeoutSeconds,
+	})
+	require.NoError(t, err)
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	New(mockStore.Store).Execute(context.Background(), run, job)
+
+	logs, err := mockStore.GetLogs(run.ID)
+	require.NoError(t, err)
+	var stdout []string
+	for _, l := range logs {
+		if l.Stream == "stdout" {
+			stdout = append(stdout, l.Content)
+		}
+	}
+	return run, stdout
+}
+
+// TestShellCommandEcho tests that a trivial echo script runs with each Unix interpreter
+func TestShellCommandEcho(t *testing.T) {
+	for _, interpreter := range []string{"", "bash", "sh"} {
+		t.Run("interpreter_"+interpreter, func(t *testing.T) {
+			run, stdout := runScript(t, interpreter, "echo hello", 10)
+			assert.Equal(t, "success", run.Status)
+			assert.Equal(t, []string{"hello"}, stdout)
+		})
+	}
+}
+
+// TestShellCommandUnsupportedInterpreter tests that Windows-only interpreters fail the run
+func TestShellCommandUnsupportedInterpreter(t *testing.T) {
+	run, _ := runScript(t, "powershell", "echo hello", 10)
+	assert.Equal(t, "failure", run.Status)
+	require.NotNil(t, run.ErrorMsg)
+	assert.Contains(t, *run.ErrorMsg, "not supported on this platform")
+}
+
+// TestShellCommandTimeoutKillsProcessGroup tests that a timeout also kills background
+// children, which would otherwise outlive the run and hold the output pipes open
+func TestShellCommandTimeoutKillsProcessGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	start := time.Now()
+	run, _ := runScript(t, "", "sleep 30 &\necho $! > "+pidFile+"\nwait", 1)
+	assert.Equal(t, "timeout", run.Status)
+	assert.Less(t, time.Since(start), 10*time.Second)
+
+	data, err := os.ReadFile(pidFile)
+	require.NoError(t, err)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return !processAlive(pid) }, 5*time.Second, 50*time.Millisecond,
+		"background child %d survived the timeout", pid)
+}
+
+// processAlive reports whether pid is still running; an unreaped zombie counts as dead
+func processAlive(pid int) bool {
+	if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+		return false
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return true
+	}
+	// The state follows the parenthesised command name
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) == 0 || fields[0] != "Z"
+}
+
+// TestShellCommandBackgroundChildDoesNotHoldRun tests that a script that exits while a
+// background child keeps stdout open succeeds after OutputWaitDelay with its output kept
+func TestShellCommandBackgroundChildDoesNotHoldRun(t *testing.T) {
+	start := time.Now()
+	run, stdout := runScript(t, "", "sleep 30 &\necho done", 60)
+	assert.Equal(t, "success", run.Status)
+	assert.Equal(t, []string{"done"}, stdout)
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
+
+// TestShellCommandLoginShell tests that login_shell switches to bash -lc and that the job
+// then sees variables exported by the user's profile
+func TestShellCommandLoginShell(t *testing.T) {
+	plain, err := shellCommand(context.Background(), "bash", "true", false)
+	require.NoError(t, err)
+	login, err := shellCommand(context.Background(), "bash", "true", true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bash", "-c", "true"}, plain.Args)
+	assert.Equal(t, []string{"bash", "-l", "-c", "true"}, login.Args)
+
+	// A controlled profile: login bash sources ~/.bash_profile
+	home := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".bash_profile"), []byte("export TASKFLOW_PROFILE_VAR=from-profile\n"), 0o644))
+	t.Setenv("HOME", home)
+	t.Setenv("TASKFLOW_PROFILE_VAR", "")
+
+	for _, loginShell := range []bool{false, true} {
+		mockStore := newMockStoreForTesting(t)
+		defer mockStore.Close()
+
+		job, err := mockStore.CreateJob(&store.Job{
+			Name:           "profile",
+			Script:         `echo "var=$TASKFLOW_PROFILE_VAR"`,
+			LoginShell:     loginShell,
+			WorkingDir:     t.TempDir(),
+			TimeoutSeconds: 10,
+		})
+		require.NoError(t, err)
+		run, err := mockStore.CreateRun(job.ID, "manual")
+		require.NoError(t, err)
+		require.NoError(t, New(mockStore.Store).Execute(context.Background(), run, job))
+		require.Equal(t, "success", run.Status)
+
+		logs, err := mockStore.GetLogs(run.ID)
+		require.NoError(t, err)
+		var stdout []string
+		for _, l := range logs {
+			if l.Stream == "stdout" {
+				stdout = append(stdout, l.Content)
+			}
+		}
+		if loginShell {
+			assert.Contains(t, stdout, "var=from-profile")
+		} else {
+			assert.Equal(t, []string{"var="}, stdout)
+		}
+	}
+}
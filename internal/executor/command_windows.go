@@ -0,0 +1,107 @@
+//go:build windows
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"syscall"
+
+	internal "github.com/taskflow/taskflow/internal"
+)
+
+// defaultInterpreter runs scripts of jobs that leave interpreter empty
+const defaultInterpreter = internal.InterpreterCmd
+
+// shellCommand builds the command that runs script with the given interpreter. login
+// loads the PowerShell profile or starts bash as a login shell; cmd has no equivalent.
+// Cancellation kills the whole process tree, since Windows has no process-group signal.
+func shellCommand(ctx context.Context, interpreter, script string, login bool) (*exec.Cmd, error) {
+	var cmd *exec.Cmd
+	switch interpreter {
+	case "", internal.InterpreterCmd:
+		cmd = exec.CommandContext(ctx, "cmd")
+		// cmd.exe does its own quote parsing, so pass the script through verbatim
+		// rather than letting exec escape it as a single argument
+		cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: "cmd /C " + script}
+	case internal.InterpreterPowerShell:
+		cmd = exec.CommandContext(ctx, "powershell", powershellArgs(login, "-Command", script)...)
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	case internal.InterpreterBash:
+		// Git for Windows / MSYS2 bash, if on PATH
+		cmd = exec.CommandContext(ctx, "bash", bashArgs(login, "-c", script)...)
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	default:
+		return nil, fmt.Errorf("interpreter %q is not supported on this platform", interpreter)
+	}
+
+	return killTreeOnCancel(cmd), nil
+}
+
+// scriptFileCommand builds the command that runs the script file at path, for scripts
+// too large to pass on the command line
+func scriptFileCommand(ctx context.Context, interpreter, path string, login bool) (*exec.Cmd, error) {
+	var cmd *exec.Cmd
+	switch interpreter {
+	case "", internal.InterpreterCmd:
+		cmd = exec.CommandContext(ctx, "cmd")
+		cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: `cmd /C "` + path + `"`}
+	case internal.InterpreterPowerShell:
+		cmd = exec.CommandContext(ctx, "powershell", powershellArgs(login, "-ExecutionPolicy", "Bypass", "-File", path)...)
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	case internal.InterpreterBash:
+		cmd = exec.CommandContext(ctx, "bash", bashArgs(login, path)...)
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	default:
+		return nil, fmt.Errorf("interpreter %q is not supported on this platform", interpreter)
+	}
+
+	return killTreeOnCancel(cmd), nil
+}
+
+// powershellArgs prefixes args with PowerShell's non-interactive flags, skipping the
+// profile unless login is set
+func powershellArgs(login bool, args ...string) []string {
+	prefix := []string{"-NonInteractive"}
+	if !login {
+		prefix = []string{"-NoProfile", "-NonInteractive"}
+	}
+	return append(prefix, args...)
+}
+
+// bashArgs prefixes args with -l when bash should run as a login shell
+func bashArgs(login bool, args ...string) []string {
+	if login {
+		return append([]string{"-l"}, args...)
+	}
+	return args
+}
+
+// scriptFileExt is the temp file extension for a script run by scriptFileCommand;
+// cmd and PowerShell pick how to run a file by its extension
+func scriptFileExt(interpreter string) string {
+	switch interpreter {
+	case "", internal.InterpreterCmd:
+		return ".bat"
+	case internal.InterpreterPowerShell:
+		return ".ps1"
+	default:
+		return ".sh"
+	}
+}
+
+// killTreeOnCancel starts cmd in a new process group and kills its whole tree on cancel
+func killTreeOnCancel(cmd *exec.Cmd) *exec.Cmd {
+	cmd.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+	cmd.Cancel = func() error {
+		// /T kills child processes too; fall back to killing just the shell
+		kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
+		if err := kill.Run(); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,47 @@
+//go:build windows
+
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// TestShellCommandEcho tests that a trivial echo script runs with each Windows interpreter
+func TestShellCommandEcho(t *testing.T) {
+	for _, interpreter := range []string{"", "cmd", "powershell"} {
+		t.Run("interpreter_"+interpreter, func(t *testing.T) {
+			mockStore := newMockStoreForTesting(t)
+			defer mockStore.Close()
+
+			job, err := mockStore.CreateJob(&store.Job{
+				Name:           "echo",
+				Script:         "echo hello",
+				Interpreter:    interpreter,
+				WorkingDir:     t.TempDir(),
+				TimeoutSeconds: 30,
+			})
+			require.NoError(t, err)
+			run, err := mockStore.CreateRun(job.ID, "manual")
+			require.NoError(t, err)
+
+			require.NoError(t, New(mockStore.Store).Execute(context.Background(), run, job))
+			assert.Equal(t, "success", run.Status)
+
+			logs, err := mockStore.GetLogs(run.ID)
+			require.NoError(t, err)
+			var stdout []string
+			for _, l := range logs {
+				if l.Stream == "stdout" {
+					stdout = append(stdout, strings.TrimSpace(l.Content))
+				}
+			}
+			assert.Equal(t, []string{"hello"}, stdout)
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	internal "github.com/taskflow/taskflow/internal"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// AgentDispatch is the body POSTed to an agent's /runs endpoint. The agent runs the job's
+// script and reports back to POST /api/agents/{id}/runs/{run_id}/result.
+type AgentDispatch struct {
+	RunID string     `json:"run_id"`
+	Job   *store.Job `json:"job"`
+}
+
+// ErrRunAlreadyFinished is returned by FinishRemote for a run that is no longer running,
+// e.g. one reaped before its agent reported back
+var ErrRunAlreadyFinished = errors.New("run has already finished")
+
+// Dispatcher hands each run to the local executor or to one of the registered remote
+// agents. Candidates are picked by smooth weighted round-robin, each weighted by its
+// capacity, and an agent already running as many runs as its capacity is skipped.
+// Remote runs hold their resource lock until the agent reports back, and finish through
+// the same outcome path as local ones.
+type Dispatcher struct {
+	store        *store.Store
+	local        *Executor
+	client       *http.Client
+	localWeight  int
+	enqueueRetry func(*store.Job, *store.Run) error
+
+	mu      sync.Mutex
+	current map[string]int // round-robin credit per candidate ID
+}
+
+// NewDispatcher creates a dispatcher that falls back to local when no agent can take a run
+func NewDispatcher(st *store.Store, local *Executor) *Dispatcher {
+	return &Dispatcher{
+		store:       st,
+		local:       local,
+		client:      &http.Client{Timeout: internal.AgentDispatchTimeout},
+		localWeight: 1,
+		current:     make(map[string]int),
+	}
+}
+
+// SetLocalWeight sets the local executor's share of runs relative to agent capacities.
+// 0 keeps runs off this host unless no agent can take them.
+func (d *Dispatcher) SetLocalWeight(weight int) {
+	d.localWeight = weight
+}
+
+// SetRetryEnqueuer sets how retries of failed remote runs are queued; they are then
+// dispatched like any other run. Without one, remote runs are not retried.
+func (d *Dispatcher) SetRetryEnqueuer(enqueue func(*store.Job, *store.Run) error) {
+	d.enqueueRetry = enqueue
+}
+
+// dispatchCandidate is an agent, or the local executor, eligible for the next run
+type dispatchCandidate struct {
+	id     string
+	weight int
+	agent  *store.Agent // nil for the local executor
+}
+
+// Dispatch runs job locally or sends it to the next agent in the rotation. A remote run
+// only starts here; it is finished by FinishRemote when the agent reports its result, or
+// by ReapRemoteRuns. If the chosen agent cannot be reached the run falls back to the
+// local executor.
+func (d *Dispatcher) Dispatch(ctx context.Context, run *store.Run, job *store.Job) error {
+	// A queued retry of a remote run continues the execution its first attempt began
+	first := run
+	if run.ParentRunID != nil {
+		parent, err := d.store.GetRun(*run.ParentRunID)
+		if err != nil {
+			return fmt.Errorf("failed to load first attempt of run %s: %w", run.ID, err)
+		}
+		first = parent
+	}
+
+	if chosen := d.next(); chosen != nil && chosen.agent != nil {
+		remoteJob := d.local.clampTimeout(run, job)
+		// Held until the run finishes remotely, so the resource stays serialized across hosts
+		release, err := d.local.acquireResourceLock(ctx, run, remoteJob)
+		if err != nil {
+			return err
+		}
+		err = d.sendToAgent(ctx, chosen.agent, run, remoteJob)
+		if err == nil {
+			return nil
+		}
+		release()
+		log.Printf("Failed to dispatch run %s to agent %s: %v\n", run.ID, chosen.id, err)
+		d.local.logSystem(run.ID, fmt.Sprintf("Agent %s could not take the run (%v); running locally", chosen.id, err))
+	}
+	return d.local.executeRetries(ctx, first, run, job)
+}
+
+// FinishRemote records the outcome an agent reported for run, whose status, exit code and
+// error are already set, the way a local attempt is finished: the final status is logged,
+// saved and broadcast, the resource lock released, and the run either retried or its
+// execution completed, which notifies and updates the failure streak. It returns
+// ErrRunAlreadyFinished if the run was no longer running.
+func (d *Dispatcher) FinishRemote(run *store.Run) error {
+	finished, err := d.store.FinishAgentRun(run.ID, run.Status)
+	if err != nil {
+		return err
+	}
+	if !finished {
+		return ErrRunAlreadyFinished
+	}
+
+	job, err := d.store.GetJob(run.JobID)
+	if err != nil {
+		return fmt.Errorf("failed to load job for run %s: %w", run.ID, err)
+	}
+
+	if run.FinishedAt == nil {
+		now := time.Now()
+		run.FinishedAt = &now
+	}
+	if run.StartedAt != nil {
+		duration := run.FinishedAt.Sub(*run.StartedAt).Milliseconds()
+		run.DurationMs = &duration
+	}
+	d.local.finishAttempt(run, job)
+	if job.ResourceLock != "" {
+		d.local.locks.ReleaseHolder(job.ResourceLock, run.ID)
+	}
+
+	first := run
+	if run.ParentRunID != nil {
+		if parent, err := d.store.GetRun(*run.ParentRunID); err == nil {
+			first = parent
+		} else {
+			log.Printf("Failed to load first attempt of run %s: %v\n", run.ID, err)
+		}
+	}
+
+	attempt := max(run.Attempt, 1)
+	if d.enqueueRetry != nil && attempt <= job.RetryCount && shouldRetry(job, run) {
+		d.local.logSystem(run.ID, fmt.Sprintf("Retrying in %d seconds (retry %d of %d)", job.RetryDelaySeconds, attempt, job.RetryCount))
+		time.AfterFunc(time.Duration(job.RetryDelaySeconds)*time.Second, func() {
+			d.retryRemote(job, first, run, attempt)
+		})
+		return nil
+	}
+	d.local.completeAttempts(job, first, run)
+	return nil
+}
+
+// retryRemote queues the attempt after last. If it cannot be queued the execution is
+// completed with last's outcome.
+func (d *Dispatcher) retryRemote(job *store.Job, first, last *store.Run, attempt int) {
+	next, err := d.store.CreateRetryRun(first, attempt+1)
+	if err != nil {
+		log.Printf("Failed to create retry run for %s: %v\n", last.ID, err)
+		d.local.completeAttempts(job, first, last)
+		return
+	}
+	if err := d.enqueueRetry(job, next); err != nil {
+		log.Printf("Failed to queue retry run %s: %v\n", next.ID, err)
+		next.Status = internal.JobStatusCancelled
+		msg := fmt.Sprintf("Retry could not be queued: %v", err)
+		next.ErrorMsg = &msg
+		d.local.finishAttempt(next, job)
+		d.local.completeAttempts(job, first, last)
+	}
+}
+
+// ReapRemoteRuns fails remote runs that have outlived their job's timeout, or whose
+// agent has gone AgentHeartbeatTimeout without a heartbeat, so an agent that dies after
+// accepting a run cannot leave it running and holding its capacity forever
+func (d *Dispatcher) ReapRemoteRuns(now time.Time) {
+	runs, err := d.store.ListRunningAgentRuns()
+	if err != nil {
+		log.Printf("Failed to list remote runs: %v\n", err)
+		return
+	}
+	if len(runs) == 0 {
+		return
+	}
+
+	agents, err := d.store.ListAgents()
+	if err != nil {
+		log.Printf("Failed to list agents: %v\n", err)
+		return
+	}
+	heartbeats := make(map[string]*time.Time, len(agents))
+	for _, agent := range agents {
+		heartbeats[agent.ID] = agent.LastHeartbeat
+	}
+
+	for _, run := range runs {
+		job, err := d.store.GetJob(run.JobID)
+		if err != nil {
+			log.Printf("Failed to load job for remote run %s: %v\n", run.ID, err)
+			continue
+		}
+		agentID := *run.AgentID
+		timeout := d.local.timeoutSeconds(job)
+		lastHeartbeat := heartbeats[agentID]
+
+		switch {
+		case run.StartedAt != nil && now.Sub(*run.StartedAt) > time.Duration(timeout)*time.Second:
+			run.Status = internal.JobStatusTimeout
+			msg := fmt.Sprintf("Job exceeded timeout of %d seconds on agent %s", timeout, agentID)
+			run.ErrorMsg = &msg
+			code := internal.ExitCodeTimeout
+			run.ExitCode = &code
+		case lastHeartbeat == nil || now.Sub(*lastHeartbeat) > internal.AgentHeartbeatTimeout:
+			run.Status = internal.JobStatusFailure
+			msg := fmt.Sprintf("Agent %s stopped sending heartbeats before reporting a result", agentID)
+			run.ErrorMsg = &msg
+		default:
+			continue
+		}
+
+		run.FinishedAt = &now
+		if err := d.FinishRemote(run); err != nil && !errors.Is(err, ErrRunAlreadyFinished) {
+			log.Printf("Failed to reap remote run %s: %v\n", run.ID, err)
+		}
+	}
+}
+
+// RunReaper reaps stalled remote runs every AgentReapInterval until ctx is done
+func (d *Dispatcher) RunReaper(ctx context.Context) {
+	ticker := time.NewTicker(internal.AgentReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			d.ReapRemoteRuns(now)
+		}
+	}
+}
+
+// next picks the candidate for the next run, or nil when neither an agent nor the local
+// executor has weight
+func (d *Dispatcher) next() *dispatchCandidate {
+	candidates := d.candidates()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var best *dispatchCandidate
+	total := 0
+	for i := range candidates {
+		c := &candidates[i]
+		d.current[c.id] += c.weight
+		total += c.weight
+		if best == nil || d.current[c.id] > d.current[best.id] {
+			best = c
+		}
+	}
+	if best != nil {
+		d.current[best.id] -= total
+	}
+	return best
+}
+
+// candidates lists the local executor and every agent with a recent heartbeat and free capacity
+func (d *Dispatcher) candidates() []dispatchCandidate {
+	var candidates []dispatchCandidate
+	if d.localWeight > 0 {
+		candidates = append(candidates, dispatchCandidate{id: internal.LocalAgentID, weight: d.localWeight})
+	}
+
+	agents, err := d.store.ListAgents()
+	if err != nil {
+		log.Printf("Failed to list agents, running locally: %v\n", err)
+		return candidates
+	}
+	for _, agent := range agents {
+		if agent.LastHeartbeat == nil || time.Since(*agent.LastHeartbeat) > internal.AgentHeartbeatTimeout {
+			continue
+		}
+		if agent.Capacity <= 0 || agent.ActiveRuns >= agent.Capacity {
+			continue
+		}
+		candidates = append(candidates, dispatchCandidate{id: agent.ID, weight: agent.Capacity, agent: agent})
+	}
+	return candidates
+}
+
+// sendToAgent POSTs the run to the agent and records the assignment once it is accepted
+func (d *Dispatcher) sendToAgent(ctx context.Context, agent *store.Agent, run *store.Run, job *store.Job) error {
+	// Jobs from list queries leave externally stored scripts unloaded
+	if err := d.store.LoadJobScript(job); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(AgentDispatch{RunID: run.ID, Job: job})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(agent.URL, "/")+"/runs", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	// The agent has the run now, so a failed update must not make it run locally as well
+	if err := d.store.AssignRunToAgent(run.ID, agent.ID); err != nil {
+		log.Printf("Failed to record run %s on agent %s: %v\n", run.ID, agent.ID, err)
+	}
+	now := time.Now()
+	run.AgentID = &agent.ID
+	run.Status = internal.JobStatusRunning
+	run.StartedAt = &now
+	if d.local.statusBroadcaster != nil {
+		d.local.statusBroadcaster(run.ID, run.Status, job)
+	}
+	d.local.logSystem(run.ID, fmt.Sprintf("Dispatched to agent %s (%s)", agent.Name, agent.ID))
+	return nil
+}
@@ -0,0 +1,312 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	internal "github.com/taskflow/taskflow/internal"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// agentServer records the run IDs and job scripts dispatched to it
+type agentServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	runIDs  []string
+	scripts []string
+}
+
+func newAgentServer(t *testing.T) *agentServer {
+	as := &agentServer{}
+	as.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body AgentDispatch
+		if r.URL.Path != "/runs" || json.NewDecoder(r.Body).Decode(&body) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		as.mu.Lock()
+		as.runIDs = append(as.runIDs, body.RunID)
+		if body.Job != nil {
+			as.scripts = append(as.scripts, body.Job.Script)
+		}
+		as.mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(as.Close)
+	return as
+}
+
+func (as *agentServer) received() int {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	return len(as.runIDs)
+}
+
+// TestDispatchFallsBackToLocalWithoutAgents tests that runs execute locally when no agent is registered
+func TestDispatchFallsBackToLocalWithoutAgents(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	dispatcher := NewDispatcher(mockStore.Store, New(mockStore.Store))
+	// Even with no local weight, a run with nowhere else to go runs here
+	dispatcher.SetLocalWeight(0)
+
+	job, err := mockStore.CreateJob(&store.Job{Name: "local", Script: "echo local", TimeoutSeconds: 10})
+	require.NoError(t, err)
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	require.NoError(t, dispatcher.Dispatch(context.Background(), run, job))
+
+	saved, err := mockStore.GetRun(run.ID)
+	require.NoError(t, err)
+	assert.Equal(t, internal.JobStatusSuccess, saved.Status)
+	assert.Nil(t, saved.AgentID)
+}
+
+// TestDispatchSkipsAgentAtCapacity tests that an agent already running its capacity gets no
+// more runs while one with room keeps receiving them
+func TestDispatchSkipsAgentAtCapacity(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	dispatcher := NewDispatcher(mockStore.Store, New(mockStore.Store))
+	dispatcher.SetLocalWeight(0)
+
+	busy := newAgentServer(t)
+	free := newAgentServer(t)
+	_, err := mockStore.RegisterAgent("busy", "busy", busy.URL, 1)
+	require.NoError(t, err)
+	_, err = mockStore.RegisterAgent("free", "free", free.URL, 3)
+	require.NoError(t, err)
+
+	job, err := mockStore.CreateJob(&store.Job{Name: "remote", Script: "echo remote", TimeoutSeconds: 10})
+	require.NoError(t, err)
+
+	// Occupy the busy agent's only slot
+	occupied, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+	require.NoError(t, mockStore.AssignRunToAgent(occupied.ID, "busy"))
+
+	for i := 0; i < 3; i++ {
+		run, err := mockStore.CreateRun(job.ID, "manual")
+		require.NoError(t, err)
+		require.NoError(t, dispatcher.Dispatch(context.Background(), run, job))
+
+		saved, err := mockStore.GetRun(run.ID)
+		require.NoError(t, err)
+		require.NotNil(t, saved.AgentID)
+		assert.Equal(t, "free", *saved.AgentID)
+		assert.Equal(t, internal.JobStatusRunning, saved.Status)
+	}
+	assert.Equal(t, 0, busy.received())
+	assert.Equal(t, 3, free.received())
+
+	// The free agent is now full too, so the next run falls back to the local executor
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+	require.NoError(t, dispatcher.Dispatch(context.Background(), run, job))
+	saved, err := mockStore.GetRun(run.ID)
+	require.NoError(t, err)
+	assert.Nil(t, saved.AgentID)
+	assert.Equal(t, internal.JobStatusSuccess, saved.Status)
+}
+
+// TestDispatchSendsExternalScript tests that a job whose script is stored externally and
+// was not loaded, as from a list query, reaches the agent with its full script
+func TestDispatchSendsExternalScript(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	dispatcher := NewDispatcher(mockStore.Store, New(mockStore.Store))
+	dispatcher.SetLocalWeight(0)
+
+	agent := newAgentServer(t)
+	_, err := mockStore.RegisterAgent("remote", "remote", agent.URL, 1)
+	require.NoError(t, err)
+
+	script := strings.Repeat("# padding\n", internal.InlineScriptMaxSize/10+1) + "echo external-ok\n"
+	created, err := mockStore.CreateJob(&store.Job{Name: "generated", Script: script, TimeoutSeconds: 10})
+	require.NoError(t, err)
+	require.True(t, created.ScriptExternal)
+
+	jobs, err := mockStore.ListJobs(nil)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Empty(t, jobs[0].Script)
+
+	run, err := mockStore.CreateRun(created.ID, "scheduled")
+	require.NoError(t, err)
+	require.NoError(t, dispatcher.Dispatch(context.Background(), run, jobs[0]))
+
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+	require.Len(t, agent.scripts, 1)
+	assert.Equal(t, script, agent.scripts[0])
+}
+
+// TestDispatchWeightsByCapacity tests that runs are spread in proportion to capacity
+func TestDispatchWeightsByCapacity(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	dispatcher := NewDispatcher(mockStore.Store, New(mockStore.Store))
+
+	agent := newAgentServer(t)
+	_, err := mockStore.RegisterAgent("big", "big", agent.URL, 2)
+	require.NoError(t, err)
+
+	job, err := mockStore.CreateJob(&store.Job{Name: "mixed", Script: "echo mixed", TimeoutSeconds: 10})
+	require.NoError(t, err)
+
+	// Weights 2 (agent) to 1 (local): two of every three runs go to the agent until it is full
+	var local int
+	for i := 0; i < 3; i++ {
+		run, err := mockStore.CreateRun(job.ID, "manual")
+		require.NoError(t, err)
+		require.NoError(t, dispatcher.Dispatch(context.Background(), run, job))
+		saved, err := mockStore.GetRun(run.ID)
+		require.NoError(t, err)
+		if saved.AgentID == nil {
+			local++
+		}
+	}
+	assert.Equal(t, 2, agent.received())
+	assert.Equal(t, 1, local)
+}
+
+// dispatchRemote dispatches a new run of job to the dispatcher's only agent and returns it
+// as stored
+func dispatchRemote(t *testing.T, mockStore *mockStoreForTesting, dispatcher *Dispatcher, job *store.Job) *store.Run {
+	t.Helper()
+
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+	require.NoError(t, dispatcher.Dispatch(context.Background(), run, job))
+
+	saved, err := mockStore.GetRun(run.ID)
+	require.NoError(t, err)
+	require.NotNil(t, saved.AgentID)
+	require.Equal(t, internal.JobStatusRunning, saved.Status)
+	return saved
+}
+
+// TestFinishRemoteCompletesLikeLocalRun tests that a remote run holds its resource lock
+// until its result arrives, and that the result then notifies and updates the failure streak
+func TestFinishRemoteCompletesLikeLocalRun(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	local := New(mockStore.Store)
+	var notified []string
+	local.SetNotificationSender(func(_ *store.Job, run *store.Run) {
+		notified = append(notified, run.Status)
+	})
+	dispatcher := NewDispatcher(mockStore.Store, local)
+	dispatcher.SetLocalWeight(0)
+
+	agent := newAgentServer(t)
+	_, err := mockStore.RegisterAgent("remote", "remote", agent.URL, 2)
+	require.NoError(t, err)
+
+	job, err := mockStore.CreateJob(&store.Job{Name: "remote", Script: "echo remote", TimeoutSeconds: 10, ResourceLock: "db"})
+	require.NoError(t, err)
+
+	run := dispatchRemote(t, mockStore, dispatcher, job)
+	assert.Equal(t, run.ID, local.locks.Holder("db"))
+
+	run.Status = internal.JobStatusFailure
+	require.NoError(t, dispatcher.FinishRemote(run))
+	assert.Empty(t, local.locks.Holder("db"))
+	assert.Equal(t, []string{internal.JobStatusFailure}, notified)
+
+	saved, err := mockStore.GetJob(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, saved.ConsecutiveFailures)
+
+	// A second result for the same run is refused
+	assert.ErrorIs(t, dispatcher.FinishRemote(run), ErrRunAlreadyFinished)
+}
+
+// TestFinishRemoteRetriesFailedRun tests that a failed remote run queues a linked retry,
+// and that the retry's outcome completes the execution on the first run
+func TestFinishRemoteRetriesFailedRun(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	dispatcher := NewDispatcher(mockStore.Store, New(mockStore.Store))
+	dispatcher.SetLocalWeight(0)
+	retries := make(chan *store.Run, 1)
+	dispatcher.SetRetryEnqueuer(func(_ *store.Job, run *store.Run) error {
+		retries <- run
+		return nil
+	})
+
+	agent := newAgentServer(t)
+	_, err := mockStore.RegisterAgent("remote", "remote", agent.URL, 2)
+	require.NoError(t, err)
+
+	job, err := mockStore.CreateJob(&store.Job{Name: "flaky", Script: "exit 1", TimeoutSeconds: 10, RetryCount: 1})
+	require.NoError(t, err)
+
+	first := dispatchRemote(t, mockStore, dispatcher, job)
+	first.Status = internal.JobStatusFailure
+	require.NoError(t, dispatcher.FinishRemote(first))
+
+	var retry *store.Run
+	select {
+	case retry = <-retries:
+	case <-time.After(2 * time.Second):
+		t.Fatal("retry was not queued")
+	}
+	assert.Equal(t, 2, retry.Attempt)
+	require.NotNil(t, retry.ParentRunID)
+	assert.Equal(t, first.ID, *retry.ParentRunID)
+
+	require.NoError(t, dispatcher.Dispatch(context.Background(), retry, job))
+	retry, err = mockStore.GetRun(retry.ID)
+	require.NoError(t, err)
+	retry.Status = internal.JobStatusSuccess
+	require.NoError(t, dispatcher.FinishRemote(retry))
+
+	saved, err := mockStore.GetRun(first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, internal.JobStatusSuccess, saved.Status)
+	assert.Equal(t, 2, agent.received())
+}
+
+// TestReapRemoteRuns tests that remote runs are failed once their timeout passes or their
+// agent stops sending heartbeats, freeing the agent's capacity
+func TestReapRemoteRuns(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	dispatcher := NewDispatcher(mockStore.Store, New(mockStore.Store))
+	dispatcher.SetLocalWeight(0)
+
+	agent := newAgentServer(t)
+	_, err := mockStore.RegisterAgent("remote", "remote", agent.URL, 2)
+	require.NoError(t, err)
+
+	short, err := mockStore.CreateJob(&store.Job{Name: "short", Script: "sleep 60", TimeoutSeconds: 10})
+	require.NoError(t, err)
+	long, err := mockStore.CreateJob(&store.Job{Name: "long", Script: "sleep 60", TimeoutSeconds: 3600})
+	require.NoError(t, err)
+	timedOut := dispatchRemote(t, mockStore, dispatcher, short)
+	abandoned := dispatchRemote(t, mockStore, dispatcher, long)
+
+	// Past the short job's timeout, with the agent's heartbeat still fresh
+	dispatcher.ReapRemoteRuns(time.Now().Add(20 * time.Second))
+	saved, err := mockStore.GetRun(timedOut.ID)
+	require.NoError(t, err)
+	assert.Equal(t, internal.JobStatusTimeout, saved.Status)
+	saved, err = mockStore.GetRun(abandoned.ID)
+	require.NoError(t, err)
+	assert.Equal(t, internal.JobStatusRunning, saved.Status)
+
+	// The agent has gone quiet
+	dispatcher.ReapRemoteRuns(time.Now().Add(internal.AgentHeartbeatTimeout + time.Minute))
+	saved, err = mockStore.GetRun(abandoned.ID)
+	require.NoError(t, err)
+	assert.Equal(t, internal.JobStatusFailure, saved.Status)
+	require.NotNil(t, saved.ErrorMsg)
+	assert.Contains(t, *saved.ErrorMsg, "heartbeats")
+
+	registered, err := mockStore.GetAgent("remote")
+	require.NoError(t, err)
+	assert.Equal(t, 0, registered.ActiveRuns)
+}
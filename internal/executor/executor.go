@@ -2,10 +2,16 @@ package executor
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"os"
 	"os/exec"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -14,26 +20,36 @@ import (
 	"github.com/taskflow/taskflow/internal/store"
 )
 
-// LogBroadcaster is a callback function for broadcasting logs via WebSocket
-type LogBroadcaster func(runID string, stream string, content string, timestamp time.Time)
+// LogBroadcaster is a callback function for broadcasting logs via WebSocket. It is called
+// once the line is stored; id is its log entry ID, or 0 if it could not be stored.
+type LogBroadcaster func(runID string, id int, stream string, content string, timestamp time.Time)
 
 // StatusBroadcaster is a callback function for broadcasting status changes via WebSocket
-type StatusBroadcaster func(runID string, status string)
+type StatusBroadcaster func(runID string, status string, job *store.Job)
 
 // NotificationSender is a callback function for sending notifications on job completion
 type NotificationSender func(job *store.Job, run *store.Run)
 
+// TimeoutWarningSender is a callback function for notifying that a run is approaching its timeout
+type TimeoutWarningSender func(job *store.Job, run *store.Run, elapsed time.Duration)
+
 // Executor handles job execution
 type Executor struct {
 	store              *store.Store
 	logBroadcaster     LogBroadcaster
 	statusBroadcaster  StatusBroadcaster
 	notificationSender NotificationSender
+	warningSender      TimeoutWarningSender
+	locks              *ResourceLocks
+	compressLogs       bool
+	maxTotalRuns       int
+	maxTimeoutSeconds  int
+	maxLogBytes        int64
 }
 
 // New creates a new executor
 func New(st *store.Store) *Executor {
-	return &Executor{store: st}
+	return &Executor{store: st, locks: NewResourceLocks()}
 }
 
 // SetLogBroadcaster sets the callback for broadcasting logs
@@ -46,13 +62,230 @@ func (e *Executor) SetStatusBroadcaster(broadcaster StatusBroadcaster) {
 	e.statusBroadcaster = broadcaster
 }
 
+// SetCompressLogs enables archiving each run's logs in compressed form once it finishes.
+// Live streaming is unaffected.
+func (e *Executor) SetCompressLogs(enabled bool) {
+	e.compressLogs = enabled
+}
+
+// SetMaxTotalRuns evicts the oldest runs after each job execution once more than max are
+// stored. 0 disables the cap.
+func (e *Executor) SetMaxTotalRuns(max int) {
+	e.maxTotalRuns = max
+}
+
+// SetMaxTimeoutSeconds caps every run's timeout, even of jobs saved before a lower
+// MAX_JOB_TIMEOUT_SECONDS was configured. 0 leaves job timeouts as they are.
+func (e *Executor) SetMaxTimeoutSeconds(max int) {
+	e.maxTimeoutSeconds = max
+}
+
+// SetMaxLogBytes caps how much of each run's output is stored and streamed; output past
+// the cap is counted but dropped. 0 stores everything.
+func (e *Executor) SetMaxLogBytes(max int64) {
+	e.maxLogBytes = max
+}
+
 // SetNotificationSender sets the callback for sending notifications
 func (e *Executor) SetNotificationSender(sender NotificationSender) {
 	e.notificationSender = sender
 }
 
-// Execute runs a job and returns the run result
+// SetTimeoutWarningSender sets the callback for jobs that opt in to timeout warning notifications
+func (e *Executor) SetTimeoutWarningSender(sender TimeoutWarningSender) {
+	e.warningSender = sender
+}
+
+// Execute runs a single attempt of a job and returns the run result
 func (e *Executor) Execute(ctx context.Context, run *store.Run, job *store.Job) error {
+	if err := e.executeAttempt(ctx, run, job); err != nil {
+		return err
+	}
+
+	e.complete(job, run)
+	return nil
+}
+
+// ExecuteTest runs a single attempt of a job in a freshly created scratch directory that is
+// removed afterwards, with its timeout capped at TestRunTimeoutSeconds. Unlike Execute it
+// leaves the job's failure streak and schedule alone and sends no notification.
+func (e *Executor) ExecuteTest(ctx context.Context, run *store.Run, job *store.Job) error {
+	dir, err := os.MkdirTemp("", "taskflow-test-")
+	if err != nil {
+		run.Status = internal.JobStatusFailure
+		msg := fmt.Sprintf("Failed to create scratch directory: %v", err)
+		run.ErrorMsg = &msg
+		e.store.UpdateRun(run)
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	testJob := *job
+	testJob.WorkingDir = dir
+	testJob.TimeoutWarnPercent = 0
+	if testJob.TimeoutSeconds <= 0 || testJob.TimeoutSeconds > internal.TestRunTimeoutSeconds {
+		testJob.TimeoutSeconds = internal.TestRunTimeoutSeconds
+	}
+
+	e.logSystem(run.ID, fmt.Sprintf("Test run in scratch directory %s (timeout %ds)", dir, testJob.TimeoutSeconds))
+	return e.executeAttempt(ctx, run, &testJob)
+}
+
+// ExecuteWithRetry runs a job, retrying failed attempts up to job.RetryCount times.
+// Each retry is recorded as a new run linked to the first one, which ends up reflecting
+// the final outcome; only the final attempt sends a notification.
+func (e *Executor) ExecuteWithRetry(ctx context.Context, run *store.Run, job *store.Job) error {
+	return e.executeRetries(ctx, run, run, job)
+}
+
+// executeRetries runs run, an attempt of the execution that began with first, followed by
+// any retries it is still due
+func (e *Executor) executeRetries(ctx context.Context, first, run *store.Run, job *store.Job) error {
+	for attempt := max(run.Attempt, 1); ; attempt++ {
+		if err := e.executeAttempt(ctx, run, job); err != nil {
+			return err
+		}
+
+		if attempt > job.RetryCount || !shouldRetry(job, run) {
+			break
+		}
+
+		retryMsg := fmt.Sprintf("Retrying in %d seconds (retry %d of %d)", job.RetryDelaySeconds, attempt, job.RetryCount)
+		e.logSystem(run.ID, retryMsg)
+
+		select {
+		case <-time.After(time.Duration(job.RetryDelaySeconds) * time.Second):
+		case <-ctx.Done():
+			e.completeAttempts(job, first, run)
+			return ctx.Err()
+		}
+
+		nextRun, err := e.store.CreateRetryRun(first, attempt+1)
+		if err != nil {
+			e.completeAttempts(job, first, run)
+			return fmt.Errorf("failed to create retry run: %w", err)
+		}
+		run = nextRun
+	}
+
+	e.completeAttempts(job, first, run)
+	return nil
+}
+
+// completeAttempts copies the last attempt's outcome onto the first run, so the run
+// listed for the execution shows how it finally ended, then completes the job
+func (e *Executor) completeAttempts(job *store.Job, first, last *store.Run) {
+	if last != first {
+		first.Status = last.Status
+		first.ExitCode = last.ExitCode
+		first.ErrorMsg = last.ErrorMsg
+		first.OutputPreview = last.OutputPreview
+		first.FinishedAt = last.FinishedAt
+		if first.StartedAt != nil && last.FinishedAt != nil {
+			duration := last.FinishedAt.Sub(*first.StartedAt).Milliseconds()
+			first.DurationMs = &duration
+		}
+		if err := e.store.UpdateRun(first); err != nil {
+			log.Printf("Failed to update run %s with its final attempt: %v\n", first.ID, err)
+		}
+		if e.statusBroadcaster != nil {
+			e.statusBroadcaster(first.ID, first.Status, job)
+		}
+	}
+	e.complete(job, last)
+}
+
+// shouldRetry reports whether a finished run is eligible for another attempt.
+// An empty RetryOnExitCodes list retries on any failure.
+func shouldRetry(job *store.Job, run *store.Run) bool {
+	if run.Status != internal.JobStatusFailure && run.Status != internal.JobStatusTimeout {
+		return false
+	}
+	if len(job.RetryOnExitCodes) == 0 {
+		return true
+	}
+	return run.ExitCode != nil && slices.Contains(job.RetryOnExitCodes, *run.ExitCode)
+}
+
+// complete records the final outcome of a job execution, sends the notification and
+// enforces the global run cap
+func (e *Executor) complete(job *store.Job, run *store.Run) {
+	e.recordOutcome(job, run)
+	e.notify(job, run)
+
+	if e.maxTotalRuns > 0 {
+		if evicted, err := e.store.EnforceGlobalRunCap(e.maxTotalRuns); err != nil {
+			log.Printf("Failed to enforce run cap: %v\n", err)
+		} else if evicted > 0 {
+			log.Printf("Evicted %d runs over the cap of %d\n", evicted, e.maxTotalRuns)
+		}
+	}
+}
+
+// recordOutcome tracks the job's consecutive failures and auto-disables it
+// once its auto_disable_after_failures threshold is reached
+func (e *Executor) recordOutcome(job *store.Job, run *store.Run) {
+	var failed bool
+	switch run.Status {
+	case internal.JobStatusSuccess:
+		failed = false
+	case internal.JobStatusFailure, internal.JobStatusTimeout:
+		failed = true
+	default:
+		// Cancelled runs say nothing about the job's health
+		return
+	}
+
+	failures, disabled, err := e.store.RecordJobOutcome(job.ID, failed)
+	if err != nil {
+		log.Printf("Failed to record outcome for job %s: %v\n", job.ID, err)
+		return
+	}
+	job.ConsecutiveFailures = failures
+
+	// pause_on_failure: a failed scheduled run stops further scheduled fires until a run succeeds
+	if failed && run.TriggerType == internal.TriggerScheduled {
+		if paused, err := e.store.PauseScheduleOnFailure(job.ID); err != nil {
+			log.Printf("Failed to pause schedule for job %s: %v\n", job.ID, err)
+		} else if paused {
+			job.SchedulePaused = true
+			e.logSystem(run.ID, "Schedule paused after this failed run (pause_on_failure); a successful manual run or re-enabling the schedule resumes it")
+		}
+	} else if !failed {
+		if resumed, err := e.store.ResumePausedSchedule(job.ID); err != nil {
+			log.Printf("Failed to resume schedule for job %s: %v\n", job.ID, err)
+		} else if resumed {
+			job.SchedulePaused = false
+			e.logSystem(run.ID, "Schedule resumed after a successful run")
+		}
+	}
+
+	if disabled {
+		job.Enabled = false
+		msg := fmt.Sprintf("Job auto-disabled after %d consecutive failures", failures)
+		log.Printf("%s: %s (%s)\n", msg, job.Name, job.ID)
+		e.logSystem(run.ID, msg)
+	}
+}
+
+// notify sends the completion notification if a sender is configured
+func (e *Executor) notify(job *store.Job, run *store.Run) {
+	if e.notificationSender != nil {
+		e.notificationSender(job, run)
+	}
+}
+
+// executeAttempt runs the job script once and records the outcome on run
+func (e *Executor) executeAttempt(ctx context.Context, run *store.Run, job *store.Job) error {
+	// Scripts too large to keep inline are only loaded when the job runs
+	if err := e.store.LoadJobScript(job); err != nil {
+		run.Status = internal.JobStatusFailure
+		msg := fmt.Sprintf("Failed to load job script: %v", err)
+		run.ErrorMsg = &msg
+		e.store.UpdateRun(run)
+		return err
+	}
+
 	// Validate job script
 	if job.Script == "" {
 		run.Status = internal.JobStatusFailure
@@ -70,6 +303,15 @@ func (e *Executor) Execute(ctx context.Context, run *store.Run, job *store.Job)
 		return fmt.Errorf("script too large")
 	}
 
+	job = e.clampTimeout(run, job)
+
+	// Serialize with other jobs that share the same resource lock
+	release, err := e.acquireResourceLock(ctx, run, job)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	// Update run status to running
 	run.Status = internal.JobStatusRunning
 	now := time.Now()
@@ -79,7 +321,38 @@ func (e *Executor) Execute(ctx context.Context, run *store.Run, job *store.Job)
 	}
 	// Broadcast status change via WebSocket
 	if e.statusBroadcaster != nil {
-		e.statusBroadcaster(run.ID, run.Status)
+		e.statusBroadcaster(run.ID, run.Status, job)
+	}
+
+	// Setup hook: a failure fails the run without starting the main script
+	if job.PreScript != "" {
+		if err := e.runHook(ctx, run, job, "pre_script", job.PreScript); err != nil {
+			finished := time.Now()
+			run.FinishedAt = &finished
+			duration := finished.Sub(*run.StartedAt).Milliseconds()
+			run.DurationMs = &duration
+			var msg string
+			if errors.Is(ctx.Err(), context.Canceled) {
+				run.Status = internal.JobStatusCancelled
+				msg = "Job was cancelled before completion"
+			} else {
+				run.Status = internal.JobStatusFailure
+				msg = fmt.Sprintf("Pre-script failed: %v", err)
+			}
+			run.ErrorMsg = &msg
+			e.runPostScript(ctx, run, job)
+			e.finishAttempt(run, job)
+			return nil
+		}
+	}
+
+	// failStart records a failure to launch the main script; the teardown hook still runs
+	failStart := func(msg string, err error) error {
+		run.Status = internal.JobStatusFailure
+		run.ErrorMsg = &msg
+		e.runPostScript(ctx, run, job)
+		e.store.UpdateRun(run)
+		return err
 	}
 
 	// Create timeout context
@@ -88,118 +361,611 @@ func (e *Executor) Execute(ctx context.Context, run *store.Run, job *store.Job)
 	defer cancel()
 
 	// Create command - scripts executed as-is (admin only, by design)
-	cmd := exec.CommandContext(execCtx, "bash", "-c", job.Script)
-	cmd.Dir = job.WorkingDir
-
-	// Set up pipes for stdout/stderr
-	stdout, err := cmd.StdoutPipe()
+	buildCommand, script := shellCommand, job.Script
+	scriptFile := len(job.Script) > internal.MaxScriptArgSize
+	if scriptFile {
+		// Too large to pass as a single argument, so the interpreter reads it from a file
+		path, cleanup, err := writeScriptFile(job.Interpreter, job.Script)
+		if err != nil {
+			return failStart(fmt.Sprintf("Failed to write script file: %v", err), err)
+		}
+		defer cleanup()
+		buildCommand, script = scriptFileCommand, path
+	}
+	cmd, err := buildCommand(execCtx, job.Interpreter, script, job.LoginShell)
 	if err != nil {
-		run.Status = internal.JobStatusFailure
-		msg := fmt.Sprintf("Failed to create stdout pipe: %v", err)
-		run.ErrorMsg = &msg
-		e.store.UpdateRun(run)
-		return err
+		return failStart(err.Error(), err)
 	}
+	cmd.Dir = job.WorkingDir
 
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		run.Status = internal.JobStatusFailure
-		msg := fmt.Sprintf("Failed to create stderr pipe: %v", err)
-		run.ErrorMsg = &msg
-		e.store.UpdateRun(run)
-		return err
+	// Record how the script is launched before it starts, so even a run that never
+	// finishes shows what it was running
+	run.CommandSnapshot = commandSnapshot(job, scriptFile)
+	if err := e.store.SetRunCommandSnapshot(run.ID, run.CommandSnapshot); err != nil {
+		log.Printf("Failed to save command snapshot for run %s: %v\n", run.ID, err)
 	}
 
+	// Set up pipes for stdout/stderr. exec copies the output into them and Wait waits for
+	// that copy, giving up OutputWaitDelay after the script exits if a background child
+	// still holds the output open. The readers only queue lines in a logSink, so a slow
+	// database cannot hold them up past that delay.
+	stdout, stdoutW := io.Pipe()
+	stderr, stderrW := io.Pipe()
+	cmd.Stdout = stdoutW
+	cmd.Stderr = stderrW
+	cmd.WaitDelay = internal.OutputWaitDelay
+
 	// Start the command
 	if err := cmd.Start(); err != nil {
-		run.Status = internal.JobStatusFailure
-		msg := fmt.Sprintf("Failed to start command: %v", err)
-		run.ErrorMsg = &msg
-		e.store.UpdateRun(run)
-		return err
+		stdoutW.Close()
+		stderrW.Close()
+		return failStart(fmt.Sprintf("Failed to start command: %v", err), err)
+	}
+
+	// Lower the script's CPU priority; children it spawns inherit the niceness
+	if job.Nice > 0 {
+		if err := setNice(cmd.Process.Pid, job.Nice); err != nil {
+			msg := fmt.Sprintf("Could not apply nice %d: %v", job.Nice, err)
+			log.Printf("Run %s: %s\n", run.ID, msg)
+			e.store.AddLog(run.ID, internal.StreamSystem, msg)
+		}
+	}
+
+	// Warn once the run has used the configured share of its timeout
+	var warnTimer *time.Timer
+	if job.TimeoutWarnPercent > 0 {
+		warnAfter := timeoutDuration * time.Duration(job.TimeoutWarnPercent) / 100
+		warnTimer = time.AfterFunc(warnAfter, func() {
+			e.warnTimeout(run, job, warnAfter, timeoutDuration)
+		})
 	}
 
 	// Stream logs concurrently with synchronization
+	tail := newOutputTail(job)
+	levels := newLevelDetector(job)
+	dropped := &droppedLogs{}
+	volume := &logVolume{max: e.maxLogBytes}
+	sink := e.newLogSink(run.ID, dropped)
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func() {
 		defer wg.Done()
-		e.streamLogs(run.ID, stdout, "stdout")
+		e.streamLogs(run.ID, stdout, "stdout", tail, levels, sink, volume)
 	}()
 	go func() {
 		defer wg.Done()
-		e.streamLogs(run.ID, stderr, "stderr")
+		e.streamLogs(run.ID, stderr, "stderr", tail, levels, sink, volume)
 	}()
 
 	// Wait for command to complete or timeout
 	err = cmd.Wait()
+	if warnTimer != nil {
+		warnTimer.Stop()
+	}
+	if errors.Is(err, exec.ErrWaitDelay) {
+		// The script itself succeeded; only a background child kept its output open
+		e.logSystem(run.ID, fmt.Sprintf("Stopped reading output %s after the script exited; a background process still held it open", internal.OutputWaitDelay))
+		err = nil
+	}
 
 	// Ensure all logs are fully written before proceeding
+	stdoutW.Close()
+	stderrW.Close()
 	wg.Wait()
+	sink.close()
+	e.reportDroppedLogs(run, dropped)
+	e.reportLogVolume(run, volume)
 
 	// Determine final status and update run
-	e.finalizeRun(run, job, err, execCtx)
+	e.finalizeRun(run, job, err, execCtx, cmd.ProcessState, tail)
+
+	// Teardown hook runs whatever the outcome; its result is logged but never changes the status
+	e.runPostScript(ctx, run, job)
+
+	e.finishAttempt(run, job)
+	return nil
+}
+
+// commandSnapshot describes how job's script is about to be launched
+func commandSnapshot(job *store.Job, scriptFile bool) *store.CommandSnapshot {
+	interpreter := job.Interpreter
+	if interpreter == "" {
+		interpreter = defaultInterpreter
+	}
+	workingDir := job.WorkingDir
+	if workingDir == "" {
+		// An empty cmd.Dir runs the script in the server's own directory
+		workingDir, _ = os.Getwd()
+	}
+	sum := sha256.Sum256([]byte(job.Script))
+
+	return &store.CommandSnapshot{
+		Interpreter:    interpreter,
+		LoginShell:     job.LoginShell,
+		WorkingDir:     workingDir,
+		ScriptSHA256:   hex.EncodeToString(sum[:]),
+		ScriptBytes:    len(job.Script),
+		ScriptFile:     scriptFile,
+		TimeoutSeconds: job.TimeoutSeconds,
+		Nice:           job.Nice,
+	}
+}
+
+// clampTimeout returns job with its timeout capped at SetMaxTimeoutSeconds. A clamped
+// job is a copy, so the caller's job keeps its saved timeout.
+func (e *Executor) clampTimeout(run *store.Run, job *store.Job) *store.Job {
+	timeout := e.timeoutSeconds(job)
+	if timeout == job.TimeoutSeconds {
+		return job
+	}
+	clamped := *job
+	clamped.TimeoutSeconds = timeout
+	e.logSystem(run.ID, fmt.Sprintf("Timeout of %d seconds exceeds the configured maximum; using %d seconds", job.TimeoutSeconds, timeout))
+	return &clamped
+}
+
+// timeoutSeconds returns the timeout a run of job gets, capped at SetMaxTimeoutSeconds
+func (e *Executor) timeoutSeconds(job *store.Job) int {
+	if e.maxTimeoutSeconds > 0 && job.TimeoutSeconds > e.maxTimeoutSeconds {
+		return e.maxTimeoutSeconds
+	}
+	return job.TimeoutSeconds
+}
+
+// acquireResourceLock waits for the job's resource lock, if it declares one, and returns
+// the function that releases it. A run that gives up waiting is finished as a failure.
+func (e *Executor) acquireResourceLock(ctx context.Context, run *store.Run, job *store.Job) (func(), error) {
+	if job.ResourceLock == "" {
+		return func() {}, nil
+	}
+
+	if holder := e.locks.Holder(job.ResourceLock); holder != "" {
+		e.store.AddLog(run.ID, internal.StreamSystem, fmt.Sprintf("Waiting for resource lock %q held by run %s", job.ResourceLock, holder))
+	}
+	waitStart := time.Now()
+	if err := e.locks.Acquire(ctx, job.ResourceLock, run.ID); err != nil {
+		// The run never started; its duration is the time spent waiting for the lock
+		finished := time.Now()
+		run.FinishedAt = &finished
+		waited := finished.Sub(waitStart).Milliseconds()
+		run.DurationMs = &waited
+		run.Status = internal.JobStatusFailure
+		msg := fmt.Sprintf("Failed to acquire resource lock %q: %v", job.ResourceLock, err)
+		run.ErrorMsg = &msg
+		e.store.UpdateRun(run)
+		if e.statusBroadcaster != nil {
+			e.statusBroadcaster(run.ID, run.Status, job)
+		}
+		return nil, err
+	}
+	return func() { e.locks.ReleaseHolder(job.ResourceLock, run.ID) }, nil
+}
 
+// finishAttempt logs and persists a finished attempt's final status, then broadcasts it
+func (e *Executor) finishAttempt(run *store.Run, job *store.Job) {
 	// Log final status
 	finalMsg := fmt.Sprintf("Job %s with status: %s", run.ID, run.Status)
-	e.store.AddLog(run.ID, internal.StreamSystem, finalMsg)
-	// Broadcast final log
-	if e.logBroadcaster != nil {
-		e.logBroadcaster(run.ID, internal.StreamSystem, finalMsg, time.Now())
-	}
+	e.logSystem(run.ID, finalMsg)
 
 	// Update run in database
 	if err := e.store.UpdateRun(run); err != nil {
 		log.Printf("Failed to update run: %v\n", err)
 	}
 
+	if e.compressLogs {
+		if err := e.store.ArchiveLogs(run.ID); err != nil {
+			log.Printf("Failed to compress logs for run %s: %v\n", run.ID, err)
+		}
+	}
+
 	// Broadcast final status change via WebSocket
 	if e.statusBroadcaster != nil {
-		e.statusBroadcaster(run.ID, run.Status)
+		e.statusBroadcaster(run.ID, run.Status, job)
 	}
+}
 
-	// Send notification if configured
-	if e.notificationSender != nil {
-		e.notificationSender(job, run)
+// runPostScript runs the job's teardown hook, if any. It runs even when the run was
+// cancelled, so it is detached from ctx's cancellation and bounded only by HookTimeout.
+func (e *Executor) runPostScript(ctx context.Context, run *store.Run, job *store.Job) {
+	if job.PostScript == "" {
+		return
+	}
+	if err := e.runHook(context.WithoutCancel(ctx), run, job, "post_script", job.PostScript); err != nil {
+		log.Printf("Run %s: post_script failed: %v\n", run.ID, err)
+	}
+}
+
+// runHook runs a pre/post-script in the job's working directory with its own timeout,
+// recording its output and result in the run's system log
+func (e *Executor) runHook(ctx context.Context, run *store.Run, job *store.Job, name, script string) error {
+	hookCtx, cancel := context.WithTimeout(ctx, internal.HookTimeout)
+	defer cancel()
+
+	cmd, err := shellCommand(hookCtx, job.Interpreter, script, job.LoginShell)
+	if err == nil {
+		cmd.Dir = job.WorkingDir
+		cmd.WaitDelay = internal.OutputWaitDelay
+		var output []byte
+		output, err = cmd.CombinedOutput()
+		for _, line := range strings.Split(strings.TrimRight(string(output), "\r\n"), "\n") {
+			if line = strings.TrimRight(line, "\r"); line != "" {
+				e.logSystem(run.ID, fmt.Sprintf("[%s] %s", name, line))
+			}
+		}
+		if errors.Is(hookCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("exceeded timeout of %s", internal.HookTimeout)
+		}
 	}
 
+	if err != nil {
+		e.logSystem(run.ID, fmt.Sprintf("%s failed: %v", name, err))
+		return err
+	}
+	e.logSystem(run.ID, fmt.Sprintf("%s completed successfully", name))
 	return nil
 }
 
-// streamLogs reads from a pipe and stores logs
-func (e *Executor) streamLogs(runID string, pipe interface{}, stream string) {
+// writeScriptFile writes script to a private temp file for scriptFileCommand and returns
+// its path and a function that removes it
+func writeScriptFile(interpreter, script string) (string, func(), error) {
+	f, err := os.CreateTemp("", "taskflow-script-*"+scriptFileExt(interpreter))
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.Remove(f.Name()) }
+
+	if _, err := f.WriteString(script); err != nil {
+		f.Close()
+		cleanup()
+		return "", nil, err
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return f.Name(), cleanup, nil
+}
+
+// logSystem records a system message in the run's log and broadcasts it
+func (e *Executor) logSystem(runID, msg string) {
+	entry, err := e.store.AddLog(runID, internal.StreamSystem, msg)
+	if e.logBroadcaster == nil {
+		return
+	}
+	if err != nil {
+		e.logBroadcaster(runID, 0, internal.StreamSystem, msg, time.Now())
+		return
+	}
+	e.logBroadcaster(runID, entry.ID, entry.Stream, entry.Content, entry.Timestamp)
+}
+
+// outputTail remembers the last non-empty line written to each output stream and
+// whether any line matched the job's success or failure pattern
+type outputTail struct {
+	mu             sync.Mutex
+	lastStdout     string
+	lastStderr     string
+	successPattern *regexp.Regexp
+	failurePattern *regexp.Regexp
+	successMatch   string
+	failureMatch   string
+}
+
+// newOutputTail returns an outputTail that also watches for the job's output patterns.
+// Patterns are validated when the job is saved; one that no longer compiles is ignored.
+func newOutputTail(job *store.Job) *outputTail {
+	t := &outputTail{}
+	if job.SuccessPattern != "" {
+		if re, err := regexp.Compile(job.SuccessPattern); err == nil {
+			t.successPattern = re
+		} else {
+			log.Printf("Ignoring invalid success pattern for job %s: %v\n", job.ID, err)
+		}
+	}
+	if job.FailurePattern != "" {
+		if re, err := regexp.Compile(job.FailurePattern); err == nil {
+			t.failurePattern = re
+		} else {
+			log.Printf("Ignoring invalid failure pattern for job %s: %v\n", job.ID, err)
+		}
+	}
+	return t
+}
+
+// record stores line as the latest output of stream
+func (t *outputTail) record(stream, line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if stream == "stderr" {
+		t.lastStderr = line
+	} else {
+		t.lastStdout = line
+	}
+	if t.successPattern != nil && t.successMatch == "" && t.successPattern.MatchString(line) {
+		t.successMatch = line
+	}
+	if t.failurePattern != nil && t.failureMatch == "" && t.failurePattern.MatchString(line) {
+		t.failureMatch = line
+	}
+}
+
+// outcome applies the output patterns to a run that was not cancelled or timed out.
+// A failure match always fails the run; otherwise a configured success pattern decides
+// the outcome regardless of exit code. ok reports whether a pattern decided it, and
+// reason explains a pattern-driven failure.
+func (t *outputTail) outcome() (success, ok bool, reason string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.failureMatch != "" {
+		return false, true, fmt.Sprintf("Output matched failure pattern: %s", t.failureMatch)
+	}
+	if t.successPattern != nil {
+		if t.successMatch != "" {
+			return true, true, ""
+		}
+		return false, true, "Output did not match success pattern"
+	}
+	return false, false, ""
+}
+
+// preview returns the last stderr line (falling back to stdout), keeping at most
+// MaxOutputPreviewLength trailing characters
+func (t *outputTail) preview() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	line := t.lastStderr
+	if line == "" {
+		line = t.lastStdout
+	}
+	if runes := []rune(line); len(runes) > internal.MaxOutputPreviewLength {
+		line = string(runes[len(runes)-internal.MaxOutputPreviewLength:])
+	}
+	return line
+}
+
+// warnTimeout records that a still-running job has reached its timeout warning threshold
+func (e *Executor) warnTimeout(run *store.Run, job *store.Job, elapsed, timeout time.Duration) {
+	msg := fmt.Sprintf("Warning: job has been running for %s, %d%% of its %s timeout",
+		elapsed.Round(time.Second), job.TimeoutWarnPercent, timeout)
+	e.logSystem(run.ID, msg)
+
+	if job.TimeoutWarnNotify && e.warningSender != nil {
+		e.warningSender(job, run, elapsed)
+	}
+}
+
+// streamLogs reads from a pipe and stores logs, writing buffered lines in batches
+func (e *Executor) streamLogs(runID string, pipe interface{}, stream string, tail *outputTail, levels *levelDetector, sink *logSink, volume *logVolume) {
 	// Simple implementation - in production, would use bufio.Scanner
 	// For now, just ensure pipe is read
 	if r, ok := pipe.(interface{ Read(p []byte) (n int, err error) }); ok {
 		buf := make([]byte, internal.LogStreamBufferSize)
+		batch := make([]store.LogEntry, 0, internal.LogFlushBatchSize)
+		// partial holds the unterminated end of the last read so lines split across reads stay whole
+		partial := ""
+		emit := func(line string) {
+			if line == "" {
+				return
+			}
+			tail.record(stream, line)
+			if !volume.admit(len(line) + 1) {
+				return
+			}
+			batch = append(batch, store.LogEntry{Timestamp: time.Now(), Stream: stream, Content: line, Level: levels.detect(line)})
+		}
 		for {
 			n, err := r.Read(buf)
 			if n > 0 {
-				lines := strings.Split(string(buf[:n]), "\n")
-				for _, line := range lines {
-					if line != "" {
-						timestamp := time.Now()
-						if _, err := e.store.AddLog(runID, stream, line); err != nil {
-							log.Printf("Failed to add log: %v\n", err)
-						}
-						// Broadcast log via WebSocket
-						if e.logBroadcaster != nil {
-							e.logBroadcaster(runID, stream, line, timestamp)
-						}
-					}
+				lines := strings.Split(partial+string(buf[:n]), "\n")
+				partial = lines[len(lines)-1]
+				for _, line := range lines[:len(lines)-1] {
+					emit(line)
 				}
+				// Hand over after every read so a quiet job's output is not held back
+				batch = sink.add(batch)
 			}
 			if err != nil {
 				break
 			}
 		}
+		emit(partial)
+		sink.add(batch)
+	}
+}
+
+// logSink queues an attempt's output lines for a single writer goroutine, so the pipe
+// readers never wait on the database and a slow write cannot outlast OutputWaitDelay.
+// The writer broadcasts each line once it is stored, so live messages carry its log ID.
+type logSink struct {
+	mu      sync.Mutex
+	pending []store.LogEntry
+	closed  bool
+	ready   chan struct{}
+	done    chan struct{}
+}
+
+// newLogSink starts the writer that stores runID's queued lines, counting lines it
+// cannot store in dropped
+func (e *Executor) newLogSink(runID string, dropped *droppedLogs) *logSink {
+	s := &logSink{ready: make(chan struct{}, 1), done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		for range s.ready {
+			s.mu.Lock()
+			pending, closed := s.pending, s.closed
+			s.pending = nil
+			s.mu.Unlock()
+
+			for len(pending) > 0 {
+				n := min(len(pending), internal.LogFlushBatchSize)
+				e.flushLogs(runID, pending[:n], dropped)
+				e.broadcastLogs(runID, pending[:n])
+				pending = pending[n:]
+			}
+			if closed {
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// broadcastLogs sends written output lines to live viewers; lines that could not be
+// stored still go out, with no ID
+func (e *Executor) broadcastLogs(runID string, entries []store.LogEntry) {
+	if e.logBroadcaster == nil {
+		return
+	}
+	for _, entry := range entries {
+		e.logBroadcaster(runID, entry.ID, entry.Stream, entry.Content, entry.Timestamp)
 	}
 }
 
+// add queues a copy of entries for the writer and returns entries emptied for reuse
+func (s *logSink) add(entries []store.LogEntry) []store.LogEntry {
+	if len(entries) == 0 {
+		return entries
+	}
+	s.mu.Lock()
+	s.pending = append(s.pending, entries...)
+	s.mu.Unlock()
+	s.wake()
+	return entries[:0]
+}
+
+// close waits until every queued line has been written and stops the writer
+func (s *logSink) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.wake()
+	<-s.done
+}
+
+// wake signals the writer without blocking; one pending signal covers any number of adds
+func (s *logSink) wake() {
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// flushLogs writes buffered output lines in one batch and returns the emptied buffer.
+// A batch that still fails after LogWriteAttempts tries is dropped and counted in dropped;
+// the first drop flags the run so its log is not mistaken for complete output.
+func (e *Executor) flushLogs(runID string, batch []store.LogEntry, dropped *droppedLogs) []store.LogEntry {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	var err error
+	for attempt := 1; attempt <= internal.LogWriteAttempts; attempt++ {
+		if err = e.store.AddLogs(runID, batch); err == nil {
+			return batch[:0]
+		}
+		if attempt < internal.LogWriteAttempts {
+			time.Sleep(internal.LogWriteRetryDelay)
+		}
+	}
+
+	log.Printf("Run %s: dropping %d log lines after %d failed writes: %v\n", runID, len(batch), internal.LogWriteAttempts, err)
+	if dropped.add(len(batch)) {
+		if err := e.store.MarkLogsIncomplete(runID); err != nil {
+			log.Printf("Run %s: %v\n", runID, err)
+		}
+	}
+	return batch[:0]
+}
+
+// droppedLogs counts output lines of one attempt that could not be stored. The stdout
+// and stderr readers share it.
+type droppedLogs struct {
+	mu    sync.Mutex
+	lines int
+}
+
+// add counts n dropped lines and reports whether they are the first
+func (d *droppedLogs) add(n int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	first := d.lines == 0
+	d.lines += n
+	return first
+}
+
+// count returns the number of dropped lines
+func (d *droppedLogs) count() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.lines
+}
+
+// reportDroppedLogs flags the run and adds one system entry once its output has been
+// read, if any lines were lost. The flag is set again here in case the database rejected
+// it when the first lines were dropped.
+func (e *Executor) reportDroppedLogs(run *store.Run, dropped *droppedLogs) {
+	n := dropped.count()
+	if n == 0 {
+		return
+	}
+
+	run.LogsIncomplete = true
+	if err := e.store.MarkLogsIncomplete(run.ID); err != nil {
+		log.Printf("Run %s: %v\n", run.ID, err)
+	}
+
+	msg := fmt.Sprintf("Log storage failed: %d output lines could not be saved, so this log is incomplete", n)
+	e.logSystem(run.ID, msg)
+}
+
+// logVolume counts the output bytes of one attempt, newlines included, and stops storing
+// lines once max would be exceeded. The stdout and stderr readers share it.
+type logVolume struct {
+	mu        sync.Mutex
+	max       int64
+	total     int64
+	stored    int64
+	truncated bool
+}
+
+// admit counts an n-byte line and reports whether it is still within the cap. Once a line
+// is refused no later line is stored, so the stored log is a prefix of the output.
+func (v *logVolume) admit(n int) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.total += int64(n)
+	if v.truncated || (v.max > 0 && v.stored+int64(n) > v.max) {
+		v.truncated = true
+		return false
+	}
+	v.stored += int64(n)
+	return true
+}
+
+// reportLogVolume records the attempt's output size on the run and, when output was cut
+// at the cap, adds one system entry saying how much was kept
+func (e *Executor) reportLogVolume(run *store.Run, volume *logVolume) {
+	volume.mu.Lock()
+	total, truncated := volume.total, volume.truncated
+	volume.mu.Unlock()
+
+	run.LogBytesTotal = total
+	run.LogTruncated = truncated
+	if err := e.store.SetRunLogVolume(run.ID, total, truncated); err != nil {
+		log.Printf("Run %s: %v\n", run.ID, err)
+	}
+	if !truncated {
+		return
+	}
+
+	msg := fmt.Sprintf("Output truncated at %d bytes (the script wrote %d bytes); raise MAX_RUN_LOG_BYTES to keep more", volume.max, total)
+	e.logSystem(run.ID, msg)
+}
+
 // CanExecute checks if a job can be executed (respecting concurrency limits)
 func (e *Executor) CanExecute() bool {
 	// In Phase 1, we only allow one concurrent job
-	runs, err := e.store.ListRuns(nil, 1, 0)
+	runs, err := e.store.ListRuns(store.RunFilter{}, 1, 0)
 	if err != nil || len(runs) == 0 {
 		return true
 	}
@@ -208,9 +974,10 @@ func (e *Executor) CanExecute() bool {
 	return status != internal.JobStatusRunning && status != internal.JobStatusPending
 }
 
-// GetRunningJob returns the currently running job, if any
+// GetRunningJob returns the most recently started run if it is still running.
+// Use Store.ListRunningRuns to see every in-flight run.
 func (e *Executor) GetRunningJob() *store.Run {
-	runs, err := e.store.ListRuns(nil, 1, 0)
+	runs, err := e.store.ListRuns(store.RunFilter{}, 1, 0)
 	if err != nil || len(runs) == 0 {
 		return nil
 	}
@@ -221,16 +988,34 @@ func (e *Executor) GetRunningJob() *store.Run {
 	return nil
 }
 
-// finalizeRun sets the final status, exit code, and error message for a completed run.
+// finalizeRun sets the final status, exit code, error message, CPU usage, and output preview for a completed run.
 // Extracted from Execute() to reduce its complexity and improve maintainability.
-func (e *Executor) finalizeRun(run *store.Run, job *store.Job, cmdErr error, execCtx context.Context) {
+// state may be nil if the process never produced a ProcessState.
+func (e *Executor) finalizeRun(run *store.Run, job *store.Job, cmdErr error, execCtx context.Context, state *os.ProcessState, tail *outputTail) {
 	finished := time.Now()
 	run.FinishedAt = &finished
 	duration := int64(finished.Sub(*run.StartedAt).Milliseconds())
 	run.DurationMs = &duration
 
+	// CPU time (user + system) from the process rusage, used for usage metering
+	if state != nil {
+		cpuSeconds := (state.UserTime() + state.SystemTime()).Seconds()
+		run.CPUSeconds = &cpuSeconds
+	}
+
+	// One-line summary of the output so run listings can show a failure reason at a glance
+	if tail != nil {
+		if preview := tail.preview(); preview != "" {
+			run.OutputPreview = &preview
+		}
+	}
+
 	if cmdErr != nil {
-		if errors.Is(execCtx.Err(), context.DeadlineExceeded) {
+		if errors.Is(execCtx.Err(), context.Canceled) {
+			run.Status = internal.JobStatusCancelled
+			msg := "Job was cancelled before completion"
+			run.ErrorMsg = &msg
+		} else if errors.Is(execCtx.Err(), context.DeadlineExceeded) {
 			run.Status = internal.JobStatusTimeout
 			msg := fmt.Sprintf("Job exceeded timeout of %d seconds", job.TimeoutSeconds)
 			run.ErrorMsg = &msg
@@ -252,4 +1037,17 @@ func (e *Executor) finalizeRun(run *store.Run, job *store.Job, cmdErr error, exe
 		code := internal.ExitCodeSuccess
 		run.ExitCode = &code
 	}
+
+	// Output patterns override the exit code, but not a cancellation or timeout
+	if tail != nil && execCtx.Err() == nil {
+		if success, ok, reason := tail.outcome(); ok {
+			if success {
+				run.Status = internal.JobStatusSuccess
+				run.ErrorMsg = nil
+			} else {
+				run.Status = internal.JobStatusFailure
+				run.ErrorMsg = &reason
+			}
+		}
+	}
 }
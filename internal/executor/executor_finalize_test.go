@@ -2,6 +2,7 @@ package executor
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -33,7 +34,7 @@ func TestFinalizeRunSuccess(t *testing.T) {
 	execCtx := context.Background()
 
 	// No error means success
-	exec.finalizeRun(run, job, nil, execCtx)
+	exec.finalizeRun(run, job, nil, execCtx, nil, nil)
 
 	assert.Equal(t, internal.JobStatusSuccess, run.Status)
 	assert.NotNil(t, run.ExitCode)
@@ -72,7 +73,7 @@ func TestFinalizeRunTimeout(t *testing.T) {
 	// Pass a generic error; the context deadline will trigger timeout logic
 	testErr := context.DeadlineExceeded
 
-	exec.finalizeRun(run, job, testErr, ctx)
+	exec.finalizeRun(run, job, testErr, ctx, nil, nil)
 
 	assert.Equal(t, internal.JobStatusTimeout, run.Status)
 	assert.NotNil(t, run.ExitCode)
@@ -103,7 +104,7 @@ func TestFinalizeRunFailureWithExitCode(t *testing.T) {
 	run.StartedAt = &now
 	execCtx := context.Background()
 
-	exec.finalizeRun(run, job, context.Canceled, execCtx)
+	exec.finalizeRun(run, job, context.Canceled, execCtx, nil, nil)
 
 	assert.Equal(t, internal.JobStatusFailure, run.Status)
 	assert.NotNil(t, run.ErrorMsg)
@@ -134,7 +135,7 @@ func TestFinalizeRunFailureWithoutExitCode(t *testing.T) {
 	// Generic error without exit code
 	testErr := context.Canceled
 
-	exec.finalizeRun(run, job, testErr, execCtx)
+	exec.finalizeRun(run, job, testErr, execCtx, nil, nil)
 
 	assert.Equal(t, internal.JobStatusFailure, run.Status)
 	assert.Nil(t, run.ExitCode) // No exit code available
@@ -164,7 +165,7 @@ func TestFinalizeRunDurationCalculation(t *testing.T) {
 	run.StartedAt = &startTime
 	execCtx := context.Background()
 
-	exec.finalizeRun(run, job, nil, execCtx)
+	exec.finalizeRun(run, job, nil, execCtx, nil, nil)
 
 	assert.NotNil(t, run.DurationMs)
 	// Duration should be approximately 5000ms (5 seconds)
@@ -195,7 +196,7 @@ func TestFinalizeRunVeryShortDuration(t *testing.T) {
 	run.StartedAt = &startTime
 	execCtx := context.Background()
 
-	exec.finalizeRun(run, job, nil, execCtx)
+	exec.finalizeRun(run, job, nil, execCtx, nil, nil)
 
 	assert.NotNil(t, run.DurationMs)
 	assert.GreaterOrEqual(t, *run.DurationMs, int64(0))
@@ -223,7 +224,7 @@ func TestFinalizeRunFinishedAtNotNil(t *testing.T) {
 	run.StartedAt = &now
 	execCtx := context.Background()
 
-	exec.finalizeRun(run, job, nil, execCtx)
+	exec.finalizeRun(run, job, nil, execCtx, nil, nil)
 
 	assert.NotNil(t, run.FinishedAt)
 	assert.GreaterOrEqual(t, run.FinishedAt.Unix(), now.Unix())
@@ -254,7 +255,7 @@ func TestFinalizeRunErrorMessageFormat(t *testing.T) {
 	defer cancel()
 	time.Sleep(10 * time.Millisecond)
 
-	exec.finalizeRun(run, job, context.DeadlineExceeded, ctx)
+	exec.finalizeRun(run, job, context.DeadlineExceeded, ctx, nil, nil)
 
 	assert.NotNil(t, run.ErrorMsg)
 	assert.Contains(t, *run.ErrorMsg, "30")
@@ -283,7 +284,7 @@ func TestFinalizeRunPreservesJobID(t *testing.T) {
 	run.StartedAt = &now
 	execCtx := context.Background()
 
-	exec.finalizeRun(run, job, nil, execCtx)
+	exec.finalizeRun(run, job, nil, execCtx, nil, nil)
 
 	assert.Equal(t, originalJobID, run.JobID)
 }
@@ -341,9 +342,28 @@ func TestFinalizeRunWithMultipleFailures(t *testing.T) {
 				execCtx = context.Background()
 			}
 
-			exec.finalizeRun(run, job, tt.err, execCtx)
+			exec.finalizeRun(run, job, tt.err, execCtx, nil, nil)
 
 			assert.Equal(t, tt.expectedStatus, run.Status)
 		})
 	}
 }
+
+// TestOutputTailPreview tests stderr preference and the preview length cap
+func TestOutputTailPreview(t *testing.T) {
+	tail := &outputTail{}
+	assert.Equal(t, "", tail.preview())
+
+	tail.record("stdout", "done")
+	assert.Equal(t, "done", tail.preview())
+
+	tail.record("stderr", "error: boom")
+	tail.record("stdout", "later stdout")
+	assert.Equal(t, "error: boom", tail.preview(), "stderr should take precedence")
+
+	long := strings.Repeat("x", internal.MaxOutputPreviewLength) + "END"
+	tail.record("stderr", long)
+	preview := tail.preview()
+	assert.Len(t, preview, internal.MaxOutputPreviewLength)
+	assert.True(t, strings.HasSuffix(preview, "END"), "preview should keep the tail of the line")
+}
@@ -2,10 +2,19 @@ package executor
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	internal "github.com/taskflow/taskflow/internal"
 	"github.com/taskflow/taskflow/internal/store"
 )
 
@@ -69,12 +78,12 @@ func TestScriptValidation(t *testing.T) {
 		},
 		{
 			name:        "script at size limit",
-			script:      string(make([]byte, 1000000)),
+			script:      string(make([]byte, internal.MaxScriptSize)),
 			expectError: false,
 		},
 		{
 			name:        "script exceeds size limit",
-			script:      string(make([]byte, 1000001)),
+			script:      string(make([]byte, internal.MaxScriptSize+1)),
 			expectError: true,
 			expectMsg:   "exceeds maximum",
 		},
@@ -150,7 +159,7 @@ func TestLargeScriptHandling(t *testing.T) {
 
 	job := &store.Job{
 		ID:             "test-job",
-		Script:         string(make([]byte, 2000000)), // 2MB
+		Script:         string(make([]byte, 9000000)), // 9MB
 		TimeoutSeconds: 10,
 	}
 
@@ -207,3 +216,896 @@ func TestMultipleRunsExecutor(t *testing.T) {
 	// (because none are in running/pending state)
 	assert.True(t, exec.CanExecute())
 }
+
+// TestExecuteWithRetryRetryableCodeExhaustsRetries tests that a listed exit code is retried until retries run out
+func TestExecuteWithRetryRetryableCodeExhaustsRetries(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+
+	exec := New(mockStore.Store)
+
+	job, err := mockStore.CreateJob(&store.Job{
+		Name:             "tempfail",
+		Script:           "exit 75",
+		WorkingDir:       t.TempDir(),
+		TimeoutSeconds:   10,
+		RetryCount:       2,
+		RetryOnExitCodes: []int{75},
+	})
+	require.NoError(t, err)
+
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	require.NoError(t, exec.ExecuteWithRetry(context.Background(), run, job))
+
+	runs, err := mockStore.ListRuns(store.RunFilter{JobID: job.ID}, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, runs, 3, "initial attempt plus two retries")
+	for _, r := range runs {
+		assert.Equal(t, "failure", r.Status)
+		require.NotNil(t, r.ExitCode)
+		assert.Equal(t, 75, *r.ExitCode)
+	}
+}
+
+// TestExecuteWithRetryLinksAttempts tests that each retry is its own run linked to the
+// first attempt, which reflects the final outcome, and that collapsing shows one run
+func TestExecuteWithRetryLinksAttempts(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+
+	exec := New(mockStore.Store)
+
+	// Fails twice, then succeeds on the third attempt
+	counter := filepath.Join(t.TempDir(), "attempts")
+	job, err := mockStore.CreateJob(&store.Job{
+		Name:           "flaky",
+		Script:         "echo x >> " + counter + "; [ $(wc -l < " + counter + ") -ge 3 ]",
+		WorkingDir:     t.TempDir(),
+		TimeoutSeconds: 10,
+		RetryCount:     2,
+	})
+	require.NoError(t, err)
+
+	first, err := mockStore.CreateTaggedRun(job.ID, "manual", []string{"hotfix"})
+	require.NoError(t, err)
+
+	require.NoError(t, exec.ExecuteWithRetry(context.Background(), first, job))
+
+	attempts, err := mockStore.ListRunAttempts(first.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 3, "initial attempt plus two retries")
+	for i, attempt := range attempts {
+		assert.Equal(t, i+1, attempt.Attempt)
+		assert.Equal(t, []string{"hotfix"}, attempt.Tags, "retries keep the trigger's tags")
+		if i == 0 {
+			assert.Nil(t, attempt.ParentRunID)
+		} else {
+			require.NotNil(t, attempt.ParentRunID)
+			assert.Equal(t, first.ID, *attempt.ParentRunID)
+		}
+	}
+	assert.Equal(t, "failure", attempts[1].Status)
+	assert.Equal(t, "success", attempts[2].Status)
+	assert.Equal(t, "success", attempts[0].Status, "the first run reflects the final outcome")
+
+	collapsed, err := mockStore.ListRuns(store.RunFilter{JobID: job.ID, FirstAttempt: true}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, collapsed, 1)
+	assert.Equal(t, first.ID, collapsed[0].ID)
+}
+
+// TestExecuteWithRetryNonRetryableCodeFailsImmediately tests that an unlisted exit code is not retried
+func TestExecuteWithRetryNonRetryableCodeFailsImmediately(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+
+	exec := New(mockStore.Store)
+
+	job, err := mockStore.CreateJob(&store.Job{
+		Name:             "permanent",
+		Script:           "exit 1",
+		WorkingDir:       t.TempDir(),
+		TimeoutSeconds:   10,
+		RetryCount:       2,
+		RetryOnExitCodes: []int{75},
+	})
+	require.NoError(t, err)
+
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	require.NoError(t, exec.ExecuteWithRetry(context.Background(), run, job))
+
+	runs, err := mockStore.ListRuns(store.RunFilter{JobID: job.ID}, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, runs, 1, "non-retryable exit code must not be retried")
+	assert.Equal(t, "failure", runs[0].Status)
+}
+
+// TestShouldRetry tests the retry eligibility rules
+func TestShouldRetry(t *testing.T) {
+	code := func(c int) *int { return &c }
+
+	tests := []struct {
+		name      string
+		status    string
+		exitCode  *int
+		exitCodes []int
+		expected  bool
+	}{
+		{"any failure retried when list empty", "failure", code(1), nil, true},
+		{"timeout retried when list empty", "timeout", code(124), nil, true},
+		{"success never retried", "success", code(0), nil, false},
+		{"listed code retried", "failure", code(75), []int{75}, true},
+		{"unlisted code not retried", "failure", code(1), []int{75}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &store.Job{RetryOnExitCodes: tt.exitCodes}
+			run := &store.Run{Status: tt.status, ExitCode: tt.exitCode}
+			assert.Equal(t, tt.expected, shouldRetry(job, run))
+		})
+	}
+}
+
+// TestExecuteRecordsCPUSeconds tests that a completed run records its CPU time
+func TestExecuteRecordsCPUSeconds(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+
+	exec := New(mockStore.Store)
+
+	job := &store.Job{
+		ID:             "test-job",
+		Script:         "echo 'hello'",
+		WorkingDir:     t.TempDir(),
+		TimeoutSeconds: 10,
+	}
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	require.NoError(t, exec.Execute(context.Background(), run, job))
+
+	assert.Equal(t, "success", run.Status)
+	require.NotNil(t, run.CPUSeconds)
+	assert.GreaterOrEqual(t, *run.CPUSeconds, 0.0)
+
+	stored, err := mockStore.GetRun(run.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.CPUSeconds)
+}
+
+// TestExecuteRecordsCommandSnapshot tests that a run stores how its script was launched
+func TestExecuteRecordsCommandSnapshot(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+
+	exec := New(mockStore.Store)
+
+	script := "echo 'hello'"
+	job := &store.Job{
+		ID:             "test-job",
+		Script:         script,
+		WorkingDir:     t.TempDir(),
+		TimeoutSeconds: 10,
+		Nice:           5,
+	}
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	require.NoError(t, exec.Execute(context.Background(), run, job))
+
+	stored, err := mockStore.GetRun(run.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.CommandSnapshot)
+	sum := sha256.Sum256([]byte(script))
+	assert.Equal(t, defaultInterpreter, stored.CommandSnapshot.Interpreter)
+	assert.Equal(t, job.WorkingDir, stored.CommandSnapshot.WorkingDir)
+	assert.Equal(t, hex.EncodeToString(sum[:]), stored.CommandSnapshot.ScriptSHA256)
+	assert.Equal(t, len(script), stored.CommandSnapshot.ScriptBytes)
+	assert.False(t, stored.CommandSnapshot.ScriptFile)
+	assert.Equal(t, 10, stored.CommandSnapshot.TimeoutSeconds)
+	assert.Equal(t, 5, stored.CommandSnapshot.Nice)
+}
+
+// TestExecuteCancelledOnShutdown tests that a run interrupted by base-context
+// cancellation is recorded as cancelled instead of being left running
+func TestExecuteCancelledOnShutdown(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+
+	exec := New(mockStore.Store)
+
+	job := &store.Job{
+		ID:             "test-job",
+		Script:         "sleep 5",
+		WorkingDir:     t.TempDir(),
+		TimeoutSeconds: 30,
+	}
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- exec.Execute(ctx, run, job)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Execute did not return after cancellation")
+	}
+
+	stored, err := mockStore.GetRun(run.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "cancelled", stored.Status)
+	assert.NotNil(t, stored.FinishedAt)
+}
+
+// TestAutoDisableAfterConsecutiveFailures tests that a job is disabled after N consecutive
+// failures and that a success in between resets the count
+func TestAutoDisableAfterConsecutiveFailures(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+
+	exec := New(mockStore.Store)
+
+	job, err := mockStore.CreateJob(&store.Job{
+		Name:                     "flaky",
+		WorkingDir:               t.TempDir(),
+		TimeoutSeconds:           10,
+		Enabled:                  true,
+		AutoDisableAfterFailures: 3,
+	})
+	require.NoError(t, err)
+
+	runScript := func(script string) {
+		job.Script = script
+		run, err := mockStore.CreateRun(job.ID, "manual")
+		require.NoError(t, err)
+		require.NoError(t, exec.Execute(context.Background(), run, job))
+	}
+
+	runScript("exit 1")
+	runScript("exit 1")
+	runScript("exit 0")
+
+	stored, err := mockStore.GetJob(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stored.ConsecutiveFailures, "success should reset the failure count")
+	assert.True(t, stored.Enabled)
+
+	runScript("exit 1")
+	runScript("exit 1")
+
+	stored, err = mockStore.GetJob(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.ConsecutiveFailures)
+	assert.True(t, stored.Enabled, "job should stay enabled below the threshold")
+
+	runScript("exit 1")
+
+	stored, err = mockStore.GetJob(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stored.ConsecutiveFailures)
+	assert.False(t, stored.Enabled, "job should be auto-disabled at the threshold")
+	assert.False(t, job.Enabled)
+}
+
+// TestExecuteRecordsOutputPreview tests that a failing run's preview holds the last error line
+func TestExecuteRecordsOutputPreview(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+
+	exec := New(mockStore.Store)
+
+	job, err := mockStore.CreateJob(&store.Job{
+		Name:           "failing",
+		Script:         "echo starting; echo 'warning: low disk' >&2; echo 'fatal: disk full' >&2; exit 1",
+		WorkingDir:     t.TempDir(),
+		TimeoutSeconds: 10,
+	})
+	require.NoError(t, err)
+
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+	require.NoError(t, exec.Execute(context.Background(), run, job))
+
+	runs, err := mockStore.ListRuns(store.RunFilter{JobID: job.ID}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	require.NotNil(t, runs[0].OutputPreview)
+	assert.Equal(t, "fatal: disk full", *runs[0].OutputPreview)
+}
+
+// TestExecuteClampsTimeoutToMax tests that a saved timeout above the configured maximum
+// is clamped at run time without changing the job
+func TestExecuteClampsTimeoutToMax(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+
+	exec := New(mockStore.Store)
+	exec.SetMaxTimeoutSeconds(1)
+
+	job := &store.Job{
+		ID:             "test-job",
+		Script:         "sleep 5",
+		WorkingDir:     t.TempDir(),
+		TimeoutSeconds: 60,
+	}
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	start := time.Now()
+	require.NoError(t, exec.Execute(context.Background(), run, job))
+
+	assert.Equal(t, "timeout", run.Status)
+	assert.Less(t, time.Since(start), 4*time.Second)
+	assert.Equal(t, 60, job.TimeoutSeconds)
+}
+
+// TestExecuteTimeoutWarning tests that a run crossing its warning threshold logs a warning
+// and notifies, while a run finishing before the threshold does neither
+func TestExecuteTimeoutWarning(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+
+	exec := New(mockStore.Store)
+	var warned atomic.Int32
+	exec.SetTimeoutWarningSender(func(job *store.Job, run *store.Run, elapsed time.Duration) {
+		warned.Add(1)
+	})
+
+	warningLogs := func(script string) int {
+		job, err := mockStore.CreateJob(&store.Job{
+			Name:               "slow",
+			Script:             script,
+			WorkingDir:         t.TempDir(),
+			TimeoutSeconds:     4,
+			TimeoutWarnPercent: 25,
+			TimeoutWarnNotify:  true,
+		})
+		require.NoError(t, err)
+
+		run, err := mockStore.CreateRun(job.ID, "manual")
+		require.NoError(t, err)
+		require.NoError(t, exec.Execute(context.Background(), run, job))
+		require.Equal(t, "success", run.Status)
+
+		logs, err := mockStore.GetLogs(run.ID)
+		require.NoError(t, err)
+		count := 0
+		for _, l := range logs {
+			if l.Stream == "system" && strings.Contains(l.Content, "25% of its 4s timeout") {
+				count++
+			}
+		}
+		return count
+	}
+
+	// Past the 1s warning threshold but well under the 4s timeout
+	assert.Equal(t, 1, warningLogs("sleep 1.5"))
+	assert.Equal(t, int32(1), warned.Load())
+
+	assert.Equal(t, 0, warningLogs("echo quick"))
+	assert.Equal(t, int32(1), warned.Load())
+}
+
+// TestExecutePersistsChattyOutputInOrder tests that batched log writes keep every line in order
+func TestExecutePersistsChattyOutputInOrder(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+
+	job, err := mockStore.CreateJob(&store.Job{
+		Name:           "chatty",
+		Script:         "for i in $(seq 1 500); do echo line-$i; done",
+		WorkingDir:     t.TempDir(),
+		TimeoutSeconds: 30,
+	})
+	require.NoError(t, err)
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	exec := New(mockStore.Store)
+	require.NoError(t, exec.Execute(context.Background(), run, job))
+	require.Equal(t, "success", run.Status)
+
+	logs, err := mockStore.GetLogs(run.ID)
+	require.NoError(t, err)
+
+	var stdout []string
+	for _, l := range logs {
+		if l.Stream == "stdout" {
+			stdout = append(stdout, l.Content)
+		}
+	}
+	require.Len(t, stdout, 500)
+	for i, line := range stdout {
+		assert.Equal(t, fmt.Sprintf("line-%d", i+1), line)
+	}
+}
+
+// TestExecuteOutputPatterns tests that success/failure patterns override the exit code
+func TestExecuteOutputPatterns(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+	exec := New(mockStore.Store)
+
+	tests := []struct {
+		name           string
+		script         string
+		successPattern string
+		failurePattern string
+		wantStatus     string
+		wantExitCode   int
+		wantErrorMsg   string
+	}{
+		{
+			name:           "exit 0 but error pattern",
+			script:         "echo 'ERROR: upload rejected'; exit 0",
+			failurePattern: `^ERROR:`,
+			wantStatus:     "failure",
+			wantExitCode:   0,
+			wantErrorMsg:   "Output matched failure pattern: ERROR: upload rejected",
+		},
+		{
+			name:           "exit 1 but ignored",
+			script:         "echo 'nothing to do'; exit 1",
+			successPattern: `nothing to do|synced \d+ files`,
+			wantStatus:     "success",
+			wantExitCode:   1,
+		},
+		{
+			name:           "exit 0 without required success pattern",
+			script:         "echo 'partial run'",
+			successPattern: `^DONE$`,
+			wantStatus:     "failure",
+			wantExitCode:   0,
+			wantErrorMsg:   "Output did not match success pattern",
+		},
+		{
+			name:           "failure pattern wins over success pattern",
+			script:         "echo DONE; echo 'FATAL: disk full' >&2",
+			successPattern: `^DONE$`,
+			failurePattern: `FATAL`,
+			wantStatus:     "failure",
+			wantExitCode:   0,
+			wantErrorMsg:   "Output matched failure pattern: FATAL: disk full",
+		},
+		{
+			name:           "patterns that do not match leave exit code in charge",
+			script:         "echo fine",
+			failurePattern: `ERROR`,
+			wantStatus:     "success",
+			wantExitCode:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, err := mockStore.CreateJob(&store.Job{
+				Name:           tt.name,
+				Script:         tt.script,
+				WorkingDir:     t.TempDir(),
+				TimeoutSeconds: 30,
+				SuccessPattern: tt.successPattern,
+				FailurePattern: tt.failurePattern,
+			})
+			require.NoError(t, err)
+			run, err := mockStore.CreateRun(job.ID, "manual")
+			require.NoError(t, err)
+
+			_ = exec.Execute(context.Background(), run, job)
+
+			assert.Equal(t, tt.wantStatus, run.Status)
+			require.NotNil(t, run.ExitCode)
+			assert.Equal(t, tt.wantExitCode, *run.ExitCode)
+			if tt.wantErrorMsg == "" {
+				assert.Nil(t, run.ErrorMsg)
+			} else {
+				require.NotNil(t, run.ErrorMsg)
+				assert.Equal(t, tt.wantErrorMsg, *run.ErrorMsg)
+			}
+		})
+	}
+}
+
+// TestExecutePostScriptRunsAfterFailure tests that the teardown hook runs after a failing
+// main script without changing the run's status
+func TestExecutePostScriptRunsAfterFailure(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+	exec := New(mockStore.Store)
+
+	dir := t.TempDir()
+	job, err := mockStore.CreateJob(&store.Job{
+		Name:           "hooks",
+		PreScript:      "echo mounted > setup.txt",
+		Script:         "cat setup.txt; exit 3",
+		PostScript:     "echo 'removing lock'; touch teardown.txt; exit 1",
+		WorkingDir:     dir,
+		TimeoutSeconds: 30,
+	})
+	require.NoError(t, err)
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	require.NoError(t, exec.Execute(context.Background(), run, job))
+
+	assert.Equal(t, "failure", run.Status)
+	require.NotNil(t, run.ExitCode)
+	assert.Equal(t, 3, *run.ExitCode, "a failing post-script doesn't change the main result")
+	assert.FileExists(t, filepath.Join(dir, "teardown.txt"))
+
+	logs, err := mockStore.GetLogs(run.ID)
+	require.NoError(t, err)
+	var contents []string
+	for _, entry := range logs {
+		contents = append(contents, entry.Content)
+	}
+	assert.Contains(t, contents, "mounted", "main script saw the pre-script's setup")
+	assert.Contains(t, contents, "[post_script] removing lock")
+	assert.Contains(t, contents, "post_script failed: exit status 1")
+}
+
+// TestExecutePreScriptFailureAbortsRun tests that a failing setup hook fails the run
+// before the main script starts, while the teardown hook still runs
+func TestExecutePreScriptFailureAbortsRun(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+	exec := New(mockStore.Store)
+
+	dir := t.TempDir()
+	job, err := mockStore.CreateJob(&store.Job{
+		Name:           "hooks",
+		PreScript:      "echo 'volume busy' >&2; exit 2",
+		Script:         "touch main.txt",
+		PostScript:     "touch teardown.txt",
+		WorkingDir:     dir,
+		TimeoutSeconds: 30,
+	})
+	require.NoError(t, err)
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	require.NoError(t, exec.Execute(context.Background(), run, job))
+
+	assert.Equal(t, "failure", run.Status)
+	require.NotNil(t, run.ErrorMsg)
+	assert.Equal(t, "Pre-script failed: exit status 2", *run.ErrorMsg)
+	assert.NotNil(t, run.FinishedAt)
+	assert.NoFileExists(t, filepath.Join(dir, "main.txt"))
+	assert.FileExists(t, filepath.Join(dir, "teardown.txt"))
+
+	stored, err := mockStore.GetRun(run.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "failure", stored.Status)
+}
+
+// TestPauseOnFailure tests that a failed scheduled run pauses the job's schedule and a
+// successful manual run resumes it
+func TestPauseOnFailure(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+	exec := New(mockStore.Store)
+
+	job, err := mockStore.CreateJob(&store.Job{
+		Name:           "ingest",
+		WorkingDir:     t.TempDir(),
+		TimeoutSeconds: 10,
+		Enabled:        true,
+		PauseOnFailure: true,
+	})
+	require.NoError(t, err)
+
+	runScript := func(trigger, script string) {
+		job.Script = script
+		run, err := mockStore.CreateRun(job.ID, trigger)
+		require.NoError(t, err)
+		require.NoError(t, exec.Execute(context.Background(), run, job))
+	}
+	paused := func() bool {
+		stored, err := mockStore.GetJob(job.ID)
+		require.NoError(t, err)
+		return stored.SchedulePaused
+	}
+
+	runScript("manual", "exit 1")
+	assert.False(t, paused(), "a failed manual run doesn't pause the schedule")
+
+	runScript("scheduled", "exit 1")
+	assert.True(t, paused(), "a failed scheduled run pauses the schedule")
+
+	runScript("manual", "exit 1")
+	assert.True(t, paused(), "a failed manual run keeps it paused")
+
+	runScript("manual", "exit 0")
+	assert.False(t, paused(), "a successful manual run resumes the schedule")
+
+	// Without the flag, failures never pause
+	job.PauseOnFailure = false
+	require.NoError(t, mockStore.UpdateJob(job))
+	runScript("scheduled", "exit 1")
+	assert.False(t, paused())
+}
+
+// TestExecuteExternalScript tests that a script too large to store inline is kept in
+// external storage and still executes, including when the job comes from a list query
+func TestExecuteExternalScript(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+
+	exec := New(mockStore.Store)
+
+	// ~2MB of no-op comment lines followed by the command that proves the script ran in full
+	script := strings.Repeat("# padding padding padding padding padding padding padding\n", 2_000_000/58) + "echo external-ok\n"
+	require.Greater(t, len(script), internal.InlineScriptMaxSize)
+
+	job, err := mockStore.CreateJob(&store.Job{
+		Name:           "generated",
+		Script:         script,
+		WorkingDir:     t.TempDir(),
+		TimeoutSeconds: 30,
+	})
+	require.NoError(t, err)
+	assert.True(t, job.ScriptExternal)
+
+	stored, err := mockStore.GetJob(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, script, stored.Script)
+
+	jobs, err := mockStore.ListJobs(nil)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Empty(t, jobs[0].Script, "list queries should not load external scripts")
+
+	run, err := mockStore.CreateRun(job.ID, "scheduled")
+	require.NoError(t, err)
+	require.NoError(t, exec.Execute(context.Background(), run, jobs[0]))
+
+	finished, err := mockStore.GetRun(run.ID)
+	require.NoError(t, err)
+	assert.Equal(t, internal.JobStatusSuccess, finished.Status)
+
+	logs, err := mockStore.GetLogs(run.ID)
+	require.NoError(t, err)
+	found := false
+	for _, l := range logs {
+		if l.Content == "external-ok" {
+			found = true
+		}
+	}
+	assert.True(t, found, "script output should be logged")
+}
+
+// TestExecuteFlagsRunWhenLogWritesFail tests that output lost to failing log writes
+// flags the run and leaves one system entry saying the log is incomplete
+func TestExecuteFlagsRunWhenLogWritesFail(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+
+	// Reject every stdout line, as a persistently locked or full logs table would
+	_, err := mockStore.DB().Exec(`CREATE TRIGGER fail_stdout_logs BEFORE INSERT ON logs
+		WHEN NEW.stream = 'stdout' BEGIN SELECT RAISE(ABORT, 'database is locked'); END`)
+	require.NoError(t, err)
+
+	exec := New(mockStore.Store)
+	job, err := mockStore.CreateJob(&store.Job{
+		Name:           "chatty",
+		Script:         "echo one; echo two",
+		WorkingDir:     t.TempDir(),
+		TimeoutSeconds: 10,
+	})
+	require.NoError(t, err)
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	require.NoError(t, exec.Execute(context.Background(), run, job))
+
+	stored, err := mockStore.GetRun(run.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "success", stored.Status, "lost output does not change the run's result")
+	assert.True(t, stored.LogsIncomplete)
+
+	logs, err := mockStore.GetLogs(run.ID)
+	require.NoError(t, err)
+	notes := 0
+	for _, entry := range logs {
+		assert.NotEqual(t, "stdout", entry.Stream)
+		if strings.Contains(entry.Content, "this log is incomplete") {
+			notes++
+			assert.Contains(t, entry.Content, "2 output lines")
+		}
+	}
+	assert.Equal(t, 1, notes, "the loss is noted once")
+}
+
+// TestExecuteKeepsOutputWhileLogWritesStall tests that output is kept in full when the
+// database stalls for longer than OutputWaitDelay after the script has exited
+func TestExecuteKeepsOutputWhileLogWritesStall(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+
+	dir := t.TempDir()
+	started := filepath.Join(dir, "started")
+	exec := New(mockStore.Store)
+	job, err := mockStore.CreateJob(&store.Job{
+		Name:           "stalled",
+		Script:         "touch " + started + "\nwhile [ ! -f go ]; do sleep 0.05; done\nseq 1 2000",
+		WorkingDir:     dir,
+		TimeoutSeconds: 30,
+	})
+	require.NoError(t, err)
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() { done <- exec.Execute(context.Background(), run, job) }()
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(started)
+		return err == nil
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// The test store has a single connection, so holding it stalls every log write
+	tx, err := mockStore.DB().Begin()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go"), nil, 0o644))
+	time.Sleep(internal.OutputWaitDelay + time.Second)
+	require.NoError(t, tx.Rollback())
+	require.NoError(t, <-done)
+
+	stored, err := mockStore.GetRun(run.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "success", stored.Status)
+	assert.False(t, stored.LogsIncomplete)
+
+	logs, err := mockStore.GetLogs(run.ID)
+	require.NoError(t, err)
+	lines := 0
+	for _, entry := range logs {
+		if entry.Stream == "stdout" {
+			lines++
+		}
+		assert.NotContains(t, entry.Content, "Stopped reading output")
+	}
+	assert.Equal(t, 2000, lines)
+}
+
+// TestExecuteBroadcastsStoredLogIDs tests that output lines are broadcast with the ID
+// they were stored under, so live viewers can resume from them
+func TestExecuteBroadcastsStoredLogIDs(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+
+	broadcast := make(map[string]int)
+	exec := New(mockStore.Store)
+	exec.SetLogBroadcaster(func(runID string, id int, stream, content string, timestamp time.Time) {
+		if stream == "stdout" {
+			broadcast[content] = id
+		}
+	})
+	job, err := mockStore.CreateJob(&store.Job{Name: "ids", Script: "echo one; echo two", WorkingDir: t.TempDir(), TimeoutSeconds: 10})
+	require.NoError(t, err)
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	require.NoError(t, exec.Execute(context.Background(), run, job))
+
+	logs, err := mockStore.GetLogs(run.ID)
+	require.NoError(t, err)
+	stored := make(map[string]int)
+	for _, entry := range logs {
+		if entry.Stream == "stdout" {
+			stored[entry.Content] = entry.ID
+		}
+	}
+	assert.Len(t, stored, 2)
+	assert.Equal(t, stored, broadcast)
+}
+
+// TestExecuteLogsCompleteByDefault tests that runs whose output is stored are not flagged
+func TestExecuteLogsCompleteByDefault(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+
+	exec := New(mockStore.Store)
+	job, err := mockStore.CreateJob(&store.Job{Name: "quiet", Script: "echo ok", WorkingDir: t.TempDir(), TimeoutSeconds: 10})
+	require.NoError(t, err)
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	require.NoError(t, exec.Execute(context.Background(), run, job))
+
+	stored, err := mockStore.GetRun(run.ID)
+	require.NoError(t, err)
+	assert.False(t, stored.LogsIncomplete)
+	assert.False(t, stored.LogTruncated)
+	assert.Equal(t, int64(3), stored.LogBytesTotal)
+}
+
+// TestExecuteTagsLogLevels tests that output lines matching the job's log level pattern
+// are stored with their normalized level and other lines with none
+func TestExecuteTagsLogLevels(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+
+	exec := New(mockStore.Store)
+	job, err := mockStore.CreateJob(&store.Job{
+		Name:            "noisy",
+		Script:          "echo '[INFO] starting'; echo 'plain line'; echo '[WARNING] slow disk'; echo '[ERROR] failed to connect' >&2",
+		WorkingDir:      t.TempDir(),
+		TimeoutSeconds:  10,
+		LogLevelPattern: `^\[(\w+)\]`,
+	})
+	require.NoError(t, err)
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	require.NoError(t, exec.Execute(context.Background(), run, job))
+
+	logs, err := mockStore.GetLogs(run.ID)
+	require.NoError(t, err)
+	levels := make(map[string]string)
+	for _, entry := range logs {
+		if entry.Stream != internal.StreamSystem {
+			levels[entry.Content] = entry.Level
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"[INFO] starting":           "info",
+		"plain line":                "",
+		"[WARNING] slow disk":       "warn",
+		"[ERROR] failed to connect": "error",
+	}, levels)
+}
+
+// TestExecuteTruncatesOutputAtLogCap tests that output past the cap is not stored and
+// that the run reports the truncation and how many bytes the script wrote
+func TestExecuteTruncatesOutputAtLogCap(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+
+	exec := New(mockStore.Store)
+	exec.SetMaxLogBytes(20)
+	// 10 lines of "line N" plus newline: 7 bytes each, 70 in total
+	job, err := mockStore.CreateJob(&store.Job{
+		Name:           "loud",
+		Script:         "for i in 0 1 2 3 4 5 6 7 8 9; do echo line $i; done",
+		WorkingDir:     t.TempDir(),
+		TimeoutSeconds: 10,
+	})
+	require.NoError(t, err)
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	require.NoError(t, exec.Execute(context.Background(), run, job))
+
+	stored, err := mockStore.GetRun(run.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "success", stored.Status)
+	assert.True(t, stored.LogTruncated)
+	assert.Equal(t, int64(70), stored.LogBytesTotal)
+
+	logs, err := mockStore.GetLogs(run.ID)
+	require.NoError(t, err)
+	var stdout []string
+	notes := 0
+	for _, entry := range logs {
+		if entry.Stream == "stdout" {
+			stdout = append(stdout, entry.Content)
+		}
+		if strings.Contains(entry.Content, "Output truncated at 20 bytes (the script wrote 70 bytes)") {
+			notes++
+		}
+	}
+	assert.Equal(t, []string{"line 0", "line 1"}, stdout)
+	assert.Equal(t, 1, notes)
+}
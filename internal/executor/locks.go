@@ -0,0 +1,81 @@
+package executor
+
+import (
+	"context"
+	"sync"
+)
+
+// ResourceLocks is an in-memory set of named locks. Jobs that declare the same
+// resource_lock are serialized, while jobs with different (or no) locks are not.
+type ResourceLocks struct {
+	mu       sync.Mutex
+	holders  map[string]string        // lock name -> holder (run ID)
+	released map[string]chan struct{} // lock name -> closed when the lock is released
+}
+
+// NewResourceLocks creates an empty lock set
+func NewResourceLocks() *ResourceLocks {
+	return &ResourceLocks{
+		holders:  make(map[string]string),
+		released: make(map[string]chan struct{}),
+	}
+}
+
+// Acquire blocks until the named lock is free, then records holder as its owner.
+// Returns ctx.Err() if the context is done before the lock is obtained.
+func (rl *ResourceLocks) Acquire(ctx context.Context, name, holder string) error {
+	for {
+		rl.mu.Lock()
+		if _, held := rl.holders[name]; !held {
+			rl.holders[name] = holder
+			rl.mu.Unlock()
+			return nil
+		}
+		ch, ok := rl.released[name]
+		if !ok {
+			ch = make(chan struct{})
+			rl.released[name] = ch
+		}
+		rl.mu.Unlock()
+
+		select {
+		case <-ch:
+			// Lock released, try again
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Release frees the named lock and wakes any waiters
+func (rl *ResourceLocks) Release(name string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.release(name)
+}
+
+// ReleaseHolder frees the named lock only if holder still owns it, so a run finished
+// late, e.g. one reaped after a restart, cannot free a lock another run took since
+func (rl *ResourceLocks) ReleaseHolder(name, holder string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if rl.holders[name] == holder {
+		rl.release(name)
+	}
+}
+
+// release frees the named lock; rl.mu must be held
+func (rl *ResourceLocks) release(name string) {
+	delete(rl.holders, name)
+	if ch, ok := rl.released[name]; ok {
+		close(ch)
+		delete(rl.released, name)
+	}
+}
+
+// Holder returns the current holder of the named lock, or "" if it is free
+func (rl *ResourceLocks) Holder(name string) string {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.holders[name]
+}
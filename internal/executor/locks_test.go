@@ -0,0 +1,110 @@
+package executor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	internal "github.com/taskflow/taskflow/internal"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// TestResourceLocksSerializeSharedLock tests that two holders of the same lock never overlap
+func TestResourceLocksSerializeSharedLock(t *testing.T) {
+	locks := NewResourceLocks()
+
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+
+	var wg sync.WaitGroup
+	for _, holder := range []string{"run-a", "run-b"} {
+		wg.Add(1)
+		go func(holder string) {
+			defer wg.Done()
+			require.NoError(t, locks.Acquire(context.Background(), "db-migration", holder))
+			defer locks.Release("db-migration")
+
+			mu.Lock()
+			active++
+			if active > maxActive {
+				maxActive = active
+			}
+			mu.Unlock()
+
+			time.Sleep(50 * time.Millisecond)
+
+			mu.Lock()
+			active--
+			mu.Unlock()
+		}(holder)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, maxActive, "jobs sharing a lock must run one at a time")
+	assert.Empty(t, locks.Holder("db-migration"))
+}
+
+// TestResourceLocksDifferentLocksConcurrent tests that distinct locks do not block each other
+func TestResourceLocksDifferentLocksConcurrent(t *testing.T) {
+	locks := NewResourceLocks()
+
+	require.NoError(t, locks.Acquire(context.Background(), "lock-a", "run-a"))
+	defer locks.Release("lock-a")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// A different lock is immediately available while lock-a is held
+	require.NoError(t, locks.Acquire(ctx, "lock-b", "run-b"))
+	assert.Equal(t, "run-a", locks.Holder("lock-a"))
+	assert.Equal(t, "run-b", locks.Holder("lock-b"))
+	locks.Release("lock-b")
+}
+
+// TestResourceLocksAcquireContextCancelled tests that waiting for a held lock honours the context
+func TestResourceLocksAcquireContextCancelled(t *testing.T) {
+	locks := NewResourceLocks()
+
+	require.NoError(t, locks.Acquire(context.Background(), "shared", "run-a"))
+	defer locks.Release("shared")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := locks.Acquire(ctx, "shared", "run-b")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, "run-a", locks.Holder("shared"))
+}
+
+// TestExecuteLockWaitFailureFinishesRun tests that a run giving up on a held resource lock
+// is recorded as finished and its final status is broadcast
+func TestExecuteLockWaitFailureFinishesRun(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	exec := New(mockStore.Store)
+	var statuses []string
+	exec.SetStatusBroadcaster(func(runID, status string, job *store.Job) {
+		statuses = append(statuses, status)
+	})
+
+	job, err := mockStore.CreateJob(&store.Job{Name: "locked", Script: "echo hi", TimeoutSeconds: 10, ResourceLock: "db"})
+	require.NoError(t, err)
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	require.NoError(t, exec.locks.Acquire(context.Background(), "db", "other-run"))
+	defer exec.locks.Release("db")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.Error(t, exec.Execute(ctx, run, job))
+
+	saved, err := mockStore.GetRun(run.ID)
+	require.NoError(t, err)
+	assert.Equal(t, internal.JobStatusFailure, saved.Status)
+	assert.NotNil(t, saved.FinishedAt)
+	assert.NotNil(t, saved.DurationMs)
+	assert.Equal(t, []string{internal.JobStatusFailure}, statuses)
+}
@@ -0,0 +1,46 @@
+package executor
+
+import (
+	"log"
+	"regexp"
+
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// levelDetector parses the severity of output lines with a job's log_level_pattern. The
+// level is the pattern's group named "level", or its first group.
+type levelDetector struct {
+	pattern *regexp.Regexp
+	group   int
+}
+
+// newLevelDetector returns a detector for the job's pattern, or nil if it has none.
+// Patterns are validated when the job is saved; one that no longer compiles is ignored.
+func newLevelDetector(job *store.Job) *levelDetector {
+	if job.LogLevelPattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(job.LogLevelPattern)
+	if err != nil || re.NumSubexp() == 0 {
+		log.Printf("Ignoring invalid log level pattern for job %s: %v\n", job.ID, err)
+		return nil
+	}
+	group := 1
+	if named := re.SubexpIndex("level"); named > 0 {
+		group = named
+	}
+	return &levelDetector{pattern: re, group: group}
+}
+
+// detect returns line's normalized level, or "" if the line doesn't match. A nil
+// detector detects nothing.
+func (d *levelDetector) detect(line string) string {
+	if d == nil {
+		return ""
+	}
+	match := d.pattern.FindStringSubmatch(line)
+	if match == nil {
+		return ""
+	}
+	return store.NormalizeLogLevel(match[d.group])
+}
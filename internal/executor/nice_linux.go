@@ -0,0 +1,10 @@
+//go:build linux
+
+package executor
+
+import "syscall"
+
+// setNice sets the scheduling niceness of a running process
+func setNice(pid, nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, pid, nice)
+}
@@ -0,0 +1,46 @@
+//go:build linux
+
+package executor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// TestExecuteAppliesNice tests that the spawned process runs with the job's nice value
+func TestExecuteAppliesNice(t *testing.T) {
+	mockStore := newMockStoreForTesting(t)
+	defer mockStore.Close()
+
+	exec := New(mockStore.Store)
+	dir := t.TempDir()
+
+	// Field 19 of /proc/self/stat is the nice value; sleep first so the renice lands
+	job, err := mockStore.CreateJob(&store.Job{
+		Name:           "niced",
+		Script:         "sleep 0.2; cut -d' ' -f19 /proc/$$/stat > nice.txt",
+		WorkingDir:     dir,
+		TimeoutSeconds: 10,
+		Nice:           10,
+	})
+	require.NoError(t, err)
+
+	run, err := mockStore.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+	require.NoError(t, exec.Execute(context.Background(), run, job))
+	require.Equal(t, "success", run.Status)
+
+	out, err := os.ReadFile(filepath.Join(dir, "nice.txt"))
+	require.NoError(t, err)
+	nice, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	require.NoError(t, err)
+	assert.Equal(t, 10, nice)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package executor
+
+import "errors"
+
+// setNice is not supported outside Linux; the job runs at normal priority
+func setNice(pid, nice int) error {
+	return errors.New("nice is only supported on Linux")
+}
@@ -0,0 +1,326 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	internal "github.com/taskflow/taskflow/internal"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// ChannelSettingsProvider abstracts notification channel settings retrieval for testability
+type ChannelSettingsProvider interface {
+	GetChannelSettings() (*store.ChannelSettings, error)
+}
+
+// Channel delivers job run notifications to one external service. A message is rendered
+// once and posted separately, so the outbox can retry the same message later.
+type Channel interface {
+	// Render builds the message for the run's outcome; each channel decides which outcomes
+	// are worth sending and returns a nil message for the rest
+	Render(job *store.Job, run *store.Run) ([]byte, error)
+	// Post delivers a message built by Render
+	Post(message []byte) error
+}
+
+// channelFactory builds a channel from the current settings, returning nil when the
+// channel is not configured
+type channelFactory func(n *Notifier, settings *store.ChannelSettings) Channel
+
+// channelFactories maps each notify_channels name to its backend
+var channelFactories = map[string]channelFactory{
+	internal.NotifyChannelTeams: func(n *Notifier, settings *store.ChannelSettings) Channel {
+		if settings.TeamsWebhookURL == "" {
+			return nil
+		}
+		return &teamsChannel{client: n.httpClient, webhookURL: settings.TeamsWebhookURL}
+	},
+	internal.NotifyChannelPagerDuty: func(n *Notifier, settings *store.ChannelSettings) Channel {
+		if settings.PagerDutyRoutingKey == "" {
+			return nil
+		}
+		return &pagerDutyChannel{client: n.httpClient, eventsURL: n.pagerDutyURL, routingKey: settings.PagerDutyRoutingKey}
+	},
+}
+
+// IsKnownChannel reports whether name is a supported notification channel
+func IsKnownChannel(name string) bool {
+	_, ok := channelFactories[name]
+	return ok
+}
+
+// SetChannels enables chat and incident channels; their settings are read from provider on every send
+func (n *Notifier) SetChannels(provider ChannelSettingsProvider) {
+	n.channels = provider
+}
+
+// sendToChannels notifies every channel the job uses: its notify_channels, or the global
+// default channels when it has none, via the outbox when set. One failing channel does not
+// stop the others.
+func (n *Notifier) sendToChannels(job *store.Job, run *store.Run) error {
+	if n.channels == nil {
+		return nil
+	}
+
+	settings, err := n.channels.GetChannelSettings()
+	if err != nil {
+		return fmt.Errorf("failed to get notification channel settings: %w", err)
+	}
+
+	names := job.NotifyChannels
+	if len(names) == 0 {
+		names = settings.DefaultChannels
+	}
+
+	var errs []error
+	for _, name := range names {
+		factory, ok := channelFactories[name]
+		if !ok {
+			log.Printf("Unknown notification channel %q for job %s, skipping", name, job.ID)
+			continue
+		}
+		channel := factory(n, settings)
+		if channel == nil {
+			log.Printf("Notification channel %s not configured, skipping for job %s", name, job.ID)
+			continue
+		}
+		if err := n.notifyChannel(name, channel, job, run); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// notifyChannel renders the run for one channel and posts it, or queues it in the outbox
+func (n *Notifier) notifyChannel(name string, channel Channel, job *store.Job, run *store.Run) error {
+	message, err := channel.Render(job, run)
+	if err != nil || message == nil {
+		return err
+	}
+
+	if n.outbox != nil {
+		return n.enqueue(&store.OutboxNotification{
+			JobID:   job.ID,
+			RunID:   run.ID,
+			Channel: name,
+			Subject: fmt.Sprintf("%s notification for run %s (status=%s)", name, run.ID, run.Status),
+			Body:    string(message),
+		})
+	}
+
+	if err := channel.Post(message); err != nil {
+		return err
+	}
+	log.Printf("%s notification sent for job %s (status=%s)", name, job.ID, run.Status)
+	return nil
+}
+
+// postToChannel posts an outbox message using the channel's current settings
+func (n *Notifier) postToChannel(name string, message []byte) error {
+	if n.channels == nil {
+		return fmt.Errorf("notification channels are not enabled")
+	}
+	settings, err := n.channels.GetChannelSettings()
+	if err != nil {
+		return fmt.Errorf("failed to get notification channel settings: %w", err)
+	}
+
+	factory, ok := channelFactories[name]
+	if !ok {
+		return fmt.Errorf("unknown notification channel %q", name)
+	}
+	channel := factory(n, settings)
+	if channel == nil {
+		return fmt.Errorf("notification channel %s is not configured", name)
+	}
+	return channel.Post(message)
+}
+
+// postJSON sends an encoded JSON body and treats any non-2xx response as a failure
+func postJSON(client *http.Client, url string, body []byte) error {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// isFailedStatus reports whether a run status counts as a failure for alerting
+func isFailedStatus(status string) bool {
+	return status == internal.JobStatusFailure || status == internal.JobStatusTimeout
+}
+
+// teamsChannel posts a message card to a Microsoft Teams incoming webhook
+type teamsChannel struct {
+	client     *http.Client
+	webhookURL string
+}
+
+type teamsCard struct {
+	Type       string         `json:"@type"`
+	Context    string         `json:"@context"`
+	ThemeColor string         `json:"themeColor"`
+	Summary    string         `json:"summary"`
+	Title      string         `json:"title"`
+	Sections   []teamsSection `json:"sections"`
+}
+
+type teamsSection struct {
+	ActivityTitle string      `json:"activityTitle"`
+	Facts         []teamsFact `json:"facts"`
+	Text          string      `json:"text,omitempty"`
+}
+
+type teamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Render builds the card when the job's notify_on and notify_exit_codes match, like email
+func (c *teamsChannel) Render(job *store.Job, run *store.Run) ([]byte, error) {
+	if !shouldNotify(job.NotifyOn, run.Status, run.ExitCode, job.NotifyExitCodes) {
+		return nil, nil
+	}
+	return json.Marshal(buildTeamsCard(job, run))
+}
+
+// Post sends the card to the webhook
+func (c *teamsChannel) Post(message []byte) error {
+	return postJSON(c.client, c.webhookURL, message)
+}
+
+// buildTeamsCard renders a run as a Teams message card
+func buildTeamsCard(job *store.Job, run *store.Run) teamsCard {
+	title := fmt.Sprintf("Job %s: %s", strings.ToUpper(run.Status), job.Name)
+
+	color := "808080"
+	if run.Status == internal.JobStatusSuccess {
+		color = "2EB886"
+	} else if isFailedStatus(run.Status) {
+		color = "D00000"
+	}
+
+	section := teamsSection{
+		ActivityTitle: job.Name,
+		Facts: []teamsFact{
+			{Name: "Status", Value: run.Status},
+			{Name: "Run ID", Value: run.ID},
+			{Name: "Trigger", Value: run.TriggerType},
+			{Name: "Duration", Value: formatDuration(run.DurationMs)},
+			{Name: "Exit Code", Value: formatExitCode(run.ExitCode)},
+			{Name: "Finished", Value: formatTime(run.FinishedAt)},
+		},
+	}
+	if run.ErrorMsg != nil && *run.ErrorMsg != "" {
+		section.Text = *run.ErrorMsg
+	}
+
+	return teamsCard{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		ThemeColor: color,
+		Summary:    title,
+		Title:      title,
+		Sections:   []teamsSection{section},
+	}
+}
+
+// pagerDutyChannel sends PagerDuty Events API v2 events. A failed run triggers an incident
+// keyed by the job, and the job's next successful run resolves it. notify_on does not
+// apply, since skipping the success would leave the incident open.
+type pagerDutyChannel struct {
+	client     *http.Client
+	eventsURL  string
+	routingKey string
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+// pagerDutyDedupKey ties every event for a job to the same incident
+func pagerDutyDedupKey(jobID string) string {
+	return "taskflow-job-" + jobID
+}
+
+// Render builds a trigger on failure or timeout and a resolve on success; other outcomes
+// send nothing. The routing key is left out so a queued event is never stored with it.
+func (c *pagerDutyChannel) Render(job *store.Job, run *store.Run) ([]byte, error) {
+	var event pagerDutyEvent
+	switch {
+	case isFailedStatus(run.Status):
+		event = buildPagerDutyTrigger(job, run)
+	case run.Status == internal.JobStatusSuccess:
+		event = pagerDutyEvent{EventAction: "resolve", DedupKey: pagerDutyDedupKey(job.ID)}
+	default:
+		return nil, nil
+	}
+	return json.Marshal(event)
+}
+
+// Post adds the current routing key to the event and sends it
+func (c *pagerDutyChannel) Post(message []byte) error {
+	var event pagerDutyEvent
+	if err := json.Unmarshal(message, &event); err != nil {
+		return fmt.Errorf("invalid PagerDuty event: %w", err)
+	}
+	event.RoutingKey = c.routingKey
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return postJSON(c.client, c.eventsURL, body)
+}
+
+// buildPagerDutyTrigger renders a failed run as a trigger event
+func buildPagerDutyTrigger(job *store.Job, run *store.Run) pagerDutyEvent {
+	payload := &pagerDutyPayload{
+		Summary:  fmt.Sprintf("TaskFlow job %s: %s", job.Name, strings.ToUpper(run.Status)),
+		Source:   "taskflow",
+		Severity: "error",
+		CustomDetails: map[string]string{
+			"job_id":    job.ID,
+			"run_id":    run.ID,
+			"trigger":   run.TriggerType,
+			"exit_code": formatExitCode(run.ExitCode),
+			"duration":  formatDuration(run.DurationMs),
+		},
+	}
+	if run.FinishedAt != nil {
+		payload.Timestamp = run.FinishedAt.UTC().Format(time.RFC3339)
+	}
+	if run.ErrorMsg != nil && *run.ErrorMsg != "" {
+		payload.CustomDetails["error"] = *run.ErrorMsg
+	}
+
+	return pagerDutyEvent{
+		EventAction: "trigger",
+		DedupKey:    pagerDutyDedupKey(job.ID),
+		Payload:     payload,
+	}
+}
@@ -0,0 +1,170 @@
+package notification
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	internal "github.com/taskflow/taskflow/internal"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// mockChannelSettings implements ChannelSettingsProvider for testing
+type mockChannelSettings struct {
+	settings *store.ChannelSettings
+}
+
+func (m *mockChannelSettings) GetChannelSettings() (*store.ChannelSettings, error) {
+	return m.settings, nil
+}
+
+// recordingServer collects the JSON bodies posted to it
+type recordingServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	bodies []map[string]interface{}
+}
+
+func newRecordingServer(t *testing.T, status int) *recordingServer {
+	rs := &recordingServer{}
+	rs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid JSON body: %v", err)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		rs.mu.Lock()
+		rs.bodies = append(rs.bodies, body)
+		rs.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(rs.Close)
+	return rs
+}
+
+func (rs *recordingServer) received() []map[string]interface{} {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return append([]map[string]interface{}(nil), rs.bodies...)
+}
+
+func newChannelNotifier(settings *store.ChannelSettings) *Notifier {
+	n := New(&mockSettingsProvider{settings: &store.SMTPSettings{}})
+	n.SetChannels(&mockChannelSettings{settings: settings})
+	return n
+}
+
+func TestTeamsChannelPostsMessageCard(t *testing.T) {
+	teams := newRecordingServer(t, http.StatusOK)
+	n := newChannelNotifier(&store.ChannelSettings{
+		TeamsWebhookURL: teams.URL,
+		DefaultChannels: []string{internal.NotifyChannelTeams},
+	})
+
+	exitCode := 2
+	errMsg := "exit status 2"
+	job := &store.Job{ID: "job-1", Name: "nightly-backup", NotifyOn: internal.NotifyFailure}
+	run := &store.Run{ID: "run-1", Status: internal.JobStatusFailure, TriggerType: "scheduled", ExitCode: &exitCode, ErrorMsg: &errMsg}
+	if err := n.SendJobNotification(job, run); err != nil {
+		t.Fatalf("SendJobNotification() error = %v", err)
+	}
+
+	bodies := teams.received()
+	if len(bodies) != 1 {
+		t.Fatalf("Teams received %d posts, want 1", len(bodies))
+	}
+	card := bodies[0]
+	if card["@type"] != "MessageCard" || card["themeColor"] != "D00000" {
+		t.Errorf("unexpected card header: %v", card)
+	}
+	if card["title"] != "Job FAILURE: nightly-backup" {
+		t.Errorf("title = %v", card["title"])
+	}
+	section := card["sections"].([]interface{})[0].(map[string]interface{})
+	if section["text"] != errMsg {
+		t.Errorf("section text = %v, want %q", section["text"], errMsg)
+	}
+	facts := map[string]interface{}{}
+	for _, f := range section["facts"].([]interface{}) {
+		fact := f.(map[string]interface{})
+		facts[fact["name"].(string)] = fact["value"]
+	}
+	if facts["Run ID"] != "run-1" || facts["Exit Code"] != "2" {
+		t.Errorf("unexpected facts: %v", facts)
+	}
+
+	// notify_on=failure skips successful runs, as for email
+	run.Status = internal.JobStatusSuccess
+	if err := n.SendJobNotification(job, run); err != nil {
+		t.Fatalf("SendJobNotification() error = %v", err)
+	}
+	if got := len(teams.received()); got != 1 {
+		t.Errorf("Teams received %d posts after a success, want 1", got)
+	}
+}
+
+func TestPagerDutyChannelTriggersAndResolves(t *testing.T) {
+	pd := newRecordingServer(t, http.StatusAccepted)
+	n := newChannelNotifier(&store.ChannelSettings{PagerDutyRoutingKey: "routing-key"})
+	n.pagerDutyURL = pd.URL
+
+	job := &store.Job{
+		ID:             "job-42",
+		Name:           "billing-sync",
+		NotifyOn:       internal.NotifyFailure,
+		NotifyChannels: []string{internal.NotifyChannelPagerDuty},
+	}
+	for _, status := range []string{internal.JobStatusFailure, internal.JobStatusCancelled, internal.JobStatusSuccess} {
+		run := &store.Run{ID: "run-" + status, Status: status, TriggerType: "scheduled"}
+		if err := n.SendJobNotification(job, run); err != nil {
+			t.Fatalf("SendJobNotification(%s) error = %v", status, err)
+		}
+	}
+
+	events := pd.received()
+	if len(events) != 2 {
+		t.Fatalf("PagerDuty received %d events, want trigger and resolve", len(events))
+	}
+
+	trigger, resolve := events[0], events[1]
+	if trigger["event_action"] != "trigger" || trigger["routing_key"] != "routing-key" {
+		t.Errorf("unexpected trigger event: %v", trigger)
+	}
+	payload := trigger["payload"].(map[string]interface{})
+	if payload["severity"] != "error" || payload["source"] != "taskflow" {
+		t.Errorf("unexpected trigger payload: %v", payload)
+	}
+	if details := payload["custom_details"].(map[string]interface{}); details["run_id"] != "run-failure" {
+		t.Errorf("custom_details = %v", details)
+	}
+
+	// The resolve is sent despite notify_on=failure and closes the same incident
+	if resolve["event_action"] != "resolve" {
+		t.Errorf("second event action = %v, want resolve", resolve["event_action"])
+	}
+	if _, ok := resolve["payload"]; ok {
+		t.Errorf("resolve event should carry no payload: %v", resolve)
+	}
+	if trigger["dedup_key"] != "taskflow-job-job-42" || resolve["dedup_key"] != trigger["dedup_key"] {
+		t.Errorf("dedup keys = %v / %v, want taskflow-job-job-42 for both", trigger["dedup_key"], resolve["dedup_key"])
+	}
+}
+
+func TestChannelFailureIsReported(t *testing.T) {
+	teams := newRecordingServer(t, http.StatusBadRequest)
+	n := newChannelNotifier(&store.ChannelSettings{
+		TeamsWebhookURL: teams.URL,
+		DefaultChannels: []string{internal.NotifyChannelTeams, internal.NotifyChannelPagerDuty},
+	})
+
+	job := &store.Job{ID: "job-1", Name: "etl", NotifyOn: internal.NotifyAlways}
+	run := &store.Run{ID: "run-1", Status: internal.JobStatusSuccess}
+	// PagerDuty has no routing key, so only the failing Teams post produces an error
+	if err := n.SendJobNotification(job, run); err == nil {
+		t.Error("SendJobNotification() should report the rejected Teams post")
+	}
+}
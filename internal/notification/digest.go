@@ -0,0 +1,159 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	internal "github.com/taskflow/taskflow/internal"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// DigestStore provides the analytics a digest summarizes
+type DigestStore interface {
+	GetOverallStats(since *time.Time) (map[string]interface{}, error)
+	GetJobStatsSince(since time.Time) ([]*store.JobStats, error)
+}
+
+// NextDigestTime returns the first send time strictly after now for a daily or weekly
+// digest sent at hour (local time). Weekly digests go out on Mondays.
+func NextDigestTime(now time.Time, frequency string, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if frequency == internal.DigestWeekly {
+		next = next.AddDate(0, 0, (int(time.Monday)-int(next.Weekday())+7)%7)
+	}
+	for !next.After(now) {
+		if frequency == internal.DigestWeekly {
+			next = next.AddDate(0, 0, 7)
+		} else {
+			next = next.AddDate(0, 0, 1)
+		}
+	}
+	return next
+}
+
+// digestPeriod is how far back a digest of the given frequency looks
+func digestPeriod(frequency string) time.Duration {
+	if frequency == internal.DigestWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// BuildDigest renders the activity summary for runs created since the start of the
+// period: totals from GetOverallStats and the jobs with the highest failure rate.
+func BuildDigest(st DigestStore, frequency string, now time.Time) (subject, body string, err error) {
+	since := now.Add(-digestPeriod(frequency))
+
+	overall, err := st.GetOverallStats(&since)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get overall stats: %w", err)
+	}
+	jobStats, err := st.GetJobStatsSince(since)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get job stats: %w", err)
+	}
+
+	total, _ := overall["total_runs"].(int)
+	successes, _ := overall["success_count"].(int)
+	failures, _ := overall["failure_count"].(int)
+	successRate := notAvailable
+	if total > 0 {
+		successRate = fmt.Sprintf("%.1f%%", float64(successes)/float64(total)*100)
+	}
+
+	worst := make([]*store.JobStats, 0, len(jobStats))
+	for _, js := range jobStats {
+		if js.FailureCount > 0 {
+			worst = append(worst, js)
+		}
+	}
+	sort.SliceStable(worst, func(i, j int) bool {
+		if worst[i].SuccessRate != worst[j].SuccessRate {
+			return worst[i].SuccessRate < worst[j].SuccessRate
+		}
+		return worst[i].FailureCount > worst[j].FailureCount
+	})
+	if len(worst) > internal.DigestWorstJobs {
+		worst = worst[:internal.DigestWorstJobs]
+	}
+
+	title := "Daily"
+	if frequency == internal.DigestWeekly {
+		title = "Weekly"
+	}
+	subject = fmt.Sprintf("%s %s Digest: %d runs, %d failed", emailSubjectPrefix, title, total, failures)
+
+	var b strings.Builder
+	heading := fmt.Sprintf("TaskFlow %s Digest", title)
+	fmt.Fprintf(&b, "%s\n%s\n\n", heading, strings.Repeat("=", len(heading)))
+	fmt.Fprintf(&b, "Period:       %s to %s\n\n", since.Format("2006-01-02 15:04 MST"), now.Format("2006-01-02 15:04 MST"))
+	fmt.Fprintf(&b, "Total runs:   %d\n", total)
+	fmt.Fprintf(&b, "Succeeded:    %d\n", successes)
+	fmt.Fprintf(&b, "Failed:       %d\n", failures)
+	fmt.Fprintf(&b, "Success rate: %s\n\n", successRate)
+
+	b.WriteString("Jobs with the most failures\n")
+	b.WriteString("---------------------------\n")
+	if len(worst) == 0 {
+		b.WriteString("No failed runs in this period.\n")
+	}
+	for _, js := range worst {
+		fmt.Fprintf(&b, "- %s: %d of %d runs failed (%.1f%% failure rate)\n",
+			js.JobName, js.FailureCount, js.TotalRuns, (1-js.SuccessRate)*100)
+	}
+
+	b.WriteString("\n---\nThis is an automated digest from TaskFlow.\n")
+	return subject, b.String(), nil
+}
+
+// SendDigest builds the digest for the period ending at now and emails it to recipients.
+// It is skipped, not failed, when SMTP is not configured.
+func (n *Notifier) SendDigest(st DigestStore, recipients []string, frequency string, now time.Time) error {
+	settings, err := n.settingsProvider.GetSMTPSettings()
+	if err != nil {
+		return fmt.Errorf("failed to get SMTP settings: %w", err)
+	}
+	if !isConfigured(settings) {
+		log.Printf("SMTP not configured, skipping %s digest", frequency)
+		return nil
+	}
+
+	subject, body, err := BuildDigest(st, frequency, now)
+	if err != nil {
+		return err
+	}
+	if err := n.send(settings, recipients, subject, body); err != nil {
+		return err
+	}
+
+	log.Printf("%s digest sent to %v", frequency, recipients)
+	return nil
+}
+
+// RunDigest sends the digest to the comma-separated recipients at each scheduled time
+// until ctx is done
+func (n *Notifier) RunDigest(ctx context.Context, st DigestStore, recipients, frequency string, hour int) {
+	to := parseEmails(recipients)
+	if len(to) == 0 {
+		log.Printf("Digest disabled: no recipients configured")
+		return
+	}
+
+	for {
+		next := NextDigestTime(time.Now(), frequency, hour)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+			if err := n.SendDigest(st, to, frequency, next); err != nil {
+				log.Printf("Failed to send %s digest: %v", frequency, err)
+			}
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package notification
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	internal "github.com/taskflow/taskflow/internal"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+func TestBuildDigest(t *testing.T) {
+	st := store.NewTestStore(t)
+	defer st.Close()
+
+	seed := map[string][]string{
+		"nightly-backup": {"success", "success", "success", "failure"},
+		"report-export":  {"success", "success"},
+		"cache-warmup":   {"success", "timeout"},
+	}
+	for name, statuses := range seed {
+		job, err := st.CreateJob(&store.Job{Name: name, Script: "true", Enabled: true})
+		if err != nil {
+			t.Fatalf("CreateJob: %v", err)
+		}
+		for _, status := range statuses {
+			run, err := st.CreateRun(job.ID, "scheduled")
+			if err != nil {
+				t.Fatalf("CreateRun: %v", err)
+			}
+			run.Status = status
+			if err := st.UpdateRun(run); err != nil {
+				t.Fatalf("UpdateRun: %v", err)
+			}
+		}
+	}
+
+	subject, body, err := BuildDigest(st, internal.DigestDaily, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("BuildDigest: %v", err)
+	}
+
+	if want := "[TaskFlow] Daily Digest: 8 runs, 2 failed"; subject != want {
+		t.Errorf("subject = %q, want %q", subject, want)
+	}
+	for _, want := range []string{
+		"Total runs:   8\n",
+		"Succeeded:    6\n",
+		"Failed:       2\n",
+		"Success rate: 75.0%\n",
+		"- cache-warmup: 1 of 2 runs failed (50.0% failure rate)\n",
+		"- nightly-backup: 1 of 4 runs failed (25.0% failure rate)\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "report-export") {
+		t.Errorf("job without failures should not be listed\n%s", body)
+	}
+	if strings.Index(body, "cache-warmup") > strings.Index(body, "nightly-backup") {
+		t.Errorf("jobs should be ordered by failure rate\n%s", body)
+	}
+}
+
+func TestNextDigestTime(t *testing.T) {
+	// Wednesday 2024-05-15
+	wed := func(hour, min int) time.Time { return time.Date(2024, 5, 15, hour, min, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		frequency string
+		want      time.Time
+	}{
+		{"daily before hour", wed(7, 30), internal.DigestDaily, wed(8, 0)},
+		{"daily at hour", wed(8, 0), internal.DigestDaily, wed(8, 0).AddDate(0, 0, 1)},
+		{"daily after hour", wed(9, 0), internal.DigestDaily, wed(8, 0).AddDate(0, 0, 1)},
+		{"weekly midweek", wed(7, 0), internal.DigestWeekly, time.Date(2024, 5, 20, 8, 0, 0, 0, time.UTC)},
+		{"weekly monday before hour", time.Date(2024, 5, 20, 6, 0, 0, 0, time.UTC), internal.DigestWeekly, time.Date(2024, 5, 20, 8, 0, 0, 0, time.UTC)},
+		{"weekly monday after hour", time.Date(2024, 5, 20, 9, 0, 0, 0, time.UTC), internal.DigestWeekly, time.Date(2024, 5, 27, 8, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NextDigestTime(tt.now, tt.frequency, 8); !got.Equal(tt.want) {
+				t.Errorf("NextDigestTime(%v, %q) = %v, want %v", tt.now, tt.frequency, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,9 +2,14 @@ package notification
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"net/smtp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,11 +32,34 @@ type SMTPSettingsProvider interface {
 // Notifier handles sending notifications
 type Notifier struct {
 	settingsProvider SMTPSettingsProvider
+	httpClient       *http.Client
+	send             func(settings *store.SMTPSettings, to []string, subject, body string) error
+
+	// outbox, when set, persists job notifications so failed deliveries are retried
+	outbox         OutboxStore
+	maxAttempts    int
+	retryBaseDelay time.Duration
+
+	// channels, when set, supplies Teams/PagerDuty settings for job notifications
+	channels     ChannelSettingsProvider
+	pagerDutyURL string
 }
 
 // New creates a new Notifier
 func New(provider SMTPSettingsProvider) *Notifier {
-	return &Notifier{settingsProvider: provider}
+	return &Notifier{
+		settingsProvider: provider,
+		httpClient:       http.DefaultClient,
+		send:             sendEmail,
+		maxAttempts:      internal.NotificationMaxAttempts,
+		retryBaseDelay:   internal.NotificationRetryBaseDelay,
+		pagerDutyURL:     internal.PagerDutyEventsURL,
+	}
+}
+
+// SetHTTPClient sets the client used for HTTP-based notifications (e.g. one built by NewHTTPClient)
+func (n *Notifier) SetHTTPClient(client *http.Client) {
+	n.httpClient = client
 }
 
 // SendTestEmail sends a test email to verify SMTP configuration
@@ -58,16 +86,50 @@ and email notifications will work as expected.
 This is an automated test from TaskFlow.
 `
 
-	return sendEmail(settings, []string{toEmail}, subject, body)
+	return n.send(settings, []string{toEmail}, subject, body)
 }
 
-// SendJobNotification sends email notification for a completed job run
+// SendJobNotification sends email and channel notifications for a completed job run
 func (n *Notifier) SendJobNotification(job *store.Job, run *store.Run) error {
-	if !shouldNotify(job.NotifyOn, run.Status) {
-		log.Printf("Notification skipped for job %s: notify_on=%q doesn't match status=%q", job.ID, job.NotifyOn, run.Status)
+	channelErr := n.sendToChannels(job, run)
+
+	if !shouldNotify(job.NotifyOn, run.Status, run.ExitCode, job.NotifyExitCodes) {
+		log.Printf("Notification skipped for job %s: notify_on=%q / notify_exit_codes=%v doesn't match status=%q exit_code=%s",
+			job.ID, job.NotifyOn, job.NotifyExitCodes, run.Status, formatExitCode(run.ExitCode))
+		return channelErr
+	}
+
+	subject, body := buildEmailContent(job, run)
+	return errors.Join(n.sendToJobRecipients(job, run, subject, body), channelErr)
+}
+
+// SendTimeoutWarning emails the job's recipients that a run is still going and nearing its timeout.
+// notify_on does not apply: the job opted in with timeout_warn_notify.
+func (n *Notifier) SendTimeoutWarning(job *store.Job, run *store.Run, elapsed time.Duration) error {
+	subject, body := buildTimeoutWarningContent(job, run, elapsed)
+	return n.sendToJobRecipients(job, run, subject, body)
+}
+
+// SendAdminAlert emails an operational alert, e.g. a slow scheduler tick, to the
+// comma-separated recipients. It is skipped, not failed, when SMTP is not configured.
+func (n *Notifier) SendAdminAlert(recipients, subject, body string) error {
+	to := parseEmails(recipients)
+	if len(to) == 0 {
 		return nil
 	}
+	settings, err := n.settingsProvider.GetSMTPSettings()
+	if err != nil {
+		return fmt.Errorf("failed to get SMTP settings: %w", err)
+	}
+	if !isConfigured(settings) {
+		log.Printf("SMTP not configured, skipping admin alert %q", subject)
+		return nil
+	}
+	return n.send(settings, to, emailSubjectPrefix+" "+subject, body+"\n\n---\nThis is an automated alert from TaskFlow.\n")
+}
 
+// sendToJobRecipients delivers a rendered email to the job's notify_emails, via the outbox when set
+func (n *Notifier) sendToJobRecipients(job *store.Job, run *store.Run, subject, body string) error {
 	emails := parseEmails(job.NotifyEmails)
 	if len(emails) == 0 {
 		log.Printf("Notification skipped for job %s: no email recipients configured", job.ID)
@@ -84,8 +146,18 @@ func (n *Notifier) SendJobNotification(job *store.Job, run *store.Run) error {
 		return nil
 	}
 
-	subject, body := buildEmailContent(job, run)
-	if err := sendEmail(settings, emails, subject, body); err != nil {
+	if n.outbox != nil {
+		return n.enqueue(&store.OutboxNotification{
+			JobID:      job.ID,
+			RunID:      run.ID,
+			Channel:    internal.NotifyChannelEmail,
+			Recipients: strings.Join(emails, ","),
+			Subject:    subject,
+			Body:       body,
+		})
+	}
+
+	if err := n.send(settings, emails, subject, body); err != nil {
 		return err
 	}
 
@@ -93,8 +165,20 @@ func (n *Notifier) SendJobNotification(job *store.Job, run *store.Run) error {
 	return nil
 }
 
-// shouldNotify determines if a notification should be sent
-func shouldNotify(notifyOn, status string) bool {
+// shouldNotify determines if a notification should be sent.
+// When notifyExitCodes is non-empty, the run's exit code must also be in the list.
+func shouldNotify(notifyOn, status string, exitCode *int, notifyExitCodes []int) bool {
+	if !matchesNotifyOn(notifyOn, status) {
+		return false
+	}
+	if len(notifyExitCodes) == 0 {
+		return true
+	}
+	return exitCode != nil && slices.Contains(notifyExitCodes, *exitCode)
+}
+
+// matchesNotifyOn checks the run status against the job's notify_on rule
+func matchesNotifyOn(notifyOn, status string) bool {
 	if notifyOn == "" {
 		notifyOn = internal.DefaultNotifyOn
 	}
@@ -133,6 +217,11 @@ func parseEmails(emailsStr string) []string {
 	return emails
 }
 
+// PreviewJobNotification renders the notification email for a job run without sending it
+func PreviewJobNotification(job *store.Job, run *store.Run) (subject, body string) {
+	return buildEmailContent(job, run)
+}
+
 // buildEmailContent creates the subject and body for the notification email
 func buildEmailContent(job *store.Job, run *store.Run) (subject, body string) {
 	statusEmoji := getStatusEmoji(run.Status)
@@ -140,6 +229,11 @@ func buildEmailContent(job *store.Job, run *store.Run) (subject, body string) {
 
 	subject = fmt.Sprintf("%s %s Job %s: %s", emailSubjectPrefix, statusEmoji, statusText, job.Name)
 
+	autoDisabled := ""
+	if !job.Enabled && job.AutoDisableAfterFailures > 0 && job.ConsecutiveFailures >= job.AutoDisableAfterFailures {
+		autoDisabled = fmt.Sprintf("\nThis job has been automatically disabled after %d consecutive failures.\nRe-enable it once the problem is fixed.\n", job.ConsecutiveFailures)
+	}
+
 	body = fmt.Sprintf(`TaskFlow Job Notification
 =========================
 
@@ -154,7 +248,7 @@ Execution Details:
 Duration: %s
 Exit Code: %s
 Finished: %s
-%s
+%s%s
 ---
 This is an automated notification from TaskFlow.
 `,
@@ -168,6 +262,37 @@ This is an automated notification from TaskFlow.
 		formatExitCode(run.ExitCode),
 		formatTime(run.FinishedAt),
 		formatErrorSection(run.ErrorMsg),
+		autoDisabled,
+	)
+
+	return subject, body
+}
+
+// buildTimeoutWarningContent creates the subject and body for a timeout warning email
+func buildTimeoutWarningContent(job *store.Job, run *store.Run, elapsed time.Duration) (subject, body string) {
+	subject = fmt.Sprintf("%s ⚠️ Job approaching timeout: %s", emailSubjectPrefix, job.Name)
+
+	elapsedMs := elapsed.Milliseconds()
+	timeoutMs := int64(job.TimeoutSeconds) * 1000
+	body = fmt.Sprintf(`TaskFlow Timeout Warning
+========================
+
+Job: %s
+Run ID: %s
+Started: %s
+
+The run is still in progress after %s, %d%% of its %s timeout.
+It will be killed if it has not finished when the timeout is reached.
+
+---
+This is an automated notification from TaskFlow.
+`,
+		job.Name,
+		run.ID,
+		formatTime(run.StartedAt),
+		formatDuration(&elapsedMs),
+		job.TimeoutWarnPercent,
+		formatDuration(&timeoutMs),
 	)
 
 	return subject, body
@@ -237,8 +362,39 @@ func sanitizeHeader(s string) string {
 	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
 }
 
-// sendEmail sends an email via SMTP
+// ErrSMTPTimeout is returned when the SMTP server does not accept a connection or
+// finish the exchange within the configured timeouts
+var ErrSMTPTimeout = errors.New("SMTP server timed out")
+
+// smtpSender delivers mail over a fresh SMTP connection per message. Connecting is bounded
+// by dialTimeout and the whole exchange by sessionTimeout; a failure to connect is retried
+// once after retryDelay, since nothing has been sent yet.
+type smtpSender struct {
+	dialTimeout    time.Duration
+	sessionTimeout time.Duration
+	retryDelay     time.Duration
+}
+
+// connectError marks a failure before any SMTP command was sent, which is safe to retry
+type connectError struct {
+	err error
+}
+
+func (e *connectError) Error() string { return e.err.Error() }
+func (e *connectError) Unwrap() error { return e.err }
+
+// sendEmail sends an email via SMTP with the default timeouts
 func sendEmail(settings *store.SMTPSettings, to []string, subject, body string) error {
+	sender := smtpSender{
+		dialTimeout:    internal.SMTPDialTimeout,
+		sessionTimeout: internal.SMTPSessionTimeout,
+		retryDelay:     internal.SMTPConnectRetryDelay,
+	}
+	return sender.send(settings, to, subject, body)
+}
+
+// send builds the message and delivers it, reconnecting once if the first connection fails
+func (s smtpSender) send(settings *store.SMTPSettings, to []string, subject, body string) error {
 	from := settings.FromEmail
 	if from == "" {
 		from = settings.Username
@@ -253,55 +409,75 @@ func sendEmail(settings *store.SMTPSettings, to []string, subject, body string)
 	}
 
 	msg := buildMessage(sanitizeHeader(fromName), sanitizeHeader(from), to, sanitizeHeader(subject), body)
-	addr := fmt.Sprintf("%s:%d", settings.Server, settings.Port)
+	addr := net.JoinHostPort(settings.Server, strconv.Itoa(settings.Port))
 
-	if settings.Port == smtpPortTLS {
-		return sendWithTLS(settings, addr, from, to, msg)
+	err := s.deliver(settings, addr, from, to, msg)
+	var connErr *connectError
+	if errors.As(err, &connErr) {
+		log.Printf("SMTP connection to %s failed, retrying once: %v\n", addr, err)
+		time.Sleep(s.retryDelay)
+		err = s.deliver(settings, addr, from, to, msg)
 	}
-	return sendWithSTARTTLS(settings, addr, from, to, msg)
-}
 
-// buildMessage constructs the email message with headers
-func buildMessage(fromName, from string, to []string, subject, body string) string {
-	var msg strings.Builder
-	msg.WriteString(fmt.Sprintf("From: %s <%s>\r\n", fromName, from))
-	msg.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(to, ", ")))
-	msg.WriteString(fmt.Sprintf("Subject: %s\r\n", subject))
-	msg.WriteString(fmt.Sprintf("Date: %s\r\n", time.Now().Format(time.RFC1123Z)))
-	msg.WriteString("MIME-Version: 1.0\r\n")
-	msg.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
-	msg.WriteString("\r\n")
-	msg.WriteString(body)
-	return msg.String()
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("%w (%s): %v", ErrSMTPTimeout, addr, err)
+	}
+	return err
 }
 
-// sendWithTLS sends email using implicit TLS (port 465)
-func sendWithTLS(settings *store.SMTPSettings, addr, from string, to []string, msg string) error {
-	tlsConfig := &tls.Config{ServerName: settings.Server}
+// deliver connects to addr and sends msg over a single SMTP session
+func (s smtpSender) deliver(settings *store.SMTPSettings, addr, from string, to []string, msg string) error {
+	dialer := &net.Dialer{Timeout: s.dialTimeout}
 
-	conn, err := tls.Dial("tcp", addr, tlsConfig)
+	var conn net.Conn
+	var err error
+	if settings.Port == smtpPortTLS {
+		// Implicit TLS (port 465); the dial timeout also bounds the handshake
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: settings.Server})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+		return &connectError{fmt.Errorf("failed to connect to SMTP server: %w", err)}
 	}
 	defer conn.Close()
 
+	// A stalled server can't hold the sender past the session deadline
+	if err := conn.SetDeadline(time.Now().Add(s.sessionTimeout)); err != nil {
+		return fmt.Errorf("failed to set SMTP deadline: %w", err)
+	}
+
 	client, err := smtp.NewClient(conn, settings.Server)
 	if err != nil {
-		return fmt.Errorf("failed to create SMTP client: %w", err)
+		return &connectError{fmt.Errorf("failed to create SMTP client: %w", err)}
 	}
 	defer client.Close()
 
+	if settings.Port != smtpPortTLS {
+		if err := startTLS(client, settings); err != nil {
+			return err
+		}
+	}
 	return sendWithClient(client, settings, from, to, msg)
 }
 
-// sendWithSTARTTLS sends email using STARTTLS (ports 25, 587)
-func sendWithSTARTTLS(settings *store.SMTPSettings, addr, from string, to []string, msg string) error {
-	client, err := smtp.Dial(addr)
-	if err != nil {
-		return fmt.Errorf("failed to connect to SMTP server: %w", err)
-	}
-	defer client.Close()
+// buildMessage constructs the email message with headers
+func buildMessage(fromName, from string, to []string, subject, body string) string {
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("From: %s <%s>\r\n", fromName, from))
+	msg.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(to, ", ")))
+	msg.WriteString(fmt.Sprintf("Subject: %s\r\n", subject))
+	msg.WriteString(fmt.Sprintf("Date: %s\r\n", time.Now().Format(time.RFC1123Z)))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(body)
+	return msg.String()
+}
 
+// startTLS upgrades a plain connection (ports 25, 587) when the server offers STARTTLS
+func startTLS(client *smtp.Client, settings *store.SMTPSettings) error {
 	if err := client.Hello("localhost"); err != nil {
 		return fmt.Errorf("SMTP HELLO failed: %w", err)
 	}
@@ -312,8 +488,7 @@ func sendWithSTARTTLS(settings *store.SMTPSettings, addr, from string, to []stri
 			return fmt.Errorf("SMTP STARTTLS failed: %w", err)
 		}
 	}
-
-	return sendWithClient(client, settings, from, to, msg)
+	return nil
 }
 
 // sendWithClient handles authentication and message delivery (DRY extracted)
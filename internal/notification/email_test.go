@@ -2,6 +2,8 @@ package notification
 
 import (
 	"errors"
+	"net"
+	"strings"
 	"testing"
 	"time"
 
@@ -52,7 +54,7 @@ func TestShouldNotify(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := shouldNotify(tt.notifyOn, tt.status)
+			got := shouldNotify(tt.notifyOn, tt.status, nil, nil)
 			if got != tt.want {
 				t.Errorf("shouldNotify(%q, %q) = %v, want %v", tt.notifyOn, tt.status, got, tt.want)
 			}
@@ -60,6 +62,32 @@ func TestShouldNotify(t *testing.T) {
 	}
 }
 
+func TestShouldNotifyExitCodes(t *testing.T) {
+	tests := []struct {
+		name      string
+		notifyOn  string
+		status    string
+		exitCode  *int
+		exitCodes []int
+		want      bool
+	}{
+		{"benign_code_suppressed", internal.NotifyFailure, internal.JobStatusFailure, intPtr(2), []int{1, 3}, false},
+		{"real_failure_notifies", internal.NotifyFailure, internal.JobStatusFailure, intPtr(1), []int{1, 3}, true},
+		{"no_list_any_code", internal.NotifyFailure, internal.JobStatusFailure, intPtr(2), nil, true},
+		{"status_rule_still_applies", internal.NotifyFailure, internal.JobStatusSuccess, intPtr(0), []int{0}, false},
+		{"missing_exit_code", internal.NotifyAlways, internal.JobStatusFailure, nil, []int{1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := shouldNotify(tt.notifyOn, tt.status, tt.exitCode, tt.exitCodes)
+			if got != tt.want {
+				t.Errorf("shouldNotify(%q, %q, %v, %v) = %v, want %v", tt.notifyOn, tt.status, tt.exitCode, tt.exitCodes, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestParseEmails(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -289,6 +317,20 @@ func TestBuildEmailContent(t *testing.T) {
 	}
 }
 
+func TestBuildTimeoutWarningContent(t *testing.T) {
+	job := &store.Job{ID: "job-123", Name: "Nightly ETL", TimeoutSeconds: 600, TimeoutWarnPercent: 80}
+	run := &store.Run{ID: "run-456", Status: internal.JobStatusRunning}
+
+	subject, body := buildTimeoutWarningContent(job, run, 8*time.Minute)
+
+	if !containsAll(subject, "[TaskFlow]", "approaching timeout", "Nightly ETL") {
+		t.Errorf("buildTimeoutWarningContent() subject = %q, missing expected parts", subject)
+	}
+	if !containsAll(body, "Nightly ETL", "run-456", "8.0 minutes", "80%", "10.0 minutes") {
+		t.Errorf("buildTimeoutWarningContent() body missing expected content:\n%s", body)
+	}
+}
+
 func TestBuildMessage(t *testing.T) {
 	msg := buildMessage("TaskFlow", "noreply@test.com", []string{"user@test.com"}, "Test Subject", "Test body")
 
@@ -397,3 +439,70 @@ func containsAll(s string, substrs ...string) bool {
 	}
 	return true
 }
+
+// TestSMTPSendUnreachableServer tests that a send to a port nobody listens on fails promptly,
+// after the single reconnect, instead of hanging
+func TestSMTPSendUnreachableServer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	sender := smtpSender{dialTimeout: 200 * time.Millisecond, sessionTimeout: 200 * time.Millisecond, retryDelay: 10 * time.Millisecond}
+	settings := &store.SMTPSettings{Server: "127.0.0.1", Port: port, FromEmail: "taskflow@example.com"}
+
+	start := time.Now()
+	err = sender.send(settings, []string{"ops@example.com"}, "subject", "body")
+	if err == nil || !strings.Contains(err.Error(), "failed to connect to SMTP server") {
+		t.Fatalf("send() error = %v, want a connection error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("send() took %v, want a prompt failure", elapsed)
+	}
+}
+
+// TestSMTPSendTimesOut tests that a server that accepts connections but never answers
+// produces ErrSMTPTimeout within the configured timeouts
+func TestSMTPSendTimesOut(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	// Accept connections but never send the 220 greeting
+	accepted := make(chan net.Conn, 4)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	sender := smtpSender{dialTimeout: 200 * time.Millisecond, sessionTimeout: 200 * time.Millisecond, retryDelay: 10 * time.Millisecond}
+	settings := &store.SMTPSettings{
+		Server:    "127.0.0.1",
+		Port:      ln.Addr().(*net.TCPAddr).Port,
+		FromEmail: "taskflow@example.com",
+	}
+
+	start := time.Now()
+	err = sender.send(settings, []string{"ops@example.com"}, "subject", "body")
+	if !errors.Is(err, ErrSMTPTimeout) {
+		t.Fatalf("send() error = %v, want ErrSMTPTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("send() took %v, want it bounded by the timeouts", elapsed)
+	}
+	if n := len(accepted); n != 2 {
+		t.Errorf("server saw %d connections, want 2 (one retry)", n)
+	}
+	for len(accepted) > 0 {
+		(<-accepted).Close()
+	}
+}
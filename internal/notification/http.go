@@ -0,0 +1,52 @@
+package notification
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// httpNotifyTimeout bounds each outbound HTTP notification request
+const httpNotifyTimeout = 10 * time.Second
+
+// ParseProxyURL validates an explicit notification proxy URL.
+// An empty string is valid and means "use the environment".
+func ParseProxyURL(raw string) (*url.URL, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL %q: scheme must be http, https or socks5", raw)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: missing host", raw)
+	}
+
+	return u, nil
+}
+
+// NewHTTPClient builds the client used for HTTP-based notifications (webhooks and similar).
+// proxyURL, when set, overrides the proxy; otherwise HTTP_PROXY, HTTPS_PROXY and NO_PROXY are honored.
+func NewHTTPClient(proxyURL string) (*http.Client, error) {
+	u, err := ParseProxyURL(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if u != nil {
+		transport.Proxy = http.ProxyURL(u)
+	} else {
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+
+	return &http.Client{Transport: transport, Timeout: httpNotifyTimeout}, nil
+}
@@ -0,0 +1,140 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	internal "github.com/taskflow/taskflow/internal"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// OutboxStore persists notifications so failed deliveries can be retried
+type OutboxStore interface {
+	EnqueueNotification(n *store.OutboxNotification) (*store.OutboxNotification, error)
+	ClaimDueNotifications(now time.Time, limit int) ([]*store.OutboxNotification, error)
+	MarkNotificationDelivered(id int64) error
+	RecordNotificationFailure(id int64, errMsg string, retryAt *time.Time) error
+}
+
+// SetOutbox routes job notifications through a persistent outbox. Each notification
+// is attempted immediately; failures are retried by RunOutbox with exponential backoff.
+func (n *Notifier) SetOutbox(outbox OutboxStore) {
+	n.outbox = outbox
+}
+
+// SetRetryPolicy overrides how many attempts are made and the delay before the first retry
+func (n *Notifier) SetRetryPolicy(maxAttempts int, baseDelay time.Duration) {
+	n.maxAttempts = maxAttempts
+	n.retryBaseDelay = baseDelay
+}
+
+// RunOutbox retries due notifications every interval until ctx is done
+func (n *Notifier) RunOutbox(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := n.ProcessOutbox(); err != nil {
+				log.Printf("Failed to process notification outbox: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ProcessOutbox attempts delivery of every pending notification that is due. Each one is
+// claimed first, so a notification whose first attempt is still in flight is not resent.
+func (n *Notifier) ProcessOutbox() error {
+	if n.outbox == nil {
+		return nil
+	}
+
+	due, err := n.outbox.ClaimDueNotifications(time.Now(), internal.NotificationOutboxBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range due {
+		if err := n.deliver(entry); err != nil {
+			log.Printf("Retry %d of notification %d for job %s failed: %v", entry.Attempts+1, entry.ID, entry.JobID, err)
+		}
+	}
+	return nil
+}
+
+// enqueue stores a rendered notification, claimed for the first delivery attempt made here
+func (n *Notifier) enqueue(notification *store.OutboxNotification) error {
+	entry, err := n.outbox.EnqueueNotification(notification)
+	if err != nil {
+		return err
+	}
+
+	if err := n.deliver(entry); err != nil {
+		return fmt.Errorf("notification %d queued for retry: %w", entry.ID, err)
+	}
+	return nil
+}
+
+// deliver makes one delivery attempt and records the outcome in the outbox
+func (n *Notifier) deliver(entry *store.OutboxNotification) error {
+	sendErr := n.sendEntry(entry)
+	if sendErr == nil {
+		if err := n.outbox.MarkNotificationDelivered(entry.ID); err != nil {
+			log.Printf("Failed to mark notification %d delivered: %v", entry.ID, err)
+		}
+		log.Printf("Notification %d sent for job %s to %s", entry.ID, entry.JobID, outboxDestination(entry))
+		return nil
+	}
+
+	var retryAt *time.Time
+	attempts := entry.Attempts + 1
+	if attempts < n.maxAttempts {
+		next := time.Now().Add(n.retryDelay(attempts))
+		retryAt = &next
+	} else {
+		log.Printf("Notification %d for job %s failed after %d attempts", entry.ID, entry.JobID, attempts)
+	}
+
+	if err := n.outbox.RecordNotificationFailure(entry.ID, sendErr.Error(), retryAt); err != nil {
+		log.Printf("Failed to record notification %d failure: %v", entry.ID, err)
+	}
+	return sendErr
+}
+
+// sendEntry sends an outbox entry using the current SMTP or channel settings
+func (n *Notifier) sendEntry(entry *store.OutboxNotification) error {
+	if entry.Channel != internal.NotifyChannelEmail {
+		return n.postToChannel(entry.Channel, []byte(entry.Body))
+	}
+
+	settings, err := n.settingsProvider.GetSMTPSettings()
+	if err != nil {
+		return fmt.Errorf("failed to get SMTP settings: %w", err)
+	}
+	if !isConfigured(settings) {
+		return fmt.Errorf("SMTP is not configured")
+	}
+	return n.send(settings, parseEmails(entry.Recipients), entry.Subject, entry.Body)
+}
+
+// outboxDestination describes where an outbox entry is sent, for logging
+func outboxDestination(entry *store.OutboxNotification) string {
+	if entry.Channel != internal.NotifyChannelEmail {
+		return entry.Channel
+	}
+	return entry.Recipients
+}
+
+// retryDelay returns the backoff before the next attempt after the given number of attempts
+func (n *Notifier) retryDelay(attempts int) time.Duration {
+	delay := n.retryBaseDelay
+	for i := 1; i < attempts && delay < internal.NotificationRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, internal.NotificationRetryMaxDelay)
+}
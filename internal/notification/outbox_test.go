@@ -0,0 +1,225 @@
+package notification
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	internal "github.com/taskflow/taskflow/internal"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// newOutboxNotifier returns a notifier backed by a test store outbox whose sends go to send
+func newOutboxNotifier(t *testing.T, maxAttempts int, send func(*store.SMTPSettings, []string, string, string) error) (*Notifier, *store.Store) {
+	t.Helper()
+
+	st := store.NewTestStore(t)
+	t.Cleanup(func() { st.Close() })
+
+	n := New(&mockSettingsProvider{settings: &store.SMTPSettings{Server: "smtp.example.com", Port: 587}})
+	n.send = send
+	n.SetOutbox(st)
+	n.SetRetryPolicy(maxAttempts, time.Millisecond)
+	return n, st
+}
+
+// drainOutbox processes the outbox until the notification leaves the pending state
+func drainOutbox(t *testing.T, n *Notifier, st *store.Store, id int64) *store.OutboxNotification {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		entry, err := st.GetOutboxNotification(id)
+		if err != nil {
+			t.Fatalf("GetOutboxNotification() error = %v", err)
+		}
+		if entry.Status != "pending" {
+			return entry
+		}
+		time.Sleep(5 * time.Millisecond)
+		if err := n.ProcessOutbox(); err != nil {
+			t.Fatalf("ProcessOutbox() error = %v", err)
+		}
+	}
+	t.Fatalf("notification %d still pending", id)
+	return nil
+}
+
+func outboxJobAndRun() (*store.Job, *store.Run) {
+	job := &store.Job{ID: "job-1", Name: "backup", NotifyOn: internal.NotifyAlways, NotifyEmails: "ops@example.com"}
+	run := &store.Run{ID: "run-1", JobID: job.ID, Status: internal.JobStatusFailure}
+	return job, run
+}
+
+// TestOutboxRetriesTransientFailure tests that a sender failing twice is delivered on the third attempt
+func TestOutboxRetriesTransientFailure(t *testing.T) {
+	calls := 0
+	n, st := newOutboxNotifier(t, 5, func(_ *store.SMTPSettings, to []string, _, _ string) error {
+		calls++
+		if calls <= 2 {
+			return errors.New("connection reset")
+		}
+		if len(to) != 1 || to[0] != "ops@example.com" {
+			t.Errorf("recipients = %v, want [ops@example.com]", to)
+		}
+		return nil
+	})
+
+	job, run := outboxJobAndRun()
+	if err := n.SendJobNotification(job, run); err == nil {
+		t.Fatal("SendJobNotification() expected the first attempt's error")
+	}
+
+	entry := drainOutbox(t, n, st, 1)
+	if entry.Status != "delivered" {
+		t.Errorf("Status = %q, want delivered", entry.Status)
+	}
+	if entry.Attempts != 3 || calls != 3 {
+		t.Errorf("Attempts = %d, calls = %d, want 3", entry.Attempts, calls)
+	}
+	if entry.LastError != nil {
+		t.Errorf("LastError = %q, want nil after delivery", *entry.LastError)
+	}
+}
+
+// TestOutboxMarksPermanentFailure tests that a sender that always fails is marked failed after max attempts
+func TestOutboxMarksPermanentFailure(t *testing.T) {
+	calls := 0
+	n, st := newOutboxNotifier(t, 3, func(*store.SMTPSettings, []string, string, string) error {
+		calls++
+		return errors.New("mailbox unavailable")
+	})
+
+	job, run := outboxJobAndRun()
+	if err := n.SendJobNotification(job, run); err == nil {
+		t.Fatal("SendJobNotification() expected an error")
+	}
+
+	entry := drainOutbox(t, n, st, 1)
+	if entry.Status != "failed" {
+		t.Errorf("Status = %q, want failed", entry.Status)
+	}
+	if entry.Attempts != 3 {
+		t.Errorf("Attempts = %d, want 3", entry.Attempts)
+	}
+	if entry.LastError == nil || *entry.LastError != "mailbox unavailable" {
+		t.Errorf("LastError = %v, want mailbox unavailable", entry.LastError)
+	}
+
+	// Failed notifications are not retried again
+	if err := n.ProcessOutbox(); err != nil {
+		t.Fatalf("ProcessOutbox() error = %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+// TestOutboxDoesNotResendInFlightNotification tests that an outbox pass running while the
+// first attempt is still sending leaves the notification to that attempt
+func TestOutboxDoesNotResendInFlightNotification(t *testing.T) {
+	var n *Notifier
+	calls := 0
+	n, st := newOutboxNotifier(t, 5, func(*store.SMTPSettings, []string, string, string) error {
+		calls++
+		if calls == 1 {
+			if err := n.ProcessOutbox(); err != nil {
+				t.Errorf("ProcessOutbox() error = %v", err)
+			}
+		}
+		return nil
+	})
+
+	job, run := outboxJobAndRun()
+	if err := n.SendJobNotification(job, run); err != nil {
+		t.Fatalf("SendJobNotification() error = %v", err)
+	}
+
+	entry, err := st.GetOutboxNotification(1)
+	if err != nil {
+		t.Fatalf("GetOutboxNotification() error = %v", err)
+	}
+	if entry.Status != "delivered" {
+		t.Errorf("Status = %q, want delivered", entry.Status)
+	}
+	if entry.Attempts != 1 || calls != 1 {
+		t.Errorf("Attempts = %d, calls = %d, want 1", entry.Attempts, calls)
+	}
+}
+
+// TestOutboxRetriesChannelPosts tests that Teams and PagerDuty posts go through the outbox:
+// a rejected post is retried, and the PagerDuty routing key is added at send time only
+func TestOutboxRetriesChannelPosts(t *testing.T) {
+	teamsCalls := 0
+	teams := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		teamsCalls++
+		if teamsCalls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(teams.Close)
+	pd := newRecordingServer(t, http.StatusAccepted)
+
+	n, st := newOutboxNotifier(t, 5, func(*store.SMTPSettings, []string, string, string) error {
+		t.Error("no email should be sent")
+		return nil
+	})
+	n.SetChannels(&mockChannelSettings{settings: &store.ChannelSettings{
+		TeamsWebhookURL:     teams.URL,
+		PagerDutyRoutingKey: "routing-key",
+	}})
+	n.pagerDutyURL = pd.URL
+
+	job := &store.Job{
+		ID:             "job-1",
+		Name:           "backup",
+		NotifyOn:       internal.NotifyAlways,
+		NotifyChannels: []string{internal.NotifyChannelTeams, internal.NotifyChannelPagerDuty},
+	}
+	run := &store.Run{ID: "run-1", JobID: job.ID, Status: internal.JobStatusFailure}
+	if err := n.SendJobNotification(job, run); err == nil {
+		t.Fatal("SendJobNotification() expected the rejected Teams post's error")
+	}
+
+	teamsEntry := drainOutbox(t, n, st, 1)
+	if teamsEntry.Channel != internal.NotifyChannelTeams || teamsEntry.Status != "delivered" || teamsEntry.Attempts != 2 {
+		t.Errorf("Teams entry = %s/%s after %d attempts, want teams/delivered after 2", teamsEntry.Channel, teamsEntry.Status, teamsEntry.Attempts)
+	}
+
+	pdEntry := drainOutbox(t, n, st, 2)
+	if pdEntry.Channel != internal.NotifyChannelPagerDuty || pdEntry.Status != "delivered" {
+		t.Errorf("PagerDuty entry = %s/%s, want pagerduty/delivered", pdEntry.Channel, pdEntry.Status)
+	}
+	if strings.Contains(pdEntry.Body, "routing-key") {
+		t.Errorf("queued PagerDuty event should not store the routing key: %s", pdEntry.Body)
+	}
+	events := pd.received()
+	if len(events) != 1 || events[0]["routing_key"] != "routing-key" {
+		t.Errorf("PagerDuty events = %v, want one carrying the routing key", events)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	n := New(&mockSettingsProvider{})
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, internal.NotificationRetryBaseDelay},
+		{2, 2 * internal.NotificationRetryBaseDelay},
+		{3, 4 * internal.NotificationRetryBaseDelay},
+		{50, internal.NotificationRetryMaxDelay},
+	}
+
+	for _, tt := range tests {
+		if got := n.retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
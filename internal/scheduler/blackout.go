@@ -0,0 +1,117 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+const blackoutClockLayout = "15:04"
+
+// ValidateBlackoutWindow checks that a window is either a recurring "HH:MM" pair or a
+// one-off RFC3339 pair that ends after it starts
+func ValidateBlackoutWindow(w store.BlackoutWindow) error {
+	if _, err := time.Parse(blackoutClockLayout, w.Start); err == nil {
+		if _, err := time.Parse(blackoutClockLayout, w.End); err != nil {
+			return fmt.Errorf("end must be HH:MM when start is HH:MM")
+		}
+		if w.Start == w.End {
+			return fmt.Errorf("start and end must differ")
+		}
+		for _, d := range w.Weekdays {
+			if d < 0 || d > 6 {
+				return fmt.Errorf("weekdays must be between 0 and 6")
+			}
+		}
+		return nil
+	}
+
+	start, err := time.Parse(time.RFC3339, w.Start)
+	if err != nil {
+		return fmt.Errorf("start must be HH:MM or an RFC3339 timestamp")
+	}
+	end, err := time.Parse(time.RFC3339, w.End)
+	if err != nil {
+		return fmt.Errorf("end must be an RFC3339 timestamp when start is")
+	}
+	if !end.After(start) {
+		return fmt.Errorf("end must be after start")
+	}
+	if len(w.Weekdays) > 0 {
+		return fmt.Errorf("weekdays only apply to recurring HH:MM windows")
+	}
+	return nil
+}
+
+// activeBlackout returns the first of the job's blackout windows that contains t,
+// evaluating recurring windows in the job's timezone
+func activeBlackout(job *store.Job, t time.Time) (store.BlackoutWindow, bool) {
+	if len(job.BlackoutWindows) == 0 {
+		return store.BlackoutWindow{}, false
+	}
+	return blackoutAt(job.BlackoutWindows, t.In(jobLocation(job)))
+}
+
+// blackoutAt returns the first window containing t, which must already be in the job's timezone
+func blackoutAt(windows []store.BlackoutWindow, t time.Time) (store.BlackoutWindow, bool) {
+	for _, w := range windows {
+		if blackoutContains(w, t) {
+			return w, true
+		}
+	}
+	return store.BlackoutWindow{}, false
+}
+
+// jobLocation returns the job's timezone, falling back to local time if it is invalid
+func jobLocation(job *store.Job) *time.Location {
+	loc, err := time.LoadLocation(job.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// blackoutContains reports whether t (already in the job's timezone) falls inside w.
+// Recurring windows are half-open [start, end); one ending before it starts crosses
+// midnight, and its weekdays refer to the day it starts.
+func blackoutContains(w store.BlackoutWindow, t time.Time) bool {
+	start, err := time.Parse(blackoutClockLayout, w.Start)
+	if err != nil {
+		from, err1 := time.Parse(time.RFC3339, w.Start)
+		to, err2 := time.Parse(time.RFC3339, w.End)
+		return err1 == nil && err2 == nil && !t.Before(from) && t.Before(to)
+	}
+	end, err := time.Parse(blackoutClockLayout, w.End)
+	if err != nil {
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	startMin := start.Hour()*60 + start.Minute()
+	endMin := end.Hour()*60 + end.Minute()
+	today := int(t.Weekday())
+	yesterday := (today + 6) % 7
+
+	if startMin < endMin {
+		return minute >= startMin && minute < endMin && onWeekday(w.Weekdays, today)
+	}
+	// Crosses midnight: the late part belongs to today's window, the early part to yesterday's
+	if minute >= startMin {
+		return onWeekday(w.Weekdays, today)
+	}
+	return minute < endMin && onWeekday(w.Weekdays, yesterday)
+}
+
+// onWeekday checks if day is in weekdays (empty means every day)
+func onWeekday(weekdays []int, day int) bool {
+	if len(weekdays) == 0 {
+		return true
+	}
+	for _, d := range weekdays {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// TestBlackoutContains tests recurring, overnight, weekday-limited and one-off windows
+func TestBlackoutContains(t *testing.T) {
+	// 2024-01-15 is a Monday (weekday 1)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		window store.BlackoutWindow
+		t      time.Time
+		want   bool
+	}{
+		{"daily inside", store.BlackoutWindow{Start: "02:00", End: "04:00"}, at(15, 3, 0), true},
+		{"daily start inclusive", store.BlackoutWindow{Start: "02:00", End: "04:00"}, at(15, 2, 0), true},
+		{"daily end exclusive", store.BlackoutWindow{Start: "02:00", End: "04:00"}, at(15, 4, 0), false},
+		{"daily outside", store.BlackoutWindow{Start: "02:00", End: "04:00"}, at(15, 5, 0), false},
+		{"overnight late part", store.BlackoutWindow{Start: "23:00", End: "01:00"}, at(15, 23, 30), true},
+		{"overnight early part", store.BlackoutWindow{Start: "23:00", End: "01:00"}, at(15, 0, 30), true},
+		{"overnight outside", store.BlackoutWindow{Start: "23:00", End: "01:00"}, at(15, 12, 0), false},
+		{"weekday match", store.BlackoutWindow{Start: "02:00", End: "04:00", Weekdays: []int{1}}, at(15, 3, 0), true},
+		{"weekday mismatch", store.BlackoutWindow{Start: "02:00", End: "04:00", Weekdays: []int{2}}, at(15, 3, 0), false},
+		// Sunday 23:00 to Monday 01:00 still covers Monday 00:30
+		{"overnight weekday from previous day", store.BlackoutWindow{Start: "23:00", End: "01:00", Weekdays: []int{0}}, at(15, 0, 30), true},
+		{"overnight weekday not previous day", store.BlackoutWindow{Start: "23:00", End: "01:00", Weekdays: []int{1}}, at(15, 0, 30), false},
+		{"one-off inside", store.BlackoutWindow{Start: "2024-01-15T00:00:00Z", End: "2024-01-16T00:00:00Z"}, at(15, 12, 0), true},
+		{"one-off outside", store.BlackoutWindow{Start: "2024-01-15T00:00:00Z", End: "2024-01-16T00:00:00Z"}, at(16, 12, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, blackoutContains(tt.window, tt.t))
+		})
+	}
+}
+
+// TestActiveBlackoutUsesJobTimezone tests that recurring windows are read in the job's timezone
+func TestActiveBlackoutUsesJobTimezone(t *testing.T) {
+	job := &store.Job{
+		Timezone:        "America/New_York",
+		BlackoutWindows: []store.BlackoutWindow{{Start: "01:00", End: "03:00"}},
+	}
+
+	// 07:00 UTC is 02:00 in New York (EST)
+	_, blocked := activeBlackout(job, time.Date(2024, 1, 15, 7, 0, 0, 0, time.UTC))
+	assert.True(t, blocked)
+	_, blocked = activeBlackout(job, time.Date(2024, 1, 15, 2, 0, 0, 0, time.UTC))
+	assert.False(t, blocked)
+}
+
+func TestValidateBlackoutWindow(t *testing.T) {
+	valid := []store.BlackoutWindow{
+		{Start: "02:00", End: "04:00"},
+		{Start: "23:00", End: "01:00", Weekdays: []int{0, 6}},
+		{Start: "2024-01-15T00:00:00Z", End: "2024-01-16T00:00:00Z"},
+	}
+	for _, w := range valid {
+		assert.NoError(t, ValidateBlackoutWindow(w), "%+v", w)
+	}
+
+	invalid := []store.BlackoutWindow{
+		{Start: "02:00", End: "02:00"},
+		{Start: "02:00", End: "2024-01-16T00:00:00Z"},
+		{Start: "25:00", End: "04:00"},
+		{Start: "02:00", End: "04:00", Weekdays: []int{7}},
+		{Start: "2024-01-16T00:00:00Z", End: "2024-01-15T00:00:00Z"},
+		{Start: "2024-01-15T00:00:00Z", End: "2024-01-16T00:00:00Z", Weekdays: []int{1}},
+	}
+	for _, w := range invalid {
+		assert.Error(t, ValidateBlackoutWindow(w), "%+v", w)
+	}
+}
@@ -0,0 +1,95 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// maxDSTShift bounds how far a DST transition moves the wall clock; real ones are an hour
+// or less, in steps of at least a quarter hour
+const maxDSTShift = 3 * time.Hour
+
+// FiresAt reports whether the schedule fires in the minute of instant t, matched on the
+// wall clock of loc. DST transitions are resolved so each scheduled wall-clock time fires
+// exactly once: a time skipped by a spring-forward gap fires at the first minute after
+// the gap, and a time repeated by a fall-back fires only on its first occurrence.
+func (m *Matcher) FiresAt(t time.Time, loc *time.Location, schedule *store.Schedule) bool {
+	local := t.Truncate(time.Minute).In(loc)
+	if m.Matches(local, schedule) {
+		return !repeatedWallMinute(local)
+	}
+	for _, skipped := range skippedWallMinutes(local) {
+		if m.Matches(skipped, schedule) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextFireTime returns the first minute after from in which FiresAt holds, or the zero
+// time if there is none within a year. It jumps between wall-clock matches with
+// NextScheduledTime and only resolves DST at loc's offset changes, found with ZoneBounds.
+func (m *Matcher) NextFireTime(schedule *store.Schedule, loc *time.Location, from time.Time) time.Time {
+	limit, out := from.AddDate(1, 0, 0), from.Location()
+	for {
+		candidate := m.NextScheduledTime(schedule, from.In(loc))
+
+		// Wall times skipped by a spring-forward fire at the first minute after the gap,
+		// which NextScheduledTime never returns, so check each offset change before the candidate
+		for t := from; ; {
+			_, end := t.In(loc).ZoneBounds()
+			if end.IsZero() || !end.After(t) || !end.Before(limit) || (!candidate.IsZero() && !end.Before(candidate)) {
+				break
+			}
+			if m.FiresAt(end, loc, schedule) {
+				return end.In(out)
+			}
+			t = end
+		}
+
+		if candidate.IsZero() || !repeatedWallMinute(candidate) {
+			return candidate.In(out)
+		}
+		// The second pass through an hour repeated by fall-back; its times already fired
+		from = candidate
+	}
+}
+
+// repeatedWallMinute reports whether local's wall-clock minute already occurred at an
+// earlier instant, i.e. it lies in the second pass through an hour repeated by fall-back
+func repeatedWallMinute(local time.Time) bool {
+	for shift := 15 * time.Minute; shift <= maxDSTShift; shift += 15 * time.Minute {
+		if sameWallMinute(local.Add(-shift), local) {
+			return true
+		}
+	}
+	return false
+}
+
+// skippedWallMinutes returns the wall-clock minutes a spring-forward jumped over if local
+// is the first minute after the gap, each expressed in the offset before the jump so it
+// reads as the nonexistent time (02:00-02:59 for a one-hour gap at 02:00)
+func skippedWallMinutes(local time.Time) []time.Time {
+	prev := local.Add(-time.Minute)
+	prevName, prevOffset := prev.Zone()
+	_, offset := local.Zone()
+	if offset <= prevOffset {
+		return nil
+	}
+
+	before := time.FixedZone(prevName, prevOffset)
+	gap := time.Duration(offset-prevOffset) * time.Second
+	var skipped []time.Time
+	for d := time.Minute; d <= gap; d += time.Minute {
+		skipped = append(skipped, prev.Add(d).In(before))
+	}
+	return skipped
+}
+
+// sameWallMinute reports whether a and b show the same date, hour and minute
+func sameWallMinute(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd && a.Hour() == b.Hour() && a.Minute() == b.Minute()
+}
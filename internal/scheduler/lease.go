@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	internal "github.com/taskflow/taskflow/internal"
+)
+
+// newInstanceID names this process in the scheduler lease so operators can tell
+// instances sharing a database apart
+func newInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), uuid.New().String()[:8])
+}
+
+// holdLease takes or renews the scheduler lease and reports whether this instance may
+// schedule jobs. Another instance started against the same database stands by until the
+// holder stops renewing, so jobs are not fired twice. Changes of role are logged.
+func (s *Scheduler) holdLease(now time.Time) bool {
+	leader, err := s.store.AcquireSchedulerLease(s.instanceID, now, internal.SchedulerLeaseTTL)
+	if err != nil {
+		log.Printf("Failed to renew the scheduler lease, skipping this tick: %v\n", err)
+		leader = false
+	}
+
+	s.mu.Lock()
+	changed := !s.leaseChecked || leader != s.leader
+	s.leader, s.leaseChecked = leader, true
+	s.mu.Unlock()
+
+	if changed && leader {
+		// Schedules may have been saved through the previous holder while this one stood by
+		s.cache.clear()
+		log.Printf("Acquired the scheduler lease as %s\n", s.instanceID)
+	} else if changed && err == nil {
+		holder := "another instance"
+		if lease, err := s.store.GetSchedulerLease(); err == nil && lease != nil {
+			holder = fmt.Sprintf("%s until %s", lease.Holder, lease.ExpiresAt.Format(time.RFC3339))
+		}
+		log.Printf("Standing by: the scheduler lease is held by %s\n", holder)
+	}
+	return leader
+}
+
+// releaseLease gives up the lease on shutdown so a standby instance takes over at its
+// next tick instead of waiting for the lease to expire
+func (s *Scheduler) releaseLease() {
+	s.mu.Lock()
+	leader := s.leader
+	s.leader = false
+	s.mu.Unlock()
+
+	if !leader {
+		return
+	}
+	if err := s.store.ReleaseSchedulerLease(s.instanceID); err != nil {
+		log.Printf("Failed to release the scheduler lease: %v\n", err)
+	}
+}
+
+// InstanceID returns the name this scheduler holds the lease under
+func (s *Scheduler) InstanceID() string {
+	return s.instanceID
+}
+
+// IsLeader reports whether this instance held the scheduler lease at its last tick
+func (s *Scheduler) IsLeader() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.leader
+}
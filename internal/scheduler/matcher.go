@@ -1,6 +1,7 @@
 package scheduler
 
 import (
+	"slices"
 	"time"
 
 	"github.com/taskflow/taskflow/internal/store"
@@ -14,14 +15,29 @@ func NewMatcher() *Matcher {
 	return &Matcher{}
 }
 
-// Matches checks if the given time matches the schedule
+// Matches checks if the given time matches the schedule. Holidays never match, and a
+// solar anchor replaces the hours and minutes with that day's sunrise or sunset.
 func (m *Matcher) Matches(t time.Time, schedule *store.Schedule) bool {
+	if !m.matchesDate(t, schedule) {
+		return false
+	}
+
+	if schedule.Solar != nil {
+		event, ok := solarEventTime(t, schedule.Solar)
+		return ok && event.Truncate(time.Minute).Equal(t.Truncate(time.Minute))
+	}
+
+	return m.matchesField(schedule.Hours, t.Hour()) &&
+		m.matchesField(schedule.Minutes, t.Minute())
+}
+
+// matchesDate checks the schedule's date fields and holidays against t's calendar day
+func (m *Matcher) matchesDate(t time.Time, schedule *store.Schedule) bool {
 	return m.matchesField(schedule.Years, t.Year()) &&
 		m.matchesField(schedule.Months, int(t.Month())) &&
 		m.matchesField(schedule.Days, t.Day()) &&
 		m.matchesField(schedule.Weekdays, int(t.Weekday())) &&
-		m.matchesField(schedule.Hours, t.Hour()) &&
-		m.matchesField(schedule.Minutes, t.Minute())
+		!slices.Contains(schedule.Holidays, t.Format(time.DateOnly))
 }
 
 // matchesField checks if value is in allowed list (nil/empty means any)
@@ -37,16 +53,29 @@ func (m *Matcher) matchesField(allowed []int, value int) bool {
 	return false
 }
 
-// NextScheduledTime calculates the next execution time based on schedule
-// This is a simplified implementation that checks minute-by-minute
+// NextScheduledTime calculates the next execution time based on schedule, matched on
+// from's wall clock. Days whose date fields do not match are skipped whole, and hours
+// that do not match an hour-based schedule are skipped to the next hour; otherwise it
+// checks minute by minute.
 func (m *Matcher) NextScheduledTime(schedule *store.Schedule, from time.Time) time.Time {
 	t := from.Add(time.Minute)
 	// Truncate to minute boundary
 	t = t.Truncate(time.Minute)
 
 	// Check next 365 days for matching time
-	for i := 0; i < 365*24*60; i++ {
-		if m.Matches(t, schedule) {
+	limit := t.Add(365 * 24 * time.Hour)
+	for t.Before(limit) {
+		if !m.matchesDate(t, schedule) {
+			y, month, d := t.Date()
+			// A zone whose DST change skips midnight may resolve it to before t
+			if next := time.Date(y, month, d+1, 0, 0, 0, 0, t.Location()); next.After(t) {
+				t = next
+				continue
+			}
+		} else if schedule.Solar == nil && !m.matchesField(schedule.Hours, t.Hour()) {
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+			continue
+		} else if m.Matches(t, schedule) {
 			return t
 		}
 		t = t.Add(time.Minute)
@@ -55,3 +84,84 @@ func (m *Matcher) NextScheduledTime(schedule *store.Schedule, from time.Time) ti
 	// No match found in the next year
 	return time.Time{}
 }
+
+// NextRunTime returns when the scheduler will next fire the job after from, matching in
+// the job's timezone and skipping blackout windows, or nil if the job or its schedule is
+// disabled or nothing matches within a year
+func NextRunTime(job *store.Job, schedule *store.Schedule, from time.Time) *time.Time {
+	if !job.Enabled || !job.ScheduleEnabled {
+		return nil
+	}
+	if schedule.RunAt != nil {
+		// A pending one-shot is next due at its run_at, even if that has passed
+		if !oneShotPending(schedule) {
+			return nil
+		}
+		next := oneShotTime(schedule)
+		return &next
+	}
+
+	m := NewMatcher()
+	loc := jobLocation(job)
+	limit := from.AddDate(1, 0, 0)
+	for next := m.NextFireTime(schedule, loc, from); !next.IsZero() && next.Before(limit); next = m.NextFireTime(schedule, loc, next) {
+		if _, blocked := blackoutAt(job.BlackoutWindows, next.In(loc)); !blocked {
+			return &next
+		}
+	}
+	return nil
+}
+
+// MinFireInterval returns the shortest gap between consecutive fires of the schedule's
+// time of day, judged over two consecutive days as if both matched its date fields, so
+// the gap across midnight counts. ok is false when the schedule never fires in that span.
+func MinFireInterval(schedule *store.Schedule) (gap time.Duration, ok bool) {
+	if schedule.RunAt != nil {
+		return 0, false
+	}
+	daily := &store.Schedule{Hours: schedule.Hours, Minutes: schedule.Minutes, Solar: schedule.Solar}
+
+	m := NewMatcher()
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	var last time.Time
+	for t := start; t.Before(start.AddDate(0, 0, 2)); t = t.Add(time.Minute) {
+		if !m.Matches(t, daily) {
+			continue
+		}
+		if !last.IsZero() && (!ok || t.Sub(last) < gap) {
+			gap, ok = t.Sub(last), true
+		}
+		last = t
+	}
+	return gap, ok
+}
+
+// UpcomingRunTimes returns up to max times in (from, until] when the job's schedule
+// matches in the job's timezone, oldest first, skipping blackout windows. Disabled and
+// paused schedules have none. Only the window is scanned, so a schedule that never
+// matches costs one check per minute of the window.
+func UpcomingRunTimes(job *store.Job, schedule *store.Schedule, from, until time.Time, max int) []time.Time {
+	if !job.Enabled || !job.ScheduleEnabled || job.SchedulePaused {
+		return nil
+	}
+	if schedule.RunAt != nil {
+		t := oneShotTime(schedule)
+		if !oneShotPending(schedule) || !t.After(from) || t.After(until) || max < 1 {
+			return nil
+		}
+		return []time.Time{t}
+	}
+
+	m := NewMatcher()
+	loc := jobLocation(job)
+	var times []time.Time
+	for t := from.Truncate(time.Minute).Add(time.Minute); !t.After(until) && len(times) < max; t = t.Add(time.Minute) {
+		if !m.FiresAt(t, loc, schedule) {
+			continue
+		}
+		if _, blocked := blackoutAt(job.BlackoutWindows, t.In(loc)); !blocked {
+			times = append(times, t)
+		}
+	}
+	return times
+}
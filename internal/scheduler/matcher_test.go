@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/taskflow/taskflow/internal/store"
 )
 
@@ -281,3 +282,207 @@ func TestEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// TestUpcomingRunTimes tests that fires are matched in the job's timezone within the window
+func TestUpcomingRunTimes(t *testing.T) {
+	from := time.Date(2026, time.January, 15, 14, 30, 0, 0, time.UTC)
+	until := from.Add(3 * time.Hour)
+
+	// 09:00 and 10:00 in New York are 14:00 and 15:00 UTC in January
+	job := &store.Job{Enabled: true, ScheduleEnabled: true, Timezone: "America/New_York"}
+	schedule := &store.Schedule{Hours: []int{9, 10, 11}, Minutes: []int{0}}
+
+	times := UpcomingRunTimes(job, schedule, from, until, 10)
+	assert.Equal(t, []time.Time{
+		time.Date(2026, time.January, 15, 15, 0, 0, 0, time.UTC),
+		time.Date(2026, time.January, 15, 16, 0, 0, 0, time.UTC),
+	}, utcTimes(times))
+
+	assert.Len(t, UpcomingRunTimes(job, schedule, from, until, 1), 1, "capped at max")
+
+	job.SchedulePaused = true
+	assert.Empty(t, UpcomingRunTimes(job, schedule, from, until, 10))
+}
+
+// utcTimes converts times to UTC so they compare equal to UTC literals
+func utcTimes(times []time.Time) []time.Time {
+	out := make([]time.Time, len(times))
+	for i, t := range times {
+		out[i] = t.UTC()
+	}
+	return out
+}
+
+// TestMatchesSkipsHolidays tests that a business-day schedule does not fire on a listed holiday
+func TestMatchesSkipsHolidays(t *testing.T) {
+	m := NewMatcher()
+	schedule := &store.Schedule{
+		Weekdays: []int{1, 2, 3, 4, 5},
+		Hours:    []int{9},
+		Minutes:  []int{0},
+		Holidays: []string{"2024-12-25"},
+	}
+
+	assert.True(t, m.Matches(time.Date(2024, 12, 24, 9, 0, 0, 0, time.UTC), schedule))
+	assert.False(t, m.Matches(time.Date(2024, 12, 25, 9, 0, 0, 0, time.UTC), schedule), "holiday is skipped")
+
+	// The next fire after Christmas Eve skips the holiday (a Wednesday) to Thursday
+	next := m.NextScheduledTime(schedule, time.Date(2024, 12, 24, 9, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 12, 26, 9, 0, 0, 0, time.UTC), next)
+}
+
+// TestMatchesSolarAnchor tests that a sunset-anchored schedule fires at that day's
+// computed sunset, shifted by its offset, and ignores other minutes
+func TestMatchesSolarAnchor(t *testing.T) {
+	m := NewMatcher()
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+	schedule := &store.Schedule{
+		Solar: &store.SolarAnchor{Event: "sunset", Latitude: 51.5074, Longitude: -0.1278},
+	}
+
+	// Sunset in London on the June solstice is at 21:21 BST
+	sunset, ok := solarEventTime(time.Date(2024, 6, 21, 0, 0, 0, 0, london), schedule.Solar)
+	assert.True(t, ok)
+	assert.Equal(t, "21:21", sunset.Format("15:04"))
+
+	assert.True(t, m.Matches(time.Date(2024, 6, 21, 21, 21, 30, 0, london), schedule))
+	assert.False(t, m.Matches(time.Date(2024, 6, 21, 21, 20, 0, 0, london), schedule))
+	assert.False(t, m.Matches(time.Date(2024, 6, 21, 21, 22, 0, 0, london), schedule))
+
+	// In winter the same anchor moves to mid-afternoon
+	next := m.NextScheduledTime(schedule, time.Date(2024, 12, 21, 12, 0, 0, 0, london))
+	assert.Equal(t, "2024-12-21 15:53", next.In(london).Format("2006-01-02 15:04"))
+
+	// An offset shifts the fire time; half an hour before sunset
+	schedule.Solar.OffsetMinutes = -30
+	assert.True(t, m.Matches(time.Date(2024, 6, 21, 20, 51, 0, 0, london), schedule))
+	assert.False(t, m.Matches(time.Date(2024, 6, 21, 21, 21, 0, 0, london), schedule))
+
+	// The sun does not set in the Arctic summer, so nothing fires that day
+	schedule.Solar = &store.SolarAnchor{Event: "sunset", Latitude: 78.2, Longitude: 15.6}
+	_, ok = solarEventTime(time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC), schedule.Solar)
+	assert.False(t, ok)
+}
+
+// TestMinFireInterval tests the shortest gap between fires, including across midnight
+func TestMinFireInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule *store.Schedule
+		want     time.Duration
+		ok       bool
+	}{
+		{"every minute", &store.Schedule{}, time.Minute, true},
+		{"every 15 minutes", &store.Schedule{Minutes: []int{0, 15, 30, 45}}, 15 * time.Minute, true},
+		{"uneven minutes", &store.Schedule{Minutes: []int{0, 10, 50}}, 10 * time.Minute, true},
+		{"across midnight", &store.Schedule{Hours: []int{0, 23}, Minutes: []int{0, 58}}, 2 * time.Minute, true},
+		{"once a day on weekdays", &store.Schedule{Weekdays: []int{1}, Hours: []int{6}, Minutes: []int{0}}, 24 * time.Hour, true},
+		{"never fires", &store.Schedule{Solar: &store.SolarAnchor{Event: "sunset", Latitude: 89, Longitude: 0}}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gap, ok := MinFireInterval(tt.schedule)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, gap)
+		})
+	}
+}
+
+// TestFiresAtDSTTransitions tests that a daily schedule fires exactly once on the US DST
+// transition days: at the end of the gap when its time is skipped, and only on the first
+// pass when its time is repeated
+func TestFiresAtDSTTransitions(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+	m := NewMatcher()
+
+	// fires lists the instants the schedule fires during the local calendar day
+	fires := func(day time.Time, schedule *store.Schedule) []time.Time {
+		var out []time.Time
+		for t := day; t.Before(day.AddDate(0, 0, 1)); t = t.Add(time.Minute) {
+			if m.FiresAt(t, newYork, schedule) {
+				out = append(out, t.UTC())
+			}
+		}
+		return out
+	}
+	springForward := time.Date(2024, time.March, 10, 0, 0, 0, 0, newYork)
+	fallBack := time.Date(2024, time.November, 3, 0, 0, 0, 0, newYork)
+
+	// 02:30 does not exist on March 10; it fires at 03:00 EDT instead
+	at0230 := &store.Schedule{Hours: []int{2}, Minutes: []int{30}}
+	assert.Equal(t, []time.Time{time.Date(2024, time.March, 10, 7, 0, 0, 0, time.UTC)}, fires(springForward, at0230))
+	assert.Equal(t, []time.Time{time.Date(2024, time.November, 3, 7, 30, 0, 0, time.UTC)}, fires(fallBack, at0230))
+
+	// 01:30 happens twice on November 3; only the first (EDT) one fires
+	at0130 := &store.Schedule{Hours: []int{1}, Minutes: []int{30}}
+	assert.Equal(t, []time.Time{time.Date(2024, time.November, 3, 5, 30, 0, 0, time.UTC)}, fires(fallBack, at0130))
+	assert.Equal(t, []time.Time{time.Date(2024, time.March, 10, 6, 30, 0, 0, time.UTC)}, fires(springForward, at0130))
+
+	// The stored next run agrees with the tick-by-tick matching
+	job := &store.Job{Enabled: true, ScheduleEnabled: true, Timezone: "America/New_York"}
+	next := NextRunTime(job, at0230, springForward)
+	require.NotNil(t, next)
+	assert.Equal(t, "2024-03-10 03:00 EDT", next.In(newYork).Format("2006-01-02 15:04 MST"))
+	next = NextRunTime(job, at0130, time.Date(2024, time.November, 3, 5, 30, 0, 0, time.UTC))
+	require.NotNil(t, next)
+	assert.Equal(t, "2024-11-04 01:30 EST", next.In(newYork).Format("2006-01-02 15:04 MST"), "the repeated 01:30 is skipped")
+}
+
+// TestNextFireTimeMatchesMinuteScan tests that jumping between candidates finds the same
+// fires as checking FiresAt minute by minute, across both US DST transitions
+func TestNextFireTimeMatchesMinuteScan(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+	m := NewMatcher()
+
+	schedules := map[string]*store.Schedule{
+		"every minute":   {},
+		"02:30 daily":    {Hours: []int{2}, Minutes: []int{30}},
+		"01:30 daily":    {Hours: []int{1}, Minutes: []int{30}},
+		"quarter hourly": {Minutes: []int{0, 15, 30, 45}},
+		"sunday 02:15":   {Weekdays: []int{0}, Hours: []int{2}, Minutes: []int{15}},
+	}
+	days := []time.Time{
+		time.Date(2024, time.March, 9, 22, 0, 0, 0, newYork),
+		time.Date(2024, time.November, 2, 22, 0, 0, 0, newYork),
+	}
+
+	for name, schedule := range schedules {
+		t.Run(name, func(t *testing.T) {
+			for _, start := range days {
+				want := start.Truncate(time.Minute)
+				for i := 0; i < 5; i++ {
+					from := want
+					for want = from.Add(time.Minute); !m.FiresAt(want, newYork, schedule); want = want.Add(time.Minute) {
+					}
+					got := m.NextFireTime(schedule, newYork, from)
+					require.True(t, want.Equal(got), "after %s: want %s, got %s", from.In(newYork), want.In(newYork), got.In(newYork))
+				}
+			}
+		})
+	}
+}
+
+// TestNextRunTimeImpossibleSchedule tests that a schedule that never fires has no next run
+func TestNextRunTimeImpossibleSchedule(t *testing.T) {
+	job := &store.Job{Enabled: true, ScheduleEnabled: true, Timezone: "America/New_York"}
+	assert.Nil(t, NextRunTime(job, &store.Schedule{Months: []int{2}, Days: []int{30}}, time.Now()))
+}
+
+func BenchmarkNextRunTimeYearly(b *testing.B) {
+	job := &store.Job{Enabled: true, ScheduleEnabled: true, Timezone: "America/New_York"}
+	schedule := &store.Schedule{Months: []int{1}, Days: []int{1}, Hours: []int{0}, Minutes: []int{0}}
+	from := time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < b.N; i++ {
+		NextRunTime(job, schedule, from)
+	}
+}
@@ -0,0 +1,28 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// oneShotTime returns the minute a one-shot schedule fires in
+func oneShotTime(schedule *store.Schedule) time.Time {
+	return schedule.RunAt.Truncate(time.Minute)
+}
+
+// oneShotPending reports whether the schedule is a one-shot that has not fired yet. A
+// one-shot is consumed once the scheduler records a fire at or after its run_at, so
+// saving a later run_at arms it again.
+func oneShotPending(schedule *store.Schedule) bool {
+	if schedule.RunAt == nil {
+		return false
+	}
+	return schedule.LastScheduledAt == nil || schedule.LastScheduledAt.Before(oneShotTime(schedule))
+}
+
+// oneShotDue reports whether a pending one-shot should fire at now. A run_at missed while
+// the server was down fires on the first tick after, rather than being dropped.
+func oneShotDue(schedule *store.Schedule, now time.Time) bool {
+	return oneShotPending(schedule) && !now.Before(oneShotTime(schedule))
+}
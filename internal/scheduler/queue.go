@@ -1,6 +1,8 @@
 package scheduler
 
 import (
+	"context"
+	"errors"
 	"log"
 	"sync"
 
@@ -14,30 +16,178 @@ type QueueItem struct {
 	Run *store.Run // Optional: if set, use this run instead of creating a new one
 }
 
-// JobQueue manages sequential job execution
+// ErrQueueFull is returned when the reject overflow policy turns a job away
+var ErrQueueFull = errors.New("job queue is full")
+
+// ErrNotQueued is returned when a run to reorder is not waiting in the queue
+var ErrNotQueued = errors.New("run is not in the job queue")
+
+// ErrAlreadyQueued is returned when a pre-created run is enqueued while it already waits
+var ErrAlreadyQueued = errors.New("run is already in the job queue")
+
+// JobQueue manages sequential job execution. Items wait in a slice rather than a channel
+// so a queued run can be moved ahead of the others.
 type JobQueue struct {
-	items   chan *QueueItem
-	running bool
-	mu      sync.RWMutex
-	done    chan struct{}
+	running  bool
+	mu       sync.RWMutex
+	inflight sync.WaitGroup
+
+	// items holds up to internal.JobQueueChannelSize queued items, head first. itemsMu
+	// guards it; notEmpty wakes the worker and notFull wakes blocked enqueuers.
+	items    []*QueueItem
+	itemsMu  sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	stopped  bool
+
+	// overflow decides what enqueueing does when items is full; onEvict is told about
+	// items the drop_oldest policy discards
+	overflow string
+	onEvict  func(item *QueueItem)
 }
 
 // NewJobQueue creates a new job queue
 func NewJobQueue() *JobQueue {
-	return &JobQueue{
-		items: make(chan *QueueItem, internal.JobQueueChannelSize),
-		done:  make(chan struct{}),
+	jq := &JobQueue{
+		items:    make([]*QueueItem, 0, internal.JobQueueChannelSize),
+		overflow: internal.QueueOverflowBlock,
 	}
+	jq.notEmpty = sync.NewCond(&jq.itemsMu)
+	jq.notFull = sync.NewCond(&jq.itemsMu)
+	return jq
+}
+
+// SetOverflowPolicy sets what enqueueing does when the queue is full: wait for room
+// (block), return ErrQueueFull (reject) or evict the oldest queued item (drop_oldest)
+func (jq *JobQueue) SetOverflowPolicy(policy string) {
+	jq.mu.Lock()
+	defer jq.mu.Unlock()
+	jq.overflow = policy
+}
+
+// SetEvictHandler sets the callback for items evicted by the drop_oldest policy
+func (jq *JobQueue) SetEvictHandler(fn func(item *QueueItem)) {
+	jq.mu.Lock()
+	defer jq.mu.Unlock()
+	jq.onEvict = fn
 }
 
 // Enqueue adds a job to the queue (creates new run during execution)
-func (jq *JobQueue) Enqueue(job *store.Job) {
-	jq.items <- &QueueItem{Job: job, Run: nil}
+func (jq *JobQueue) Enqueue(job *store.Job) error {
+	return jq.enqueue(&QueueItem{Job: job, Run: nil})
 }
 
 // EnqueueWithRun adds a job with a pre-created run to the queue
-func (jq *JobQueue) EnqueueWithRun(job *store.Job, run *store.Run) {
-	jq.items <- &QueueItem{Job: job, Run: run}
+func (jq *JobQueue) EnqueueWithRun(job *store.Job, run *store.Run) error {
+	return jq.enqueue(&QueueItem{Job: job, Run: run})
+}
+
+// enqueue adds item, applying the overflow policy if the queue is full. Only the block
+// policy ever waits, so the scheduler loop can't stall behind a stuck worker.
+func (jq *JobQueue) enqueue(item *QueueItem) error {
+	jq.mu.RLock()
+	policy, onEvict := jq.overflow, jq.onEvict
+	jq.mu.RUnlock()
+
+	jq.itemsMu.Lock()
+	if item.Run != nil && jq.indexOf(item.Run.ID) >= 0 {
+		jq.itemsMu.Unlock()
+		return ErrAlreadyQueued
+	}
+	var evicted *QueueItem
+	if len(jq.items) >= internal.JobQueueChannelSize {
+		switch policy {
+		case internal.QueueOverflowReject:
+			jq.itemsMu.Unlock()
+			log.Printf("Job queue full, rejected job %s\n", item.Job.ID)
+			return ErrQueueFull
+		case internal.QueueOverflowDropOldest:
+			evicted = jq.items[0]
+			jq.items = jq.items[1:]
+		default:
+			for len(jq.items) >= internal.JobQueueChannelSize && !jq.stopped {
+				jq.notFull.Wait()
+			}
+			// Another caller may have queued the same run while this one waited
+			if item.Run != nil && jq.indexOf(item.Run.ID) >= 0 {
+				jq.itemsMu.Unlock()
+				return ErrAlreadyQueued
+			}
+		}
+	}
+	jq.items = append(jq.items, item)
+	jq.notEmpty.Signal()
+	jq.itemsMu.Unlock()
+
+	if evicted != nil {
+		log.Printf("Job queue full, evicted oldest queued job %s to make room for job %s\n", evicted.Job.ID, item.Job.ID)
+		if onEvict != nil {
+			onEvict(evicted)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of queued items
+func (jq *JobQueue) Len() int {
+	jq.itemsMu.Lock()
+	defer jq.itemsMu.Unlock()
+	return len(jq.items)
+}
+
+// MoveToFront moves the queued item holding runID to the head of the queue, so it is
+// the next one executed. It returns ErrNotQueued if no queued item holds that run.
+func (jq *JobQueue) MoveToFront(runID string) error {
+	jq.itemsMu.Lock()
+	defer jq.itemsMu.Unlock()
+
+	i := jq.indexOf(runID)
+	if i < 0 {
+		return ErrNotQueued
+	}
+	item := jq.items[i]
+	copy(jq.items[1:i+1], jq.items[:i])
+	jq.items[0] = item
+	return nil
+}
+
+// indexOf returns the position of the queued item holding runID, or -1. The caller
+// holds itemsMu.
+func (jq *JobQueue) indexOf(runID string) int {
+	for i, item := range jq.items {
+		if item.Run != nil && item.Run.ID == runID {
+			return i
+		}
+	}
+	return -1
+}
+
+// next removes and returns the head of the queue, waiting for one while the queue is
+// empty. It returns nil once the queue is stopped.
+func (jq *JobQueue) next() *QueueItem {
+	jq.itemsMu.Lock()
+	defer jq.itemsMu.Unlock()
+
+	for len(jq.items) == 0 && !jq.stopped {
+		jq.notEmpty.Wait()
+	}
+	if jq.stopped {
+		return nil
+	}
+	return jq.take()
+}
+
+// take removes and returns the head of the queue, or nil if it is empty. The caller
+// holds itemsMu.
+func (jq *JobQueue) take() *QueueItem {
+	if len(jq.items) == 0 {
+		return nil
+	}
+	item := jq.items[0]
+	jq.items[0] = nil
+	jq.items = jq.items[1:]
+	jq.notFull.Signal()
+	return item
 }
 
 // Start begins processing queued jobs
@@ -48,26 +198,70 @@ func (jq *JobQueue) Start(handler func(*store.Job, *store.Run) error) {
 
 	go func() {
 		for {
-			select {
-			case item := <-jq.items:
-				if item != nil && item.Job != nil {
-					if err := handler(item.Job, item.Run); err != nil {
-						log.Printf("Error handling job %s: %v\n", item.Job.ID, err)
-					}
-				}
-			case <-jq.done:
+			item := jq.next()
+			if item == nil {
 				return
 			}
+			if item.Job == nil {
+				continue
+			}
+
+			if !jq.begin(item) {
+				return
+			}
+
+			if err := handler(item.Job, item.Run); err != nil {
+				log.Printf("Error handling job %s: %v\n", item.Job.ID, err)
+			}
+			jq.inflight.Done()
 		}
 	}()
 }
 
+// begin registers item as in-flight work, under the lock so Stop/Wait never race with
+// Add. If the queue was stopped after item was taken, it goes back to the head of the
+// queue so its pre-created run is not stranded and begin reports false.
+func (jq *JobQueue) begin(item *QueueItem) bool {
+	jq.mu.Lock()
+	defer jq.mu.Unlock()
+	if !jq.running {
+		jq.itemsMu.Lock()
+		jq.items = append([]*QueueItem{item}, jq.items...)
+		jq.itemsMu.Unlock()
+		return false
+	}
+	jq.inflight.Add(1)
+	return true
+}
+
 // Stop stops the queue
 func (jq *JobQueue) Stop() {
 	jq.mu.Lock()
 	jq.running = false
 	jq.mu.Unlock()
-	close(jq.done)
+
+	jq.itemsMu.Lock()
+	jq.stopped = true
+	jq.notEmpty.Broadcast()
+	jq.notFull.Broadcast()
+	jq.itemsMu.Unlock()
+}
+
+// Wait blocks until the in-flight job (if any) finishes or ctx is done.
+// Call after Stop so no new jobs are started while waiting.
+func (jq *JobQueue) Wait(ctx context.Context) error {
+	finished := make(chan struct{})
+	go func() {
+		jq.inflight.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // IsRunning returns true if the queue is running
@@ -0,0 +1,137 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	internal "github.com/taskflow/taskflow/internal"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// TestJobQueueWaitForInflightJob tests that Wait blocks until the running job finishes
+func TestJobQueueWaitForInflightJob(t *testing.T) {
+	jq := NewJobQueue()
+
+	started := make(chan struct{})
+	var finished atomic.Bool
+	jq.Start(func(job *store.Job, run *store.Run) error {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		finished.Store(true)
+		return nil
+	})
+
+	jq.EnqueueWithRun(&store.Job{ID: "job"}, &store.Run{ID: "run"})
+	<-started
+	jq.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, jq.Wait(ctx))
+	assert.True(t, finished.Load(), "Wait must not return before the in-flight job finishes")
+}
+
+// TestJobQueueWaitTimeout tests that Wait gives up when its context expires
+func TestJobQueueWaitTimeout(t *testing.T) {
+	jq := NewJobQueue()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	jq.Start(func(job *store.Job, run *store.Run) error {
+		close(started)
+		<-release
+		return nil
+	})
+	defer close(release)
+
+	jq.EnqueueWithRun(&store.Job{ID: "job"}, &store.Run{ID: "run"})
+	<-started
+	jq.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, jq.Wait(ctx), context.DeadlineExceeded)
+}
+
+// TestJobQueueDropOldest tests that the drop_oldest policy evicts the head of a full queue
+// and reports it to the evict handler
+func TestJobQueueDropOldest(t *testing.T) {
+	jq := NewJobQueue()
+	jq.SetOverflowPolicy(internal.QueueOverflowDropOldest)
+	var evicted []string
+	jq.SetEvictHandler(func(item *QueueItem) {
+		evicted = append(evicted, item.Run.ID)
+	})
+
+	for i := 0; i <= internal.JobQueueChannelSize; i++ {
+		require.NoError(t, jq.EnqueueWithRun(&store.Job{ID: "job"}, &store.Run{ID: fmt.Sprintf("run-%d", i)}))
+	}
+
+	assert.Equal(t, []string{"run-0"}, evicted)
+	require.Len(t, jq.items, internal.JobQueueChannelSize)
+	assert.Equal(t, "run-1", jq.take().Run.ID)
+
+	jq.SetOverflowPolicy(internal.QueueOverflowReject)
+	require.NoError(t, jq.EnqueueWithRun(&store.Job{ID: "job"}, &store.Run{ID: "fits"}))
+	assert.ErrorIs(t, jq.EnqueueWithRun(&store.Job{ID: "job"}, &store.Run{ID: "rejected"}), ErrQueueFull)
+}
+
+// TestJobQueueRejectsDuplicateRun tests that a pre-created run cannot wait in the queue twice
+func TestJobQueueRejectsDuplicateRun(t *testing.T) {
+	jq := NewJobQueue()
+	require.NoError(t, jq.EnqueueWithRun(&store.Job{ID: "job"}, &store.Run{ID: "run-a"}))
+	assert.ErrorIs(t, jq.EnqueueWithRun(&store.Job{ID: "job"}, &store.Run{ID: "run-a"}), ErrAlreadyQueued)
+	assert.Equal(t, 1, jq.Len())
+}
+
+// TestJobQueueMoveToFront tests that a run moved to the front is dispatched before the
+// runs queued ahead of it
+func TestJobQueueMoveToFront(t *testing.T) {
+	jq := NewJobQueue()
+	for _, id := range []string{"run-a", "run-b", "run-c"} {
+		require.NoError(t, jq.EnqueueWithRun(&store.Job{ID: "job"}, &store.Run{ID: id}))
+	}
+
+	require.NoError(t, jq.MoveToFront("run-c"))
+	assert.ErrorIs(t, jq.MoveToFront("run-missing"), ErrNotQueued)
+
+	var mu sync.Mutex
+	var order []string
+	jq.Start(func(job *store.Job, run *store.Run) error {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, run.ID)
+		return nil
+	})
+	defer jq.Stop()
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(order) == 3
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"run-c", "run-a", "run-b"}, order)
+}
+
+// TestJobQueueStopKeepsTakenItem tests that an item the worker took just before Stop is
+// put back at the head of the queue instead of being dropped
+func TestJobQueueStopKeepsTakenItem(t *testing.T) {
+	jq := NewJobQueue()
+	for _, id := range []string{"run-a", "run-b"} {
+		require.NoError(t, jq.EnqueueWithRun(&store.Job{ID: "job"}, &store.Run{ID: id}))
+	}
+
+	item := jq.next()
+	require.NotNil(t, item)
+	jq.Stop()
+
+	assert.False(t, jq.begin(item))
+	require.Equal(t, 2, jq.Len())
+	assert.Equal(t, "run-a", jq.take().Run.ID)
+}
@@ -0,0 +1,72 @@
+package scheduler
+
+import (
+	"sync"
+
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// scheduleCache holds each job's schedule between ticks so the scheduler does not
+// re-read every schedule from the database every minute. Entries are dropped by
+// invalidate when a schedule changes and reloaded on the next lookup. Changes saved
+// through another instance are not seen here, so the whole cache is cleared whenever
+// this instance takes the scheduler lease.
+type scheduleCache struct {
+	mu        sync.Mutex
+	schedules map[string]*store.Schedule
+	// gen is bumped on every invalidation so a lookup that raced with one
+	// does not cache the schedule it read before the change
+	gen uint64
+}
+
+func newScheduleCache() *scheduleCache {
+	return &scheduleCache{schedules: make(map[string]*store.Schedule)}
+}
+
+// get returns the cached schedule for jobID, loading and caching it on a miss
+func (c *scheduleCache) get(jobID string, load func(string) (*store.Schedule, error)) (*store.Schedule, error) {
+	c.mu.Lock()
+	if schedule, ok := c.schedules[jobID]; ok {
+		c.mu.Unlock()
+		return schedule, nil
+	}
+	gen := c.gen
+	c.mu.Unlock()
+
+	// Load outside the lock so invalidate never waits on the database
+	schedule, err := load(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.gen == gen {
+		c.schedules[jobID] = schedule
+	}
+	c.mu.Unlock()
+	return schedule, nil
+}
+
+// invalidate drops the cached schedule for jobID
+func (c *scheduleCache) invalidate(jobID string) {
+	c.mu.Lock()
+	delete(c.schedules, jobID)
+	c.gen++
+	c.mu.Unlock()
+}
+
+// clear drops every cached schedule
+func (c *scheduleCache) clear() {
+	c.mu.Lock()
+	clear(c.schedules)
+	c.gen++
+	c.mu.Unlock()
+}
+
+// cached reports whether a schedule for jobID is currently cached
+func (c *scheduleCache) cached(jobID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.schedules[jobID]
+	return ok
+}
@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// TestScheduleCacheIgnoresLoadRacingInvalidate tests that a schedule read before an
+// invalidation is returned but not cached, so the change is picked up next time
+func TestScheduleCacheIgnoresLoadRacingInvalidate(t *testing.T) {
+	c := newScheduleCache()
+	loads := 0
+
+	stale := func(jobID string) (*store.Schedule, error) {
+		loads++
+		// The schedule is saved while this (now stale) read is in flight
+		c.invalidate(jobID)
+		return &store.Schedule{JobID: jobID, Hours: []int{1}}, nil
+	}
+	schedule, err := c.get("job-1", stale)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, schedule.Hours)
+	assert.False(t, c.cached("job-1"))
+
+	fresh := func(jobID string) (*store.Schedule, error) {
+		loads++
+		return &store.Schedule{JobID: jobID, Hours: []int{2}}, nil
+	}
+	schedule, err = c.get("job-1", fresh)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, schedule.Hours)
+	assert.True(t, c.cached("job-1"))
+
+	// Served from the cache without another load
+	_, err = c.get("job-1", fresh)
+	require.NoError(t, err)
+	assert.Equal(t, 2, loads)
+}
@@ -16,23 +16,70 @@ type Scheduler struct {
 	store   *store.Store
 	queue   *JobQueue
 	matcher *Matcher
+	cache   *scheduleCache
 	ticker  *time.Ticker
 	done    chan struct{}
 	mu      sync.RWMutex
 	running bool
+
+	// restartGrace suppresses re-firing jobs that ran shortly before a restart
+	restartGrace time.Duration
+	startedAt    time.Time
+
+	// check runs one scheduling pass; tests replace it to simulate slow ticks
+	check func()
+	ticks *tickMonitor
+
+	// Only the instance holding the scheduler lease fires jobs
+	instanceID   string
+	leader       bool
+	leaseChecked bool
 }
 
 // New creates a new scheduler
 func New(st *store.Store) *Scheduler {
-	return &Scheduler{
+	s := &Scheduler{
 		store:   st,
 		queue:   NewJobQueue(),
 		matcher: NewMatcher(),
+		cache:   newScheduleCache(),
 		ticker:  time.NewTicker(internal.SchedulerCheckInterval),
 		done:    make(chan struct{}),
+
+		restartGrace: time.Duration(internal.DefaultRestartGraceSeconds) * time.Second,
+		ticks:        newTickMonitor(),
+		instanceID:   newInstanceID(),
+	}
+	s.check = s.checkAndScheduleJobs
+	s.queue.SetEvictHandler(s.cancelEvicted)
+	return s
+}
+
+// SetQueueOverflowPolicy sets what happens when a job is enqueued while the queue is full:
+// internal.QueueOverflowBlock, QueueOverflowReject or QueueOverflowDropOldest
+func (s *Scheduler) SetQueueOverflowPolicy(policy string) {
+	s.queue.SetOverflowPolicy(policy)
+}
+
+// cancelEvicted cancels the pending run of an item evicted from a full queue, so it
+// doesn't stay pending forever
+func (s *Scheduler) cancelEvicted(item *QueueItem) {
+	if item.Run == nil {
+		return
+	}
+	if _, err := s.store.CancelPendingRun(item.Run.ID, "Evicted from the full job queue (drop_oldest policy)"); err != nil {
+		log.Printf("Failed to cancel evicted run %s: %v\n", item.Run.ID, err)
 	}
 }
 
+// SetRestartGrace sets how long after startup a job whose last run started
+// within that window is not fired again. Zero disables the check.
+func (s *Scheduler) SetRestartGrace(grace time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.restartGrace = grace
+}
+
 // Start begins the scheduling loop
 func (s *Scheduler) Start(ctx context.Context, handler func(*store.Job, *store.Run) error) error {
 	s.mu.Lock()
@@ -41,9 +88,16 @@ func (s *Scheduler) Start(ctx context.Context, handler func(*store.Job, *store.R
 		return fmt.Errorf("scheduler is already running")
 	}
 	s.running = true
+	s.startedAt = time.Now()
 	s.mu.Unlock()
 
-	s.queue.Start(handler)
+	s.queue.Start(func(job *store.Job, run *store.Run) error {
+		if status, stale := s.leftPendingWhileQueued(run); stale {
+			log.Printf("Skipping run %s of job %s: it became %s while queued\n", run.ID, job.ID, status)
+			return nil
+		}
+		return handler(job, run)
+	})
 
 	go s.run(ctx)
 
@@ -64,6 +118,7 @@ func (s *Scheduler) Stop() {
 	close(s.done)
 	s.ticker.Stop()
 	s.queue.Stop()
+	s.releaseLease()
 
 	log.Println("Scheduler stopped")
 }
@@ -73,7 +128,7 @@ func (s *Scheduler) run(ctx context.Context) {
 	for {
 		select {
 		case <-s.ticker.C:
-			s.checkAndScheduleJobs()
+			s.tick()
 		case <-s.done:
 			return
 		case <-ctx.Done():
@@ -82,28 +137,52 @@ func (s *Scheduler) run(ctx context.Context) {
 	}
 }
 
-// checkAndScheduleJobs checks all jobs and schedules those that should run
+// checkAndScheduleJobs schedules the jobs that should run this minute. Only jobs whose
+// next_run_at has come are read, so the tick's cost follows the number of due jobs.
+// Instances without the scheduler lease do nothing.
 func (s *Scheduler) checkAndScheduleJobs() {
-	jobs, err := s.store.ListJobs(nil)
-	if err != nil {
-		log.Printf("Failed to list jobs: %v\n", err)
+	now := time.Now()
+	if !s.holdLease(now) {
 		return
 	}
+	s.computeMissingNextRuns(now)
 
-	now := time.Now()
+	jobs, err := s.store.ListDueJobs(now)
+	if err != nil {
+		log.Printf("Failed to list due jobs: %v\n", err)
+		return
+	}
 
 	for _, job := range jobs {
-		if !job.Enabled {
+		schedule, err := s.cache.get(job.ID, s.loadSchedule)
+		if err != nil {
+			log.Printf("Failed to get schedule for job %s: %v\n", job.ID, err)
 			continue
 		}
 
-		schedule, err := s.store.GetJobSchedule(job.ID)
-		if err != nil {
-			log.Printf("Failed to get schedule for job %s: %v\n", job.ID, err)
+		// A next_run_at left in an earlier minute means that fire was missed (the server
+		// was down or a tick was late); missed fires are not caught up. Jobs skipped
+		// below keep theirs and are looked at again next tick. Schedules are matched in
+		// the job's timezone, firing once across DST gaps and repeats. One-shots are not
+		// matched at all: they fire at the first tick at or after run_at, then never again.
+		var due bool
+		if schedule.RunAt != nil {
+			due = oneShotDue(schedule, now)
+		} else {
+			due = s.matcher.FiresAt(now, jobLocation(job), schedule)
+		}
+		if !due {
+			s.setNextRun(job, schedule, now)
 			continue
 		}
 
-		if !s.matcher.Matches(now, schedule) {
+		if job.SchedulePaused {
+			log.Printf("Skipping job %s: schedule paused after a failed run (pause_on_failure)\n", job.ID)
+			continue
+		}
+
+		if window, ok := activeBlackout(job, now); ok {
+			log.Printf("Skipping job %s: inside blackout window %s-%s\n", job.ID, window.Start, window.End)
 			continue
 		}
 
@@ -111,13 +190,110 @@ func (s *Scheduler) checkAndScheduleJobs() {
 			continue
 		}
 
-		s.queue.Enqueue(job)
+		if s.ranWithinRestartGrace(job.ID, now) {
+			log.Printf("Skipping job %s: last run is within the restart grace window\n", job.ID)
+			continue
+		}
+
+		// The run is created up front so a queued scheduled fire is visible as a pending
+		// run, e.g. to the manual_while_scheduled policy
+		run, err := s.store.CreateRun(job.ID, internal.TriggerScheduled)
+		if err != nil {
+			log.Printf("Failed to create run for job %s: %v\n", job.ID, err)
+			continue
+		}
+		if err := s.queue.EnqueueWithRun(job, run); err != nil {
+			log.Printf("Skipping scheduled run %s of job %s: %v\n", run.ID, job.ID, err)
+			if _, err := s.store.CancelPendingRun(run.ID, "Skipped: the job queue was full (reject policy)"); err != nil {
+				log.Printf("Failed to cancel skipped run %s: %v\n", run.ID, err)
+			}
+			continue
+		}
+		if err := s.store.RecordScheduleFire(job.ID, now); err != nil {
+			log.Printf("Failed to record schedule fire for job %s: %v\n", job.ID, err)
+		}
+		if schedule.RunAt != nil {
+			// The recorded fire consumes the one-shot; the cached copy predates it
+			s.cache.invalidate(job.ID)
+			fired := *schedule
+			fired.LastScheduledAt = &now
+			schedule = &fired
+			log.Printf("One-shot schedule of job %s fired and is now consumed\n", job.ID)
+		}
+		s.setNextRun(job, schedule, now)
+	}
+}
+
+// computeMissingNextRuns sets next_run_at for jobs whose job or schedule changed since
+// the last tick. The current minute counts, so a job saved just before a matching tick fires.
+func (s *Scheduler) computeMissingNextRuns(now time.Time) {
+	jobs, err := s.store.ListJobsWithoutNextRun()
+	if err != nil {
+		log.Printf("Failed to list jobs without a next run: %v\n", err)
+		return
+	}
+
+	from := now.Truncate(time.Minute).Add(-time.Minute)
+	for _, job := range jobs {
+		// A NULL next_run_at means the job or schedule was saved, possibly by another
+		// instance whose invalidation never reached this cache
+		s.cache.invalidate(job.ID)
+		schedule, err := s.cache.get(job.ID, s.loadSchedule)
+		if err != nil {
+			log.Printf("Failed to get schedule for job %s: %v\n", job.ID, err)
+			continue
+		}
+		s.setNextRun(job, schedule, from)
+	}
+}
+
+// setNextRun stores the job's first fire time after from. A schedule that matches nothing
+// within a year is looked at again a year on.
+func (s *Scheduler) setNextRun(job *store.Job, schedule *store.Schedule, from time.Time) {
+	next := from.AddDate(1, 0, 0)
+	if t := NextRunTime(job, schedule, from); t != nil {
+		next = *t
+	}
+	if err := s.store.SetJobNextRunAt(job.ID, next); err != nil {
+		log.Printf("Failed to set next run for job %s: %v\n", job.ID, err)
+	}
+}
+
+// loadSchedule reads a job's schedule for the cache, warning once per load about
+// stored fields that could not be parsed
+func (s *Scheduler) loadSchedule(jobID string) (*store.Schedule, error) {
+	schedule, err := s.store.GetJobSchedule(jobID)
+	if err == nil && len(schedule.CorruptFields) > 0 {
+		log.Printf("Warning: job %s has corrupt schedule fields %v, treating them as any; re-save the schedule to fix\n", jobID, schedule.CorruptFields)
+	}
+	return schedule, err
+}
+
+// InvalidateSchedule drops the scheduler's cached schedule for a job so the next tick
+// uses the saved schedule. It only touches the cache, so it never blocks on the
+// scheduling loop and is safe to call from request handlers.
+func (s *Scheduler) InvalidateSchedule(jobID string) {
+	s.cache.invalidate(jobID)
+}
+
+// leftPendingWhileQueued reports whether a pre-created run stopped being pending after
+// it was enqueued, e.g. cancelled by a manual trigger or already executed, and its status
+func (s *Scheduler) leftPendingWhileQueued(run *store.Run) (string, bool) {
+	if run == nil {
+		return "", false
 	}
+	current, err := s.store.GetRun(run.ID)
+	if err != nil || current.Status == internal.JobStatusPending {
+		return "", false
+	}
+	return current.Status, true
 }
 
-// alreadyRanThisMinute checks if a job has already run in the current minute
+// alreadyRanThisMinute checks if a job has already run in the current minute. Minutes
+// are compared as instants, not wall-clock times, so the repeated hour of a DST fall-back
+// does not look like the first pass; FiresAt keeps that hour from firing twice.
 func (s *Scheduler) alreadyRanThisMinute(jobID string, now time.Time) bool {
-	runs, err := s.store.ListRuns(&jobID, 1, 0)
+	runs, err := s.store.ListRuns(store.RunFilter{JobID: jobID}, 1, 0)
 	if err != nil || len(runs) == 0 {
 		return false
 	}
@@ -127,10 +303,35 @@ func (s *Scheduler) alreadyRanThisMinute(jobID string, now time.Time) bool {
 		return false
 	}
 
-	// Use time.Truncate for cleaner minute-precision comparison
+	// time.Truncate works on absolute time, so the timezones of the two don't matter
 	return lastRun.StartedAt.Truncate(time.Minute).Equal(now.Truncate(time.Minute))
 }
 
+// ranWithinRestartGrace reports whether the scheduler was started recently and the
+// job's last run began within the grace window, regardless of minute boundaries.
+// Only applied right after startup so minute-level schedules keep firing normally.
+func (s *Scheduler) ranWithinRestartGrace(jobID string, now time.Time) bool {
+	s.mu.RLock()
+	grace, startedAt := s.restartGrace, s.startedAt
+	s.mu.RUnlock()
+
+	if grace <= 0 || now.Sub(startedAt) > grace {
+		return false
+	}
+
+	runs, err := s.store.ListRuns(store.RunFilter{JobID: jobID}, 1, 0)
+	if err != nil || len(runs) == 0 || runs[0].StartedAt == nil {
+		return false
+	}
+
+	return now.Sub(*runs[0].StartedAt) < grace
+}
+
+// Wait blocks until the currently executing job finishes or ctx is done
+func (s *Scheduler) Wait(ctx context.Context) error {
+	return s.queue.Wait(ctx)
+}
+
 // IsRunning returns true if scheduler is running
 func (s *Scheduler) IsRunning() bool {
 	s.mu.RLock()
@@ -138,12 +339,25 @@ func (s *Scheduler) IsRunning() bool {
 	return s.running
 }
 
-// Enqueue adds a job to the execution queue (for scheduled triggers)
-func (s *Scheduler) Enqueue(job *store.Job) {
-	s.queue.Enqueue(job)
+// Enqueue adds a job to the execution queue; its run is created when it executes.
+// It returns ErrQueueFull if the queue is full under the reject policy.
+func (s *Scheduler) Enqueue(job *store.Job) error {
+	return s.queue.Enqueue(job)
+}
+
+// EnqueueWithRun adds a job with a pre-created run to the queue.
+// It returns ErrQueueFull if the queue is full under the reject policy.
+func (s *Scheduler) EnqueueWithRun(job *store.Job, run *store.Run) error {
+	return s.queue.EnqueueWithRun(job, run)
+}
+
+// MoveToFront makes a queued run the next one executed. It returns ErrNotQueued if the
+// run is not waiting in the queue.
+func (s *Scheduler) MoveToFront(runID string) error {
+	return s.queue.MoveToFront(runID)
 }
 
-// EnqueueWithRun adds a job with a pre-created run to the queue (for manual triggers)
-func (s *Scheduler) EnqueueWithRun(job *store.Job, run *store.Run) {
-	s.queue.EnqueueWithRun(job, run)
+// QueueLength returns the number of runs waiting in the queue
+func (s *Scheduler) QueueLength() int {
+	return s.queue.Len()
 }
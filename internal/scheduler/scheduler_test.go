@@ -0,0 +1,530 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	internal "github.com/taskflow/taskflow/internal"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// TestCheckAndScheduleJobsSkipsDisabledSchedule tests that a schedule-disabled job is not
+// enqueued by the scheduler but can still be enqueued manually
+func TestCheckAndScheduleJobsSkipsDisabledSchedule(t *testing.T) {
+	st := store.NewTestStore(t)
+	defer st.Close()
+
+	job, err := st.CreateJob(&store.Job{Name: "paused", Script: "echo hi", Enabled: true})
+	require.NoError(t, err)
+	// An empty schedule matches every minute
+	require.NoError(t, st.SetJobSchedule(job.ID, &store.Schedule{JobID: job.ID}))
+
+	s := New(st)
+	defer s.ticker.Stop()
+
+	s.checkAndScheduleJobs()
+	require.Len(t, s.queue.items, 1, "enabled schedule should be enqueued")
+	s.queue.take()
+
+	require.NoError(t, st.SetJobScheduleEnabled(job.ID, false))
+
+	s.checkAndScheduleJobs()
+	assert.Len(t, s.queue.items, 0, "disabled schedule should not be enqueued")
+
+	run, err := st.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+	s.EnqueueWithRun(job, run)
+
+	require.Len(t, s.queue.items, 1, "manual trigger should still be enqueued")
+	item := s.queue.take()
+	assert.Equal(t, run.ID, item.Run.ID)
+}
+
+// TestRestartGraceSuppressesRecentRun tests that right after a restart a job whose last run
+// started within the grace window is not fired again, even across a minute boundary
+func TestRestartGraceSuppressesRecentRun(t *testing.T) {
+	st := store.NewTestStore(t)
+	defer st.Close()
+
+	job, err := st.CreateJob(&store.Job{Name: "every-minute", Script: "echo hi", Enabled: true})
+	require.NoError(t, err)
+	require.NoError(t, st.SetJobSchedule(job.ID, &store.Schedule{JobID: job.ID}))
+
+	// Last run started in the previous minute (1-61s ago), just before the process restarted
+	run, err := st.CreateRun(job.ID, "scheduled")
+	require.NoError(t, err)
+	startedAt := time.Now().Truncate(time.Minute).Add(-time.Second)
+	run.StartedAt = &startedAt
+	run.Status = "success"
+	require.NoError(t, st.UpdateRun(run))
+
+	// Simulate a fresh restart
+	s := New(st)
+	defer s.ticker.Stop()
+	s.SetRestartGrace(90 * time.Second)
+	s.startedAt = time.Now()
+
+	s.checkAndScheduleJobs()
+	assert.Len(t, s.queue.items, 0, "recent run should suppress an immediate re-fire after restart")
+
+	// Once the scheduler has been up longer than the grace window, normal scheduling resumes
+	s.startedAt = time.Now().Add(-2 * time.Minute)
+	s.checkAndScheduleJobs()
+	assert.Len(t, s.queue.items, 1)
+}
+
+// TestCheckAndScheduleJobsRecordsFire tests that enqueueing a scheduled run updates fire tracking
+func TestCheckAndScheduleJobsRecordsFire(t *testing.T) {
+	st := store.NewTestStore(t)
+	defer st.Close()
+
+	job, err := st.CreateJob(&store.Job{Name: "tracked", Script: "echo hi", Enabled: true})
+	require.NoError(t, err)
+	require.NoError(t, st.SetJobSchedule(job.ID, &store.Schedule{JobID: job.ID}))
+
+	s := New(st)
+	defer s.ticker.Stop()
+
+	before := time.Now()
+	s.checkAndScheduleJobs()
+	require.Len(t, s.queue.items, 1)
+
+	schedule, err := st.GetJobSchedule(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, schedule.ScheduledFireCount)
+	require.NotNil(t, schedule.LastScheduledAt)
+	assert.False(t, schedule.LastScheduledAt.Before(before.Truncate(time.Second)))
+
+	// Editing the schedule keeps the tracking intact
+	require.NoError(t, st.SetJobSchedule(job.ID, &store.Schedule{JobID: job.ID, Hours: []int{3}}))
+	schedule, err = st.GetJobSchedule(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, schedule.ScheduledFireCount)
+	assert.Equal(t, []int{3}, schedule.Hours)
+}
+
+// TestCheckAndScheduleJobsUsesNextRunAt tests that a tick only fires jobs whose next_run_at
+// has come and advances next_run_at past the minute it fired in
+func TestCheckAndScheduleJobsUsesNextRunAt(t *testing.T) {
+	st := store.NewTestStore(t)
+	defer st.Close()
+
+	job, err := st.CreateJob(&store.Job{Name: "every-minute", Script: "echo hi", Enabled: true})
+	require.NoError(t, err)
+	require.NoError(t, st.SetJobSchedule(job.ID, &store.Schedule{JobID: job.ID}))
+
+	s := New(st)
+	defer s.ticker.Stop()
+
+	now := time.Now()
+	s.checkAndScheduleJobs()
+	require.Len(t, s.queue.items, 1)
+
+	next, err := st.GetJobNextRunAt(job.ID)
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	assert.True(t, next.After(now), "next_run_at should move past the minute that fired")
+	assert.True(t, next.Before(now.Add(2*time.Minute)), "every-minute schedule is due again within a minute, got %v", next)
+
+	// A job that matches now but is not due yet is not even read
+	require.NoError(t, st.SetJobNextRunAt(job.ID, now.Add(time.Hour)))
+	s.checkAndScheduleJobs()
+	assert.Len(t, s.queue.items, 1)
+}
+
+// TestCheckAndScheduleJobsSkipsBlackout tests that a job whose fire time falls inside a
+// blackout window is not enqueued, and fires normally once the window no longer applies
+func TestCheckAndScheduleJobsSkipsBlackout(t *testing.T) {
+	st := store.NewTestStore(t)
+	defer st.Close()
+
+	now := time.Now().UTC()
+	job, err := st.CreateJob(&store.Job{
+		Name:     "nightly",
+		Script:   "echo hi",
+		Enabled:  true,
+		Timezone: "UTC",
+		BlackoutWindows: []store.BlackoutWindow{{
+			Start: now.Add(-30 * time.Minute).Format("15:04"),
+			End:   now.Add(30 * time.Minute).Format("15:04"),
+		}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, st.SetJobSchedule(job.ID, &store.Schedule{JobID: job.ID}))
+
+	s := New(st)
+	defer s.ticker.Stop()
+
+	s.checkAndScheduleJobs()
+	assert.Len(t, s.queue.items, 0, "fire inside a blackout should be skipped")
+
+	// Move the window away from the current time
+	job.BlackoutWindows = []store.BlackoutWindow{{
+		Start: now.Add(2 * time.Hour).Format("15:04"),
+		End:   now.Add(3 * time.Hour).Format("15:04"),
+	}}
+	require.NoError(t, st.UpdateJob(job))
+
+	s.checkAndScheduleJobs()
+	assert.Len(t, s.queue.items, 1, "fire outside the blackout should be enqueued")
+}
+
+// TestCheckAndScheduleJobsSkipsPausedSchedule tests that a schedule paused by
+// pause_on_failure doesn't fire until it is resumed
+func TestCheckAndScheduleJobsSkipsPausedSchedule(t *testing.T) {
+	st := store.NewTestStore(t)
+	defer st.Close()
+
+	job, err := st.CreateJob(&store.Job{Name: "ingest", Script: "exit 1", Enabled: true, Timezone: "UTC", PauseOnFailure: true})
+	require.NoError(t, err)
+	require.NoError(t, st.SetJobSchedule(job.ID, &store.Schedule{JobID: job.ID}))
+	paused, err := st.PauseScheduleOnFailure(job.ID)
+	require.NoError(t, err)
+	require.True(t, paused)
+
+	s := New(st)
+	defer s.ticker.Stop()
+
+	s.checkAndScheduleJobs()
+	assert.Len(t, s.queue.items, 0, "a paused schedule should not fire")
+
+	// Re-enabling the schedule is how an admin resumes it
+	require.NoError(t, st.SetJobScheduleEnabled(job.ID, true))
+	s.checkAndScheduleJobs()
+	assert.Len(t, s.queue.items, 1, "a resumed schedule fires again")
+}
+
+// TestSetJobScheduleInvalidatesCache tests that saving a schedule drops the scheduler's
+// cached copy so the very next tick uses the new schedule
+func TestSetJobScheduleInvalidatesCache(t *testing.T) {
+	st := store.NewTestStore(t)
+	defer st.Close()
+
+	s := New(st)
+	defer s.ticker.Stop()
+	st.SetScheduleChangeHook(s.InvalidateSchedule)
+
+	job, err := st.CreateJob(&store.Job{Name: "report", Script: "echo hi", Enabled: true, Timezone: "UTC"})
+	require.NoError(t, err)
+
+	// An hour that is never the current one
+	otherHour := (time.Now().UTC().Hour() + 12) % 24
+	require.NoError(t, st.SetJobSchedule(job.ID, &store.Schedule{JobID: job.ID, Hours: []int{otherHour}}))
+
+	s.checkAndScheduleJobs()
+	assert.Len(t, s.queue.items, 0)
+	assert.True(t, s.cache.cached(job.ID), "schedule should be cached after a tick")
+
+	// Every minute
+	require.NoError(t, st.SetJobSchedule(job.ID, &store.Schedule{JobID: job.ID}))
+	assert.False(t, s.cache.cached(job.ID), "saving the schedule should invalidate the cache entry")
+
+	s.checkAndScheduleJobs()
+	assert.Len(t, s.queue.items, 1, "the updated schedule should apply on the next tick")
+}
+
+// TestScheduledRunCancelledWhileQueued tests that a scheduled fire creates a pending run
+// up front and that the run is dropped if it is cancelled before the queue reaches it
+func TestScheduledRunCancelledWhileQueued(t *testing.T) {
+	st := store.NewTestStore(t)
+	defer st.Close()
+
+	job, err := st.CreateJob(&store.Job{Name: "replaced", Script: "echo hi", Enabled: true})
+	require.NoError(t, err)
+	require.NoError(t, st.SetJobSchedule(job.ID, &store.Schedule{JobID: job.ID}))
+
+	s := New(st)
+	s.checkAndScheduleJobs()
+	require.Len(t, s.queue.items, 1)
+
+	active, err := st.ListActiveRuns(job.ID, internal.TriggerScheduled)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	cancelled, err := st.CancelPendingRun(active[0].ID, "Replaced by a manual trigger")
+	require.NoError(t, err)
+	require.True(t, cancelled)
+
+	handled := make(chan string, 2)
+	require.NoError(t, s.Start(context.Background(), func(job *store.Job, run *store.Run) error {
+		handled <- run.ID
+		return nil
+	}))
+	defer s.Stop()
+
+	manual, err := st.CreateRun(job.ID, internal.TriggerManual)
+	require.NoError(t, err)
+	s.EnqueueWithRun(job, manual)
+
+	select {
+	case id := <-handled:
+		assert.Equal(t, manual.ID, id, "the cancelled scheduled run must not execute")
+	case <-time.After(2 * time.Second):
+		t.Fatal("manual run was not handled")
+	}
+}
+
+// TestQueuedRunSkippedOnceNoLongerPending tests that the worker drops a queued run that
+// already finished, e.g. executed from an earlier queue entry
+func TestQueuedRunSkippedOnceNoLongerPending(t *testing.T) {
+	st := store.NewTestStore(t)
+	defer st.Close()
+
+	job, err := st.CreateJob(&store.Job{Name: "finished", Script: "echo hi", Enabled: true})
+	require.NoError(t, err)
+
+	s := New(st)
+	stale, err := st.CreateRun(job.ID, internal.TriggerManual)
+	require.NoError(t, err)
+	require.NoError(t, s.EnqueueWithRun(job, stale))
+	stale.Status = internal.JobStatusSuccess
+	require.NoError(t, st.UpdateRun(stale))
+
+	handled := make(chan string, 2)
+	require.NoError(t, s.Start(context.Background(), func(job *store.Job, run *store.Run) error {
+		handled <- run.ID
+		return nil
+	}))
+	defer s.Stop()
+
+	next, err := st.CreateRun(job.ID, internal.TriggerManual)
+	require.NoError(t, err)
+	require.NoError(t, s.EnqueueWithRun(job, next))
+
+	select {
+	case id := <-handled:
+		assert.Equal(t, next.ID, id, "the finished run must not execute again")
+	case <-time.After(2 * time.Second):
+		t.Fatal("pending run was not handled")
+	}
+}
+
+// TestCheckAndScheduleJobsRejectsWhenQueueFull tests that under the reject policy a full queue
+// neither blocks the scheduler nor leaves the skipped run pending
+func TestCheckAndScheduleJobsRejectsWhenQueueFull(t *testing.T) {
+	st := store.NewTestStore(t)
+	defer st.Close()
+
+	job, err := st.CreateJob(&store.Job{Name: "overflow", Script: "echo hi", Enabled: true})
+	require.NoError(t, err)
+	require.NoError(t, st.SetJobSchedule(job.ID, &store.Schedule{JobID: job.ID}))
+
+	s := New(st)
+	defer s.ticker.Stop()
+	s.SetQueueOverflowPolicy(internal.QueueOverflowReject)
+
+	// No worker is running, so the queue stays full like during a stalled execution
+	for i := 0; i < internal.JobQueueChannelSize; i++ {
+		require.NoError(t, s.EnqueueWithRun(&store.Job{ID: "filler"}, &store.Run{ID: fmt.Sprintf("filler-%d", i)}))
+	}
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	done := make(chan struct{})
+	go func() {
+		s.checkAndScheduleJobs()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("scheduler blocked on the full queue")
+	}
+
+	assert.Len(t, s.queue.items, internal.JobQueueChannelSize)
+	assert.Contains(t, logs.String(), "Skipping scheduled run")
+	assert.Contains(t, logs.String(), job.ID)
+
+	runs, err := st.ListRuns(store.RunFilter{JobID: job.ID}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, internal.JobStatusCancelled, runs[0].Status)
+	require.NotNil(t, runs[0].ErrorMsg)
+	assert.Contains(t, *runs[0].ErrorMsg, "queue was full")
+}
+
+// TestSlowTickWarning tests that a scheduling pass longer than the threshold logs a
+// warning, alerts once per cooldown and is recorded in the tick stats
+func TestSlowTickWarning(t *testing.T) {
+	st := store.NewTestStore(t)
+	defer st.Close()
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	s := New(st)
+	s.check = func() { time.Sleep(30 * time.Millisecond) }
+	s.SetSlowTickThreshold(10 * time.Millisecond)
+	var alerts []TickAlert
+	s.SetTickAlertHandler(func(alert TickAlert) { alerts = append(alerts, alert) })
+
+	s.tick()
+	s.tick()
+
+	assert.Contains(t, logs.String(), "Warning: scheduler tick at")
+	require.Len(t, alerts, 1, "a second slow tick within the cooldown must not alert again")
+	assert.Equal(t, TickAlertSlow, alerts[0].Kind)
+	assert.GreaterOrEqual(t, alerts[0].Duration, 30*time.Millisecond)
+
+	stats := s.TickStats()
+	assert.Equal(t, 2, stats.SlowTicks)
+	assert.Len(t, stats.RecentDurationsMs, 2)
+	assert.GreaterOrEqual(t, stats.MaxDurationMs, int64(30))
+	require.NotNil(t, stats.LastTickAt)
+}
+
+// TestMissedTickWarning tests that a gap of more than two intervals between ticks is
+// reported with the number of minute windows that were never checked
+func TestMissedTickWarning(t *testing.T) {
+	m := newTickMonitor()
+	start := time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+
+	_, ok := m.begin(start)
+	assert.False(t, ok)
+	_, ok = m.begin(start.Add(2 * time.Minute))
+	assert.False(t, ok, "two intervals is within the tolerated gap")
+
+	alert, ok := m.begin(start.Add(6 * time.Minute))
+	require.True(t, ok)
+	assert.Equal(t, TickAlertMissed, alert.Kind)
+	assert.Equal(t, 3, alert.Missed)
+	assert.Equal(t, 3, m.missedTicks)
+}
+
+// TestSecondInstanceStandsByWhileLeaseHeld tests that a second scheduler sharing the
+// database does not fire jobs while the first holds an active lease, and takes over
+// once that lease lapses
+func TestSecondInstanceStandsByWhileLeaseHeld(t *testing.T) {
+	st := store.NewTestStore(t)
+	defer st.Close()
+
+	job, err := st.CreateJob(&store.Job{Name: "every-minute", Script: "echo hi", Enabled: true, Timezone: "UTC"})
+	require.NoError(t, err)
+	require.NoError(t, st.SetJobSchedule(job.ID, &store.Schedule{JobID: job.ID}))
+
+	first := New(st)
+	defer first.ticker.Stop()
+	second := New(st)
+	defer second.ticker.Stop()
+
+	// The first instance takes the lease, so the second one fires nothing this tick,
+	// even with the job still due
+	ok, err := st.AcquireSchedulerLease(first.InstanceID(), time.Now(), time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+	second.checkAndScheduleJobs()
+	assert.False(t, second.IsLeader())
+	assert.Equal(t, 0, second.QueueLength(), "standby instance must not schedule")
+	runs, err := st.ListRuns(store.RunFilter{JobID: job.ID}, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+
+	first.checkAndScheduleJobs()
+	assert.True(t, first.IsLeader())
+	assert.Equal(t, 1, first.QueueLength())
+
+	// Once the first instance stops renewing and the lease expires, the second takes over
+	ok, err = st.AcquireSchedulerLease(first.InstanceID(), time.Now().Add(-time.Hour), time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+	second.checkAndScheduleJobs()
+	assert.True(t, second.IsLeader())
+	lease, err := st.GetSchedulerLease()
+	require.NoError(t, err)
+	require.NotNil(t, lease)
+	assert.Equal(t, second.InstanceID(), lease.Holder)
+}
+
+// TestNewLeaderSeesScheduleSavedThroughOtherInstance tests that an instance taking over the
+// lease does not fire from a schedule it cached before another instance saved a new one
+func TestNewLeaderSeesScheduleSavedThroughOtherInstance(t *testing.T) {
+	st := store.NewTestStore(t)
+	defer st.Close()
+
+	first := New(st)
+	defer first.ticker.Stop()
+	second := New(st)
+	defer second.ticker.Stop()
+	// Saves go through the first instance, so only its cache hears about them
+	st.SetScheduleChangeHook(first.InvalidateSchedule)
+
+	job, err := st.CreateJob(&store.Job{Name: "report", Script: "echo hi", Enabled: true, Timezone: "UTC"})
+	require.NoError(t, err)
+	otherHour := (time.Now().UTC().Hour() + 12) % 24
+	require.NoError(t, st.SetJobSchedule(job.ID, &store.Schedule{JobID: job.ID, Hours: []int{otherHour}}))
+
+	// The second instance leads first and caches the old schedule
+	second.checkAndScheduleJobs()
+	require.True(t, second.IsLeader())
+	require.True(t, second.cache.cached(job.ID))
+
+	// The first takes over once that lease lapses, and the schedule is changed through it
+	_, err = st.AcquireSchedulerLease(second.InstanceID(), time.Now().Add(-time.Hour), time.Minute)
+	require.NoError(t, err)
+	first.checkAndScheduleJobs()
+	require.True(t, first.IsLeader())
+	second.checkAndScheduleJobs()
+	require.False(t, second.IsLeader())
+	require.NoError(t, st.SetJobSchedule(job.ID, &store.Schedule{JobID: job.ID}))
+	assert.True(t, second.cache.cached(job.ID), "the standby's copy is now stale")
+
+	// When the second instance leads again it drops its stale copies and fires the new schedule
+	_, err = st.AcquireSchedulerLease(first.InstanceID(), time.Now().Add(-time.Hour), time.Minute)
+	require.NoError(t, err)
+	require.True(t, second.holdLease(time.Now()))
+	assert.False(t, second.cache.cached(job.ID), "taking the lease should clear the cache")
+	second.checkAndScheduleJobs()
+	assert.Equal(t, 1, second.QueueLength(), "the new every-minute schedule should fire")
+}
+
+// TestOneShotFiresOnce tests that a run_at schedule waits for its time, fires once when it
+// comes and is then consumed rather than firing again
+func TestOneShotFiresOnce(t *testing.T) {
+	st := store.NewTestStore(t)
+	defer st.Close()
+
+	job, err := st.CreateJob(&store.Job{Name: "once", Script: "echo once", Enabled: true})
+	require.NoError(t, err)
+	later := time.Now().Add(time.Hour).Truncate(time.Minute)
+	require.NoError(t, st.SetJobSchedule(job.ID, &store.Schedule{JobID: job.ID, RunAt: &later}))
+
+	s := New(st)
+	defer s.ticker.Stop()
+
+	s.checkAndScheduleJobs()
+	assert.Len(t, s.queue.items, 0, "a one-shot must not fire before run_at")
+	next, err := st.GetJobNextRunAt(job.ID)
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	assert.True(t, next.Equal(later))
+
+	// Its time has come (a couple of minutes ago, as after a short outage)
+	due := time.Now().Add(-2 * time.Minute).Truncate(time.Minute)
+	require.NoError(t, st.SetJobSchedule(job.ID, &store.Schedule{JobID: job.ID, RunAt: &due}))
+	s.InvalidateSchedule(job.ID)
+
+	s.checkAndScheduleJobs()
+	require.Len(t, s.queue.items, 1)
+	assert.Equal(t, job.ID, s.queue.take().Job.ID)
+
+	schedule, err := st.GetJobSchedule(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, schedule.ScheduledFireCount)
+	assert.False(t, oneShotPending(schedule))
+	assert.Nil(t, NextRunTime(job, schedule, time.Now()))
+
+	// Even when looked at again, a consumed one-shot stays quiet
+	require.NoError(t, st.SetJobNextRunAt(job.ID, due))
+	s.checkAndScheduleJobs()
+	assert.Len(t, s.queue.items, 0, "a consumed one-shot must not fire again")
+	assert.False(t, oneShotDue(schedule, time.Now().Add(time.Hour)))
+}
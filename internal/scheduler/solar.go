@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"math"
+	"time"
+
+	internal "github.com/taskflow/taskflow/internal"
+	"github.com/taskflow/taskflow/internal/store"
+)
+
+// julianUnixEpoch is the Julian date of 1970-01-01T00:00:00Z
+const julianUnixEpoch = 2440587.5
+
+// julian2000 is the Julian date of the J2000 epoch, 2000-01-01T12:00:00Z
+const julian2000 = 2451545.0
+
+// solarEventTime returns the anchor's sunrise or sunset, shifted by its offset, on the
+// calendar date of day in day's location. ok is false when the sun does not rise or set
+// there that day (polar day or night). Uses the sunrise equation, accurate to a minute
+// or two away from the poles, which is enough for a minute-resolution scheduler.
+func solarEventTime(day time.Time, anchor *store.SolarAnchor) (time.Time, bool) {
+	noon := time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, time.UTC)
+	n := float64(noon.Unix())/86400 + julianUnixEpoch - julian2000
+
+	// Mean solar time at the longitude, then the sun's position on the ecliptic
+	meanNoon := n - anchor.Longitude/360
+	anomaly := math.Mod(357.5291+0.98560028*meanNoon, 360)
+	center := 1.9148*sinDeg(anomaly) + 0.0200*sinDeg(2*anomaly) + 0.0003*sinDeg(3*anomaly)
+	longitude := math.Mod(anomaly+center+180+102.9372, 360)
+	transit := julian2000 + meanNoon + 0.0053*sinDeg(anomaly) - 0.0069*sinDeg(2*longitude)
+
+	sinDeclination := sinDeg(longitude) * sinDeg(23.4397)
+	cosDeclination := math.Cos(math.Asin(sinDeclination))
+	// -0.833 degrees allows for refraction and the sun's radius
+	cosHourAngle := (sinDeg(-0.833) - sinDeg(anchor.Latitude)*sinDeclination) / (cosDeg(anchor.Latitude) * cosDeclination)
+	if cosHourAngle < -1 || cosHourAngle > 1 {
+		return time.Time{}, false
+	}
+	hourAngle := math.Acos(cosHourAngle) * 180 / math.Pi
+
+	event := transit + hourAngle/360
+	if anchor.Event == internal.SolarEventSunrise {
+		event = transit - hourAngle/360
+	}
+
+	unix := (event - julianUnixEpoch) * 86400
+	t := time.Unix(0, int64(unix*float64(time.Second))).In(day.Location())
+	return t.Add(time.Duration(anchor.OffsetMinutes) * time.Minute), true
+}
+
+func sinDeg(deg float64) float64 {
+	return math.Sin(deg * math.Pi / 180)
+}
+
+func cosDeg(deg float64) float64 {
+	return math.Cos(deg * math.Pi / 180)
+}
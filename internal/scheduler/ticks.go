@@ -0,0 +1,183 @@
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	internal "github.com/taskflow/taskflow/internal"
+)
+
+// Kinds of scheduler tick alert
+const (
+	TickAlertSlow   = "slow"
+	TickAlertMissed = "missed"
+)
+
+// TickAlert describes a tick that ran longer than the slow-tick threshold, or a gap
+// between ticks long enough that whole minutes were never checked
+type TickAlert struct {
+	Kind     string        // TickAlertSlow or TickAlertMissed
+	At       time.Time     // when the tick started
+	Duration time.Duration // the tick's duration (slow) or the gap since the previous tick (missed)
+	Missed   int           // minute windows skipped (missed only)
+}
+
+// Message is a one-line, human-readable description of the alert
+func (a TickAlert) Message() string {
+	if a.Kind == TickAlertMissed {
+		return fmt.Sprintf("scheduler tick at %s came %s after the previous one; %d minute window(s) were not checked and their jobs did not fire",
+			a.At.Format(time.RFC3339), a.Duration.Round(time.Second), a.Missed)
+	}
+	return fmt.Sprintf("scheduler tick at %s took %s; jobs due in the next minute may be skipped",
+		a.At.Format(time.RFC3339), a.Duration.Round(time.Millisecond))
+}
+
+// TickStats reports recent scheduler tick timings for the status endpoint
+type TickStats struct {
+	LastTickAt        *time.Time `json:"last_tick_at"`
+	LastDurationMs    int64      `json:"last_duration_ms"`
+	MaxDurationMs     int64      `json:"max_duration_ms"`
+	RecentDurationsMs []int64    `json:"recent_durations_ms"`
+	SlowThresholdMs   int64      `json:"slow_threshold_ms"`
+	SlowTicks         int        `json:"slow_ticks"`
+	MissedTicks       int        `json:"missed_ticks"`
+}
+
+// tickMonitor times scheduler ticks, keeps the most recent durations and raises alerts
+// for slow or missed ticks, at most one per internal.SchedulerAlertCooldown
+type tickMonitor struct {
+	mu        sync.Mutex
+	interval  time.Duration
+	threshold time.Duration
+	onAlert   func(TickAlert)
+	lastAlert time.Time
+
+	lastTickAt   time.Time
+	lastDuration time.Duration
+	maxDuration  time.Duration
+	recent       []time.Duration
+	slowTicks    int
+	missedTicks  int
+}
+
+func newTickMonitor() *tickMonitor {
+	return &tickMonitor{
+		interval:  internal.SchedulerCheckInterval,
+		threshold: internal.DefaultSlowTickThreshold,
+	}
+}
+
+// SetSlowTickThreshold sets how long a tick may take before a slow-tick warning is
+// logged. Zero disables slow-tick warnings; missed ticks are still reported.
+func (s *Scheduler) SetSlowTickThreshold(threshold time.Duration) {
+	s.ticks.mu.Lock()
+	defer s.ticks.mu.Unlock()
+	s.ticks.threshold = threshold
+}
+
+// SetTickAlertHandler sets a function called with slow or missed tick alerts, e.g. to
+// notify admins. It runs on the scheduling loop, so it should not block.
+func (s *Scheduler) SetTickAlertHandler(handler func(TickAlert)) {
+	s.ticks.mu.Lock()
+	defer s.ticks.mu.Unlock()
+	s.ticks.onAlert = handler
+}
+
+// TickStats returns the scheduler's recent tick timings
+func (s *Scheduler) TickStats() TickStats {
+	m := s.ticks
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := TickStats{
+		LastDurationMs:    m.lastDuration.Milliseconds(),
+		MaxDurationMs:     m.maxDuration.Milliseconds(),
+		RecentDurationsMs: make([]int64, 0, len(m.recent)),
+		SlowThresholdMs:   m.threshold.Milliseconds(),
+		SlowTicks:         m.slowTicks,
+		MissedTicks:       m.missedTicks,
+	}
+	if !m.lastTickAt.IsZero() {
+		last := m.lastTickAt
+		stats.LastTickAt = &last
+	}
+	for _, d := range m.recent {
+		stats.RecentDurationsMs = append(stats.RecentDurationsMs, d.Milliseconds())
+	}
+	return stats
+}
+
+// tick runs one scheduling pass, timing it and checking the gap since the previous one
+func (s *Scheduler) tick() {
+	start := time.Now()
+	if alert, ok := s.ticks.begin(start); ok {
+		log.Printf("Warning: %s\n", alert.Message())
+		s.ticks.raise(alert)
+	}
+
+	s.check()
+
+	if alert, ok := s.ticks.end(start, time.Since(start)); ok {
+		log.Printf("Warning: %s\n", alert.Message())
+		s.ticks.raise(alert)
+	}
+}
+
+// begin records the start of a tick and reports a missed-tick alert when more than
+// internal.MissedTickFactor intervals have passed since the previous one
+func (m *tickMonitor) begin(start time.Time) (TickAlert, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	previous := m.lastTickAt
+	m.lastTickAt = start
+	if previous.IsZero() || m.interval <= 0 {
+		return TickAlert{}, false
+	}
+
+	gap := start.Sub(previous)
+	if gap <= time.Duration(internal.MissedTickFactor)*m.interval {
+		return TickAlert{}, false
+	}
+	missed := int(gap/m.interval) - 1
+	m.missedTicks += missed
+	return TickAlert{Kind: TickAlertMissed, At: start, Duration: gap, Missed: missed}, true
+}
+
+// end records a finished tick's duration and reports a slow-tick alert when it ran
+// longer than the threshold
+func (m *tickMonitor) end(start time.Time, duration time.Duration) (TickAlert, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastDuration = duration
+	if duration > m.maxDuration {
+		m.maxDuration = duration
+	}
+	m.recent = append(m.recent, duration)
+	if len(m.recent) > internal.TickDurationHistory {
+		m.recent = m.recent[len(m.recent)-internal.TickDurationHistory:]
+	}
+
+	if m.threshold <= 0 || duration <= m.threshold {
+		return TickAlert{}, false
+	}
+	m.slowTicks++
+	return TickAlert{Kind: TickAlertSlow, At: start, Duration: duration}, true
+}
+
+// raise passes an alert to the handler unless one was passed within the cooldown
+func (m *tickMonitor) raise(alert TickAlert) {
+	m.mu.Lock()
+	handler := m.onAlert
+	if handler == nil || (!m.lastAlert.IsZero() && alert.At.Sub(m.lastAlert) < internal.SchedulerAlertCooldown) {
+		m.mu.Unlock()
+		return
+	}
+	m.lastAlert = alert.At
+	m.mu.Unlock()
+
+	handler(alert)
+}
@@ -0,0 +1,144 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// agentSelect reads an agent with the number of its runs that are still pending or running
+const agentSelect = `SELECT a.id, a.name, a.url, a.capacity, a.last_heartbeat, a.created_at,
+	(SELECT COUNT(*) FROM runs r WHERE r.agent_id = a.id AND r.status IN ('pending', 'running'))
+	FROM agents a`
+
+// scanAgent scans a row selected with agentSelect into an Agent
+func scanAgent(row rowScanner) (*Agent, error) {
+	agent := &Agent{}
+	var lastHeartbeat, createdAt sql.NullTime
+	if err := row.Scan(&agent.ID, &agent.Name, &agent.URL, &agent.Capacity, &lastHeartbeat, &createdAt, &agent.ActiveRuns); err != nil {
+		return nil, err
+	}
+	if lastHeartbeat.Valid {
+		agent.LastHeartbeat = &lastHeartbeat.Time
+	}
+	if createdAt.Valid {
+		agent.CreatedAt = createdAt.Time
+	}
+	return agent, nil
+}
+
+// RegisterAgent adds an agent, or updates the name, URL and capacity of one already
+// registered under the same ID. Registering counts as a heartbeat.
+func (s *Store) RegisterAgent(id, name, url string, capacity int) (*Agent, error) {
+	_, err := s.db.Exec(
+		`INSERT INTO agents (id, name, url, capacity, last_heartbeat, created_at) VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(id) DO UPDATE SET name = excluded.name, url = excluded.url,
+		 capacity = excluded.capacity, last_heartbeat = excluded.last_heartbeat`,
+		id, name, url, capacity, time.Now(), time.Now(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register agent: %w", err)
+	}
+	return s.GetAgent(id)
+}
+
+// GetAgent retrieves an agent by ID
+func (s *Store) GetAgent(id string) (*Agent, error) {
+	agent, err := scanAgent(s.db.QueryRow(agentSelect+` WHERE a.id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.New("agent not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get agent: %w", err)
+	}
+	return agent, nil
+}
+
+// ListAgents returns every registered agent ordered by ID
+func (s *Store) ListAgents() ([]*Agent, error) {
+	rows, err := s.db.Query(agentSelect + ` ORDER BY a.id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agents: %w", err)
+	}
+	defer rows.Close()
+
+	var agents []*Agent
+	for rows.Next() {
+		agent, err := scanAgent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan agent: %w", err)
+		}
+		agents = append(agents, agent)
+	}
+	return agents, rows.Err()
+}
+
+// HeartbeatAgent records that an agent is still alive
+func (s *Store) HeartbeatAgent(id string) error {
+	result, err := s.db.Exec(`UPDATE agents SET last_heartbeat = ? WHERE id = ?`, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to record heartbeat: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return errors.New("agent not found")
+	}
+	return nil
+}
+
+// DeleteAgent unregisters an agent. Runs it already executed keep their agent_id.
+func (s *Store) DeleteAgent(id string) error {
+	result, err := s.db.Exec(`DELETE FROM agents WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete agent: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return errors.New("agent not found")
+	}
+	return nil
+}
+
+// AssignRunToAgent marks a run as running on a remote agent
+func (s *Store) AssignRunToAgent(runID, agentID string) error {
+	_, err := s.db.Exec(
+		`UPDATE runs SET agent_id = ?, status = 'running', started_at = ? WHERE id = ?`,
+		agentID, time.Now(), runID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to assign run to agent: %w", err)
+	}
+	return nil
+}
+
+// ListRunningAgentRuns retrieves the runs still running on remote agents, oldest first
+func (s *Store) ListRunningAgentRuns() ([]*Run, error) {
+	rows, err := s.db.Query(
+		`SELECT ` + runColumns + ` FROM runs
+		 WHERE agent_id IS NOT NULL AND status = 'running'
+		 ORDER BY started_at ASC, id ASC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agent runs: %w", err)
+	}
+	defer rows.Close()
+
+	return collectRuns(rows)
+}
+
+// FinishAgentRun moves a run still running on an agent to status and reports whether it
+// did. False means the run was already finished, by its agent's result or by the reaper,
+// so only one of them records the outcome.
+func (s *Store) FinishAgentRun(runID, status string) (bool, error) {
+	result, err := s.execRetry(
+		`UPDATE runs SET status = ? WHERE id = ? AND agent_id IS NOT NULL AND status = 'running'`,
+		status, runID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to finish agent run: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to finish agent run: %w", err)
+	}
+	return n > 0, nil
+}
@@ -1,7 +1,12 @@
 package store
 
 import (
+	"fmt"
+	"sort"
+	"strings"
 	"time"
+
+	internal "github.com/taskflow/taskflow/internal"
 )
 
 // DailyExecutionStats represents execution statistics for a single day
@@ -38,9 +43,235 @@ type DurationDataPoint struct {
 	RunCount    int    `json:"run_count"`
 }
 
+// UserUsage represents metered resource usage for runs of a user's jobs
+type UserUsage struct {
+	UserID     int     `json:"user_id"`
+	Since      string  `json:"since"`
+	RunCount   int     `json:"run_count"`
+	CPUSeconds float64 `json:"cpu_seconds"`
+}
+
+// JobSuccessRate is a job's success rate over finished runs created since a point in time.
+// Pending and running runs are not counted; cancelled runs count against the rate.
+type JobSuccessRate struct {
+	JobID          string  `json:"job_id"`
+	Since          string  `json:"since"`
+	TotalRuns      int     `json:"total_runs"`
+	SuccessCount   int     `json:"success_count"`
+	FailureCount   int     `json:"failure_count"` // failures and timeouts
+	CancelledCount int     `json:"cancelled_count"`
+	SuccessRate    float64 `json:"success_rate"`
+}
+
+// RunOverlap is a pair of a job's runs whose execution intervals overlapped.
+// First started before (or at the same time as) Second.
+type RunOverlap struct {
+	FirstRunID        string    `json:"first_run_id"`
+	SecondRunID       string    `json:"second_run_id"`
+	FirstStartedAt    time.Time `json:"first_started_at"`
+	FirstFinishedAt   time.Time `json:"first_finished_at"`
+	SecondStartedAt   time.Time `json:"second_started_at"`
+	SecondFinishedAt  time.Time `json:"second_finished_at"`
+	OverlapDurationMs int64     `json:"overlap_duration_ms"`
+}
+
+// InventoryItem is one distinct value in use across jobs and how many jobs use it
+type InventoryItem struct {
+	Value    string `json:"value"`
+	JobCount int    `json:"job_count"`
+}
+
+// JobInventory summarizes where and how jobs run
+type JobInventory struct {
+	WorkingDirs  []InventoryItem `json:"working_dirs"`
+	Interpreters []InventoryItem `json:"interpreters"` // "default" = platform default shell
+}
+
+// GetJobInventory returns the distinct working directories and interpreters used by jobs,
+// each with its job count, most used first
+func (s *Store) GetJobInventory() (*JobInventory, error) {
+	workingDirs, err := s.countJobsBy(`working_dir`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count working dirs: %w", err)
+	}
+	interpreters, err := s.countJobsBy(`COALESCE(NULLIF(interpreter, ''), 'default')`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count interpreters: %w", err)
+	}
+	return &JobInventory{WorkingDirs: workingDirs, Interpreters: interpreters}, nil
+}
+
+// countJobsBy groups jobs by a column expression
+func (s *Store) countJobsBy(expr string) ([]InventoryItem, error) {
+	rows, err := s.db.Query(`SELECT ` + expr + ` AS value, COUNT(*) AS job_count
+		FROM jobs GROUP BY value ORDER BY job_count DESC, value ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []InventoryItem{}
+	for rows.Next() {
+		var item InventoryItem
+		if err := rows.Scan(&item.Value, &item.JobCount); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetUserUsage aggregates run counts and CPU-seconds for jobs created by a user since the given time
+func (s *Store) GetUserUsage(userID int, since time.Time) (*UserUsage, error) {
+	usage := &UserUsage{UserID: userID, Since: since.UTC().Format(time.RFC3339)}
+
+	err := s.db.QueryRow(`
+		SELECT
+			COUNT(r.id),
+			COALESCE(SUM(r.cpu_seconds), 0)
+		FROM runs r
+		JOIN jobs j ON j.id = r.job_id
+		WHERE j.created_by = ?
+		AND r.created_at >= ?
+		AND r.trigger_type != ?
+	`, userID, since, internal.TriggerTest).Scan(&usage.RunCount, &usage.CPUSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
+
+// GetJobSuccessRate computes a job's success rate over its finished runs created since the given time
+func (s *Store) GetJobSuccessRate(jobID string, since time.Time) (*JobSuccessRate, error) {
+	rate := &JobSuccessRate{JobID: jobID, Since: since.UTC().Format(time.RFC3339)}
+
+	err := s.db.QueryRow(`
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(CASE WHEN status = 'success' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status IN ('failure', 'timeout') THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status = 'cancelled' THEN 1 ELSE 0 END), 0)
+		FROM runs
+		WHERE job_id = ?
+		AND status NOT IN (?, ?, ?)
+		AND created_at >= ?
+		AND trigger_type != ?
+	`, jobID, internal.JobStatusPending, internal.JobStatusRunning, internal.JobStatusAwaitingApproval,
+		since, internal.TriggerTest).Scan(&rate.TotalRuns, &rate.SuccessCount, &rate.FailureCount, &rate.CancelledCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if rate.TotalRuns > 0 {
+		rate.SuccessRate = float64(rate.SuccessCount) / float64(rate.TotalRuns)
+	}
+	return rate, nil
+}
+
+// GetJobRunOverlaps reports every pair of the job's finished runs, started since the given
+// time, whose [started_at, finished_at) intervals overlap
+func (s *Store) GetJobRunOverlaps(jobID string, since time.Time) ([]RunOverlap, error) {
+	runs, err := s.ListFinishedRunsSince(jobID, since)
+	if err != nil {
+		return nil, err
+	}
+	return findRunOverlaps(runs), nil
+}
+
+// findRunOverlaps sweeps runs in start order, comparing each run only with earlier
+// runs that were still executing when it started
+func findRunOverlaps(runs []*Run) []RunOverlap {
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].StartedAt.Before(*runs[j].StartedAt) })
+
+	overlaps := make([]RunOverlap, 0)
+	var active []*Run
+	for _, run := range runs {
+		start := *run.StartedAt
+
+		// Drop runs that finished before this one started; touching intervals do not overlap
+		kept := active[:0]
+		for _, prev := range active {
+			if prev.FinishedAt.After(start) {
+				kept = append(kept, prev)
+			}
+		}
+		active = kept
+
+		for _, prev := range active {
+			end := *prev.FinishedAt
+			if run.FinishedAt.Before(end) {
+				end = *run.FinishedAt
+			}
+			if !end.After(start) {
+				continue
+			}
+			overlaps = append(overlaps, RunOverlap{
+				FirstRunID:        prev.ID,
+				SecondRunID:       run.ID,
+				FirstStartedAt:    *prev.StartedAt,
+				FirstFinishedAt:   *prev.FinishedAt,
+				SecondStartedAt:   start,
+				SecondFinishedAt:  *run.FinishedAt,
+				OverlapDurationMs: end.Sub(start).Milliseconds(),
+			})
+		}
+		active = append(active, run)
+	}
+	return overlaps
+}
+
+// excludeTestRuns leaves test runs (POST /jobs/{id}/test) out of run analytics
+const excludeTestRuns = ` AND trigger_type != '` + internal.TriggerTest + `'`
+
+// AnalyticsFilter scopes analytics to a subset of jobs. Empty fields do not filter, so the
+// zero value covers every job.
+type AnalyticsFilter struct {
+	JobID     string
+	CreatedBy *int   // only jobs created by this user
+	TagKey    string // with TagValue, only jobs whose metadata has TagKey set to TagValue
+	TagValue  string
+}
+
+// jobConditions returns the filter as " AND ..." conditions on jobs columns and their args
+func (f AnalyticsFilter) jobConditions() (string, []interface{}) {
+	var conds strings.Builder
+	args := []interface{}{}
+	if f.JobID != "" {
+		conds.WriteString(` AND id = ?`)
+		args = append(args, f.JobID)
+	}
+	if f.CreatedBy != nil {
+		conds.WriteString(` AND created_by = ?`)
+		args = append(args, *f.CreatedBy)
+	}
+	if f.TagKey != "" {
+		// Quote the key so dots in it are not read as nested paths
+		conds.WriteString(` AND json_extract(metadata, ?) = ?`)
+		args = append(args, `$."`+f.TagKey+`"`, f.TagValue)
+	}
+	return conds.String(), args
+}
+
+// runConditions returns the filter as an " AND ..." condition on runs.job_id and its args
+func (f AnalyticsFilter) runConditions() (string, []interface{}) {
+	conds, args := f.jobConditions()
+	if conds == "" {
+		return "", nil
+	}
+	return ` AND job_id IN (SELECT id FROM jobs WHERE 1 = 1` + conds + `)`, args
+}
+
 // GetExecutionTrends returns daily execution statistics for the specified number of days
 func (s *Store) GetExecutionTrends(days int) ([]*DailyExecutionStats, error) {
+	return s.GetExecutionTrendsFiltered(days, AnalyticsFilter{})
+}
+
+// GetExecutionTrendsFiltered returns daily execution statistics for runs of the jobs
+// matching filter
+func (s *Store) GetExecutionTrendsFiltered(days int, filter AnalyticsFilter) ([]*DailyExecutionStats, error) {
 	startDate := time.Now().AddDate(0, 0, -days).Format("2006-01-02")
+	runFilter, filterArgs := filter.runConditions()
 
 	query := `
 		SELECT
@@ -53,12 +284,12 @@ func (s *Store) GetExecutionTrends(days int) ([]*DailyExecutionStats, error) {
 		FROM runs
 		WHERE started_at IS NOT NULL
 		AND date(started_at) >= ?
-		AND status IN ('success', 'failure', 'timeout')
+		AND status IN ('success', 'failure', 'timeout')` + excludeTestRuns + runFilter + `
 		GROUP BY date(started_at)
 		ORDER BY date(started_at) ASC
 	`
 
-	rows, err := s.db.Query(query, startDate)
+	rows, err := s.db.Query(query, append([]interface{}{startDate}, filterArgs...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -83,6 +314,35 @@ func (s *Store) GetExecutionTrends(days int) ([]*DailyExecutionStats, error) {
 
 // GetJobStats returns execution statistics for all jobs
 func (s *Store) GetJobStats() ([]*JobStats, error) {
+	return s.queryJobStats(nil, "")
+}
+
+// GetJobStatsSince returns execution statistics for all jobs over runs created at or after since
+func (s *Store) GetJobStatsSince(since time.Time) ([]*JobStats, error) {
+	return s.queryJobStats(&since, "")
+}
+
+// GetJobStatsForJob returns execution statistics for a single job
+func (s *Store) GetJobStatsForJob(jobID string) (*JobStats, error) {
+	stats, err := s.queryJobStats(nil, "WHERE j.id = ?", jobID)
+	if err != nil {
+		return nil, err
+	}
+	if len(stats) == 0 {
+		return nil, fmt.Errorf("job not found")
+	}
+	return stats[0], nil
+}
+
+// queryJobStats aggregates finished runs per job, optionally limited to runs created since
+// a time and filtered by a WHERE clause on jobs j
+func (s *Store) queryJobStats(since *time.Time, where string, args ...interface{}) ([]*JobStats, error) {
+	runFilter := ""
+	if since != nil {
+		runFilter = "AND r.created_at >= ?"
+		args = append([]interface{}{*since}, args...)
+	}
+
 	query := `
 		SELECT
 			j.id,
@@ -96,11 +356,13 @@ func (s *Store) GetJobStats() ([]*JobStats, error) {
 			MAX(r.started_at) as last_run_at
 		FROM jobs j
 		LEFT JOIN runs r ON j.id = r.job_id AND r.status IN ('success', 'failure', 'timeout')
+			AND r.trigger_type != '` + internal.TriggerTest + `' ` + runFilter + `
+		` + where + `
 		GROUP BY j.id, j.name
 		ORDER BY total_runs DESC
 	`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -141,7 +403,7 @@ func (s *Store) GetJobDurationTrends(jobID string, days int) ([]*DurationDataPoi
 		AND started_at IS NOT NULL
 		AND date(started_at) >= ?
 		AND status IN ('success', 'failure', 'timeout')
-		AND duration_ms IS NOT NULL
+		AND duration_ms IS NOT NULL` + excludeTestRuns + `
 		GROUP BY date(started_at)
 		ORDER BY date(started_at) ASC
 	`
@@ -168,22 +430,39 @@ func (s *Store) GetJobDurationTrends(jobID string, days int) ([]*DurationDataPoi
 	return points, rows.Err()
 }
 
-// GetOverallStats returns overall system statistics
-func (s *Store) GetOverallStats() (map[string]interface{}, error) {
+// GetOverallStats returns overall system statistics. If since is non-nil, the run
+// totals and success rate only count runs created at or after it.
+func (s *Store) GetOverallStats(since *time.Time) (map[string]interface{}, error) {
+	return s.GetOverallStatsFiltered(since, AnalyticsFilter{})
+}
+
+// GetOverallStatsFiltered returns the statistics of GetOverallStats restricted to the jobs
+// matching filter and their runs
+func (s *Store) GetOverallStatsFiltered(since *time.Time, filter AnalyticsFilter) (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
+	runFilter, runArgs := filter.runConditions()
+	jobFilter, jobArgs := filter.jobConditions()
 
-	// Total runs and success rate
-	var totalRuns, successCount, failureCount int
-	var avgDuration float64
-	err := s.db.QueryRow(`
+	query := `
 		SELECT
 			COUNT(*),
 			COALESCE(SUM(CASE WHEN status = 'success' THEN 1 ELSE 0 END), 0),
 			COALESCE(SUM(CASE WHEN status IN ('failure', 'timeout') THEN 1 ELSE 0 END), 0),
 			COALESCE(AVG(duration_ms), 0)
 		FROM runs
-		WHERE status IN ('success', 'failure', 'timeout')
-	`).Scan(&totalRuns, &successCount, &failureCount, &avgDuration)
+		WHERE status IN ('success', 'failure', 'timeout')` + excludeTestRuns
+	args := []interface{}{}
+	if since != nil {
+		query += ` AND created_at >= ?`
+		args = append(args, *since)
+	}
+	query += runFilter
+	args = append(args, runArgs...)
+
+	// Total runs and success rate
+	var totalRuns, successCount, failureCount int
+	var avgDuration float64
+	err := s.db.QueryRow(query, args...).Scan(&totalRuns, &successCount, &failureCount, &avgDuration)
 	if err != nil {
 		return nil, err
 	}
@@ -203,8 +482,9 @@ func (s *Store) GetOverallStats() (map[string]interface{}, error) {
 	err = s.db.QueryRow(`
 		SELECT COUNT(*) FROM runs
 		WHERE started_at >= datetime('now', '-24 hours')
-		AND status IN ('success', 'failure', 'timeout')
-	`).Scan(&last24h)
+		AND status IN ('success', 'failure', 'timeout')`+excludeTestRuns+runFilter,
+		runArgs...,
+	).Scan(&last24h)
 	if err != nil {
 		return nil, err
 	}
@@ -215,16 +495,25 @@ func (s *Store) GetOverallStats() (map[string]interface{}, error) {
 	err = s.db.QueryRow(`
 		SELECT COUNT(*) FROM runs
 		WHERE started_at >= datetime('now', '-7 days')
-		AND status IN ('success', 'failure', 'timeout')
-	`).Scan(&last7d)
+		AND status IN ('success', 'failure', 'timeout')`+excludeTestRuns+runFilter,
+		runArgs...,
+	).Scan(&last7d)
 	if err != nil {
 		return nil, err
 	}
 	stats["runs_last_7d"] = last7d
 
+	// Runs currently executing
+	var running int
+	err = s.db.QueryRow(`SELECT COUNT(*) FROM runs WHERE status = 'running'`+excludeTestRuns+runFilter, runArgs...).Scan(&running)
+	if err != nil {
+		return nil, err
+	}
+	stats["running_now"] = running
+
 	// Total jobs
 	var totalJobs int
-	err = s.db.QueryRow(`SELECT COUNT(*) FROM jobs`).Scan(&totalJobs)
+	err = s.db.QueryRow(`SELECT COUNT(*) FROM jobs WHERE 1 = 1`+jobFilter, jobArgs...).Scan(&totalJobs)
 	if err != nil {
 		return nil, err
 	}
@@ -232,7 +521,7 @@ func (s *Store) GetOverallStats() (map[string]interface{}, error) {
 
 	// Active jobs (enabled)
 	var activeJobs int
-	err = s.db.QueryRow(`SELECT COUNT(*) FROM jobs WHERE enabled = 1`).Scan(&activeJobs)
+	err = s.db.QueryRow(`SELECT COUNT(*) FROM jobs WHERE enabled = 1`+jobFilter, jobArgs...).Scan(&activeJobs)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,206 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGetUserUsageSumsCPUSeconds tests that usage aggregates only the user's runs
+func TestGetUserUsageSumsCPUSeconds(t *testing.T) {
+	st := NewTestStore(t)
+	defer st.Close()
+
+	alice, err := st.CreateUser("alice", "alice@example.com", "hash", "user")
+	require.NoError(t, err)
+	bob, err := st.CreateUser("bob", "bob@example.com", "hash", "user")
+	require.NoError(t, err)
+
+	aliceJob, err := st.CreateJob(&Job{Name: "a", Script: "true", CreatedBy: alice.ID})
+	require.NoError(t, err)
+	bobJob, err := st.CreateJob(&Job{Name: "b", Script: "true", CreatedBy: bob.ID})
+	require.NoError(t, err)
+
+	addRun := func(jobID string, cpu float64) {
+		run, err := st.CreateRun(jobID, "manual")
+		require.NoError(t, err)
+		run.Status = "success"
+		run.CPUSeconds = &cpu
+		require.NoError(t, st.UpdateRun(run))
+	}
+	addRun(aliceJob.ID, 1.5)
+	addRun(aliceJob.ID, 2.25)
+	addRun(bobJob.ID, 10)
+
+	usage, err := st.GetUserUsage(alice.ID, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, usage.RunCount)
+	assert.InDelta(t, 3.75, usage.CPUSeconds, 0.0001)
+
+	future, err := st.GetUserUsage(alice.ID, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, future.RunCount)
+}
+
+// TestGetJobSuccessRate tests the success rate over a window, excluding unfinished runs,
+// runs before the window and other jobs' runs
+func TestGetJobSuccessRate(t *testing.T) {
+	st := NewTestStore(t)
+	defer st.Close()
+
+	job, err := st.CreateJob(&Job{Name: "etl", Script: "true"})
+	require.NoError(t, err)
+	other, err := st.CreateJob(&Job{Name: "other", Script: "true"})
+	require.NoError(t, err)
+
+	addRun := func(jobID, status string, age time.Duration) {
+		run, err := st.CreateRun(jobID, "scheduled")
+		require.NoError(t, err)
+		run.Status = status
+		require.NoError(t, st.UpdateRun(run))
+		_, err = st.db.Exec(`UPDATE runs SET created_at = ? WHERE id = ?`, time.Now().Add(-age), run.ID)
+		require.NoError(t, err)
+	}
+
+	day := 24 * time.Hour
+	for i := 0; i < 7; i++ {
+		addRun(job.ID, "success", time.Duration(i+1)*day)
+	}
+	addRun(job.ID, "failure", 2*day)
+	addRun(job.ID, "timeout", 10*day)
+	addRun(job.ID, "cancelled", 20*day)
+	addRun(job.ID, "running", time.Minute)
+	addRun(job.ID, "pending", time.Minute)
+	addRun(job.ID, "awaiting_approval", time.Minute)
+	addRun(job.ID, "failure", 45*day) // outside a 30-day window
+	addRun(other.ID, "failure", day)
+
+	rate, err := st.GetJobSuccessRate(job.ID, time.Now().Add(-30*day))
+	require.NoError(t, err)
+
+	assert.Equal(t, job.ID, rate.JobID)
+	assert.Equal(t, 10, rate.TotalRuns)
+	assert.Equal(t, 7, rate.SuccessCount)
+	assert.Equal(t, 2, rate.FailureCount)
+	assert.Equal(t, 1, rate.CancelledCount)
+	assert.InDelta(t, 0.7, rate.SuccessRate, 1e-9)
+
+	// A 3-day window only sees the two most recent successes and the failure
+	recent, err := st.GetJobSuccessRate(job.ID, time.Now().Add(-3*day+time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 3, recent.TotalRuns)
+	assert.InDelta(t, 2.0/3.0, recent.SuccessRate, 1e-9)
+
+	empty, err := st.GetJobSuccessRate(job.ID, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, empty.TotalRuns)
+	assert.Equal(t, 0.0, empty.SuccessRate)
+}
+
+// TestGetJobRunOverlaps tests that only runs whose execution intervals intersect are paired
+func TestGetJobRunOverlaps(t *testing.T) {
+	st := NewTestStore(t)
+	defer st.Close()
+
+	job, err := st.CreateJob(&Job{Name: "etl", Script: "true"})
+	require.NoError(t, err)
+
+	base := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	addRun := func(start, finish time.Duration) *Run {
+		run, err := st.CreateRun(job.ID, "scheduled")
+		require.NoError(t, err)
+		startedAt, finishedAt := base.Add(start), base.Add(finish)
+		run.Status = "success"
+		run.StartedAt = &startedAt
+		run.FinishedAt = &finishedAt
+		require.NoError(t, st.UpdateRun(run))
+		return run
+	}
+
+	first := addRun(0, 10*time.Minute)
+	second := addRun(5*time.Minute, 15*time.Minute) // overlaps first by 5 minutes
+	addRun(15*time.Minute, 20*time.Minute)          // starts exactly when second finishes
+	addRun(30*time.Minute, 40*time.Minute)          // runs alone
+	running, err := st.CreateRun(job.ID, "manual")  // unfinished runs are ignored
+	require.NoError(t, err)
+	runningStart := base.Add(32 * time.Minute)
+	running.Status = "running"
+	running.StartedAt = &runningStart
+	require.NoError(t, st.UpdateRun(running))
+
+	overlaps, err := st.GetJobRunOverlaps(job.ID, base.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, overlaps, 1)
+	assert.Equal(t, first.ID, overlaps[0].FirstRunID)
+	assert.Equal(t, second.ID, overlaps[0].SecondRunID)
+	assert.Equal(t, int64(5*time.Minute/time.Millisecond), overlaps[0].OverlapDurationMs)
+
+	// Runs started before the window are not considered
+	none, err := st.GetJobRunOverlaps(job.ID, base.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
+
+// TestAnalyticsFilter tests that trends and overall stats filtered by job, owner or tag
+// exclude other jobs' runs
+func TestAnalyticsFilter(t *testing.T) {
+	st := NewTestStore(t)
+	defer st.Close()
+
+	billing, err := st.CreateJob(&Job{Name: "billing", Script: "true", Enabled: true, CreatedBy: 2, Metadata: map[string]string{"team": "payments"}})
+	require.NoError(t, err)
+	search, err := st.CreateJob(&Job{Name: "search", Script: "true", Enabled: true, CreatedBy: 3})
+	require.NoError(t, err)
+
+	addRun := func(jobID, status string) {
+		run, err := st.CreateRun(jobID, "scheduled")
+		require.NoError(t, err)
+		started := time.Now().Add(-time.Minute)
+		run.Status = status
+		run.StartedAt = &started
+		require.NoError(t, st.UpdateRun(run))
+	}
+	addRun(billing.ID, "success")
+	addRun(billing.ID, "failure")
+	addRun(search.ID, "success")
+	addRun(search.ID, "success")
+	addRun(search.ID, "timeout")
+
+	totalRuns := func(trends []*DailyExecutionStats) int {
+		total := 0
+		for _, day := range trends {
+			total += day.TotalRuns
+		}
+		return total
+	}
+
+	all, err := st.GetExecutionTrends(7)
+	require.NoError(t, err)
+	assert.Equal(t, 5, totalRuns(all))
+
+	byJob, err := st.GetExecutionTrendsFiltered(7, AnalyticsFilter{JobID: billing.ID})
+	require.NoError(t, err)
+	require.Len(t, byJob, 1)
+	assert.Equal(t, 2, byJob[0].TotalRuns)
+	assert.Equal(t, 1, byJob[0].FailureCount)
+	assert.Equal(t, 0, byJob[0].TimeoutCount)
+
+	owner := 3
+	byOwner, err := st.GetExecutionTrendsFiltered(7, AnalyticsFilter{CreatedBy: &owner})
+	require.NoError(t, err)
+	assert.Equal(t, 3, totalRuns(byOwner))
+
+	stats, err := st.GetOverallStatsFiltered(nil, AnalyticsFilter{TagKey: "team", TagValue: "payments"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats["total_runs"])
+	assert.Equal(t, 1, stats["failure_count"])
+	assert.Equal(t, 1, stats["total_jobs"])
+
+	none, err := st.GetOverallStatsFiltered(nil, AnalyticsFilter{TagKey: "team", TagValue: "search"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, none["total_runs"])
+	assert.Equal(t, 0, none["total_jobs"])
+}
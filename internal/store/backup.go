@@ -0,0 +1,241 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	internal "github.com/taskflow/taskflow/internal"
+)
+
+// secretSettingKeys are settings left out of backups unless secrets are requested
+var secretSettingKeys = map[string]bool{
+	"smtp_password":        true,
+	teamsWebhookURLKey:     true,
+	pagerDutyRoutingKeyKey: true,
+}
+
+// errDryRun rolls back a restore transaction once a dry run has applied everything
+var errDryRun = errors.New("dry run")
+
+// Backup is a versioned snapshot of jobs, schedules, users and settings.
+// Users carry no password hashes; users created by a restore must have their password reset.
+type Backup struct {
+	Version   int          `json:"version"`
+	CreatedAt time.Time    `json:"created_at"`
+	Jobs      []*BackupJob `json:"jobs"`
+	Users     []*User      `json:"users"`
+	Settings  []Setting    `json:"settings"`
+}
+
+// BackupJob is a job together with its schedule, if it has one
+type BackupJob struct {
+	Job
+	Schedule *Schedule `json:"schedule,omitempty"`
+}
+
+// RestoreResult summarizes what a restore applied, or would apply for a dry run
+type RestoreResult struct {
+	DryRun        bool     `json:"dry_run"`
+	Jobs          int      `json:"jobs"`
+	Schedules     int      `json:"schedules"`
+	UsersCreated  []string `json:"users_created"` // usernames that need a password reset
+	UsersExisting int      `json:"users_existing"`
+	Settings      int      `json:"settings"`
+}
+
+// CreateBackup snapshots the configuration. Secret settings such as the SMTP password
+// are only included when includeSecrets is true.
+func (s *Store) CreateBackup(includeSecrets bool) (*Backup, error) {
+	backup := &Backup{Version: internal.BackupVersion, CreatedAt: time.Now()}
+
+	jobs, err := s.ListJobs(nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		if err := s.LoadJobScript(job); err != nil {
+			return nil, err
+		}
+		entry := &BackupJob{Job: *job}
+		schedule, err := s.GetJobSchedule(job.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get schedule for job %s: %w", job.ID, err)
+		}
+		if schedule.ID != 0 {
+			entry.Schedule = schedule
+		}
+		backup.Jobs = append(backup.Jobs, entry)
+	}
+
+	if backup.Users, err = s.ListUsers(); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.GetSettings("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list settings: %w", err)
+	}
+	for _, setting := range settings {
+		if secretSettingKeys[setting.Key] && !includeSecrets {
+			continue
+		}
+		backup.Settings = append(backup.Settings, setting)
+	}
+
+	return backup, nil
+}
+
+// ValidateBackup checks that a backup can be restored
+func ValidateBackup(backup *Backup) error {
+	if backup.Version != internal.BackupVersion {
+		return fmt.Errorf("unsupported backup version %d (expected %d)", backup.Version, internal.BackupVersion)
+	}
+	for i, job := range backup.Jobs {
+		if job.ID == "" || job.Name == "" {
+			return fmt.Errorf("job %d: id and name are required", i)
+		}
+	}
+	for i, user := range backup.Users {
+		if user.Username == "" {
+			return fmt.Errorf("user %d: username is required", i)
+		}
+		if user.Role != internal.RoleAdmin && user.Role != internal.RoleUser {
+			return fmt.Errorf("user %s: invalid role %q", user.Username, user.Role)
+		}
+	}
+	for _, setting := range backup.Settings {
+		if setting.Key == "" {
+			return errors.New("setting key is required")
+		}
+	}
+	return nil
+}
+
+// RestoreBackup applies a backup in one transaction. Jobs are replaced by ID, users are
+// matched by username (existing users are left as they are), and settings are overwritten;
+// anything not in the backup is kept. With dryRun the changes are applied and rolled back,
+// so the result reports what a real restore would do.
+func (s *Store) RestoreBackup(backup *Backup, dryRun bool) (*RestoreResult, error) {
+	if err := ValidateBackup(backup); err != nil {
+		return nil, err
+	}
+
+	result := &RestoreResult{DryRun: dryRun, UsersCreated: []string{}}
+	err := s.WithTx(func(tx *sql.Tx) error {
+		// Job owners are remapped from backup user IDs to the IDs in this database
+		userIDs := make(map[int]int)
+		for _, user := range backup.Users {
+			id, created, err := restoreUser(tx, user)
+			if err != nil {
+				return err
+			}
+			userIDs[user.ID] = id
+			if created {
+				result.UsersCreated = append(result.UsersCreated, user.Username)
+			} else {
+				result.UsersExisting++
+			}
+		}
+
+		for _, entry := range backup.Jobs {
+			job := entry.Job
+			if id, ok := userIDs[job.CreatedBy]; ok {
+				job.CreatedBy = id
+			}
+			if err := s.restoreJob(tx, &job, entry.Schedule); err != nil {
+				return fmt.Errorf("failed to restore job %s: %w", job.ID, err)
+			}
+			result.Jobs++
+			if entry.Schedule != nil {
+				result.Schedules++
+			}
+		}
+
+		for _, setting := range backup.Settings {
+			if _, err := tx.Exec(
+				`INSERT INTO settings (key, value, updated_at) VALUES (?, ?, ?)
+				 ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+				setting.Key, setting.Value, time.Now(),
+			); err != nil {
+				return fmt.Errorf("failed to restore setting %s: %w", setting.Key, err)
+			}
+			result.Settings++
+		}
+
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+
+	if !dryRun {
+		for _, entry := range backup.Jobs {
+			s.notifyScheduleChanged(entry.ID)
+		}
+	}
+	return result, nil
+}
+
+// restoreUser returns the ID of the user with user's username, creating the user without
+// a usable password if none exists
+func restoreUser(tx *sql.Tx, user *User) (id int, created bool, err error) {
+	err = tx.QueryRow(`SELECT id FROM users WHERE username = ?`, user.Username).Scan(&id)
+	if err == nil {
+		return id, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, false, fmt.Errorf("failed to look up user %s: %w", user.Username, err)
+	}
+
+	createdAt := user.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	res, err := tx.Exec(
+		`INSERT INTO users (username, email, password_hash, role, created_at) VALUES (?, ?, '', ?, ?)`,
+		user.Username, user.Email, user.Role, createdAt,
+	)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to create user %s: %w", user.Username, err)
+	}
+	newID, err := res.LastInsertId()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get user id: %w", err)
+	}
+	return int(newID), true, nil
+}
+
+// restoreJob replaces the job with job.ID and its schedule
+func (s *Store) restoreJob(tx *sql.Tx, job *Job, schedule *Schedule) error {
+	if _, err := tx.Exec(`DELETE FROM jobs WHERE id = ?`, job.ID); err != nil {
+		return fmt.Errorf("failed to replace job: %w", err)
+	}
+	if err := s.checkJobName(tx, job); err != nil {
+		return err
+	}
+
+	// createJob always starts the schedule active, so the saved state is put back afterwards
+	scheduleEnabled, schedulePaused := job.ScheduleEnabled, job.SchedulePaused
+	if err := createJob(tx, job); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		`UPDATE jobs SET schedule_enabled = ?, schedule_paused = ?, consecutive_failures = ? WHERE id = ?`,
+		scheduleEnabled, schedulePaused, job.ConsecutiveFailures, job.ID,
+	); err != nil {
+		return fmt.Errorf("failed to restore schedule state: %w", err)
+	}
+
+	if schedule == nil {
+		if _, err := tx.Exec(`DELETE FROM schedules WHERE job_id = ?`, job.ID); err != nil {
+			return fmt.Errorf("failed to clear schedule: %w", err)
+		}
+		return nil
+	}
+	return saveSchedule(tx, job.ID, schedule)
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBackupRestoreRoundTrip tests that restoring a backup into a fresh store reproduces
+// its jobs, schedules, users and settings
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	src := NewTestStore(t)
+	defer src.Close()
+
+	admin, err := src.CreateUser("ops", "ops@example.com", "hash", "admin")
+	require.NoError(t, err)
+	nightly, err := src.CreateJob(&Job{
+		Name: "nightly", Script: "backup.sh", TimeoutSeconds: 600, Enabled: true,
+		Metadata: map[string]string{"team": "infra"}, CreatedBy: admin.ID,
+	})
+	require.NoError(t, err)
+	require.NoError(t, src.SetJobSchedule(nightly.ID, &Schedule{Hours: []int{2}, Minutes: []int{30}}))
+	require.NoError(t, src.SetJobScheduleEnabled(nightly.ID, false))
+	adhoc, err := src.CreateJob(&Job{Name: "adhoc", Script: "true", TimeoutSeconds: 60})
+	require.NoError(t, err)
+	require.NoError(t, src.SetSMTPSettings(&SMTPSettings{Server: "smtp.example.com", Port: 587, Password: "s3cret"}))
+
+	backup, err := src.CreateBackup(false)
+	require.NoError(t, err)
+	for _, setting := range backup.Settings {
+		assert.NotEqual(t, "smtp_password", setting.Key, "secrets are excluded by default")
+	}
+
+	// Round-trip through JSON as the API does
+	data, err := json.Marshal(backup)
+	require.NoError(t, err)
+	var decoded Backup
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	dst := NewTestStore(t)
+	defer dst.Close()
+	// An existing user keeps its own ID; the restored job owner must follow it
+	_, err = dst.CreateUser("someone-else", "x@example.com", "hash", "user")
+	require.NoError(t, err)
+
+	dry, err := dst.RestoreBackup(&decoded, true)
+	require.NoError(t, err)
+	assert.True(t, dry.DryRun)
+	assert.Equal(t, 2, dry.Jobs)
+	jobs, err := dst.ListJobs(nil)
+	require.NoError(t, err)
+	assert.Empty(t, jobs, "dry run changes nothing")
+
+	result, err := dst.RestoreBackup(&decoded, false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Jobs)
+	assert.Equal(t, 1, result.Schedules)
+	assert.Equal(t, []string{"ops"}, result.UsersCreated)
+
+	restored, err := dst.GetJob(nightly.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "nightly", restored.Name)
+	assert.Equal(t, "backup.sh", restored.Script)
+	assert.Equal(t, 600, restored.TimeoutSeconds)
+	assert.Equal(t, map[string]string{"team": "infra"}, restored.Metadata)
+	assert.False(t, restored.ScheduleEnabled)
+	owner, err := dst.GetUserByUsername("ops")
+	require.NoError(t, err)
+	assert.Equal(t, owner.ID, restored.CreatedBy)
+	assert.Empty(t, owner.PasswordHash)
+
+	schedule, err := dst.GetJobSchedule(nightly.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, schedule.Hours)
+	assert.Equal(t, []int{30}, schedule.Minutes)
+
+	_, err = dst.GetJob(adhoc.ID)
+	assert.NoError(t, err)
+
+	smtp, err := dst.GetSMTPSettings()
+	require.NoError(t, err)
+	assert.Equal(t, "smtp.example.com", smtp.Server)
+	assert.Empty(t, smtp.Password)
+
+	// Restoring again replaces the jobs rather than duplicating them
+	_, err = dst.RestoreBackup(&decoded, false)
+	require.NoError(t, err)
+	jobs, err = dst.ListJobs(nil)
+	require.NoError(t, err)
+	assert.Len(t, jobs, 2)
+}
+
+// TestRestoreBackupRejectsUnknownVersion tests that backups from another format version are refused
+func TestRestoreBackupRejectsUnknownVersion(t *testing.T) {
+	s := NewTestStore(t)
+	defer s.Close()
+
+	_, err := s.RestoreBackup(&Backup{Version: 99}, false)
+	assert.ErrorContains(t, err, "unsupported backup version")
+}
@@ -0,0 +1,59 @@
+//go:build cgo
+
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWriteRetriesTransientBusy tests that run and log writes are retried while SQLite
+// reports the database busy, and that other errors are returned without retrying
+func TestWriteRetriesTransientBusy(t *testing.T) {
+	st := NewTestStore(t)
+	defer st.Close()
+
+	job, err := st.CreateJob(&Job{Name: "contended", Script: "true", TimeoutSeconds: 60})
+	require.NoError(t, err)
+
+	var calls, failures int
+	var injected error
+	st.execFn = func(query string, args ...interface{}) (sql.Result, error) {
+		calls++
+		if calls <= failures {
+			return nil, injected
+		}
+		return st.db.Exec(query, args...)
+	}
+
+	// Two busy errors, then success
+	injected = sqlite3.Error{Code: sqlite3.ErrBusy}
+	failures = 2
+	run, err := st.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	calls, injected = 0, sqlite3.Error{Code: sqlite3.ErrLocked}
+	run.Status = "running"
+	require.NoError(t, st.UpdateRun(run))
+	assert.Equal(t, 3, calls)
+	saved, err := st.GetRun(run.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "running", saved.Status)
+
+	// Busy beyond the retry budget surfaces the error
+	calls, failures = 0, 10
+	_, err = st.AddLog(run.ID, "stdout", "line")
+	assert.True(t, isBusyError(err))
+	assert.Equal(t, 4, calls, "one attempt plus the default three retries")
+
+	// A real error is not retried
+	calls, injected = 0, errors.New("no such table: runs")
+	assert.Error(t, st.UpdateRun(run))
+	assert.Equal(t, 1, calls)
+}
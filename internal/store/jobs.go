@@ -8,10 +8,131 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	internal "github.com/taskflow/taskflow/internal"
 )
 
+// jobColumns is the column list shared by every job SELECT so that scanJob
+// stays in sync with the queries that feed it.
+const jobColumns = `id, name, description, script, working_dir, timeout_seconds,
+	 retry_count, retry_delay_seconds, enabled, notify_emails, notify_on, timezone,
+	 created_by, created_at, updated_at, notify_exit_codes, resource_lock, retry_on_exit_codes,
+	 auto_disable_after_failures, consecutive_failures, schedule_enabled, nice, interpreter,
+	 timeout_warn_percent, timeout_warn_notify, blackout_windows, success_pattern, failure_pattern,
+	 metadata, pre_script, post_script, pause_on_failure, schedule_paused, script_external,
+	 manual_while_scheduled, login_shell, notify_channels, requires_approval, log_level_pattern`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanJob scans a row selected with jobColumns into a Job
+func scanJob(row rowScanner) (*Job, error) {
+	job := &Job{}
+	var notifyExitCodes, retryOnExitCodes, blackoutWindows, metadata, notifyChannels sql.NullString
+
+	if err := row.Scan(
+		&job.ID, &job.Name, &job.Description, &job.Script, &job.WorkingDir,
+		&job.TimeoutSeconds, &job.RetryCount, &job.RetryDelaySeconds, &job.Enabled,
+		&job.NotifyEmails, &job.NotifyOn, &job.Timezone, &job.CreatedBy,
+		&job.CreatedAt, &job.UpdatedAt, &notifyExitCodes, &job.ResourceLock, &retryOnExitCodes,
+		&job.AutoDisableAfterFailures, &job.ConsecutiveFailures, &job.ScheduleEnabled,
+		&job.Nice, &job.Interpreter, &job.TimeoutWarnPercent, &job.TimeoutWarnNotify,
+		&blackoutWindows, &job.SuccessPattern, &job.FailurePattern, &metadata,
+		&job.PreScript, &job.PostScript, &job.PauseOnFailure, &job.SchedulePaused,
+		&job.ScriptExternal, &job.ManualWhileScheduled, &job.LoginShell, &notifyChannels,
+		&job.RequiresApproval, &job.LogLevelPattern,
+	); err != nil {
+		return nil, err
+	}
+
+	if notifyExitCodes.Valid {
+		if err := json.Unmarshal([]byte(notifyExitCodes.String), &job.NotifyExitCodes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal notify_exit_codes: %w", err)
+		}
+	}
+	if retryOnExitCodes.Valid {
+		if err := json.Unmarshal([]byte(retryOnExitCodes.String), &job.RetryOnExitCodes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal retry_on_exit_codes: %w", err)
+		}
+	}
+	if blackoutWindows.Valid {
+		if err := json.Unmarshal([]byte(blackoutWindows.String), &job.BlackoutWindows); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal blackout_windows: %w", err)
+		}
+	}
+
+	if metadata.Valid {
+		if err := json.Unmarshal([]byte(metadata.String), &job.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+		}
+	}
+	if notifyChannels.Valid {
+		if err := json.Unmarshal([]byte(notifyChannels.String), &job.NotifyChannels); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal notify_channels: %w", err)
+		}
+	}
+
+	return job, nil
+}
+
+// ErrDuplicateJobName is returned by CreateJob and UpdateJob when unique job names are
+// enforced and another job already has the name
+var ErrDuplicateJobName = errors.New("job name already in use")
+
 // CreateJob creates a new job
 func (s *Store) CreateJob(job *Job) (*Job, error) {
+	return s.CreateJobWithSchedule(job, nil)
+}
+
+// CreateJobWithSchedule creates a new job and, if schedule is non-nil, its schedule in
+// one transaction, so a failed schedule write leaves no job behind
+func (s *Store) CreateJobWithSchedule(job *Job, schedule *Schedule) (*Job, error) {
+	err := s.WithTx(func(tx *sql.Tx) error {
+		if err := s.checkJobName(tx, job); err != nil {
+			return err
+		}
+		if err := createJob(tx, job); err != nil {
+			return err
+		}
+		if schedule == nil {
+			return nil
+		}
+		schedule.JobID = job.ID
+		return saveSchedule(tx, job.ID, schedule)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if schedule != nil {
+		s.notifyScheduleChanged(job.ID)
+	}
+	return job, nil
+}
+
+// checkJobName returns ErrDuplicateJobName if unique names are enforced and a job
+// other than job itself already has its name
+func (s *Store) checkJobName(tx *sql.Tx, job *Job) error {
+	if !s.uniqueJobNames {
+		return nil
+	}
+
+	var exists bool
+	err := tx.QueryRow(
+		`SELECT EXISTS(SELECT 1 FROM jobs WHERE name = ? AND id != ?)`, job.Name, job.ID,
+	).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check job name: %w", err)
+	}
+	if exists {
+		return ErrDuplicateJobName
+	}
+	return nil
+}
+
+// createJob inserts job, filling in its ID and timestamps when unset
+func createJob(tx *sql.Tx, job *Job) error {
 	if job.ID == "" {
 		job.ID = uuid.New().String()
 	}
@@ -21,38 +142,109 @@ func (s *Store) CreateJob(job *Job) (*Job, error) {
 	if job.UpdatedAt.IsZero() {
 		job.UpdatedAt = time.Now()
 	}
+	// New jobs always start with their schedule active; it is toggled via SetJobScheduleEnabled
+	job.ScheduleEnabled = true
 
-	_, err := s.db.Exec(
+	notifyExitCodes, err := intsToNullJSON(job.NotifyExitCodes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notify_exit_codes: %w", err)
+	}
+	retryOnExitCodes, err := intsToNullJSON(job.RetryOnExitCodes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal retry_on_exit_codes: %w", err)
+	}
+	blackoutWindows, err := blackoutWindowsToNullJSON(job.BlackoutWindows)
+	if err != nil {
+		return fmt.Errorf("failed to marshal blackout_windows: %w", err)
+	}
+	metadata, err := metadataToNullJSON(job.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	notifyChannels, err := stringsToNullJSON(job.NotifyChannels)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notify_channels: %w", err)
+	}
+	inlineScript := splitScript(job)
+
+	_, err = tx.Exec(
 		`INSERT INTO jobs (id, name, description, script, working_dir, timeout_seconds,
 		 retry_count, retry_delay_seconds, enabled, notify_emails, notify_on, timezone,
-		 created_by, created_at, updated_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		job.ID, job.Name, job.Description, job.Script, job.WorkingDir, job.TimeoutSeconds,
+		 created_by, created_at, updated_at, notify_exit_codes, resource_lock, retry_on_exit_codes,
+		 auto_disable_after_failures, nice, interpreter, timeout_warn_percent, timeout_warn_notify,
+		 blackout_windows, success_pattern, failure_pattern, metadata, pre_script, post_script,
+		 pause_on_failure, script_external, manual_while_scheduled, login_shell, notify_channels,
+		 requires_approval, log_level_pattern)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.ID, job.Name, job.Description, inlineScript, job.WorkingDir, job.TimeoutSeconds,
 		job.RetryCount, job.RetryDelaySeconds, job.Enabled, job.NotifyEmails, job.NotifyOn,
-		job.Timezone, job.CreatedBy, job.CreatedAt, job.UpdatedAt,
+		job.Timezone, job.CreatedBy, job.CreatedAt, job.UpdatedAt, notifyExitCodes, job.ResourceLock,
+		retryOnExitCodes, job.AutoDisableAfterFailures, job.Nice, job.Interpreter,
+		job.TimeoutWarnPercent, job.TimeoutWarnNotify, blackoutWindows, job.SuccessPattern,
+		job.FailurePattern, metadata, job.PreScript, job.PostScript, job.PauseOnFailure,
+		job.ScriptExternal, job.ManualWhileScheduled, job.LoginShell, notifyChannels,
+		job.RequiresApproval, job.LogLevelPattern,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create job: %w", err)
+		return fmt.Errorf("failed to create job: %w", err)
 	}
 
-	return job, nil
+	return saveExternalScript(tx, job)
+}
+
+// splitScript decides where job.Script is stored, setting job.ScriptExternal, and returns
+// the value for the jobs.script column: the script itself, or empty when it goes to job_scripts.
+func splitScript(job *Job) string {
+	job.ScriptExternal = len(job.Script) > internal.InlineScriptMaxSize
+	if job.ScriptExternal {
+		return ""
+	}
+	return job.Script
+}
+
+// saveExternalScript writes an external script to job_scripts, or removes the job's row
+// there once its script fits inline again
+func saveExternalScript(tx *sql.Tx, job *Job) error {
+	if !job.ScriptExternal {
+		if _, err := tx.Exec(`DELETE FROM job_scripts WHERE job_id = ?`, job.ID); err != nil {
+			return fmt.Errorf("failed to delete external script: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO job_scripts (job_id, script) VALUES (?, ?)
+		 ON CONFLICT(job_id) DO UPDATE SET script = excluded.script`,
+		job.ID, job.Script,
+	); err != nil {
+		return fmt.Errorf("failed to save external script: %w", err)
+	}
+	return nil
+}
+
+// LoadJobScript fills in Script for a job whose script is stored externally. Jobs from
+// list queries carry only the ScriptExternal flag; GetJob already loads the script.
+func (s *Store) LoadJobScript(job *Job) error {
+	if !job.ScriptExternal || job.Script != "" {
+		return nil
+	}
+
+	err := s.db.QueryRow(`SELECT script FROM job_scripts WHERE job_id = ?`, job.ID).Scan(&job.Script)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.New("external script not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load external script: %w", err)
+	}
+	return nil
 }
 
 // GetJob retrieves a job by ID
 func (s *Store) GetJob(id string) (*Job, error) {
-	job := &Job{}
-
-	err := s.db.QueryRow(
-		`SELECT id, name, description, script, working_dir, timeout_seconds,
-		 retry_count, retry_delay_seconds, enabled, notify_emails, notify_on, timezone,
-		 created_by, created_at, updated_at FROM jobs WHERE id = ?`,
+	job, err := scanJob(s.db.QueryRow(
+		`SELECT `+jobColumns+` FROM jobs WHERE id = ?`,
 		id,
-	).Scan(
-		&job.ID, &job.Name, &job.Description, &job.Script, &job.WorkingDir,
-		&job.TimeoutSeconds, &job.RetryCount, &job.RetryDelaySeconds, &job.Enabled,
-		&job.NotifyEmails, &job.NotifyOn, &job.Timezone, &job.CreatedBy,
-		&job.CreatedAt, &job.UpdatedAt,
-	)
+	))
 
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, errors.New("job not found")
@@ -60,26 +252,35 @@ func (s *Store) GetJob(id string) (*Job, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get job: %w", err)
 	}
+	if err := s.LoadJobScript(job); err != nil {
+		return nil, err
+	}
 
 	return job, nil
 }
 
 // ListJobs retrieves all jobs, optionally filtered by creator
 func (s *Store) ListJobs(createdBy *int) ([]*Job, error) {
-	query := `SELECT id, name, description, script, working_dir, timeout_seconds,
-	 retry_count, retry_delay_seconds, enabled, notify_emails, notify_on, timezone,
-	 created_by, created_at, updated_at FROM jobs`
+	return s.ListJobsByMetadata(createdBy, "", "")
+}
 
-	var rows *sql.Rows
-	var err error
+// ListJobsByMetadata retrieves jobs, optionally filtered by creator, whose metadata
+// has key set to value. An empty key disables the metadata filter.
+func (s *Store) ListJobsByMetadata(createdBy *int, key, value string) ([]*Job, error) {
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE 1 = 1`
+	args := []interface{}{}
 
 	if createdBy != nil {
-		query += ` WHERE created_by = ?`
-		rows, err = s.db.Query(query + ` ORDER BY created_at DESC`, *createdBy)
-	} else {
-		rows, err = s.db.Query(query + ` ORDER BY created_at DESC`)
+		query += ` AND created_by = ?`
+		args = append(args, *createdBy)
+	}
+	if key != "" {
+		// Quote the key so dots in it are not read as nested paths
+		query += ` AND json_extract(metadata, ?) = ?`
+		args = append(args, `$."`+key+`"`, value)
 	}
 
+	rows, err := s.db.Query(query+` ORDER BY created_at DESC`, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list jobs: %w", err)
 	}
@@ -87,13 +288,8 @@ func (s *Store) ListJobs(createdBy *int) ([]*Job, error) {
 
 	jobs := make([]*Job, 0)
 	for rows.Next() {
-		job := &Job{}
-		if err := rows.Scan(
-			&job.ID, &job.Name, &job.Description, &job.Script, &job.WorkingDir,
-			&job.TimeoutSeconds, &job.RetryCount, &job.RetryDelaySeconds, &job.Enabled,
-			&job.NotifyEmails, &job.NotifyOn, &job.Timezone, &job.CreatedBy,
-			&job.CreatedAt, &job.UpdatedAt,
-		); err != nil {
+		job, err := scanJob(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan job: %w", err)
 		}
 		jobs = append(jobs, job)
@@ -106,14 +302,61 @@ func (s *Store) ListJobs(createdBy *int) ([]*Job, error) {
 func (s *Store) UpdateJob(job *Job) error {
 	job.UpdatedAt = time.Now()
 
-	result, err := s.db.Exec(
+	notifyExitCodes, err := intsToNullJSON(job.NotifyExitCodes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notify_exit_codes: %w", err)
+	}
+	retryOnExitCodes, err := intsToNullJSON(job.RetryOnExitCodes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal retry_on_exit_codes: %w", err)
+	}
+	blackoutWindows, err := blackoutWindowsToNullJSON(job.BlackoutWindows)
+	if err != nil {
+		return fmt.Errorf("failed to marshal blackout_windows: %w", err)
+	}
+	metadata, err := metadataToNullJSON(job.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	notifyChannels, err := stringsToNullJSON(job.NotifyChannels)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notify_channels: %w", err)
+	}
+	inlineScript := splitScript(job)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.checkJobName(tx, job); err != nil {
+		return err
+	}
+
+	// Re-enabling a job clears its failure streak so it is not immediately auto-disabled again
+	result, err := tx.Exec(
 		`UPDATE jobs SET name = ?, description = ?, script = ?, working_dir = ?,
-		 timeout_seconds = ?, retry_count = ?, retry_delay_seconds = ?, enabled = ?,
-		 notify_emails = ?, notify_on = ?, timezone = ?, updated_at = ?
+		 timeout_seconds = ?, retry_count = ?, retry_delay_seconds = ?,
+		 consecutive_failures = CASE WHEN enabled = 0 AND ? THEN 0 ELSE consecutive_failures END,
+		 enabled = ?, notify_emails = ?, notify_on = ?, timezone = ?, updated_at = ?,
+		 notify_exit_codes = ?, resource_lock = ?, retry_on_exit_codes = ?,
+		 auto_disable_after_failures = ?, nice = ?, interpreter = ?,
+		 timeout_warn_percent = ?, timeout_warn_notify = ?, blackout_windows = ?,
+		 success_pattern = ?, failure_pattern = ?, metadata = ?,
+		 pre_script = ?, post_script = ?,
+		 schedule_paused = CASE WHEN ? THEN schedule_paused ELSE 0 END, pause_on_failure = ?,
+		 script_external = ?, manual_while_scheduled = ?, login_shell = ?, notify_channels = ?,
+		 requires_approval = ?, log_level_pattern = ?, next_run_at = NULL
 		 WHERE id = ?`,
-		job.Name, job.Description, job.Script, job.WorkingDir, job.TimeoutSeconds,
-		job.RetryCount, job.RetryDelaySeconds, job.Enabled, job.NotifyEmails,
-		job.NotifyOn, job.Timezone, job.UpdatedAt, job.ID,
+		job.Name, job.Description, inlineScript, job.WorkingDir, job.TimeoutSeconds,
+		job.RetryCount, job.RetryDelaySeconds, job.Enabled, job.Enabled, job.NotifyEmails,
+		job.NotifyOn, job.Timezone, job.UpdatedAt, notifyExitCodes, job.ResourceLock,
+		retryOnExitCodes, job.AutoDisableAfterFailures, job.Nice, job.Interpreter,
+		job.TimeoutWarnPercent, job.TimeoutWarnNotify, blackoutWindows, job.SuccessPattern,
+		job.FailurePattern, metadata, job.PreScript, job.PostScript, job.PauseOnFailure,
+		job.PauseOnFailure, job.ScriptExternal, job.ManualWhileScheduled, job.LoginShell, notifyChannels,
+		job.RequiresApproval, job.LogLevelPattern, job.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update job: %w", err)
@@ -128,14 +371,133 @@ func (s *Store) UpdateJob(job *Job) error {
 		return errors.New("job not found")
 	}
 
+	if err := saveExternalScript(tx, job); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
-// DeleteJob deletes a job
-func (s *Store) DeleteJob(id string) error {
-	result, err := s.db.Exec(`DELETE FROM jobs WHERE id = ?`, id)
+// RecordJobOutcome updates a job's consecutive failure count after a finished run.
+// A success resets the count; a failure increments it and, once the job's
+// auto_disable_after_failures threshold is reached, disables the job.
+// Returns the new count and whether this call disabled the job.
+func (s *Store) RecordJobOutcome(jobID string, failed bool) (failures int, disabled bool, err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if !failed {
+		if _, err := tx.Exec(`UPDATE jobs SET consecutive_failures = 0 WHERE id = ?`, jobID); err != nil {
+			return 0, false, fmt.Errorf("failed to reset consecutive failures: %w", err)
+		}
+		return 0, false, tx.Commit()
+	}
+
+	var threshold int
+	var enabled bool
+	err = tx.QueryRow(
+		`UPDATE jobs SET consecutive_failures = consecutive_failures + 1 WHERE id = ?
+		 RETURNING consecutive_failures, auto_disable_after_failures, enabled`,
+		jobID,
+	).Scan(&failures, &threshold, &enabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, errors.New("job not found")
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to increment consecutive failures: %w", err)
+	}
+
+	if enabled && threshold > 0 && failures >= threshold {
+		if _, err := tx.Exec(`UPDATE jobs SET enabled = 0, updated_at = ? WHERE id = ?`, time.Now(), jobID); err != nil {
+			return 0, false, fmt.Errorf("failed to disable job: %w", err)
+		}
+		disabled = true
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return failures, disabled, nil
+}
+
+// ListDueJobs returns the enabled jobs with an active schedule whose next_run_at is at or
+// before now, so each scheduler tick reads only the jobs that may fire
+func (s *Store) ListDueJobs(now time.Time) ([]*Job, error) {
+	return s.listScheduledJobs(`next_run_at <= ?`, now.UTC())
+}
+
+// ListJobsWithoutNextRun returns the enabled jobs with an active schedule whose
+// next_run_at was cleared by a change to the job or its schedule, or never set
+func (s *Store) ListJobsWithoutNextRun() ([]*Job, error) {
+	return s.listScheduledJobs(`next_run_at IS NULL`)
+}
+
+// listScheduledJobs returns enabled jobs that have a saved, enabled schedule and match cond
+func (s *Store) listScheduledJobs(cond string, args ...interface{}) ([]*Job, error) {
+	rows, err := s.db.Query(
+		`SELECT `+jobColumns+` FROM jobs
+		 WHERE enabled = 1 AND schedule_enabled = 1 AND id IN (SELECT job_id FROM schedules) AND `+cond+`
+		 ORDER BY created_at`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduled jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// SetJobNextRunAt records when the scheduler should next consider firing a job
+func (s *Store) SetJobNextRunAt(jobID string, next time.Time) error {
+	if _, err := s.db.Exec(`UPDATE jobs SET next_run_at = ? WHERE id = ?`, next.UTC(), jobID); err != nil {
+		return fmt.Errorf("failed to set next_run_at: %w", err)
+	}
+	return nil
+}
+
+// GetJobNextRunAt returns a job's stored next_run_at, or nil if it is not computed yet
+func (s *Store) GetJobNextRunAt(jobID string) (*time.Time, error) {
+	var next sql.NullTime
+	err := s.db.QueryRow(`SELECT next_run_at FROM jobs WHERE id = ?`, jobID).Scan(&next)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.New("job not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get next_run_at: %w", err)
+	}
+	if !next.Valid {
+		return nil, nil
+	}
+	return &next.Time, nil
+}
+
+// SetJobScheduleEnabled turns scheduled triggering on or off for a job.
+// Manual triggers are unaffected.
+func (s *Store) SetJobScheduleEnabled(jobID string, enabled bool) error {
+	// Enabling also resumes a schedule paused by pause_on_failure
+	result, err := s.db.Exec(
+		`UPDATE jobs SET schedule_enabled = ?,
+		 schedule_paused = CASE WHEN ? THEN 0 ELSE schedule_paused END, updated_at = ?, next_run_at = NULL
+		 WHERE id = ?`,
+		enabled, enabled, time.Now(), jobID,
+	)
 	if err != nil {
-		return fmt.Errorf("failed to delete job: %w", err)
+		return fmt.Errorf("failed to update schedule_enabled: %w", err)
 	}
 
 	rows, err := result.RowsAffected()
@@ -150,8 +512,87 @@ func (s *Store) DeleteJob(id string) error {
 	return nil
 }
 
+// PauseScheduleOnFailure pauses a job's schedule after a failed scheduled run if the job
+// has pause_on_failure set. It reports whether the schedule was newly paused.
+func (s *Store) PauseScheduleOnFailure(jobID string) (bool, error) {
+	result, err := s.db.Exec(
+		`UPDATE jobs SET schedule_paused = 1, updated_at = ?
+		 WHERE id = ? AND pause_on_failure = 1 AND schedule_paused = 0`,
+		time.Now(), jobID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to pause schedule: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// ResumePausedSchedule clears a pause set by PauseScheduleOnFailure, reporting whether
+// the schedule was paused
+func (s *Store) ResumePausedSchedule(jobID string) (bool, error) {
+	result, err := s.db.Exec(
+		`UPDATE jobs SET schedule_paused = 0, updated_at = ? WHERE id = ? AND schedule_paused = 1`,
+		time.Now(), jobID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to resume schedule: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// DeleteJob deletes a job
+func (s *Store) DeleteJob(id string) error {
+	err := s.WithTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`DELETE FROM jobs WHERE id = ?`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete job: %w", err)
+		}
+		// Foreign keys are not enforced, so the cascade has to be done by hand
+		if _, err := tx.Exec(`DELETE FROM job_scripts WHERE job_id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete external script: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+
+		if rows == 0 {
+			return errors.New("job not found")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.notifyScheduleChanged(id)
+	return nil
+}
+
 // SetJobSchedule saves or updates a job's schedule
 func (s *Store) SetJobSchedule(jobID string, schedule *Schedule) error {
+	if err := s.WithTx(func(tx *sql.Tx) error {
+		return saveSchedule(tx, jobID, schedule)
+	}); err != nil {
+		return err
+	}
+
+	s.notifyScheduleChanged(jobID)
+	return nil
+}
+
+// saveSchedule upserts a job's schedule row
+func saveSchedule(tx *sql.Tx, jobID string, schedule *Schedule) error {
 	yearsJSON, err := json.Marshal(schedule.Years)
 	if err != nil {
 		return fmt.Errorf("failed to marshal years: %w", err)
@@ -176,25 +617,51 @@ func (s *Store) SetJobSchedule(jobID string, schedule *Schedule) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal minutes: %w", err)
 	}
+	holidaysJSON, err := stringsToNullJSON(schedule.Holidays)
+	if err != nil {
+		return fmt.Errorf("failed to marshal holidays: %w", err)
+	}
+	var solarJSON sql.NullString
+	if schedule.Solar != nil {
+		data, err := json.Marshal(schedule.Solar)
+		if err != nil {
+			return fmt.Errorf("failed to marshal solar: %w", err)
+		}
+		solarJSON = sql.NullString{String: string(data), Valid: true}
+	}
 
-	_, err = s.db.Exec(
-		`INSERT OR REPLACE INTO schedules (job_id, years, months, days, weekdays, hours, minutes)
-		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+	// Upsert so fire tracking survives schedule edits
+	_, err = tx.Exec(
+		`INSERT INTO schedules (job_id, years, months, days, weekdays, hours, minutes, holidays, solar, run_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(job_id) DO UPDATE SET years = excluded.years, months = excluded.months,
+		 days = excluded.days, weekdays = excluded.weekdays, hours = excluded.hours, minutes = excluded.minutes,
+		 holidays = excluded.holidays, solar = excluded.solar, run_at = excluded.run_at`,
 		jobID, string(yearsJSON), string(monthsJSON), string(daysJSON),
-		string(weekdaysJSON), string(hoursJSON), string(minutesJSON),
+		string(weekdaysJSON), string(hoursJSON), string(minutesJSON), holidaysJSON, solarJSON, schedule.RunAt,
 	)
+	if err != nil {
+		return err
+	}
+
+	// The scheduler recomputes the next fire time from the new schedule
+	_, err = tx.Exec(`UPDATE jobs SET next_run_at = NULL WHERE id = ?`, jobID)
 	return err
 }
 
 // GetJobSchedule retrieves a job's schedule
 func (s *Store) GetJobSchedule(jobID string) (*Schedule, error) {
 	schedule := &Schedule{JobID: jobID}
-	var yearsJSON, monthsJSON, daysJSON, weekdaysJSON, hoursJSON, minutesJSON sql.NullString
+	var yearsJSON, monthsJSON, daysJSON, weekdaysJSON, hoursJSON, minutesJSON, holidaysJSON, solarJSON sql.NullString
+	var lastScheduledAt, runAt sql.NullTime
+	var fireCount sql.NullInt64
 
 	err := s.db.QueryRow(
-		`SELECT id, years, months, days, weekdays, hours, minutes FROM schedules WHERE job_id = ?`,
+		`SELECT id, years, months, days, weekdays, hours, minutes, holidays, solar, run_at, last_scheduled_at, scheduled_fire_count
+		 FROM schedules WHERE job_id = ?`,
 		jobID,
-	).Scan(&schedule.ID, &yearsJSON, &monthsJSON, &daysJSON, &weekdaysJSON, &hoursJSON, &minutesJSON)
+	).Scan(&schedule.ID, &yearsJSON, &monthsJSON, &daysJSON, &weekdaysJSON, &hoursJSON, &minutesJSON,
+		&holidaysJSON, &solarJSON, &runAt, &lastScheduledAt, &fireCount)
 
 	if errors.Is(err, sql.ErrNoRows) {
 		// Return empty schedule if none exists
@@ -204,37 +671,53 @@ func (s *Store) GetJobSchedule(jobID string) (*Schedule, error) {
 		return nil, fmt.Errorf("failed to get schedule: %w", err)
 	}
 
-	// Unmarshal JSON arrays with error handling
-	if yearsJSON.Valid {
-		if err := json.Unmarshal([]byte(yearsJSON.String), &schedule.Years); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal years: %w", err)
-		}
+	// A field that doesn't unmarshal (e.g. after a manual database edit) is treated as
+	// "any" and reported in CorruptFields, so one bad value doesn't stop the job for good
+	fields := []struct {
+		name string
+		raw  sql.NullString
+		dst  interface{}
+	}{
+		{"years", yearsJSON, &schedule.Years},
+		{"months", monthsJSON, &schedule.Months},
+		{"days", daysJSON, &schedule.Days},
+		{"weekdays", weekdaysJSON, &schedule.Weekdays},
+		{"hours", hoursJSON, &schedule.Hours},
+		{"minutes", minutesJSON, &schedule.Minutes},
+		{"holidays", holidaysJSON, &schedule.Holidays},
+		{"solar", solarJSON, &schedule.Solar},
 	}
-	if monthsJSON.Valid {
-		if err := json.Unmarshal([]byte(monthsJSON.String), &schedule.Months); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal months: %w", err)
+	for _, f := range fields {
+		if !f.raw.Valid {
+			continue
 		}
-	}
-	if daysJSON.Valid {
-		if err := json.Unmarshal([]byte(daysJSON.String), &schedule.Days); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal days: %w", err)
+		if err := json.Unmarshal([]byte(f.raw.String), f.dst); err != nil {
+			// Unmarshalling null resets the slice or pointer to nil
+			json.Unmarshal([]byte("null"), f.dst)
+			schedule.CorruptFields = append(schedule.CorruptFields, f.name)
 		}
 	}
-	if weekdaysJSON.Valid {
-		if err := json.Unmarshal([]byte(weekdaysJSON.String), &schedule.Weekdays); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal weekdays: %w", err)
-		}
+	if runAt.Valid {
+		schedule.RunAt = &runAt.Time
 	}
-	if hoursJSON.Valid {
-		if err := json.Unmarshal([]byte(hoursJSON.String), &schedule.Hours); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal hours: %w", err)
-		}
-	}
-	if minutesJSON.Valid {
-		if err := json.Unmarshal([]byte(minutesJSON.String), &schedule.Minutes); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal minutes: %w", err)
-		}
+	if lastScheduledAt.Valid {
+		schedule.LastScheduledAt = &lastScheduledAt.Time
 	}
+	schedule.ScheduledFireCount = int(fireCount.Int64)
 
 	return schedule, nil
 }
+
+// RecordScheduleFire notes that the scheduler enqueued a job at the given time.
+// Tracked separately from runs so skipped or failed run creation is still visible.
+func (s *Store) RecordScheduleFire(jobID string, at time.Time) error {
+	_, err := s.db.Exec(
+		`UPDATE schedules SET last_scheduled_at = ?, scheduled_fire_count = COALESCE(scheduled_fire_count, 0) + 1
+		 WHERE job_id = ?`,
+		at, jobID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record schedule fire: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	internal "github.com/taskflow/taskflow/internal"
+)
+
+// TestUpdateJobReenableResetsFailures tests that re-enabling an auto-disabled job clears its failure streak
+func TestUpdateJobReenableResetsFailures(t *testing.T) {
+	s := NewTestStore(t)
+	defer s.Close()
+
+	job, err := s.CreateJob(&Job{Name: "flaky", Script: "exit 1", Enabled: true, AutoDisableAfterFailures: 1})
+	require.NoError(t, err)
+
+	failures, disabled, err := s.RecordJobOutcome(job.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, failures)
+	assert.True(t, disabled)
+
+	job, err = s.GetJob(job.ID)
+	require.NoError(t, err)
+	require.False(t, job.Enabled)
+
+	job.Enabled = true
+	require.NoError(t, s.UpdateJob(job))
+
+	job, err = s.GetJob(job.ID)
+	require.NoError(t, err)
+	assert.True(t, job.Enabled)
+	assert.Equal(t, 0, job.ConsecutiveFailures)
+}
+
+// TestJobMetadataRoundTrip tests that metadata survives create, update and metadata filtering
+func TestJobMetadataRoundTrip(t *testing.T) {
+	s := NewTestStore(t)
+	defer s.Close()
+
+	job, err := s.CreateJob(&Job{Name: "sync", Script: "true", Metadata: map[string]string{"service": "billing"}})
+	require.NoError(t, err)
+
+	got, err := s.GetJob(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"service": "billing"}, got.Metadata)
+
+	got.Metadata = map[string]string{"service": "payments", "owner": "team-a"}
+	require.NoError(t, s.UpdateJob(got))
+
+	matches, err := s.ListJobsByMetadata(nil, "owner", "team-a")
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, got.Metadata, matches[0].Metadata)
+
+	matches, err = s.ListJobsByMetadata(nil, "service", "billing")
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+
+	got.Metadata = nil
+	require.NoError(t, s.UpdateJob(got))
+	got, err = s.GetJob(job.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.Metadata)
+}
+
+// TestJobScriptExternalStorage tests that oversized scripts move to job_scripts and back
+// inline when an update shrinks them
+func TestJobScriptExternalStorage(t *testing.T) {
+	s := NewTestStore(t)
+	defer s.Close()
+
+	large := strings.Repeat("x", internal.InlineScriptMaxSize+1)
+	job, err := s.CreateJob(&Job{Name: "generated", Script: large, Enabled: true})
+	require.NoError(t, err)
+	assert.True(t, job.ScriptExternal)
+
+	var inline string
+	require.NoError(t, s.db.QueryRow(`SELECT script FROM jobs WHERE id = ?`, job.ID).Scan(&inline))
+	assert.Empty(t, inline)
+
+	stored, err := s.GetJob(job.ID)
+	require.NoError(t, err)
+	assert.True(t, stored.ScriptExternal)
+	assert.Equal(t, large, stored.Script)
+
+	stored.Script = "echo small"
+	require.NoError(t, s.UpdateJob(stored))
+	assert.False(t, stored.ScriptExternal)
+
+	var external int
+	require.NoError(t, s.db.QueryRow(`SELECT COUNT(*) FROM job_scripts WHERE job_id = ?`, job.ID).Scan(&external))
+	assert.Equal(t, 0, external)
+
+	jobs, err := s.ListJobs(nil)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "echo small", jobs[0].Script)
+}
+
+// TestUniqueJobNames tests that duplicate names are only rejected when enforcement is on
+func TestUniqueJobNames(t *testing.T) {
+	s := NewTestStore(t)
+	defer s.Close()
+
+	_, err := s.CreateJob(&Job{Name: "nightly", Script: "true"})
+	require.NoError(t, err)
+
+	// Disabled by default: duplicates are allowed
+	_, err = s.CreateJob(&Job{Name: "nightly", Script: "true"})
+	require.NoError(t, err)
+
+	s.SetEnforceUniqueJobNames(true)
+
+	_, err = s.CreateJob(&Job{Name: "nightly", Script: "true"})
+	assert.ErrorIs(t, err, ErrDuplicateJobName)
+
+	other, err := s.CreateJob(&Job{Name: "weekly", Script: "true"})
+	require.NoError(t, err)
+
+	// Saving a job under its own name is fine; taking another job's is not
+	require.NoError(t, s.UpdateJob(other))
+	other.Name = "nightly"
+	assert.ErrorIs(t, s.UpdateJob(other), ErrDuplicateJobName)
+
+	got, err := s.GetJob(other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "weekly", got.Name)
+}
+
+// TestGetJobScheduleToleratesCorruptField tests that a stored field that fails to parse is
+// treated as "any" and flagged instead of failing the whole schedule
+func TestGetJobScheduleToleratesCorruptField(t *testing.T) {
+	s := NewTestStore(t)
+	defer s.Close()
+
+	job, err := s.CreateJob(&Job{Name: "nightly", Script: "true"})
+	require.NoError(t, err)
+	require.NoError(t, s.SetJobSchedule(job.ID, &Schedule{Hours: []int{2}, Minutes: []int{30}}))
+
+	_, err = s.db.Exec(`UPDATE schedules SET hours = ? WHERE job_id = ?`, "[2, oops", job.ID)
+	require.NoError(t, err)
+
+	schedule, err := s.GetJobSchedule(job.ID)
+	require.NoError(t, err)
+	assert.Nil(t, schedule.Hours, "the corrupt field defaults to any")
+	assert.Equal(t, []int{30}, schedule.Minutes, "other fields still load")
+	assert.Equal(t, []string{"hours"}, schedule.CorruptFields)
+
+	// Saving the schedule again clears the corruption
+	require.NoError(t, s.SetJobSchedule(job.ID, &Schedule{Hours: []int{3}, Minutes: []int{30}}))
+	schedule, err = s.GetJobSchedule(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3}, schedule.Hours)
+	assert.Empty(t, schedule.CorruptFields)
+}
+
+// TestListDueJobs tests that only enabled, scheduled jobs whose next_run_at has come are
+// returned, and that saving a schedule clears next_run_at for recomputation
+func TestListDueJobs(t *testing.T) {
+	s := NewTestStore(t)
+	defer s.Close()
+
+	now := time.Now()
+	newJob := func(name string, enabled bool, next time.Time) *Job {
+		job, err := s.CreateJobWithSchedule(&Job{Name: name, Script: "echo hi", Enabled: enabled}, &Schedule{})
+		require.NoError(t, err)
+		require.NoError(t, s.SetJobNextRunAt(job.ID, next))
+		return job
+	}
+	due := newJob("due", true, now.Add(-time.Minute))
+	newJob("later", true, now.Add(time.Hour))
+	newJob("disabled", false, now.Add(-time.Minute))
+	paused := newJob("schedule-off", true, now.Add(-time.Minute))
+	_, err := s.db.Exec(`UPDATE jobs SET schedule_enabled = 0 WHERE id = ?`, paused.ID)
+	require.NoError(t, err)
+	// No schedule row, so never due
+	unscheduled, err := s.CreateJob(&Job{Name: "unscheduled", Script: "echo hi", Enabled: true})
+	require.NoError(t, err)
+	require.NoError(t, s.SetJobNextRunAt(unscheduled.ID, now.Add(-time.Minute)))
+
+	jobs, err := s.ListDueJobs(now)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, due.ID, jobs[0].ID)
+
+	missing, err := s.ListJobsWithoutNextRun()
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+
+	// Editing the schedule leaves next_run_at for the scheduler to recompute
+	require.NoError(t, s.SetJobSchedule(due.ID, &Schedule{Hours: []int{3}}))
+	next, err := s.GetJobNextRunAt(due.ID)
+	require.NoError(t, err)
+	assert.Nil(t, next)
+	missing, err = s.ListJobsWithoutNextRun()
+	require.NoError(t, err)
+	require.Len(t, missing, 1)
+	assert.Equal(t, due.ID, missing[0].ID)
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SchedulerLease is the single row of scheduler_lock: which instance may run the
+// scheduling loop, and until when without renewing. Times are stored as Unix
+// milliseconds so lease expiry compares correctly across instances.
+type SchedulerLease struct {
+	Holder     string    `json:"holder"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// AcquireSchedulerLease takes or renews the scheduler lease for holder until now+ttl. It
+// reports false, changing nothing, while another holder's lease has not expired. The
+// check and the write are one statement, so two instances can't both win.
+func (s *Store) AcquireSchedulerLease(holder string, now time.Time, ttl time.Duration) (bool, error) {
+	result, err := s.db.Exec(
+		`INSERT INTO scheduler_lock (id, holder, acquired_at, expires_at) VALUES (1, ?, ?, ?)
+		 ON CONFLICT(id) DO UPDATE SET
+		 acquired_at = CASE WHEN holder = excluded.holder THEN acquired_at ELSE excluded.acquired_at END,
+		 holder = excluded.holder, expires_at = excluded.expires_at
+		 WHERE holder = excluded.holder OR expires_at <= ?`,
+		holder, now.UnixMilli(), now.Add(ttl).UnixMilli(), now.UnixMilli(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire scheduler lease: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// ReleaseSchedulerLease gives up holder's lease so a standby can take over at once
+func (s *Store) ReleaseSchedulerLease(holder string) error {
+	if _, err := s.db.Exec(`DELETE FROM scheduler_lock WHERE holder = ?`, holder); err != nil {
+		return fmt.Errorf("failed to release scheduler lease: %w", err)
+	}
+	return nil
+}
+
+// GetSchedulerLease returns the current lease, or nil if no instance holds one
+func (s *Store) GetSchedulerLease() (*SchedulerLease, error) {
+	var lease SchedulerLease
+	var acquiredAt, expiresAt int64
+	err := s.db.QueryRow(`SELECT holder, acquired_at, expires_at FROM scheduler_lock WHERE id = 1`).
+		Scan(&lease.Holder, &acquiredAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduler lease: %w", err)
+	}
+	lease.AcquiredAt = time.UnixMilli(acquiredAt)
+	lease.ExpiresAt = time.UnixMilli(expiresAt)
+	return &lease, nil
+}
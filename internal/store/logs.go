@@ -1,7 +1,15 @@
 package store
 
 import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -14,7 +22,7 @@ func (s *Store) AddLog(runID, stream, content string) (*LogEntry, error) {
 		Content:   content,
 	}
 
-	result, err := s.db.Exec(
+	result, err := s.execRetry(
 		`INSERT INTO logs (run_id, timestamp, stream, content) VALUES (?, ?, ?, ?)`,
 		log.RunID, log.Timestamp, log.Stream, log.Content,
 	)
@@ -31,22 +39,104 @@ func (s *Store) AddLog(runID, stream, content string) (*LogEntry, error) {
 	return log, nil
 }
 
+// logBatchRows caps rows per INSERT statement to stay under SQLite's bound variable limit
+const logBatchRows = 200
+
+// AddLogs adds several log entries for a run in one transaction using multi-row inserts.
+// Entries are stored in slice order; a zero Timestamp is set to the current time. Once
+// the transaction commits, each entry's ID is set to the ID it was stored under.
+func (s *Store) AddLogs(runID string, entries []LogEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	ids := make([]int, 0, len(entries))
+	for start := 0; start < len(entries); start += logBatchRows {
+		batch := entries[start:min(start+logBatchRows, len(entries))]
+
+		var query strings.Builder
+		query.WriteString(`INSERT INTO logs (run_id, timestamp, stream, content, level) VALUES `)
+		args := make([]interface{}, 0, len(batch)*5)
+		for i, entry := range batch {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+			query.WriteString("(?, ?, ?, ?, ?)")
+			timestamp := entry.Timestamp
+			if timestamp.IsZero() {
+				timestamp = now
+			}
+			var level interface{}
+			if entry.Level != "" {
+				level = entry.Level
+			}
+			args = append(args, runID, timestamp, entry.Stream, entry.Content, level)
+		}
+
+		result, err := tx.Exec(query.String(), args...)
+		if err != nil {
+			return fmt.Errorf("failed to add logs: %w", err)
+		}
+		// The transaction is the only writer, so one statement's rows get consecutive IDs
+		last, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get log ids: %w", err)
+		}
+		for i := range batch {
+			ids = append(ids, int(last)-len(batch)+1+i)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	for i := range entries {
+		entries[i].ID = ids[i]
+	}
+	return nil
+}
+
 // GetLogs retrieves logs for a run
 func (s *Store) GetLogs(runID string) ([]*LogEntry, error) {
 	return s.GetLogsPaginated(runID, 0, 0)
 }
 
 // GetLogsPaginated retrieves logs for a run with limit/offset support.
-// If limit is 0, all logs are returned.
+// If limit is 0, all logs are returned. Archived (compressed) logs are
+// transparently decompressed and come before any rows written afterwards.
 func (s *Store) GetLogsPaginated(runID string, limit, offset int) ([]*LogEntry, error) {
+	archived, err := s.getArchivedLogs(runID)
+	if err != nil {
+		return nil, err
+	}
+	if archived != nil {
+		live, err := s.getLiveLogs(runID, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		return paginateLogs(append(archived, live...), limit, offset), nil
+	}
+
+	return s.getLiveLogs(runID, limit, offset)
+}
+
+// getLiveLogs reads uncompressed log rows for a run
+func (s *Store) getLiveLogs(runID string, limit, offset int) ([]*LogEntry, error) {
 	var query string
 	var args []interface{}
 
 	if limit > 0 {
-		query = `SELECT id, run_id, timestamp, stream, content FROM logs WHERE run_id = ? ORDER BY id ASC LIMIT ? OFFSET ?`
+		query = `SELECT id, run_id, timestamp, stream, content, level FROM logs WHERE run_id = ? ORDER BY id ASC LIMIT ? OFFSET ?`
 		args = []interface{}{runID, limit, offset}
 	} else {
-		query = `SELECT id, run_id, timestamp, stream, content FROM logs WHERE run_id = ? ORDER BY id ASC`
+		query = `SELECT id, run_id, timestamp, stream, content, level FROM logs WHERE run_id = ? ORDER BY id ASC`
 		args = []interface{}{runID}
 	}
 
@@ -56,30 +146,280 @@ func (s *Store) GetLogsPaginated(runID string, limit, offset int) ([]*LogEntry,
 	}
 	defer rows.Close()
 
+	return collectLogs(rows)
+}
+
+// GetLogsSince retrieves up to limit log entries for a run with an ID greater than afterID,
+// oldest first. If limit is 0, all newer entries are returned. Archived entries keep
+// their original IDs, so the same afterID works before and after compression.
+func (s *Store) GetLogsSince(runID string, afterID, limit int) ([]*LogEntry, error) {
+	archived, err := s.getArchivedLogs(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, run_id, timestamp, stream, content, level FROM logs WHERE run_id = ? AND id > ? ORDER BY id ASC`
+	args := []interface{}{runID, afterID}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get logs: %w", err)
+	}
+	defer rows.Close()
+
+	live, err := collectLogs(rows)
+	if err != nil {
+		return nil, err
+	}
+	if archived == nil {
+		return live, nil
+	}
+
+	logs := make([]*LogEntry, 0, len(live))
+	for _, entry := range archived {
+		if entry.ID > afterID {
+			logs = append(logs, entry)
+		}
+	}
+	return paginateLogs(append(logs, live...), limit, 0), nil
+}
+
+// GetLogsTail retrieves the last n log entries for a run, oldest first. Only those rows
+// are read, newest first and then reversed; an archived log is decompressed only when
+// the live rows written after it are fewer than n.
+func (s *Store) GetLogsTail(runID string, n int) ([]*LogEntry, error) {
+	rows, err := s.db.Query(
+		`SELECT id, run_id, timestamp, stream, content, level FROM logs WHERE run_id = ? ORDER BY id DESC LIMIT ?`,
+		runID, n,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get logs: %w", err)
+	}
+	defer rows.Close()
+
+	live, err := collectLogs(rows)
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(live)
+	if len(live) >= n {
+		return live, nil
+	}
+
+	archived, err := s.getArchivedLogs(runID)
+	if err != nil {
+		return nil, err
+	}
+	if missing := n - len(live); len(archived) > missing {
+		archived = archived[len(archived)-missing:]
+	}
+	return append(archived, live...), nil
+}
+
+// NormalizeLogLevel maps a parsed severity to the form it is stored and filtered in:
+// lower case, with common spellings folded ("WARNING" and "warn" are both "warn")
+func NormalizeLogLevel(level string) string {
+	level = strings.ToLower(strings.TrimSpace(level))
+	switch level {
+	case "warning":
+		return "warn"
+	case "err":
+		return "error"
+	case "information":
+		return "info"
+	}
+	return level
+}
+
+// GetLogsByLevel retrieves a run's log entries whose level is one of levels, oldest
+// first, with limit/offset applied to the matching entries (limit 0 = all). It also
+// returns how many entries match in total.
+func (s *Store) GetLogsByLevel(runID string, levels []string, limit, offset int) ([]*LogEntry, int, error) {
+	archived, err := s.getArchivedLogs(runID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(levels)), ", ")
+	args := []interface{}{runID}
+	for _, level := range levels {
+		args = append(args, level)
+	}
+	rows, err := s.db.Query(
+		`SELECT id, run_id, timestamp, stream, content, level FROM logs
+		 WHERE run_id = ? AND level IN (`+placeholders+`) ORDER BY id ASC`,
+		args...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get logs: %w", err)
+	}
+	defer rows.Close()
+
+	live, err := collectLogs(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	matching := make([]*LogEntry, 0, len(live))
+	for _, entry := range archived {
+		if slices.Contains(levels, entry.Level) {
+			matching = append(matching, entry)
+		}
+	}
+	matching = append(matching, live...)
+	return paginateLogs(matching, limit, offset), len(matching), nil
+}
+
+// collectLogs scans every remaining log row
+func collectLogs(rows *sql.Rows) ([]*LogEntry, error) {
 	logs := make([]*LogEntry, 0)
 	for rows.Next() {
 		log := &LogEntry{}
-		if err := rows.Scan(&log.ID, &log.RunID, &log.Timestamp, &log.Stream, &log.Content); err != nil {
+		var level sql.NullString
+		if err := rows.Scan(&log.ID, &log.RunID, &log.Timestamp, &log.Stream, &log.Content, &level); err != nil {
 			return nil, fmt.Errorf("failed to scan log: %w", err)
 		}
+		log.Level = level.String
 		logs = append(logs, log)
 	}
 
 	return logs, rows.Err()
 }
 
+// paginateLogs applies limit/offset to an in-memory slice (limit 0 = all)
+func paginateLogs(logs []*LogEntry, limit, offset int) []*LogEntry {
+	if offset >= len(logs) {
+		return make([]*LogEntry, 0)
+	}
+	logs = logs[offset:]
+	if limit > 0 && limit < len(logs) {
+		logs = logs[:limit]
+	}
+	return logs
+}
+
 // GetLogCount returns the total number of log entries for a run.
 func (s *Store) GetLogCount(runID string) (int, error) {
 	var count int
-	err := s.db.QueryRow(`SELECT COUNT(*) FROM logs WHERE run_id = ?`, runID).Scan(&count)
+	err := s.db.QueryRow(
+		`SELECT (SELECT COUNT(*) FROM logs WHERE run_id = ?) +
+		        COALESCE((SELECT line_count FROM logs_archive WHERE run_id = ?), 0)`,
+		runID, runID,
+	).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count logs: %w", err)
 	}
 	return count, nil
 }
 
-// DeleteLogs deletes logs for a run
+// ArchiveLogs compresses a finished run's log rows into logs_archive and removes
+// the per-line rows. Lines written later (or already-archived runs) are merged
+// on read, so calling it more than once is safe.
+func (s *Store) ArchiveLogs(runID string) error {
+	existing, err := s.getArchivedLogs(runID)
+	if err != nil {
+		return err
+	}
+	live, err := s.getLiveLogs(runID, 0, 0)
+	if err != nil {
+		return err
+	}
+	if len(live) == 0 {
+		return nil
+	}
+
+	all := append(existing, live...)
+	data, err := compressLogs(all)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		`INSERT INTO logs_archive (run_id, line_count, data, created_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(run_id) DO UPDATE SET line_count = excluded.line_count, data = excluded.data`,
+		runID, len(all), data, time.Now(),
+	); err != nil {
+		return fmt.Errorf("failed to archive logs: %w", err)
+	}
+
+	// Only drop the rows that were archived; anything appended meanwhile stays live
+	if _, err := tx.Exec(`DELETE FROM logs WHERE run_id = ? AND id <= ?`, runID, live[len(live)-1].ID); err != nil {
+		return fmt.Errorf("failed to delete archived log rows: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// getArchivedLogs returns the decompressed archive for a run, or nil if none exists
+func (s *Store) getArchivedLogs(runID string) ([]*LogEntry, error) {
+	var data []byte
+	err := s.db.QueryRow(`SELECT data FROM logs_archive WHERE run_id = ?`, runID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get archived logs: %w", err)
+	}
+
+	return decompressLogs(data)
+}
+
+// compressLogs gzips log entries as newline-delimited JSON
+func compressLogs(logs []*LogEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(zw)
+	for _, entry := range logs {
+		if err := enc.Encode(entry); err != nil {
+			return nil, fmt.Errorf("failed to encode log: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress logs: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressLogs reverses compressLogs
+func decompressLogs(data []byte) ([]*LogEntry, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress logs: %w", err)
+	}
+	defer zr.Close()
+
+	logs := make([]*LogEntry, 0)
+	dec := json.NewDecoder(zr)
+	for {
+		entry := &LogEntry{}
+		if err := dec.Decode(entry); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to decode archived log: %w", err)
+		}
+		logs = append(logs, entry)
+	}
+	return logs, nil
+}
+
+// DeleteLogs deletes logs for a run, including any compressed archive
 func (s *Store) DeleteLogs(runID string) error {
-	_, err := s.db.Exec(`DELETE FROM logs WHERE run_id = ?`, runID)
+	if _, err := s.db.Exec(`DELETE FROM logs WHERE run_id = ?`, runID); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM logs_archive WHERE run_id = ?`, runID)
 	return err
 }
@@ -0,0 +1,241 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestArchiveLogsRoundTrip tests that archived logs decompress to the original lines
+func TestArchiveLogsRoundTrip(t *testing.T) {
+	s := NewTestStore(t)
+	defer s.Close()
+
+	job, err := s.CreateJob(&Job{Name: "verbose", Script: "echo hi"})
+	require.NoError(t, err)
+	run, err := s.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	lines := []struct{ stream, content string }{
+		{"stdout", "starting"},
+		{"stderr", "warning: something odd"},
+		{"stdout", "done ✓"},
+		{"system", "Job finished"},
+	}
+	for _, l := range lines {
+		_, err := s.AddLog(run.ID, l.stream, l.content)
+		require.NoError(t, err)
+	}
+
+	original, err := s.GetLogs(run.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, s.ArchiveLogs(run.ID))
+
+	live, err := s.getLiveLogs(run.ID, 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, live, "per-line rows should be dropped after archiving")
+
+	archived, err := s.GetLogs(run.ID)
+	require.NoError(t, err)
+	require.Len(t, archived, len(original))
+	for i := range original {
+		assert.Equal(t, original[i].ID, archived[i].ID)
+		assert.Equal(t, original[i].Stream, archived[i].Stream)
+		assert.Equal(t, original[i].Content, archived[i].Content)
+		assert.True(t, original[i].Timestamp.Equal(archived[i].Timestamp))
+	}
+
+	// Lines added after archiving are appended, and counts/pagination span both
+	_, err = s.AddLog(run.ID, "system", "Retrying in 5 seconds")
+	require.NoError(t, err)
+
+	count, err := s.GetLogCount(run.ID)
+	require.NoError(t, err)
+	assert.Equal(t, len(lines)+1, count)
+
+	page, err := s.GetLogsPaginated(run.ID, 2, 3)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, "Job finished", page[0].Content)
+	assert.Equal(t, "Retrying in 5 seconds", page[1].Content)
+}
+
+// TestGetLogsSince tests resuming after a log ID, with a limit, across archived and live rows
+func TestGetLogsSince(t *testing.T) {
+	s := NewTestStore(t)
+	defer s.Close()
+
+	job, err := s.CreateJob(&Job{Name: "verbose", Script: "echo hi"})
+	require.NoError(t, err)
+	run, err := s.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	var ids []int
+	for i := 0; i < 6; i++ {
+		entry, err := s.AddLog(run.ID, "stdout", fmt.Sprintf("line %d", i))
+		require.NoError(t, err)
+		ids = append(ids, entry.ID)
+	}
+
+	contents := func(logs []*LogEntry) []string {
+		var out []string
+		for _, entry := range logs {
+			out = append(out, entry.Content)
+		}
+		return out
+	}
+
+	logs, err := s.GetLogsSince(run.ID, ids[1], 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"line 2", "line 3", "line 4"}, contents(logs))
+
+	all, err := s.GetLogsSince(run.ID, 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, all, 6)
+
+	// Archive the first lines and keep writing; IDs stay stable across the boundary
+	require.NoError(t, s.ArchiveLogs(run.ID))
+	_, err = s.AddLog(run.ID, "stdout", "line 6")
+	require.NoError(t, err)
+
+	logs, err = s.GetLogsSince(run.ID, ids[3], 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"line 4", "line 5", "line 6"}, contents(logs))
+
+	logs, err = s.GetLogsSince(run.ID, ids[3], 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"line 4", "line 5"}, contents(logs))
+}
+
+// TestGetLogsTail tests that exactly the last n lines come back oldest first, including
+// lines split across an archive and rows written after it
+func TestGetLogsTail(t *testing.T) {
+	s := NewTestStore(t)
+	defer s.Close()
+
+	job, err := s.CreateJob(&Job{Name: "chatty", Script: "echo hi"})
+	require.NoError(t, err)
+	run, err := s.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	for i := 0; i < 8; i++ {
+		_, err := s.AddLog(run.ID, "stdout", fmt.Sprintf("line %d", i))
+		require.NoError(t, err)
+	}
+
+	contents := func(logs []*LogEntry) []string {
+		var out []string
+		for _, entry := range logs {
+			out = append(out, entry.Content)
+		}
+		return out
+	}
+
+	logs, err := s.GetLogsTail(run.ID, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"line 5", "line 6", "line 7"}, contents(logs))
+
+	logs, err = s.GetLogsTail(run.ID, 50)
+	require.NoError(t, err)
+	assert.Len(t, logs, 8)
+
+	require.NoError(t, s.ArchiveLogs(run.ID))
+	_, err = s.AddLog(run.ID, "stdout", "line 8")
+	require.NoError(t, err)
+
+	logs, err = s.GetLogsTail(run.ID, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"line 6", "line 7", "line 8"}, contents(logs))
+}
+
+// TestAddLogsPersistsInOrder tests that a batch insert stores every line in order,
+// matching the result of inserting the same lines one at a time, and reports their IDs
+func TestAddLogsPersistsInOrder(t *testing.T) {
+	s := NewTestStore(t)
+	defer s.Close()
+
+	job, err := s.CreateJob(&Job{Name: "chatty", Script: "echo hi"})
+	require.NoError(t, err)
+	perLine, err := s.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+	batched, err := s.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	// More than logBatchRows so the batch spans several INSERT statements
+	entries := make([]LogEntry, 2*logBatchRows+17)
+	for i := range entries {
+		entries[i] = LogEntry{Stream: "stdout", Content: fmt.Sprintf("line %d", i)}
+		if i%3 == 0 {
+			entries[i].Stream = "stderr"
+		}
+	}
+
+	for _, e := range entries {
+		_, err := s.AddLog(perLine.ID, e.Stream, e.Content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, s.AddLogs(batched.ID, entries))
+
+	want, err := s.GetLogs(perLine.ID)
+	require.NoError(t, err)
+	got, err := s.GetLogs(batched.ID)
+	require.NoError(t, err)
+
+	require.Len(t, got, len(entries))
+	require.Len(t, want, len(entries))
+	for i := range entries {
+		assert.Equal(t, batched.ID, got[i].RunID)
+		assert.Equal(t, want[i].Stream, got[i].Stream)
+		assert.Equal(t, want[i].Content, got[i].Content)
+		assert.False(t, got[i].Timestamp.IsZero())
+		assert.Equal(t, got[i].ID, entries[i].ID, "AddLogs should report each stored ID")
+	}
+
+	require.NoError(t, s.AddLogs(batched.ID, nil))
+	count, err := s.GetLogCount(batched.ID)
+	require.NoError(t, err)
+	assert.Equal(t, len(entries), count)
+}
+
+func benchmarkLogEntries(n int) []LogEntry {
+	entries := make([]LogEntry, n)
+	for i := range entries {
+		entries[i] = LogEntry{Stream: "stdout", Content: fmt.Sprintf("line %d", i)}
+	}
+	return entries
+}
+
+func BenchmarkAddLogPerLine(b *testing.B) {
+	s := NewTestStore(b)
+	defer s.Close()
+	job, _ := s.CreateJob(&Job{Name: "bench", Script: "echo hi"})
+	run, _ := s.CreateRun(job.ID, "manual")
+	entries := benchmarkLogEntries(100)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, e := range entries {
+			if _, err := s.AddLog(run.ID, e.Stream, e.Content); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkAddLogsBatch(b *testing.B) {
+	s := NewTestStore(b)
+	defer s.Close()
+	job, _ := s.CreateJob(&Job{Name: "bench", Script: "echo hi"})
+	run, _ := s.CreateRun(job.ID, "manual")
+	entries := benchmarkLogEntries(100)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := s.AddLogs(run.ID, entries); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
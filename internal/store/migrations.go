@@ -3,6 +3,7 @@ package store
 import (
 	"database/sql"
 	"fmt"
+	"time"
 )
 
 // Migrations contains all database schema migrations
@@ -136,6 +137,297 @@ CREATE TABLE IF NOT EXISTS settings (
     value TEXT,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
+`,
+	},
+	{
+		name: "009_add_jobs_notify_exit_codes",
+		query: `
+ALTER TABLE jobs ADD COLUMN notify_exit_codes TEXT;
+`,
+	},
+	{
+		name: "010_add_jobs_resource_lock",
+		query: `
+ALTER TABLE jobs ADD COLUMN resource_lock TEXT DEFAULT '';
+`,
+	},
+	{
+		name: "011_add_runs_created_at",
+		query: `
+ALTER TABLE runs ADD COLUMN created_at DATETIME;
+UPDATE runs SET created_at = COALESCE(started_at, CURRENT_TIMESTAMP) WHERE created_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_runs_created_at_id ON runs(created_at, id);
+`,
+	},
+	{
+		name: "012_add_jobs_retry_on_exit_codes",
+		query: `
+ALTER TABLE jobs ADD COLUMN retry_on_exit_codes TEXT;
+`,
+	},
+	{
+		name: "013_add_runs_cpu_seconds",
+		query: `
+ALTER TABLE runs ADD COLUMN cpu_seconds REAL;
+`,
+	},
+	{
+		name: "014_add_jobs_auto_disable",
+		query: `
+ALTER TABLE jobs ADD COLUMN auto_disable_after_failures INTEGER DEFAULT 0;
+ALTER TABLE jobs ADD COLUMN consecutive_failures INTEGER DEFAULT 0;
+`,
+	},
+	{
+		name: "015_add_jobs_schedule_enabled",
+		query: `
+ALTER TABLE jobs ADD COLUMN schedule_enabled BOOLEAN DEFAULT 1;
+`,
+	},
+	{
+		name: "016_add_runs_output_preview",
+		query: `
+ALTER TABLE runs ADD COLUMN output_preview TEXT;
+`,
+	},
+	{
+		name: "017_create_logs_archive",
+		query: `
+CREATE TABLE IF NOT EXISTS logs_archive (
+    run_id TEXT PRIMARY KEY REFERENCES runs(id) ON DELETE CASCADE,
+    line_count INTEGER NOT NULL,
+    data BLOB NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+`,
+	},
+	{
+		name: "018_add_schedules_fire_tracking",
+		query: `
+ALTER TABLE schedules ADD COLUMN last_scheduled_at DATETIME;
+ALTER TABLE schedules ADD COLUMN scheduled_fire_count INTEGER DEFAULT 0;
+`,
+	},
+	{
+		name: "019_add_jobs_nice",
+		query: `
+ALTER TABLE jobs ADD COLUMN nice INTEGER DEFAULT 0;
+`,
+	},
+	{
+		name: "020_create_notification_outbox",
+		query: `
+CREATE TABLE IF NOT EXISTS notification_outbox (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    job_id TEXT NOT NULL,
+    run_id TEXT NOT NULL,
+    recipients TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_due ON notification_outbox(status, next_attempt_at);
+`,
+	},
+	{
+		name: "021_add_jobs_interpreter",
+		query: `
+ALTER TABLE jobs ADD COLUMN interpreter TEXT DEFAULT '';
+`,
+	},
+	{
+		name: "022_add_jobs_timeout_warning",
+		query: `
+ALTER TABLE jobs ADD COLUMN timeout_warn_percent INTEGER DEFAULT 0;
+ALTER TABLE jobs ADD COLUMN timeout_warn_notify BOOLEAN DEFAULT 0;
+`,
+	},
+	{
+		name: "023_add_jobs_blackout_windows",
+		query: `
+ALTER TABLE jobs ADD COLUMN blackout_windows TEXT;
+`,
+	},
+	{
+		name: "024_add_jobs_output_patterns",
+		query: `
+ALTER TABLE jobs ADD COLUMN success_pattern TEXT DEFAULT '';
+ALTER TABLE jobs ADD COLUMN failure_pattern TEXT DEFAULT '';
+`,
+	},
+	{
+		name: "025_add_jobs_metadata",
+		query: `
+ALTER TABLE jobs ADD COLUMN metadata TEXT;
+`,
+	},
+	{
+		name: "026_add_jobs_hook_scripts",
+		query: `
+ALTER TABLE jobs ADD COLUMN pre_script TEXT DEFAULT '';
+ALTER TABLE jobs ADD COLUMN post_script TEXT DEFAULT '';
+`,
+	},
+	{
+		name: "027_create_job_templates",
+		query: `
+CREATE TABLE IF NOT EXISTS job_templates (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT DEFAULT '',
+    definition TEXT NOT NULL,
+    created_by INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+`,
+	},
+	{
+		name: "028_add_jobs_pause_on_failure",
+		query: `
+ALTER TABLE jobs ADD COLUMN pause_on_failure INTEGER DEFAULT 0;
+ALTER TABLE jobs ADD COLUMN schedule_paused INTEGER DEFAULT 0;
+`,
+	},
+	{
+		name: "029_create_job_scripts",
+		query: `
+ALTER TABLE jobs ADD COLUMN script_external INTEGER DEFAULT 0;
+CREATE TABLE IF NOT EXISTS job_scripts (
+    job_id TEXT PRIMARY KEY REFERENCES jobs(id) ON DELETE CASCADE,
+    script TEXT NOT NULL
+);
+`,
+	},
+	{
+		name: "030_add_jobs_manual_while_scheduled",
+		query: `
+ALTER TABLE jobs ADD COLUMN manual_while_scheduled TEXT DEFAULT '';
+`,
+	},
+	{
+		name: "031_add_jobs_login_shell",
+		query: `
+ALTER TABLE jobs ADD COLUMN login_shell INTEGER DEFAULT 0;
+`,
+	},
+	{
+		name: "032_add_runs_tags",
+		query: `
+ALTER TABLE runs ADD COLUMN tags TEXT;
+`,
+	},
+	{
+		name: "033_add_runs_acknowledged",
+		query: `
+ALTER TABLE runs ADD COLUMN acknowledged_by INTEGER;
+ALTER TABLE runs ADD COLUMN acknowledged_at DATETIME;
+`,
+	},
+	{
+		name: "034_add_runs_logs_incomplete",
+		query: `
+ALTER TABLE runs ADD COLUMN logs_incomplete INTEGER DEFAULT 0;
+`,
+	},
+	{
+		name: "035_add_runs_attempts",
+		query: `
+ALTER TABLE runs ADD COLUMN attempt INTEGER DEFAULT 1;
+ALTER TABLE runs ADD COLUMN parent_run_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_runs_parent_run_id ON runs(parent_run_id);
+`,
+	},
+	{
+		name: "036_add_jobs_notify_channels",
+		query: `
+ALTER TABLE jobs ADD COLUMN notify_channels TEXT;
+`,
+	},
+	{
+		name: "037_add_runs_command_snapshot",
+		query: `
+ALTER TABLE runs ADD COLUMN command_snapshot TEXT;
+`,
+	},
+	{
+		name: "038_create_agents",
+		query: `
+CREATE TABLE IF NOT EXISTS agents (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL,
+    capacity INTEGER NOT NULL DEFAULT 1,
+    last_heartbeat DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+ALTER TABLE runs ADD COLUMN agent_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_runs_agent_id ON runs(agent_id);
+`,
+	},
+	{
+		name: "039_add_jobs_next_run_at",
+		query: `
+ALTER TABLE jobs ADD COLUMN next_run_at DATETIME;
+CREATE INDEX IF NOT EXISTS idx_jobs_next_run_at ON jobs(next_run_at);
+`,
+	},
+	{
+		name: "040_add_schedules_calendar",
+		query: `
+ALTER TABLE schedules ADD COLUMN holidays TEXT;
+ALTER TABLE schedules ADD COLUMN solar TEXT;
+`,
+	},
+	{
+		name: "041_add_runs_log_volume",
+		query: `
+ALTER TABLE runs ADD COLUMN log_bytes_total INTEGER DEFAULT 0;
+ALTER TABLE runs ADD COLUMN log_truncated INTEGER DEFAULT 0;
+`,
+	},
+	{
+		name: "042_add_run_approval",
+		query: `
+ALTER TABLE jobs ADD COLUMN requires_approval INTEGER DEFAULT 0;
+ALTER TABLE runs ADD COLUMN triggered_by INTEGER;
+ALTER TABLE runs ADD COLUMN approved_by INTEGER;
+ALTER TABLE runs ADD COLUMN approved_at DATETIME;
+`,
+	},
+	{
+		name: "043_create_scheduler_lock",
+		query: `
+CREATE TABLE IF NOT EXISTS scheduler_lock (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    holder TEXT NOT NULL,
+    acquired_at INTEGER NOT NULL,
+    expires_at INTEGER NOT NULL
+);
+`,
+	},
+	{
+		name: "044_add_log_levels",
+		query: `
+ALTER TABLE jobs ADD COLUMN log_level_pattern TEXT DEFAULT '';
+ALTER TABLE logs ADD COLUMN level TEXT;
+`,
+	},
+	{
+		name: "045_add_schedule_run_at",
+		query: `
+ALTER TABLE schedules ADD COLUMN run_at DATETIME;
+`,
+	},
+	{
+		name: "046_add_notification_outbox_channel",
+		query: `
+ALTER TABLE notification_outbox ADD COLUMN channel TEXT NOT NULL DEFAULT 'email';
 `,
 	},
 }
@@ -178,3 +470,44 @@ CREATE TABLE IF NOT EXISTS schema_migrations (
 
 	return nil
 }
+
+// MigrationStatus reports whether a defined migration has been applied to the database
+type MigrationStatus struct {
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// ListMigrations returns every migration in the order it runs, with the time each applied
+// one was recorded in schema_migrations
+func (s *Store) ListMigrations() ([]MigrationStatus, error) {
+	rows, err := s.db.Query("SELECT name, executed_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]*time.Time)
+	for rows.Next() {
+		var name string
+		var executedAt sql.NullTime
+		if err := rows.Scan(&name, &executedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		applied[name] = nil
+		if executedAt.Valid {
+			t := executedAt.Time
+			applied[name] = &t
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		appliedAt, ok := applied[m.name]
+		statuses = append(statuses, MigrationStatus{Name: m.name, Applied: ok, AppliedAt: appliedAt})
+	}
+	return statuses, nil
+}
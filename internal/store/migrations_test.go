@@ -0,0 +1,35 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestListMigrations tests that a fresh store reports every migration as applied and that
+// a migration missing from schema_migrations is reported as pending
+func TestListMigrations(t *testing.T) {
+	s := NewTestStore(t)
+	defer s.Close()
+
+	statuses, err := s.ListMigrations()
+	require.NoError(t, err)
+	require.Len(t, statuses, len(migrations))
+	for i, status := range statuses {
+		assert.Equal(t, migrations[i].name, status.Name)
+		assert.True(t, status.Applied, status.Name)
+		assert.NotNil(t, status.AppliedAt, status.Name)
+	}
+
+	last := migrations[len(migrations)-1].name
+	_, err = s.db.Exec("DELETE FROM schema_migrations WHERE name = ?", last)
+	require.NoError(t, err)
+
+	statuses, err = s.ListMigrations()
+	require.NoError(t, err)
+	pending := statuses[len(statuses)-1]
+	assert.Equal(t, last, pending.Name)
+	assert.False(t, pending.Applied)
+	assert.Nil(t, pending.AppliedAt)
+}
@@ -20,46 +20,134 @@ type User struct {
 
 // Job represents a scheduled job
 type Job struct {
-	ID                string         `json:"id"`
-	Name              string         `json:"name"`
-	Description       string         `json:"description"`
-	Script            string         `json:"script"`
-	WorkingDir        string         `json:"working_dir"`
-	TimeoutSeconds    int            `json:"timeout_seconds"`
-	RetryCount        int            `json:"retry_count"`
-	RetryDelaySeconds int            `json:"retry_delay_seconds"`
-	Enabled           bool           `json:"enabled"`
-	NotifyEmails      string         `json:"notify_emails"`
-	NotifyOn          string         `json:"notify_on"` // "always", "failure", "success"
-	Timezone          string         `json:"timezone"`
-	CreatedBy         int            `json:"created_by"`
-	CreatedAt         time.Time      `json:"created_at"`
-	UpdatedAt         time.Time      `json:"updated_at"`
+	ID                       string            `json:"id"`
+	Name                     string            `json:"name"`
+	Description              string            `json:"description"`
+	Script                   string            `json:"script"`
+	ScriptExternal           bool              `json:"script_external"` // script is stored in job_scripts; list queries leave Script empty
+	WorkingDir               string            `json:"working_dir"`
+	TimeoutSeconds           int               `json:"timeout_seconds"`
+	RetryCount               int               `json:"retry_count"`
+	RetryDelaySeconds        int               `json:"retry_delay_seconds"`
+	RetryOnExitCodes         []int             `json:"retry_on_exit_codes"` // nil = retry on any failure
+	Enabled                  bool              `json:"enabled"`
+	ScheduleEnabled          bool              `json:"schedule_enabled"` // false = manual triggers only
+	NotifyEmails             string            `json:"notify_emails"`
+	NotifyOn                 string            `json:"notify_on"`         // "always", "failure", "success"
+	NotifyExitCodes          []int             `json:"notify_exit_codes"` // nil = any exit code
+	NotifyChannels           []string          `json:"notify_channels"`   // "teams", "pagerduty"; nil = the global default channels
+	Timezone                 string            `json:"timezone"`
+	ResourceLock             string            `json:"resource_lock"`               // jobs sharing a lock name never run concurrently
+	AutoDisableAfterFailures int               `json:"auto_disable_after_failures"` // 0 = never auto-disable
+	ConsecutiveFailures      int               `json:"consecutive_failures"`
+	Nice                     int               `json:"nice"`                   // 0-19 scheduling niceness (Linux only)
+	Interpreter              string            `json:"interpreter"`            // "" = platform default (bash on Unix, cmd on Windows)
+	LoginShell               bool              `json:"login_shell"`            // run as a login shell (bash -lc) so profile scripts are sourced
+	TimeoutWarnPercent       int               `json:"timeout_warn_percent"`   // 0 = no warning; else warn at this % of the timeout
+	TimeoutWarnNotify        bool              `json:"timeout_warn_notify"`    // also email notify_emails when the warning fires
+	BlackoutWindows          []BlackoutWindow  `json:"blackout_windows"`       // scheduled fires inside any window are skipped
+	SuccessPattern           string            `json:"success_pattern"`        // if set, output must match this regex to succeed
+	FailurePattern           string            `json:"failure_pattern"`        // output matching this regex fails the run
+	LogLevelPattern          string            `json:"log_level_pattern"`      // regex whose first (or "level") group is each output line's severity
+	Metadata                 map[string]string `json:"metadata"`               // free-form tags for integrations, e.g. ticket or service IDs
+	PreScript                string            `json:"pre_script"`             // setup run before Script; its failure fails the run
+	PostScript               string            `json:"post_script"`            // teardown always run after Script; never changes the status
+	PauseOnFailure           bool              `json:"pause_on_failure"`       // a failed scheduled run pauses the schedule
+	ManualWhileScheduled     string            `json:"manual_while_scheduled"` // "" or "allow", "skip", "replace": manual triggers while a scheduled run is active
+	RequiresApproval         bool              `json:"requires_approval"`      // manual triggers wait for another admin's approval
+	SchedulePaused           bool              `json:"schedule_paused"`        // set by pause_on_failure; cleared by a successful run or re-enabling the schedule
+	CreatedBy                int               `json:"created_by"`
+	CreatedAt                time.Time         `json:"created_at"`
+	UpdatedAt                time.Time         `json:"updated_at"`
+}
+
+// BlackoutWindow is a period during which a job's schedule does not fire. Start and End are
+// either "HH:MM" in the job's timezone for a window recurring daily (or on Weekdays only,
+// 0-6 Sun-Sat), or RFC3339 timestamps for a one-off window.
+type BlackoutWindow struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Weekdays []int  `json:"weekdays,omitempty"`
 }
 
 // Schedule represents cron-like scheduling
 type Schedule struct {
-	ID       int            `json:"id"`
-	JobID    string         `json:"job_id"`
-	Years    []int          `json:"years"`    // nil = any
-	Months   []int          `json:"months"`   // 1-12
-	Days     []int          `json:"days"`     // 1-31
-	Weekdays []int          `json:"weekdays"` // 0-6 (Sun-Sat)
-	Hours    []int          `json:"hours"`    // 0-23
-	Minutes  []int          `json:"minutes"`  // 0-59
+	ID                 int          `json:"id"`
+	JobID              string       `json:"job_id"`
+	Years              []int        `json:"years"`                    // nil = any
+	Months             []int        `json:"months"`                   // 1-12
+	Days               []int        `json:"days"`                     // 1-31
+	Weekdays           []int        `json:"weekdays"`                 // 0-6 (Sun-Sat)
+	Hours              []int        `json:"hours"`                    // 0-23
+	Minutes            []int        `json:"minutes"`                  // 0-59
+	Holidays           []string     `json:"holidays"`                 // YYYY-MM-DD dates the schedule never fires on
+	Solar              *SolarAnchor `json:"solar"`                    // when set, replaces hours/minutes with a sunrise/sunset time
+	RunAt              *time.Time   `json:"run_at"`                   // when set, a one-shot that fires once at this minute instead of recurring
+	LastScheduledAt    *time.Time   `json:"last_scheduled_at"`        // last time the scheduler enqueued this job
+	ScheduledFireCount int          `json:"scheduled_fire_count"`     // number of times the scheduler enqueued this job
+	CorruptFields      []string     `json:"corrupt_fields,omitempty"` // stored fields that failed to parse and are treated as "any"; saving the schedule fixes them
+}
+
+// SolarAnchor ties a schedule's time of day to sunrise or sunset at a location, worked
+// out for each day
+type SolarAnchor struct {
+	Event         string  `json:"event"`          // "sunrise" or "sunset"
+	Latitude      float64 `json:"latitude"`       // -90 to 90, north positive
+	Longitude     float64 `json:"longitude"`      // -180 to 180, east positive
+	OffsetMinutes int     `json:"offset_minutes"` // shift from the event, e.g. -30 for half an hour before
 }
 
 // Run represents a job execution
 type Run struct {
-	ID          string         `json:"id"`
-	JobID       string         `json:"job_id"`
-	Status      string         `json:"status"` // "pending", "running", "success", "failure", "timeout", "cancelled"
-	ExitCode    *int           `json:"exit_code"`
-	TriggerType string         `json:"trigger_type"` // "scheduled", "manual"
-	StartedAt   *time.Time     `json:"started_at"`
-	FinishedAt  *time.Time     `json:"finished_at"`
-	DurationMs  *int64         `json:"duration_ms"`
-	ErrorMsg    *string        `json:"error_message"`
+	ID              string           `json:"id"`
+	JobID           string           `json:"job_id"`
+	Status          string           `json:"status"` // "awaiting_approval", "pending", "running", "success", "failure", "timeout", "cancelled"
+	ExitCode        *int             `json:"exit_code"`
+	TriggerType     string           `json:"trigger_type"` // "scheduled", "manual", "test"
+	StartedAt       *time.Time       `json:"started_at"`
+	FinishedAt      *time.Time       `json:"finished_at"`
+	DurationMs      *int64           `json:"duration_ms"`
+	ErrorMsg        *string          `json:"error_message"`
+	CPUSeconds      *float64         `json:"cpu_seconds"`     // user + system CPU time of the script process
+	OutputPreview   *string          `json:"output_preview"`  // last stderr (or stdout) line, capped
+	Tags            []string         `json:"tags,omitempty"`  // labels set at trigger time, e.g. "hotfix"
+	AcknowledgedBy  *int             `json:"acknowledged_by"` // user who took ownership of the run, e.g. a failure
+	AcknowledgedAt  *time.Time       `json:"acknowledged_at"`
+	LogsIncomplete  bool             `json:"logs_incomplete"`  // some output lines could not be stored
+	Attempt         int              `json:"attempt"`          // 1 for the first try, then one more per retry
+	ParentRunID     *string          `json:"parent_run_id"`    // first attempt's run, set on retries
+	CommandSnapshot *CommandSnapshot `json:"command_snapshot"` // how the script was launched; nil until it starts
+	AgentID         *string          `json:"agent_id"`         // remote agent the run was dispatched to; nil = executed locally
+	LogBytesTotal   int64            `json:"log_bytes_total"`  // bytes of output the script wrote, stored or not
+	LogTruncated    bool             `json:"log_truncated"`    // output past MAX_RUN_LOG_BYTES was not stored
+	TriggeredBy     *int             `json:"triggered_by"`     // user who triggered a manual run; nil for scheduled runs and older runs
+	ApprovedBy      *int             `json:"approved_by"`      // admin who approved a run of a requires_approval job
+	ApprovedAt      *time.Time       `json:"approved_at"`
+	CreatedAt       time.Time        `json:"created_at"`
+}
+
+// CommandSnapshot records how a run's script was launched, for audits and reproducing a
+// run after the job has changed. It holds a hash of the script, never the script itself.
+type CommandSnapshot struct {
+	Interpreter    string `json:"interpreter"` // resolved, so the platform default is named
+	LoginShell     bool   `json:"login_shell"`
+	WorkingDir     string `json:"working_dir"`
+	ScriptSHA256   string `json:"script_sha256"`
+	ScriptBytes    int    `json:"script_bytes"`
+	ScriptFile     bool   `json:"script_file"` // passed to the interpreter as a temp file rather than an argument
+	TimeoutSeconds int    `json:"timeout_seconds"`
+	Nice           int    `json:"nice"`
+}
+
+// Agent is a remote executor that runs dispatched jobs on another host
+type Agent struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	URL           string     `json:"url"`      // base URL the server POSTs runs to
+	Capacity      int        `json:"capacity"` // concurrent runs the agent accepts; also its round-robin weight
+	ActiveRuns    int        `json:"active_runs"`
+	LastHeartbeat *time.Time `json:"last_heartbeat"`
+	CreatedAt     time.Time  `json:"created_at"`
 }
 
 // LogEntry represents a log line from job execution
@@ -69,6 +157,7 @@ type LogEntry struct {
 	Timestamp time.Time `json:"timestamp"`
 	Stream    string    `json:"stream"` // "stdout", "stderr", "system"
 	Content   string    `json:"content"`
+	Level     string    `json:"level,omitempty"` // severity parsed with the job's log_level_pattern, e.g. "error"
 }
 
 // Metric represents resource usage at a point in time
@@ -104,6 +193,36 @@ type Setting struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// JobTemplate is a reusable partial job definition that new jobs can be created from.
+// Definition is a job request body (script, timeout, notify settings, schedule, ...)
+// kept as JSON so any subset of job fields can be stored.
+type JobTemplate struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Definition  json.RawMessage `json:"definition"`
+	CreatedBy   int             `json:"created_by"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// OutboxNotification is a rendered notification awaiting (or done with) delivery
+type OutboxNotification struct {
+	ID            int64      `json:"id"`
+	JobID         string     `json:"job_id"`
+	RunID         string     `json:"run_id"`
+	Channel       string     `json:"channel"`    // "email", "teams" or "pagerduty"
+	Recipients    string     `json:"recipients"` // comma-separated; empty for webhook channels
+	Subject       string     `json:"subject"`
+	Body          string     `json:"body"`
+	Status        string     `json:"status"` // "pending", "delivered", "failed"
+	Attempts      int        `json:"attempts"`
+	LastError     *string    `json:"last_error"`
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
 // Scan helpers for JSON array fields
 func (s *Schedule) ScanYears(val interface{}) error {
 	return scanJSONArray(val, &s.Years)
@@ -150,6 +269,54 @@ func scanJSONArray(val interface{}, target *[]int) error {
 	return nil
 }
 
+// intsToNullJSON encodes an int slice as a JSON array, storing NULL when empty
+func intsToNullJSON(vals []int) (sql.NullString, error) {
+	if len(vals) == 0 {
+		return sql.NullString{Valid: false}, nil
+	}
+	data, err := json.Marshal(vals)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// stringsToNullJSON encodes a string slice as JSON, storing NULL when empty
+func stringsToNullJSON(vals []string) (sql.NullString, error) {
+	if len(vals) == 0 {
+		return sql.NullString{Valid: false}, nil
+	}
+	data, err := json.Marshal(vals)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// blackoutWindowsToNullJSON encodes blackout windows as JSON, storing NULL when empty
+func blackoutWindowsToNullJSON(windows []BlackoutWindow) (sql.NullString, error) {
+	if len(windows) == 0 {
+		return sql.NullString{Valid: false}, nil
+	}
+	data, err := json.Marshal(windows)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// metadataToNullJSON encodes job metadata as a JSON object, storing NULL when empty
+func metadataToNullJSON(metadata map[string]string) (sql.NullString, error) {
+	if len(metadata) == 0 {
+		return sql.NullString{Valid: false}, nil
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
 // NullInt64ToPointer converts sql.NullInt64 to *int64
 func NullInt64ToPointer(n sql.NullInt64) *int64 {
 	if n.Valid {
@@ -0,0 +1,173 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	internal "github.com/taskflow/taskflow/internal"
+)
+
+const outboxColumns = `id, job_id, run_id, channel, recipients, subject, body, status, attempts, last_error, next_attempt_at, created_at, updated_at`
+
+// EnqueueNotification stores a pending notification already claimed for its first
+// delivery attempt, so the outbox worker leaves it alone unless that attempt records no
+// outcome within NotificationClaimTimeout
+func (s *Store) EnqueueNotification(n *OutboxNotification) (*OutboxNotification, error) {
+	now := time.Now()
+	claimedUntil := now.Add(internal.NotificationClaimTimeout)
+	if n.Channel == "" {
+		n.Channel = internal.NotifyChannelEmail
+	}
+	n.Status = "pending"
+	n.Attempts = 0
+	n.LastError = nil
+	n.NextAttemptAt = &claimedUntil
+	n.CreatedAt = now
+	n.UpdatedAt = now
+
+	result, err := s.db.Exec(
+		`INSERT INTO notification_outbox (job_id, run_id, channel, recipients, subject, body, status, next_attempt_at, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		n.JobID, n.RunID, n.Channel, n.Recipients, n.Subject, n.Body, n.Status, n.NextAttemptAt, n.CreatedAt, n.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue notification: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification id: %w", err)
+	}
+
+	n.ID = id
+	return n, nil
+}
+
+// GetOutboxNotification retrieves an outbox entry by ID
+func (s *Store) GetOutboxNotification(id int64) (*OutboxNotification, error) {
+	n, err := scanOutboxNotification(s.db.QueryRow(
+		`SELECT `+outboxColumns+` FROM notification_outbox WHERE id = ?`, id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.New("notification not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification: %w", err)
+	}
+	return n, nil
+}
+
+// ClaimDueNotifications atomically claims up to limit pending notifications whose next
+// attempt is at or before now, oldest first, by pushing their next attempt out by
+// NotificationClaimTimeout. A notification is claimed by one caller only, so concurrent
+// workers never deliver it twice; the attempt's outcome replaces the claim.
+func (s *Store) ClaimDueNotifications(now time.Time, limit int) ([]*OutboxNotification, error) {
+	rows, err := s.db.Query(
+		`UPDATE notification_outbox SET next_attempt_at = ?, updated_at = ?
+		 WHERE id IN (SELECT id FROM notification_outbox
+		              WHERE status = 'pending' AND next_attempt_at <= ?
+		              ORDER BY next_attempt_at ASC, id ASC LIMIT ?)
+		 RETURNING `+outboxColumns,
+		now.Add(internal.NotificationClaimTimeout), now, now, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due notifications: %w", err)
+	}
+	defer rows.Close()
+
+	claimed, err := collectOutboxNotifications(rows)
+	if err != nil {
+		return nil, err
+	}
+	// RETURNING has no defined order
+	sort.Slice(claimed, func(i, j int) bool { return claimed[i].ID < claimed[j].ID })
+	return claimed, nil
+}
+
+// ListOutboxNotifications returns outbox entries newest first, optionally filtered by status
+func (s *Store) ListOutboxNotifications(status string, limit, offset int) ([]*OutboxNotification, error) {
+	query := `SELECT ` + outboxColumns + ` FROM notification_outbox`
+	args := []interface{}{}
+	if status != "" {
+		query += ` WHERE status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY id DESC LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	defer rows.Close()
+
+	return collectOutboxNotifications(rows)
+}
+
+// MarkNotificationDelivered records a successful delivery attempt
+func (s *Store) MarkNotificationDelivered(id int64) error {
+	_, err := s.db.Exec(
+		`UPDATE notification_outbox
+		 SET status = 'delivered', attempts = attempts + 1, last_error = NULL, next_attempt_at = NULL, updated_at = ?
+		 WHERE id = ?`,
+		time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification delivered: %w", err)
+	}
+	return nil
+}
+
+// RecordNotificationFailure records a failed delivery attempt. A nil retryAt marks
+// the notification as permanently failed; otherwise it stays pending until retryAt.
+func (s *Store) RecordNotificationFailure(id int64, errMsg string, retryAt *time.Time) error {
+	status := "pending"
+	if retryAt == nil {
+		status = "failed"
+	}
+
+	_, err := s.db.Exec(
+		`UPDATE notification_outbox
+		 SET status = ?, attempts = attempts + 1, last_error = ?, next_attempt_at = ?, updated_at = ?
+		 WHERE id = ?`,
+		status, errMsg, retryAt, time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record notification failure: %w", err)
+	}
+	return nil
+}
+
+// scanOutboxNotification scans a single outbox row selected with outboxColumns
+func scanOutboxNotification(row rowScanner) (*OutboxNotification, error) {
+	var n OutboxNotification
+	var lastError sql.NullString
+	var nextAttemptAt sql.NullTime
+
+	if err := row.Scan(&n.ID, &n.JobID, &n.RunID, &n.Channel, &n.Recipients, &n.Subject, &n.Body, &n.Status,
+		&n.Attempts, &lastError, &nextAttemptAt, &n.CreatedAt, &n.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	if lastError.Valid {
+		n.LastError = &lastError.String
+	}
+	n.NextAttemptAt = NullTimeToPointer(nextAttemptAt)
+	return &n, nil
+}
+
+// collectOutboxNotifications scans all rows into outbox entries
+func collectOutboxNotifications(rows *sql.Rows) ([]*OutboxNotification, error) {
+	var notifications []*OutboxNotification
+	for rows.Next() {
+		n, err := scanOutboxNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
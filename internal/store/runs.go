@@ -2,25 +2,159 @@ package store
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	internal "github.com/taskflow/taskflow/internal"
 )
 
+// runColumns is the column list shared by every run SELECT so that scanRun
+// stays in sync with the queries that feed it.
+const runColumns = `id, job_id, status, exit_code, trigger_type, started_at, finished_at, duration_ms, error_message, created_at, cpu_seconds, output_preview, tags, acknowledged_by, acknowledged_at, logs_incomplete, attempt, parent_run_id, command_snapshot, agent_id, log_bytes_total, log_truncated, triggered_by, approved_by, approved_at`
+
+// scanRun scans a row selected with runColumns into a Run
+func scanRun(row rowScanner) (*Run, error) {
+	run := &Run{}
+	var exitCode sql.NullInt64
+	var startedAt, finishedAt, createdAt, acknowledgedAt, approvedAt sql.NullTime
+	var durationMs, acknowledgedBy, triggeredBy, approvedBy sql.NullInt64
+	var errorMsg, outputPreview, tags, parentRunID, commandSnapshot, agentID sql.NullString
+	var cpuSeconds sql.NullFloat64
+	var logsIncomplete, logTruncated sql.NullBool
+	var attempt, logBytesTotal sql.NullInt64
+
+	if err := row.Scan(
+		&run.ID, &run.JobID, &run.Status, &exitCode, &run.TriggerType,
+		&startedAt, &finishedAt, &durationMs, &errorMsg, &createdAt, &cpuSeconds, &outputPreview, &tags,
+		&acknowledgedBy, &acknowledgedAt, &logsIncomplete, &attempt, &parentRunID, &commandSnapshot, &agentID,
+		&logBytesTotal, &logTruncated, &triggeredBy, &approvedBy, &approvedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	populateRunPointers(run, exitCode, startedAt, finishedAt, durationMs, errorMsg)
+	if createdAt.Valid {
+		run.CreatedAt = createdAt.Time
+	}
+	if cpuSeconds.Valid {
+		run.CPUSeconds = &cpuSeconds.Float64
+	}
+	if outputPreview.Valid {
+		run.OutputPreview = &outputPreview.String
+	}
+	if tags.Valid {
+		if err := json.Unmarshal([]byte(tags.String), &run.Tags); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+		}
+	}
+	if acknowledgedBy.Valid {
+		userID := int(acknowledgedBy.Int64)
+		run.AcknowledgedBy = &userID
+	}
+	if acknowledgedAt.Valid {
+		run.AcknowledgedAt = &acknowledgedAt.Time
+	}
+	run.LogsIncomplete = logsIncomplete.Bool
+	run.Attempt = 1
+	if attempt.Valid {
+		run.Attempt = int(attempt.Int64)
+	}
+	if parentRunID.Valid {
+		run.ParentRunID = &parentRunID.String
+	}
+	if commandSnapshot.Valid {
+		run.CommandSnapshot = &CommandSnapshot{}
+		if err := json.Unmarshal([]byte(commandSnapshot.String), run.CommandSnapshot); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal command_snapshot: %w", err)
+		}
+	}
+	if agentID.Valid {
+		run.AgentID = &agentID.String
+	}
+	run.LogBytesTotal = logBytesTotal.Int64
+	run.LogTruncated = logTruncated.Bool
+	if triggeredBy.Valid {
+		userID := int(triggeredBy.Int64)
+		run.TriggeredBy = &userID
+	}
+	if approvedBy.Valid {
+		userID := int(approvedBy.Int64)
+		run.ApprovedBy = &userID
+	}
+	if approvedAt.Valid {
+		run.ApprovedAt = &approvedAt.Time
+	}
+	return run, nil
+}
+
 // CreateRun creates a new job run
 func (s *Store) CreateRun(jobID, triggerType string) (*Run, error) {
-	run := &Run{
-		ID:          uuid.New().String(),
+	return s.CreateTaggedRun(jobID, triggerType, nil)
+}
+
+// CreateTaggedRun creates a new job run labelled with tags for later filtering
+func (s *Store) CreateTaggedRun(jobID, triggerType string, tags []string) (*Run, error) {
+	return s.insertRun(&Run{
 		JobID:       jobID,
-		Status:      "pending",
 		TriggerType: triggerType,
+		Tags:        tags,
+		Attempt:     1,
+	})
+}
+
+// CreateManualRun creates the run for a manual trigger by userID. A run of a job that
+// requires approval starts out awaiting_approval and is not queued until ApproveRun.
+func (s *Store) CreateManualRun(job *Job, userID *int, tags []string) (*Run, error) {
+	run := &Run{
+		JobID:       job.ID,
+		TriggerType: internal.TriggerManual,
+		Tags:        tags,
+		Attempt:     1,
+		TriggeredBy: userID,
+	}
+	if job.RequiresApproval {
+		run.Status = internal.JobStatusAwaitingApproval
+	}
+	return s.insertRun(run)
+}
+
+// CreateRetryRun creates the run for another attempt of parent, linked to the first
+// attempt and carrying its trigger type and tags
+func (s *Store) CreateRetryRun(parent *Run, attempt int) (*Run, error) {
+	parentID := parent.ID
+	if parent.ParentRunID != nil {
+		parentID = *parent.ParentRunID
+	}
+	return s.insertRun(&Run{
+		JobID:       parent.JobID,
+		TriggerType: parent.TriggerType,
+		Tags:        parent.Tags,
+		Attempt:     attempt,
+		ParentRunID: &parentID,
+	})
+}
+
+// insertRun stores a new run, pending unless it has a status, filling in its ID and
+// creation time
+func (s *Store) insertRun(run *Run) (*Run, error) {
+	run.ID = uuid.New().String()
+	if run.Status == "" {
+		run.Status = internal.JobStatusPending
+	}
+	run.CreatedAt = time.Now()
+
+	tagsJSON, err := stringsToNullJSON(run.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tags: %w", err)
 	}
 
-	_, err := s.db.Exec(
-		`INSERT INTO runs (id, job_id, status, trigger_type) VALUES (?, ?, ?, ?)`,
-		run.ID, run.JobID, run.Status, run.TriggerType,
+	_, err = s.execRetry(
+		`INSERT INTO runs (id, job_id, status, trigger_type, tags, attempt, parent_run_id, triggered_by, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		run.ID, run.JobID, run.Status, run.TriggerType, tagsJSON, run.Attempt, run.ParentRunID, run.TriggeredBy, run.CreatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create run: %w", err)
@@ -29,22 +163,30 @@ func (s *Store) CreateRun(jobID, triggerType string) (*Run, error) {
 	return run, nil
 }
 
+// ListRunAttempts returns every attempt of the run with the given ID (the run itself if
+// it is the first attempt, or its parent's family if it is a retry), in attempt order
+func (s *Store) ListRunAttempts(id string) ([]*Run, error) {
+	rows, err := s.db.Query(
+		`SELECT `+runColumns+` FROM runs
+		 WHERE id = (SELECT COALESCE(parent_run_id, id) FROM runs WHERE id = ?)
+		    OR parent_run_id = (SELECT COALESCE(parent_run_id, id) FROM runs WHERE id = ?)
+		 ORDER BY attempt ASC, created_at ASC`,
+		id, id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list run attempts: %w", err)
+	}
+	defer rows.Close()
+
+	return collectRuns(rows)
+}
+
 // GetRun retrieves a run by ID
 func (s *Store) GetRun(id string) (*Run, error) {
-	run := &Run{}
-	var exitCode sql.NullInt64
-	var startedAt, finishedAt sql.NullTime
-	var durationMs sql.NullInt64
-	var errorMsg sql.NullString
-
-	err := s.db.QueryRow(
-		`SELECT id, job_id, status, exit_code, trigger_type, started_at, finished_at, duration_ms, error_message
-		 FROM runs WHERE id = ?`,
+	run, err := scanRun(s.db.QueryRow(
+		`SELECT `+runColumns+` FROM runs WHERE id = ?`,
 		id,
-	).Scan(
-		&run.ID, &run.JobID, &run.Status, &exitCode, &run.TriggerType,
-		&startedAt, &finishedAt, &durationMs, &errorMsg,
-	)
+	))
 
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, errors.New("run not found")
@@ -53,12 +195,44 @@ func (s *Store) GetRun(id string) (*Run, error) {
 		return nil, fmt.Errorf("failed to get run: %w", err)
 	}
 
-	populateRunPointers(run, exitCode, startedAt, finishedAt, durationMs, errorMsg)
 	return run, nil
 }
 
-// ListRuns retrieves runs with optional filtering
-func (s *Store) ListRuns(jobID *string, limit int, offset int) ([]*Run, error) {
+// RunFilter narrows run listings. Empty fields do not filter, so the zero value lists every run.
+type RunFilter struct {
+	JobID        string
+	Tag          string // only runs tagged with Tag at trigger time
+	Acknowledged *bool  // only runs that have (true) or have not (false) been acknowledged
+	FirstAttempt bool   // leave out retry attempts, so each execution appears once as its first run
+}
+
+// conditions returns the filter as " AND ..." conditions on runs columns and their args
+func (f RunFilter) conditions() (string, []interface{}) {
+	var conds strings.Builder
+	args := []interface{}{}
+	if f.JobID != "" {
+		conds.WriteString(` AND job_id = ?`)
+		args = append(args, f.JobID)
+	}
+	if f.Tag != "" {
+		conds.WriteString(` AND EXISTS (SELECT 1 FROM json_each(runs.tags) WHERE json_each.value = ?)`)
+		args = append(args, f.Tag)
+	}
+	if f.Acknowledged != nil {
+		if *f.Acknowledged {
+			conds.WriteString(` AND acknowledged_at IS NOT NULL`)
+		} else {
+			conds.WriteString(` AND acknowledged_at IS NULL`)
+		}
+	}
+	if f.FirstAttempt {
+		conds.WriteString(` AND parent_run_id IS NULL`)
+	}
+	return conds.String(), args
+}
+
+// ListRuns retrieves runs matching filter, newest first
+func (s *Store) ListRuns(filter RunFilter, limit int, offset int) ([]*Run, error) {
 	// Validate and normalize pagination parameters
 	const maxLimit = 1000
 	if limit <= 0 || limit > maxLimit {
@@ -68,42 +242,268 @@ func (s *Store) ListRuns(jobID *string, limit int, offset int) ([]*Run, error) {
 		offset = 0
 	}
 
-	baseQuery := `SELECT id, job_id, status, exit_code, trigger_type, started_at, finished_at, duration_ms, error_message
-	 FROM runs`
-	orderAndPagination := ` ORDER BY started_at DESC LIMIT ? OFFSET ?`
+	conds, args := filter.conditions()
+	query := `SELECT ` + runColumns + ` FROM runs WHERE 1 = 1` + conds +
+		` ORDER BY started_at DESC LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+	defer rows.Close()
+
+	return collectRuns(rows)
+}
+
+// CountRuns returns how many runs match filter
+func (s *Store) CountRuns(filter RunFilter) (int, error) {
+	conds, args := filter.conditions()
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM runs WHERE 1 = 1`+conds, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count runs: %w", err)
+	}
+	return count, nil
+}
+
+// ListRunsAfter retrieves runs using keyset (cursor) pagination, newest first.
+// afterID is the ID of the last run from the previous page; empty starts from the newest run.
+// Unlike offset pagination, pages stay stable when new runs are inserted mid-iteration.
+func (s *Store) ListRunsAfter(filter RunFilter, afterID string, limit int) ([]*Run, error) {
+	const maxLimit = 1000
+	if limit <= 0 || limit > maxLimit {
+		limit = 100
+	}
 
-	var rows *sql.Rows
-	var err error
+	conds, args := filter.conditions()
+	query := `SELECT ` + runColumns + ` FROM runs WHERE 1 = 1` + conds
 
-	if jobID != nil {
-		query := baseQuery + ` WHERE job_id = ?` + orderAndPagination
-		rows, err = s.db.Query(query, *jobID, limit, offset)
-	} else {
-		query := baseQuery + orderAndPagination
-		rows, err = s.db.Query(query, limit, offset)
+	if afterID != "" {
+		var exists int
+		err := s.db.QueryRow(`SELECT 1 FROM runs WHERE id = ?`, afterID).Scan(&exists)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("run not found")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve cursor: %w", err)
+		}
+		// Compare against the cursor's stored created_at text rather than a value
+		// round-tripped through time.Time, which can be re-encoded differently
+		query += ` AND (created_at < (SELECT created_at FROM runs WHERE id = ?)
+			OR (created_at = (SELECT created_at FROM runs WHERE id = ?) AND id < ?))`
+		args = append(args, afterID, afterID, afterID)
 	}
 
+	query += ` ORDER BY created_at DESC, id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list runs: %w", err)
 	}
 	defer rows.Close()
 
+	return collectRuns(rows)
+}
+
+// ListFinishedRunsSince retrieves a job's runs that have both started and finished,
+// starting at or after since, oldest first. Test runs are left out.
+func (s *Store) ListFinishedRunsSince(jobID string, since time.Time) ([]*Run, error) {
+	rows, err := s.db.Query(
+		`SELECT `+runColumns+` FROM runs
+		 WHERE job_id = ? AND started_at IS NOT NULL AND finished_at IS NOT NULL AND started_at >= ?`+excludeTestRuns+`
+		 ORDER BY started_at ASC, id ASC`,
+		jobID, since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list finished runs: %w", err)
+	}
+	defer rows.Close()
+
+	return collectRuns(rows)
+}
+
+// ListActiveRuns retrieves a job's pending and running runs of the given trigger type, oldest first
+func (s *Store) ListActiveRuns(jobID, triggerType string) ([]*Run, error) {
+	rows, err := s.db.Query(
+		`SELECT `+runColumns+` FROM runs
+		 WHERE job_id = ? AND trigger_type = ? AND status IN (?, ?)
+		 ORDER BY created_at ASC, id ASC`,
+		jobID, triggerType, internal.JobStatusPending, internal.JobStatusRunning,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list active runs: %w", err)
+	}
+	defer rows.Close()
+
+	return collectRuns(rows)
+}
+
+// RecentManualRun returns the job's newest manual run if it was created within the manual
+// trigger interval, so a double-clicked "Run now" can be refused. It returns nil when the
+// interval is disabled or has passed.
+func (s *Store) RecentManualRun(jobID string) (*Run, error) {
+	if s.manualTriggerInterval <= 0 {
+		return nil, nil
+	}
+
+	run, err := scanRun(s.db.QueryRow(
+		`SELECT `+runColumns+` FROM runs
+		 WHERE job_id = ? AND trigger_type = ? AND created_at >= ?
+		 ORDER BY created_at DESC, id DESC LIMIT 1`,
+		jobID, internal.TriggerManual, time.Now().Add(-s.manualTriggerInterval),
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find recent manual run: %w", err)
+	}
+	return run, nil
+}
+
+// ManualTriggerInterval returns the minimum time between manual runs of the same job
+func (s *Store) ManualTriggerInterval() time.Duration {
+	return s.manualTriggerInterval
+}
+
+// ErrRunAlreadyAcknowledged is returned when acknowledging a run someone already acknowledged
+var ErrRunAlreadyAcknowledged = errors.New("run already acknowledged")
+
+// AcknowledgeRun records that userID has taken ownership of a run. Only the first
+// acknowledgement sticks; later ones get ErrRunAlreadyAcknowledged.
+func (s *Store) AcknowledgeRun(id string, userID int) error {
+	result, err := s.db.Exec(
+		`UPDATE runs SET acknowledged_by = ?, acknowledged_at = ? WHERE id = ? AND acknowledged_at IS NULL`,
+		userID, time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to acknowledge run: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrRunAlreadyAcknowledged
+	}
+	return nil
+}
+
+// ActiveRun is an in-flight run with its job's name and how long it has been in flight
+type ActiveRun struct {
+	*Run
+	JobName   string `json:"job_name"`   // empty if the job has been deleted
+	ElapsedMs int64  `json:"elapsed_ms"` // since started_at, or since created_at while still pending
+}
+
+// ListRunningRuns retrieves every pending and running run across all jobs, oldest first
+func (s *Store) ListRunningRuns() ([]*ActiveRun, error) {
+	rows, err := s.db.Query(
+		`SELECT `+runColumns+` FROM runs WHERE status IN (?, ?) ORDER BY created_at ASC, id ASC`,
+		internal.JobStatusPending, internal.JobStatusRunning,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list running runs: %w", err)
+	}
+	runs, err := collectRuns(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	nameRows, err := s.db.Query(
+		`SELECT id, name FROM jobs WHERE id IN (SELECT job_id FROM runs WHERE status IN (?, ?))`,
+		internal.JobStatusPending, internal.JobStatusRunning,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job names: %w", err)
+	}
+	defer nameRows.Close()
+	names := make(map[string]string)
+	for nameRows.Next() {
+		var id, name string
+		if err := nameRows.Scan(&id, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan job name: %w", err)
+		}
+		names[id] = name
+	}
+	if err := nameRows.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active := make([]*ActiveRun, 0, len(runs))
+	for _, run := range runs {
+		since := run.CreatedAt
+		if run.StartedAt != nil {
+			since = *run.StartedAt
+		}
+		active = append(active, &ActiveRun{
+			Run:       run,
+			JobName:   names[run.JobID],
+			ElapsedMs: now.Sub(since).Milliseconds(),
+		})
+	}
+	return active, nil
+}
+
+// CancelPendingRun marks a run that has not started as cancelled with reason as its error
+// message. It reports false if the run is no longer pending.
+func (s *Store) CancelPendingRun(id, reason string) (bool, error) {
+	result, err := s.db.Exec(
+		`UPDATE runs SET status = ?, finished_at = ?, error_message = ? WHERE id = ? AND status = ?`,
+		internal.JobStatusCancelled, time.Now(), reason, id, internal.JobStatusPending,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel run: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// ErrRunNotAwaitingApproval is returned when approving or rejecting a run that is not
+// awaiting approval, e.g. because another admin got to it first
+var ErrRunNotAwaitingApproval = errors.New("run is not awaiting approval")
+
+// ApproveRun records approverID's approval of a run awaiting approval and makes it
+// pending, ready to be queued
+func (s *Store) ApproveRun(id string, approverID int) error {
+	result, err := s.db.Exec(
+		`UPDATE runs SET status = ?, approved_by = ?, approved_at = ? WHERE id = ? AND status = ?`,
+		internal.JobStatusPending, approverID, time.Now(), id, internal.JobStatusAwaitingApproval,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to approve run: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrRunNotAwaitingApproval
+	}
+	return nil
+}
+
+// RejectRun cancels a run awaiting approval with reason as its error message
+func (s *Store) RejectRun(id, reason string) error {
+	result, err := s.db.Exec(
+		`UPDATE runs SET status = ?, finished_at = ?, error_message = ? WHERE id = ? AND status = ?`,
+		internal.JobStatusCancelled, time.Now(), reason, id, internal.JobStatusAwaitingApproval,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to reject run: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrRunNotAwaitingApproval
+	}
+	return nil
+}
+
+// collectRuns scans every remaining row selected with runColumns
+func collectRuns(rows *sql.Rows) ([]*Run, error) {
 	runs := make([]*Run, 0)
 	for rows.Next() {
-		run := &Run{}
-		var exitCode sql.NullInt64
-		var startedAt, finishedAt sql.NullTime
-		var durationMs sql.NullInt64
-		var errorMsg sql.NullString
-
-		if err := rows.Scan(
-			&run.ID, &run.JobID, &run.Status, &exitCode, &run.TriggerType,
-			&startedAt, &finishedAt, &durationMs, &errorMsg,
-		); err != nil {
+		run, err := scanRun(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan run: %w", err)
 		}
-
-		populateRunPointers(run, exitCode, startedAt, finishedAt, durationMs, errorMsg)
 		runs = append(runs, run)
 	}
 
@@ -112,8 +512,9 @@ func (s *Store) ListRuns(jobID *string, limit int, offset int) ([]*Run, error) {
 
 // UpdateRun updates a run's status and metadata
 func (s *Store) UpdateRun(run *Run) error {
-	_, err := s.db.Exec(
-		`UPDATE runs SET status = ?, exit_code = ?, started_at = ?, finished_at = ?, duration_ms = ?, error_message = ?
+	_, err := s.execRetry(
+		`UPDATE runs SET status = ?, exit_code = ?, started_at = ?, finished_at = ?, duration_ms = ?, error_message = ?,
+		 cpu_seconds = ?, output_preview = ?
 		 WHERE id = ?`,
 		run.Status,
 		PointerToNullInt64(run.ExitCode),
@@ -121,39 +522,78 @@ func (s *Store) UpdateRun(run *Run) error {
 		PointerToNullTime(run.FinishedAt),
 		PointerToNullInt64Ptr(run.DurationMs),
 		run.ErrorMsg,
+		run.CPUSeconds,
+		run.OutputPreview,
 		run.ID,
 	)
 	return err
 }
 
-// DeleteRun deletes a run and associated logs/metrics
-func (s *Store) DeleteRun(id string) error {
-	// Delete associated logs
-	if _, err := s.db.Exec(`DELETE FROM logs WHERE run_id = ?`, id); err != nil {
-		return fmt.Errorf("failed to delete logs: %w", err)
+// SetRunCommandSnapshot records how a run's script was launched
+func (s *Store) SetRunCommandSnapshot(runID string, snapshot *CommandSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal command_snapshot: %w", err)
 	}
-
-	// Delete associated metrics
-	if _, err := s.db.Exec(`DELETE FROM metrics WHERE run_id = ?`, id); err != nil {
-		return fmt.Errorf("failed to delete metrics: %w", err)
+	if _, err := s.db.Exec(`UPDATE runs SET command_snapshot = ? WHERE id = ?`, string(data), runID); err != nil {
+		return fmt.Errorf("failed to save command snapshot: %w", err)
 	}
+	return nil
+}
 
-	// Delete the run
-	result, err := s.db.Exec(`DELETE FROM runs WHERE id = ?`, id)
-	if err != nil {
-		return fmt.Errorf("failed to delete run: %w", err)
+// MarkLogsIncomplete flags a run whose output could not all be stored. It only touches
+// the runs table, so it can succeed while writes to the logs table keep failing.
+func (s *Store) MarkLogsIncomplete(runID string) error {
+	if _, err := s.db.Exec(`UPDATE runs SET logs_incomplete = 1 WHERE id = ?`, runID); err != nil {
+		return fmt.Errorf("failed to mark logs incomplete: %w", err)
 	}
+	return nil
+}
 
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get affected rows: %w", err)
+// SetRunLogVolume records how many bytes of output a run wrote and whether storing it
+// stopped at the log cap
+func (s *Store) SetRunLogVolume(runID string, bytesTotal int64, truncated bool) error {
+	if _, err := s.db.Exec(`UPDATE runs SET log_bytes_total = ?, log_truncated = ? WHERE id = ?`, bytesTotal, truncated, runID); err != nil {
+		return fmt.Errorf("failed to save log volume: %w", err)
 	}
+	return nil
+}
 
-	if rows == 0 {
-		return errors.New("run not found")
-	}
+// DeleteRun deletes a run, its retry attempts and their logs/metrics in one transaction
+func (s *Store) DeleteRun(id string) error {
+	return s.WithTx(func(tx *sql.Tx) error {
+		const runIDs = `SELECT id FROM runs WHERE id = ? OR parent_run_id = ?`
 
-	return nil
+		// Delete associated logs
+		if _, err := tx.Exec(`DELETE FROM logs WHERE run_id IN (`+runIDs+`)`, id, id); err != nil {
+			return fmt.Errorf("failed to delete logs: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM logs_archive WHERE run_id IN (`+runIDs+`)`, id, id); err != nil {
+			return fmt.Errorf("failed to delete archived logs: %w", err)
+		}
+
+		// Delete associated metrics
+		if _, err := tx.Exec(`DELETE FROM metrics WHERE run_id IN (`+runIDs+`)`, id, id); err != nil {
+			return fmt.Errorf("failed to delete metrics: %w", err)
+		}
+
+		// Delete the run
+		result, err := tx.Exec(`DELETE FROM runs WHERE id = ? OR parent_run_id = ?`, id, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete run: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+
+		if rows == 0 {
+			return errors.New("run not found")
+		}
+
+		return nil
+	})
 }
 
 // DeleteOldRuns deletes runs older than the specified number of days
@@ -175,6 +615,93 @@ func (s *Store) DeleteOldRuns(days int) error {
 	return nil
 }
 
+// PurgeOldRunContent deletes the logs and metrics of runs older than the specified
+// number of days but keeps the runs themselves, so status and duration history survive.
+// It returns how many runs were purged.
+func (s *Store) PurgeOldRunContent(days int) (int, error) {
+	cutoff := time.Now().AddDate(0, 0, -days)
+	rows, err := s.db.Query(
+		`SELECT id FROM runs WHERE started_at < ? AND (
+			EXISTS (SELECT 1 FROM logs WHERE logs.run_id = runs.id) OR
+			EXISTS (SELECT 1 FROM logs_archive WHERE logs_archive.run_id = runs.id) OR
+			EXISTS (SELECT 1 FROM metrics WHERE metrics.run_id = runs.id))`,
+		cutoff,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find runs to purge: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if err := s.DeleteLogs(id); err != nil {
+			return 0, fmt.Errorf("failed to purge logs for run %s: %w", id, err)
+		}
+		if err := s.DeleteMetrics(id); err != nil {
+			return 0, fmt.Errorf("failed to purge metrics for run %s: %w", id, err)
+		}
+	}
+	return len(ids), nil
+}
+
+// EnforceGlobalRunCap deletes the oldest finished runs, with their retry attempts, logs
+// and metrics, until at most max runs remain. Pending, running and awaiting-approval runs
+// are never evicted.
+// It returns how many runs were deleted; max <= 0 disables the cap.
+func (s *Store) EnforceGlobalRunCap(max int) (int, error) {
+	if max <= 0 {
+		return 0, nil
+	}
+
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM runs`).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count runs: %w", err)
+	}
+	excess := total - max
+	if excess <= 0 {
+		return 0, nil
+	}
+
+	var evicted int64
+	err := s.WithTx(func(tx *sql.Tx) error {
+		const oldest = `SELECT id FROM runs WHERE parent_run_id IS NULL AND status NOT IN (?, ?, ?)
+			ORDER BY created_at ASC, id ASC LIMIT ?`
+		const runIDs = `SELECT id FROM runs WHERE id IN (` + oldest + `) OR parent_run_id IN (` + oldest + `)`
+		args := []interface{}{
+			internal.JobStatusPending, internal.JobStatusRunning, internal.JobStatusAwaitingApproval, excess,
+			internal.JobStatusPending, internal.JobStatusRunning, internal.JobStatusAwaitingApproval, excess,
+		}
+
+		for _, table := range []string{"logs", "logs_archive", "metrics"} {
+			if _, err := tx.Exec(`DELETE FROM `+table+` WHERE run_id IN (`+runIDs+`)`, args...); err != nil {
+				return fmt.Errorf("failed to delete %s: %w", table, err)
+			}
+		}
+
+		result, err := tx.Exec(`DELETE FROM runs WHERE id IN (`+runIDs+`)`, args...)
+		if err != nil {
+			return fmt.Errorf("failed to delete runs: %w", err)
+		}
+		evicted, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(evicted), nil
+}
+
 // populateRunPointers converts nullable database types to Run struct pointers.
 // This eliminates duplicate null-checking code in GetRun and ListRuns.
 // Follows DRY principle: null conversion logic in one place.
@@ -0,0 +1,332 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestListRunsAfterStableWithConcurrentInsert tests that a run inserted between
+// two cursor fetches causes neither a duplicate nor a skipped row
+func TestListRunsAfterStableWithConcurrentInsert(t *testing.T) {
+	st := NewTestStore(t)
+	defer st.Close()
+
+	job, err := st.CreateJob(&Job{Name: "cursor", Script: "true", TimeoutSeconds: 60})
+	require.NoError(t, err)
+
+	var created []string
+	for i := 0; i < 4; i++ {
+		run, err := st.CreateRun(job.ID, "manual")
+		require.NoError(t, err)
+		created = append(created, run.ID)
+	}
+
+	page1, err := st.ListRunsAfter(RunFilter{}, "", 2)
+	require.NoError(t, err)
+	require.Len(t, page1, 2)
+
+	// A new run arrives mid-iteration; with offset pagination this would shift page 2
+	_, err = st.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+
+	page2, err := st.ListRunsAfter(RunFilter{}, page1[len(page1)-1].ID, 2)
+	require.NoError(t, err)
+	require.Len(t, page2, 2)
+
+	seen := make(map[string]bool)
+	for _, run := range append(page1, page2...) {
+		assert.False(t, seen[run.ID], "run %s returned twice", run.ID)
+		seen[run.ID] = true
+	}
+	for _, id := range created {
+		assert.True(t, seen[id], "run %s was skipped", id)
+	}
+}
+
+// TestListRunsAfterMigratedTimestamps tests that cursor pages stay whole when
+// created_at holds text in a different layout than the driver writes, as with
+// rows backfilled by migration
+func TestListRunsAfterMigratedTimestamps(t *testing.T) {
+	st := NewTestStore(t)
+	defer st.Close()
+
+	job, err := st.CreateJob(&Job{Name: "migrated", Script: "true", TimeoutSeconds: 60})
+	require.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		_, err := st.CreateRun(job.ID, "manual")
+		require.NoError(t, err)
+	}
+	_, err = st.db.Exec(`UPDATE runs SET created_at = '2026-01-01 10:00:00'`)
+	require.NoError(t, err)
+
+	seen := make(map[string]bool)
+	cursor := ""
+	// Bounded so a cursor that never advances fails instead of looping
+	for pages := 0; pages < 4; pages++ {
+		page, err := st.ListRunsAfter(RunFilter{}, cursor, 2)
+		require.NoError(t, err)
+		for _, run := range page {
+			assert.False(t, seen[run.ID], "run %s returned twice", run.ID)
+			seen[run.ID] = true
+		}
+		if len(page) < 2 {
+			break
+		}
+		cursor = page[len(page)-1].ID
+	}
+	assert.Len(t, seen, 4)
+
+	count, err := st.CountRuns(RunFilter{JobID: job.ID})
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+}
+
+// TestListRunsAfterUnknownCursor tests that an unknown cursor is reported
+func TestListRunsAfterUnknownCursor(t *testing.T) {
+	st := NewTestStore(t)
+	defer st.Close()
+
+	_, err := st.ListRunsAfter(RunFilter{}, "does-not-exist", 10)
+	assert.EqualError(t, err, "run not found")
+}
+
+// TestDeleteRunRollsBackOnFailure tests that a failure partway through DeleteRun
+// leaves the run and its logs untouched
+func TestDeleteRunRollsBackOnFailure(t *testing.T) {
+	st := NewTestStore(t)
+	defer st.Close()
+
+	job, err := st.CreateJob(&Job{Name: "atomic", Script: "true", TimeoutSeconds: 60})
+	require.NoError(t, err)
+	run, err := st.CreateRun(job.ID, "manual")
+	require.NoError(t, err)
+	require.NoError(t, st.AddLogs(run.ID, []LogEntry{{Stream: "stdout", Content: "hello"}}))
+
+	// Dropping metrics makes the third delete fail after logs were already removed
+	_, err = st.DB().Exec(`DROP TABLE metrics`)
+	require.NoError(t, err)
+
+	err = st.DeleteRun(run.ID)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to delete metrics")
+
+	logs, err := st.GetLogs(run.ID)
+	require.NoError(t, err)
+	assert.Len(t, logs, 1)
+	_, err = st.GetRun(run.ID)
+	assert.NoError(t, err)
+}
+
+// TestWithTxRollsBackOnError tests that writes made before fn fails are discarded
+func TestWithTxRollsBackOnError(t *testing.T) {
+	st := NewTestStore(t)
+	defer st.Close()
+
+	job, err := st.CreateJob(&Job{Name: "atomic", Script: "true", TimeoutSeconds: 60})
+	require.NoError(t, err)
+
+	err = st.WithTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`UPDATE jobs SET name = 'renamed' WHERE id = ?`, job.ID); err != nil {
+			return err
+		}
+		return errors.New("forced failure")
+	})
+	assert.EqualError(t, err, "forced failure")
+
+	got, err := st.GetJob(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "atomic", got.Name)
+}
+
+// TestListRunningRuns tests that every pending and running run is listed with its job name
+func TestListRunningRuns(t *testing.T) {
+	st := NewTestStore(t)
+	defer st.Close()
+
+	backup, err := st.CreateJob(&Job{Name: "backup", Script: "true", TimeoutSeconds: 60})
+	require.NoError(t, err)
+	report, err := st.CreateJob(&Job{Name: "report", Script: "true", TimeoutSeconds: 60})
+	require.NoError(t, err)
+
+	started := time.Now().Add(-time.Minute)
+	var running []string
+	for _, job := range []*Job{backup, report, backup} {
+		run, err := st.CreateRun(job.ID, "scheduled")
+		require.NoError(t, err)
+		run.Status = "running"
+		run.StartedAt = &started
+		require.NoError(t, st.UpdateRun(run))
+		running = append(running, run.ID)
+	}
+	pending, err := st.CreateRun(report.ID, "manual")
+	require.NoError(t, err)
+	finished, err := st.CreateRun(report.ID, "manual")
+	require.NoError(t, err)
+	finished.Status = "success"
+	require.NoError(t, st.UpdateRun(finished))
+
+	active, err := st.ListRunningRuns()
+	require.NoError(t, err)
+	require.Len(t, active, 4)
+
+	byID := make(map[string]*ActiveRun)
+	for _, run := range active {
+		byID[run.ID] = run
+	}
+	for _, id := range running {
+		require.Contains(t, byID, id)
+		assert.GreaterOrEqual(t, byID[id].ElapsedMs, int64(time.Minute/time.Millisecond))
+	}
+	assert.Equal(t, "backup", byID[running[0]].JobName)
+	assert.Equal(t, "report", byID[running[1]].JobName)
+	require.Contains(t, byID, pending.ID)
+	assert.Equal(t, "pending", byID[pending.ID].Status)
+	assert.NotContains(t, byID, finished.ID)
+}
+
+// TestPurgeOldRunContent tests that runs past the content cutoff lose their logs and
+// metrics but keep their metadata, while recent runs are untouched
+func TestPurgeOldRunContent(t *testing.T) {
+	st := NewTestStore(t)
+	defer st.Close()
+
+	job, err := st.CreateJob(&Job{Name: "sla", Script: "true", TimeoutSeconds: 60})
+	require.NoError(t, err)
+
+	old, err := st.CreateRun(job.ID, "scheduled")
+	require.NoError(t, err)
+	recent, err := st.CreateRun(job.ID, "scheduled")
+	require.NoError(t, err)
+	for _, run := range []*Run{old, recent} {
+		require.NoError(t, st.AddLogs(run.ID, []LogEntry{{Stream: "stdout", Content: "hello"}}))
+		_, err = st.AddMetric(run.ID, 10, 20, 1024)
+		require.NoError(t, err)
+	}
+
+	oldStart := time.Now().AddDate(0, 0, -10)
+	exitCode := 0
+	duration := int64(1500)
+	old.Status = "success"
+	old.StartedAt = &oldStart
+	old.ExitCode = &exitCode
+	old.DurationMs = &duration
+	require.NoError(t, st.UpdateRun(old))
+	now := time.Now()
+	recent.StartedAt = &now
+	require.NoError(t, st.UpdateRun(recent))
+
+	purged, err := st.PurgeOldRunContent(7)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	kept, err := st.GetRun(old.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "success", kept.Status)
+	require.NotNil(t, kept.DurationMs)
+	assert.Equal(t, int64(1500), *kept.DurationMs)
+	logs, err := st.GetLogs(old.ID)
+	require.NoError(t, err)
+	assert.Empty(t, logs)
+	metrics, err := st.GetMetrics(old.ID)
+	require.NoError(t, err)
+	assert.Empty(t, metrics)
+
+	logs, err = st.GetLogs(recent.ID)
+	require.NoError(t, err)
+	assert.Len(t, logs, 1)
+	metrics, err = st.GetMetrics(recent.ID)
+	require.NoError(t, err)
+	assert.Len(t, metrics, 1)
+
+	// A second pass has nothing left to purge
+	purged, err = st.PurgeOldRunContent(7)
+	require.NoError(t, err)
+	assert.Equal(t, 0, purged)
+}
+
+func TestEnforceGlobalRunCap(t *testing.T) {
+	st := NewTestStore(t)
+	defer st.Close()
+
+	job, err := st.CreateJob(&Job{Name: "busy", Script: "true", TimeoutSeconds: 60})
+	require.NoError(t, err)
+
+	// Five runs, oldest first; the oldest is still running and must survive eviction
+	runs := make([]*Run, 5)
+	for i := range runs {
+		runs[i], err = st.CreateRun(job.ID, "scheduled")
+		require.NoError(t, err)
+		runs[i].Status = "success"
+		if i == 0 {
+			runs[i].Status = "running"
+		}
+		require.NoError(t, st.UpdateRun(runs[i]))
+		_, err = st.db.Exec(`UPDATE runs SET created_at = ? WHERE id = ?`, time.Now().Add(-time.Duration(5-i)*time.Hour), runs[i].ID)
+		require.NoError(t, err)
+		require.NoError(t, st.AddLogs(runs[i].ID, []LogEntry{{Stream: "stdout", Content: "hello"}}))
+		_, err = st.AddMetric(runs[i].ID, 10, 20, 1024)
+		require.NoError(t, err)
+	}
+
+	evicted, err := st.EnforceGlobalRunCap(3)
+	require.NoError(t, err)
+	assert.Equal(t, 2, evicted)
+
+	for i, run := range runs {
+		_, err := st.GetRun(run.ID)
+		if i == 1 || i == 2 {
+			assert.Error(t, err, "run %d should have been evicted", i)
+			logs, err := st.GetLogs(run.ID)
+			require.NoError(t, err)
+			assert.Empty(t, logs)
+			metrics, err := st.GetMetrics(run.ID)
+			require.NoError(t, err)
+			assert.Empty(t, metrics)
+		} else {
+			assert.NoError(t, err, "run %d should have been kept", i)
+		}
+	}
+
+	// At the cap nothing more is evicted, and 0 disables the cap
+	evicted, err = st.EnforceGlobalRunCap(3)
+	require.NoError(t, err)
+	assert.Equal(t, 0, evicted)
+	evicted, err = st.EnforceGlobalRunCap(0)
+	require.NoError(t, err)
+	assert.Equal(t, 0, evicted)
+}
+
+// TestEnforceGlobalRunCapKeepsAwaitingApproval tests that a run held for approval is not
+// evicted however old it is
+func TestEnforceGlobalRunCapKeepsAwaitingApproval(t *testing.T) {
+	st := NewTestStore(t)
+	defer st.Close()
+
+	job, err := st.CreateJob(&Job{Name: "gated", Script: "true", TimeoutSeconds: 60, RequiresApproval: true})
+	require.NoError(t, err)
+	userID := 1
+	held, err := st.CreateManualRun(job, &userID, nil)
+	require.NoError(t, err)
+	require.Equal(t, "awaiting_approval", held.Status)
+	_, err = st.db.Exec(`UPDATE runs SET created_at = ? WHERE id = ?`, time.Now().Add(-time.Hour), held.ID)
+	require.NoError(t, err)
+
+	finished, err := st.CreateRun(job.ID, "scheduled")
+	require.NoError(t, err)
+	finished.Status = "success"
+	require.NoError(t, st.UpdateRun(finished))
+
+	evicted, err := st.EnforceGlobalRunCap(1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, evicted)
+	_, err = st.GetRun(held.ID)
+	assert.NoError(t, err, "the awaiting run must survive")
+	_, err = st.GetRun(finished.ID)
+	assert.Error(t, err)
+}
@@ -2,6 +2,7 @@ package store
 
 import (
 	"database/sql"
+	"strings"
 	"time"
 )
 
@@ -144,6 +145,83 @@ func (s *Store) SetSMTPSettings(settings *SMTPSettings) error {
 	return nil
 }
 
+// Notification channel settings helpers
+
+// ChannelSettings holds the chat and incident services job notifications can be sent to
+type ChannelSettings struct {
+	TeamsWebhookURL     string   `json:"teams_webhook_url"`
+	PagerDutyRoutingKey string   `json:"pagerduty_routing_key"`
+	DefaultChannels     []string `json:"default_channels"` // used by jobs without notify_channels
+}
+
+const (
+	teamsWebhookURLKey     = "notify_teams_webhook_url"
+	pagerDutyRoutingKeyKey = "notify_pagerduty_routing_key"
+	defaultChannelsKey     = "notify_default_channels"
+)
+
+// GetChannelSettings retrieves the notification channel settings
+func (s *Store) GetChannelSettings() (*ChannelSettings, error) {
+	settings := &ChannelSettings{}
+
+	if setting, err := s.GetSetting(teamsWebhookURLKey); err != nil {
+		return nil, err
+	} else if setting != nil {
+		settings.TeamsWebhookURL = setting.Value
+	}
+
+	if setting, err := s.GetSetting(pagerDutyRoutingKeyKey); err != nil {
+		return nil, err
+	} else if setting != nil {
+		settings.PagerDutyRoutingKey = setting.Value
+	}
+
+	if setting, err := s.GetSetting(defaultChannelsKey); err != nil {
+		return nil, err
+	} else if setting != nil {
+		for _, name := range strings.Split(setting.Value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				settings.DefaultChannels = append(settings.DefaultChannels, name)
+			}
+		}
+	}
+
+	return settings, nil
+}
+
+// SetChannelSettings saves the notification channel settings
+func (s *Store) SetChannelSettings(settings *ChannelSettings) error {
+	if err := s.SetSetting(teamsWebhookURLKey, settings.TeamsWebhookURL); err != nil {
+		return err
+	}
+	if err := s.SetSetting(pagerDutyRoutingKeyKey, settings.PagerDutyRoutingKey); err != nil {
+		return err
+	}
+	return s.SetSetting(defaultChannelsKey, strings.Join(settings.DefaultChannels, ","))
+}
+
+// Maintenance mode helpers
+
+const maintenanceModeKey = "maintenance_mode"
+
+// IsMaintenanceMode reports whether the server-wide maintenance flag is on
+func (s *Store) IsMaintenanceMode() (bool, error) {
+	setting, err := s.GetSetting(maintenanceModeKey)
+	if err != nil {
+		return false, err
+	}
+	return setting != nil && setting.Value == "true", nil
+}
+
+// SetMaintenanceMode turns the server-wide maintenance flag on or off
+func (s *Store) SetMaintenanceMode(enabled bool) error {
+	value := "false"
+	if enabled {
+		value = "true"
+	}
+	return s.SetSetting(maintenanceModeKey, value)
+}
+
 // Helper functions
 func parseIntSafe(s string, result *int) (bool, error) {
 	if s == "" {
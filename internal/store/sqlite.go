@@ -1,15 +1,33 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	internal "github.com/taskflow/taskflow/internal"
 )
 
 // Store handles all database operations
 type Store struct {
 	db *sql.DB
+	// path is the database file, empty for test stores
+	path string
+
+	// scheduleChanged is called with a job ID after its schedule is saved or the job is deleted
+	scheduleChanged func(jobID string)
+
+	// uniqueJobNames makes CreateJob/UpdateJob reject a name another job already uses
+	uniqueJobNames bool
+
+	// manualTriggerInterval is the minimum time between manual runs of the same job; 0 disables the check
+	manualTriggerInterval time.Duration
+
+	// writeRetries is how many times execRetry retries a write that failed on a busy database
+	writeRetries int
+	// execFn replaces db.Exec in execRetry when set, so tests can inject errors
+	execFn func(query string, args ...interface{}) (sql.Result, error)
 }
 
 // New creates a new Store instance and initializes the database
@@ -51,7 +69,7 @@ func New(dbPath string) (*Store, error) {
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	return &Store{db: db}, nil
+	return &Store{db: db, path: dbPath, writeRetries: internal.DefaultDBWriteRetries}, nil
 }
 
 // Close closes the database connection
@@ -59,6 +77,82 @@ func (s *Store) Close() error {
 	return s.db.Close()
 }
 
+// SetScheduleChangeHook registers fn to be called after a job's schedule is saved or
+// the job is deleted, so schedule caches can be refreshed. fn must not block.
+func (s *Store) SetScheduleChangeHook(fn func(jobID string)) {
+	s.scheduleChanged = fn
+}
+
+// SetEnforceUniqueJobNames turns on rejection of duplicate job names with ErrDuplicateJobName
+func (s *Store) SetEnforceUniqueJobNames(enforce bool) {
+	s.uniqueJobNames = enforce
+}
+
+// SetManualTriggerInterval sets how long after a manual run is created further manual
+// triggers of the same job are refused (see RecentManualRun); 0 disables the check
+func (s *Store) SetManualTriggerInterval(interval time.Duration) {
+	s.manualTriggerInterval = interval
+}
+
+// SetWriteRetries sets how many times a hot-path write (run and log updates) is retried
+// when SQLite reports the database busy or locked; 0 disables retrying
+func (s *Store) SetWriteRetries(retries int) {
+	s.writeRetries = retries
+}
+
+// execRetry runs a write, retrying with doubling backoff while it fails on a busy or
+// locked database. Any other error is returned at once.
+func (s *Store) execRetry(query string, args ...interface{}) (sql.Result, error) {
+	exec := s.db.Exec
+	if s.execFn != nil {
+		exec = s.execFn
+	}
+
+	delay := internal.DBWriteRetryBaseDelay
+	for attempt := 0; ; attempt++ {
+		result, err := exec(query, args...)
+		if err == nil || !isBusyError(err) || attempt >= s.writeRetries {
+			return result, err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// notifyScheduleChanged calls the schedule change hook, if any
+func (s *Store) notifyScheduleChanged(jobID string) {
+	if s.scheduleChanged != nil {
+		s.scheduleChanged(jobID)
+	}
+}
+
+// WithTx runs fn inside a transaction, committing if fn returns nil and rolling back otherwise
+func (s *Store) WithTx(fn func(*sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Path returns the database file path, or "" for an in-memory test store
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Ping checks that the database connection is usable
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
 // DB returns the underlying database connection for advanced queries
 func (s *Store) DB() *sql.DB {
 	return s.db
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const templateColumns = `id, name, description, definition, created_by, created_at, updated_at`
+
+// scanTemplate scans a row selected with templateColumns into a JobTemplate
+func scanTemplate(row rowScanner) (*JobTemplate, error) {
+	t := &JobTemplate{}
+	var definition string
+	if err := row.Scan(&t.ID, &t.Name, &t.Description, &definition, &t.CreatedBy, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	t.Definition = []byte(definition)
+	return t, nil
+}
+
+// CreateJobTemplate stores a new job template
+func (s *Store) CreateJobTemplate(t *JobTemplate) (*JobTemplate, error) {
+	t.ID = uuid.New().String()
+	t.CreatedAt = time.Now()
+	t.UpdatedAt = t.CreatedAt
+
+	_, err := s.db.Exec(
+		`INSERT INTO job_templates (id, name, description, definition, created_by, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		t.ID, t.Name, t.Description, string(t.Definition), t.CreatedBy, t.CreatedAt, t.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job template: %w", err)
+	}
+	return t, nil
+}
+
+// GetJobTemplate retrieves a job template by ID
+func (s *Store) GetJobTemplate(id string) (*JobTemplate, error) {
+	t, err := scanTemplate(s.db.QueryRow(`SELECT `+templateColumns+` FROM job_templates WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.New("job template not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job template: %w", err)
+	}
+	return t, nil
+}
+
+// ListJobTemplates retrieves all job templates ordered by name
+func (s *Store) ListJobTemplates() ([]*JobTemplate, error) {
+	rows, err := s.db.Query(`SELECT ` + templateColumns + ` FROM job_templates ORDER BY name ASC, created_at ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list job templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := make([]*JobTemplate, 0)
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job template: %w", err)
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+// UpdateJobTemplate replaces a template's name, description and definition
+func (s *Store) UpdateJobTemplate(t *JobTemplate) error {
+	t.UpdatedAt = time.Now()
+
+	result, err := s.db.Exec(
+		`UPDATE job_templates SET name = ?, description = ?, definition = ?, updated_at = ? WHERE id = ?`,
+		t.Name, t.Description, string(t.Definition), t.UpdatedAt, t.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update job template: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return errors.New("job template not found")
+	}
+	return nil
+}
+
+// DeleteJobTemplate deletes a job template. Jobs created from it are unaffected.
+func (s *Store) DeleteJobTemplate(id string) error {
+	result, err := s.db.Exec(`DELETE FROM job_templates WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete job template: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return errors.New("job template not found")
+	}
+	return nil
+}
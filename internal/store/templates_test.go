@@ -0,0 +1,47 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestJobTemplateCRUD tests creating, reading, updating, listing and deleting templates
+func TestJobTemplateCRUD(t *testing.T) {
+	s := NewTestStore(t)
+	defer s.Close()
+
+	created, err := s.CreateJobTemplate(&JobTemplate{
+		Name:       "nightly backup",
+		Definition: json.RawMessage(`{"script":"backup.sh","timeout_seconds":600}`),
+		CreatedBy:  1,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, created.ID)
+
+	got, err := s.GetJobTemplate(created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "nightly backup", got.Name)
+	assert.JSONEq(t, `{"script":"backup.sh","timeout_seconds":600}`, string(got.Definition))
+
+	got.Description = "Standard backup job"
+	got.Definition = json.RawMessage(`{"script":"backup.sh --full"}`)
+	require.NoError(t, s.UpdateJobTemplate(got))
+
+	_, err = s.CreateJobTemplate(&JobTemplate{Name: "alpha", Definition: json.RawMessage(`{}`)})
+	require.NoError(t, err)
+	templates, err := s.ListJobTemplates()
+	require.NoError(t, err)
+	require.Len(t, templates, 2)
+	assert.Equal(t, "alpha", templates[0].Name)
+	assert.Equal(t, "Standard backup job", templates[1].Description)
+	assert.JSONEq(t, `{"script":"backup.sh --full"}`, string(templates[1].Definition))
+
+	require.NoError(t, s.DeleteJobTemplate(created.ID))
+	_, err = s.GetJobTemplate(created.ID)
+	assert.Error(t, err)
+	assert.Error(t, s.DeleteJobTemplate(created.ID))
+	assert.Error(t, s.UpdateJobTemplate(&JobTemplate{ID: "missing", Definition: json.RawMessage(`{}`)}))
+}
@@ -5,10 +5,11 @@ import (
 	"testing"
 
 	_ "github.com/mattn/go-sqlite3"
+	internal "github.com/taskflow/taskflow/internal"
 )
 
 // NewTestStore creates an in-memory SQLite database for testing
-func NewTestStore(t *testing.T) *Store {
+func NewTestStore(t testing.TB) *Store {
 	db, err := sql.Open("sqlite3", ":memory:")
 	if err != nil {
 		t.Fatalf("failed to create test database: %v", err)
@@ -18,9 +19,13 @@ func NewTestStore(t *testing.T) *Store {
 		t.Fatalf("failed to ping test database: %v", err)
 	}
 
+	// Each :memory: connection is a separate database, so pin the pool to one
+	// connection to keep concurrent writers (e.g. log streaming) on the same schema.
+	db.SetMaxOpenConns(1)
+
 	if err := RunMigrations(db); err != nil {
 		t.Fatalf("failed to run migrations: %v", err)
 	}
 
-	return &Store{db: db}
+	return &Store{db: db, writeRetries: internal.DefaultDBWriteRetries}
 }
@@ -0,0 +1,5 @@
+package internal
+
+// Version is the build version, injected at build time with
+// -ldflags "-X github.com/taskflow/taskflow/internal.Version=v1.2.3"
+var Version = "dev"